@@ -17,19 +17,25 @@ import (
 	"github.com/sipeed/picoclaw/pkg/channels"
 	_ "github.com/sipeed/picoclaw/pkg/channels/dingtalk"
 	_ "github.com/sipeed/picoclaw/pkg/channels/discord"
+	_ "github.com/sipeed/picoclaw/pkg/channels/email"
 	_ "github.com/sipeed/picoclaw/pkg/channels/feishu"
 	_ "github.com/sipeed/picoclaw/pkg/channels/irc"
 	_ "github.com/sipeed/picoclaw/pkg/channels/line"
 	_ "github.com/sipeed/picoclaw/pkg/channels/maixcam"
 	_ "github.com/sipeed/picoclaw/pkg/channels/matrix"
+	_ "github.com/sipeed/picoclaw/pkg/channels/mattermost"
+	_ "github.com/sipeed/picoclaw/pkg/channels/mqtt"
 	_ "github.com/sipeed/picoclaw/pkg/channels/onebot"
 	_ "github.com/sipeed/picoclaw/pkg/channels/pico"
 	_ "github.com/sipeed/picoclaw/pkg/channels/qq"
+	_ "github.com/sipeed/picoclaw/pkg/channels/rocketchat"
 	_ "github.com/sipeed/picoclaw/pkg/channels/slack"
 	_ "github.com/sipeed/picoclaw/pkg/channels/telegram"
+	_ "github.com/sipeed/picoclaw/pkg/channels/webhook"
 	_ "github.com/sipeed/picoclaw/pkg/channels/wecom"
 	_ "github.com/sipeed/picoclaw/pkg/channels/whatsapp"
 	_ "github.com/sipeed/picoclaw/pkg/channels/whatsapp_native"
+	_ "github.com/sipeed/picoclaw/pkg/channels/xmpp"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cron"
 	"github.com/sipeed/picoclaw/pkg/devices"
@@ -37,8 +43,10 @@ import (
 	"github.com/sipeed/picoclaw/pkg/heartbeat"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/outbox"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/state"
+	"github.com/sipeed/picoclaw/pkg/tokenizer"
 	"github.com/sipeed/picoclaw/pkg/tools"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
@@ -64,6 +72,8 @@ func gatewayCmd(debug bool) error {
 		cfg.Agents.Defaults.ModelName = modelID
 	}
 
+	tokenizer.SetVocabURL(tokenizer.DefaultVocabURL)
+
 	msgBus := bus.NewMessageBus()
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
 
@@ -135,6 +145,21 @@ func gatewayCmd(debug bool) error {
 		return fmt.Errorf("error creating channel manager: %w", err)
 	}
 
+	// Persist outbound messages so replies survive restarts and outages
+	var outboxStore *outbox.Store
+	if cfg.Gateway.Outbox.Enabled {
+		outboxStore, err = outbox.Open(outbox.DefaultPath(cfg.WorkspacePath()))
+		if err != nil {
+			fmt.Printf("Error opening outbox, outbound messages will not be persisted: %v\n", err)
+			outboxStore = nil
+		} else {
+			channelManager.SetOutbox(outboxStore, cfg.Gateway.Outbox.MaxAttempts)
+			if stats, statsErr := outboxStore.Stats(context.Background()); statsErr == nil {
+				fmt.Printf("✓ Outbox: %d pending, %d dead\n", stats.Pending, stats.Dead)
+			}
+		}
+	}
+
 	// Inject channel manager and media store into agent loop
 	agentLoop.SetChannelManager(channelManager)
 	agentLoop.SetMediaStore(mediaStore)
@@ -183,6 +208,15 @@ func gatewayCmd(debug bool) error {
 	// Setup shared HTTP server with health endpoints and webhook handlers
 	healthServer := health.NewServer(cfg.Gateway.Host, cfg.Gateway.Port)
 	addr := fmt.Sprintf("%s:%d", cfg.Gateway.Host, cfg.Gateway.Port)
+	if outboxStore != nil {
+		healthServer.RegisterDetail("outbox", func() any {
+			stats, statsErr := outboxStore.Stats(context.Background())
+			if statsErr != nil {
+				return map[string]any{"error": statsErr.Error()}
+			}
+			return stats
+		})
+	}
 	channelManager.SetupHTTPServer(addr, healthServer)
 
 	if err := channelManager.StartAll(ctx); err != nil {
@@ -211,6 +245,9 @@ func gatewayCmd(debug bool) error {
 	defer shutdownCancel()
 
 	channelManager.StopAll(shutdownCtx)
+	if outboxStore != nil {
+		outboxStore.Close()
+	}
 	deviceService.Stop()
 	heartbeatService.Stop()
 	cronService.Stop()
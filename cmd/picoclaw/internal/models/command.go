@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
+)
+
+func NewModelsCommand() *cobra.Command {
+	var apiBase, apiKey string
+
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "Manage local Ollama models",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+		// Resolve the server at execution time so it reflects the current
+		// config; --api-base takes precedence.
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if cmd.Flags().Changed("api-base") {
+				apiBase = ollama.NormalizeAPIBase(apiBase)
+				return nil
+			}
+			cfg, err := internal.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			apiBase = providers.OllamaAPIBase(cfg)
+			apiKey = providers.OllamaAPIKey(cfg, apiBase)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&apiBase, "api-base", "", "Ollama server URL (default: from config, else "+ollama.DefaultAPIBase+")")
+
+	client := func() *ollama.Provider { return ollama.NewProvider(apiBase, "", ollama.WithAPIKey(apiKey)) }
+	cmd.AddCommand(
+		newListCommand(client),
+		newPullCommand(client),
+		newRemoveCommand(client),
+	)
+
+	return cmd
+}
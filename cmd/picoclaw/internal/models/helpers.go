@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
+)
+
+// formatSize renders a byte count the way Ollama does (decimal units).
+func formatSize(bytes int64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[exp])
+}
+
+// progressPrinter prints pull progress: one line per status change, and a
+// download percentage only when it has advanced by at least ten points, so
+// the output stays readable in logs as well as on a terminal.
+type progressPrinter struct {
+	out        io.Writer
+	lastStatus string
+	lastPct    int
+}
+
+func newProgressPrinter(out io.Writer) *progressPrinter {
+	return &progressPrinter{out: out, lastPct: -1}
+}
+
+func (p *progressPrinter) update(u ollama.PullProgress) {
+	if u.Status == "success" {
+		return
+	}
+	if u.Status != p.lastStatus {
+		p.lastStatus = u.Status
+		p.lastPct = -1
+		if u.Total == 0 {
+			fmt.Fprintf(p.out, "  %s\n", u.Status)
+		}
+	}
+	if u.Total <= 0 {
+		return
+	}
+	pct := int(u.Completed * 100 / u.Total)
+	if p.lastPct < 0 || pct >= p.lastPct+10 || (pct == 100 && p.lastPct != 100) {
+		p.lastPct = pct
+		fmt.Fprintf(p.out, "  %s  %3d%% of %s\n", u.Status, pct, formatSize(u.Total))
+	}
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
+)
+
+// fakeServer serves /api/tags, /api/pull and /api/delete over an in-memory
+// list of model names.
+func fakeServer(t *testing.T, models ...string) *ollama.Provider {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		list := make([]map[string]any, 0, len(models))
+		for _, name := range models {
+			list = append(list, map[string]any{
+				"name":        name,
+				"size":        2019393189,
+				"modified_at": "2026-03-01T10:00:00Z",
+				"details":     map[string]any{"parameter_size": "3.2B"},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"models": list})
+	})
+	mux.HandleFunc("POST /api/pull", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		enc := json.NewEncoder(w)
+		enc.Encode(map[string]any{"status": "pulling manifest"})
+		for _, done := range []int{0, 5, 40, 45, 100} {
+			enc.Encode(map[string]any{"status": "pulling 6a0746a1ec1a", "total": 2000000000, "completed": done * 20000000})
+		}
+		enc.Encode(map[string]any{"status": "verifying sha256 digest"})
+		enc.Encode(map[string]any{"status": "success"})
+		models = append(models, req.Model)
+	})
+	mux.HandleFunc("DELETE /api/delete", func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		for i, name := range models {
+			if name == req.Model {
+				models = append(models[:i], models[i+1:]...)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model '` + req.Model + `' not found"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return ollama.NewProvider(server.URL, "")
+}
+
+func TestModelsListCmd(t *testing.T) {
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	require.NoError(t, modelsListCmd(ctx, &out, fakeServer(t), false))
+	assert.Contains(t, out.String(), "No models installed")
+
+	out.Reset()
+	require.NoError(t, modelsListCmd(ctx, &out, fakeServer(t, "llama3.2:3b"), false))
+	lines := strings.Split(out.String(), "\n")
+	assert.True(t, strings.HasPrefix(lines[0], "NAME"))
+	assert.Contains(t, lines[1], "llama3.2:3b")
+	assert.Contains(t, lines[1], "2.0 GB")
+	assert.Contains(t, lines[1], "3.2B")
+
+	out.Reset()
+	require.NoError(t, modelsListCmd(ctx, &out, fakeServer(t, "llama3.2:3b"), true))
+	var decoded []ollama.LocalModel
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, "llama3.2:3b", decoded[0].Name)
+}
+
+func TestModelsPullAndRemoveCmd(t *testing.T) {
+	ctx := context.Background()
+	client := fakeServer(t)
+
+	var out bytes.Buffer
+	require.NoError(t, modelsPullCmd(ctx, &out, client, []string{"qwen3:8b"}))
+	assert.Equal(t, `Pulling qwen3:8b...
+  pulling manifest
+  pulling 6a0746a1ec1a    0% of 2.0 GB
+  pulling 6a0746a1ec1a   40% of 2.0 GB
+  pulling 6a0746a1ec1a  100% of 2.0 GB
+  verifying sha256 digest
+✓ Pulled qwen3:8b
+`, out.String())
+
+	models, err := client.ListModels(ctx)
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+
+	out.Reset()
+	require.NoError(t, modelsRemoveCmd(ctx, &out, client, []string{"qwen3:8b"}))
+	assert.Equal(t, "✓ Deleted qwen3:8b\n", out.String())
+
+	err = modelsRemoveCmd(ctx, &out, client, []string{"qwen3:8b"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "4.7 GB", formatSize(4_661_224_676))
+	assert.Equal(t, "274.3 MB", formatSize(274_302_450))
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
+)
+
+func newListCommand(client func() *ollama.Provider) *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List models installed on the Ollama server",
+		Args:    cobra.NoArgs,
+		Example: `  picoclaw models list
+  picoclaw models list --api-base http://gpu-box:11434`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return modelsListCmd(cmd.Context(), cmd.OutOrStdout(), client(), jsonOutput)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print models as JSON")
+
+	return cmd
+}
+
+func modelsListCmd(ctx context.Context, out io.Writer, client *ollama.Provider, jsonOutput bool) error {
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(models)
+	}
+
+	if len(models) == 0 {
+		fmt.Fprintln(out, "No models installed. Pull one with: picoclaw models pull <model>")
+		return nil
+	}
+
+	width := len("NAME")
+	for _, m := range models {
+		width = max(width, len(m.Name))
+	}
+	fmt.Fprintf(out, "%-*s  %9s  %-7s  %s\n", width, "NAME", "SIZE", "PARAMS", "MODIFIED")
+	for _, m := range models {
+		fmt.Fprintf(out, "%-*s  %9s  %-7s  %s\n",
+			width, m.Name, formatSize(m.Size), m.Details.ParameterSize, m.ModifiedAt.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintf(out, "\nUse a model with \"model\": \"ollama/<name>\" in model_list.\n")
+	return nil
+}
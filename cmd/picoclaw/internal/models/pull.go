@@ -0,0 +1,35 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
+)
+
+func newPullCommand(client func() *ollama.Provider) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull <model>...",
+		Short: "Download models from the Ollama library",
+		Args:  cobra.MinimumNArgs(1),
+		Example: `  picoclaw models pull llama3.2
+  picoclaw models pull qwen3:8b gemma3:4b`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return modelsPullCmd(cmd.Context(), cmd.OutOrStdout(), client(), args)
+		},
+	}
+}
+
+func modelsPullCmd(ctx context.Context, out io.Writer, client *ollama.Provider, names []string) error {
+	for _, name := range names {
+		fmt.Fprintf(out, "Pulling %s...\n", name)
+		if err := client.PullModel(ctx, name, newProgressPrinter(out).update); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "✓ Pulled %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
+)
+
+func newRemoveCommand(client func() *ollama.Provider) *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <model>...",
+		Aliases: []string{"remove"},
+		Short:   "Delete models from the Ollama server",
+		Args:    cobra.MinimumNArgs(1),
+		Example: `  picoclaw models rm llama3.2`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return modelsRemoveCmd(cmd.Context(), cmd.OutOrStdout(), client(), args)
+		},
+	}
+}
+
+func modelsRemoveCmd(ctx context.Context, out io.Writer, client *ollama.Provider, names []string) error {
+	for _, name := range names {
+		if err := client.DeleteModel(ctx, name); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "✓ Deleted %s\n", name)
+	}
+	return nil
+}
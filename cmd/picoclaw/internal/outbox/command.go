@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/outbox"
+)
+
+func NewOutboxCommand() *cobra.Command {
+	var storePath string
+
+	cmd := &cobra.Command{
+		Use:   "outbox",
+		Short: "Inspect and manage undelivered outbound messages",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+		// Resolve storePath at execution time so it reflects the current config
+		// and is shared across all subcommands.
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := internal.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			storePath = outbox.DefaultPath(cfg.WorkspacePath())
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		newListCommand(func() string { return storePath }),
+		newRetryCommand(func() string { return storePath }),
+		newPurgeCommand(func() string { return storePath }),
+	)
+
+	return cmd
+}
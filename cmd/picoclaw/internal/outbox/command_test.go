@@ -0,0 +1,39 @@
+package outbox
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOutboxCommand(t *testing.T) {
+	cmd := NewOutboxCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "outbox", cmd.Use)
+	assert.Equal(t, "Inspect and manage undelivered outbound messages", cmd.Short)
+	assert.False(t, cmd.HasFlags())
+
+	assert.Nil(t, cmd.Run)
+	assert.NotNil(t, cmd.RunE)
+	assert.NotNil(t, cmd.PersistentPreRunE)
+
+	allowedCommands := []string{"list", "retry", "purge"}
+
+	subcommands := cmd.Commands()
+	assert.Len(t, subcommands, len(allowedCommands))
+
+	for _, subcmd := range subcommands {
+		found := slices.Contains(allowedCommands, subcmd.Name())
+		assert.True(t, found, "unexpected subcommand %q", subcmd.Name())
+
+		assert.False(t, subcmd.HasSubCommands())
+		assert.True(t, subcmd.HasExample())
+
+		assert.Nil(t, subcmd.Run)
+		assert.NotNil(t, subcmd.RunE)
+	}
+}
@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/outbox"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// parseIDs converts entry ID arguments.
+func parseIDs(args []string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid outbox entry ID %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseState accepts "pending", "dead" or "all"; "all" maps to the empty
+// state, which matches every entry.
+func parseState(value string) (outbox.State, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "all":
+		return "", nil
+	case string(outbox.StatePending):
+		return outbox.StatePending, nil
+	case string(outbox.StateDead):
+		return outbox.StateDead, nil
+	}
+	return "", fmt.Errorf("invalid --state value %q: use pending, dead or all", value)
+}
+
+// preview summarizes an entry's payload on one line.
+func preview(e outbox.Entry) string {
+	switch e.Kind {
+	case outbox.KindMessage:
+		var msg bus.OutboundMessage
+		if err := e.Decode(&msg); err != nil {
+			return "(undecodable message)"
+		}
+		return utils.Truncate(strings.ReplaceAll(msg.Content, "\n", " "), 80)
+	case outbox.KindMedia:
+		var msg bus.OutboundMediaMessage
+		if err := e.Decode(&msg); err != nil {
+			return "(undecodable media)"
+		}
+		return fmt.Sprintf("(%d media part(s))", len(msg.Parts))
+	}
+	return fmt.Sprintf("(unknown kind %q)", e.Kind)
+}
@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/outbox"
+)
+
+// seedStore creates an outbox with one pending telegram message and one
+// dead-lettered slack message.
+func seedStore(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "outbox.db")
+	store, err := outbox.Open(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+	_, err = store.Add(ctx, outbox.KindMessage, "telegram", "42",
+		bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "daily report\nall green"})
+	require.NoError(t, err)
+	id, err := store.Add(ctx, outbox.KindMedia, "slack", "C1",
+		bus.OutboundMediaMessage{Channel: "slack", ChatID: "C1", Parts: []bus.MediaPart{{Type: "image"}}})
+	require.NoError(t, err)
+	_, err = store.Fail(ctx, id, "channel_not_found", true, 10)
+	require.NoError(t, err)
+	return path
+}
+
+func stats(t *testing.T, path string) outbox.Stats {
+	t.Helper()
+	store, err := outbox.Open(path)
+	require.NoError(t, err)
+	defer store.Close()
+	st, err := store.Stats(context.Background())
+	require.NoError(t, err)
+	return st
+}
+
+func TestOutboxListCmd(t *testing.T) {
+	path := seedStore(t)
+
+	var out bytes.Buffer
+	require.NoError(t, outboxListCmd(&out, path, listOptions{state: "all", limit: 50}))
+	assert.Contains(t, out.String(), "Outbox: 1 pending, 1 dead")
+	assert.Contains(t, out.String(), "telegram:42  [pending, 0 attempt(s)]")
+	assert.Contains(t, out.String(), "daily report all green")
+	assert.Contains(t, out.String(), "(1 media part(s))")
+	assert.Contains(t, out.String(), "last error: channel_not_found")
+
+	out.Reset()
+	require.NoError(t, outboxListCmd(&out, path, listOptions{state: "dead", limit: 50}))
+	assert.NotContains(t, out.String(), "telegram:42")
+
+	_, err := parseState("stuck")
+	assert.Error(t, err)
+}
+
+func TestOutboxRetryCmd(t *testing.T) {
+	path := seedStore(t)
+
+	var out bytes.Buffer
+	assert.Error(t, outboxRetryCmd(&out, path, nil, retryOptions{}))
+	assert.Error(t, outboxRetryCmd(&out, path, []string{"2"}, retryOptions{all: true}))
+	assert.Error(t, outboxRetryCmd(&out, path, []string{"x"}, retryOptions{}))
+
+	require.NoError(t, outboxRetryCmd(&out, path, nil, retryOptions{all: true}))
+	assert.Contains(t, out.String(), "Queued 1 message(s)")
+	assert.Equal(t, outbox.Stats{Pending: 2}, withoutOldest(stats(t, path)))
+}
+
+func TestOutboxPurgeCmd(t *testing.T) {
+	path := seedStore(t)
+
+	var out bytes.Buffer
+	require.NoError(t, outboxPurgeCmd(&out, path, nil, purgeOptions{state: "dead"}, false))
+	assert.Contains(t, out.String(), "Deleted 1 message(s)")
+	assert.Equal(t, outbox.Stats{Pending: 1}, withoutOldest(stats(t, path)))
+
+	assert.Error(t, outboxPurgeCmd(&out, path, []string{"1"}, purgeOptions{state: "all"}, true))
+
+	out.Reset()
+	require.NoError(t, outboxPurgeCmd(&out, path, []string{"1"}, purgeOptions{state: "dead"}, false))
+	assert.Contains(t, out.String(), "Deleted 1 message(s)")
+	assert.Equal(t, outbox.Stats{}, stats(t, path))
+}
+
+func withoutOldest(st outbox.Stats) outbox.Stats {
+	st.OldestPending = time.Time{}
+	return st
+}
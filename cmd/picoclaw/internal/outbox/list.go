@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/outbox"
+)
+
+type listOptions struct {
+	state      string
+	channel    string
+	limit      int
+	jsonOutput bool
+}
+
+func newListCommand(storePath func() string) *cobra.Command {
+	var opts listOptions
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List pending and dead-lettered messages",
+		Args:  cobra.NoArgs,
+		Example: `  picoclaw outbox list
+  picoclaw outbox list --state dead --channel telegram`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return outboxListCmd(cmd.OutOrStdout(), storePath(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.state, "state", "all", "Only list entries in this state: pending, dead or all")
+	cmd.Flags().StringVar(&opts.channel, "channel", "", "Only list entries for this channel (e.g. telegram or telegram:work)")
+	cmd.Flags().IntVar(&opts.limit, "limit", 50, "Maximum number of entries")
+	cmd.Flags().BoolVar(&opts.jsonOutput, "json", false, "Print entries as JSON")
+
+	return cmd
+}
+
+func outboxListCmd(out io.Writer, storePath string, opts listOptions) error {
+	state, err := parseState(opts.state)
+	if err != nil {
+		return err
+	}
+
+	store, err := outbox.Open(storePath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	entries, err := store.List(ctx, outbox.Filter{State: state, Channel: opts.channel, Limit: opts.limit})
+	if err != nil {
+		return err
+	}
+
+	if opts.jsonOutput {
+		if entries == nil {
+			entries = []outbox.Entry{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Outbox: %d pending, %d dead\n", stats.Pending, stats.Dead)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out)
+	for _, e := range entries {
+		fmt.Fprintf(out, "#%d  %s  %s:%s  [%s, %d attempt(s)]\n",
+			e.ID, e.CreatedAt.Local().Format("2006-01-02 15:04"), e.Channel, e.ChatID, e.State, e.Attempts)
+		fmt.Fprintf(out, "  %s\n", preview(e))
+		if e.LastError != "" {
+			fmt.Fprintf(out, "  last error: %s\n", e.LastError)
+		}
+		if e.State == outbox.StatePending && e.Attempts > 0 {
+			fmt.Fprintf(out, "  next attempt: %s\n", e.NextAttemptAt.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+	return nil
+}
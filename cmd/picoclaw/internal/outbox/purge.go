@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/outbox"
+)
+
+type purgeOptions struct {
+	state   string
+	channel string
+}
+
+func newPurgeCommand(storePath func() string) *cobra.Command {
+	var opts purgeOptions
+
+	cmd := &cobra.Command{
+		Use:   "purge [id...]",
+		Short: "Delete messages from the outbox",
+		Long: `Delete messages from the outbox so they are never delivered.
+
+Without IDs, every entry in --state (dead letters by default), optionally
+limited to one channel, is deleted.`,
+		Args: cobra.ArbitraryArgs,
+		Example: `  picoclaw outbox purge
+  picoclaw outbox purge 12
+  picoclaw outbox purge --state all --channel discord`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return outboxPurgeCmd(cmd.OutOrStdout(), storePath(), args, opts, cmd.Flags().Changed("state"))
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.state, "state", "dead", "Delete entries in this state: pending, dead or all")
+	cmd.Flags().StringVar(&opts.channel, "channel", "", "Only delete entries for this channel")
+
+	return cmd
+}
+
+func outboxPurgeCmd(out io.Writer, storePath string, args []string, opts purgeOptions, stateSet bool) error {
+	var filter outbox.Filter
+	if len(args) > 0 {
+		if stateSet || opts.channel != "" {
+			return errors.New("pass entry IDs or --state/--channel, not both")
+		}
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		filter.IDs = ids
+	} else {
+		state, err := parseState(opts.state)
+		if err != nil {
+			return err
+		}
+		filter = outbox.Filter{State: state, Channel: opts.channel}
+	}
+
+	store, err := outbox.Open(storePath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	n, err := store.Purge(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✓ Deleted %d message(s)\n", n)
+	return nil
+}
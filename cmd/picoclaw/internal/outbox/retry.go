@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/outbox"
+)
+
+type retryOptions struct {
+	all     bool
+	channel string
+}
+
+func newRetryCommand(storePath func() string) *cobra.Command {
+	var opts retryOptions
+
+	cmd := &cobra.Command{
+		Use:   "retry [id...]",
+		Short: "Queue messages for immediate redelivery",
+		Long: `Queue messages for immediate redelivery by the running gateway.
+
+Without --all, only the given entries are retried. With --all, every
+dead-lettered entry (optionally limited to one channel) is retried.
+Retried entries start over with a fresh attempt count.`,
+		Args: cobra.ArbitraryArgs,
+		Example: `  picoclaw outbox retry 12 13
+  picoclaw outbox retry --all --channel slack`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return outboxRetryCmd(cmd.OutOrStdout(), storePath(), args, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.all, "all", false, "Retry every dead-lettered entry")
+	cmd.Flags().StringVar(&opts.channel, "channel", "", "With --all, only retry entries for this channel")
+
+	return cmd
+}
+
+func outboxRetryCmd(out io.Writer, storePath string, args []string, opts retryOptions) error {
+	filter := outbox.Filter{Channel: opts.channel}
+	switch {
+	case opts.all && len(args) > 0:
+		return errors.New("pass entry IDs or --all, not both")
+	case opts.all:
+		filter.State = outbox.StateDead
+	case len(args) == 0:
+		return errors.New("pass the entry IDs to retry, or --all for every dead letter")
+	default:
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		// Explicit IDs may also name pending entries waiting out their backoff.
+		filter = outbox.Filter{IDs: ids}
+	}
+
+	store, err := outbox.Open(storePath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	n, err := store.Retry(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✓ Queued %d message(s) for redelivery\n", n)
+	return nil
+}
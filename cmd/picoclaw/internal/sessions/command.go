@@ -0,0 +1,46 @@
+package sessions
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+)
+
+func NewSessionsCommand() *cobra.Command {
+	var workspace, backend string
+
+	cmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Manage stored conversation sessions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+		// Resolve the workspace at execution time so it reflects the
+		// current config and is shared across all subcommands.
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := internal.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			workspace = cfg.WorkspacePath()
+			backend = cfg.Agents.Defaults.SessionStore
+			return nil
+		},
+	}
+
+	sessionsDir := func() string { return filepath.Join(workspace, "sessions") }
+	storeBackend := func() string { return backend }
+
+	cmd.AddCommand(
+		newExportCommand(sessionsDir, storeBackend),
+		newImportCommand(sessionsDir, storeBackend),
+		newMigrateCommand(sessionsDir, storeBackend),
+		newSearchCommand(func() string { return workspace }),
+	)
+
+	return cmd
+}
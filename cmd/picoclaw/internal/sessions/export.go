@@ -0,0 +1,82 @@
+package sessions
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+type exportOptions struct {
+	dir    string
+	format string
+	output string
+}
+
+func newExportCommand(sessionsDir, backend func() string) *cobra.Command {
+	var opts exportOptions
+
+	cmd := &cobra.Command{
+		Use:   "export <session-key>",
+		Short: "Export a session as JSONL, Markdown or OpenAI messages JSON",
+		Args:  cobra.ExactArgs(1),
+		Example: `  picoclaw sessions export agent:main:telegram:direct:123456 > chat.jsonl
+  picoclaw sessions export agent:main:telegram:direct:123456 -o chat.md
+  picoclaw sessions export agent:main:pico:direct:pico:abc --format openai`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.dir == "" {
+				opts.dir = sessionsDir()
+			}
+			return sessionsExportCmd(cmd.OutOrStdout(), backend(), args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.dir, "dir", "", "Sessions directory (default: <workspace>/sessions)")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "",
+		"Output format: jsonl, markdown or openai (default: from --output extension, else jsonl)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "Write to this file instead of stdout")
+
+	return cmd
+}
+
+func sessionsExportCmd(out io.Writer, backend, key string, opts exportOptions) error {
+	format := opts.format
+	if format == "" {
+		format = session.FormatFromPath(opts.output)
+	}
+	if format == "" {
+		format = session.FormatJSONL
+	}
+
+	store, err := session.OpenStore(backend, opts.dir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	transcript, err := session.ExportTranscript(store, key)
+	if err != nil {
+		return err
+	}
+
+	if opts.output == "" {
+		return session.EncodeTranscript(out, transcript, format)
+	}
+
+	f, err := os.Create(opts.output)
+	if err != nil {
+		return err
+	}
+	if err := session.EncodeTranscript(f, transcript, format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Exported %d messages from %s to %s\n", len(transcript.Messages), key, opts.output)
+	return nil
+}
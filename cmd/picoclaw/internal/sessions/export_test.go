@@ -0,0 +1,57 @@
+package sessions
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func seedSession(t *testing.T, backend, dir, key string) {
+	t.Helper()
+	store, err := session.OpenStore(backend, dir)
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, session.ImportTranscript(store, session.Transcript{
+		Key:     key,
+		Summary: "earlier chat",
+		Messages: []providers.Message{
+			{Role: "user", Content: "hello"},
+			{Role: "assistant", Content: "hi there"},
+		},
+	}))
+}
+
+func TestSessionsExportCmd(t *testing.T) {
+	dir := t.TempDir()
+	seedSession(t, config.SessionStoreSQLite, dir, "telegram:1")
+
+	var out bytes.Buffer
+	require.NoError(t, sessionsExportCmd(&out, config.SessionStoreSQLite, "telegram:1", exportOptions{dir: dir}))
+	assert.Contains(t, out.String(), `"type":"session"`)
+	assert.Contains(t, out.String(), `"content":"hi there"`)
+
+	// The format follows the output file extension.
+	target := filepath.Join(t.TempDir(), "chat.md")
+	out.Reset()
+	require.NoError(t, sessionsExportCmd(&out, config.SessionStoreSQLite, "telegram:1",
+		exportOptions{dir: dir, output: target}))
+	assert.Contains(t, out.String(), "Exported 2 messages from telegram:1")
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "## Summary")
+	assert.Contains(t, string(data), "### Assistant")
+
+	err = sessionsExportCmd(&out, config.SessionStoreSQLite, "missing", exportOptions{dir: dir})
+	assert.ErrorIs(t, err, session.ErrSessionNotFound)
+
+	err = sessionsExportCmd(&out, config.SessionStoreSQLite, "telegram:1", exportOptions{dir: dir, format: "xml"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package sessions
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+type importOptions struct {
+	dir    string
+	format string
+	key    string
+	force  bool
+}
+
+func newImportCommand(sessionsDir, backend func() string) *cobra.Command {
+	var opts importOptions
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import a session exported with 'sessions export'",
+		Long: `Import a session exported with 'sessions export' into the configured
+session store. A running gateway using the json session store only sees
+imported sessions after a restart.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  picoclaw sessions import chat.jsonl
+  picoclaw sessions import chat.md --key agent:main:telegram:direct:123456
+  cat chat.json | picoclaw sessions import - --format openai --key agent:main:cli:default`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.dir == "" {
+				opts.dir = sessionsDir()
+			}
+			return sessionsImportCmd(cmd.InOrStdin(), cmd.OutOrStdout(), backend(), args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.dir, "dir", "", "Sessions directory (default: <workspace>/sessions)")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "",
+		"Input format: jsonl, markdown or openai (default: from file extension)")
+	cmd.Flags().StringVar(&opts.key, "key", "", "Session key to import into (default: the key stored in the file)")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Replace an existing session with the same key")
+
+	return cmd
+}
+
+// sessionsImportCmd reads a transcript from path ("-" for stdin) into the
+// session store.
+func sessionsImportCmd(stdin io.Reader, out io.Writer, backend, path string, opts importOptions) error {
+	format := opts.format
+	if format == "" {
+		format = session.FormatFromPath(path)
+	}
+	if format == "" {
+		return fmt.Errorf("cannot infer the format of %q; use --format (%s)",
+			path, strings.Join(session.TranscriptFormats, ", "))
+	}
+
+	in := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	transcript, err := session.DecodeTranscript(in, format)
+	if err != nil {
+		return err
+	}
+	if opts.key != "" {
+		transcript.Key = opts.key
+	}
+	if transcript.Key == "" {
+		return fmt.Errorf("%s does not record a session key; use --key", path)
+	}
+
+	store, err := session.OpenStore(backend, opts.dir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if !opts.force {
+		if _, err := session.ExportTranscript(store, transcript.Key); err == nil {
+			return fmt.Errorf("session %s already exists; use --force to replace it", transcript.Key)
+		}
+	}
+	if err := session.ImportTranscript(store, transcript); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Imported %d messages into %s\n", len(transcript.Messages), transcript.Key)
+	return nil
+}
@@ -0,0 +1,57 @@
+package sessions
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func TestSessionsImportCmd(t *testing.T) {
+	src := t.TempDir()
+	seedSession(t, config.SessionStoreJSON, src, "telegram:1")
+
+	file := filepath.Join(t.TempDir(), "chat.json")
+	var out bytes.Buffer
+	require.NoError(t, sessionsExportCmd(&out, config.SessionStoreJSON, "telegram:1",
+		exportOptions{dir: src, output: file}))
+
+	// Move the session to another machine using a different backend.
+	dst := t.TempDir()
+	out.Reset()
+	require.NoError(t, sessionsImportCmd(nil, &out, config.SessionStoreSQLite, file, importOptions{dir: dst}))
+	assert.Contains(t, out.String(), "Imported 2 messages into telegram:1")
+
+	store, err := session.OpenStore(config.SessionStoreSQLite, dst)
+	require.NoError(t, err)
+	assert.Equal(t, "earlier chat", store.GetSummary("telegram:1"))
+	assert.Len(t, store.GetHistory("telegram:1"), 2)
+	store.Close()
+
+	// Existing sessions are only replaced with --force.
+	err = sessionsImportCmd(nil, &out, config.SessionStoreSQLite, file, importOptions{dir: dst})
+	assert.ErrorContains(t, err, "already exists")
+	require.NoError(t, sessionsImportCmd(nil, &out, config.SessionStoreSQLite, file,
+		importOptions{dir: dst, force: true}))
+
+	// --key imports under a new key; stdin needs an explicit format.
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	err = sessionsImportCmd(strings.NewReader(string(data)), &out, config.SessionStoreSQLite, "-",
+		importOptions{dir: dst, key: "discord:2"})
+	assert.ErrorContains(t, err, "use --format")
+	require.NoError(t, sessionsImportCmd(strings.NewReader(string(data)), &out, config.SessionStoreSQLite, "-",
+		importOptions{dir: dst, key: "discord:2", format: session.FormatOpenAI}))
+
+	store, err = session.OpenStore(config.SessionStoreSQLite, dst)
+	require.NoError(t, err)
+	defer store.Close()
+	assert.Len(t, store.GetHistory("discord:2"), 2)
+}
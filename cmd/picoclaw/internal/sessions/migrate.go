@@ -0,0 +1,95 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/memory"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func newMigrateCommand(sessionsDir, storeBackend func() string) *cobra.Command {
+	var dir, to string
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Import sessions written by other backends into the SQLite or JSONL store",
+		Args:  cobra.NoArgs,
+		Example: `  picoclaw sessions migrate
+  picoclaw sessions migrate --to jsonl
+  picoclaw sessions migrate --dir ~/.picoclaw/workspace-helper/sessions`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if dir == "" {
+				dir = sessionsDir()
+			}
+			if to == "" {
+				to = storeBackend()
+			}
+			if to == "" || to == config.SessionStoreJSON {
+				to = config.SessionStoreSQLite
+			}
+			return sessionsMigrateCmd(cmd.Context(), cmd.OutOrStdout(), dir, to)
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Sessions directory to migrate (default: <workspace>/sessions)")
+	cmd.Flags().StringVar(&to, "to", "",
+		"Target backend: sqlite or jsonl (default: the configured session_store, or sqlite)")
+
+	return cmd
+}
+
+// sessionsMigrateCmd imports the legacy sessions in dir into the backend
+// named by to: JSON sessions into JSONL, or JSON and JSONL sessions into
+// the SQLite database in the same directory. Migrated files are renamed to
+// *.migrated, so running it again only picks up new files.
+func sessionsMigrateCmd(ctx context.Context, out io.Writer, dir, to string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var (
+		store memory.Store
+		dest  string
+	)
+	switch to {
+	case config.SessionStoreSQLite:
+		dest = filepath.Join(dir, session.SQLiteFileName)
+		s, err := memory.NewSQLiteStore(dest)
+		if err != nil {
+			return err
+		}
+		store = s
+	case config.SessionStoreJSONL:
+		dest = dir
+		s, err := memory.NewJSONLStore(dir)
+		if err != nil {
+			return err
+		}
+		store = s
+	default:
+		return fmt.Errorf("cannot migrate into session store %q; use sqlite or jsonl", to)
+	}
+	defer store.Close()
+
+	fromJSON, err := memory.MigrateFromJSON(ctx, dir, store)
+	if err != nil {
+		return err
+	}
+	fromJSONL := 0
+	if to == config.SessionStoreSQLite {
+		fromJSONL, err = memory.MigrateFromJSONL(ctx, dir, store)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "Migrated %d JSON and %d JSONL sessions into %s\n", fromJSON, fromJSONL, dest)
+	fmt.Fprintf(out, "Set \"agents.defaults.session_store\" to %q to use it.\n", to)
+	return nil
+}
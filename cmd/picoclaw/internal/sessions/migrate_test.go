@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/memory"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func TestSessionsMigrateCmd(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	legacy, err := json.Marshal(map[string]any{
+		"key":      "telegram:1",
+		"messages": []providers.Message{{Role: "user", Content: "from json"}},
+		"summary":  "legacy summary",
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "telegram_1.json"), legacy, 0o644))
+
+	jsonl, err := memory.NewJSONLStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, jsonl.AddMessage(ctx, "discord:2", "user", "from jsonl"))
+
+	var out bytes.Buffer
+	require.NoError(t, sessionsMigrateCmd(ctx, &out, dir, config.SessionStoreSQLite))
+	assert.Contains(t, out.String(), "Migrated 1 JSON and 1 JSONL sessions")
+
+	store, err := memory.NewSQLiteStore(filepath.Join(dir, session.SQLiteFileName))
+	require.NoError(t, err)
+	defer store.Close()
+
+	history, err := store.GetHistory(ctx, "telegram:1")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "from json", history[0].Content)
+
+	summary, err := store.GetSummary(ctx, "telegram:1")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy summary", summary)
+
+	history, err = store.GetHistory(ctx, "discord:2")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "from jsonl", history[0].Content)
+
+	// A second run finds nothing left to import.
+	out.Reset()
+	require.NoError(t, sessionsMigrateCmd(ctx, &out, dir, config.SessionStoreSQLite))
+	assert.Contains(t, out.String(), "Migrated 0 JSON and 0 JSONL sessions")
+}
+
+func TestSessionsMigrateCmd_JSONL(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	legacy, err := json.Marshal(map[string]any{
+		"key":      "telegram:1",
+		"messages": []providers.Message{{Role: "user", Content: "from json"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "telegram_1.json"), legacy, 0o644))
+
+	var out bytes.Buffer
+	require.NoError(t, sessionsMigrateCmd(ctx, &out, dir, config.SessionStoreJSONL))
+	assert.Contains(t, out.String(), "Migrated 1 JSON and 0 JSONL sessions")
+
+	store, err := memory.NewJSONLStore(dir)
+	require.NoError(t, err)
+	history, err := store.GetHistory(ctx, "telegram:1")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "from json", history[0].Content)
+
+	assert.Error(t, sessionsMigrateCmd(ctx, &out, dir, config.SessionStoreJSON))
+}
@@ -0,0 +1,93 @@
+package sessions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/history"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+type searchOptions struct {
+	agentID    string
+	sessionKey string
+	channel    string
+	sender     string
+	since      string
+	until      string
+	limit      int
+	jsonOutput bool
+}
+
+func newSearchCommand(workspace func() string) *cobra.Command {
+	var opts searchOptions
+
+	cmd := &cobra.Command{
+		Use:   "search [query]",
+		Short: "Full-text search across conversation history",
+		Args:  cobra.ArbitraryArgs,
+		Example: `  picoclaw sessions search "deploy script"
+  picoclaw sessions search invoice --channel telegram --since 7d
+  picoclaw sessions search --sender 123456 --since 2026-03-01 --until 2026-03-31`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sessionsSearchCmd(cmd.OutOrStdout(), workspace(), strings.Join(args, " "), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.agentID, "agent", "", "Only search messages of this agent ID")
+	cmd.Flags().StringVar(&opts.sessionKey, "session", "", "Only search this session key")
+	cmd.Flags().StringVar(&opts.channel, "channel", "", "Only search messages from this channel")
+	cmd.Flags().StringVar(&opts.sender, "sender", "", "Only search conversations with this sender ID")
+	cmd.Flags().StringVar(&opts.since, "since", "", "Only messages since a date (2006-01-02), RFC 3339 time or duration ago (24h, 7d)")
+	cmd.Flags().StringVar(&opts.until, "until", "", "Only messages before a date (inclusive), RFC 3339 time or duration ago")
+	cmd.Flags().IntVar(&opts.limit, "limit", 10, "Maximum number of results")
+	cmd.Flags().BoolVar(&opts.jsonOutput, "json", false, "Print results as JSON")
+
+	return cmd
+}
+
+func sessionsSearchCmd(out io.Writer, workspace, query string, opts searchOptions) error {
+	since, until, err := history.ParseRange(opts.since, opts.until, time.Now())
+	if err != nil {
+		return err
+	}
+
+	results, err := history.NewIndex(workspace).Search(query, history.Filter{
+		AgentID:    opts.agentID,
+		SessionKey: opts.sessionKey,
+		Channel:    opts.channel,
+		SenderID:   opts.sender,
+		Since:      since,
+		Until:      until,
+	}, opts.limit)
+	if err != nil {
+		return err
+	}
+
+	if opts.jsonOutput {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No matching messages.")
+		return nil
+	}
+	for _, r := range results {
+		sender := ""
+		if r.SenderID != "" {
+			sender = " " + r.SenderID
+		}
+		fmt.Fprintf(out, "%s  %s  %s:%s%s  [%s]\n",
+			r.Time.Local().Format("2006-01-02 15:04"), r.AgentID, r.Channel, r.ChatID, sender, r.Role)
+		fmt.Fprintf(out, "  session: %s\n", r.SessionKey)
+		fmt.Fprintf(out, "  %s\n\n", utils.Truncate(strings.ReplaceAll(r.Content, "\n", " "), 200))
+	}
+	return nil
+}
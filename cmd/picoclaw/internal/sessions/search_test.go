@@ -0,0 +1,45 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/history"
+)
+
+func TestSessionsSearchCmd(t *testing.T) {
+	workspace := t.TempDir()
+	idx := history.NewIndex(workspace)
+	now := time.Now()
+	require.NoError(t, idx.Append(
+		history.Entry{Time: now.Add(-48 * time.Hour), AgentID: "main", SessionKey: "s1", Channel: "telegram", SenderID: "42", Role: "user", Content: "please renew the TLS certificate"},
+		history.Entry{Time: now.Add(-time.Hour), AgentID: "main", SessionKey: "s2", Channel: "discord", SenderID: "7", Role: "user", Content: "where is the certificate stored?"},
+		history.Entry{Time: now, AgentID: "main", SessionKey: "s2", Channel: "discord", Role: "assistant", Content: "unrelated answer"},
+	))
+
+	var out bytes.Buffer
+	require.NoError(t, sessionsSearchCmd(&out, workspace, "certificate", searchOptions{limit: 10}))
+	assert.Contains(t, out.String(), "renew the TLS certificate")
+	assert.Contains(t, out.String(), "where is the certificate stored?")
+	assert.NotContains(t, out.String(), "unrelated answer")
+
+	out.Reset()
+	require.NoError(t, sessionsSearchCmd(&out, workspace, "certificate", searchOptions{
+		channel: "telegram", limit: 10, jsonOutput: true,
+	}))
+	var results []history.Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "s1", results[0].SessionKey)
+
+	out.Reset()
+	require.NoError(t, sessionsSearchCmd(&out, workspace, "certificate", searchOptions{since: "1d", limit: 10}))
+	assert.NotContains(t, out.String(), "renew the TLS certificate")
+
+	assert.Error(t, sessionsSearchCmd(&out, workspace, "x", searchOptions{since: "yesterday"}))
+}
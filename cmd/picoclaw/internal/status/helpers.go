@@ -1,11 +1,13 @@
 package status
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/outbox"
 )
 
 func statusCmd() {
@@ -38,6 +40,16 @@ func statusCmd() {
 		fmt.Println("Workspace:", workspace, "✗")
 	}
 
+	outboxPath := outbox.DefaultPath(workspace)
+	if _, err := os.Stat(outboxPath); err == nil {
+		if store, err := outbox.Open(outboxPath); err == nil {
+			if stats, err := store.Stats(context.Background()); err == nil {
+				fmt.Printf("Outbox: %d pending, %d dead\n", stats.Pending, stats.Dead)
+			}
+			store.Close()
+		}
+	}
+
 	if _, err := os.Stat(configPath); err == nil {
 		fmt.Printf("Model: %s\n", cfg.Agents.Defaults.GetModelName())
 
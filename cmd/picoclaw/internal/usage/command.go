@@ -0,0 +1,45 @@
+package usage
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+)
+
+type usageOptions struct {
+	agentID    string
+	sessionKey string
+	channel    string
+	model      string
+	since      string
+	jsonOutput bool
+}
+
+func NewUsageCommand() *cobra.Command {
+	var opts usageOptions
+
+	cmd := &cobra.Command{
+		Use:     "usage",
+		Aliases: []string{"u"},
+		Short:   "Show token usage and cost",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := internal.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			return usageCmd(cmd.OutOrStdout(), cfg.WorkspacePath(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.agentID, "agent", "", "Only include usage of this agent ID")
+	cmd.Flags().StringVar(&opts.sessionKey, "session", "", "Only include usage of this session key")
+	cmd.Flags().StringVar(&opts.channel, "channel", "", "Only include usage from this channel")
+	cmd.Flags().StringVar(&opts.model, "model", "", "Only include usage of this model")
+	cmd.Flags().StringVar(&opts.since, "since", "", "Only include usage since a date (2006-01-02) or duration ago (24h, 7d)")
+	cmd.Flags().BoolVar(&opts.jsonOutput, "json", false, "Print the summary as JSON")
+
+	return cmd
+}
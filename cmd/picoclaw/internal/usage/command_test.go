@@ -0,0 +1,66 @@
+package usage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+func TestNewUsageCommand(t *testing.T) {
+	cmd := NewUsageCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "usage", cmd.Use)
+	assert.Equal(t, "Show token usage and cost", cmd.Short)
+	assert.True(t, cmd.HasAlias("u"))
+
+	assert.True(t, cmd.HasFlags())
+	for _, name := range []string{"agent", "session", "channel", "model", "since", "json"} {
+		assert.NotNil(t, cmd.Flags().Lookup(name), "missing flag %q", name)
+	}
+
+	assert.Nil(t, cmd.Run)
+	assert.NotNil(t, cmd.RunE)
+	assert.False(t, cmd.HasSubCommands())
+}
+
+func TestUsageCmd_PrintsFilteredSummary(t *testing.T) {
+	workspace := t.TempDir()
+	ledger := usage.NewLedger(workspace, nil)
+	require.NoError(t, ledger.Append(usage.Record{AgentID: "main", Channel: "telegram", Model: "gpt4", TotalTokens: 40}))
+	require.NoError(t, ledger.Append(usage.Record{AgentID: "helper", Channel: "slack", Model: "gpt4", TotalTokens: 2}))
+
+	var out bytes.Buffer
+	require.NoError(t, usageCmd(&out, workspace, usageOptions{agentID: "main"}))
+
+	assert.Contains(t, out.String(), "Total: 1 requests, 40 tokens")
+	assert.Contains(t, out.String(), "telegram:")
+	assert.NotContains(t, out.String(), "helper")
+}
+
+func TestUsageCmd_EmptyLedger(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, usageCmd(&out, t.TempDir(), usageOptions{}))
+	assert.Equal(t, "No usage recorded.\n", out.String())
+}
+
+func TestUsageCmd_Since(t *testing.T) {
+	workspace := t.TempDir()
+	ledger := usage.NewLedger(workspace, nil)
+	require.NoError(t, ledger.Append(usage.Record{
+		Time: time.Now().AddDate(0, 0, -10), AgentID: "main", Model: "gpt4", TotalTokens: 40,
+	}))
+	require.NoError(t, ledger.Append(usage.Record{Time: time.Now(), AgentID: "main", Model: "gpt4", TotalTokens: 2}))
+
+	var out bytes.Buffer
+	require.NoError(t, usageCmd(&out, workspace, usageOptions{since: "7d"}))
+	assert.Contains(t, out.String(), "Total: 1 requests, 2 tokens")
+
+	assert.Error(t, usageCmd(&out, workspace, usageOptions{since: "yesterday"}))
+}
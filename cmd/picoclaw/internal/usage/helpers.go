@@ -0,0 +1,62 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/history"
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+func usageCmd(out io.Writer, workspace string, opts usageOptions) error {
+	filter := usage.Filter{
+		AgentID:    opts.agentID,
+		SessionKey: opts.sessionKey,
+		Channel:    opts.channel,
+		Model:      opts.model,
+	}
+	if opts.since != "" {
+		since, _, err := history.ParseRange(opts.since, "", time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Since = since
+	}
+
+	summary, err := usage.NewLedger(workspace, nil).Summarize(filter)
+	if err != nil {
+		return err
+	}
+
+	if opts.jsonOutput {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summary)
+	}
+
+	if summary.Total.Requests == 0 {
+		fmt.Fprintln(out, "No usage recorded.")
+		return nil
+	}
+
+	fmt.Fprintln(out, "\nToken Usage:")
+	fmt.Fprintln(out, "------------")
+	fmt.Fprintf(out, "  Total: %s\n", summary.Total)
+	printGroup(out, "model", summary.ByModel)
+	printGroup(out, "agent", summary.ByAgent)
+	printGroup(out, "channel", summary.ByChannel)
+	printGroup(out, "session", summary.BySession)
+	return nil
+}
+
+func printGroup(out io.Writer, name string, group map[string]usage.Totals) {
+	if len(group) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n  By %s:\n", name)
+	for _, key := range usage.SortedKeys(group) {
+		fmt.Fprintf(out, "    %s: %s\n", key, group[key])
+	}
+}
@@ -39,9 +39,13 @@ func TestNewPicoclawCommand(t *testing.T) {
 		"gateway",
 		"mcp-feishu-doc",
 		"migrate",
+		"models",
 		"onboard",
+		"outbox",
+		"sessions",
 		"skills",
 		"status",
+		"usage",
 		"version",
 	}
 
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/commands"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// setupApprovals installs the tool approval policy on every agent and starts
+// intercepting /approve and /deny on the bus. The agent loop handles one
+// message at a time and is blocked while a tool waits for approval, so the
+// replies have to be picked up before they are queued. Messages from other
+// chats stay queued until the approval is resolved or times out.
+func (al *AgentLoop) setupApprovals() {
+	cfg := al.cfg.Tools.Approval
+	if !cfg.Enabled {
+		return
+	}
+
+	al.approvals = tools.NewApprovalManager(time.Duration(cfg.TimeoutSeconds)*time.Second, al.publishApprovalPrompt)
+	for _, agentID := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(agentID)
+		if !ok {
+			continue
+		}
+		policy, err := tools.NewApprovalPolicy(cfg.Rules, agent.Workspace)
+		if err != nil {
+			logger.ErrorCF("agent", "Invalid tool approval rules skipped",
+				map[string]any{"agent_id": agentID, "error": err.Error()})
+		}
+		agent.Tools.SetApproval(policy, al.approvals)
+	}
+
+	var approvalDefs []commands.Definition
+	for _, def := range commands.BuiltinDefinitions() {
+		if def.Name == "approve" || def.Name == "deny" {
+			approvalDefs = append(approvalDefs, def)
+		}
+	}
+	approvalCommands := commands.NewRegistry(approvalDefs)
+
+	al.bus.SetInboundInterceptor(func(msg bus.InboundMessage) bool {
+		if !commands.HasCommandPrefix(msg.Content) {
+			return false
+		}
+		rt := &commands.Runtime{
+			ResolveApproval: func(id string, approved bool) (string, error) {
+				return al.resolveApproval(msg.Channel, msg.AccountID, msg.ChatID, msg.SenderID, id, approved)
+			},
+		}
+		result := commands.NewExecutor(approvalCommands, rt).Execute(context.Background(), commands.Request{
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			SenderID: msg.SenderID,
+			Text:     msg.Content,
+			Reply: func(text string) error {
+				return al.publishApprovalReply(msg.Channel, msg.AccountID, msg.ChatID, text)
+			},
+		})
+		return result.Outcome == commands.OutcomeHandled
+	})
+}
+
+func (al *AgentLoop) resolveApproval(channel, accountID, chatID, senderID, id string, approved bool) (string, error) {
+	if al.approvals == nil {
+		return "", fmt.Errorf("tool approval is not enabled")
+	}
+	req, err := al.approvals.Resolve(id, channel, accountID, chatID, senderID, approved)
+	if err != nil {
+		return "", err
+	}
+	return req.Tool, nil
+}
+
+// publishApprovalPrompt asks the originating chat to approve a tool call.
+// Without a channel manager (e.g. "picoclaw agent") nobody would see the
+// prompt, so the call is refused straight away instead of timing out.
+func (al *AgentLoop) publishApprovalPrompt(ctx context.Context, req tools.ApprovalRequest) error {
+	if al.channelManager == nil {
+		return fmt.Errorf("no channel available to ask for approval")
+	}
+	msg := bus.OutboundMessage{
+		Channel:   req.Channel,
+		AccountID: req.AccountID,
+		ChatID:    req.ChatID,
+		Content:   req.Prompt(),
+	}
+	// Buttons are only attached where they render natively. The text
+	// fallback asks for a numbered reply, which is not a command and would
+	// queue behind the blocked agent loop instead of resolving the request.
+	if ch, ok := al.channelManager.GetChannel(channels.ChannelKey(req.Channel, req.AccountID)); ok {
+		if _, ok := ch.(channels.InteractiveCapable); ok {
+			msg.Interactive = approvalButtons(req.ID)
+		}
+	}
+	return al.bus.PublishOutbound(ctx, msg)
+}
+
+// approvalButtons returns Approve and Deny buttons that send /approve <id>
+// and /deny <id> back when clicked.
+func approvalButtons(id string) *bus.Interactive {
+	return &bus.Interactive{
+		Buttons: []bus.Button{
+			{Label: "Approve", Value: "/approve " + id, Style: "primary"},
+			{Label: "Deny", Value: "/deny " + id, Style: "danger"},
+		},
+	}
+}
+
+func (al *AgentLoop) publishApprovalReply(channel, accountID, chatID, text string) error {
+	pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer pubCancel()
+	return al.bus.PublishOutbound(pubCtx, bus.OutboundMessage{
+		Channel:   channel,
+		AccountID: accountID,
+		ChatID:    chatID,
+		Content:   text,
+	})
+}
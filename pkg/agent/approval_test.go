@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func newApprovalTestLoop(t *testing.T) (*AgentLoop, *bus.MessageBus, *captureArgsTool) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{
+			Approval: config.ApprovalConfig{
+				Enabled:        true,
+				TimeoutSeconds: 5,
+				Rules:          []config.ApprovalRule{{Tool: "danger"}},
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	t.Cleanup(msgBus.Close)
+
+	al := NewAgentLoop(cfg, msgBus, &toolCallEchoProvider{toolName: "danger", toolArgs: map[string]any{"x": 1}})
+	chManager, err := channels.NewManager(&config.Config{}, msgBus, nil)
+	if err != nil {
+		t.Fatalf("Failed to create channel manager: %v", err)
+	}
+	al.SetChannelManager(chManager)
+
+	tool := &captureArgsTool{name: "danger", result: tools.NewToolResult("danger done")}
+	al.RegisterTool(tool)
+	return al, msgBus, tool
+}
+
+// runWithApproval processes one message, answers the approval prompt with
+// reply and returns the agent's response and the acknowledgement.
+func runWithApproval(t *testing.T, al *AgentLoop, msgBus *bus.MessageBus, reply string) (string, string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg := bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "do something dangerous",
+		Peer:     bus.Peer{Kind: "direct", ID: "user1"},
+	}
+	type outcome struct {
+		response string
+		err      error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		response, err := al.processMessage(ctx, msg)
+		done <- outcome{response, err}
+	}()
+
+	prompt, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || !strings.Contains(prompt.Content, "Approval required for tool danger") {
+		t.Fatalf("expected approval prompt, got %+v", prompt)
+	}
+	id := strings.Fields(prompt.Content[strings.Index(prompt.Content, "/approve "):])[1]
+
+	if err := msgBus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  reply + " " + id,
+	}); err != nil {
+		t.Fatalf("PublishInbound failed: %v", err)
+	}
+	ack, _ := msgBus.SubscribeOutbound(ctx)
+
+	out := <-done
+	if out.err != nil {
+		t.Fatalf("processMessage failed: %v", out.err)
+	}
+	return out.response, ack.Content
+}
+
+func TestApproval_ApproveRunsTool(t *testing.T) {
+	al, msgBus, tool := newApprovalTestLoop(t)
+
+	response, ack := runWithApproval(t, al, msgBus, "/approve")
+	if !strings.HasPrefix(ack, "Approved danger") {
+		t.Fatalf("unexpected acknowledgement: %q", ack)
+	}
+	if response != "danger done" || len(tool.Calls()) != 1 {
+		t.Fatalf("response=%q calls=%d, want tool to run once", response, len(tool.Calls()))
+	}
+}
+
+func TestApproval_DenyBlocksTool(t *testing.T) {
+	al, msgBus, tool := newApprovalTestLoop(t)
+
+	response, ack := runWithApproval(t, al, msgBus, "/deny")
+	if !strings.HasPrefix(ack, "Denied danger") {
+		t.Fatalf("unexpected acknowledgement: %q", ack)
+	}
+	if !strings.Contains(response, "denied") || len(tool.Calls()) != 0 {
+		t.Fatalf("response=%q calls=%d, want tool to be blocked", response, len(tool.Calls()))
+	}
+}
+
+type interactiveFakeChannel struct{ fakeChannel }
+
+func (c *interactiveFakeChannel) SendInteractive(context.Context, bus.OutboundMessage) error {
+	return nil
+}
+
+func TestApproval_ButtonsSendCommands(t *testing.T) {
+	al, msgBus, tool := newApprovalTestLoop(t)
+	al.channelManager.RegisterChannel("telegram", &interactiveFakeChannel{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg := bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "do something dangerous",
+		Peer:     bus.Peer{Kind: "direct", ID: "user1"},
+	}
+	done := make(chan string, 1)
+	go func() {
+		response, _ := al.processMessage(ctx, msg)
+		done <- response
+	}()
+
+	prompt, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || prompt.Interactive == nil || len(prompt.Interactive.Buttons) != 2 {
+		t.Fatalf("expected approval prompt with buttons, got %+v", prompt)
+	}
+	approve, deny := prompt.Interactive.Buttons[0], prompt.Interactive.Buttons[1]
+	if !strings.HasPrefix(approve.Value, "/approve ") || !strings.HasPrefix(deny.Value, "/deny ") ||
+		strings.TrimPrefix(approve.Value, "/approve ") != strings.TrimPrefix(deny.Value, "/deny ") {
+		t.Fatalf("buttons = %+v, want /approve and /deny for the same id", prompt.Interactive.Buttons)
+	}
+
+	// A click arrives as an inbound message carrying the button's value.
+	if err := msgBus.PublishInbound(ctx, bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  approve.CallbackValue(),
+	}); err != nil {
+		t.Fatalf("PublishInbound failed: %v", err)
+	}
+	if ack, _ := msgBus.SubscribeOutbound(ctx); !strings.HasPrefix(ack.Content, "Approved danger") {
+		t.Fatalf("unexpected acknowledgement: %q", ack.Content)
+	}
+	if response := <-done; response != "danger done" || len(tool.Calls()) != 1 {
+		t.Fatalf("response=%q calls=%d, want tool to run once", response, len(tool.Calls()))
+	}
+}
+
+func TestApproval_NamedAccount(t *testing.T) {
+	al, msgBus, tool := newApprovalTestLoop(t)
+	al.channelManager.RegisterChannel("telegram", &fakeChannel{})
+	al.channelManager.RegisterChannel(channels.ChannelKey("telegram", "work"), &interactiveFakeChannel{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg := bus.InboundMessage{
+		Channel:   "telegram",
+		AccountID: "work",
+		SenderID:  "user1",
+		ChatID:    "chat1",
+		Content:   "do something dangerous",
+		Peer:      bus.Peer{Kind: "direct", ID: "user1"},
+	}
+	done := make(chan string, 1)
+	go func() {
+		response, _ := al.processMessage(ctx, msg)
+		done <- response
+	}()
+
+	prompt, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || prompt.AccountID != "work" || prompt.Interactive == nil {
+		t.Fatalf("expected approval prompt with buttons on the work account, got %+v", prompt)
+	}
+	approve := prompt.Interactive.Buttons[0].CallbackValue()
+
+	// The same chat ID on the default bot account cannot resolve it.
+	click := bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: approve}
+	if err := msgBus.PublishInbound(ctx, click); err != nil {
+		t.Fatalf("PublishInbound failed: %v", err)
+	}
+	if ack, _ := msgBus.SubscribeOutbound(ctx); strings.HasPrefix(ack.Content, "Approved") || ack.AccountID != "" {
+		t.Fatalf("approval resolved from another account: %+v", ack)
+	}
+
+	click.AccountID = "work"
+	if err := msgBus.PublishInbound(ctx, click); err != nil {
+		t.Fatalf("PublishInbound failed: %v", err)
+	}
+	if ack, _ := msgBus.SubscribeOutbound(ctx); !strings.HasPrefix(ack.Content, "Approved danger") || ack.AccountID != "work" {
+		t.Fatalf("unexpected acknowledgement: %+v", ack)
+	}
+	if response := <-done; response != "danger done" || len(tool.Calls()) != 1 {
+		t.Fatalf("response=%q calls=%d, want tool to run once", response, len(tool.Calls()))
+	}
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sipeed/picoclaw/pkg/commands"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/routing"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// resolveBranchKey maps a chat's scope key to the session key of the branch
+// selected with /sessions switch (or created with /branch).
+func (al *AgentLoop) resolveBranchKey(scopeKey string) string {
+	if al.state == nil {
+		return scopeKey
+	}
+	return routing.BuildBranchSessionKey(scopeKey, al.state.GetActiveBranch(scopeKey))
+}
+
+// rewindTurns drops the last turns user turns from history. A turn starts at a
+// user message and includes every assistant and tool message after it. It
+// returns the shortened history and the number of turns actually removed.
+func rewindTurns(history []providers.Message, turns int) ([]providers.Message, int) {
+	cut, removed := len(history), 0
+	for i := len(history) - 1; i >= 0 && removed < turns; i-- {
+		if history[i].Role == "user" {
+			cut = i
+			removed++
+		}
+	}
+	return history[:cut], removed
+}
+
+func (al *AgentLoop) rewindSession(agent *AgentInstance, sessionKey string, turns int) (int, error) {
+	if agent.Sessions == nil {
+		return 0, fmt.Errorf("sessions not initialized for agent")
+	}
+	history, removed := rewindTurns(agent.Sessions.GetHistory(sessionKey), turns)
+	if removed == 0 {
+		return 0, nil
+	}
+	agent.Sessions.SetHistory(sessionKey, history)
+	if err := agent.Sessions.Save(sessionKey); err != nil {
+		return removed, err
+	}
+	if al.history != nil {
+		if _, err := al.history.RewindSession(agent.ID, sessionKey, removed); err != nil {
+			return removed, fmt.Errorf("failed to rewind message history: %w", err)
+		}
+	}
+	return removed, nil
+}
+
+// branchSession copies the session at sessionKey into a new named branch of
+// the same chat and makes it the active one.
+func (al *AgentLoop) branchSession(agent *AgentInstance, sessionKey, name string) (string, error) {
+	if agent.Sessions == nil {
+		return "", fmt.Errorf("sessions not initialized for agent")
+	}
+	baseKey, _ := routing.ParseBranchSessionKey(sessionKey)
+	branch := routing.NormalizeBranchName(name)
+	if branch == routing.DefaultBranchName {
+		return "", fmt.Errorf("%q is reserved for the original session", routing.DefaultBranchName)
+	}
+	if slices.Contains(al.state.GetBranches(baseKey), branch) {
+		return "", fmt.Errorf("branch %s already exists", branch)
+	}
+
+	err := session.ImportTranscript(agent.Sessions, session.Transcript{
+		Key:      routing.BuildBranchSessionKey(baseKey, branch),
+		Summary:  agent.Sessions.GetSummary(sessionKey),
+		Messages: agent.Sessions.GetHistory(sessionKey),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := al.state.AddBranch(baseKey, branch); err != nil {
+		return "", err
+	}
+	return branch, al.state.SetActiveBranch(baseKey, branch)
+}
+
+func (al *AgentLoop) listSessionBranches(agent *AgentInstance, sessionKey string) []commands.SessionBranch {
+	baseKey, active := routing.ParseBranchSessionKey(sessionKey)
+	names := append([]string{routing.DefaultBranchName}, al.state.GetBranches(baseKey)...)
+
+	branches := make([]commands.SessionBranch, 0, len(names))
+	for _, name := range names {
+		branch := commands.SessionBranch{Name: name, Active: name == active}
+		if agent.Sessions != nil {
+			branch.Messages = len(agent.Sessions.GetHistory(routing.BuildBranchSessionKey(baseKey, name)))
+		}
+		branches = append(branches, branch)
+	}
+	return branches
+}
+
+func (al *AgentLoop) switchSessionBranch(sessionKey, name string) (string, error) {
+	baseKey, _ := routing.ParseBranchSessionKey(sessionKey)
+	branch := routing.NormalizeBranchName(name)
+	if branch == routing.DefaultBranchName {
+		return branch, al.state.SetActiveBranch(baseKey, "")
+	}
+	if !slices.Contains(al.state.GetBranches(baseKey), branch) {
+		return "", fmt.Errorf("no branch named %s; use /branch %s to create it", branch, branch)
+	}
+	return branch, al.state.SetActiveBranch(baseKey, branch)
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/history"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestRewindTurns(t *testing.T) {
+	history := []providers.Message{
+		{Role: "user", Content: "one"},
+		{Role: "assistant", Content: "1"},
+		{Role: "user", Content: "two"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1"}}},
+		{Role: "tool", ToolCallID: "c1"},
+		{Role: "assistant", Content: "2"},
+	}
+
+	got, removed := rewindTurns(history, 1)
+	if removed != 1 || len(got) != 2 || got[1].Content != "1" {
+		t.Fatalf("rewindTurns(1) = %d, %+v", removed, got)
+	}
+	got, removed = rewindTurns(history, 5)
+	if removed != 2 || len(got) != 0 {
+		t.Fatalf("rewindTurns(5) = %d, %+v", removed, got)
+	}
+	got, removed = rewindTurns(nil, 1)
+	if removed != 0 || len(got) != 0 {
+		t.Fatalf("rewindTurns(nil) = %d, %+v", removed, got)
+	}
+}
+
+func TestProcessMessage_BranchRewindSessions(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Session: config.SessionConfig{
+			DMScope: "per-channel-peer",
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &countingMockProvider{response: "LLM reply"})
+	agent := al.registry.GetDefaultAgent()
+	helper := testHelper{al: al}
+
+	send := func(content string) string {
+		return helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+			Channel:  "whatsapp",
+			SenderID: "user1",
+			ChatID:   "chat1",
+			Content:  content,
+			Peer:     bus.Peer{Kind: "direct", ID: "user1"},
+		})
+	}
+	const mainKey = "agent:main:whatsapp:direct:user1"
+	const ideaKey = mainKey + ":branch:idea"
+	historyLen := func(key string) int { return len(agent.Sessions.GetHistory(key)) }
+
+	send("hello")
+	if got := historyLen(mainKey); got != 2 {
+		t.Fatalf("main history = %d messages, want 2", got)
+	}
+
+	if reply := send("/branch Idea"); !strings.HasPrefix(reply, "Created branch idea") {
+		t.Fatalf("unexpected /branch reply: %q", reply)
+	}
+	if reply := send("/branch idea"); !strings.Contains(reply, "already exists") {
+		t.Fatalf("unexpected duplicate /branch reply: %q", reply)
+	}
+
+	send("tell me more")
+	if historyLen(mainKey) != 2 || historyLen(ideaKey) != 4 {
+		t.Fatalf("history main=%d idea=%d, want 2 and 4", historyLen(mainKey), historyLen(ideaKey))
+	}
+
+	reply := send("/sessions")
+	for _, want := range []string{"  main (2 messages)", "* idea (4 messages)"} {
+		if !strings.Contains(reply, want) {
+			t.Fatalf("/sessions reply missing %q: %q", want, reply)
+		}
+	}
+
+	if reply := send("/rewind"); reply != "Rewound 1 turn." {
+		t.Fatalf("unexpected /rewind reply: %q", reply)
+	}
+	if got := historyLen(ideaKey); got != 2 {
+		t.Fatalf("idea history after rewind = %d messages, want 2", got)
+	}
+	if results, _ := al.history.Search("tell me more", history.Filter{SessionKey: ideaKey}, 10); len(results) != 0 {
+		t.Fatalf("rewound turn is still searchable: %+v", results)
+	}
+
+	if reply := send("/sessions switch main"); reply != "Switched to branch main" {
+		t.Fatalf("unexpected /sessions switch reply: %q", reply)
+	}
+	send("back on main")
+	if historyLen(mainKey) != 4 || historyLen(ideaKey) != 2 {
+		t.Fatalf("history main=%d idea=%d, want 4 and 2", historyLen(mainKey), historyLen(ideaKey))
+	}
+}
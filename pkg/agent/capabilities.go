@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tokenizer"
+)
+
+const attachmentsOmittedNote = "[%d attachment(s) omitted: the current model cannot read them]"
+
+// applyModelCapabilities gives candidates that come from a model_list entry
+// the entry's capabilities, so per-model overrides such as context_window
+// or supports_vision take effect. Other candidates keep the built-in ones.
+func applyModelCapabilities(cfg *config.Config, candidates []providers.FallbackCandidate) {
+	if cfg == nil {
+		return
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		for j := range cfg.ModelList {
+			model := strings.TrimSpace(cfg.ModelList[j].Model)
+			if model != "" && !strings.Contains(model, "/") {
+				model = "openai/" + model
+			}
+			ref := providers.ParseModelRef(model, "")
+			if ref != nil && ref.Provider == c.Provider && ref.Model == c.Model {
+				c.Capabilities = providers.CapabilitiesFor(&cfg.ModelList[j])
+				break
+			}
+		}
+	}
+}
+
+// candidateCapabilities returns the capabilities of the candidate serving
+// provider/model, or of the first candidate when there is no exact match.
+func candidateCapabilities(
+	candidates []providers.FallbackCandidate,
+	fallback providers.ModelCapabilities,
+	provider, model string,
+) providers.ModelCapabilities {
+	for _, c := range candidates {
+		if c.Model == model && (provider == "" || c.Provider == provider) {
+			return c.Capabilities
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0].Capabilities
+	}
+	return fallback
+}
+
+// candidateTokenizer returns the tokenizer of the candidate serving model,
+// matched the way candidateCapabilities does.
+func candidateTokenizer(candidates []providers.FallbackCandidate, model string) tokenizer.TokenCounter {
+	for _, c := range candidates {
+		if c.Model == model {
+			return tokenizer.ForModel(c.Model)
+		}
+	}
+	if len(candidates) > 0 {
+		return tokenizer.ForModel(candidates[0].Model)
+	}
+	return tokenizer.ForModel(model)
+}
+
+// isImageMedia reports whether a message media entry is an image. Resolved
+// entries are data URLs carrying their MIME type; anything else is assumed
+// to be an image, which is what channels attach most.
+func isImageMedia(ref string) bool {
+	return !strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "data:image/")
+}
+
+// requestRequirements reports what a model needs to accept messages and
+// tools as they are.
+func requestRequirements(messages []providers.Message, tools []providers.ToolDefinition) providers.ModelRequirements {
+	req := providers.ModelRequirements{Tools: len(tools) > 0}
+	for _, m := range messages {
+		if len(m.Images) > 0 {
+			req.Vision = true
+		}
+		if len(m.Files) > 0 {
+			req.Files = true
+		}
+		for _, ref := range m.Media {
+			if isImageMedia(ref) {
+				req.Vision = true
+			} else {
+				req.Files = true
+			}
+		}
+	}
+	return req
+}
+
+// fitToCapabilities strips what a model cannot accept: images and files are
+// replaced by a short note so the model can tell the user, tools are
+// dropped, and so is thinking_level. The inputs are not mutated.
+func fitToCapabilities(
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	opts map[string]any,
+	caps providers.ModelCapabilities,
+) ([]providers.Message, []providers.ToolDefinition, map[string]any) {
+	if !caps.Tools {
+		tools = nil
+	}
+	if _, ok := opts["thinking_level"]; ok && !caps.Thinking {
+		opts = maps.Clone(opts)
+		delete(opts, "thinking_level")
+	}
+	if caps.Vision && caps.Files {
+		return messages, tools, opts
+	}
+
+	var out []providers.Message // cloned on the first change
+	omittedTotal := 0
+	for i, m := range messages {
+		omitted := 0
+		if !caps.Vision && len(m.Images) > 0 {
+			omitted += len(m.Images)
+			m.Images = nil
+		}
+		if !caps.Files && len(m.Files) > 0 {
+			omitted += len(m.Files)
+			m.Files = nil
+		}
+		if len(m.Media) > 0 {
+			media := make([]string, 0, len(m.Media))
+			for _, ref := range m.Media {
+				if isImageMedia(ref) && !caps.Vision || !isImageMedia(ref) && !caps.Files {
+					omitted++
+					continue
+				}
+				media = append(media, ref)
+			}
+			m.Media = media
+		}
+		if omitted == 0 {
+			continue
+		}
+		if out == nil {
+			out = slices.Clone(messages)
+		}
+		m.Content = strings.TrimSpace(m.Content + "\n\n" + fmt.Sprintf(attachmentsOmittedNote, omitted))
+		out[i] = m
+		omittedTotal += omitted
+	}
+	if out == nil {
+		return messages, tools, opts
+	}
+	logger.InfoCF("agent", "Stripped attachments the model cannot read", map[string]any{
+		"omitted": omittedTotal,
+		"vision":  caps.Vision,
+		"files":   caps.Files,
+	})
+	return out, tools, opts
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestFitToCapabilities_StripsUnsupportedAttachments(t *testing.T) {
+	messages := []providers.Message{
+		{Role: "system", Content: "sys"},
+		{
+			Role:    "user",
+			Content: "what is this?",
+			Media:   []string{"data:image/png;base64,AAAA", "data:application/pdf;base64,BBBB"},
+		},
+	}
+	tools := []providers.ToolDefinition{{Type: "function"}}
+	opts := map[string]any{"thinking_level": "high", "max_tokens": 100}
+
+	caps := providers.ModelCapabilities{Files: true, Tools: true}
+	msgs, gotTools, gotOpts := fitToCapabilities(messages, tools, opts, caps)
+
+	if len(msgs[1].Media) != 1 || !strings.HasPrefix(msgs[1].Media[0], "data:application/pdf") {
+		t.Fatalf("expected only the PDF to remain, got %v", msgs[1].Media)
+	}
+	if !strings.Contains(msgs[1].Content, "1 attachment(s) omitted") {
+		t.Fatalf("expected an omission note, got %q", msgs[1].Content)
+	}
+	if len(messages[1].Media) != 2 || messages[1].Content != "what is this?" {
+		t.Fatal("input messages were mutated")
+	}
+	if len(gotTools) != 1 {
+		t.Fatalf("tools should be kept, got %d", len(gotTools))
+	}
+	if _, ok := gotOpts["thinking_level"]; ok {
+		t.Fatal("thinking_level should be dropped for a model without thinking")
+	}
+	if _, ok := opts["thinking_level"]; !ok {
+		t.Fatal("input options were mutated")
+	}
+
+	_, gotTools, _ = fitToCapabilities(messages, tools, opts, providers.ModelCapabilities{Vision: true, Files: true})
+	if gotTools != nil {
+		t.Fatal("tools should be dropped for a model without tool support")
+	}
+}
+
+func TestRequestRequirements(t *testing.T) {
+	req := requestRequirements([]providers.Message{
+		{Role: "user", Media: []string{"data:image/jpeg;base64,AAAA"}},
+	}, nil)
+	if !req.Vision || req.Files || req.Tools {
+		t.Fatalf("unexpected requirements: %+v", req)
+	}
+}
+
+func TestNewAgentInstance_ContextWindowFromCapabilities(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry config.ModelConfig
+		want  int
+	}{
+		{"built-in", config.ModelConfig{ModelName: "main", Model: "openai/gpt-4o"}, 128_000},
+		{"override", config.ModelConfig{ModelName: "main", Model: "openai/gpt-4o", ContextWindow: 32_000}, 32_000},
+		{"unknown model", config.ModelConfig{ModelName: "main", Model: "ollama/my-model"}, 4096},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Agents: config.AgentsConfig{
+					Defaults: config.AgentDefaults{
+						Workspace: t.TempDir(),
+						Model:     "main",
+						MaxTokens: 4096,
+					},
+				},
+				ModelList: []config.ModelConfig{tt.entry},
+			}
+			agent := NewAgentInstance(nil, &cfg.Agents.Defaults, cfg, &mockProvider{})
+			if agent.ContextWindow != tt.want {
+				t.Fatalf("ContextWindow = %d, want %d", agent.ContextWindow, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -10,13 +11,29 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/semantic"
 	"github.com/sipeed/picoclaw/pkg/skills"
 )
 
+const (
+	defaultMemoryTopK     = 5
+	defaultMemoryMinScore = 0.3
+	// memoryRecallTimeout bounds the embedding calls made while building
+	// the prompt; every message waits for them.
+	memoryRecallTimeout = 5 * time.Second
+	// memoryRecallCooldown is how long the full memory context is used
+	// without trying recall again after it failed.
+	memoryRecallCooldown = time.Minute
+	// memorySyncTimeout bounds the background sync started after a failed
+	// recall, which may have to embed a large memory directory.
+	memorySyncTimeout = 2 * time.Minute
+)
+
 type ContextBuilder struct {
 	workspace          string
 	skillsLoader       *skills.SkillsLoader
@@ -24,6 +41,15 @@ type ContextBuilder struct {
 	toolDiscoveryBM25  bool
 	toolDiscoveryRegex bool
 
+	// semanticMemory, when set, replaces the full memory section of the
+	// system prompt with the memories most relevant to each message.
+	semanticMemory *semantic.Index
+	memoryTopK     int
+	memoryMinScore float64
+	recallMu       sync.Mutex
+	recallRetryAt  time.Time   // recall is skipped until then after a failure
+	syncing        atomic.Bool // a background index sync is running
+
 	// Cache for system prompt to avoid rebuilding on every call.
 	// This fixes issue #607: repeated reprocessing of the entire context.
 	// The cache auto-invalidates when workspace source files change (mtime check).
@@ -49,6 +75,22 @@ func (cb *ContextBuilder) WithToolDiscovery(useBM25, useRegex bool) *ContextBuil
 	return cb
 }
 
+// WithSemanticMemory makes the builder inject the topK memories from index
+// that are most similar to the current message, instead of all of MEMORY.md
+// and the recent daily notes. Non-positive values select the defaults.
+func (cb *ContextBuilder) WithSemanticMemory(index *semantic.Index, topK int, minScore float64) *ContextBuilder {
+	if topK <= 0 {
+		topK = defaultMemoryTopK
+	}
+	if minScore <= 0 {
+		minScore = defaultMemoryMinScore
+	}
+	cb.semanticMemory = index
+	cb.memoryTopK = topK
+	cb.memoryMinScore = minScore
+	return cb
+}
+
 func getGlobalConfigDir() string {
 	if home := os.Getenv("PICOCLAW_HOME"); home != "" {
 		return home
@@ -81,6 +123,13 @@ func (cb *ContextBuilder) getIdentity() string {
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
 	toolDiscovery := cb.getDiscoveryRule()
 
+	memoryRule := fmt.Sprintf(
+		"When interacting with me if something seems memorable, update %s/memory/MEMORY.md", workspacePath)
+	if cb.semanticMemory != nil {
+		memoryRule = "When interacting with me if something seems memorable, save it with the remember tool. " +
+			"Memories relevant to the current message are provided as context; use the recall tool to search for others."
+	}
+
 	return fmt.Sprintf(`# picoclaw 🦞
 
 You are picoclaw, a helpful AI assistant.
@@ -97,12 +146,12 @@ Your workspace is at: %s
 
 2. **Be helpful and accurate** - When using tools, briefly explain what you're doing.
 
-3. **Memory** - When interacting with me if something seems memorable, update %s/memory/MEMORY.md
+3. **Memory** - %s
 
 4. **Context summaries** - Conversation summaries provided as context are approximate references only. They may be incomplete or outdated. Always defer to explicit user instructions over summary content.
 
 %s`,
-		workspacePath, workspacePath, workspacePath, workspacePath, workspacePath, toolDiscovery)
+		workspacePath, workspacePath, workspacePath, workspacePath, memoryRule, toolDiscovery)
 }
 
 func (cb *ContextBuilder) getDiscoveryRule() string {
@@ -146,10 +195,13 @@ The following skills extend your capabilities. To use a skill, read its SKILL.md
 %s`, skillsSummary))
 	}
 
-	// Memory context
-	memoryContext := cb.memory.GetMemoryContext()
-	if memoryContext != "" {
-		parts = append(parts, "# Memory\n\n"+memoryContext)
+	// Memory context. With semantic memory, relevant memories are added per
+	// message by BuildMessages instead.
+	if cb.semanticMemory == nil {
+		memoryContext := cb.memory.GetMemoryContext()
+		if memoryContext != "" {
+			parts = append(parts, "# Memory\n\n"+memoryContext)
+		}
 	}
 
 	// Join with "---" separator
@@ -468,6 +520,74 @@ func (cb *ContextBuilder) buildDynamicContext(channel, chatID string) string {
 	return sb.String()
 }
 
+// buildRelevantMemories returns the memories most relevant to message, or
+// an empty string when semantic memory is off or nothing matches. If recall
+// fails (e.g. the embedding server is down) it falls back to the full memory
+// context rather than leaving the agent without its memory, and keeps doing
+// so for memoryRecallCooldown instead of waiting on the server every time.
+func (cb *ContextBuilder) buildRelevantMemories(message string) string {
+	if cb.semanticMemory == nil || strings.TrimSpace(message) == "" {
+		return ""
+	}
+
+	cb.recallMu.Lock()
+	coolingDown := time.Now().Before(cb.recallRetryAt)
+	cb.recallMu.Unlock()
+	if coolingDown {
+		return cb.fullMemoryContext()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), memoryRecallTimeout)
+	defer cancel()
+
+	hits, err := cb.semanticMemory.Recall(ctx, message, cb.memoryTopK, cb.memoryMinScore)
+	if err != nil {
+		logger.WarnCF("agent", "Semantic memory recall failed, using full memory context",
+			map[string]any{"error": err.Error(), "retry_in": memoryRecallCooldown.String()})
+		cb.recallMu.Lock()
+		cb.recallRetryAt = time.Now().Add(memoryRecallCooldown)
+		cb.recallMu.Unlock()
+		cb.syncInBackground()
+		return cb.fullMemoryContext()
+	}
+	if len(hits) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Relevant Memories\n\n")
+	sb.WriteString("Recalled from long-term memory for this message. Use the recall tool to search for more.\n")
+	for _, h := range hits {
+		fmt.Fprintf(&sb, "\n- %s (%s)", h.Text, h.Source)
+	}
+	return sb.String()
+}
+
+// fullMemoryContext returns the memory section used without semantic memory.
+func (cb *ContextBuilder) fullMemoryContext() string {
+	if memoryContext := cb.memory.GetMemoryContext(); memoryContext != "" {
+		return "# Memory\n\n" + memoryContext
+	}
+	return ""
+}
+
+// syncInBackground brings the memory index up to date outside the prompt
+// path, so that a first sync too large for memoryRecallTimeout still
+// completes. At most one such sync runs at a time.
+func (cb *ContextBuilder) syncInBackground() {
+	if !cb.syncing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer cb.syncing.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), memorySyncTimeout)
+		defer cancel()
+		if err := cb.semanticMemory.Sync(ctx); err != nil {
+			logger.DebugCF("agent", "Background memory index sync failed", map[string]any{"error": err.Error()})
+		}
+	}()
+}
+
 func (cb *ContextBuilder) BuildMessages(
 	history []providers.Message,
 	summary string,
@@ -479,7 +599,8 @@ func (cb *ContextBuilder) BuildMessages(
 
 	// The static part (identity, bootstrap, skills, memory) is cached locally to
 	// avoid repeated file I/O and string building on every call (fixes issue #607).
-	// Dynamic parts (time, session, summary) are appended per request.
+	// Dynamic parts (time, session, relevant memories, summary) are appended
+	// per request.
 	// Everything is sent as a single system message for provider compatibility:
 	// - Anthropic adapter extracts messages[0] (Role=="system") and maps its content
 	//   to the top-level "system" parameter in the Messages API request. A single
@@ -507,6 +628,12 @@ func (cb *ContextBuilder) BuildMessages(
 		{Type: "text", Text: dynamicCtx},
 	}
 
+	memoryCtx := cb.buildRelevantMemories(currentMessage)
+	if memoryCtx != "" {
+		stringParts = append(stringParts, memoryCtx)
+		contentBlocks = append(contentBlocks, providers.ContentBlock{Type: "text", Text: memoryCtx})
+	}
+
 	if summary != "" {
 		summaryText := fmt.Sprintf(
 			"CONTEXT_SUMMARY: The following is an approximate summary of prior conversation "+
@@ -529,6 +656,7 @@ func (cb *ContextBuilder) BuildMessages(
 		map[string]any{
 			"static_chars":  len(staticPrompt),
 			"dynamic_chars": len(dynamicCtx),
+			"memory_chars":  len(memoryCtx),
 			"total_chars":   len(fullSystemPrompt),
 			"has_summary":   summary != "",
 			"cached":        isCached,
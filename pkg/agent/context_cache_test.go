@@ -1,14 +1,18 @@
 package agent
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/semantic"
 )
 
 // setupWorkspace creates a temporary workspace with standard directories and optional files.
@@ -667,3 +671,86 @@ func BenchmarkBuildMessagesWithCache(b *testing.B) {
 		_ = cb.BuildMessages(history, "summary", "new message", nil, "cli", "test")
 	}
 }
+
+// topicEmbedder embeds texts as hits against a fixed list of topics.
+type topicEmbedder struct {
+	err   error
+	calls atomic.Int32
+}
+
+func (e *topicEmbedder) Model() string {
+	return "topics"
+}
+
+func (e *topicEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls.Add(1)
+	if e.err != nil {
+		return nil, e.err
+	}
+	topics := []string{"cat", "coffee", "rust"}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := []float32{0.01, 0, 0, 0}
+		for j, topic := range topics {
+			if strings.Contains(strings.ToLower(text), topic) {
+				v[j+1] = 1
+			}
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+// TestSemanticMemoryInjectsRelevantMemories verifies that with semantic
+// memory only the memories relevant to the current message reach the
+// system prompt, and that a failing embedder falls back to the full memory.
+func TestSemanticMemoryInjectsRelevantMemories(t *testing.T) {
+	tmpDir := setupWorkspace(t, map[string]string{
+		"memory/MEMORY.md": "# Memory\n- User's cat is called Miso\n- User drinks coffee black\n",
+	})
+	defer os.RemoveAll(tmpDir)
+
+	embedder := &topicEmbedder{}
+	cb := NewContextBuilder(tmpDir).WithSemanticMemory(semantic.NewIndex(tmpDir, embedder), 3, 0.5)
+
+	if prompt := cb.BuildSystemPrompt(); strings.Contains(prompt, "Miso") || !strings.Contains(prompt, "remember tool") {
+		t.Errorf("static prompt should not contain the full memory:\n%s", prompt)
+	}
+
+	sys := cb.BuildMessages(nil, "", "What's my cat's name?", nil, "test", "chat1")[0]
+	if !strings.Contains(sys.Content, "# Relevant Memories") || !strings.Contains(sys.Content, "User's cat is called Miso (MEMORY.md)") {
+		t.Errorf("relevant memory missing:\n%s", sys.Content)
+	}
+	if strings.Contains(sys.Content, "coffee black") {
+		t.Error("irrelevant memory should not be injected")
+	}
+	if len(sys.SystemParts) != 3 {
+		t.Errorf("expected static, dynamic and memory blocks, got %d", len(sys.SystemParts))
+	}
+
+	sys = cb.BuildMessages(nil, "", "Tell me about rust", nil, "test", "chat1")[0]
+	if strings.Contains(sys.Content, "# Relevant Memories") {
+		t.Error("no memories should be injected without a match")
+	}
+
+	embedder.err = fmt.Errorf("embedding server down")
+	sys = cb.BuildMessages(nil, "", "What's my cat's name?", nil, "test", "chat1")[0]
+	if !strings.Contains(sys.Content, "# Memory") || !strings.Contains(sys.Content, "coffee black") {
+		t.Errorf("expected fallback to full memory:\n%s", sys.Content)
+	}
+
+	// During the cooldown the server is not asked again.
+	calls := embedder.calls.Load()
+	sys = cb.BuildMessages(nil, "", "What's my cat's name?", nil, "test", "chat1")[0]
+	if embedder.calls.Load() != calls || !strings.Contains(sys.Content, "coffee black") {
+		t.Errorf("expected immediate fallback during the cooldown (%d embed calls):\n%s",
+			embedder.calls.Load()-calls, sys.Content)
+	}
+
+	embedder.err = nil
+	cb.recallRetryAt = time.Time{}
+	sys = cb.BuildMessages(nil, "", "What's my cat's name?", nil, "test", "chat1")[0]
+	if !strings.Contains(sys.Content, "# Relevant Memories") {
+		t.Errorf("expected recall after the cooldown:\n%s", sys.Content)
+	}
+}
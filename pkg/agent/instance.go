@@ -11,7 +11,9 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/routing"
+	"github.com/sipeed/picoclaw/pkg/semantic"
 	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/tokenizer"
 	"github.com/sipeed/picoclaw/pkg/tools"
 )
 
@@ -23,15 +25,18 @@ type AgentInstance struct {
 	Model                     string
 	Fallbacks                 []string
 	Workspace                 string
+	RestrictToWorkspace       bool
 	MaxIterations             int
 	MaxTokens                 int
 	Temperature               float64
 	ThinkingLevel             ThinkingLevel
 	ContextWindow             int
+	Capabilities              providers.ModelCapabilities // of the primary model
+	Tokenizer                 tokenizer.TokenCounter      // counts tokens for the primary model
 	SummarizeMessageThreshold int
 	SummarizeTokenPercent     int
 	Provider                  providers.LLMProvider
-	Sessions                  *session.SessionManager
+	Sessions                  session.SessionStore
 	ContextBuilder            *ContextBuilder
 	Tools                     *tools.ToolRegistry
 	Subagents                 *config.SubagentsConfig
@@ -42,9 +47,15 @@ type AgentInstance struct {
 	// was successfully resolved. It scores each incoming message and decides
 	// whether to route to LightCandidates or stay with Candidates.
 	Router *routing.Router
-	// LightCandidates holds the resolved provider candidates for the light model.
+	// LightModel and LightCandidates hold the configured light model and its
+	// resolved provider candidates. They are set whenever routing.light_model
+	// resolves, even with routing disabled, so budgets can downgrade to them.
 	// Pre-computed at agent creation to avoid repeated model_list lookups at runtime.
+	LightModel      string
 	LightCandidates []providers.FallbackCandidate
+
+	// Budgets limits the agent's token and cost spend; see config.BudgetConfig.
+	Budgets []config.BudgetConfig
 }
 
 // NewAgentInstance creates an agent instance from config.
@@ -60,14 +71,34 @@ func NewAgentInstance(
 	model := resolveAgentModel(agentCfg, defaults)
 	fallbacks := resolveAgentFallbacks(agentCfg, defaults)
 
+	var agentTools config.AgentToolsConfig
+	if agentCfg != nil && agentCfg.Tools != nil {
+		agentTools = *agentCfg.Tools
+	}
+
 	restrict := defaults.RestrictToWorkspace
+	if agentTools.RestrictToWorkspace != nil {
+		restrict = *agentTools.RestrictToWorkspace
+	}
 	readRestrict := restrict && !defaults.AllowReadOutsideWorkspace
 
-	// Compile path whitelist patterns from config.
-	allowReadPaths := compilePatterns(cfg.Tools.AllowReadPaths)
-	allowWritePaths := compilePatterns(cfg.Tools.AllowWritePaths)
+	// Compile path whitelist patterns from config; per-agent lists replace the global ones.
+	readPaths, writePaths := cfg.Tools.AllowReadPaths, cfg.Tools.AllowWritePaths
+	if agentTools.AllowReadPaths != nil {
+		readPaths = agentTools.AllowReadPaths
+	}
+	if agentTools.AllowWritePaths != nil {
+		writePaths = agentTools.AllowWritePaths
+	}
+	allowReadPaths := compilePatterns(readPaths)
+	allowWritePaths := compilePatterns(writePaths)
 
 	toolsRegistry := tools.NewToolRegistry()
+	perms, err := tools.NewToolPermissions(agentTools.Allow, agentTools.Deny)
+	if err != nil {
+		log.Fatalf("Critical error: invalid tool permissions for agent %q: %v", agentCfg.ID, err)
+	}
+	toolsRegistry.SetPermissions(perms)
 
 	if cfg.Tools.IsToolEnabled("read_file") {
 		maxReadFileSize := cfg.Tools.ReadFile.MaxReadFileSize
@@ -84,6 +115,11 @@ func NewAgentInstance(
 		if err != nil {
 			log.Fatalf("Critical error: unable to initialize exec tool: %v", err)
 		}
+		if len(agentTools.ExecAllowPatterns) > 0 {
+			if err := execTool.SetAllowPatterns(agentTools.ExecAllowPatterns); err != nil {
+				log.Fatalf("Critical error: unable to initialize exec tool: %v", err)
+			}
+		}
 		toolsRegistry.Register(execTool)
 	}
 
@@ -98,7 +134,11 @@ func NewAgentInstance(
 	}
 
 	sessionsDir := filepath.Join(workspace, "sessions")
-	sessionsManager := session.NewSessionManager(sessionsDir)
+	sessionsManager, err := session.OpenStore(defaults.SessionStore, sessionsDir)
+	if err != nil {
+		log.Printf("sessions: %v — falling back to JSON session files in %s", err, sessionsDir)
+		sessionsManager = session.NewSessionManager(sessionsDir)
+	}
 
 	mcpDiscoveryActive := cfg.Tools.MCP.Enabled && cfg.Tools.MCP.Discovery.Enabled
 	contextBuilder := NewContextBuilder(workspace).WithToolDiscovery(
@@ -106,6 +146,20 @@ func NewAgentInstance(
 		mcpDiscoveryActive && cfg.Tools.MCP.Discovery.UseRegex,
 	)
 
+	if sm := defaults.SemanticMemory; sm != nil && sm.Enabled {
+		if index, err := newSemanticIndex(cfg, workspace, sm); err != nil {
+			log.Printf("semantic memory: %v — using MEMORY.md in the system prompt", err)
+		} else {
+			contextBuilder.WithSemanticMemory(index, sm.TopK, sm.MinScore)
+			if cfg.Tools.IsToolEnabled("remember") {
+				toolsRegistry.Register(tools.NewRememberTool(index))
+			}
+			if cfg.Tools.IsToolEnabled("recall") {
+				toolsRegistry.Register(tools.NewRecallTool(index, sm.MinScore))
+			}
+		}
+	}
+
 	agentID := routing.DefaultAgentID
 	agentName := ""
 	var subagents *config.SubagentsConfig
@@ -195,19 +249,39 @@ func NewAgentInstance(
 	}
 
 	candidates := providers.ResolveCandidatesWithLookup(modelCfg, defaults.Provider, resolveFromModelList)
+	applyModelCapabilities(cfg, candidates)
+
+	capabilities, _ := providers.LookupCapabilities(model)
+	counter := tokenizer.ForModel(model)
+	if len(candidates) > 0 {
+		capabilities = candidates[0].Capabilities
+		counter = tokenizer.ForModel(candidates[0].Model)
+	}
+	// Without a known window, fall back to the output limit as a
+	// conservative stand-in.
+	contextWindow := capabilities.ContextWindow
+	if contextWindow == 0 {
+		contextWindow = maxTokens
+	}
 
 	// Model routing setup: pre-resolve light model candidates at creation time
 	// to avoid repeated model_list lookups on every incoming message.
 	var router *routing.Router
+	var lightModel string
 	var lightCandidates []providers.FallbackCandidate
-	if rc := defaults.Routing; rc != nil && rc.Enabled && rc.LightModel != "" {
+	if rc := defaults.Routing; rc != nil && rc.LightModel != "" {
 		lightModelCfg := providers.ModelConfig{Primary: rc.LightModel}
 		resolved := providers.ResolveCandidatesWithLookup(lightModelCfg, defaults.Provider, resolveFromModelList)
+		applyModelCapabilities(cfg, resolved)
 		if len(resolved) > 0 {
-			router = routing.New(routing.RouterConfig{
-				LightModel: rc.LightModel,
-				Threshold:  rc.Threshold,
-			})
+			if rc.Enabled {
+				router = routing.New(routing.RouterConfig{
+					LightModel: rc.LightModel,
+					Threshold:  rc.Threshold,
+					Tokenizer:  counter,
+				})
+			}
+			lightModel = rc.LightModel
 			lightCandidates = resolved
 		} else {
 			log.Printf("routing: light_model %q not found in model_list — routing disabled for agent %q",
@@ -215,17 +289,25 @@ func NewAgentInstance(
 		}
 	}
 
+	var budgets []config.BudgetConfig
+	if agentCfg != nil {
+		budgets = agentCfg.Budgets
+	}
+
 	return &AgentInstance{
 		ID:                        agentID,
 		Name:                      agentName,
 		Model:                     model,
 		Fallbacks:                 fallbacks,
 		Workspace:                 workspace,
+		RestrictToWorkspace:       restrict,
 		MaxIterations:             maxIter,
 		MaxTokens:                 maxTokens,
 		Temperature:               temperature,
 		ThinkingLevel:             thinkingLevel,
-		ContextWindow:             maxTokens,
+		ContextWindow:             contextWindow,
+		Capabilities:              capabilities,
+		Tokenizer:                 counter,
 		SummarizeMessageThreshold: summarizeMessageThreshold,
 		SummarizeTokenPercent:     summarizeTokenPercent,
 		Provider:                  provider,
@@ -236,8 +318,27 @@ func NewAgentInstance(
 		SkillsFilter:              skillsFilter,
 		Candidates:                candidates,
 		Router:                    router,
+		LightModel:                lightModel,
 		LightCandidates:           lightCandidates,
+		Budgets:                   budgets,
+	}
+}
+
+// newSemanticIndex creates the memory vector index for workspace using the
+// embedding model configured in sm.
+func newSemanticIndex(cfg *config.Config, workspace string, sm *config.SemanticMemoryConfig) (*semantic.Index, error) {
+	if strings.TrimSpace(sm.EmbeddingModel) == "" {
+		return nil, fmt.Errorf("embedding_model is not set")
+	}
+	mc, err := cfg.GetModelConfig(sm.EmbeddingModel)
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := semantic.NewEmbedderFromConfig(mc)
+	if err != nil {
+		return nil, err
 	}
+	return semantic.NewIndex(workspace, embedder), nil
 }
 
 // resolveAgentWorkspace determines the workspace directory for an agent.
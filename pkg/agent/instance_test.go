@@ -1,7 +1,9 @@
 package agent
 
 import (
+	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/sipeed/picoclaw/pkg/config"
@@ -160,3 +162,50 @@ func TestNewAgentInstance_ResolveCandidatesFromModelListAlias(t *testing.T) {
 		})
 	}
 }
+
+func TestNewAgentInstance_PerAgentToolPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	restrict := false
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:           tmpDir,
+				Model:               "test-model",
+				RestrictToWorkspace: true,
+			},
+			List: []config.AgentConfig{
+				{ID: "support", Tools: &config.AgentToolsConfig{Deny: []string{"exec", "*_file"}}},
+				{ID: "ops", Tools: &config.AgentToolsConfig{
+					RestrictToWorkspace: &restrict,
+					ExecAllowPatterns:   []string{`^kubectl `},
+				}},
+			},
+		},
+		Tools: config.ToolsConfig{
+			Exec:      config.ExecConfig{ToolConfig: config.ToolConfig{Enabled: true}},
+			ReadFile:  config.ReadFileToolConfig{Enabled: true},
+			WriteFile: config.ToolConfig{Enabled: true},
+			ListDir:   config.ToolConfig{Enabled: true},
+		},
+	}
+
+	support := NewAgentInstance(&cfg.Agents.List[0], &cfg.Agents.Defaults, cfg, &mockProvider{})
+	if got := support.Tools.List(); len(got) != 1 || got[0] != "list_dir" {
+		t.Fatalf("support tools = %v, want [list_dir]", got)
+	}
+	if !support.RestrictToWorkspace {
+		t.Fatal("support should inherit restrict_to_workspace from defaults")
+	}
+
+	ops := NewAgentInstance(&cfg.Agents.List[1], &cfg.Agents.Defaults, cfg, &mockProvider{})
+	if _, ok := ops.Tools.Get("exec"); !ok {
+		t.Fatal("ops should have the exec tool")
+	}
+	if ops.RestrictToWorkspace {
+		t.Fatal("ops should override restrict_to_workspace")
+	}
+	result := ops.Tools.Execute(context.Background(), "exec", map[string]any{"command": "echo hi"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "not in allowlist") {
+		t.Fatalf("exec outside allow patterns = %+v, want allowlist block", result)
+	}
+}
@@ -17,13 +17,13 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
-	"unicode/utf8"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/commands"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/history"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/mcp"
 	"github.com/sipeed/picoclaw/pkg/media"
@@ -31,7 +31,9 @@ import (
 	"github.com/sipeed/picoclaw/pkg/routing"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/state"
+	"github.com/sipeed/picoclaw/pkg/tokenizer"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/usage"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
@@ -48,19 +50,26 @@ type AgentLoop struct {
 	mediaStore     media.MediaStore
 	transcriber    voice.Transcriber
 	cmdRegistry    *commands.Registry
+	usage          *usage.Ledger
+	history        *history.Index
+	approvals      *tools.ApprovalManager
 }
 
 // processOptions configures how a message is processed
 type processOptions struct {
 	SessionKey      string   // Session identifier for history/context
 	Channel         string   // Target channel for tool execution
+	AccountID       string   // Channel account the message arrived on ("" = default)
 	ChatID          string   // Target chat ID for tool execution
+	ThreadID        string   // Platform thread replies are posted in ("" = none)
+	MessageID       string   // Platform ID of the inbound message being answered
 	SenderID        string   // Sender ID for tool-scoped access control and auditing
 	UserMessage     string   // User message content (may include prefix)
 	Media           []string // media:// refs from inbound message
 	DefaultResponse string   // Response when LLM returns empty
 	EnableSummary   bool     // Whether to trigger summarization
 	SendResponse    bool     // Whether to send response via bus
+	Stream          bool     // Whether to stream partial replies to the channel
 	NoHistory       bool     // If true, don't load session history (for heartbeat)
 }
 
@@ -84,9 +93,10 @@ func NewAgentLoop(
 	provider providers.LLMProvider,
 ) *AgentLoop {
 	registry := NewAgentRegistry(cfg, provider)
+	historyIndex := history.NewIndex(cfg.WorkspacePath())
 
 	// Register shared tools to all agents
-	registerSharedTools(cfg, msgBus, registry, provider)
+	registerSharedTools(cfg, msgBus, registry, provider, historyIndex)
 
 	// Set up shared fallback chain
 	cooldown := providers.NewCooldownTracker()
@@ -107,7 +117,11 @@ func NewAgentLoop(
 		summarizing: sync.Map{},
 		fallback:    fallbackChain,
 		cmdRegistry: commands.NewRegistry(commands.BuiltinDefinitions()),
+		usage:       usage.NewLedger(cfg.WorkspacePath(), usage.NewPriceTable(cfg.ModelList)),
+		history:     historyIndex,
 	}
+	al.setupApprovals()
+	al.backfillHistory()
 
 	return al
 }
@@ -118,6 +132,7 @@ func registerSharedTools(
 	msgBus *bus.MessageBus,
 	registry *AgentRegistry,
 	provider providers.LLMProvider,
+	historyIndex *history.Index,
 ) {
 	for _, agentID := range registry.ListAgentIDs() {
 		agent, ok := registry.GetAgent(agentID)
@@ -176,14 +191,10 @@ func registerSharedTools(
 		// Message tool
 		if cfg.Tools.IsToolEnabled("message") {
 			messageTool := tools.NewMessageTool()
-			messageTool.SetSendCallback(func(channel, chatID, content string) error {
+			messageTool.SetSendCallback(func(msg bus.OutboundMessage) error {
 				pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer pubCancel()
-				return msgBus.PublishOutbound(pubCtx, bus.OutboundMessage{
-					Channel: channel,
-					ChatID:  chatID,
-					Content: content,
-				})
+				return msgBus.PublishOutbound(pubCtx, msg)
 			})
 			agent.Tools.Register(messageTool)
 		}
@@ -192,13 +203,19 @@ func registerSharedTools(
 		if cfg.Tools.IsToolEnabled("send_file") {
 			sendFileTool := tools.NewSendFileTool(
 				agent.Workspace,
-				cfg.Agents.Defaults.RestrictToWorkspace,
+				agent.RestrictToWorkspace,
 				cfg.Agents.Defaults.GetMaxMediaSize(),
 				nil,
 			)
 			agent.Tools.Register(sendFileTool)
 		}
 
+		// Conversation history search, scoped to the agent's own messages
+		// and by default to the current session
+		if cfg.Tools.IsToolEnabled("search_history") {
+			agent.Tools.Register(tools.NewSearchHistoryTool(historyIndex, agent.ID, cfg.Tools.SearchHistory.Scope))
+		}
+
 		// Skill discovery and installation tools
 		skills_enabled := cfg.Tools.IsToolEnabled("skills")
 		find_skills_enable := cfg.Tools.IsToolEnabled("find_skills")
@@ -403,9 +420,11 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 
 					if !alreadySent {
 						al.bus.PublishOutbound(ctx, bus.OutboundMessage{
-							Channel: msg.Channel,
-							ChatID:  msg.ChatID,
-							Content: response,
+							Channel:   msg.Channel,
+							AccountID: msg.AccountID,
+							ChatID:    msg.ChatID,
+							ThreadID:  msg.ThreadID,
+							Content:   response,
 						})
 						logger.InfoCF("agent", "Published outbound response",
 							map[string]any{
@@ -652,7 +671,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 
 	// Resolve session key from route, while preserving explicit agent-scoped keys.
 	scopeKey := resolveScopeKey(route, msg.SessionKey)
-	sessionKey := scopeKey
+	sessionKey := al.resolveBranchKey(scopeKey)
 
 	logger.InfoCF("agent", "Routed message",
 		map[string]any{
@@ -667,13 +686,17 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	opts := processOptions{
 		SessionKey:      sessionKey,
 		Channel:         msg.Channel,
+		AccountID:       msg.AccountID,
 		ChatID:          msg.ChatID,
+		ThreadID:        msg.ThreadID,
+		MessageID:       msg.MessageID,
 		SenderID:        msg.SenderID,
-		UserMessage:     msg.Content,
+		UserMessage:     withQuotedMessage(msg.Content, unseenQuote(agent, sessionKey, msg.ReplyTo)),
 		Media:           msg.Media,
 		DefaultResponse: defaultResponse,
 		EnableSummary:   true,
 		SendResponse:    false,
+		Stream:          al.cfg.Agents.Defaults.Streaming,
 	}
 
 	// context-dependent commands check their own Runtime fields and report
@@ -686,13 +709,18 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 }
 
 func (al *AgentLoop) resolveMessageRoute(msg bus.InboundMessage) (routing.ResolvedRoute, *AgentInstance, error) {
+	accountID := msg.AccountID
+	if accountID == "" {
+		accountID = inboundMetadata(msg, metadataKeyAccountID)
+	}
 	route := al.registry.ResolveRoute(routing.RouteInput{
 		Channel:    msg.Channel,
-		AccountID:  inboundMetadata(msg, metadataKeyAccountID),
+		AccountID:  accountID,
 		Peer:       extractPeer(msg),
 		ParentPeer: extractParentPeer(msg),
 		GuildID:    inboundMetadata(msg, metadataKeyGuildID),
 		TeamID:     inboundMetadata(msg, metadataKeyTeamID),
+		ThreadID:   msg.ThreadID,
 	})
 
 	agent, ok := al.registry.GetAgent(route.AgentID)
@@ -771,6 +799,7 @@ func (al *AgentLoop) processSystemMessage(
 	return al.runAgentLoop(ctx, agent, processOptions{
 		SessionKey:      sessionKey,
 		Channel:         originChannel,
+		AccountID:       msg.AccountID,
 		ChatID:          originChatID,
 		SenderID:        msg.SenderID,
 		UserMessage:     fmt.Sprintf("[System: %s] %s", msg.SenderID, msg.Content),
@@ -822,6 +851,7 @@ func (al *AgentLoop) runAgentLoop(
 
 	// 2. Save user message to session
 	agent.Sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
+	al.recordHistory(agent, opts, "user", opts.UserMessage)
 
 	// 3. Run LLM iteration loop
 	finalContent, iteration, err := al.runLLMIteration(ctx, agent, messages, opts)
@@ -840,6 +870,7 @@ func (al *AgentLoop) runAgentLoop(
 	// 5. Save final assistant message to session
 	agent.Sessions.AddMessage(opts.SessionKey, "assistant", finalContent)
 	agent.Sessions.Save(opts.SessionKey)
+	al.recordHistory(agent, opts, "assistant", finalContent)
 
 	// 6. Optional: summarization
 	if opts.EnableSummary {
@@ -849,9 +880,11 @@ func (al *AgentLoop) runAgentLoop(
 	// 7. Optional: send response via bus
 	if opts.SendResponse {
 		al.bus.PublishOutbound(ctx, bus.OutboundMessage{
-			Channel: opts.Channel,
-			ChatID:  opts.ChatID,
-			Content: finalContent,
+			Channel:   opts.Channel,
+			AccountID: opts.AccountID,
+			ChatID:    opts.ChatID,
+			ThreadID:  opts.ThreadID,
+			Content:   finalContent,
 		})
 	}
 
@@ -868,6 +901,8 @@ func (al *AgentLoop) runAgentLoop(
 	return finalContent, nil
 }
 
+// targetReasoningChannelID returns the reasoning chat configured for a channel;
+// channelName may be a key built by channels.ChannelKey.
 func (al *AgentLoop) targetReasoningChannelID(channelName string) (chatID string) {
 	if al.channelManager == nil {
 		return ""
@@ -898,10 +933,12 @@ func (al *AgentLoop) handleReasoning(
 	pubCtx, pubCancel := context.WithTimeout(ctx, 5*time.Second)
 	defer pubCancel()
 
+	name, accountID := channels.ParseChannelKey(channelName)
 	if err := al.bus.PublishOutbound(pubCtx, bus.OutboundMessage{
-		Channel: channelName,
-		ChatID:  channelID,
-		Content: reasoningContent,
+		Channel:   name,
+		AccountID: accountID,
+		ChatID:    channelID,
+		Content:   reasoningContent,
 	}); err != nil {
 		// Treat context.DeadlineExceeded / context.Canceled as expected
 		// (bus full under load, or parent canceled).  Check the error
@@ -924,6 +961,190 @@ func (al *AgentLoop) handleReasoning(
 	}
 }
 
+// recordUsage appends the token usage reported for one LLM call to the
+// usage ledger. Providers that do not report usage are skipped.
+func (al *AgentLoop) recordUsage(
+	agent *AgentInstance,
+	opts processOptions,
+	model string,
+	resp *providers.LLMResponse,
+) {
+	if al.usage == nil || resp == nil || resp.Usage == nil {
+		return
+	}
+	if err := al.usage.Append(usage.Record{
+		AgentID:          agent.ID,
+		SessionKey:       opts.SessionKey,
+		Channel:          opts.Channel,
+		SenderID:         opts.SenderID,
+		Model:            model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+	}); err != nil {
+		logger.WarnCF("agent", "Failed to record token usage", map[string]any{
+			"agent_id": agent.ID,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// backfillHistory adds the sessions that predate the history index, or
+// were written while it was not recorded, to the index. A session is
+// skipped once the index holds any of its messages. Backfilled messages
+// carry the session's last update time and no channel or sender, so only
+// session-scoped and unfiltered searches find them.
+func (al *AgentLoop) backfillHistory() {
+	if al.history == nil {
+		return
+	}
+	for _, agentID := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(agentID)
+		if !ok || agent.Sessions == nil {
+			continue
+		}
+		if err := backfillAgentHistory(al.history, agent); err != nil {
+			logger.WarnCF("agent", "Failed to backfill message history", map[string]any{
+				"agent_id": agent.ID,
+				"error":    err.Error(),
+			})
+		}
+	}
+}
+
+// backfillAgentHistory indexes the sessions of agent that predate the
+// history index. Sessions handled by an earlier run are recorded in the
+// index, so only new ones are checked; those that were recorded live are
+// found in the index without loading them.
+func backfillAgentHistory(index *history.Index, agent *AgentInstance) error {
+	sessions, err := agent.Sessions.ListSessions()
+	if err != nil {
+		return err
+	}
+	done := index.BackfilledSessions(agent.ID)
+
+	var indexed map[string]bool // read on first need
+	handled := make([]string, 0, len(sessions))
+	backfilled := 0
+	for _, info := range sessions {
+		// Agents sharing a workspace also share its sessions.
+		if parsed := routing.ParseAgentSessionKey(info.Key); parsed != nil &&
+			routing.NormalizeAgentID(parsed.AgentID) != routing.NormalizeAgentID(agent.ID) {
+			continue
+		}
+		handled = append(handled, info.Key)
+		if done[info.Key] {
+			continue
+		}
+		if indexed == nil {
+			if indexed, err = index.SessionKeys(agent.ID); err != nil {
+				return err
+			}
+		}
+		if indexed[info.Key] {
+			continue
+		}
+		var entries []history.Entry
+		for _, msg := range agent.Sessions.GetHistory(info.Key) {
+			if msg.Role != "user" && msg.Role != "assistant" {
+				continue
+			}
+			entries = append(entries, history.Entry{
+				Time:       info.UpdatedAt,
+				AgentID:    agent.ID,
+				SessionKey: info.Key,
+				Role:       msg.Role,
+				Content:    msg.Content,
+			})
+		}
+		if err := index.Append(entries...); err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			backfilled++
+		}
+	}
+	if backfilled > 0 {
+		logger.InfoCF("agent", "Backfilled message history", map[string]any{
+			"agent_id": agent.ID,
+			"sessions": backfilled,
+		})
+	}
+	if len(handled) == len(done) && indexed == nil {
+		return nil // nothing new
+	}
+	return index.SetBackfilledSessions(agent.ID, handled)
+}
+
+// recordHistory adds a message of the current turn to the searchable
+// history index. Heartbeat turns, which run without history, are skipped.
+func (al *AgentLoop) recordHistory(agent *AgentInstance, opts processOptions, role, content string) {
+	if al.history == nil || opts.NoHistory {
+		return
+	}
+	if err := al.history.Append(history.Entry{
+		AgentID:    agent.ID,
+		SessionKey: opts.SessionKey,
+		Channel:    opts.Channel,
+		ChatID:     opts.ChatID,
+		SenderID:   opts.SenderID,
+		Role:       role,
+		Content:    content,
+	}); err != nil {
+		logger.WarnCF("agent", "Failed to record message history", map[string]any{
+			"agent_id": agent.ID,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// checkBudget evaluates the agent's usage budgets for the current turn. A
+// ledger that cannot be read is logged and treated as within budget.
+func (al *AgentLoop) checkBudget(agent *AgentInstance, opts processOptions) usage.BudgetResult {
+	if al.usage == nil || len(agent.Budgets) == 0 {
+		return usage.BudgetResult{}
+	}
+	result, err := al.usage.CheckBudgets(agent.Budgets, usage.BudgetSubject{
+		AgentID:  agent.ID,
+		SenderID: opts.SenderID,
+		Channel:  opts.Channel,
+	}, time.Now())
+	if err != nil {
+		logger.WarnCF("agent", "Failed to check usage budgets", map[string]any{
+			"agent_id": agent.ID,
+			"error":    err.Error(),
+		})
+		return usage.BudgetResult{}
+	}
+	return result
+}
+
+// streamDeltaFunc returns a callback that publishes partial LLM output to the
+// originating channel, or nil when streaming does not apply to this turn.
+// Each call starts a fresh accumulation, so retries and fallback attempts
+// replace rather than extend what the user has seen so far.
+func (al *AgentLoop) streamDeltaFunc(ctx context.Context, opts processOptions) func(string) {
+	if !opts.Stream || opts.Channel == "" || opts.ChatID == "" || constants.IsInternalChannel(opts.Channel) {
+		return nil
+	}
+	var content strings.Builder
+	return func(delta string) {
+		content.WriteString(delta)
+		if err := al.bus.PublishOutboundDelta(ctx, bus.OutboundDeltaMessage{
+			Channel:   opts.Channel,
+			AccountID: opts.AccountID,
+			ChatID:    opts.ChatID,
+			Delta:     delta,
+			Content:   content.String(),
+		}); err != nil {
+			logger.DebugCF("agent", "Stream delta publish skipped", map[string]any{
+				"channel": opts.Channel,
+				"error":   err.Error(),
+			})
+		}
+	}
+}
+
 // runLLMIteration executes the LLM call loop with tool handling.
 func (al *AgentLoop) runLLMIteration(
 	ctx context.Context,
@@ -950,9 +1171,38 @@ func (al *AgentLoop) runLLMIteration(
 				"max":       agent.MaxIterations,
 			})
 
+		// Budgets are re-checked before every call so a long tool chain
+		// cannot run past a limit that was reached mid-turn.
+		switch budget := al.checkBudget(agent, opts); budget.State {
+		case usage.BudgetHard:
+			logger.WarnCF("agent", "Usage budget exhausted, refusing LLM call",
+				map[string]any{
+					"agent_id": agent.ID,
+					"scope":    budget.Budget.Scope,
+					"period":   budget.Budget.Period,
+					"used":     budget.Used.String(),
+				})
+			return budget.Message(), iteration, nil
+		case usage.BudgetSoft:
+			if len(agent.LightCandidates) > 0 && activeModel != agent.LightModel {
+				logger.InfoCF("agent", "Soft usage budget reached, downgrading to light model",
+					map[string]any{
+						"agent_id":    agent.ID,
+						"scope":       budget.Budget.Scope,
+						"period":      budget.Budget.Period,
+						"light_model": agent.LightModel,
+					})
+				activeCandidates, activeModel = agent.LightCandidates, agent.LightModel
+			}
+		}
+
 		// Build tool definitions
 		providerToolDefs := agent.Tools.ToProviderDefs()
 
+		// Try the models that can take the request as it is first; the
+		// others only see what they can accept.
+		candidates := providers.RankByCapability(activeCandidates, requestRequirements(messages, providerToolDefs))
+
 		// Log LLM request details
 		logger.DebugCF("agent", "LLM request",
 			map[string]any{
@@ -994,13 +1244,54 @@ func (al *AgentLoop) runLLMIteration(
 			}
 		}
 
+		compress := func() {
+			al.forceCompression(agent, opts.SessionKey)
+			newHistory := agent.Sessions.GetHistory(opts.SessionKey)
+			newSummary := agent.Sessions.GetSummary(opts.SessionKey)
+			messages = agent.ContextBuilder.BuildMessages(
+				newHistory, newSummary, "",
+				nil, opts.Channel, opts.ChatID,
+			)
+		}
+
+		chat := func(ctx context.Context, model string, caps providers.ModelCapabilities) (*providers.LLMResponse, error) {
+			var resp *providers.LLMResponse
+			var err error
+			msgs, toolDefs, callOpts := fitToCapabilities(messages, providerToolDefs, llmOpts, caps)
+			// Compress up front when the request cannot fit the window of the
+			// model about to be called, instead of waiting for the provider
+			// to reject it.
+			counter := candidateTokenizer(candidates, model)
+			if tokens, window, over := exceedsContextWindow(agent, counter, caps, msgs, toolDefs); over {
+				logger.WarnCF("agent", "Request exceeds the context window, compressing before the call",
+					map[string]any{
+						"agent_id":       agent.ID,
+						"model":          model,
+						"tokens":         tokens,
+						"context_window": window,
+					})
+				compress()
+				msgs, toolDefs, callOpts = fitToCapabilities(messages, providerToolDefs, llmOpts, caps)
+			}
+			sp, canStream := agent.Provider.(providers.StreamingProvider)
+			if onDelta := al.streamDeltaFunc(ctx, opts); canStream && onDelta != nil {
+				resp, err = sp.ChatStream(ctx, msgs, toolDefs, model, callOpts, onDelta)
+			} else {
+				resp, err = agent.Provider.Chat(ctx, msgs, toolDefs, model, callOpts)
+			}
+			if err == nil {
+				al.recordUsage(agent, opts, model, resp)
+			}
+			return resp, err
+		}
+
 		callLLM := func() (*providers.LLMResponse, error) {
 			if len(activeCandidates) > 1 && al.fallback != nil {
 				fbResult, fbErr := al.fallback.Execute(
 					ctx,
-					activeCandidates,
+					candidates,
 					func(ctx context.Context, provider, model string) (*providers.LLMResponse, error) {
-						return agent.Provider.Chat(ctx, messages, providerToolDefs, model, llmOpts)
+						return chat(ctx, model, candidateCapabilities(candidates, agent.Capabilities, provider, model))
 					},
 				)
 				if fbErr != nil {
@@ -1016,7 +1307,7 @@ func (al *AgentLoop) runLLMIteration(
 				}
 				return fbResult.Response, nil
 			}
-			return agent.Provider.Chat(ctx, messages, providerToolDefs, activeModel, llmOpts)
+			return chat(ctx, activeModel, candidateCapabilities(candidates, agent.Capabilities, "", activeModel))
 		}
 
 		// Retry loop for context/token errors
@@ -1070,19 +1361,15 @@ func (al *AgentLoop) runLLMIteration(
 
 				if retry == 0 && !constants.IsInternalChannel(opts.Channel) {
 					al.bus.PublishOutbound(ctx, bus.OutboundMessage{
-						Channel: opts.Channel,
-						ChatID:  opts.ChatID,
-						Content: "Context window exceeded. Compressing history and retrying...",
+						Channel:   opts.Channel,
+						AccountID: opts.AccountID,
+						ChatID:    opts.ChatID,
+						ThreadID:  opts.ThreadID,
+						Content:   "Context window exceeded. Compressing history and retrying...",
 					})
 				}
 
-				al.forceCompression(agent, opts.SessionKey)
-				newHistory := agent.Sessions.GetHistory(opts.SessionKey)
-				newSummary := agent.Sessions.GetSummary(opts.SessionKey)
-				messages = agent.ContextBuilder.BuildMessages(
-					newHistory, newSummary, "",
-					nil, opts.Channel, opts.ChatID,
-				)
+				compress()
 				continue
 			}
 			break
@@ -1098,11 +1385,12 @@ func (al *AgentLoop) runLLMIteration(
 			return "", iteration, fmt.Errorf("LLM call failed after retries: %w", err)
 		}
 
+		channelKey := channels.ChannelKey(opts.Channel, opts.AccountID)
 		go al.handleReasoning(
 			ctx,
 			response.Reasoning,
-			opts.Channel,
-			al.targetReasoningChannelID(opts.Channel),
+			channelKey,
+			al.targetReasoningChannelID(channelKey),
 		)
 
 		logger.DebugCF("agent", "LLM response",
@@ -1112,7 +1400,7 @@ func (al *AgentLoop) runLLMIteration(
 				"content_chars":  len(response.Content),
 				"tool_calls":     len(response.ToolCalls),
 				"reasoning":      response.Reasoning,
-				"target_channel": al.targetReasoningChannelID(opts.Channel),
+				"target_channel": al.targetReasoningChannelID(channelKey),
 				"channel":        opts.Channel,
 			})
 		// Check if no tool calls - then check reasoning content if any
@@ -1236,9 +1524,11 @@ func (al *AgentLoop) runLLMIteration(
 						outCtx, outCancel := context.WithTimeout(context.Background(), 5*time.Second)
 						defer outCancel()
 						_ = al.bus.PublishOutbound(outCtx, bus.OutboundMessage{
-							Channel: opts.Channel,
-							ChatID:  opts.ChatID,
-							Content: result.ForUser,
+							Channel:   opts.Channel,
+							AccountID: opts.AccountID,
+							ChatID:    opts.ChatID,
+							ThreadID:  opts.ThreadID,
+							Content:   result.ForUser,
 						})
 					}
 
@@ -1261,15 +1551,19 @@ func (al *AgentLoop) runLLMIteration(
 					pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
 					defer pubCancel()
 					_ = al.bus.PublishInbound(pubCtx, bus.InboundMessage{
-						Channel:  "system",
-						SenderID: fmt.Sprintf("async:%s", tc.Name),
-						ChatID:   fmt.Sprintf("%s:%s", opts.Channel, opts.ChatID),
-						Content:  content,
+						Channel:   "system",
+						AccountID: opts.AccountID,
+						SenderID:  fmt.Sprintf("async:%s", tc.Name),
+						ChatID:    fmt.Sprintf("%s:%s", opts.Channel, opts.ChatID),
+						Content:   content,
 					})
 				}
 
+				toolCtx := tools.WithToolAccount(ctx, opts.AccountID)
+				toolCtx = tools.WithToolThread(toolCtx, opts.ThreadID, opts.MessageID)
+				toolCtx = tools.WithToolSession(toolCtx, opts.SessionKey)
 				toolResult := agent.Tools.ExecuteWithContext(
-					ctx,
+					toolCtx,
 					tc.Name,
 					toolArgs,
 					opts.Channel,
@@ -1287,9 +1581,11 @@ func (al *AgentLoop) runLLMIteration(
 			// Send ForUser content to user immediately if not Silent
 			if !r.result.Silent && r.result.ForUser != "" && opts.SendResponse {
 				al.bus.PublishOutbound(ctx, bus.OutboundMessage{
-					Channel: opts.Channel,
-					ChatID:  opts.ChatID,
-					Content: r.result.ForUser,
+					Channel:   opts.Channel,
+					AccountID: opts.AccountID,
+					ChatID:    opts.ChatID,
+					ThreadID:  opts.ThreadID,
+					Content:   r.result.ForUser,
 				})
 				logger.DebugCF("agent", "Sent tool result to user",
 					map[string]any{
@@ -1313,9 +1609,10 @@ func (al *AgentLoop) runLLMIteration(
 					parts = append(parts, part)
 				}
 				al.bus.PublishOutboundMedia(ctx, bus.OutboundMediaMessage{
-					Channel: opts.Channel,
-					ChatID:  opts.ChatID,
-					Parts:   parts,
+					Channel:   opts.Channel,
+					AccountID: opts.AccountID,
+					ChatID:    opts.ChatID,
+					Parts:     parts,
 				})
 			}
 
@@ -1420,7 +1717,7 @@ func (al *AgentLoop) selectCandidates(
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
 func (al *AgentLoop) maybeSummarize(agent *AgentInstance, sessionKey, channel, chatID string) {
 	newHistory := agent.Sessions.GetHistory(sessionKey)
-	tokenEstimate := al.estimateTokens(newHistory)
+	tokenEstimate := tokenizer.CountMessages(agent.Tokenizer, newHistory, nil)
 	threshold := agent.ContextWindow * agent.SummarizeTokenPercent / 100
 
 	if len(newHistory) > agent.SummarizeMessageThreshold || tokenEstimate > threshold {
@@ -1451,8 +1748,14 @@ func (al *AgentLoop) forceCompression(agent *AgentInstance, sessionKey string) {
 		return
 	}
 
-	// Helper to find the mid-point of the conversation
+	// Drop the oldest half, and more while the rest would still take over
+	// half of the context window.
 	mid := len(conversation) / 2
+	kept := tokenizer.CountMessages(agent.Tokenizer, conversation[mid:], nil)
+	for mid < len(conversation)-1 && kept > agent.ContextWindow/2 {
+		kept -= tokenizer.CountMessage(agent.Tokenizer, conversation[mid])
+		mid++
+	}
 
 	// New history structure:
 	// 1. System Prompt (with compression note appended)
@@ -1599,7 +1902,7 @@ func (al *AgentLoop) summarizeSession(agent *AgentInstance, sessionKey string) {
 		if m.Role != "user" && m.Role != "assistant" {
 			continue
 		}
-		msgTokens := len(m.Content) / 2
+		msgTokens := tokenizer.CountMessage(agent.Tokenizer, m)
 		if msgTokens > maxMessageTokens {
 			omitted = true
 			continue
@@ -1785,16 +2088,25 @@ func (al *AgentLoop) summarizeBatch(
 	return fallback.String(), nil
 }
 
-// estimateTokens estimates the number of tokens in a message list.
-// Uses a safe heuristic of 2.5 characters per token to account for CJK and other
-// overheads better than the previous 3 chars/token.
-func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
-	totalChars := 0
-	for _, m := range messages {
-		totalChars += utf8.RuneCountInString(m.Content)
-	}
-	// 2.5 chars per token = totalChars * 2 / 5
-	return totalChars * 2 / 5
+// exceedsContextWindow reports whether a request leaves too little room for
+// the reply in the context window of the model about to be called, which
+// routing or fallback may have picked instead of the primary model; caps and
+// counter are that model's. Models whose window is unknown are never
+// reported; their limit is only learned from errors.
+func exceedsContextWindow(
+	agent *AgentInstance,
+	counter tokenizer.TokenCounter,
+	caps providers.ModelCapabilities,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+) (tokens, window int, over bool) {
+	window = caps.ContextWindow
+	if window <= 0 {
+		return 0, window, false
+	}
+	reserve := min(agent.MaxTokens, window/4)
+	tokens = tokenizer.CountMessages(counter, messages, tools)
+	return tokens, window, tokens+reserve > window
 }
 
 func (al *AgentLoop) handleCommand(
@@ -1882,8 +2194,61 @@ func (al *AgentLoop) buildCommandsRuntime(agent *AgentInstance, opts *processOpt
 			agent.Sessions.SetHistory(opts.SessionKey, make([]providers.Message, 0))
 			agent.Sessions.SetSummary(opts.SessionKey, "")
 			agent.Sessions.Save(opts.SessionKey)
+			if al.history != nil {
+				if _, err := al.history.RemoveSession(agent.ID, opts.SessionKey); err != nil {
+					return fmt.Errorf("failed to clear message history: %w", err)
+				}
+			}
 			return nil
 		}
+
+		rt.RewindHistory = func(turns int) (int, error) {
+			if opts == nil {
+				return 0, fmt.Errorf("process options not available")
+			}
+			return al.rewindSession(agent, opts.SessionKey, turns)
+		}
+
+		if al.approvals != nil && opts != nil {
+			rt.ResolveApproval = func(id string, approved bool) (string, error) {
+				return al.resolveApproval(opts.Channel, opts.AccountID, opts.ChatID, opts.SenderID, id, approved)
+			}
+		}
+
+		if al.state != nil && opts != nil {
+			rt.BranchSession = func(name string) (string, error) {
+				return al.branchSession(agent, opts.SessionKey, name)
+			}
+			rt.ListSessions = func() ([]commands.SessionBranch, error) {
+				return al.listSessionBranches(agent, opts.SessionKey), nil
+			}
+			rt.SwitchSession = func(name string) (string, error) {
+				return al.switchSessionBranch(opts.SessionKey, name)
+			}
+		}
+
+		if al.usage != nil {
+			rt.GetUsage = func(scope string) (usage.Summary, error) {
+				var filter usage.Filter
+				switch scope {
+				case commands.UsageScopeSession:
+					if opts == nil {
+						return usage.Summary{}, fmt.Errorf("process options not available")
+					}
+					filter.SessionKey = opts.SessionKey
+					filter.AgentID = agent.ID
+				case commands.UsageScopeAgent:
+					filter.AgentID = agent.ID
+				case commands.UsageScopeSender:
+					if opts == nil || opts.SenderID == "" {
+						return usage.Summary{}, fmt.Errorf("sender not available")
+					}
+					filter.Channel = opts.Channel
+					filter.SenderID = opts.SenderID
+				}
+				return al.usage.Summarize(filter)
+			}
+		}
 	}
 	return rt
 }
@@ -1911,6 +2276,36 @@ func extractPeer(msg bus.InboundMessage) *routing.RoutePeer {
 	return &routing.RoutePeer{Kind: msg.Peer.Kind, ID: peerID}
 }
 
+// withQuotedMessage prefixes content with the message it replies to, so the
+// model sees what the user is answering.
+func withQuotedMessage(content string, quoted *bus.QuotedMessage) string {
+	if quoted == nil || quoted.Content == "" {
+		return content
+	}
+	author := quoted.SenderName
+	if author == "" {
+		author = quoted.SenderID
+	}
+	if author == "" {
+		author = "unknown"
+	}
+	return fmt.Sprintf("[quoted message from %s]: %s\n\n%s", author, quoted.Content, content)
+}
+
+// unseenQuote returns quoted unless the session already holds its text, as
+// it does for the root of a thread after the first reply in it was quoted.
+func unseenQuote(agent *AgentInstance, sessionKey string, quoted *bus.QuotedMessage) *bus.QuotedMessage {
+	if quoted == nil || quoted.Content == "" {
+		return quoted
+	}
+	for _, m := range agent.Sessions.GetHistory(sessionKey) {
+		if strings.Contains(m.Content, quoted.Content) {
+			return nil
+		}
+	}
+	return quoted
+}
+
 func inboundMetadata(msg bus.InboundMessage, key string) string {
 	if msg.Metadata == nil {
 		return ""
@@ -14,10 +14,14 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/history"
 	"github.com/sipeed/picoclaw/pkg/media"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/routing"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/tokenizer"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/usage"
 )
 
 type fakeChannel struct{ id string }
@@ -579,6 +583,392 @@ func TestProcessMessage_UsesRouteSessionKey(t *testing.T) {
 	}
 }
 
+func TestProcessMessage_PerThreadSessionAndQuote(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Session: config.SessionConfig{PerThread: true},
+	}
+
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &simpleMockProvider{response: "ok"})
+	msg := bus.InboundMessage{
+		Channel:  "slack",
+		SenderID: "U1",
+		ChatID:   "C1/1700.1",
+		Content:  "what about this?",
+		Peer:     bus.Peer{Kind: "channel", ID: "C1"},
+		ThreadID: "1700.1",
+		ReplyTo:  &bus.QuotedMessage{SenderName: "alice", Content: "ship it"},
+	}
+
+	helper := testHelper{al: al}
+	_ = helper.executeAndGetResponse(t, context.Background(), msg)
+
+	agent := al.registry.GetDefaultAgent()
+	history := agent.Sessions.GetHistory("agent:main:slack:channel:c1:thread:1700.1")
+	if len(history) != 2 {
+		t.Fatalf("expected thread session history len=2, got %d", len(history))
+	}
+	want := "[quoted message from alice]: ship it\n\nwhat about this?"
+	if history[0].Content != want {
+		t.Errorf("user message = %q, want %q", history[0].Content, want)
+	}
+	if got := agent.Sessions.GetHistory("agent:main:slack:channel:c1"); len(got) != 0 {
+		t.Errorf("channel session should be empty, got %d messages", len(got))
+	}
+
+	// The next reply in the thread quotes the same root, which the session
+	// already holds.
+	msg.Content = "and now?"
+	_ = helper.executeAndGetResponse(t, context.Background(), msg)
+	history = agent.Sessions.GetHistory("agent:main:slack:channel:c1:thread:1700.1")
+	if len(history) != 4 || history[2].Content != "and now?" {
+		t.Errorf("second user message = %+v, want it without the quote", history)
+	}
+}
+
+type streamingMockProvider struct {
+	simpleMockProvider
+	deltas []string
+}
+
+func (m *streamingMockProvider) ChatStream(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+	onDelta func(delta string),
+) (*providers.LLMResponse, error) {
+	for _, d := range m.deltas {
+		onDelta(d)
+	}
+	return m.Chat(ctx, messages, tools, model, opts)
+}
+
+func TestProcessMessage_StreamsDeltasWhenEnabled(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				Streaming:         true,
+			},
+		},
+	}
+
+	msgBus := bus.NewMessageBus()
+	provider := &streamingMockProvider{
+		simpleMockProvider: simpleMockProvider{response: "Hello world"},
+		deltas:             []string{"Hello", " world"},
+	}
+	al := NewAgentLoop(cfg, msgBus, provider)
+
+	helper := testHelper{al: al}
+	response := helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "hi",
+		Peer:     bus.Peer{Kind: "direct", ID: "user1"},
+	})
+	if response != "Hello world" {
+		t.Fatalf("response = %q, want %q", response, "Hello world")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var got []bus.OutboundDeltaMessage
+	for range provider.deltas {
+		delta, ok := msgBus.SubscribeOutboundDelta(ctx)
+		if !ok {
+			t.Fatalf("expected %d deltas, got %d", len(provider.deltas), len(got))
+		}
+		got = append(got, delta)
+	}
+	if got[0].Channel != "telegram" || got[0].ChatID != "chat1" {
+		t.Fatalf("unexpected delta target: %+v", got[0])
+	}
+	if got[1].Delta != " world" || got[1].Content != "Hello world" {
+		t.Fatalf("unexpected second delta: %+v", got[1])
+	}
+}
+
+type usageMockProvider struct{}
+
+func (m *usageMockProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	return &providers.LLMResponse{
+		Content: "ok",
+		Usage:   &providers.UsageInfo{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150},
+	}, nil
+}
+
+func (m *usageMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestProcessMessage_RecordsUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				ModelName:         "priced",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		ModelList: []config.ModelConfig{
+			{
+				ModelName: "priced",
+				Model:     "openai/priced-model",
+				Pricing:   &config.ModelPricing{Input: 1, Output: 2, Currency: "EUR"},
+			},
+		},
+	}
+
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &usageMockProvider{})
+	msg := bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "hello",
+		Peer:     bus.Peer{Kind: "direct", ID: "user1"},
+	}
+	helper := testHelper{al: al}
+	_ = helper.executeAndGetResponse(t, context.Background(), msg)
+
+	summary, err := al.usage.Summarize(usage.Filter{})
+	if err != nil {
+		t.Fatalf("Summarize() error: %v", err)
+	}
+	if summary.Total.Requests != 1 || summary.Total.TotalTokens != 150 {
+		t.Fatalf("Total = %+v, want 1 request / 150 tokens", summary.Total)
+	}
+	if got := summary.ByChannel["telegram"].Requests; got != 1 {
+		t.Fatalf("ByChannel[telegram].Requests = %d, want 1", got)
+	}
+	if summary.Total.Currency != "EUR" || summary.Total.Cost <= 0 {
+		t.Fatalf("expected priced usage in EUR, got %+v", summary.Total)
+	}
+
+	reply := helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "/usage agent",
+		Peer:     bus.Peer{Kind: "direct", ID: "user1"},
+	})
+	if !strings.Contains(reply, "150 tokens") {
+		t.Fatalf("/usage agent reply = %q", reply)
+	}
+}
+
+type modelRecordingProvider struct {
+	models []string
+}
+
+func (m *modelRecordingProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	m.models = append(m.models, model)
+	return &providers.LLMResponse{
+		Content: "ok",
+		Usage:   &providers.UsageInfo{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150},
+	}, nil
+}
+
+func (m *modelRecordingProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestProcessMessage_EnforcesBudgets(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				ModelName:         "primary",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				Routing:           &config.RoutingConfig{LightModel: "light"},
+			},
+			List: []config.AgentConfig{
+				{
+					ID:      "main",
+					Default: true,
+					Budgets: []config.BudgetConfig{
+						{
+							Scope:      config.BudgetScopeSender,
+							Period:     config.BudgetPeriodDaily,
+							SoftTokens: 100,
+							HardTokens: 300,
+							Message:    "budget exhausted",
+						},
+					},
+				},
+			},
+		},
+		ModelList: []config.ModelConfig{
+			{ModelName: "primary", Model: "openai/primary-model"},
+			{ModelName: "light", Model: "openai/light-model"},
+		},
+	}
+
+	provider := &modelRecordingProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	helper := testHelper{al: al}
+	send := func(sender string) string {
+		return helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+			Channel:  "telegram",
+			SenderID: sender,
+			ChatID:   "chat-" + sender,
+			Content:  "hello",
+			Peer:     bus.Peer{Kind: "direct", ID: sender},
+		})
+	}
+
+	send("user1") // 150 tokens: soft limit reached afterwards
+	send("user1") // downgraded, 300 tokens: hard limit reached afterwards
+	if reply := send("user1"); reply != "budget exhausted" {
+		t.Fatalf("reply at hard limit = %q, want refusal", reply)
+	}
+	if len(provider.models) != 2 {
+		t.Fatalf("provider called %d times, want 2 (no call at the hard limit)", len(provider.models))
+	}
+	if provider.models[0] == provider.models[1] || provider.models[1] != "light" {
+		t.Fatalf("models = %q, want primary then light", provider.models)
+	}
+
+	// Sender budgets are counted per sender.
+	if reply := send("user2"); reply != "ok" {
+		t.Fatalf("reply for another sender = %q, want ok", reply)
+	}
+}
+
+func TestProcessMessage_RecordsHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &usageMockProvider{})
+	helper := testHelper{al: al}
+	_ = helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "where did I park?",
+		Peer:     bus.Peer{Kind: "direct", ID: "user1"},
+	})
+
+	entries, err := al.history.Entries(history.Filter{Channel: "telegram"})
+	if err != nil {
+		t.Fatalf("Entries() error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected user and assistant entries, got %+v", entries)
+	}
+	if entries[0].Role != "user" || entries[0].Content != "where did I park?" || entries[0].SenderID != "user1" {
+		t.Errorf("user entry = %+v", entries[0])
+	}
+	if entries[1].Role != "assistant" || entries[1].Content != "ok" || entries[1].AgentID != "main" {
+		t.Errorf("assistant entry = %+v", entries[1])
+	}
+
+	// /clear forgets the session's indexed messages too.
+	_ = helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "/clear",
+		Peer:     bus.Peer{Kind: "direct", ID: "user1"},
+	})
+	entries, err = al.history.Entries(history.Filter{})
+	if err != nil {
+		t.Fatalf("Entries() error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("entries after /clear = %+v, want none", entries)
+	}
+}
+
+func TestNewAgentLoop_BackfillsHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sessions := session.NewSessionManager(filepath.Join(tmpDir, "sessions"))
+	sessions.AddMessage("agent:main:main", "user", "the wifi password is on the fridge")
+	sessions.AddFullMessage("agent:main:main", providers.Message{Role: "tool", Content: "tool output"})
+	sessions.AddMessage("agent:main:main", "assistant", "noted")
+	sessions.AddMessage("agent:helper:main", "user", "belongs to another agent")
+	for _, key := range []string{"agent:main:main", "agent:helper:main"} {
+		if err := sessions.Save(key); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &usageMockProvider{})
+	entries, err := al.history.Entries(history.Filter{})
+	if err != nil {
+		t.Fatalf("Entries() error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Content != "the wifi password is on the fridge" ||
+		entries[1].Role != "assistant" || entries[0].SessionKey != "agent:main:main" {
+		t.Fatalf("backfilled entries = %+v", entries)
+	}
+
+	// Sessions already in the index are not added twice.
+	al = NewAgentLoop(cfg, bus.NewMessageBus(), &usageMockProvider{})
+	if entries, _ := al.history.Entries(history.Filter{}); len(entries) != 2 {
+		t.Errorf("entries after second start = %d, want 2", len(entries))
+	}
+	if done := al.history.BackfilledSessions("main"); len(done) != 1 || !done["agent:main:main"] {
+		t.Errorf("backfilled sessions = %v, want agent:main:main", done)
+	}
+}
+
 func TestProcessMessage_CommandOutcomes(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "agent-test-*")
 	if err != nil {
@@ -1418,3 +1808,51 @@ func TestResolveMediaRefs_UsesMetaContentType(t *testing.T) {
 		t.Fatalf("expected jpeg prefix, got %q", result[0].Media[0][:30])
 	}
 }
+
+func TestExceedsContextWindow(t *testing.T) {
+	agent := &AgentInstance{
+		MaxTokens:    1000,
+		Capabilities: providers.ModelCapabilities{ContextWindow: 1_000_000},
+		Tokenizer:    tokenizer.Default(),
+	}
+	// The window is the called model's, here a light model much smaller than
+	// the primary one.
+	light := providers.ModelCapabilities{ContextWindow: 2000}
+	counter := tokenizer.Default()
+	short := []providers.Message{{Role: "user", Content: "hello"}}
+	if _, _, over := exceedsContextWindow(agent, counter, light, short, nil); over {
+		t.Fatal("a short request should fit")
+	}
+
+	// 500 reserved for the reply (a quarter of the window) leaves 1500.
+	long := []providers.Message{{Role: "user", Content: strings.Repeat("word ", 1600)}}
+	tokens, window, over := exceedsContextWindow(agent, counter, light, long, nil)
+	if !over || window != 2000 || tokens < 1600 {
+		t.Fatalf("exceedsContextWindow = %d, %d, %v; want over", tokens, window, over)
+	}
+
+	if _, _, over := exceedsContextWindow(agent, counter, providers.ModelCapabilities{}, long, nil); over {
+		t.Fatal("an unknown window should never be reported as exceeded")
+	}
+}
+
+func TestCandidateTokenizer(t *testing.T) {
+	candidates := []providers.FallbackCandidate{
+		{Provider: "openai", Model: "gpt-4"},
+		{Provider: "anthropic", Model: "claude-sonnet-4"},
+	}
+	text := "Hello world, how's it going?"
+	gpt := tokenizer.ForModel("gpt-4").Count(text)
+	claude := tokenizer.ForModel("claude-sonnet-4").Count(text)
+	if gpt == claude {
+		t.Fatal("test needs tokenizers that count differently")
+	}
+
+	if got := candidateTokenizer(candidates, "claude-sonnet-4").Count(text); got != claude {
+		t.Errorf("fallback candidate count = %d, want %d (its own tokenizer)", got, claude)
+	}
+	// A routing alias is served by the first candidate.
+	if got := candidateTokenizer(candidates, "light").Count(text); got != gpt {
+		t.Errorf("alias count = %d, want %d (first candidate's tokenizer)", got, gpt)
+	}
+}
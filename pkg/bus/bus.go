@@ -13,12 +13,28 @@ var ErrBusClosed = errors.New("message bus closed")
 
 const defaultBusBufferSize = 64
 
+// InboundInterceptor sees every inbound message before it is queued and
+// returns true when it consumed the message. It runs on the publisher's
+// goroutine, so it must not block. This lets replies reach code that is
+// waiting inside the agent loop, which consumes messages one at a time.
+type InboundInterceptor func(msg InboundMessage) bool
+
+// OutboundSpill receives the outbound messages still buffered when the bus
+// is closed, so they can be persisted instead of discarded.
+type OutboundSpill interface {
+	SpillOutbound(msg OutboundMessage)
+	SpillOutboundMedia(msg OutboundMediaMessage)
+}
+
 type MessageBus struct {
 	inbound       chan InboundMessage
 	outbound      chan OutboundMessage
 	outboundMedia chan OutboundMediaMessage
+	outboundDelta chan OutboundDeltaMessage
 	done          chan struct{}
 	closed        atomic.Bool
+	interceptor   atomic.Pointer[InboundInterceptor]
+	spill         atomic.Pointer[OutboundSpill]
 }
 
 func NewMessageBus() *MessageBus {
@@ -26,10 +42,30 @@ func NewMessageBus() *MessageBus {
 		inbound:       make(chan InboundMessage, defaultBusBufferSize),
 		outbound:      make(chan OutboundMessage, defaultBusBufferSize),
 		outboundMedia: make(chan OutboundMediaMessage, defaultBusBufferSize),
+		outboundDelta: make(chan OutboundDeltaMessage, defaultBusBufferSize),
 		done:          make(chan struct{}),
 	}
 }
 
+// SetOutboundSpill installs s to receive outbound messages drained by Close;
+// nil removes it.
+func (mb *MessageBus) SetOutboundSpill(s OutboundSpill) {
+	if s == nil {
+		mb.spill.Store(nil)
+		return
+	}
+	mb.spill.Store(&s)
+}
+
+// SetInboundInterceptor installs fn as the inbound interceptor; nil removes it.
+func (mb *MessageBus) SetInboundInterceptor(fn InboundInterceptor) {
+	if fn == nil {
+		mb.interceptor.Store(nil)
+		return
+	}
+	mb.interceptor.Store(&fn)
+}
+
 func (mb *MessageBus) PublishInbound(ctx context.Context, msg InboundMessage) error {
 	if mb.closed.Load() {
 		return ErrBusClosed
@@ -37,6 +73,9 @@ func (mb *MessageBus) PublishInbound(ctx context.Context, msg InboundMessage) er
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	if fn := mb.interceptor.Load(); fn != nil && (*fn)(msg) {
+		return nil
+	}
 	select {
 	case mb.inbound <- msg:
 		return nil
@@ -114,12 +153,44 @@ func (mb *MessageBus) SubscribeOutboundMedia(ctx context.Context) (OutboundMedia
 	}
 }
 
+// PublishOutboundDelta offers a streaming delta without blocking. Deltas are
+// best-effort: when nobody keeps up with the stream the delta is dropped,
+// which is harmless because each one carries the full text so far.
+func (mb *MessageBus) PublishOutboundDelta(ctx context.Context, msg OutboundDeltaMessage) error {
+	if mb.closed.Load() {
+		return ErrBusClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case mb.outboundDelta <- msg:
+	default:
+	}
+	return nil
+}
+
+func (mb *MessageBus) SubscribeOutboundDelta(ctx context.Context) (OutboundDeltaMessage, bool) {
+	select {
+	case msg, ok := <-mb.outboundDelta:
+		return msg, ok
+	case <-mb.done:
+		return OutboundDeltaMessage{}, false
+	case <-ctx.Done():
+		return OutboundDeltaMessage{}, false
+	}
+}
+
 func (mb *MessageBus) Close() {
 	if mb.closed.CompareAndSwap(false, true) {
 		close(mb.done)
 
 		// Drain buffered channels so messages aren't silently lost.
 		// Channels are NOT closed to avoid send-on-closed panics from concurrent publishers.
+		var spill OutboundSpill
+		if p := mb.spill.Load(); p != nil {
+			spill = *p
+		}
 		drained := 0
 		for {
 			select {
@@ -132,7 +203,10 @@ func (mb *MessageBus) Close() {
 	doneInbound:
 		for {
 			select {
-			case <-mb.outbound:
+			case msg := <-mb.outbound:
+				if spill != nil {
+					spill.SpillOutbound(msg)
+				}
 				drained++
 			default:
 				goto doneOutbound
@@ -141,13 +215,25 @@ func (mb *MessageBus) Close() {
 	doneOutbound:
 		for {
 			select {
-			case <-mb.outboundMedia:
+			case msg := <-mb.outboundMedia:
+				if spill != nil {
+					spill.SpillOutboundMedia(msg)
+				}
 				drained++
 			default:
 				goto doneMedia
 			}
 		}
 	doneMedia:
+		for {
+			select {
+			case <-mb.outboundDelta:
+				drained++
+			default:
+				goto doneDelta
+			}
+		}
+	doneDelta:
 		if drained > 0 {
 			logger.DebugCF("bus", "Drained buffered messages during close", map[string]any{
 				"count": drained,
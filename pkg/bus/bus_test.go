@@ -36,6 +36,40 @@ func TestPublishConsume(t *testing.T) {
 	}
 }
 
+func TestPublishInbound_Interceptor(t *testing.T) {
+	mb := NewMessageBus()
+	defer mb.Close()
+
+	ctx := context.Background()
+	var seen []string
+	mb.SetInboundInterceptor(func(msg InboundMessage) bool {
+		seen = append(seen, msg.Content)
+		return msg.Content == "/approve abc"
+	})
+
+	for _, content := range []string{"/approve abc", "hello"} {
+		if err := mb.PublishInbound(ctx, InboundMessage{Channel: "test", ChatID: "chat1", Content: content}); err != nil {
+			t.Fatalf("PublishInbound failed: %v", err)
+		}
+	}
+	if len(seen) != 2 {
+		t.Fatalf("interceptor saw %d messages, want 2", len(seen))
+	}
+
+	got, ok := mb.ConsumeInbound(ctx)
+	if !ok || got.Content != "hello" {
+		t.Fatalf("expected only 'hello' to be queued, got %q (ok=%v)", got.Content, ok)
+	}
+
+	mb.SetInboundInterceptor(nil)
+	if err := mb.PublishInbound(ctx, InboundMessage{Channel: "test", ChatID: "chat1", Content: "/approve abc"}); err != nil {
+		t.Fatalf("PublishInbound failed: %v", err)
+	}
+	if got, _ := mb.ConsumeInbound(ctx); got.Content != "/approve abc" {
+		t.Fatalf("expected message to be queued after removing interceptor, got %q", got.Content)
+	}
+}
+
 func TestPublishOutboundSubscribe(t *testing.T) {
 	mb := NewMessageBus()
 	defer mb.Close()
@@ -227,3 +261,58 @@ func TestCloseIdempotent(t *testing.T) {
 		t.Fatalf("expected ErrBusClosed after multiple closes, got %v", err)
 	}
 }
+
+type recordingSpill struct {
+	msgs  []OutboundMessage
+	media []OutboundMediaMessage
+}
+
+func (s *recordingSpill) SpillOutbound(msg OutboundMessage)           { s.msgs = append(s.msgs, msg) }
+func (s *recordingSpill) SpillOutboundMedia(msg OutboundMediaMessage) { s.media = append(s.media, msg) }
+
+func TestClose_SpillsBufferedOutbound(t *testing.T) {
+	mb := NewMessageBus()
+	spill := &recordingSpill{}
+	mb.SetOutboundSpill(spill)
+
+	ctx := context.Background()
+	mb.PublishOutbound(ctx, OutboundMessage{ChatID: "1", Content: "a"})
+	mb.PublishOutbound(ctx, OutboundMessage{ChatID: "1", Content: "b"})
+	mb.PublishOutboundMedia(ctx, OutboundMediaMessage{ChatID: "2"})
+	mb.PublishInbound(ctx, InboundMessage{Content: "ignored"})
+	mb.Close()
+
+	if len(spill.msgs) != 2 || spill.msgs[0].Content != "a" || spill.msgs[1].Content != "b" {
+		t.Fatalf("unexpected spilled messages: %+v", spill.msgs)
+	}
+	if len(spill.media) != 1 || spill.media[0].ChatID != "2" {
+		t.Fatalf("unexpected spilled media: %+v", spill.media)
+	}
+}
+
+func TestPublishOutboundDelta_DropsWhenFull(t *testing.T) {
+	mb := NewMessageBus()
+	defer mb.Close()
+
+	ctx := context.Background()
+
+	// Publishing past the buffer size must never block.
+	for i := range defaultBusBufferSize + 8 {
+		if err := mb.PublishOutboundDelta(ctx, OutboundDeltaMessage{Content: "x"}); err != nil {
+			t.Fatalf("PublishOutboundDelta failed at %d: %v", i, err)
+		}
+	}
+
+	got, ok := mb.SubscribeOutboundDelta(ctx)
+	if !ok {
+		t.Fatal("SubscribeOutboundDelta returned ok=false")
+	}
+	if got.Content != "x" {
+		t.Fatalf("expected content 'x', got %q", got.Content)
+	}
+
+	mb.Close()
+	if err := mb.PublishOutboundDelta(ctx, OutboundDeltaMessage{}); err != ErrBusClosed {
+		t.Fatalf("expected ErrBusClosed, got %v", err)
+	}
+}
@@ -77,6 +77,7 @@ type SenderInfo struct {
 
 type InboundMessage struct {
 	Channel          string            `json:"channel"`
+	AccountID        string            `json:"account_id,omitempty"` // channel account; empty for the default one
 	SenderID         string            `json:"sender_id"`
 	Sender           SenderInfo        `json:"sender"`
 	ChatID           string            `json:"chat_id"`
@@ -89,14 +90,93 @@ type InboundMessage struct {
 	Peer             Peer              `json:"peer"`                  // routing peer
 	MessageID        string            `json:"message_id,omitempty"`  // platform message ID
 	MediaScope       string            `json:"media_scope,omitempty"` // media lifecycle scope
+	ThreadID         string            `json:"thread_id,omitempty"`   // platform thread the message belongs to
+	ReplyTo          *QuotedMessage    `json:"reply_to,omitempty"`    // message being replied to, if any
 	SessionKey       string            `json:"session_key"`
 	Metadata         map[string]string `json:"metadata,omitempty"`
 }
 
+// QuotedMessage is the parent of an inbound reply as far as the platform
+// exposes it. Content may be empty when the parent could not be fetched.
+type QuotedMessage struct {
+	MessageID  string `json:"message_id,omitempty"`
+	SenderID   string `json:"sender_id,omitempty"`
+	SenderName string `json:"sender_name,omitempty"`
+	Content    string `json:"content,omitempty"`
+}
+
 type OutboundMessage struct {
-	Channel string `json:"channel"`
-	ChatID  string `json:"chat_id"`
-	Content string `json:"content"`
+	Channel          string       `json:"channel"`
+	AccountID        string       `json:"account_id,omitempty"`
+	ChatID           string       `json:"chat_id"`
+	Content          string       `json:"content"`
+	ReplyToMessageID string       `json:"reply_to_message_id,omitempty"` // platform message to reply to
+	ThreadID         string       `json:"thread_id,omitempty"`           // platform thread to post in
+	Interactive      *Interactive `json:"interactive,omitempty"`         // optional buttons/select/card
+}
+
+// Interactive describes structured controls attached to an outbound message.
+// Channels that implement channels.InteractiveCapable render them natively;
+// for all others the Manager folds them into a numbered text list.
+type Interactive struct {
+	Card    *Card    `json:"card,omitempty"`
+	Buttons []Button `json:"buttons,omitempty"`
+	Select  *Select  `json:"select,omitempty"`
+}
+
+// Card is a simple header shown above the message body.
+type Card struct {
+	Title    string `json:"title,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// Button is a clickable action. Clicking it sends Value (or Label when Value
+// is empty) back to the agent as an inbound message. Buttons with a URL open
+// the link instead and never call back.
+type Button struct {
+	Label string `json:"label"`
+	Value string `json:"value,omitempty"`
+	Style string `json:"style,omitempty"` // "primary" | "danger" | ""
+	URL   string `json:"url,omitempty"`
+}
+
+// Select is a single-choice menu; the chosen option's Value is sent back.
+type Select struct {
+	Placeholder string         `json:"placeholder,omitempty"`
+	Options     []SelectOption `json:"options"`
+}
+
+type SelectOption struct {
+	Label string `json:"label"`
+	Value string `json:"value,omitempty"`
+}
+
+// CallbackValue returns the content delivered when the button is clicked.
+func (b Button) CallbackValue() string {
+	if b.Value != "" {
+		return b.Value
+	}
+	return b.Label
+}
+
+// CallbackValue returns the content delivered when the option is chosen.
+func (o SelectOption) CallbackValue() string {
+	if o.Value != "" {
+		return o.Value
+	}
+	return o.Label
+}
+
+// OutboundDeltaMessage carries a partial assistant reply while the LLM is
+// still generating it. Content is the full text produced so far for the
+// current response, so a dropped delta never corrupts what channels display;
+// the final OutboundMessage always follows and supersedes it.
+type OutboundDeltaMessage struct {
+	Channel   string `json:"channel"`
+	AccountID string `json:"account_id,omitempty"`
+	ChatID    string `json:"chat_id"`
+	Delta     string `json:"delta"`
+	Content   string `json:"content"`
 }
 
 // MediaPart describes a single media attachment to send.
@@ -110,7 +190,8 @@ type MediaPart struct {
 
 // OutboundMediaMessage carries media attachments from Agent to channels via the bus.
 type OutboundMediaMessage struct {
-	Channel string      `json:"channel"`
-	ChatID  string      `json:"chat_id"`
-	Parts   []MediaPart `json:"parts"`
+	Channel   string      `json:"channel"`
+	AccountID string      `json:"account_id,omitempty"`
+	ChatID    string      `json:"chat_id"`
+	Parts     []MediaPart `json:"parts"`
 }
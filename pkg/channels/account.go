@@ -0,0 +1,24 @@
+package channels
+
+import (
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/routing"
+)
+
+// ChannelKey identifies a channel instance in the Manager. The default account
+// of a channel is keyed by the channel name alone, other accounts by
+// "<name>:<account>".
+func ChannelKey(name, accountID string) string {
+	if accountID == "" || routing.NormalizeAccountID(accountID) == routing.DefaultAccountID {
+		return name
+	}
+	return name + ":" + accountID
+}
+
+// ParseChannelKey splits a key built by ChannelKey into channel name and
+// account ID ("" for the default account).
+func ParseChannelKey(key string) (name, accountID string) {
+	name, accountID, _ = strings.Cut(key, ":")
+	return name, accountID
+}
@@ -0,0 +1,118 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestChannelKey(t *testing.T) {
+	tests := []struct {
+		name, accountID, want string
+	}{
+		{"telegram", "", "telegram"},
+		{"telegram", "default", "telegram"},
+		{"telegram", "team-a", "telegram:team-a"},
+	}
+	for _, tt := range tests {
+		key := ChannelKey(tt.name, tt.accountID)
+		if key != tt.want {
+			t.Errorf("ChannelKey(%q, %q) = %q, want %q", tt.name, tt.accountID, key, tt.want)
+		}
+		name, accountID := ParseChannelKey(key)
+		if name != tt.name {
+			t.Errorf("ParseChannelKey(%q) name = %q, want %q", key, name, tt.name)
+		}
+		if key != tt.name && accountID != tt.accountID {
+			t.Errorf("ParseChannelKey(%q) account = %q, want %q", key, accountID, tt.accountID)
+		}
+	}
+}
+
+func TestManagerCreatesOneChannelPerAccount(t *testing.T) {
+	tokens := make(map[string]string)
+	RegisterFactory("telegram", func(cfg *config.Config, b *bus.MessageBus) (Channel, error) {
+		ch := &mockChannel{BaseChannel: *NewBaseChannel("telegram", nil, b, nil)}
+		tokens[cfg.Channels.Telegram.Token] = cfg.Channels.Telegram.Proxy
+		return ch, nil
+	})
+	defer func() {
+		factoriesMu.Lock()
+		delete(factories, "telegram")
+		factoriesMu.Unlock()
+	}()
+
+	cfg := config.DefaultConfig()
+	err := json.Unmarshal([]byte(`{
+		"enabled": true,
+		"token": "main-token",
+		"proxy": "http://proxy",
+		"accounts": [
+			{"id": "Team-A", "token": "team-token"},
+			{"id": "default", "token": "ignored"},
+			{"id": "team-a", "token": "duplicate"}
+		]
+	}`), &cfg.Channels.Telegram)
+	if err != nil {
+		t.Fatalf("unmarshal telegram config: %v", err)
+	}
+
+	m, err := NewManager(cfg, bus.NewMessageBus(), nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if _, ok := m.GetChannel("telegram"); !ok {
+		t.Error("default account channel missing")
+	}
+	ch, ok := m.GetChannel("telegram:team-a")
+	if !ok {
+		t.Fatalf("account channel missing; channels = %v", m.GetEnabledChannels())
+	}
+	if len(m.GetEnabledChannels()) != 2 {
+		t.Errorf("channels = %v, want 2", m.GetEnabledChannels())
+	}
+	if got := ch.(*mockChannel).AccountID(); got != "team-a" {
+		t.Errorf("AccountID() = %q, want team-a", got)
+	}
+	if tokens["team-token"] != "http://proxy" {
+		t.Errorf("account did not inherit channel settings: %v", tokens)
+	}
+	if _, ok := tokens["ignored"]; ok {
+		t.Error("reserved default account id was instantiated")
+	}
+}
+
+func TestDispatchOutboundRoutesByAccount(t *testing.T) {
+	m := newTestManager()
+	m.bus = bus.NewMessageBus()
+	sent := make(chan string, 2)
+	for _, key := range []string{"telegram", "telegram:team-a"} {
+		key := key
+		ch := &mockChannel{sendFn: func(_ context.Context, _ bus.OutboundMessage) error {
+			sent <- key
+			return nil
+		}}
+		ch.running.Store(true)
+		m.channels[key] = ch
+		m.workers[key] = newChannelWorker(key, ch)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for key, w := range m.workers {
+		go m.runWorker(ctx, key, w)
+	}
+	go m.dispatchOutbound(ctx)
+
+	if err := m.bus.PublishOutbound(ctx, bus.OutboundMessage{
+		Channel: "telegram", AccountID: "team-a", ChatID: "1", Content: "hi",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-sent; got != "telegram:team-a" {
+		t.Errorf("message delivered to %q, want telegram:team-a", got)
+	}
+}
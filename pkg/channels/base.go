@@ -93,6 +93,7 @@ type BaseChannel struct {
 	bus                 *bus.MessageBus
 	running             atomic.Bool
 	name                string
+	accountID           string
 	allowList           []string
 	maxMessageLength    int
 	groupTrigger        config.GroupTriggerConfig
@@ -100,6 +101,7 @@ type BaseChannel struct {
 	placeholderRecorder PlaceholderRecorder
 	owner               Channel // the concrete channel that embeds this BaseChannel
 	reasoningChannelID  string
+	inboundGuard        *InboundGuard
 	recentMsgIDs        sync.Map // message_id -> time.Time
 	dedupeCount         atomic.Int64
 }
@@ -244,6 +246,27 @@ func (c *BaseChannel) HandleMessage(
 	media []string,
 	metadata map[string]string,
 	senderOpts ...bus.SenderInfo,
+) {
+	c.HandleThreadedMessage(ctx, peer, ThreadInfo{}, messageID, senderID, chatID, content, media, metadata, senderOpts...)
+}
+
+// ThreadInfo places an inbound message in its conversation: the platform
+// thread it was posted in and the message it replies to.
+type ThreadInfo struct {
+	ThreadID string
+	ReplyTo  *bus.QuotedMessage
+}
+
+// HandleThreadedMessage is HandleMessage for channels that know which thread
+// a message belongs to or which message it quotes.
+func (c *BaseChannel) HandleThreadedMessage(
+	ctx context.Context,
+	peer bus.Peer,
+	thread ThreadInfo,
+	messageID, senderID, chatID, content string,
+	media []string,
+	metadata map[string]string,
+	senderOpts ...bus.SenderInfo,
 ) {
 	// Use SenderInfo-based allow check when available, else fall back to string
 	var sender bus.SenderInfo
@@ -270,6 +293,10 @@ func (c *BaseChannel) HandleMessage(
 		resolvedSenderID = sender.CanonicalID
 	}
 
+	if !c.admitInbound(ctx, chatID, resolvedSenderID, messageID, thread.ThreadID, content) {
+		return
+	}
+
 	scope := BuildMediaScope(c.name, chatID, messageID)
 
 	processableMediaPaths := c.resolveProcessableMediaPaths(media)
@@ -279,6 +306,7 @@ func (c *BaseChannel) HandleMessage(
 
 	msg := bus.InboundMessage{
 		Channel:          c.name,
+		AccountID:        c.accountID,
 		SenderID:         resolvedSenderID,
 		Sender:           sender,
 		ChatID:           chatID,
@@ -290,6 +318,8 @@ func (c *BaseChannel) HandleMessage(
 		Peer:             peer,
 		MessageID:        messageID,
 		MediaScope:       scope,
+		ThreadID:         thread.ThreadID,
+		ReplyTo:          thread.ReplyTo,
 		Metadata:         metadata,
 	}
 
@@ -299,19 +329,19 @@ func (c *BaseChannel) HandleMessage(
 		// Typing — independent pipeline
 		if tc, ok := c.owner.(TypingCapable); ok {
 			if stop, err := tc.StartTyping(ctx, chatID); err == nil {
-				c.placeholderRecorder.RecordTypingStop(c.name, chatID, stop)
+				c.placeholderRecorder.RecordTypingStop(ChannelKey(c.name, c.accountID), chatID, stop)
 			}
 		}
 		// Reaction — independent pipeline
 		if rc, ok := c.owner.(ReactionCapable); ok && messageID != "" {
 			if undo, err := rc.ReactToMessage(ctx, chatID, messageID); err == nil {
-				c.placeholderRecorder.RecordReactionUndo(c.name, chatID, undo)
+				c.placeholderRecorder.RecordReactionUndo(ChannelKey(c.name, c.accountID), chatID, undo)
 			}
 		}
 		// Placeholder — independent pipeline
 		if pc, ok := c.owner.(PlaceholderCapable); ok {
 			if phID, err := pc.SendPlaceholder(ctx, chatID); err == nil && phID != "" {
-				c.placeholderRecorder.RecordPlaceholder(c.name, chatID, phID)
+				c.placeholderRecorder.RecordPlaceholder(ChannelKey(c.name, c.accountID), chatID, phID)
 			}
 		}
 	}
@@ -361,6 +391,10 @@ func (c *BaseChannel) HandleMessageWithFileRefs(
 		resolvedSenderID = sender.CanonicalID
 	}
 
+	if !c.admitInbound(ctx, chatID, resolvedSenderID, messageID, "", content) {
+		return
+	}
+
 	scope := BuildMediaScope(c.name, chatID, messageID)
 
 	processableMediaPaths := c.resolveProcessableMediaPaths(media)
@@ -370,6 +404,7 @@ func (c *BaseChannel) HandleMessageWithFileRefs(
 
 	msg := bus.InboundMessage{
 		Channel:          c.name,
+		AccountID:        c.accountID,
 		SenderID:         resolvedSenderID,
 		Sender:           sender,
 		ChatID:           chatID,
@@ -479,6 +514,17 @@ func (c *BaseChannel) GetPlaceholderRecorder() PlaceholderRecorder {
 	return c.placeholderRecorder
 }
 
+// SetAccountID marks the channel as serving a named account of its platform.
+// Inbound messages carry the account ID so replies go back through the
+// same account.
+func (c *BaseChannel) SetAccountID(accountID string) { c.accountID = accountID }
+
+// AccountID returns the account this channel serves ("" for the default one).
+func (c *BaseChannel) AccountID() string { return c.accountID }
+
+// SetInboundGuard injects the rate limiter shared by all channels.
+func (c *BaseChannel) SetInboundGuard(g *InboundGuard) { c.inboundGuard = g }
+
 // SetOwner injects the concrete channel that embeds this BaseChannel.
 // This allows HandleMessage to auto-trigger TypingCapable / ReactionCapable / PlaceholderCapable.
 func (c *BaseChannel) SetOwner(ch Channel) {
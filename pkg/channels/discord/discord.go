@@ -42,6 +42,7 @@ type DiscordChannel struct {
 	typingMu   sync.Mutex
 	typingStop map[string]chan struct{} // chatID → stop signal
 	botUserID  string                   // stored for mention checking
+	callbacks  channels.CallbackRegistry
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordChannel, error) {
@@ -81,6 +82,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 	c.botUserID = botUser.ID
 
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(c.handleInteraction)
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -125,7 +127,7 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return channels.ErrNotRunning
 	}
 
-	channelID := msg.ChatID
+	channelID := targetChannel(msg)
 	if channelID == "" {
 		return fmt.Errorf("channel ID is empty")
 	}
@@ -134,7 +136,30 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return nil
 	}
 
-	return c.sendChunk(ctx, channelID, msg.Content)
+	return c.sendChunk(ctx, channelID, msg.Content, replyReference(channelID, msg.ReplyToMessageID))
+}
+
+// targetChannel returns the channel msg is posted in. Discord threads are
+// channels of their own, so a ThreadID takes precedence over the chat.
+func targetChannel(msg bus.OutboundMessage) string {
+	if msg.ThreadID != "" {
+		return msg.ThreadID
+	}
+	return msg.ChatID
+}
+
+// replyReference quotes messageID, or returns nil when there is nothing to
+// reply to. A reply to a deleted message is sent as a plain message.
+func replyReference(channelID, messageID string) *discordgo.MessageReference {
+	if messageID == "" {
+		return nil
+	}
+	failIfNotExists := false
+	return &discordgo.MessageReference{
+		MessageID:       messageID,
+		ChannelID:       channelID,
+		FailIfNotExists: &failIfNotExists,
+	}
 }
 
 // SendMedia implements the channels.MediaSender interface.
@@ -238,6 +263,11 @@ func (c *DiscordChannel) EditMessage(ctx context.Context, chatID string, message
 	return err
 }
 
+// DeleteMessage implements channels.MessageDeleter.
+func (c *DiscordChannel) DeleteMessage(ctx context.Context, chatID string, messageID string) error {
+	return c.session.ChannelMessageDelete(chatID, messageID)
+}
+
 // SendPlaceholder implements channels.PlaceholderCapable.
 // It sends a placeholder message that will later be edited to the actual
 // response via EditMessage (channels.MessageEditor).
@@ -259,14 +289,21 @@ func (c *DiscordChannel) SendPlaceholder(ctx context.Context, chatID string) (st
 	return msg.ID, nil
 }
 
-func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string) error {
+func (c *DiscordChannel) sendChunk(
+	ctx context.Context,
+	channelID, content string,
+	reference *discordgo.MessageReference,
+) error {
 	// Use the passed ctx for timeout control
 	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
 	defer cancel()
 
 	done := make(chan error, 1)
 	go func() {
-		_, err := c.session.ChannelMessageSend(channelID, content)
+		_, err := c.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content:   content,
+			Reference: reference,
+		})
 		done <- err
 	}()
 
@@ -349,18 +386,9 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 	// double-expanding links that appear in the referenced message.
 	content = c.resolveDiscordRefs(s, content, m.GuildID)
 
-	// Prepend referenced (quoted) message content if this is a reply
-	if m.MessageReference != nil && m.ReferencedMessage != nil {
-		refContent := m.ReferencedMessage.Content
-		if refContent != "" {
-			refAuthor := "unknown"
-			if m.ReferencedMessage.Author != nil {
-				refAuthor = m.ReferencedMessage.Author.Username
-			}
-			refContent = c.resolveDiscordRefs(s, refContent, m.GuildID)
-			content = fmt.Sprintf("[quoted message from %s]: %s\n\n%s",
-				refAuthor, refContent, content)
-		}
+	thread := channels.ThreadInfo{ReplyTo: c.quotedMessage(s, m)}
+	if ch, err := s.State.Channel(m.ChannelID); err == nil && ch.IsThread() {
+		thread.ThreadID = m.ChannelID
 	}
 
 	senderID := m.Author.ID
@@ -436,7 +464,25 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		"is_dm":        fmt.Sprintf("%t", m.GuildID == ""),
 	}
 
-	c.HandleMessage(c.ctx, peer, m.ID, senderID, m.ChannelID, content, mediaPaths, metadata, sender)
+	c.HandleThreadedMessage(c.ctx, peer, thread, m.ID, senderID, m.ChannelID, content, mediaPaths, metadata, sender)
+}
+
+// quotedMessage returns the message m replies to, or nil when m is not a
+// reply or Discord did not resolve the referenced message.
+func (c *DiscordChannel) quotedMessage(s *discordgo.Session, m *discordgo.MessageCreate) *bus.QuotedMessage {
+	if m.MessageReference == nil || m.ReferencedMessage == nil {
+		return nil
+	}
+	ref := m.ReferencedMessage
+	quoted := &bus.QuotedMessage{
+		MessageID: ref.ID,
+		Content:   c.resolveDiscordRefs(s, ref.Content, m.GuildID),
+	}
+	if ref.Author != nil {
+		quoted.SenderID = ref.Author.ID
+		quoted.SenderName = ref.Author.Username
+	}
+	return quoted
 }
 
 // startTyping starts a continuous typing indicator loop for the given chatID.
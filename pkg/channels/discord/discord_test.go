@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/bwmarrin/discordgo"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
 )
 
 func TestApplyDiscordProxy_CustomProxy(t *testing.T) {
@@ -89,3 +91,41 @@ func TestApplyDiscordProxy_InvalidProxyURL(t *testing.T) {
 		t.Fatal("applyDiscordProxy() expected error for invalid proxy URL, got nil")
 	}
 }
+
+func TestQuotedMessageAndReplyTarget(t *testing.T) {
+	c := &DiscordChannel{}
+	s := &discordgo.Session{State: discordgo.NewState()}
+
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:               "m2",
+		ChannelID:        "c1",
+		MessageReference: &discordgo.MessageReference{MessageID: "m1"},
+		ReferencedMessage: &discordgo.Message{
+			ID:      "m1",
+			Content: "ship it",
+			Author:  &discordgo.User{ID: "u1", Username: "alice"},
+		},
+	}}
+	quoted := c.quotedMessage(s, m)
+	if quoted == nil || *quoted != (bus.QuotedMessage{
+		MessageID: "m1", SenderID: "u1", SenderName: "alice", Content: "ship it",
+	}) {
+		t.Errorf("quoted = %+v", quoted)
+	}
+	m.MessageReference = nil
+	if c.quotedMessage(s, m) != nil {
+		t.Error("expected no quote for a plain message")
+	}
+
+	msg := bus.OutboundMessage{ChatID: "c1", ThreadID: "t1", ReplyToMessageID: "m2"}
+	if got := targetChannel(msg); got != "t1" {
+		t.Errorf("targetChannel = %q, want thread", got)
+	}
+	ref := replyReference("t1", msg.ReplyToMessageID)
+	if ref == nil || ref.MessageID != "m2" || ref.ChannelID != "t1" || *ref.FailIfNotExists {
+		t.Errorf("reference = %+v", ref)
+	}
+	if replyReference("c1", "") != nil {
+		t.Error("expected no reference without a reply target")
+	}
+}
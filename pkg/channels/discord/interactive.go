@@ -0,0 +1,189 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// Discord limits custom_id and select option values to 100 characters.
+	maxCustomID = 100
+
+	buttonIDPrefix = "picoclaw:b:"
+	selectCustomID = "picoclaw:s"
+
+	// Discord allows five action rows of five buttons each; one row is kept
+	// for the select menu.
+	maxButtonsPerRow = 5
+	maxButtonRows    = 4
+)
+
+// SendInteractive implements channels.InteractiveCapable with message
+// components; the card becomes an embed.
+func (c *DiscordChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	channelID := targetChannel(msg)
+	if channelID == "" {
+		return fmt.Errorf("channel ID is empty")
+	}
+
+	data := c.buildInteractiveMessage(msg.Content, msg.Interactive)
+	data.Reference = replyReference(channelID, msg.ReplyToMessageID)
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.session.ChannelMessageSendComplex(channelID, data)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("discord send: %w", channels.ErrTemporary)
+		}
+		return nil
+	case <-sendCtx.Done():
+		return sendCtx.Err()
+	}
+}
+
+func (c *DiscordChannel) buildInteractiveMessage(content string, in *bus.Interactive) *discordgo.MessageSend {
+	data := &discordgo.MessageSend{Content: content}
+
+	if in.Card != nil && (in.Card.Title != "" || in.Card.ImageURL != "") {
+		embed := &discordgo.MessageEmbed{Title: in.Card.Title}
+		if in.Card.ImageURL != "" {
+			embed.Image = &discordgo.MessageEmbedImage{URL: in.Card.ImageURL}
+		}
+		data.Embeds = []*discordgo.MessageEmbed{embed}
+	}
+
+	var row []discordgo.MessageComponent
+	for _, b := range in.Buttons {
+		if len(data.Components) == maxButtonRows {
+			break
+		}
+		btn := discordgo.Button{Label: b.Label}
+		switch {
+		case b.URL != "":
+			btn.Style = discordgo.LinkButton
+			btn.URL = b.URL
+		case b.Style == "danger":
+			btn.Style = discordgo.DangerButton
+		case b.Style == "primary":
+			btn.Style = discordgo.PrimaryButton
+		default:
+			btn.Style = discordgo.SecondaryButton
+		}
+		if b.URL == "" {
+			btn.CustomID = buttonIDPrefix + c.callbacks.Encode(b.CallbackValue(), maxCustomID-len(buttonIDPrefix))
+		}
+		row = append(row, btn)
+		if len(row) == maxButtonsPerRow {
+			data.Components = append(data.Components, discordgo.ActionsRow{Components: row})
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		data.Components = append(data.Components, discordgo.ActionsRow{Components: row})
+	}
+
+	if in.Select != nil && len(in.Select.Options) > 0 {
+		menu := discordgo.SelectMenu{
+			MenuType:    discordgo.StringSelectMenu,
+			CustomID:    selectCustomID,
+			Placeholder: in.Select.Placeholder,
+		}
+		for _, o := range in.Select.Options {
+			menu.Options = append(menu.Options, discordgo.SelectMenuOption{
+				Label: o.Label,
+				Value: c.callbacks.Encode(o.CallbackValue(), maxCustomID),
+			})
+		}
+		data.Components = append(data.Components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{menu},
+		})
+	}
+
+	return data
+}
+
+// handleInteraction turns a button click or select choice into an inbound
+// message. The interaction is acknowledged without changing the message.
+func (c *DiscordChannel) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i == nil || i.Interaction == nil || i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	data := i.MessageComponentData()
+	var kind, value string
+	switch {
+	case strings.HasPrefix(data.CustomID, buttonIDPrefix):
+		kind = channels.InteractionButton
+		value = c.callbacks.Decode(strings.TrimPrefix(data.CustomID, buttonIDPrefix))
+	case data.CustomID == selectCustomID && len(data.Values) > 0:
+		kind = channels.InteractionSelect
+		value = c.callbacks.Decode(data.Values[0])
+	default:
+		return // someone else's component
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		logger.DebugCF("discord", "Failed to acknowledge interaction", map[string]any{"error": err.Error()})
+	}
+	if value == "" {
+		return
+	}
+
+	user := i.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "discord",
+		PlatformID:  user.ID,
+		CanonicalID: identity.BuildCanonicalID("discord", user.ID),
+		Username:    user.Username,
+		DisplayName: user.Username,
+	}
+
+	peer := bus.Peer{Kind: "channel", ID: i.ChannelID}
+	if i.GuildID == "" {
+		peer = bus.Peer{Kind: "direct", ID: user.ID}
+	}
+
+	var originID string
+	if i.Message != nil {
+		originID = i.Message.ID
+	}
+
+	metadata := map[string]string{
+		"user_id":      user.ID,
+		"username":     user.Username,
+		"display_name": sender.DisplayName,
+		"guild_id":     i.GuildID,
+		"channel_id":   i.ChannelID,
+		"is_dm":        fmt.Sprintf("%t", i.GuildID == ""),
+	}
+
+	c.HandleInteraction(c.ctx, peer, user.ID, i.ChannelID, originID, kind, value, metadata, sender)
+}
@@ -0,0 +1,61 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestBuildInteractiveMessage(t *testing.T) {
+	c := &DiscordChannel{}
+	long := strings.Repeat("v", 150)
+	data := c.buildInteractiveMessage("Deploy?", &bus.Interactive{
+		Card: &bus.Card{Title: "Release", ImageURL: "https://example.com/a.png"},
+		Buttons: []bus.Button{
+			{Label: "Yes", Value: "yes", Style: "primary"},
+			{Label: "No", Style: "danger"},
+			{Label: "Docs", URL: "https://example.com"},
+			{Label: "Long", Value: long},
+		},
+		Select: &bus.Select{Placeholder: "Env", Options: []bus.SelectOption{{Label: "staging"}}},
+	})
+
+	if data.Content != "Deploy?" || len(data.Embeds) != 1 || data.Embeds[0].Title != "Release" ||
+		data.Embeds[0].Image == nil || data.Embeds[0].Image.URL != "https://example.com/a.png" {
+		t.Fatalf("unexpected content/embed: %+v", data)
+	}
+	if len(data.Components) != 2 {
+		t.Fatalf("components = %d, want 2 rows", len(data.Components))
+	}
+
+	buttons := data.Components[0].(discordgo.ActionsRow).Components
+	if len(buttons) != 4 {
+		t.Fatalf("buttons = %d, want 4", len(buttons))
+	}
+	yes := buttons[0].(discordgo.Button)
+	if yes.Style != discordgo.PrimaryButton || yes.CustomID != buttonIDPrefix+"yes" {
+		t.Errorf("yes button = %+v", yes)
+	}
+	if no := buttons[1].(discordgo.Button); no.Style != discordgo.DangerButton || no.CustomID != buttonIDPrefix+"No" {
+		t.Errorf("no button = %+v", no)
+	}
+	if docs := buttons[2].(discordgo.Button); docs.Style != discordgo.LinkButton || docs.CustomID != "" {
+		t.Errorf("link button = %+v", docs)
+	}
+	longBtn := buttons[3].(discordgo.Button)
+	if len(longBtn.CustomID) > maxCustomID {
+		t.Errorf("custom_id too long: %d", len(longBtn.CustomID))
+	}
+	if got := c.callbacks.Decode(strings.TrimPrefix(longBtn.CustomID, buttonIDPrefix)); got != long {
+		t.Errorf("decoded long value = %q", got)
+	}
+
+	menu := data.Components[1].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+	if menu.CustomID != selectCustomID || menu.Placeholder != "Env" || len(menu.Options) != 1 ||
+		menu.Options[0].Value != "staging" {
+		t.Errorf("select menu = %+v", menu)
+	}
+}
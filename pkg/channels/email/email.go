@@ -0,0 +1,545 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	defaultMailbox      = "INBOX"
+	defaultPollInterval = 60 * time.Second
+	// defaultMaxMessageSize bounds the raw size of a fetched message.
+	defaultMaxMessageSize = 25 << 20
+	// idleRefresh re-issues IDLE before servers drop it (RFC 2177 suggests 29 minutes).
+	idleRefresh = 25 * time.Minute
+)
+
+// emailThread remembers what is needed to reply into a conversation.
+type emailThread struct {
+	to         string
+	subject    string
+	lastID     string
+	references []string
+	quoted     string
+	quotedFrom string
+	quotedDate string
+}
+
+// EmailChannel reads mail from an IMAP mailbox and replies over SMTP. Each
+// thread (the root Message-ID of a conversation) is its own chat.
+type EmailChannel struct {
+	*channels.BaseChannel
+	config       config.EmailConfig
+	address      string
+	pollInterval time.Duration
+
+	mu       sync.Mutex
+	threads  map[string]*emailThread // chat ID -> thread
+	messages map[string]string       // Message-ID -> chat ID
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewEmailChannel creates a new email channel.
+func NewEmailChannel(cfg config.EmailConfig, messageBus *bus.MessageBus) (*EmailChannel, error) {
+	if cfg.IMAPServer == "" {
+		return nil, fmt.Errorf("email imap_server is required")
+	}
+	if cfg.SMTPServer == "" {
+		return nil, fmt.Errorf("email smtp_server is required")
+	}
+	addr, err := mail.ParseAddress(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("email address %q is invalid: %w", cfg.Address, err)
+	}
+	if cfg.Username == "" {
+		cfg.Username = addr.Address
+	}
+	if cfg.Mailbox == "" {
+		cfg.Mailbox = defaultMailbox
+	}
+	pollInterval := time.Duration(cfg.PollInterval) * time.Second
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	// Addresses are case-insensitive; senders are matched in lower case.
+	allowFrom := make([]string, 0, len(cfg.AllowFrom))
+	for _, entry := range cfg.AllowFrom {
+		allowFrom = append(allowFrom, strings.ToLower(strings.TrimSpace(entry)))
+	}
+
+	base := channels.NewBaseChannel("email", cfg, messageBus, allowFrom,
+		channels.WithReasoningChannelID(cfg.ReasoningChannelID),
+	)
+
+	return &EmailChannel{
+		BaseChannel:  base,
+		config:       cfg,
+		address:      strings.ToLower(addr.Address),
+		pollInterval: pollInterval,
+		threads:      make(map[string]*emailThread),
+		messages:     make(map[string]string),
+	}, nil
+}
+
+// Start begins watching the mailbox in the background.
+func (c *EmailChannel) Start(ctx context.Context) error {
+	logger.InfoC("email", "Starting email channel")
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+
+	go c.watch()
+
+	c.SetRunning(true)
+	logger.InfoCF("email", "Email channel started", map[string]any{
+		"imap_server": c.config.IMAPServer,
+		"mailbox":     c.config.Mailbox,
+		"address":     c.address,
+	})
+	return nil
+}
+
+// Stop stops watching the mailbox.
+func (c *EmailChannel) Stop(ctx context.Context) error {
+	logger.InfoC("email", "Stopping email channel")
+	c.SetRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.done != nil {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+		}
+	}
+	logger.InfoC("email", "Email channel stopped")
+	return nil
+}
+
+// Send replies into the thread identified by msg.ChatID.
+func (c *EmailChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	if strings.TrimSpace(msg.Content) == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	thread, ok := c.threads[msg.ChatID]
+	var reply outgoingMessage
+	if ok {
+		reply = c.buildReply(thread, msg.Content)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no email thread %q to reply to: %w", msg.ChatID, channels.ErrSendFailed)
+	}
+
+	raw, err := reply.Bytes()
+	if err != nil {
+		return fmt.Errorf("email compose failed: %w", err)
+	}
+	if err := c.sendMail(thread.to, raw); err != nil {
+		return fmt.Errorf("email send failed: %v: %w", err, channels.ErrTemporary)
+	}
+
+	c.mu.Lock()
+	thread.lastID = reply.messageID
+	thread.references = reply.references
+	c.messages[reply.messageID] = msg.ChatID
+	c.mu.Unlock()
+
+	logger.DebugCF("email", "Reply sent", map[string]any{
+		"to":         thread.to,
+		"chat_id":    msg.ChatID,
+		"message_id": reply.messageID,
+	})
+	return nil
+}
+
+// buildReply composes a reply to the latest message of thread, quoting it
+// below the answer. c.mu must be held.
+func (c *EmailChannel) buildReply(thread *emailThread, content string) outgoingMessage {
+	body := strings.TrimRight(content, "\n")
+	if strings.TrimSpace(thread.quoted) != "" {
+		body += fmt.Sprintf("\n\nOn %s, %s wrote:\n%s\n", thread.quotedDate, thread.quotedFrom, quoteText(thread.quoted))
+	}
+
+	references := append([]string{}, thread.references...)
+	if thread.lastID != "" && (len(references) == 0 || references[len(references)-1] != thread.lastID) {
+		references = append(references, thread.lastID)
+	}
+
+	_, domain, _ := strings.Cut(c.address, "@")
+	return outgoingMessage{
+		from:       c.config.Address,
+		to:         thread.to,
+		subject:    replySubject(thread.subject),
+		messageID:  fmt.Sprintf("<%s@%s>", uuid.NewString(), domain),
+		inReplyTo:  thread.lastID,
+		references: references,
+		date:       time.Now().Format(time.RFC1123Z),
+		body:       body,
+	}
+}
+
+// watch keeps an IMAP session open, reconnecting after failures.
+func (c *EmailChannel) watch() {
+	defer close(c.done)
+	for {
+		err := c.runSession()
+		if c.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.WarnCF("email", "IMAP session ended", map[string]any{
+				"error": err.Error(),
+			})
+		}
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// runSession logs in and processes unseen mail until the connection fails or
+// the channel stops. It waits with IDLE when the server supports it and
+// polls otherwise.
+func (c *EmailChannel) runSession() error {
+	tlsConfig := &tls.Config{ServerName: hostOf(c.config.IMAPServer)}
+	client, err := dialIMAP(c.ctx, c.config.IMAPServer, c.config.IMAPTLS, tlsConfig, c.config.MaxMessageSize)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	stop := context.AfterFunc(c.ctx, func() { client.Close() })
+	defer stop()
+
+	if err := client.Login(c.config.Username, c.config.Password); err != nil {
+		return fmt.Errorf("imap login: %w", err)
+	}
+	if err := client.Select(c.config.Mailbox); err != nil {
+		return fmt.Errorf("imap select %s: %w", c.config.Mailbox, err)
+	}
+	defer client.Logout()
+
+	for {
+		if err := c.fetchUnseen(client); err != nil {
+			return err
+		}
+		if client.SupportsIdle() {
+			if err := client.Idle(c.ctx, idleRefresh); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+func (c *EmailChannel) fetchUnseen(client *imapClient) error {
+	uids, err := client.SearchUnseen()
+	if err != nil {
+		return fmt.Errorf("imap search: %w", err)
+	}
+	for _, uid := range uids {
+		size, err := client.Size(uid)
+		if err != nil {
+			return fmt.Errorf("imap fetch %d: %w", uid, err)
+		}
+		if size > c.config.MaxMessageSize {
+			logger.WarnCF("email", "Skipping message larger than max_message_size", map[string]any{
+				"uid":              uid,
+				"size":             size,
+				"max_message_size": c.config.MaxMessageSize,
+			})
+			if err := client.MarkSeen(uid); err != nil {
+				return fmt.Errorf("imap store %d: %w", uid, err)
+			}
+			continue
+		}
+		raw, err := client.Fetch(uid)
+		if err != nil {
+			return fmt.Errorf("imap fetch %d: %w", uid, err)
+		}
+		c.handleRaw(c.ctx, raw)
+		if err := client.MarkSeen(uid); err != nil {
+			return fmt.Errorf("imap store %d: %w", uid, err)
+		}
+	}
+	return nil
+}
+
+// handleRaw parses one RFC 5322 message and publishes it to the bus.
+func (c *EmailChannel) handleRaw(ctx context.Context, raw []byte) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		logger.WarnCF("email", "Skipping unparsable message", map[string]any{"error": err.Error()})
+		return
+	}
+
+	from, err := mail.ParseAddress(decodeHeader(msg.Header.Get("From")))
+	if err != nil {
+		logger.WarnCF("email", "Skipping message without a valid sender", map[string]any{
+			"from": msg.Header.Get("From"),
+		})
+		return
+	}
+	sender := strings.ToLower(from.Address)
+	if sender == c.address {
+		return
+	}
+	// Never answer auto-responders, bounces or our own replies.
+	if auto := strings.ToLower(msg.Header.Get("Auto-Submitted")); auto != "" && auto != "no" {
+		return
+	}
+	senderInfo := bus.SenderInfo{
+		Platform:    "email",
+		PlatformID:  sender,
+		CanonicalID: "email:" + sender,
+		Username:    sender,
+		DisplayName: from.Name,
+	}
+	if !c.IsAllowedSender(senderInfo) {
+		logger.DebugCF("email", "Ignoring message from sender not in allow_from", map[string]any{
+			"from": sender,
+		})
+		return
+	}
+
+	messageID := firstOr(parseMessageIDs(msg.Header.Get("Message-ID")), "")
+	if messageID == "" {
+		sum := sha256.Sum256(raw)
+		messageID = "<" + hex.EncodeToString(sum[:8]) + "@picoclaw.local>"
+	}
+	inReplyTo := firstOr(parseMessageIDs(msg.Header.Get("In-Reply-To")), "")
+	references := parseMessageIDs(msg.Header.Get("References"))
+	subject := decodeHeader(msg.Header.Get("Subject"))
+
+	body, err := parseEmailBody(textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		logger.WarnCF("email", "Failed to parse message body", map[string]any{
+			"message_id": messageID,
+			"error":      err.Error(),
+		})
+	}
+	text := body.Text()
+
+	chatID, isReply := c.resolveThread(messageID, inReplyTo, references)
+	content := stripQuotedReply(text)
+	if !isReply && subject != "" {
+		content = strings.TrimSpace("Subject: " + subject + "\n\n" + content)
+	}
+
+	scope := channels.BuildMediaScope("email", chatID, messageID)
+	mediaRefs := make([]string, 0, len(body.attachments))
+	for _, att := range body.attachments {
+		ref, err := c.storeAttachment(att, scope)
+		if err != nil {
+			logger.WarnCF("email", "Failed to save attachment", map[string]any{
+				"filename": att.filename,
+				"error":    err.Error(),
+			})
+			continue
+		}
+		mediaRefs = append(mediaRefs, ref)
+	}
+	if content == "" && len(mediaRefs) == 0 {
+		return
+	}
+
+	threadRefs := references
+	if len(threadRefs) == 0 && inReplyTo != "" {
+		threadRefs = []string{inReplyTo}
+	}
+	date := msg.Header.Get("Date")
+	if date == "" {
+		date = time.Now().Format(time.RFC1123Z)
+	}
+	c.mu.Lock()
+	c.messages[messageID] = chatID
+	c.threads[chatID] = &emailThread{
+		to:         from.Address,
+		subject:    subject,
+		lastID:     messageID,
+		references: append(append([]string{}, threadRefs...), messageID),
+		quoted:     text,
+		quotedFrom: from.String(),
+		quotedDate: date,
+	}
+	c.mu.Unlock()
+
+	metadata := map[string]string{
+		"subject":    subject,
+		"message_id": messageID,
+	}
+	if inReplyTo != "" {
+		metadata["in_reply_to"] = inReplyTo
+	}
+
+	c.HandleMessage(ctx,
+		bus.Peer{Kind: "group", ID: chatID},
+		messageID, sender, chatID, content, mediaRefs, metadata, senderInfo,
+	)
+}
+
+// resolveThread maps a message to its chat ID: the thread of a message it
+// replies to when that is known, otherwise the root of its References chain,
+// otherwise a new thread rooted at the message itself.
+func (c *EmailChannel) resolveThread(messageID, inReplyTo string, references []string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	candidates := append([]string{inReplyTo}, references...)
+	for _, id := range candidates {
+		if chatID, ok := c.messages[id]; ok && id != "" {
+			return chatID, true
+		}
+	}
+	if len(references) > 0 {
+		return threadChatID(references[0]), true
+	}
+	if inReplyTo != "" {
+		return threadChatID(inReplyTo), true
+	}
+	return threadChatID(messageID), false
+}
+
+func (c *EmailChannel) storeAttachment(att emailAttachment, scope string) (string, error) {
+	mediaDir := filepath.Join(os.TempDir(), "picoclaw_media")
+	if err := os.MkdirAll(mediaDir, 0o700); err != nil {
+		return "", err
+	}
+	filename := strings.ReplaceAll(utils.SanitizeFilename(att.filename), "*", "_")
+	tmp, err := os.CreateTemp(mediaDir, "email-*-"+filename)
+	if err != nil {
+		return "", err
+	}
+	localPath := tmp.Name()
+	if _, err := tmp.Write(att.data); err != nil {
+		tmp.Close()
+		os.Remove(localPath)
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(localPath)
+		return "", err
+	}
+
+	if store := c.GetMediaStore(); store != nil {
+		ref, err := store.Store(localPath, media.MediaMeta{
+			Filename:    filename,
+			ContentType: att.contentType,
+			Source:      "email",
+		}, scope)
+		if err == nil {
+			return ref, nil
+		}
+		logger.WarnCF("email", "Failed to store media in MediaStore, falling back to local path", map[string]any{
+			"path":  localPath,
+			"error": err.Error(),
+		})
+	}
+	return localPath, nil
+}
+
+// sendMail delivers raw to a single recipient. smtp_tls selects implicit TLS
+// (usually port 465); otherwise STARTTLS is used when the server offers it.
+func (c *EmailChannel) sendMail(to string, raw []byte) error {
+	host := hostOf(c.config.SMTPServer)
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if c.config.SMTPTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.config.SMTPServer, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", c.config.SMTPServer)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !c.config.SMTPTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return err
+			}
+		}
+	}
+	if ok, _ := client.Extension("AUTH"); ok && c.config.Password != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.config.Username, c.config.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(c.address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func threadChatID(messageID string) string {
+	return strings.Trim(messageID, "<>")
+}
+
+func firstOr(values []string, fallback string) string {
+	if len(values) > 0 {
+		return values[0]
+	}
+	return fallback
+}
@@ -0,0 +1,466 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// fakeIMAP serves a single mailbox over plain-text IMAP, offering STARTTLS
+// when tlsConfig is set.
+type fakeIMAP struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	mu        sync.Mutex
+	messages  map[uint32]string
+	seen      map[uint32]bool
+	nextUID   uint32
+	loginTLS  bool
+}
+
+func newFakeIMAP(t *testing.T) *fakeIMAP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeIMAP{ln: ln, messages: map[uint32]string{}, seen: map[uint32]bool{}, nextUID: 1}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeIMAP) add(raw string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[s.nextUID] = strings.ReplaceAll(raw, "\n", "\r\n")
+	s.nextUID++
+}
+
+func (s *fakeIMAP) isSeen(uid uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[uid]
+}
+
+func (s *fakeIMAP) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	r := bufio.NewReader(conn)
+	secure := false
+	fmt.Fprint(conn, "* OK fake IMAP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		upper := strings.ToUpper(cmd)
+		switch {
+		case strings.HasPrefix(upper, "LOGIN "):
+			if cmd != `LOGIN "bot@example.com" "secret"` {
+				fmt.Fprintf(conn, "%s NO bad credentials\r\n", tag)
+				continue
+			}
+			s.mu.Lock()
+			s.loginTLS = secure
+			s.mu.Unlock()
+		case upper == "CAPABILITY":
+			if s.tlsConfig != nil && !secure {
+				fmt.Fprint(conn, "* CAPABILITY IMAP4rev1 STARTTLS\r\n")
+			} else {
+				fmt.Fprint(conn, "* CAPABILITY IMAP4rev1\r\n")
+			}
+		case upper == "STARTTLS" && s.tlsConfig != nil && !secure:
+			fmt.Fprintf(conn, "%s OK begin TLS\r\n", tag)
+			conn = tls.Server(conn, s.tlsConfig)
+			r = bufio.NewReader(conn)
+			secure = true
+			continue
+		case strings.HasPrefix(upper, "SELECT "):
+			fmt.Fprint(conn, "* FLAGS (\\Seen)\r\n")
+		case upper == "UID SEARCH UNSEEN":
+			s.mu.Lock()
+			var uids []string
+			for uid := uint32(1); uid < s.nextUID; uid++ {
+				if !s.seen[uid] {
+					uids = append(uids, fmt.Sprint(uid))
+				}
+			}
+			s.mu.Unlock()
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case strings.HasPrefix(upper, "UID FETCH "):
+			var uid uint32
+			fmt.Sscanf(cmd, "UID FETCH %d", &uid)
+			s.mu.Lock()
+			raw := s.messages[uid]
+			s.mu.Unlock()
+			if strings.Contains(upper, "RFC822.SIZE") {
+				fmt.Fprintf(conn, "* %d FETCH (UID %d RFC822.SIZE %d)\r\n", uid, uid, len(raw))
+				break
+			}
+			fmt.Fprintf(conn, "* %d FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, uid, len(raw), raw)
+		case strings.HasPrefix(upper, "UID STORE "):
+			var uid uint32
+			fmt.Sscanf(cmd, "UID STORE %d", &uid)
+			s.mu.Lock()
+			s.seen[uid] = true
+			s.mu.Unlock()
+		case upper == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK LOGOUT completed\r\n", tag)
+			return
+		default:
+			fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+			continue
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+// fakeSMTP accepts mail and hands each message's DATA to received.
+type fakeSMTP struct {
+	ln       net.Listener
+	received chan string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln, received: make(chan string, 4)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 fake SMTP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimRight(line, "\r\n"))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			fmt.Fprint(conn, "250-localhost\r\n250 AUTH PLAIN\r\n")
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			fmt.Fprint(conn, "235 authenticated\r\n")
+		case cmd == "DATA":
+			fmt.Fprint(conn, "354 go ahead\r\n")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.received <- data.String()
+			fmt.Fprint(conn, "250 queued\r\n")
+		case cmd == "QUIT":
+			fmt.Fprint(conn, "221 bye\r\n")
+			return
+		default:
+			fmt.Fprint(conn, "250 ok\r\n")
+		}
+	}
+}
+
+func startTestChannel(t *testing.T, imap *fakeIMAP, smtpServer *fakeSMTP) (*EmailChannel, *bus.MessageBus) {
+	t.Helper()
+	msgBus := bus.NewMessageBus()
+	ch, err := NewEmailChannel(config.EmailConfig{
+		IMAPServer:   imap.ln.Addr().String(),
+		SMTPServer:   smtpServer.ln.Addr().String(),
+		Address:      "PicoClaw <bot@example.com>",
+		Password:     "secret",
+		PollInterval: 1,
+		AllowFrom:    config.FlexibleStringSlice{"Alice@Example.com"},
+	}, msgBus)
+	if err != nil {
+		t.Fatalf("NewEmailChannel: %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ch.Stop(ctx)
+	})
+	return ch, msgBus
+}
+
+func nextInbound(t *testing.T, msgBus *bus.MessageBus) bus.InboundMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("timed out waiting for inbound message")
+	}
+	return msg
+}
+
+const firstMessage = `From: Alice <alice@example.com>
+To: bot@example.com
+Subject: Quarterly numbers
+Message-ID: <root-1@example.com>
+Date: Mon, 02 Mar 2026 10:00:00 +0000
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Can you summarise the attached =
+report?
+--b1
+Content-Type: text/plain; name="report.txt"
+Content-Disposition: attachment; filename="report.txt"
+Content-Transfer-Encoding: base64
+
+UmV2ZW51ZSB3YXMgdXAgMTIlLg==
+--b1--
+`
+
+func TestEmailChannel_ReceiveAndReplyInThread(t *testing.T) {
+	imap := newFakeIMAP(t)
+	smtpServer := newFakeSMTP(t)
+	imap.add(`From: mallory@example.com
+To: bot@example.com
+Subject: hi
+Message-ID: <spam@example.com>
+
+Not allowed.
+`)
+	imap.add(firstMessage)
+
+	ch, msgBus := startTestChannel(t, imap, smtpServer)
+
+	msg := nextInbound(t, msgBus)
+	if msg.Channel != "email" || msg.ChatID != "root-1@example.com" {
+		t.Fatalf("inbound channel/chat = %q/%q", msg.Channel, msg.ChatID)
+	}
+	if msg.SenderID != "email:alice@example.com" {
+		t.Errorf("SenderID = %q", msg.SenderID)
+	}
+	if msg.Peer.Kind != "group" || msg.Peer.ID != "root-1@example.com" {
+		t.Errorf("Peer = %+v", msg.Peer)
+	}
+	wantContent := "Subject: Quarterly numbers\n\nCan you summarise the attached report?"
+	if msg.Content != wantContent {
+		t.Errorf("Content = %q, want %q", msg.Content, wantContent)
+	}
+	if len(msg.Attachments) != 1 || !strings.Contains(msg.Attachments[0].TextContent, "Revenue was up 12%.") {
+		t.Errorf("Attachments = %+v", msg.Attachments)
+	}
+	if err := ch.Send(context.Background(), bus.OutboundMessage{
+		Channel: "email",
+		ChatID:  msg.ChatID,
+		Content: "Revenue grew 12%.",
+	}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var sent string
+	select {
+	case sent = <-smtpServer.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received by SMTP server")
+	}
+	for _, want := range []string{
+		"To: alice@example.com",
+		"Subject: Re: Quarterly numbers",
+		"In-Reply-To: <root-1@example.com>",
+		"References: <root-1@example.com>",
+		"Revenue grew 12%.",
+		"> Can you summarise the attached report?",
+	} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("sent mail missing %q:\n%s", want, sent)
+		}
+	}
+
+	// A reply that only carries In-Reply-To of our message stays in the thread.
+	replyID := ch.threads[msg.ChatID].lastID
+	imap.add(fmt.Sprintf(`From: alice@example.com
+To: bot@example.com
+Subject: Re: Quarterly numbers
+Message-ID: <reply-2@example.com>
+In-Reply-To: %s
+
+Thanks, and costs?
+
+On Mon, 02 Mar 2026, PicoClaw <bot@example.com> wrote:
+> Revenue grew 12%%.
+`, replyID))
+
+	followUp := nextInbound(t, msgBus)
+	if followUp.ChatID != msg.ChatID {
+		t.Errorf("follow-up ChatID = %q, want %q", followUp.ChatID, msg.ChatID)
+	}
+	if followUp.Content != "Thanks, and costs?" {
+		t.Errorf("follow-up Content = %q", followUp.Content)
+	}
+	if !imap.isSeen(1) || !imap.isSeen(2) {
+		t.Error("fetched messages should be marked \\Seen")
+	}
+}
+
+func TestIMAPClient_StartTLS(t *testing.T) {
+	certSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer certSrv.Close()
+	imap := newFakeIMAP(t)
+	imap.tlsConfig = &tls.Config{Certificates: certSrv.TLS.Certificates}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certSrv.Certificate())
+	client, err := dialIMAP(context.Background(), imap.ln.Addr().String(), false,
+		&tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}, defaultMaxMessageSize)
+	if err != nil {
+		t.Fatalf("dialIMAP: %v", err)
+	}
+	defer client.Close()
+	if !client.secure {
+		t.Fatal("connection was not upgraded with STARTTLS")
+	}
+	if err := client.Login("bot@example.com", "secret"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	imap.mu.Lock()
+	defer imap.mu.Unlock()
+	if !imap.loginTLS {
+		t.Fatal("LOGIN was sent before STARTTLS")
+	}
+}
+
+func TestIMAPClient_RefusesCleartextLogin(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+	client := &imapClient{conn: conn, r: bufio.NewReader(conn), caps: map[string]bool{}, host: "imap.example.com"}
+	defer client.Close()
+
+	// Nothing reads the pipe, so a LOGIN attempt would block the test.
+	err := client.Login("bot@example.com", "secret")
+	if err == nil || !strings.Contains(err.Error(), "unencrypted") {
+		t.Fatalf("Login error = %v, want refusal to send the password unencrypted", err)
+	}
+}
+
+func TestIMAPClient_LiteralLimit(t *testing.T) {
+	client := &imapClient{
+		r:          bufio.NewReader(strings.NewReader("* 1 FETCH (UID 1 BODY[] {4096}\r\n")),
+		maxLiteral: 1024,
+	}
+	if _, err := client.readResponse(); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("readResponse error = %v, want literal limit error", err)
+	}
+}
+
+func TestEmailChannel_SkipsOversizedMessage(t *testing.T) {
+	imap := newFakeIMAP(t)
+	imap.add("From: alice@example.com\nSubject: big\n\n" + strings.Repeat("x", 2048) + "\n")
+
+	ch, err := NewEmailChannel(config.EmailConfig{
+		IMAPServer:     imap.ln.Addr().String(),
+		SMTPServer:     "127.0.0.1:25",
+		Address:        "bot@example.com",
+		MaxMessageSize: 1024,
+		AllowFrom:      config.FlexibleStringSlice{"alice@example.com"},
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewEmailChannel: %v", err)
+	}
+	client, err := dialIMAP(context.Background(), imap.ln.Addr().String(), false, nil, 1024)
+	if err != nil {
+		t.Fatalf("dialIMAP: %v", err)
+	}
+	defer client.Close()
+	if err := client.Select("INBOX"); err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+
+	if err := ch.fetchUnseen(client); err != nil {
+		t.Fatalf("fetchUnseen: %v", err)
+	}
+	if !imap.isSeen(1) {
+		t.Fatal("oversized message was not marked seen")
+	}
+}
+
+func TestEmailChannel_SendUnknownThread(t *testing.T) {
+	ch, _ := startTestChannel(t, newFakeIMAP(t), newFakeSMTP(t))
+	err := ch.Send(context.Background(), bus.OutboundMessage{Channel: "email", ChatID: "nope", Content: "hi"})
+	if err == nil {
+		t.Fatal("expected error for unknown thread")
+	}
+}
+
+func TestNewEmailChannel_Validation(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	for name, cfg := range map[string]config.EmailConfig{
+		"missing imap":  {SMTPServer: "smtp:25", Address: "bot@example.com"},
+		"missing smtp":  {IMAPServer: "imap:143", Address: "bot@example.com"},
+		"invalid email": {IMAPServer: "imap:143", SMTPServer: "smtp:25", Address: "bot"},
+	} {
+		if _, err := NewEmailChannel(cfg, msgBus); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseEmailBody_HTMLOnly(t *testing.T) {
+	body, err := parseEmailBody(map[string][]string{"Content-Type": {"text/html; charset=utf-8"}},
+		strings.NewReader("<html><head><style>p{}</style></head><body><p>Hello &amp; welcome</p>Bye<br>now</body></html>"))
+	if err != nil {
+		t.Fatalf("parseEmailBody: %v", err)
+	}
+	if got, want := body.Text(), "Hello & welcome\nBye\nnow"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestQuoteText(t *testing.T) {
+	got := quoteText("line one\n\n> earlier\n")
+	want := "> line one\n>\n>> earlier"
+	if got != want {
+		t.Errorf("quoteText = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,366 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapClient is a minimal IMAP4rev1 client covering what the channel needs:
+// STARTTLS, LOGIN, SELECT, UID SEARCH/FETCH/STORE and IDLE. Responses are
+// read line by line; literals ({n}) are collected alongside the line they
+// belong to.
+type imapClient struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	tag        int
+	caps       map[string]bool
+	host       string
+	secure     bool // the connection is encrypted
+	maxLiteral int  // largest literal accepted from the server
+}
+
+// imapResponse is an untagged server response.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// dialIMAP connects to server with implicit TLS when useTLS is set. Plain
+// connections are upgraded with STARTTLS when the server offers it. Literals
+// larger than maxLiteral bytes are refused.
+func dialIMAP(
+	ctx context.Context,
+	server string,
+	useTLS bool,
+	tlsConfig *tls.Config,
+	maxLiteral int,
+) (*imapClient, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", server)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", server)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &imapClient{
+		conn:       conn,
+		r:          bufio.NewReader(conn),
+		caps:       map[string]bool{},
+		host:       hostOf(server),
+		secure:     useTLS,
+		maxLiteral: maxLiteral,
+	}
+	greeting, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting.line, "* OK") && !strings.HasPrefix(greeting.line, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected imap greeting: %s", greeting.line)
+	}
+	if !useTLS {
+		if err := c.startTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// startTLS upgrades a plain connection when the server advertises STARTTLS.
+func (c *imapClient) startTLS(tlsConfig *tls.Config) error {
+	if err := c.capabilities(); err != nil {
+		return err
+	}
+	if !c.caps["STARTTLS"] {
+		return nil
+	}
+	if _, err := c.command("STARTTLS"); err != nil {
+		return fmt.Errorf("imap starttls: %w", err)
+	}
+	tlsConn := tls.Client(c.conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("imap starttls: %w", err)
+	}
+	c.conn = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	c.secure = true
+	// Capabilities sent before the handshake must be discarded (RFC 3501 6.2.1).
+	return c.capabilities()
+}
+
+func (c *imapClient) Close() error {
+	return c.conn.Close()
+}
+
+// Login authenticates with LOGIN. Like net/smtp's PLAIN auth, it refuses to
+// send the password over an unencrypted connection unless the server is on
+// the loopback interface.
+func (c *imapClient) Login(username, password string) error {
+	if !c.secure && !isLoopback(c.host) {
+		return fmt.Errorf("refusing to send the password to %s unencrypted: "+
+			"the server does not offer STARTTLS (set imap_tls for implicit TLS)", c.host)
+	}
+	if c.caps["LOGINDISABLED"] {
+		return fmt.Errorf("server disabled LOGIN on this connection")
+	}
+	if _, err := c.command("LOGIN %s %s", imapQuote(username), imapQuote(password)); err != nil {
+		return err
+	}
+	return c.capabilities()
+}
+
+// capabilities replaces c.caps with the server's current CAPABILITY list.
+func (c *imapClient) capabilities() error {
+	resps, err := c.command("CAPABILITY")
+	if err != nil {
+		return err
+	}
+	c.caps = map[string]bool{}
+	for _, resp := range resps {
+		if rest, ok := strings.CutPrefix(resp.line, "* CAPABILITY "); ok {
+			for _, cap := range strings.Fields(rest) {
+				c.caps[strings.ToUpper(cap)] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (c *imapClient) Select(mailbox string) error {
+	_, err := c.command("SELECT %s", imapQuote(mailbox))
+	return err
+}
+
+// SearchUnseen returns the UIDs of messages without the \Seen flag.
+func (c *imapClient) SearchUnseen() ([]uint32, error) {
+	resps, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range resps {
+		rest, ok := strings.CutPrefix(resp.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(rest) {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid uid in search response: %q", field)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// Size returns the RFC822.SIZE of a message.
+func (c *imapClient) Size(uid uint32) (int, error) {
+	resps, err := c.command("UID FETCH %d (RFC822.SIZE)", uid)
+	if err != nil {
+		return 0, err
+	}
+	for _, resp := range resps {
+		_, rest, ok := strings.Cut(resp.line, "RFC822.SIZE ")
+		if !ok {
+			continue
+		}
+		end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if end < 0 {
+			end = len(rest)
+		}
+		size, err := strconv.Atoi(rest[:end])
+		if err != nil {
+			return 0, fmt.Errorf("invalid RFC822.SIZE in fetch response: %q", resp.line)
+		}
+		return size, nil
+	}
+	return 0, fmt.Errorf("message %d not found", uid)
+}
+
+// Fetch returns the raw RFC 5322 message without setting \Seen.
+func (c *imapClient) Fetch(uid uint32) ([]byte, error) {
+	resps, err := c.command("UID FETCH %d BODY.PEEK[]", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range resps {
+		if strings.Contains(resp.line, "FETCH") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("message %d not found", uid)
+}
+
+func (c *imapClient) MarkSeen(uid uint32) error {
+	_, err := c.command(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+func (c *imapClient) SupportsIdle() bool {
+	return c.caps["IDLE"]
+}
+
+// Idle waits until the server reports new mail, timeout passes or ctx is done.
+func (c *imapClient) Idle(ctx context.Context, timeout time.Duration) error {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s IDLE\r\n", tag); err != nil {
+		return err
+	}
+	cont, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(cont.line, "+") {
+		return fmt.Errorf("imap IDLE rejected: %s", cont.line)
+	}
+
+	stop := context.AfterFunc(ctx, func() { c.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return err
+			}
+			break
+		}
+		if strings.HasSuffix(resp.line, " EXISTS") || strings.HasSuffix(resp.line, " RECENT") {
+			break
+		}
+	}
+	c.conn.SetReadDeadline(time.Time{})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if _, err := io.WriteString(c.conn, "DONE\r\n"); err != nil {
+		return err
+	}
+	_, err = c.readUntilTagged(tag)
+	return err
+}
+
+func (c *imapClient) Logout() error {
+	_, err := c.command("LOGOUT")
+	return err
+}
+
+func (c *imapClient) nextTag() string {
+	c.tag++
+	return fmt.Sprintf("a%03d", c.tag)
+}
+
+// command sends a tagged command and returns the untagged responses that
+// preceded its completion. A NO or BAD completion is returned as an error.
+func (c *imapClient) command(format string, args ...any) ([]imapResponse, error) {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+	return c.readUntilTagged(tag)
+}
+
+func (c *imapClient) readUntilTagged(tag string) ([]imapResponse, error) {
+	var untagged []imapResponse
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		rest, ok := strings.CutPrefix(resp.line, tag+" ")
+		if !ok {
+			untagged = append(untagged, resp)
+			continue
+		}
+		status, text, _ := strings.Cut(rest, " ")
+		if !strings.EqualFold(status, "OK") {
+			return nil, fmt.Errorf("imap %s: %s", strings.ToUpper(status), text)
+		}
+		return untagged, nil
+	}
+}
+
+// readResponse reads one response line, following any literals it announces.
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var resp imapResponse
+	var line strings.Builder
+	for {
+		part, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		part = strings.TrimRight(part, "\r\n")
+		line.WriteString(part)
+
+		size, ok := literalSize(part)
+		if !ok {
+			resp.line = line.String()
+			return resp, nil
+		}
+		if size > c.maxLiteral {
+			return resp, fmt.Errorf("imap literal of %d bytes exceeds the %d byte limit", size, c.maxLiteral)
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+}
+
+// literalSize parses a trailing "{n}" literal announcement.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(line[open+1 : len(line)-1])
+	if err != nil || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func hostOf(server string) string {
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		return server
+	}
+	return host
+}
@@ -0,0 +1,261 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// maxAttachmentBytes caps how much of a single attachment is kept.
+const maxAttachmentBytes = 25 << 20
+
+var (
+	messageIDPattern = regexp.MustCompile(`<[^<>\s]+>`)
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</tr>|</h[1-6]>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlDropPattern  = regexp.MustCompile(`(?is)<(style|script|head)[^>]*>.*?</(style|script|head)>`)
+	blankRunPattern  = regexp.MustCompile(`\n{3,}`)
+	wordDecoder      = &mime.WordDecoder{}
+)
+
+// emailAttachment is a file part of an inbound message.
+type emailAttachment struct {
+	filename    string
+	contentType string
+	data        []byte
+}
+
+// emailBody holds the readable parts of a MIME message.
+type emailBody struct {
+	text        string
+	html        string
+	attachments []emailAttachment
+}
+
+// Text returns the plain-text body, falling back to the HTML body with its
+// markup stripped.
+func (b emailBody) Text() string {
+	if strings.TrimSpace(b.text) != "" {
+		return b.text
+	}
+	return htmlToText(b.html)
+}
+
+// parseEmailBody walks a (possibly multipart) body and collects the first
+// text/plain and text/html parts plus every attachment.
+func parseEmailBody(header textproto.MIMEHeader, body io.Reader) (emailBody, error) {
+	var out emailBody
+	err := parsePart(header, body, &out)
+	return out, err
+}
+
+func parsePart(header textproto.MIMEHeader, body io.Reader, out *emailBody) error {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := parsePart(part.Header, part, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	decoded := decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if disposition == "attachment" || filename != "" || !isText {
+		data, err := io.ReadAll(io.LimitReader(decoded, maxAttachmentBytes))
+		if err != nil {
+			return err
+		}
+		if filename == "" {
+			filename = "attachment"
+			if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+				filename += exts[0]
+			}
+		}
+		out.attachments = append(out.attachments, emailAttachment{
+			filename:    filename,
+			contentType: mediaType,
+			data:        data,
+		})
+		return nil
+	}
+
+	data, err := io.ReadAll(decoded)
+	if err != nil {
+		return err
+	}
+	text := decodeCharset(data, params["charset"])
+	switch {
+	case mediaType == "text/plain" && out.text == "":
+		out.text = text
+	case mediaType == "text/html" && out.html == "":
+		out.html = text
+	}
+	return nil
+}
+
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// decodeCharset converts Latin-1 bodies to UTF-8; everything else is assumed
+// to be UTF-8 (or its ASCII subset) already.
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return string(data)
+	}
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+func htmlToText(s string) string {
+	s = htmlDropPattern.ReplaceAllString(s, "")
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(blankRunPattern.ReplaceAllString(s, "\n\n"))
+}
+
+// parseMessageIDs extracts every <id> from a Message-ID style header.
+func parseMessageIDs(value string) []string {
+	return messageIDPattern.FindAllString(value, -1)
+}
+
+// stripQuotedReply removes the quoted history mail clients append to replies
+// (lines starting with ">" and the "On ... wrote:" line introducing them).
+func stripQuotedReply(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	for len(kept) > 0 {
+		last := strings.TrimSpace(kept[len(kept)-1])
+		if last == "" || strings.HasSuffix(last, "wrote:") {
+			kept = kept[:len(kept)-1]
+			continue
+		}
+		break
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// quoteText prefixes every line of text with "> ".
+func quoteText(text string) string {
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	for i, line := range lines {
+		if line == "" || strings.HasPrefix(line, ">") {
+			lines[i] = ">" + line
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func replySubject(subject string) string {
+	subject = strings.TrimSpace(subject)
+	if subject == "" {
+		return "Re: your message"
+	}
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// outgoingMessage is a plain-text reply ready to be written to SMTP.
+type outgoingMessage struct {
+	from       string
+	to         string
+	subject    string
+	messageID  string
+	inReplyTo  string
+	references []string
+	date       string
+	body       string
+}
+
+func (m outgoingMessage) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+		}
+	}
+	writeHeader("From", m.from)
+	writeHeader("To", m.to)
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", m.subject))
+	writeHeader("Date", m.date)
+	writeHeader("Message-ID", m.messageID)
+	writeHeader("In-Reply-To", m.inReplyTo)
+	writeHeader("References", strings.Join(m.references, " "))
+	writeHeader("Auto-Submitted", "auto-replied")
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", "text/plain; charset=utf-8")
+	writeHeader("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	body := strings.ReplaceAll(strings.ReplaceAll(m.body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -6,8 +6,14 @@ import (
 	"strings"
 
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
 )
 
+// cardActionKey marks callback values that belong to picoclaw controls; its
+// value is the interaction kind ("button" or "select").
+const cardActionKey = "picoclaw"
+
 // mentionPlaceholderRegex matches @_user_N placeholders inserted by Feishu for mentions.
 var mentionPlaceholderRegex = regexp.MustCompile(`@_user_\d+`)
 
@@ -40,6 +46,90 @@ func buildMarkdownCard(content string) (string, error) {
 	return string(data), nil
 }
 
+// buildInteractiveCard builds a JSON 2.0 card with an optional header, the
+// markdown content and the interactive controls. Buttons call back with
+// {"picoclaw":"button","value":...}; the select menu calls back with
+// {"picoclaw":"select"} and the chosen option value.
+func buildInteractiveCard(content string, in *bus.Interactive) (string, error) {
+	var elements []map[string]any
+	if content != "" {
+		elements = append(elements, map[string]any{"tag": "markdown", "content": content})
+	}
+	if in.Card != nil && in.Card.ImageURL != "" {
+		// Card images need an uploaded img_key; a link keeps the URL usable.
+		elements = append(elements, map[string]any{
+			"tag":     "markdown",
+			"content": "[" + cardImageLabel(in.Card) + "](" + in.Card.ImageURL + ")",
+		})
+	}
+
+	for _, b := range in.Buttons {
+		style := "default"
+		if b.Style == "primary" || b.Style == "danger" {
+			style = b.Style
+		}
+		behavior := map[string]any{
+			"type":  "callback",
+			"value": map[string]any{cardActionKey: "button", "value": b.CallbackValue()},
+		}
+		if b.URL != "" {
+			behavior = map[string]any{"type": "open_url", "default_url": b.URL}
+		}
+		elements = append(elements, map[string]any{
+			"tag":       "button",
+			"text":      map[string]any{"tag": "plain_text", "content": b.Label},
+			"type":      style,
+			"behaviors": []map[string]any{behavior},
+		})
+	}
+
+	if in.Select != nil && len(in.Select.Options) > 0 {
+		options := make([]map[string]any, 0, len(in.Select.Options))
+		for _, o := range in.Select.Options {
+			options = append(options, map[string]any{
+				"text":  map[string]any{"tag": "plain_text", "content": o.Label},
+				"value": o.CallbackValue(),
+			})
+		}
+		placeholder := in.Select.Placeholder
+		if placeholder == "" {
+			placeholder = "Choose an option"
+		}
+		elements = append(elements, map[string]any{
+			"tag":         "select_static",
+			"placeholder": map[string]any{"tag": "plain_text", "content": placeholder},
+			"options":     options,
+			"behaviors": []map[string]any{{
+				"type":  "callback",
+				"value": map[string]any{cardActionKey: "select"},
+			}},
+		})
+	}
+
+	card := map[string]any{
+		"schema": "2.0",
+		"body":   map[string]any{"elements": elements},
+	}
+	if in.Card != nil && in.Card.Title != "" {
+		card["header"] = map[string]any{
+			"title":    map[string]any{"tag": "plain_text", "content": in.Card.Title},
+			"template": "blue",
+		}
+	}
+	data, err := json.Marshal(card)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func cardImageLabel(card *bus.Card) string {
+	if card.Title != "" {
+		return card.Title
+	}
+	return "image"
+}
+
 // extractJSONStringField unmarshals content as JSON and returns the value of the given string field.
 // Returns "" if the content is invalid JSON or the field is missing/empty.
 func extractJSONStringField(content, field string) string {
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
 )
 
 func TestExtractJSONStringField(t *testing.T) {
@@ -227,6 +229,61 @@ func TestBuildMarkdownCard(t *testing.T) {
 	}
 }
 
+func TestBuildInteractiveCard(t *testing.T) {
+	result, err := buildInteractiveCard("Deploy?", &bus.Interactive{
+		Card:    &bus.Card{Title: "Release"},
+		Buttons: []bus.Button{{Label: "Yes", Value: "yes", Style: "primary"}, {Label: "Docs", URL: "https://example.com"}},
+		Select:  &bus.Select{Options: []bus.SelectOption{{Label: "staging", Value: "stg"}}},
+	})
+	if err != nil {
+		t.Fatalf("buildInteractiveCard() error: %v", err)
+	}
+
+	var card struct {
+		Header struct {
+			Title struct{ Content string } `json:"title"`
+		} `json:"header"`
+		Body struct {
+			Elements []struct {
+				Tag       string `json:"tag"`
+				Content   string `json:"content"`
+				Type      string `json:"type"`
+				Behaviors []struct {
+					Type       string         `json:"type"`
+					Value      map[string]any `json:"value"`
+					DefaultURL string         `json:"default_url"`
+				} `json:"behaviors"`
+				Options []struct {
+					Value string `json:"value"`
+				} `json:"options"`
+			} `json:"elements"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(result), &card); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if card.Header.Title.Content != "Release" {
+		t.Errorf("header title = %q", card.Header.Title.Content)
+	}
+	els := card.Body.Elements
+	if len(els) != 4 {
+		t.Fatalf("elements = %d, want 4", len(els))
+	}
+	if els[0].Tag != "markdown" || els[0].Content != "Deploy?" {
+		t.Errorf("markdown element = %+v", els[0])
+	}
+	if els[1].Tag != "button" || els[1].Type != "primary" || els[1].Behaviors[0].Value["value"] != "yes" ||
+		els[1].Behaviors[0].Value[cardActionKey] != "button" {
+		t.Errorf("button element = %+v", els[1])
+	}
+	if els[2].Behaviors[0].Type != "open_url" || els[2].Behaviors[0].DefaultURL != "https://example.com" {
+		t.Errorf("link button = %+v", els[2])
+	}
+	if els[3].Tag != "select_static" || len(els[3].Options) != 1 || els[3].Options[0].Value != "stg" {
+		t.Errorf("select element = %+v", els[3])
+	}
+}
+
 func TestStripMentionPlaceholders(t *testing.T) {
 	strPtr := func(s string) *string { return &s }
 
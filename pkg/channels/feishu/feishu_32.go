@@ -45,11 +45,21 @@ func (c *FeishuChannel) EditMessage(ctx context.Context, chatID, messageID, cont
 	return errUnsupported
 }
 
+// DeleteMessage is a stub method to satisfy MessageDeleter
+func (c *FeishuChannel) DeleteMessage(ctx context.Context, chatID, messageID string) error {
+	return errUnsupported
+}
+
 // SendPlaceholder is a stub method to satisfy PlaceholderCapable
 func (c *FeishuChannel) SendPlaceholder(ctx context.Context, chatID string) (string, error) {
 	return "", errUnsupported
 }
 
+// SendInteractive is a stub method to satisfy InteractiveCapable
+func (c *FeishuChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	return errUnsupported
+}
+
 // ReactToMessage is a stub method to satisfy ReactionCapable
 func (c *FeishuChannel) ReactToMessage(ctx context.Context, chatID, messageID string) (func(), error) {
 	return func() {}, errUnsupported
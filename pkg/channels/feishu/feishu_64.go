@@ -11,12 +11,14 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 
 	lark "github.com/larksuite/oapi-sdk-go/v3"
 	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
 	larkdispatcher "github.com/larksuite/oapi-sdk-go/v3/event/dispatcher"
+	larkcallback "github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 	larkws "github.com/larksuite/oapi-sdk-go/v3/ws"
 
@@ -36,6 +38,8 @@ type FeishuChannel struct {
 	wsClient *larkws.Client
 
 	botOpenID atomic.Value // stores string; populated lazily for @mention detection
+	chatTypes sync.Map     // chatID → chat_type, for routing card callbacks
+	threads   sync.Map     // thread ID → latest inbound message ID in it, to post into the thread
 
 	mu     sync.Mutex
 	cancel context.CancelFunc
@@ -69,7 +73,8 @@ func (c *FeishuChannel) Start(ctx context.Context) error {
 	}
 
 	dispatcher := larkdispatcher.NewEventDispatcher(c.config.VerificationToken, c.config.EncryptKey).
-		OnP2MessageReceiveV1(c.handleMessageReceive)
+		OnP2MessageReceiveV1(c.handleMessageReceive).
+		OnP2CardActionTrigger(c.handleCardAction)
 
 	runCtx, cancel := context.WithCancel(ctx)
 
@@ -126,7 +131,25 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	if err != nil {
 		return fmt.Errorf("feishu send: card build failed: %w", err)
 	}
-	return c.sendCard(ctx, msg.ChatID, cardContent)
+	return c.deliverCard(ctx, msg, cardContent)
+}
+
+// SendInteractive implements channels.InteractiveCapable.
+// Buttons and the select menu are rendered as card components.
+func (c *FeishuChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	if msg.ChatID == "" {
+		return fmt.Errorf("chat ID is empty: %w", channels.ErrSendFailed)
+	}
+
+	cardContent, err := buildInteractiveCard(msg.Content, msg.Interactive)
+	if err != nil {
+		return fmt.Errorf("feishu send: card build failed: %w", err)
+	}
+	return c.deliverCard(ctx, msg, cardContent)
 }
 
 // EditMessage implements channels.MessageEditor.
@@ -152,6 +175,20 @@ func (c *FeishuChannel) EditMessage(ctx context.Context, chatID, messageID, cont
 	return nil
 }
 
+// DeleteMessage implements channels.MessageDeleter.
+func (c *FeishuChannel) DeleteMessage(ctx context.Context, chatID, messageID string) error {
+	req := larkim.NewDeleteMessageReqBuilder().MessageId(messageID).Build()
+
+	resp, err := c.client.Im.V1.Message.Delete(ctx, req)
+	if err != nil {
+		return fmt.Errorf("feishu delete: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("feishu delete api error (code=%d msg=%s)", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
 // SendPlaceholder implements channels.PlaceholderCapable.
 // Sends an interactive card with placeholder text and returns its message ID.
 func (c *FeishuChannel) SendPlaceholder(ctx context.Context, chatID string) (string, error) {
@@ -390,6 +427,7 @@ func (c *FeishuChannel) handleMessageReceive(ctx context.Context, event *larkim.
 	chatType := stringValue(message.ChatType)
 	if chatType != "" {
 		metadata["chat_type"] = chatType
+		c.chatTypes.Store(chatID, chatType)
 	}
 	if sender != nil && sender.TenantKey != nil {
 		metadata["tenant_key"] = *sender.TenantKey
@@ -424,10 +462,109 @@ func (c *FeishuChannel) handleMessageReceive(ctx context.Context, event *larkim.
 		"preview":    utils.Truncate(content, 80),
 	})
 
-	c.HandleMessage(ctx, peer, messageID, senderID, chatID, content, mediaRefs, metadata, senderInfo)
+	thread := channels.ThreadInfo{ThreadID: stringValue(message.ThreadId)}
+	if thread.ThreadID != "" && messageID != "" {
+		c.threads.Store(thread.ThreadID, messageID)
+	}
+	if parentID := stringValue(message.ParentId); parentID != "" {
+		thread.ReplyTo = c.fetchQuotedMessage(ctx, parentID)
+	}
+
+	c.HandleThreadedMessage(ctx, peer, thread, messageID, senderID, chatID, content, mediaRefs, metadata, senderInfo)
 	return nil
 }
 
+// fetchQuotedMessage loads the message a reply points at. Only the ID is
+// known when the message cannot be fetched (e.g. it was recalled).
+func (c *FeishuChannel) fetchQuotedMessage(ctx context.Context, messageID string) *bus.QuotedMessage {
+	quoted := &bus.QuotedMessage{MessageID: messageID}
+
+	req := larkim.NewGetMessageReqBuilder().MessageId(messageID).Build()
+	resp, err := c.client.Im.V1.Message.Get(ctx, req)
+	if err != nil || !resp.Success() || resp.Data == nil || len(resp.Data.Items) == 0 {
+		logger.DebugCF("feishu", "Failed to fetch quoted message", map[string]any{
+			"message_id": messageID,
+			"error":      fmt.Sprint(err),
+		})
+		return quoted
+	}
+
+	parent := resp.Data.Items[0]
+	if parent.Sender != nil {
+		quoted.SenderID = stringValue(parent.Sender.Id)
+	}
+	if parent.Body != nil {
+		quoted.Content = extractContent(stringValue(parent.MsgType), stringValue(parent.Body.Content))
+		// Unlike the user's own message, keep who the parent mentioned.
+		for _, m := range parent.Mentions {
+			if key := stringValue(m.Key); key != "" {
+				quoted.Content = strings.ReplaceAll(quoted.Content, key, "@"+stringValue(m.Name))
+			}
+		}
+	}
+	return quoted
+}
+
+// handleCardAction turns a click on a card button or select menu into an
+// inbound message. An empty response leaves the card unchanged.
+func (c *FeishuChannel) handleCardAction(
+	ctx context.Context,
+	event *larkcallback.CardActionTriggerEvent,
+) (*larkcallback.CardActionTriggerResponse, error) {
+	if event == nil || event.Event == nil || event.Event.Action == nil || event.Event.Context == nil {
+		return nil, nil
+	}
+	action := event.Event.Action
+
+	var kind, value string
+	switch action.Value[cardActionKey] {
+	case "button":
+		kind = channels.InteractionButton
+		value, _ = action.Value["value"].(string)
+	case "select":
+		kind = channels.InteractionSelect
+		value = action.Option
+	default:
+		return nil, nil
+	}
+	chatID := event.Event.Context.OpenChatID
+	if value == "" || chatID == "" {
+		return nil, nil
+	}
+
+	var senderID string
+	if op := event.Event.Operator; op != nil {
+		senderID = stringValue(op.UserID)
+		if senderID == "" {
+			senderID = op.OpenID
+		}
+	}
+	if senderID == "" {
+		return nil, nil
+	}
+	senderInfo := bus.SenderInfo{
+		Platform:    "feishu",
+		PlatformID:  senderID,
+		CanonicalID: identity.BuildCanonicalID("feishu", senderID),
+	}
+
+	// Card callbacks do not carry the chat type, so route by what the chat
+	// was when its last message arrived.
+	peer := bus.Peer{Kind: "group", ID: chatID}
+	if chatType, _ := c.chatTypes.Load(chatID); chatType == "p2p" {
+		peer = bus.Peer{Kind: "direct", ID: senderID}
+	}
+
+	metadata := map[string]string{}
+	if op := event.Event.Operator; op != nil && op.TenantKey != nil {
+		metadata["tenant_key"] = *op.TenantKey
+	}
+
+	c.HandleInteraction(ctx, peer, senderID, chatID, event.Event.Context.OpenMessageID,
+		kind, value, metadata, senderInfo)
+	return &larkcallback.CardActionTriggerResponse{}, nil
+}
+
 // --- Internal helpers ---
 
 // fetchBotOpenID calls the Feishu bot info API to retrieve and store the bot's open_id.
@@ -689,6 +826,49 @@ func appendMediaTags(content, messageType string, mediaRefs []string) string {
 }
 
 // sendCard sends an interactive card message to a chat.
+// deliverCard sends a card to msg's chat. It goes out as a reply when msg
+// names a message to reply to, and into the thread when msg names one; Feishu
+// only posts into a thread by replying to a message in it.
+func (c *FeishuChannel) deliverCard(ctx context.Context, msg bus.OutboundMessage, cardContent string) error {
+	replyTo := msg.ReplyToMessageID
+	if replyTo == "" && msg.ThreadID != "" {
+		if v, ok := c.threads.Load(msg.ThreadID); ok {
+			replyTo = v.(string)
+		}
+	}
+	if replyTo == "" {
+		return c.sendCard(ctx, msg.ChatID, cardContent)
+	}
+	return c.replyCard(ctx, replyTo, msg.ThreadID != "", cardContent)
+}
+
+func (c *FeishuChannel) replyCard(ctx context.Context, messageID string, inThread bool, cardContent string) error {
+	req := larkim.NewReplyMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewReplyMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypeInteractive).
+			Content(cardContent).
+			ReplyInThread(inThread).
+			Build()).
+		Build()
+
+	resp, err := c.client.Im.V1.Message.Reply(ctx, req)
+	if err != nil {
+		return fmt.Errorf("feishu reply card: %w", channels.ErrTemporary)
+	}
+
+	if !resp.Success() {
+		return fmt.Errorf("feishu api error (code=%d msg=%s): %w", resp.Code, resp.Msg, channels.ErrTemporary)
+	}
+
+	logger.DebugCF("feishu", "Feishu card reply sent", map[string]any{
+		"reply_to":  messageID,
+		"in_thread": inThread,
+	})
+
+	return nil
+}
+
 func (c *FeishuChannel) sendCard(ctx context.Context, chatID, cardContent string) error {
 	req := larkim.NewCreateMessageReqBuilder().
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
@@ -3,9 +3,22 @@
 package feishu
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	larkcallback "github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
 )
 
 func TestExtractContent(t *testing.T) {
@@ -254,3 +267,121 @@ func TestExtractFeishuSenderID(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleCardAction(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, err := NewFeishuChannel(config.FeishuConfig{}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.chatTypes.Store("oc_dm", "p2p")
+
+	resp, err := ch.handleCardAction(context.Background(), &larkcallback.CardActionTriggerEvent{
+		Event: &larkcallback.CardActionTriggerRequest{
+			Operator: &larkcallback.Operator{OpenID: "ou_1"},
+			Action: &larkcallback.CallBackAction{
+				Tag:    "select_static",
+				Value:  map[string]any{cardActionKey: "select"},
+				Option: "stg",
+			},
+			Context: &larkcallback.Context{OpenChatID: "oc_dm", OpenMessageID: "om_1"},
+		},
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("handleCardAction() = %v, %v", resp, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound for card action")
+	}
+	if msg.Content != "stg" || msg.ChatID != "oc_dm" || msg.Peer.Kind != "direct" || msg.Peer.ID != "ou_1" ||
+		msg.Metadata[channels.MetadataInteraction] != channels.InteractionSelect ||
+		msg.Metadata[channels.MetadataInteractionMessageID] != "om_1" {
+		t.Errorf("inbound = %+v", msg)
+	}
+
+	// Actions on cards not built by picoclaw are ignored.
+	resp, _ = ch.handleCardAction(context.Background(), &larkcallback.CardActionTriggerEvent{
+		Event: &larkcallback.CardActionTriggerRequest{
+			Action:  &larkcallback.CallBackAction{Value: map[string]any{"other": "x"}},
+			Context: &larkcallback.Context{OpenChatID: "oc_dm"},
+		},
+	})
+	if resp != nil {
+		t.Errorf("foreign action response = %+v", resp)
+	}
+}
+
+func TestThreadedMessageAndReply(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	var replyPath string
+	var replyBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "tenant_access_token"):
+			fmt.Fprint(w, `{"code":0,"tenant_access_token":"t","expire":7200}`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/messages/om_parent"):
+			fmt.Fprint(w, `{"code":0,"data":{"items":[{"message_id":"om_parent","msg_type":"text",`+
+				`"sender":{"id":"ou_bob"},"body":{"content":"{\"text\":\"@_user_1 restart the db\"}"},`+
+				`"mentions":[{"key":"@_user_1","name":"ops"}]}]}}`)
+		case strings.HasSuffix(r.URL.Path, "/reply"):
+			replyPath = r.URL.Path
+			_ = json.NewDecoder(r.Body).Decode(&replyBody)
+			fmt.Fprint(w, `{"code":0,"data":{}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	msgBus := bus.NewMessageBus()
+	ch, err := NewFeishuChannel(config.FeishuConfig{}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.client = lark.NewClient("app", "secret", lark.WithOpenBaseUrl(srv.URL))
+
+	err = ch.handleMessageReceive(context.Background(), &larkim.P2MessageReceiveV1{
+		Event: &larkim.P2MessageReceiveV1Data{
+			Sender: &larkim.EventSender{SenderId: &larkim.UserId{OpenId: strPtr("ou_alice")}},
+			Message: &larkim.EventMessage{
+				MessageId:   strPtr("om_2"),
+				ParentId:    strPtr("om_parent"),
+				ThreadId:    strPtr("omt_1"),
+				ChatId:      strPtr("oc_1"),
+				ChatType:    strPtr("p2p"),
+				MessageType: strPtr("text"),
+				Content:     strPtr(`{"text":"why?"}`),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	want := bus.QuotedMessage{MessageID: "om_parent", SenderID: "ou_bob", Content: "@ops restart the db"}
+	if msg.ThreadID != "omt_1" || msg.ReplyTo == nil || *msg.ReplyTo != want {
+		t.Fatalf("thread=%q reply_to=%+v", msg.ThreadID, msg.ReplyTo)
+	}
+
+	// Posting into the thread replies to its latest message.
+	ch.SetRunning(true)
+	if err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID: "oc_1", Content: "because", ThreadID: "omt_1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(replyPath, "/messages/om_2/reply") || replyBody["reply_in_thread"] != true {
+		t.Errorf("reply path=%q body=%v", replyPath, replyBody)
+	}
+}
@@ -0,0 +1,139 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// Metadata keys set on inbound messages produced by a button click or a
+// select menu choice.
+const (
+	// MetadataInteraction is "button" or "select".
+	MetadataInteraction = "interaction"
+	// MetadataInteractionMessageID is the platform ID of the message that
+	// carried the control, so the agent can tie the answer to its question.
+	MetadataInteractionMessageID = "interaction_message_id"
+)
+
+const (
+	InteractionButton = "button"
+	InteractionSelect = "select"
+)
+
+// InteractiveFallbackText renders content plus its interactive controls as
+// plain text for channels that cannot show them natively. Buttons and
+// select options are numbered so the user can answer with the number or the
+// option text; link buttons are shown with their URL.
+func InteractiveFallbackText(content string, in *bus.Interactive) string {
+	if in == nil {
+		return content
+	}
+
+	var sb strings.Builder
+	if in.Card != nil && in.Card.Title != "" {
+		sb.WriteString("**" + in.Card.Title + "**\n\n")
+	}
+	sb.WriteString(content)
+
+	var lines []string
+	for _, b := range in.Buttons {
+		if b.URL != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", b.Label, b.URL))
+			continue
+		}
+		lines = append(lines, b.Label)
+	}
+	if in.Select != nil {
+		for _, o := range in.Select.Options {
+			lines = append(lines, o.Label)
+		}
+	}
+	if len(lines) == 0 {
+		return sb.String()
+	}
+
+	if sb.Len() > 0 {
+		sb.WriteString("\n\n")
+	}
+	if in.Select != nil && in.Select.Placeholder != "" && len(in.Buttons) == 0 {
+		sb.WriteString(in.Select.Placeholder + "\n")
+	}
+	for i, line := range lines {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, line)
+	}
+	sb.WriteString("Reply with a number or the option text.")
+	return sb.String()
+}
+
+// HandleInteraction publishes a button click or select choice as an inbound
+// message whose content is the control's value. originMessageID is the
+// platform ID of the message carrying the control; it is recorded in
+// metadata rather than used as the inbound message ID, so repeated clicks
+// are not deduplicated and no reaction is attached to the bot's own message.
+func (c *BaseChannel) HandleInteraction(
+	ctx context.Context,
+	peer bus.Peer,
+	senderID, chatID, originMessageID, kind, value string,
+	metadata map[string]string,
+	senderOpts ...bus.SenderInfo,
+) {
+	if metadata == nil {
+		metadata = make(map[string]string, 2)
+	}
+	metadata[MetadataInteraction] = kind
+	if originMessageID != "" {
+		metadata[MetadataInteractionMessageID] = originMessageID
+	}
+	c.HandleMessage(ctx, peer, "", senderID, chatID, value, nil, metadata, senderOpts...)
+}
+
+// maxCallbackTokens bounds the number of long values a CallbackRegistry keeps.
+const maxCallbackTokens = 1000
+
+// CallbackRegistry maps interactive values that exceed a platform's callback
+// payload limit (e.g. Telegram's 64-byte callback_data) to short tokens.
+// Only the most recent maxCallbackTokens long values are remembered; older
+// tokens resolve to "".
+type CallbackRegistry struct {
+	mu     sync.Mutex
+	values map[string]string
+	order  []string
+}
+
+const callbackTokenPrefix = "pc:"
+
+// Encode returns value unchanged when it fits in maxLen bytes and does not
+// look like a token; otherwise it stores value and returns a short token.
+func (r *CallbackRegistry) Encode(value string, maxLen int) string {
+	if len(value) <= maxLen && !strings.HasPrefix(value, callbackTokenPrefix) {
+		return value
+	}
+	token := callbackTokenPrefix + uniqueID()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.values == nil {
+		r.values = make(map[string]string)
+	}
+	r.values[token] = value
+	r.order = append(r.order, token)
+	if len(r.order) > maxCallbackTokens {
+		delete(r.values, r.order[0])
+		r.order = r.order[1:]
+	}
+	return token
+}
+
+// Decode reverses Encode. Unknown or expired tokens yield "".
+func (r *CallbackRegistry) Decode(data string) string {
+	if !strings.HasPrefix(data, callbackTokenPrefix) {
+		return data
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.values[data]
+}
@@ -0,0 +1,226 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestInteractiveFallbackText(t *testing.T) {
+	in := &bus.Interactive{
+		Card: &bus.Card{Title: "Deploy"},
+		Buttons: []bus.Button{
+			{Label: "Yes", Value: "yes", Style: "primary"},
+			{Label: "Docs", URL: "https://example.com/docs"},
+		},
+		Select: &bus.Select{Options: []bus.SelectOption{{Label: "staging"}}},
+	}
+	got := InteractiveFallbackText("Ship it?", in)
+	want := "**Deploy**\n\nShip it?\n\n1. Yes\n2. Docs: https://example.com/docs\n3. staging\n" +
+		"Reply with a number or the option text."
+	if got != want {
+		t.Fatalf("InteractiveFallbackText() =\n%q\nwant\n%q", got, want)
+	}
+
+	if got := InteractiveFallbackText("plain", nil); got != "plain" {
+		t.Fatalf("nil interactive = %q", got)
+	}
+}
+
+// mockInteractiveChannel records which send path the Manager used.
+type mockInteractiveChannel struct {
+	mockChannel
+	mu          sync.Mutex
+	interactive []bus.OutboundMessage
+}
+
+func (m *mockInteractiveChannel) SendInteractive(_ context.Context, msg bus.OutboundMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interactive = append(m.interactive, msg)
+	return nil
+}
+
+func TestRunWorker_InteractiveRouting(t *testing.T) {
+	m := newTestManager()
+	in := &bus.Interactive{Buttons: []bus.Button{{Label: "A"}, {Label: "B"}}}
+
+	var mu sync.Mutex
+	var plain []bus.OutboundMessage
+	sendFn := func(_ context.Context, msg bus.OutboundMessage) error {
+		mu.Lock()
+		plain = append(plain, msg)
+		mu.Unlock()
+		return nil
+	}
+
+	// A channel without InteractiveCapable gets the numbered fallback.
+	w := &channelWorker{
+		ch:      &mockChannel{sendFn: sendFn},
+		queue:   make(chan outboundItem, 1),
+		done:    make(chan struct{}),
+		limiter: rate.NewLimiter(rate.Inf, 1),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.runWorker(ctx, "plain", w)
+	w.queue <- outboundItem{msg: bus.OutboundMessage{ChatID: "1", Content: "Pick", Interactive: in}}
+
+	ich := &mockInteractiveChannel{mockChannel: mockChannel{sendFn: sendFn}}
+	iw := &channelWorker{
+		ch:      ich,
+		queue:   make(chan outboundItem, 1),
+		done:    make(chan struct{}),
+		limiter: rate.NewLimiter(rate.Inf, 1),
+	}
+	go m.runWorker(ctx, "rich", iw)
+	var stopped atomic.Bool
+	m.RecordTypingStop("rich", "2", func() { stopped.Store(true) })
+	m.RecordPlaceholder("rich", "2", "ph")
+	iw.queue <- outboundItem{msg: bus.OutboundMessage{ChatID: "2", Content: "Pick", Interactive: in}}
+
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(plain) != 1 || plain[0].Interactive != nil || !strings.Contains(plain[0].Content, "1. A\n2. B") {
+		t.Fatalf("fallback sends = %+v", plain)
+	}
+	ich.mu.Lock()
+	defer ich.mu.Unlock()
+	if len(ich.interactive) != 1 || ich.interactive[0].Interactive != in || ich.interactive[0].Content != "Pick" {
+		t.Fatalf("interactive sends = %+v", ich.interactive)
+	}
+	if !stopped.Load() {
+		t.Error("typing was not stopped before the interactive send")
+	}
+	if _, ok := m.placeholders.Load("rich:2"); ok {
+		t.Error("placeholder should be dropped by the interactive send")
+	}
+}
+
+// streamingInteractiveChannel streams into placeholders and renders controls.
+type streamingInteractiveChannel struct {
+	recordingEditor
+	interactive []bus.OutboundMessage
+	sent        []string
+	deleted     []string
+	canDelete   bool
+}
+
+func (c *streamingInteractiveChannel) Send(_ context.Context, msg bus.OutboundMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg.Content)
+	return nil
+}
+
+func (c *streamingInteractiveChannel) SendInteractive(_ context.Context, msg bus.OutboundMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactive = append(c.interactive, msg)
+	return nil
+}
+
+func (c *streamingInteractiveChannel) DeleteMessage(_ context.Context, _, messageID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.canDelete {
+		return ErrSendFailed
+	}
+	c.deleted = append(c.deleted, messageID)
+	return nil
+}
+
+func TestSendWithRetry_InteractiveReplacesStreamedPlaceholder(t *testing.T) {
+	setStreamInterval(t, "test", time.Millisecond)
+
+	for _, canDelete := range []bool{true, false} {
+		m := newTestManager()
+		ch := &streamingInteractiveChannel{canDelete: canDelete}
+		m.channels["test"] = ch
+		w := &channelWorker{ch: ch, limiter: rate.NewLimiter(rate.Inf, 1)}
+		m.RecordPlaceholder("test", "1", "ph1")
+
+		ctx := context.Background()
+		m.handleOutboundDelta(ctx, bus.OutboundDeltaMessage{Channel: "test", ChatID: "1", Delta: "Pa", Content: "Pa"})
+		deadline := time.Now().Add(time.Second)
+		for len(ch.snapshot()) == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		in := &bus.Interactive{Buttons: []bus.Button{{Label: "OK", Value: "ok"}}}
+		final := bus.OutboundMessage{Channel: "test", ChatID: "1", Content: "Partial answer", Interactive: in}
+		if err := m.sendWithRetry(ctx, "test", w, final); err != nil {
+			t.Fatalf("sendWithRetry(interactive) = %v", err)
+		}
+		// The next unrelated reply must not edit the old placeholder.
+		next := bus.OutboundMessage{Channel: "test", ChatID: "1", Content: "later"}
+		if err := m.sendWithRetry(ctx, "test", w, next); err != nil {
+			t.Fatalf("sendWithRetry(text) = %v", err)
+		}
+
+		ch.mu.Lock()
+		if len(ch.interactive) != 1 || len(ch.sent) != 1 || ch.sent[0] != "later" {
+			t.Errorf("canDelete=%v: interactive=%+v sent=%q", canDelete, ch.interactive, ch.sent)
+		}
+		last := ch.edits[len(ch.edits)-1]
+		if canDelete && (len(ch.deleted) != 1 || ch.deleted[0] != "ph1" || last != "Pa") {
+			t.Errorf("deleted=%q edits=%q, want the streamed placeholder deleted", ch.deleted, ch.edits)
+		}
+		if !canDelete && last != "Partial answer" {
+			t.Errorf("edits=%q, want the placeholder finalized to the full text", ch.edits)
+		}
+		ch.mu.Unlock()
+		if _, ok := m.streams.Load("test:1"); ok {
+			t.Errorf("canDelete=%v: stream state left behind", canDelete)
+		}
+	}
+}
+
+func TestCallbackRegistry(t *testing.T) {
+	var r CallbackRegistry
+	if got := r.Encode("short", 64); got != "short" {
+		t.Fatalf("Encode(short) = %q", got)
+	}
+	long := strings.Repeat("x", 100)
+	token := r.Encode(long, 64)
+	if len(token) > 64 || token == long {
+		t.Fatalf("Encode(long) = %q", token)
+	}
+	if got := r.Decode(token); got != long {
+		t.Fatalf("Decode(token) = %q", got)
+	}
+	if got := r.Decode("short"); got != "short" {
+		t.Fatalf("Decode(short) = %q", got)
+	}
+	if got := r.Decode("pc:unknown"); got != "" {
+		t.Fatalf("Decode(unknown) = %q", got)
+	}
+}
+
+func TestHandleInteraction(t *testing.T) {
+	mb := bus.NewMessageBus()
+	ch := NewBaseChannel("test", nil, mb, nil)
+	ch.HandleInteraction(context.Background(), bus.Peer{Kind: "direct", ID: "u1"},
+		"u1", "c1", "m42", InteractionButton, "yes", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.Content != "yes" || msg.MessageID != "" ||
+		msg.Metadata[MetadataInteraction] != InteractionButton ||
+		msg.Metadata[MetadataInteractionMessageID] != "m42" {
+		t.Fatalf("inbound = %+v", msg)
+	}
+}
@@ -3,6 +3,7 @@ package channels
 import (
 	"context"
 
+	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/commands"
 )
 
@@ -19,6 +20,12 @@ type MessageEditor interface {
 	EditMessage(ctx context.Context, chatID string, messageID string, content string) error
 }
 
+// MessageDeleter — channels that can delete a message they sent. The manager
+// uses it to remove a placeholder that an interactive reply replaces.
+type MessageDeleter interface {
+	DeleteMessage(ctx context.Context, chatID string, messageID string) error
+}
+
 // ReactionCapable — channels that can add a reaction (e.g. 👀) to an inbound message.
 // ReactToMessage adds a reaction and returns an undo function to remove it.
 // The undo function MUST be idempotent and safe to call multiple times.
@@ -50,3 +57,12 @@ type PlaceholderRecorder interface {
 type CommandRegistrarCapable interface {
 	RegisterCommands(ctx context.Context, defs []commands.Definition) error
 }
+
+// InteractiveCapable — channels that can render bus.Interactive controls
+// (buttons, select menus, cards) natively. Manager routes outbound messages
+// carrying Interactive to SendInteractive; channels without it receive the
+// controls folded into a numbered text list via Send.
+// Clicks are reported back through BaseChannel.HandleInteraction.
+type InteractiveCapable interface {
+	SendInteractive(ctx context.Context, msg bus.OutboundMessage) error
+}
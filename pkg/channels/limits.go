@@ -0,0 +1,292 @@
+package channels
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// throttleReplyInterval bounds how often one sender is told about a limit.
+	throttleReplyInterval = time.Minute
+	// limiterIdleTTL is how long an untouched bucket, strike list or reply
+	// timestamp is kept; a bucket idle this long would be full again anyway.
+	limiterIdleTTL   = 10 * time.Minute
+	limiterPruneTick = time.Minute
+)
+
+// InboundVerdict is the outcome of InboundGuard.Check.
+type InboundVerdict int
+
+const (
+	InboundAllowed InboundVerdict = iota
+	// InboundThrottled means a sender, chat or channel rate limit was hit.
+	InboundThrottled
+	// InboundTooLong means the message exceeds the length cap.
+	InboundTooLong
+	// InboundBanned means the sender is temporarily banned for flooding.
+	InboundBanned
+)
+
+func (v InboundVerdict) String() string {
+	switch v {
+	case InboundAllowed:
+		return "allowed"
+	case InboundThrottled:
+		return "throttled"
+	case InboundTooLong:
+		return "too_long"
+	case InboundBanned:
+		return "banned"
+	}
+	return "unknown"
+}
+
+// InboundGuard enforces config.InboundLimitsConfig for all channels of a
+// Manager: token buckets per channel, chat and sender, a length cap, and
+// temporary bans for senders who keep hitting their limit. BaseChannel
+// consults it before publishing to the bus.
+type InboundGuard struct {
+	cfg config.InboundLimitsConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*guardBucket // "channel|chat|sender:<key>" → bucket
+	strikes   map[string][]time.Time  // sender key → recent throttle times
+	bans      map[string]time.Time    // sender key → ban expiry
+	replied   map[string]time.Time    // sender key → last throttle reply
+	lastPrune time.Time
+}
+
+type guardBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewInboundGuard returns a guard for cfg, or nil when limits are disabled.
+func NewInboundGuard(cfg config.InboundLimitsConfig) *InboundGuard {
+	if !cfg.Enabled && len(cfg.Overrides) == 0 {
+		return nil
+	}
+	return &InboundGuard{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: make(map[string]*guardBucket),
+		strikes: make(map[string][]time.Time),
+		bans:    make(map[string]time.Time),
+		replied: make(map[string]time.Time),
+	}
+}
+
+// Check decides whether an inbound message may be published. channel is
+// the channel name and key the instance key from ChannelKey. notify reports
+// whether the sender should be told, which happens at most once per
+// throttleReplyInterval per sender.
+func (g *InboundGuard) Check(channel, key, chatID, senderID, content string) (verdict InboundVerdict, notify bool) {
+	cfg := g.cfg.For(channel, key)
+	if !cfg.Enabled {
+		return InboundAllowed, false
+	}
+
+	now := g.now()
+	senderKey := key + "|" + senderID
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prune(now)
+
+	if until, ok := g.bans[senderKey]; ok {
+		if now.Before(until) {
+			return InboundBanned, false
+		}
+		delete(g.bans, senderKey)
+	}
+
+	if cfg.MaxMessageLength > 0 && len([]rune(content)) > cfg.MaxMessageLength {
+		return InboundTooLong, g.shouldNotify(senderKey, now)
+	}
+
+	// Reserve a token in every bucket first so a rejection by one of them
+	// does not use up the others.
+	limits := []struct {
+		key  string
+		rule config.RateLimitConfig
+	}{
+		{"channel:" + key, cfg.PerChannel},
+		{"chat:" + key + "|" + chatID, cfg.PerChat},
+		{"sender:" + senderKey, cfg.PerSender},
+	}
+	reservations := make([]*rate.Reservation, 0, len(limits))
+	allowed := true
+	senderLimited := false
+	for i, l := range limits {
+		lim := g.bucket(l.key, l.rule, now)
+		if lim == nil {
+			continue
+		}
+		r := lim.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if !r.OK() || r.DelayFrom(now) > 0 {
+			allowed = false
+			senderLimited = i == len(limits)-1
+			break
+		}
+	}
+	if allowed {
+		return InboundAllowed, false
+	}
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+
+	// Only the sender's own limit counts towards a flood ban: a busy chat
+	// or channel is not any single member's fault.
+	if senderLimited && g.strike(senderKey, cfg, now) {
+		logger.WarnCF("channels", "Sender temporarily banned for flooding", map[string]any{
+			"channel":   key,
+			"chat_id":   chatID,
+			"sender_id": senderID,
+			"minutes":   cfg.BanMinutes,
+		})
+	}
+	return InboundThrottled, g.shouldNotify(senderKey, now)
+}
+
+// bucket returns the limiter for key, creating it from rule; nil means the
+// rule sets no limit.
+func (g *InboundGuard) bucket(key string, rule config.RateLimitConfig, now time.Time) *rate.Limiter {
+	if rule.PerMinute <= 0 {
+		return nil
+	}
+	b, ok := g.buckets[key]
+	if !ok {
+		burst := max(rule.Burst, 1)
+		b = &guardBucket{limiter: rate.NewLimiter(rate.Limit(rule.PerMinute/60), burst)}
+		g.buckets[key] = b
+	}
+	b.lastSeen = now
+	return b.limiter
+}
+
+// strike records a throttled message and bans the sender once it has
+// FloodStrikes of them within the flood window. It reports a new ban.
+func (g *InboundGuard) strike(senderKey string, cfg config.InboundLimitsConfig, now time.Time) bool {
+	if cfg.FloodStrikes <= 0 || cfg.BanMinutes <= 0 {
+		return false
+	}
+	window := time.Duration(cfg.FloodWindowSeconds) * time.Second
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	recent := g.strikes[senderKey][:0]
+	for _, t := range g.strikes[senderKey] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	if len(recent) < cfg.FloodStrikes {
+		g.strikes[senderKey] = recent
+		return false
+	}
+
+	delete(g.strikes, senderKey)
+	g.bans[senderKey] = now.Add(time.Duration(cfg.BanMinutes) * time.Minute)
+	return true
+}
+
+func (g *InboundGuard) shouldNotify(senderKey string, now time.Time) bool {
+	if last, ok := g.replied[senderKey]; ok && now.Sub(last) < throttleReplyInterval {
+		return false
+	}
+	g.replied[senderKey] = now
+	return true
+}
+
+// prune drops idle state at most once per limiterPruneTick.
+func (g *InboundGuard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < limiterPruneTick {
+		return
+	}
+	g.lastPrune = now
+	for k, b := range g.buckets {
+		if now.Sub(b.lastSeen) > limiterIdleTTL {
+			delete(g.buckets, k)
+		}
+	}
+	for k, ts := range g.strikes {
+		if len(ts) == 0 || now.Sub(ts[len(ts)-1]) > limiterIdleTTL {
+			delete(g.strikes, k)
+		}
+	}
+	for k, until := range g.bans {
+		if !now.Before(until) {
+			delete(g.bans, k)
+		}
+	}
+	for k, t := range g.replied {
+		if now.Sub(t) > limiterIdleTTL {
+			delete(g.replied, k)
+		}
+	}
+}
+
+// Reply returns the text to send for a verdict on channel key; "" means
+// stay silent.
+func (g *InboundGuard) Reply(channel, key string, verdict InboundVerdict) string {
+	cfg := g.cfg.For(channel, key)
+	switch verdict {
+	case InboundTooLong:
+		return cfg.TooLongReply
+	case InboundThrottled:
+		return cfg.ThrottleReply
+	}
+	return ""
+}
+
+// admitInbound runs the inbound guard for a message and reports whether it
+// may be published. A rejected sender gets the configured reply in the same
+// chat and thread, rate limited by the guard itself.
+func (c *BaseChannel) admitInbound(ctx context.Context, chatID, senderID, messageID, threadID, content string) bool {
+	g := c.inboundGuard
+	if g == nil {
+		return true
+	}
+	key := ChannelKey(c.name, c.accountID)
+	verdict, notify := g.Check(c.name, key, chatID, senderID, content)
+	if verdict == InboundAllowed {
+		return true
+	}
+
+	logger.DebugCF("channels", "Inbound message rejected by limits", map[string]any{
+		"channel":   key,
+		"chat_id":   chatID,
+		"sender_id": senderID,
+		"verdict":   verdict.String(),
+	})
+	if reply := g.Reply(c.name, key, verdict); notify && reply != "" {
+		err := c.bus.PublishOutbound(ctx, bus.OutboundMessage{
+			Channel:          c.name,
+			AccountID:        c.accountID,
+			ChatID:           chatID,
+			Content:          reply,
+			ReplyToMessageID: messageID,
+			ThreadID:         threadID,
+		})
+		if err != nil {
+			logger.WarnCF("channels", "Failed to send limit notice", map[string]any{
+				"channel": key,
+				"chat_id": chatID,
+				"error":   err.Error(),
+			})
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newTestGuard(cfg config.InboundLimitsConfig) (*InboundGuard, *time.Time) {
+	g := NewInboundGuard(cfg)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestInboundGuard_SenderThrottle(t *testing.T) {
+	g, now := newTestGuard(config.InboundLimitsConfig{
+		Enabled:   true,
+		PerSender: config.RateLimitConfig{PerMinute: 6, Burst: 2},
+	})
+
+	for i := range 2 {
+		if v, _ := g.Check("telegram", "telegram", "c1", "u1", "hi"); v != InboundAllowed {
+			t.Fatalf("message %d: verdict %v, want allowed within burst", i, v)
+		}
+	}
+	v, notify := g.Check("telegram", "telegram", "c1", "u1", "hi")
+	if v != InboundThrottled || !notify {
+		t.Fatalf("third message = %v, notify=%v; want throttled with notice", v, notify)
+	}
+	if _, notify := g.Check("telegram", "telegram", "c1", "u1", "hi"); notify {
+		t.Fatal("notice should be sent at most once per interval")
+	}
+
+	// Another sender has its own bucket.
+	if v, _ := g.Check("telegram", "telegram", "c1", "u2", "hi"); v != InboundAllowed {
+		t.Fatalf("other sender verdict %v, want allowed", v)
+	}
+
+	// One token refills every 10s at 6/min.
+	*now = now.Add(10 * time.Second)
+	if v, _ := g.Check("telegram", "telegram", "c1", "u1", "hi"); v != InboundAllowed {
+		t.Fatalf("after refill verdict %v, want allowed", v)
+	}
+}
+
+func TestInboundGuard_ChatLimitDoesNotConsumeSenderTokens(t *testing.T) {
+	g, _ := newTestGuard(config.InboundLimitsConfig{
+		Enabled:   true,
+		PerSender: config.RateLimitConfig{PerMinute: 60, Burst: 1},
+		PerChat:   config.RateLimitConfig{PerMinute: 60, Burst: 1},
+	})
+
+	if v, _ := g.Check("discord", "discord", "c1", "u1", "hi"); v != InboundAllowed {
+		t.Fatalf("first verdict %v", v)
+	}
+	if v, _ := g.Check("discord", "discord", "c1", "u2", "hi"); v != InboundThrottled {
+		t.Fatalf("second sender in busy chat = %v, want throttled", v)
+	}
+	// u2 was rejected by the chat bucket, so its own token is still there.
+	if v, _ := g.Check("discord", "discord", "c2", "u2", "hi"); v != InboundAllowed {
+		t.Fatalf("u2 in another chat = %v, want allowed", v)
+	}
+}
+
+func TestInboundGuard_FloodBan(t *testing.T) {
+	g, now := newTestGuard(config.InboundLimitsConfig{
+		Enabled:            true,
+		PerSender:          config.RateLimitConfig{PerMinute: 1, Burst: 1},
+		FloodStrikes:       3,
+		FloodWindowSeconds: 60,
+		BanMinutes:         5,
+	})
+
+	g.Check("slack", "slack", "c1", "u1", "hi")
+	for range 3 {
+		if v, _ := g.Check("slack", "slack", "c1", "u1", "hi"); v != InboundThrottled {
+			t.Fatalf("verdict %v, want throttled", v)
+		}
+	}
+	// Banned even in another chat, and long after the bucket refilled.
+	*now = now.Add(4 * time.Minute)
+	if v, notify := g.Check("slack", "slack", "c2", "u1", "hi"); v != InboundBanned || notify {
+		t.Fatalf("verdict %v notify=%v, want silent ban", v, notify)
+	}
+	*now = now.Add(2 * time.Minute)
+	if v, _ := g.Check("slack", "slack", "c1", "u1", "hi"); v != InboundAllowed {
+		t.Fatalf("after ban verdict %v, want allowed", v)
+	}
+}
+
+func TestInboundGuard_TooLongAndOverrides(t *testing.T) {
+	g, _ := newTestGuard(config.InboundLimitsConfig{
+		Enabled:          true,
+		MaxMessageLength: 5,
+		TooLongReply:     "too long",
+		Overrides: map[string]config.InboundLimitsConfig{
+			"telegram:work": {Enabled: false},
+			"discord":       {Enabled: true, MaxMessageLength: 10},
+		},
+	})
+
+	if v, _ := g.Check("slack", "slack", "c", "u", "héllo"); v != InboundAllowed {
+		t.Fatalf("5 runes verdict %v, want allowed", v)
+	}
+	if v, _ := g.Check("slack", "slack", "c", "u", "hello!"); v != InboundTooLong {
+		t.Fatalf("6 runes verdict %v, want too long", v)
+	}
+	if got := g.Reply("slack", "slack", InboundTooLong); got != "too long" {
+		t.Fatalf("Reply = %q", got)
+	}
+	if v, _ := g.Check("telegram", "telegram:work", "c", "u", strings.Repeat("x", 100)); v != InboundAllowed {
+		t.Fatalf("disabled account verdict %v, want allowed", v)
+	}
+	if v, _ := g.Check("discord", "discord:alt", "c", "u", "hello!"); v != InboundAllowed {
+		t.Fatalf("discord override verdict %v, want allowed", v)
+	}
+}
+
+func TestAdmitInbound_AccountOverride(t *testing.T) {
+	mb := bus.NewMessageBus()
+	defer mb.Close()
+	g, _ := newTestGuard(config.InboundLimitsConfig{
+		Enabled:          true,
+		MaxMessageLength: 5,
+		Overrides: map[string]config.InboundLimitsConfig{
+			"telegram:work": {Enabled: false},
+		},
+	})
+	work := NewBaseChannel("telegram", nil, mb, nil)
+	work.SetAccountID("work")
+	work.SetInboundGuard(g)
+	main := NewBaseChannel("telegram", nil, mb, nil)
+	main.SetInboundGuard(g)
+
+	long := strings.Repeat("x", 100)
+	if !work.admitInbound(context.Background(), "c", "u", "m1", "", long) {
+		t.Fatal("work account should use its override and admit long messages")
+	}
+	if main.admitInbound(context.Background(), "c", "u", "m2", "", long) {
+		t.Fatal("default account should keep the global length limit")
+	}
+}
+
+func TestNewInboundGuard_Disabled(t *testing.T) {
+	if g := NewInboundGuard(config.InboundLimitsConfig{}); g != nil {
+		t.Fatal("expected nil guard when limits are disabled")
+	}
+}
+
+func TestHandleMessage_ThrottledSenderGetsReply(t *testing.T) {
+	mb := bus.NewMessageBus()
+	defer mb.Close()
+	ch := NewBaseChannel("test", nil, mb, nil)
+	g, _ := newTestGuard(config.InboundLimitsConfig{
+		Enabled:       true,
+		PerSender:     config.RateLimitConfig{PerMinute: 1, Burst: 1},
+		ThrottleReply: "slow down",
+	})
+	ch.SetInboundGuard(g)
+
+	peer := bus.Peer{Kind: "group", ID: "chat1"}
+	ch.HandleThreadedMessage(context.Background(), peer, ThreadInfo{ThreadID: "t1"},
+		"m1", "user1", "chat1", "hello", nil, nil)
+	ch.HandleThreadedMessage(context.Background(), peer, ThreadInfo{ThreadID: "t1"},
+		"m2", "user1", "chat1", "hello again", nil, nil)
+
+	if got := drainInbound(mb, 10); got != 1 {
+		t.Fatalf("expected 1 inbound message, got %d", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	out, ok := mb.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected a throttle notice")
+	}
+	if out.Content != "slow down" || out.ChatID != "chat1" || out.ReplyToMessageID != "m2" || out.ThreadID != "t1" {
+		t.Fatalf("unexpected notice: %+v", out)
+	}
+}
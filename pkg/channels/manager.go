@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +24,8 @@ import (
 	"github.com/sipeed/picoclaw/pkg/health"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/outbox"
+	"github.com/sipeed/picoclaw/pkg/routing"
 )
 
 const (
@@ -58,19 +61,25 @@ type placeholderEntry struct {
 
 // channelRateConfig maps channel name to per-second rate limit.
 var channelRateConfig = map[string]float64{
-	"telegram": 20,
-	"discord":  1,
-	"slack":    1,
-	"matrix":   2,
-	"line":     10,
-	"qq":       5,
-	"irc":      2,
+	"telegram":   20,
+	"discord":    1,
+	"slack":      1,
+	"matrix":     2,
+	"line":       10,
+	"qq":         5,
+	"irc":        2,
+	"email":      1,
+	"webhook":    10,
+	"mqtt":       20,
+	"mattermost": 10,
+	"rocketchat": 5,
+	"xmpp":       5,
 }
 
 type channelWorker struct {
 	ch         Channel
-	queue      chan bus.OutboundMessage
-	mediaQueue chan bus.OutboundMediaMessage
+	queue      chan outboundItem
+	mediaQueue chan outboundMediaItem
 	done       chan struct{}
 	mediaDone  chan struct{}
 	limiter    *rate.Limiter
@@ -89,6 +98,14 @@ type Manager struct {
 	placeholders  sync.Map // "channel:chatID" → placeholderID (string)
 	typingStops   sync.Map // "channel:chatID" → func()
 	reactionUndos sync.Map // "channel:chatID" → reactionEntry
+	streams       sync.Map // "channel:chatID" → *streamState
+
+	outbox            *outbox.Store
+	outboxMaxAttempts int
+	outboxInflight    sync.Map   // outbox entry ID → struct{}, while queued or sending
+	outboxClaimMu     sync.Mutex // orders outbox inserts and settles with retrier claims
+
+	inboundGuard *InboundGuard // nil when inbound limits are disabled
 }
 
 type asyncTask struct {
@@ -116,11 +133,34 @@ func (m *Manager) RecordReactionUndo(channel, chatID string, undo func()) {
 	m.reactionUndos.Store(key, reactionEntry{undo: undo, createdAt: time.Now()})
 }
 
-// preSend handles typing stop, reaction undo, and placeholder editing before sending a message.
+// preSend handles typing stop, reaction undo, streaming shutdown, and placeholder editing before sending a message.
 // Returns true if the message was edited into a placeholder (skip Send).
 func (m *Manager) preSend(ctx context.Context, name string, msg bus.OutboundMessage, ch Channel) bool {
 	key := name + ":" + msg.ChatID
 
+	// 1-3. Stop typing, undo reaction, finish streaming
+	m.stopIndicators(key)
+
+	// 4. Try editing placeholder
+	if v, loaded := m.placeholders.LoadAndDelete(key); loaded {
+		m.releaseStream(key)
+		if entry, ok := v.(placeholderEntry); ok && entry.id != "" {
+			if editor, ok := ch.(MessageEditor); ok {
+				if err := editor.EditMessage(ctx, msg.ChatID, entry.id, msg.Content); err == nil {
+					return true // edited successfully, skip Send
+				}
+				// edit failed → fall through to normal Send
+			}
+		}
+	}
+
+	return false
+}
+
+// stopIndicators stops typing, undoes the reaction and finishes any stream
+// recorded for key. Media only need this part of preSend and leave the
+// placeholder for the next text reply.
+func (m *Manager) stopIndicators(key string) {
 	// 1. Stop typing
 	if v, loaded := m.typingStops.LoadAndDelete(key); loaded {
 		if entry, ok := v.(typingEntry); ok {
@@ -135,19 +175,42 @@ func (m *Manager) preSend(ctx context.Context, name string, msg bus.OutboundMess
 		}
 	}
 
-	// 3. Try editing placeholder
-	if v, loaded := m.placeholders.LoadAndDelete(key); loaded {
-		if entry, ok := v.(placeholderEntry); ok && entry.id != "" {
-			if editor, ok := ch.(MessageEditor); ok {
-				if err := editor.EditMessage(ctx, msg.ChatID, entry.id, msg.Content); err == nil {
-					return true // edited successfully, skip Send
-				}
-				// edit failed → fall through to normal Send
-			}
+	// 3. Stop streaming edits so they cannot overwrite the final content
+	m.finishStream(key)
+}
+
+// retirePlaceholder prepares for an interactive message. A plain-text edit
+// cannot carry controls, so the placeholder, with whatever text was streamed
+// into it, is deleted where the channel supports that and otherwise edited
+// to the final text. Either way its entry is dropped so that a later reply
+// does not edit it.
+func (m *Manager) retirePlaceholder(ctx context.Context, name string, msg bus.OutboundMessage, ch Channel) {
+	key := name + ":" + msg.ChatID
+	m.stopIndicators(key)
+
+	v, loaded := m.placeholders.LoadAndDelete(key)
+	if !loaded {
+		return
+	}
+	m.releaseStream(key)
+	entry, ok := v.(placeholderEntry)
+	if !ok || entry.id == "" {
+		return
+	}
+	if deleter, ok := ch.(MessageDeleter); ok {
+		if err := deleter.DeleteMessage(ctx, msg.ChatID, entry.id); err == nil {
+			return
+		}
+	}
+	if editor, ok := ch.(MessageEditor); ok {
+		if err := editor.EditMessage(ctx, msg.ChatID, entry.id, msg.Content); err != nil {
+			logger.WarnCF("channels", "Failed to finalize placeholder", map[string]any{
+				"channel": name,
+				"chat_id": msg.ChatID,
+				"error":   err.Error(),
+			})
 		}
 	}
-
-	return false
 }
 
 func NewManager(cfg *config.Config, messageBus *bus.MessageBus, store media.MediaStore) (*Manager, error) {
@@ -158,6 +221,9 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus, store media.Medi
 		config:     cfg,
 		mediaStore: store,
 	}
+	if cfg != nil {
+		m.inboundGuard = NewInboundGuard(cfg.Channels.Limits)
+	}
 
 	if err := m.initChannels(); err != nil {
 		return nil, err
@@ -166,8 +232,91 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus, store media.Medi
 	return m, nil
 }
 
-// initChannel is a helper that looks up a factory by name and creates the channel.
-func (m *Manager) initChannel(name, displayName string) {
+// channelSpec describes how a channel is enabled: the key its settings live
+// under in config.ChannelsConfig and a check that those settings are usable.
+type channelSpec struct {
+	name        string
+	configKey   string
+	displayName string
+	enabled     func(c config.ChannelsConfig) bool
+}
+
+var channelSpecs = []channelSpec{
+	{"telegram", "telegram", "Telegram", func(c config.ChannelsConfig) bool {
+		return c.Telegram.Enabled && c.Telegram.Token != ""
+	}},
+	{"whatsapp_native", "whatsapp", "WhatsApp Native", func(c config.ChannelsConfig) bool {
+		return c.WhatsApp.Enabled && c.WhatsApp.UseNative
+	}},
+	{"whatsapp", "whatsapp", "WhatsApp", func(c config.ChannelsConfig) bool {
+		return c.WhatsApp.Enabled && !c.WhatsApp.UseNative && c.WhatsApp.BridgeURL != ""
+	}},
+	{"feishu", "feishu", "Feishu", func(c config.ChannelsConfig) bool {
+		return c.Feishu.Enabled
+	}},
+	{"discord", "discord", "Discord", func(c config.ChannelsConfig) bool {
+		return c.Discord.Enabled && c.Discord.Token != ""
+	}},
+	{"maixcam", "maixcam", "MaixCam", func(c config.ChannelsConfig) bool {
+		return c.MaixCam.Enabled
+	}},
+	{"qq", "qq", "QQ", func(c config.ChannelsConfig) bool {
+		return c.QQ.Enabled
+	}},
+	{"dingtalk", "dingtalk", "DingTalk", func(c config.ChannelsConfig) bool {
+		return c.DingTalk.Enabled && c.DingTalk.ClientID != ""
+	}},
+	{"slack", "slack", "Slack", func(c config.ChannelsConfig) bool {
+		return c.Slack.Enabled && c.Slack.BotToken != ""
+	}},
+	{"matrix", "matrix", "Matrix", func(c config.ChannelsConfig) bool {
+		return c.Matrix.Enabled && c.Matrix.Homeserver != "" && c.Matrix.UserID != "" && c.Matrix.AccessToken != ""
+	}},
+	{"line", "line", "LINE", func(c config.ChannelsConfig) bool {
+		return c.LINE.Enabled && c.LINE.ChannelAccessToken != ""
+	}},
+	{"onebot", "onebot", "OneBot", func(c config.ChannelsConfig) bool {
+		return c.OneBot.Enabled && c.OneBot.WSUrl != ""
+	}},
+	{"wecom", "wecom", "WeCom", func(c config.ChannelsConfig) bool {
+		return c.WeCom.Enabled && c.WeCom.Token != ""
+	}},
+	{"wecom_aibot", "wecom_aibot", "WeCom AI Bot", func(c config.ChannelsConfig) bool {
+		return c.WeComAIBot.Enabled && c.WeComAIBot.Token != ""
+	}},
+	{"wecom_app", "wecom_app", "WeCom App", func(c config.ChannelsConfig) bool {
+		return c.WeComApp.Enabled && c.WeComApp.CorpID != ""
+	}},
+	{"pico", "pico", "Pico", func(c config.ChannelsConfig) bool {
+		return c.Pico.Enabled && c.Pico.Token != ""
+	}},
+	{"irc", "irc", "IRC", func(c config.ChannelsConfig) bool {
+		return c.IRC.Enabled && c.IRC.Server != ""
+	}},
+	{"email", "email", "Email", func(c config.ChannelsConfig) bool {
+		return c.Email.Enabled && c.Email.IMAPServer != ""
+	}},
+	{"webhook", "webhook", "Webhook", func(c config.ChannelsConfig) bool {
+		return c.Webhook.Enabled
+	}},
+	{"mqtt", "mqtt", "MQTT", func(c config.ChannelsConfig) bool {
+		return c.MQTT.Enabled && c.MQTT.Broker != ""
+	}},
+	{"mattermost", "mattermost", "Mattermost", func(c config.ChannelsConfig) bool {
+		return c.Mattermost.Enabled && c.Mattermost.ServerURL != "" && c.Mattermost.Token != ""
+	}},
+	{"rocketchat", "rocketchat", "Rocket.Chat", func(c config.ChannelsConfig) bool {
+		return c.RocketChat.Enabled && c.RocketChat.ServerURL != "" && c.RocketChat.AuthToken != ""
+	}},
+	{"xmpp", "xmpp", "XMPP", func(c config.ChannelsConfig) bool {
+		return c.XMPP.Enabled && c.XMPP.JID != ""
+	}},
+}
+
+// initChannel is a helper that looks up a factory by name and creates the
+// channel for one account; cfg carries that account's settings.
+func (m *Manager) initChannel(cfg *config.Config, name, accountID, displayName string) {
+	key := ChannelKey(name, accountID)
 	f, ok := getFactory(name)
 	if !ok {
 		logger.WarnCF("channels", "Factory not registered", map[string]any{
@@ -177,14 +326,22 @@ func (m *Manager) initChannel(name, displayName string) {
 	}
 	logger.DebugCF("channels", "Attempting to initialize channel", map[string]any{
 		"channel": displayName,
+		"key":     key,
 	})
-	ch, err := f(m.config, m.bus)
+	ch, err := f(cfg, m.bus)
 	if err != nil {
 		logger.ErrorCF("channels", "Failed to initialize channel", map[string]any{
 			"channel": displayName,
+			"key":     key,
 			"error":   err.Error(),
 		})
-	} else {
+	} else if ch != nil {
+		// Tag the channel with its account so inbound messages carry it
+		if accountID != "" {
+			if setter, ok := ch.(interface{ SetAccountID(id string) }); ok {
+				setter.SetAccountID(accountID)
+			}
+		}
 		// Inject MediaStore if channel supports it
 		if m.mediaStore != nil {
 			if setter, ok := ch.(interface{ SetMediaStore(s media.MediaStore) }); ok {
@@ -195,13 +352,20 @@ func (m *Manager) initChannel(name, displayName string) {
 		if setter, ok := ch.(interface{ SetPlaceholderRecorder(r PlaceholderRecorder) }); ok {
 			setter.SetPlaceholderRecorder(m)
 		}
+		// Inject the shared inbound guard so limits apply across all channels
+		if m.inboundGuard != nil {
+			if setter, ok := ch.(interface{ SetInboundGuard(g *InboundGuard) }); ok {
+				setter.SetInboundGuard(m.inboundGuard)
+			}
+		}
 		// Inject owner reference so BaseChannel.HandleMessage can auto-trigger typing/reaction
 		if setter, ok := ch.(interface{ SetOwner(ch Channel) }); ok {
 			setter.SetOwner(ch)
 		}
-		m.channels[name] = ch
+		m.channels[key] = ch
 		logger.InfoCF("channels", "Channel enabled successfully", map[string]any{
 			"channel": displayName,
+			"key":     key,
 		})
 	}
 }
@@ -209,76 +373,11 @@ func (m *Manager) initChannel(name, displayName string) {
 func (m *Manager) initChannels() error {
 	logger.InfoC("channels", "Initializing channel manager")
 
-	if m.config.Channels.Telegram.Enabled && m.config.Channels.Telegram.Token != "" {
-		m.initChannel("telegram", "Telegram")
-	}
-
-	if m.config.Channels.WhatsApp.Enabled {
-		waCfg := m.config.Channels.WhatsApp
-		if waCfg.UseNative {
-			m.initChannel("whatsapp_native", "WhatsApp Native")
-		} else if waCfg.BridgeURL != "" {
-			m.initChannel("whatsapp", "WhatsApp")
+	for _, spec := range channelSpecs {
+		if spec.enabled(m.config.Channels) {
+			m.initChannel(m.config, spec.name, "", spec.displayName)
 		}
-	}
-
-	if m.config.Channels.Feishu.Enabled {
-		m.initChannel("feishu", "Feishu")
-	}
-
-	if m.config.Channels.Discord.Enabled && m.config.Channels.Discord.Token != "" {
-		m.initChannel("discord", "Discord")
-	}
-
-	if m.config.Channels.MaixCam.Enabled {
-		m.initChannel("maixcam", "MaixCam")
-	}
-
-	if m.config.Channels.QQ.Enabled {
-		m.initChannel("qq", "QQ")
-	}
-
-	if m.config.Channels.DingTalk.Enabled && m.config.Channels.DingTalk.ClientID != "" {
-		m.initChannel("dingtalk", "DingTalk")
-	}
-
-	if m.config.Channels.Slack.Enabled && m.config.Channels.Slack.BotToken != "" {
-		m.initChannel("slack", "Slack")
-	}
-
-	if m.config.Channels.Matrix.Enabled &&
-		m.config.Channels.Matrix.Homeserver != "" &&
-		m.config.Channels.Matrix.UserID != "" &&
-		m.config.Channels.Matrix.AccessToken != "" {
-		m.initChannel("matrix", "Matrix")
-	}
-
-	if m.config.Channels.LINE.Enabled && m.config.Channels.LINE.ChannelAccessToken != "" {
-		m.initChannel("line", "LINE")
-	}
-
-	if m.config.Channels.OneBot.Enabled && m.config.Channels.OneBot.WSUrl != "" {
-		m.initChannel("onebot", "OneBot")
-	}
-
-	if m.config.Channels.WeCom.Enabled && m.config.Channels.WeCom.Token != "" {
-		m.initChannel("wecom", "WeCom")
-	}
-
-	if m.config.Channels.WeComAIBot.Enabled && m.config.Channels.WeComAIBot.Token != "" {
-		m.initChannel("wecom_aibot", "WeCom AI Bot")
-	}
-
-	if m.config.Channels.WeComApp.Enabled && m.config.Channels.WeComApp.CorpID != "" {
-		m.initChannel("wecom_app", "WeCom App")
-	}
-
-	if m.config.Channels.Pico.Enabled && m.config.Channels.Pico.Token != "" {
-		m.initChannel("pico", "Pico")
-	}
-
-	if m.config.Channels.IRC.Enabled && m.config.Channels.IRC.Server != "" {
-		m.initChannel("irc", "IRC")
+		m.initChannelAccounts(spec)
 	}
 
 	logger.InfoCF("channels", "Channel initialization completed", map[string]any{
@@ -288,6 +387,34 @@ func (m *Manager) initChannels() error {
 	return nil
 }
 
+// initChannelAccounts creates one channel per extra account configured under
+// the spec's config key.
+func (m *Manager) initChannelAccounts(spec channelSpec) {
+	seen := make(map[string]bool)
+	for _, rawID := range m.config.Channels.AccountIDs(spec.configKey) {
+		accountID := routing.NormalizeAccountID(rawID)
+		if strings.TrimSpace(rawID) == "" || accountID == routing.DefaultAccountID || seen[accountID] {
+			logger.ErrorCF("channels", "Skipping channel account with an empty, reserved or duplicate id",
+				map[string]any{"channel": spec.displayName, "account_id": rawID})
+			continue
+		}
+		seen[accountID] = true
+
+		cfg, err := m.config.WithChannelAccount(spec.configKey, rawID)
+		if err != nil {
+			logger.ErrorCF("channels", "Invalid channel account config", map[string]any{
+				"channel":    spec.displayName,
+				"account_id": rawID,
+				"error":      err.Error(),
+			})
+			continue
+		}
+		if spec.enabled(cfg.Channels) {
+			m.initChannel(cfg, spec.name, accountID, spec.displayName)
+		}
+	}
+}
+
 // SetupHTTPServer creates a shared HTTP server with the given listen address.
 // It registers health endpoints from the health server and discovers channels
 // that implement WebhookHandler and/or HealthChecker to register their handlers.
@@ -299,9 +426,18 @@ func (m *Manager) SetupHTTPServer(addr string, healthServer *health.Server) {
 		healthServer.RegisterOnMux(m.mux)
 	}
 
-	// Discover and register webhook handlers and health checkers
+	// Discover and register webhook handlers and health checkers. Accounts of
+	// the same channel need distinct paths; a second handler for a path would
+	// make the mux panic, so it is skipped instead.
+	registered := make(map[string]string)
 	for name, ch := range m.channels {
 		if wh, ok := ch.(WebhookHandler); ok {
+			if owner, dup := registered[wh.WebhookPath()]; dup {
+				logger.ErrorCF("channels", "Webhook path already in use; set a different webhook_path for this account",
+					map[string]any{"channel": name, "path": wh.WebhookPath(), "used_by": owner})
+				continue
+			}
+			registered[wh.WebhookPath()] = name
 			m.mux.Handle(wh.WebhookPath(), wh)
 			logger.InfoCF("channels", "Webhook handler registered", map[string]any{
 				"channel": name,
@@ -309,6 +445,10 @@ func (m *Manager) SetupHTTPServer(addr string, healthServer *health.Server) {
 			})
 		}
 		if hc, ok := ch.(HealthChecker); ok {
+			if _, dup := registered[hc.HealthPath()]; dup {
+				continue
+			}
+			registered[hc.HealthPath()] = name
 			m.mux.HandleFunc(hc.HealthPath(), hc.HealthHandler)
 			logger.InfoCF("channels", "Health endpoint registered", map[string]any{
 				"channel": name,
@@ -351,7 +491,7 @@ func (m *Manager) StartAll(ctx context.Context) error {
 			continue
 		}
 		// Lazily create worker only after channel starts successfully
-		w := newChannelWorker(name, channel)
+		w := newChannelWorker(channel.Name(), channel)
 		m.workers[name] = w
 		go m.runWorker(dispatchCtx, name, w)
 		go m.runMediaWorker(dispatchCtx, name, w)
@@ -360,10 +500,16 @@ func (m *Manager) StartAll(ctx context.Context) error {
 	// Start the dispatcher that reads from the bus and routes to workers
 	go m.dispatchOutbound(dispatchCtx)
 	go m.dispatchOutboundMedia(dispatchCtx)
+	go m.dispatchOutboundDelta(dispatchCtx)
 
 	// Start the TTL janitor that cleans up stale typing/placeholder entries
 	go m.runTTLJanitor(dispatchCtx)
 
+	// Replay persisted messages and redeliver failed ones when they are due
+	if m.outbox != nil {
+		go m.runOutboxRetrier(dispatchCtx)
+	}
+
 	// Start shared HTTP server if configured
 	if m.httpServer != nil {
 		go func() {
@@ -457,8 +603,8 @@ func newChannelWorker(name string, ch Channel) *channelWorker {
 
 	return &channelWorker{
 		ch:         ch,
-		queue:      make(chan bus.OutboundMessage, defaultChannelQueueSize),
-		mediaQueue: make(chan bus.OutboundMediaMessage, defaultChannelQueueSize),
+		queue:      make(chan outboundItem, defaultChannelQueueSize),
+		mediaQueue: make(chan outboundMediaItem, defaultChannelQueueSize),
 		done:       make(chan struct{}),
 		mediaDone:  make(chan struct{}),
 		limiter:    rate.NewLimiter(rate.Limit(rateVal), burst),
@@ -471,23 +617,46 @@ func (m *Manager) runWorker(ctx context.Context, name string, w *channelWorker)
 	defer close(w.done)
 	for {
 		select {
-		case msg, ok := <-w.queue:
+		case item, ok := <-w.queue:
 			if !ok {
 				return
 			}
+			msg := item.msg
+			if msg.Interactive != nil {
+				if _, ok := w.ch.(InteractiveCapable); !ok {
+					msg.Content = InteractiveFallbackText(msg.Content, msg.Interactive)
+					msg.Interactive = nil
+				}
+			}
 			maxLen := 0
 			if mlp, ok := w.ch.(MessageLengthProvider); ok {
 				maxLen = mlp.MaxMessageLength()
 			}
 			if maxLen > 0 && len([]rune(msg.Content)) > maxLen {
 				chunks := SplitMessage(msg.Content, maxLen)
-				for _, chunk := range chunks {
+				sent := item.progress
+				var err error
+				// A persisted message stops at the first failed part and
+				// resumes from it on the next delivery round.
+				for i := sent; i < len(chunks) && (err == nil || item.outboxID == 0); i++ {
 					chunkMsg := msg
-					chunkMsg.Content = chunk
-					m.sendWithRetry(ctx, name, w, chunkMsg)
+					chunkMsg.Content = chunks[i]
+					// Controls belong under the last part of the text;
+					// only the first part quotes the replied-to message.
+					if i < len(chunks)-1 {
+						chunkMsg.Interactive = nil
+					}
+					if i > 0 {
+						chunkMsg.ReplyToMessageID = ""
+					}
+					if err = m.sendWithRetry(ctx, name, w, chunkMsg); err == nil {
+						sent = i + 1
+					}
 				}
+				m.settleOutbox(item.outboxID, item.progress, sent, err)
 			} else {
-				m.sendWithRetry(ctx, name, w, msg)
+				err := m.sendWithRetry(ctx, name, w, msg)
+				m.settleOutbox(item.outboxID, 0, 0, err)
 			}
 		case <-ctx.Done():
 			return
@@ -500,23 +669,34 @@ func (m *Manager) runWorker(ctx context.Context, name string, w *channelWorker)
 //   - ErrNotRunning / ErrSendFailed: permanent, no retry
 //   - ErrRateLimit: fixed delay retry
 //   - ErrTemporary / unknown: exponential backoff retry
-func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWorker, msg bus.OutboundMessage) {
+//
+// It returns the last send error, or the context error when shutting down.
+func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWorker, msg bus.OutboundMessage) error {
 	// Rate limit: wait for token
 	if err := w.limiter.Wait(ctx); err != nil {
 		// ctx canceled, shutting down
-		return
+		return err
 	}
 
+	ic, interactive := w.ch.(InteractiveCapable)
+	interactive = interactive && msg.Interactive != nil
+
 	// Pre-send: stop typing and try to edit placeholder
-	if m.preSend(ctx, name, msg, w.ch) {
-		return // placeholder was edited successfully, skip Send
+	if interactive {
+		m.retirePlaceholder(ctx, name, msg, w.ch)
+	} else if m.preSend(ctx, name, msg, w.ch) {
+		return nil // placeholder was edited successfully, skip Send
 	}
 
 	var lastErr error
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		lastErr = w.ch.Send(ctx, msg)
+		if interactive {
+			lastErr = ic.SendInteractive(ctx, msg)
+		} else {
+			lastErr = w.ch.Send(ctx, msg)
+		}
 		if lastErr == nil {
-			return
+			return nil
 		}
 
 		// Permanent failures — don't retry
@@ -535,7 +715,7 @@ func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWork
 			case <-time.After(rateLimitDelay):
 				continue
 			case <-ctx.Done():
-				return
+				return ctx.Err()
 			}
 		}
 
@@ -544,7 +724,7 @@ func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWork
 		select {
 		case <-time.After(backoff):
 		case <-ctx.Done():
-			return
+			return ctx.Err()
 		}
 	}
 
@@ -555,6 +735,7 @@ func (m *Manager) sendWithRetry(ctx context.Context, name string, w *channelWork
 		"error":   lastErr.Error(),
 		"retries": maxRetries,
 	})
+	return lastErr
 }
 
 func dispatchLoop[M any](
@@ -577,7 +758,7 @@ func dispatchLoop[M any](
 		channel := getChannel(msg)
 
 		// Silently skip internal channels
-		if constants.IsInternalChannel(channel) {
+		if name, _ := ParseChannelKey(channel); constants.IsInternalChannel(name) {
 			continue
 		}
 
@@ -605,10 +786,11 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 	dispatchLoop(
 		ctx, m,
 		m.bus.SubscribeOutbound,
-		func(msg bus.OutboundMessage) string { return msg.Channel },
+		func(msg bus.OutboundMessage) string { return ChannelKey(msg.Channel, msg.AccountID) },
 		func(ctx context.Context, w *channelWorker, msg bus.OutboundMessage) bool {
+			item := outboundItem{msg: msg, outboxID: m.trackOutbound(outbox.KindMessage, msg.Channel, msg.AccountID, msg.ChatID, msg)}
 			select {
-			case w.queue <- msg:
+			case w.queue <- item:
 				return true
 			case <-ctx.Done():
 				return false
@@ -625,10 +807,11 @@ func (m *Manager) dispatchOutboundMedia(ctx context.Context) {
 	dispatchLoop(
 		ctx, m,
 		m.bus.SubscribeOutboundMedia,
-		func(msg bus.OutboundMediaMessage) string { return msg.Channel },
+		func(msg bus.OutboundMediaMessage) string { return ChannelKey(msg.Channel, msg.AccountID) },
 		func(ctx context.Context, w *channelWorker, msg bus.OutboundMediaMessage) bool {
+			item := outboundMediaItem{msg: msg, outboxID: m.trackOutbound(outbox.KindMedia, msg.Channel, msg.AccountID, msg.ChatID, msg)}
 			select {
-			case w.mediaQueue <- msg:
+			case w.mediaQueue <- item:
 				return true
 			case <-ctx.Done():
 				return false
@@ -646,11 +829,12 @@ func (m *Manager) runMediaWorker(ctx context.Context, name string, w *channelWor
 	defer close(w.mediaDone)
 	for {
 		select {
-		case msg, ok := <-w.mediaQueue:
+		case item, ok := <-w.mediaQueue:
 			if !ok {
 				return
 			}
-			m.sendMediaWithRetry(ctx, name, w, msg)
+			err := m.sendMediaWithRetry(ctx, name, w, item.msg)
+			m.settleOutbox(item.outboxID, 0, 0, err)
 		case <-ctx.Done():
 			return
 		}
@@ -659,25 +843,25 @@ func (m *Manager) runMediaWorker(ctx context.Context, name string, w *channelWor
 
 // sendMediaWithRetry sends a media message through the channel with rate limiting and
 // retry logic. If the channel does not implement MediaSender, it silently skips.
-func (m *Manager) sendMediaWithRetry(ctx context.Context, name string, w *channelWorker, msg bus.OutboundMediaMessage) {
+func (m *Manager) sendMediaWithRetry(ctx context.Context, name string, w *channelWorker, msg bus.OutboundMediaMessage) error {
 	ms, ok := w.ch.(MediaSender)
 	if !ok {
 		logger.DebugCF("channels", "Channel does not support MediaSender, skipping media", map[string]any{
 			"channel": name,
 		})
-		return
+		return nil
 	}
 
 	// Rate limit: wait for token
 	if err := w.limiter.Wait(ctx); err != nil {
-		return
+		return err
 	}
 
 	var lastErr error
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		lastErr = ms.SendMedia(ctx, msg)
 		if lastErr == nil {
-			return
+			return nil
 		}
 
 		// Permanent failures — don't retry
@@ -696,7 +880,7 @@ func (m *Manager) sendMediaWithRetry(ctx context.Context, name string, w *channe
 			case <-time.After(rateLimitDelay):
 				continue
 			case <-ctx.Done():
-				return
+				return ctx.Err()
 			}
 		}
 
@@ -705,7 +889,7 @@ func (m *Manager) sendMediaWithRetry(ctx context.Context, name string, w *channe
 		select {
 		case <-time.After(backoff):
 		case <-ctx.Done():
-			return
+			return ctx.Err()
 		}
 	}
 
@@ -716,9 +900,10 @@ func (m *Manager) sendMediaWithRetry(ctx context.Context, name string, w *channe
 		"error":   lastErr.Error(),
 		"retries": maxRetries,
 	})
+	return lastErr
 }
 
-// runTTLJanitor periodically scans the typingStops and placeholders maps
+// runTTLJanitor periodically scans the typingStops, placeholders and streams maps
 // and evicts entries that have exceeded their TTL. This prevents memory
 // accumulation when outbound paths fail to trigger preSend (e.g. LLM errors).
 func (m *Manager) runTTLJanitor(ctx context.Context) {
@@ -758,6 +943,16 @@ func (m *Manager) runTTLJanitor(ctx context.Context) {
 				}
 				return true
 			})
+			m.streams.Range(func(key, value any) bool {
+				if st, ok := value.(*streamState); ok {
+					if now.Sub(st.createdAt) > placeholderTTL {
+						if _, loaded := m.streams.LoadAndDelete(key); loaded {
+							st.close()
+						}
+					}
+				}
+				return true
+			})
 		}
 	}
 }
@@ -813,6 +1008,8 @@ func (m *Manager) UnregisterChannel(name string) {
 	delete(m.channels, name)
 }
 
+// SendToChannel queues content for chatID on the channel instance identified
+// by channelName, which may be a key built by ChannelKey.
 func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, content string) error {
 	m.mu.RLock()
 	_, exists := m.channels[channelName]
@@ -823,15 +1020,18 @@ func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, conten
 		return fmt.Errorf("channel %s not found", channelName)
 	}
 
+	name, accountID := ParseChannelKey(channelName)
 	msg := bus.OutboundMessage{
-		Channel: channelName,
-		ChatID:  chatID,
-		Content: content,
+		Channel:   name,
+		AccountID: accountID,
+		ChatID:    chatID,
+		Content:   content,
 	}
 
 	if wExists && w != nil {
+		item := outboundItem{msg: msg, outboxID: m.trackOutbound(outbox.KindMessage, name, accountID, chatID, msg)}
 		select {
-		case w.queue <- msg:
+		case w.queue <- item:
 			return nil
 		case <-ctx.Done():
 			return ctx.Err()
@@ -269,7 +269,7 @@ func TestWorkerRateLimiter(t *testing.T) {
 	// Create a worker with a low rate: 2 msg/s, burst 1
 	w := &channelWorker{
 		ch:      ch,
-		queue:   make(chan bus.OutboundMessage, 10),
+		queue:   make(chan outboundItem, 10),
 		done:    make(chan struct{}),
 		limiter: rate.NewLimiter(2, 1),
 	}
@@ -280,7 +280,7 @@ func TestWorkerRateLimiter(t *testing.T) {
 
 	// Enqueue 4 messages
 	for i := range 4 {
-		w.queue <- bus.OutboundMessage{Channel: "test", ChatID: "1", Content: fmt.Sprintf("msg%d", i)}
+		w.queue <- outboundItem{msg: bus.OutboundMessage{Channel: "test", ChatID: "1", Content: fmt.Sprintf("msg%d", i)}}
 	}
 
 	// Wait enough time for all messages to be sent (4 msgs at 2/s = ~2s, give extra margin)
@@ -346,7 +346,7 @@ func TestRunWorker_MessageSplitting(t *testing.T) {
 
 	w := &channelWorker{
 		ch:      ch,
-		queue:   make(chan bus.OutboundMessage, 10),
+		queue:   make(chan outboundItem, 10),
 		done:    make(chan struct{}),
 		limiter: rate.NewLimiter(rate.Inf, 1),
 	}
@@ -356,7 +356,7 @@ func TestRunWorker_MessageSplitting(t *testing.T) {
 	go m.runWorker(ctx, "test", w)
 
 	// Send a message that should be split
-	w.queue <- bus.OutboundMessage{Channel: "test", ChatID: "1", Content: "hello world"}
+	w.queue <- outboundItem{msg: bus.OutboundMessage{Channel: "test", ChatID: "1", Content: "hello world"}}
 
 	time.Sleep(100 * time.Millisecond)
 
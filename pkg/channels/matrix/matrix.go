@@ -284,8 +284,9 @@ func (c *MatrixChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	}
 
 	_, err := c.client.SendMessageEvent(ctx, roomID, event.EventMessage, &event.MessageEventContent{
-		MsgType: event.MsgText,
-		Body:    content,
+		MsgType:   event.MsgText,
+		Body:      content,
+		RelatesTo: matrixRelation(msg),
 	})
 	if err != nil {
 		return fmt.Errorf("matrix send: %w", channels.ErrTemporary)
@@ -293,6 +294,25 @@ func (c *MatrixChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	return nil
 }
 
+// matrixRelation places an outbound message in its thread and marks it as a
+// reply. Thread messages that reply to nothing in particular fall back to
+// quoting the thread root, as the spec asks for clients without threads.
+func matrixRelation(msg bus.OutboundMessage) *event.RelatesTo {
+	threadID := id.EventID(msg.ThreadID)
+	replyTo := id.EventID(msg.ReplyToMessageID)
+	if threadID == "" && replyTo == "" {
+		return nil
+	}
+	rel := &event.RelatesTo{}
+	if replyTo != "" {
+		rel.SetReplyTo(replyTo)
+	}
+	if threadID != "" {
+		rel.SetThread(threadID, threadID)
+	}
+	return rel
+}
+
 // SendMedia implements channels.MediaSender.
 func (c *MatrixChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessage) error {
 	if !c.IsRunning() {
@@ -547,6 +567,9 @@ func (c *MatrixChannel) handleMessageEvent(ctx context.Context, evt *event.Event
 		return
 	}
 
+	// Drop the quoted parent some clients prepend; it is passed as ReplyTo.
+	msgEvt.RemoveReplyFallback()
+
 	roomID := evt.RoomID.String()
 	scope := channels.BuildMediaScope("matrix", roomID, evt.ID.String())
 
@@ -618,9 +641,15 @@ func (c *MatrixChannel) handleMessageEvent(ctx context.Context, evt *event.Event
 		metadata["reply_to_msg_id"] = replyTo.String()
 	}
 
-	c.HandleMessage(
+	thread := channels.ThreadInfo{ThreadID: msgEvt.GetRelatesTo().GetThreadParent().String()}
+	if replyTo := msgEvt.GetRelatesTo().GetNonFallbackReplyTo(); replyTo != "" {
+		thread.ReplyTo = c.quotedEvent(ctx, evt.RoomID, replyTo)
+	}
+
+	c.HandleThreadedMessage(
 		c.baseContext(),
 		bus.Peer{Kind: peerKind, ID: peerID},
+		thread,
 		evt.ID.String(),
 		senderID,
 		roomID,
@@ -631,6 +660,29 @@ func (c *MatrixChannel) handleMessageEvent(ctx context.Context, evt *event.Event
 	)
 }
 
+// quotedEvent fetches the event a message replies to. Only the ID is known
+// when the event cannot be fetched or is not a plain message (e.g. encrypted).
+func (c *MatrixChannel) quotedEvent(ctx context.Context, roomID id.RoomID, eventID id.EventID) *bus.QuotedMessage {
+	quoted := &bus.QuotedMessage{MessageID: eventID.String()}
+	parent, err := c.client.GetEvent(ctx, roomID, eventID)
+	if err != nil {
+		logger.DebugCF("matrix", "Failed to fetch replied-to event", map[string]any{
+			"event_id": eventID.String(),
+			"error":    err.Error(),
+		})
+		return quoted
+	}
+	quoted.SenderID = parent.Sender.String()
+	if parent.Content.Parsed == nil {
+		_ = parent.Content.ParseRaw(parent.Type)
+	}
+	if msgEvt, ok := parent.Content.Parsed.(*event.MessageEventContent); ok {
+		msgEvt.RemoveReplyFallback()
+		quoted.Content = msgEvt.Body
+	}
+	return quoted
+}
+
 func (c *MatrixChannel) extractInboundContent(
 	ctx context.Context,
 	msgEvt *event.MessageEventContent,
@@ -2,14 +2,20 @@ package matrix
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
 )
 
 func TestMatrixLocalpartMentionRegexp(t *testing.T) {
@@ -289,3 +295,55 @@ func TestMatrixOutboundContent(t *testing.T) {
 		t.Fatalf("unexpected fallback body: %q", noCaption.Body)
 	}
 }
+
+func TestMatrixRelation(t *testing.T) {
+	if rel := matrixRelation(bus.OutboundMessage{ChatID: "!r:x"}); rel != nil {
+		t.Fatalf("expected no relation, got %+v", rel)
+	}
+
+	rel := matrixRelation(bus.OutboundMessage{ReplyToMessageID: "$m"})
+	if rel.GetReplyTo() != "$m" || rel.GetThreadParent() != "" {
+		t.Fatalf("reply relation = %+v", rel)
+	}
+
+	rel = matrixRelation(bus.OutboundMessage{ThreadID: "$root"})
+	if rel.GetThreadParent() != "$root" || !rel.IsFallingBack || rel.GetNonFallbackReplyTo() != "" {
+		t.Fatalf("thread relation = %+v", rel)
+	}
+
+	rel = matrixRelation(bus.OutboundMessage{ThreadID: "$root", ReplyToMessageID: "$m"})
+	if rel.GetThreadParent() != "$root" || rel.GetNonFallbackReplyTo() != "$m" {
+		t.Fatalf("threaded reply relation = %+v", rel)
+	}
+}
+
+func TestQuotedEvent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/event/$p") {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errcode":"M_NOT_FOUND","error":"Event not found"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"type":"m.room.message","event_id":"$p","room_id":"!r:x","sender":"@bob:x",`+
+			`"content":{"msgtype":"m.text","body":"restart the db"}}`)
+	}))
+	defer srv.Close()
+
+	client, err := mautrix.NewClient(srv.URL, "@bot:x", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &MatrixChannel{client: client}
+
+	quoted := c.quotedEvent(context.Background(), "!r:x", "$p")
+	want := bus.QuotedMessage{MessageID: "$p", SenderID: "@bob:x", Content: "restart the db"}
+	if *quoted != want {
+		t.Fatalf("quoted = %+v, want %+v", *quoted, want)
+	}
+
+	missing := c.quotedEvent(context.Background(), "!r:x", "$gone")
+	if missing.MessageID != "$gone" || missing.Content != "" {
+		t.Fatalf("missing = %+v", *missing)
+	}
+}
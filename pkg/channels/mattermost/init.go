@@ -0,0 +1,16 @@
+package mattermost
+
+import (
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func init() {
+	channels.RegisterFactory("mattermost", func(cfg *config.Config, b *bus.MessageBus) (channels.Channel, error) {
+		if !cfg.Channels.Mattermost.Enabled {
+			return nil, nil
+		}
+		return NewMattermostChannel(cfg.Channels.Mattermost, b)
+	})
+}
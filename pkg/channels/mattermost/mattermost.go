@@ -0,0 +1,607 @@
+package mattermost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	apiPrefix         = "/api/v4"
+	reactionEmoji     = "eyes"
+	typingInterval    = 4 * time.Second
+	reconnectInterval = 5 * time.Second
+	readTimeout       = 90 * time.Second
+	pingInterval      = 30 * time.Second
+)
+
+// MattermostChannel connects to a Mattermost server as a bot account:
+// events arrive over the WebSocket API, replies go through the REST API.
+//
+// Chat IDs are "<channel_id>" or "<channel_id>/<root_post_id>" for threads.
+// Posts in team channels always get a threaded reply, so every thread is its
+// own conversation.
+type MattermostChannel struct {
+	*channels.BaseChannel
+	config      config.MattermostConfig
+	baseURL     string
+	httpClient  *http.Client
+	botUserID   string
+	botUsername string
+	// mentionPattern matches "@<bot username>" case-insensitively.
+	mentionPattern *regexp.Regexp
+	ctx            context.Context
+	cancel         context.CancelFunc
+
+	connMu sync.Mutex
+	conn   *websocket.Conn
+}
+
+type mmUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type mmPost struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"user_id"`
+	ChannelID string   `json:"channel_id"`
+	RootID    string   `json:"root_id"`
+	Message   string   `json:"message"`
+	Type      string   `json:"type"`
+	FileIDs   []string `json:"file_ids"`
+}
+
+type mmEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+type mmPostedData struct {
+	Post        string `json:"post"`
+	ChannelType string `json:"channel_type"`
+	SenderName  string `json:"sender_name"`
+	Mentions    string `json:"mentions"`
+}
+
+type mmFileInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewMattermostChannel creates a Mattermost channel.
+func NewMattermostChannel(cfg config.MattermostConfig, messageBus *bus.MessageBus) (*MattermostChannel, error) {
+	if cfg.ServerURL == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("mattermost server_url and token are required")
+	}
+	u, err := url.Parse(strings.TrimRight(cfg.ServerURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid mattermost server_url %q", cfg.ServerURL)
+	}
+
+	base := channels.NewBaseChannel("mattermost", cfg, messageBus, cfg.AllowFrom,
+		channels.WithMaxMessageLength(16383),
+		channels.WithGroupTrigger(cfg.GroupTrigger),
+		channels.WithReasoningChannelID(cfg.ReasoningChannelID),
+	)
+
+	return &MattermostChannel{
+		BaseChannel: base,
+		config:      cfg,
+		baseURL:     u.String(),
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Start looks up the bot user and connects the WebSocket event stream.
+func (c *MattermostChannel) Start(ctx context.Context) error {
+	logger.InfoC("mattermost", "Starting Mattermost channel")
+
+	var me mmUser
+	if err := c.api(ctx, http.MethodGet, "/users/me", nil, &me); err != nil {
+		return fmt.Errorf("mattermost auth failed: %w", err)
+	}
+	c.botUserID = me.ID
+	c.botUsername = me.Username
+	if me.Username != "" {
+		c.mentionPattern = regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(me.Username) + `\b`)
+	}
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	go c.eventLoop()
+
+	c.SetRunning(true)
+	logger.InfoCF("mattermost", "Mattermost channel started", map[string]any{
+		"bot_user_id": c.botUserID,
+		"username":    c.botUsername,
+	})
+	return nil
+}
+
+// Stop closes the event stream.
+func (c *MattermostChannel) Stop(ctx context.Context) error {
+	logger.InfoC("mattermost", "Stopping Mattermost channel")
+	c.SetRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.connMu.Lock()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.connMu.Unlock()
+	return nil
+}
+
+func (c *MattermostChannel) websocketURL() string {
+	u, _ := url.Parse(c.baseURL + apiPrefix + "/websocket")
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	return u.String()
+}
+
+// eventLoop keeps a WebSocket connection open until the channel stops.
+func (c *MattermostChannel) eventLoop() {
+	for {
+		if err := c.listen(); err != nil && c.ctx.Err() == nil {
+			logger.WarnCF("mattermost", "WebSocket disconnected, reconnecting", map[string]any{
+				"error": err.Error(),
+			})
+		}
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(reconnectInterval):
+		}
+	}
+}
+
+func (c *MattermostChannel) listen() error {
+	header := http.Header{"Authorization": []string{"Bearer " + c.config.Token}}
+	dialer := websocket.Dialer{HandshakeTimeout: 15 * time.Second, Proxy: http.ProxyFromEnvironment}
+	conn, resp, err := dialer.DialContext(c.ctx, c.websocketURL(), header)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	c.connMu.Lock()
+	c.conn = conn
+	c.connMu.Unlock()
+	defer func() {
+		c.connMu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.connMu.Unlock()
+	}()
+
+	// Older servers ignore the header and expect an authentication challenge.
+	challenge := map[string]any{
+		"seq":    1,
+		"action": "authentication_challenge",
+		"data":   map[string]string{"token": c.config.Token},
+	}
+	if err := c.writeJSON(conn, challenge); err != nil {
+		return err
+	}
+
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+	done := make(chan struct{})
+	defer close(done)
+	go c.pinger(conn, done)
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var event mmEvent
+		if err := json.Unmarshal(data, &event); err != nil || event.Event == "" {
+			continue
+		}
+		if event.Event == "posted" {
+			var posted mmPostedData
+			if err := json.Unmarshal(event.Data, &posted); err == nil {
+				c.handlePosted(posted)
+			}
+		}
+	}
+}
+
+func (c *MattermostChannel) pinger(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.connMu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+			c.connMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *MattermostChannel) writeJSON(conn *websocket.Conn, v any) error {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return conn.WriteJSON(v)
+}
+
+func (c *MattermostChannel) handlePosted(data mmPostedData) {
+	var post mmPost
+	if err := json.Unmarshal([]byte(data.Post), &post); err != nil {
+		return
+	}
+	// Skip our own posts and system messages (joins, header changes, ...).
+	if post.UserID == c.botUserID || post.UserID == "" || post.Type != "" {
+		return
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "mattermost",
+		PlatformID:  post.UserID,
+		CanonicalID: identity.BuildCanonicalID("mattermost", post.UserID),
+		Username:    strings.TrimPrefix(data.SenderName, "@"),
+	}
+	if !c.IsAllowedSender(sender) {
+		logger.DebugCF("mattermost", "Message rejected by allowlist", map[string]any{
+			"user_id": post.UserID,
+		})
+		return
+	}
+
+	isDirect := data.ChannelType == "D"
+	content, mentioned := c.stripBotMention(post.Message)
+	mentioned = mentioned || c.isMentioned(data.Mentions)
+	if !isDirect {
+		respond, cleaned := c.ShouldRespondInGroup(mentioned, content)
+		if !respond {
+			return
+		}
+		content = cleaned
+	}
+
+	rootID := post.RootID
+	if rootID == "" && !isDirect {
+		rootID = post.ID
+	}
+	chatID := post.ChannelID
+	if rootID != "" {
+		chatID = post.ChannelID + "/" + rootID
+	}
+
+	var mediaPaths []string
+	if len(post.FileIDs) > 0 {
+		scope := channels.BuildMediaScope("mattermost", chatID, post.ID)
+		for _, fileID := range post.FileIDs {
+			ref, name := c.downloadFile(fileID, scope)
+			if ref == "" {
+				continue
+			}
+			mediaPaths = append(mediaPaths, ref)
+			content += fmt.Sprintf("\n[file: %s]", name)
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	// Threads get their own session; direct messages stay with the user.
+	peer := bus.Peer{Kind: "channel", ID: chatID}
+	if isDirect {
+		peer = bus.Peer{Kind: "direct", ID: post.UserID}
+	}
+
+	metadata := map[string]string{
+		"platform":   "mattermost",
+		"channel_id": post.ChannelID,
+		"root_id":    rootID,
+		"post_id":    post.ID,
+	}
+	if mentioned {
+		metadata["is_mention"] = "true"
+	}
+
+	logger.DebugCF("mattermost", "Received message", map[string]any{
+		"sender_id": post.UserID,
+		"chat_id":   chatID,
+		"preview":   utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(c.ctx, peer, post.ID, post.UserID, chatID, content, mediaPaths, metadata, sender)
+}
+
+// stripBotMention removes "@<bot username>" and reports whether it was there.
+func (c *MattermostChannel) stripBotMention(text string) (string, bool) {
+	if c.mentionPattern == nil || !c.mentionPattern.MatchString(text) {
+		return strings.TrimSpace(text), false
+	}
+	return strings.TrimSpace(c.mentionPattern.ReplaceAllString(text, "")), true
+}
+
+func (c *MattermostChannel) isMentioned(mentions string) bool {
+	if mentions == "" {
+		return false
+	}
+	var ids []string
+	if err := json.Unmarshal([]byte(mentions), &ids); err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if id == c.botUserID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *MattermostChannel) downloadFile(fileID, scope string) (ref, name string) {
+	var info mmFileInfo
+	if err := c.api(c.ctx, http.MethodGet, "/files/"+fileID+"/info", nil, &info); err != nil {
+		logger.WarnCF("mattermost", "Failed to get file info", map[string]any{
+			"file_id": fileID,
+			"error":   err.Error(),
+		})
+		return "", ""
+	}
+	localPath := utils.DownloadFile(c.baseURL+apiPrefix+"/files/"+fileID, info.Name, utils.DownloadOptions{
+		LoggerPrefix: "mattermost",
+		ExtraHeaders: map[string]string{"Authorization": "Bearer " + c.config.Token},
+	})
+	if localPath == "" {
+		return "", ""
+	}
+	if store := c.GetMediaStore(); store != nil {
+		ref, err := store.Store(localPath, media.MediaMeta{
+			Filename: info.Name,
+			Source:   "mattermost",
+		}, scope)
+		if err == nil {
+			return ref, info.Name
+		}
+	}
+	return localPath, info.Name
+}
+
+func parseChatID(chatID string) (channelID, rootID string) {
+	channelID, rootID, _ = strings.Cut(chatID, "/")
+	return channelID, rootID
+}
+
+// Send posts a message, in the thread when the chat is one.
+func (c *MattermostChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	_, err := c.createPost(ctx, msg.ChatID, msg.Content, nil)
+	return err
+}
+
+func (c *MattermostChannel) createPost(ctx context.Context, chatID, message string, fileIDs []string) (string, error) {
+	channelID, rootID := parseChatID(chatID)
+	if channelID == "" {
+		return "", fmt.Errorf("invalid mattermost chat ID %q: %w", chatID, channels.ErrSendFailed)
+	}
+	var created mmPost
+	err := c.api(ctx, http.MethodPost, "/posts", map[string]any{
+		"channel_id": channelID,
+		"root_id":    rootID,
+		"message":    message,
+		"file_ids":   fileIDs,
+	}, &created)
+	return created.ID, err
+}
+
+// EditMessage implements channels.MessageEditor.
+func (c *MattermostChannel) EditMessage(ctx context.Context, chatID, messageID, content string) error {
+	return c.api(ctx, http.MethodPut, "/posts/"+messageID+"/patch", map[string]string{"message": content}, nil)
+}
+
+// SendPlaceholder implements channels.PlaceholderCapable.
+func (c *MattermostChannel) SendPlaceholder(ctx context.Context, chatID string) (string, error) {
+	if !c.config.Placeholder.Enabled {
+		return "", nil
+	}
+	text := c.config.Placeholder.Text
+	if text == "" {
+		text = "Thinking... 💭"
+	}
+	return c.createPost(ctx, chatID, text, nil)
+}
+
+// StartTyping implements channels.TypingCapable. Mattermost shows the
+// indicator for a few seconds, so it is refreshed until stop is called.
+func (c *MattermostChannel) StartTyping(ctx context.Context, chatID string) (func(), error) {
+	if !c.config.Typing.Enabled || !c.IsRunning() {
+		return func() {}, nil
+	}
+	channelID, rootID := parseChatID(chatID)
+	body := map[string]string{"channel_id": channelID, "parent_id": rootID}
+
+	typingCtx, cancel := context.WithCancel(c.ctx)
+	go func() {
+		ticker := time.NewTicker(typingInterval)
+		defer ticker.Stop()
+		for {
+			if err := c.api(typingCtx, http.MethodPost, "/users/me/typing", body, nil); err != nil && typingCtx.Err() == nil {
+				logger.DebugCF("mattermost", "Typing indicator failed", map[string]any{"error": err.Error()})
+			}
+			select {
+			case <-typingCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel, nil
+}
+
+// ReactToMessage implements channels.ReactionCapable.
+func (c *MattermostChannel) ReactToMessage(ctx context.Context, chatID, messageID string) (func(), error) {
+	err := c.api(ctx, http.MethodPost, "/reactions", map[string]string{
+		"user_id":    c.botUserID,
+		"post_id":    messageID,
+		"emoji_name": reactionEmoji,
+	}, nil)
+	if err != nil {
+		return func() {}, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			path := fmt.Sprintf("/users/%s/posts/%s/reactions/%s", c.botUserID, messageID, reactionEmoji)
+			_ = c.api(context.Background(), http.MethodDelete, path, nil, nil)
+		})
+	}, nil
+}
+
+// SendMedia implements channels.MediaSender by uploading the files and
+// posting them with the first caption.
+func (c *MattermostChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	store := c.GetMediaStore()
+	if store == nil {
+		return fmt.Errorf("no media store available: %w", channels.ErrSendFailed)
+	}
+	channelID, _ := parseChatID(msg.ChatID)
+
+	var fileIDs, captions []string
+	for _, part := range msg.Parts {
+		localPath, err := store.Resolve(part.Ref)
+		if err != nil {
+			logger.ErrorCF("mattermost", "Failed to resolve media ref", map[string]any{
+				"ref":   part.Ref,
+				"error": err.Error(),
+			})
+			continue
+		}
+		filename := part.Filename
+		if filename == "" {
+			filename = filepath.Base(localPath)
+		}
+		fileID, err := c.uploadFile(ctx, channelID, localPath, filename)
+		if err != nil {
+			return err
+		}
+		fileIDs = append(fileIDs, fileID)
+		if part.Caption != "" {
+			captions = append(captions, part.Caption)
+		}
+	}
+	if len(fileIDs) == 0 {
+		return nil
+	}
+	_, err := c.createPost(ctx, msg.ChatID, strings.Join(captions, "\n"), fileIDs)
+	return err
+}
+
+func (c *MattermostChannel) uploadFile(ctx context.Context, channelID, localPath, filename string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", localPath, channels.ErrSendFailed)
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("channel_id", channelID)
+	part, err := w.CreateFormFile("files", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	var out struct {
+		FileInfos []mmFileInfo `json:"file_infos"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/files", &body, w.FormDataContentType(), &out); err != nil {
+		return "", err
+	}
+	if len(out.FileInfos) == 0 {
+		return "", fmt.Errorf("mattermost upload returned no file: %w", channels.ErrSendFailed)
+	}
+	return out.FileInfos[0].ID, nil
+}
+
+// api performs a JSON REST call; in and out may be nil.
+func (c *MattermostChannel) api(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	return c.do(ctx, method, path, body, "application/json", out)
+}
+
+func (c *MattermostChannel) do(ctx context.Context, method, path string, body io.Reader, contentType string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return channels.ClassifyNetError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return channels.ClassifySendError(resp.StatusCode,
+			fmt.Errorf("mattermost %s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg))))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,227 @@
+package mattermost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type fakeServer struct {
+	*httptest.Server
+	events chan any
+
+	mu     sync.Mutex
+	bodies map[string][]map[string]any
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	fs := &fakeServer{events: make(chan any, 8), bodies: make(map[string][]map[string]any)}
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/users/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(mmUser{ID: "bot", Username: "picobot"})
+	})
+	mux.HandleFunc("/api/v4/websocket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		for event := range fs.events {
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/api/v4/", func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/api/v4")
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		fs.mu.Lock()
+		fs.bodies[key] = append(fs.bodies[key], body)
+		fs.mu.Unlock()
+		if key == "POST /posts" {
+			_ = json.NewEncoder(w).Encode(mmPost{ID: "reply1"})
+			return
+		}
+		w.Write([]byte("{}"))
+	})
+	fs.Server = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		close(fs.events)
+		fs.Close()
+	})
+	return fs
+}
+
+func (fs *fakeServer) body(key string) map[string]any {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	list := fs.bodies[key]
+	if len(list) == 0 {
+		return nil
+	}
+	return list[len(list)-1]
+}
+
+func (fs *fakeServer) called(key string) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	_, ok := fs.bodies[key]
+	return ok
+}
+
+func postedEvent(post mmPost, channelType string, mentions ...string) map[string]any {
+	postJSON, _ := json.Marshal(post)
+	mentionsJSON, _ := json.Marshal(mentions)
+	return map[string]any{
+		"event": "posted",
+		"data": map[string]string{
+			"post":         string(postJSON),
+			"channel_type": channelType,
+			"sender_name":  "@alice",
+			"mentions":     string(mentionsJSON),
+		},
+	}
+}
+
+func startChannel(t *testing.T, fs *fakeServer, cfg config.MattermostConfig) (*MattermostChannel, *bus.MessageBus) {
+	t.Helper()
+	cfg.ServerURL = fs.URL
+	cfg.Token = "tok"
+	msgBus := bus.NewMessageBus()
+	ch, err := NewMattermostChannel(cfg, msgBus)
+	if err != nil {
+		t.Fatalf("NewMattermostChannel() error = %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = ch.Stop(context.Background()) })
+	return ch, msgBus
+}
+
+func nextInbound(t *testing.T, msgBus *bus.MessageBus) (bus.InboundMessage, bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	return msgBus.ConsumeInbound(ctx)
+}
+
+func TestMattermostInboundThreadsAndMentions(t *testing.T) {
+	fs := newFakeServer(t)
+	_, msgBus := startChannel(t, fs, config.MattermostConfig{
+		GroupTrigger: config.GroupTriggerConfig{MentionOnly: true},
+	})
+
+	// Not mentioned in a team channel: ignored.
+	fs.events <- postedEvent(mmPost{ID: "p0", UserID: "alice", ChannelID: "town", Message: "hello all"}, "O")
+	if msg, ok := nextInbound(t, msgBus); ok {
+		t.Fatalf("unexpected inbound %+v", msg)
+	}
+
+	// Mentioned top-level post: the reply starts a thread on it.
+	fs.events <- postedEvent(mmPost{ID: "p1", UserID: "alice", ChannelID: "town", Message: "@PicoBot what's up?"}, "O", "bot")
+	msg, ok := nextInbound(t, msgBus)
+	if !ok {
+		t.Fatal("no inbound for mention")
+	}
+	if msg.ChatID != "town/p1" || msg.Content != "what's up?" || msg.Peer.Kind != "channel" || msg.Peer.ID != "town/p1" {
+		t.Errorf("mention inbound = chat %q content %q peer %+v", msg.ChatID, msg.Content, msg.Peer)
+	}
+
+	// Follow-up in the thread keeps the same chat and session peer.
+	fs.events <- postedEvent(mmPost{ID: "p2", UserID: "alice", ChannelID: "town", RootID: "p1", Message: "@picobot and now?"}, "O")
+	msg, ok = nextInbound(t, msgBus)
+	if !ok || msg.ChatID != "town/p1" || msg.Peer.ID != "town/p1" {
+		t.Errorf("thread reply inbound = %+v, %v", msg, ok)
+	}
+
+	// Direct messages need no mention and are keyed by user.
+	fs.events <- postedEvent(mmPost{ID: "p3", UserID: "alice", ChannelID: "dm", Message: "hi"}, "D")
+	msg, ok = nextInbound(t, msgBus)
+	if !ok || msg.ChatID != "dm" || msg.Peer.Kind != "direct" || msg.Peer.ID != "alice" {
+		t.Errorf("DM inbound = %+v, %v", msg, ok)
+	}
+
+	// Own posts and system messages are ignored.
+	fs.events <- postedEvent(mmPost{ID: "p4", UserID: "bot", ChannelID: "dm", Message: "echo"}, "D")
+	fs.events <- postedEvent(mmPost{ID: "p5", UserID: "alice", ChannelID: "dm", Type: "system_join_channel"}, "D")
+	if msg, ok := nextInbound(t, msgBus); ok {
+		t.Fatalf("unexpected inbound %+v", msg)
+	}
+}
+
+func TestMattermostOutbound(t *testing.T) {
+	fs := newFakeServer(t)
+	ch, _ := startChannel(t, fs, config.MattermostConfig{
+		Typing:      config.TypingConfig{Enabled: true},
+		Placeholder: config.PlaceholderConfig{Enabled: true, Text: "..."},
+	})
+	ctx := context.Background()
+
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "town/p1", Content: "answer"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if body := fs.body("POST /posts"); body["channel_id"] != "town" || body["root_id"] != "p1" || body["message"] != "answer" {
+		t.Errorf("post body = %v", body)
+	}
+
+	id, err := ch.SendPlaceholder(ctx, "town/p1")
+	if err != nil || id != "reply1" {
+		t.Fatalf("SendPlaceholder() = %q, %v", id, err)
+	}
+	if err := ch.EditMessage(ctx, "town/p1", id, "final"); err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	if body := fs.body("PUT /posts/reply1/patch"); body["message"] != "final" {
+		t.Errorf("patch body = %v", body)
+	}
+
+	undo, err := ch.ReactToMessage(ctx, "town/p1", "p1")
+	if err != nil {
+		t.Fatalf("ReactToMessage() error = %v", err)
+	}
+	if body := fs.body("POST /reactions"); body["post_id"] != "p1" || body["emoji_name"] != "eyes" {
+		t.Errorf("reaction body = %v", body)
+	}
+	undo()
+	if !fs.called("DELETE /users/bot/posts/p1/reactions/eyes") {
+		t.Error("reaction was not removed")
+	}
+
+	stop, err := ch.StartTyping(ctx, "town/p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	deadline := time.Now().Add(2 * time.Second)
+	for fs.body("POST /users/me/typing") == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if body := fs.body("POST /users/me/typing"); body["channel_id"] != "town" || body["parent_id"] != "p1" {
+		t.Errorf("typing body = %v", body)
+	}
+}
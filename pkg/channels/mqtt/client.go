@@ -0,0 +1,365 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errClientClosed = errors.New("mqtt: connection closed")
+
+// willMessage is published by the broker when the client disappears.
+type willMessage struct {
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
+}
+
+type clientOptions struct {
+	broker    string
+	clientID  string
+	username  string
+	password  string
+	keepAlive time.Duration
+	will      *willMessage
+	tlsConfig *tls.Config
+}
+
+// client is a minimal MQTT 3.1.1 client: clean sessions, QoS 0 and 1.
+type client struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	keepAlive time.Duration
+	onPublish func(publishPacket)
+
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	nextID uint16
+	acks   map[uint16]chan []byte
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// brokerAddress turns tcp://, mqtt://, ssl://, tls:// or mqtts:// URLs (or a
+// bare host:port) into a dial address and whether TLS is used.
+func brokerAddress(broker string) (addr string, useTLS bool, err error) {
+	if !strings.Contains(broker, "://") {
+		broker = "tcp://" + broker
+	}
+	u, err := url.Parse(broker)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid broker %q: %w", broker, err)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	if u.Hostname() == "" {
+		return "", false, fmt.Errorf("invalid broker %q: missing host", broker)
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+func dialMQTT(ctx context.Context, opts clientOptions, onPublish func(publishPacket)) (*client, error) {
+	addr, useTLS, err := brokerAddress(opts.broker)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if useTLS {
+		cfg := opts.tlsConfig
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			cfg = cfg.Clone()
+			cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	c := &client{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		keepAlive: opts.keepAlive,
+		onPublish: onPublish,
+		acks:      make(map[uint16]chan []byte),
+		closed:    make(chan struct{}),
+	}
+	if err := c.connect(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.readLoop()
+	if c.keepAlive > 0 {
+		go c.pingLoop()
+	}
+	return c, nil
+}
+
+func (c *client) connect(opts clientOptions) error {
+	flags := byte(0x02) // clean session
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+	if opts.will != nil {
+		flags |= 0x04 | opts.will.qos<<3
+		if opts.will.retain {
+			flags |= 0x20
+		}
+	}
+	if opts.username != "" {
+		flags |= 0x80
+		if opts.password != "" {
+			flags |= 0x40
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.keepAlive/time.Second))
+	body = appendString(body, opts.clientID)
+	if opts.will != nil {
+		body = appendString(body, opts.will.topic)
+		body = appendBytes(body, opts.will.payload)
+	}
+	if opts.username != "" {
+		body = appendString(body, opts.username)
+		if opts.password != "" {
+			body = appendString(body, opts.password)
+		}
+	}
+
+	_ = c.conn.SetDeadline(time.Now().Add(15 * time.Second))
+	defer c.conn.SetDeadline(time.Time{})
+	if _, err := c.conn.Write(encodePacket(packetConnect, 0, body)); err != nil {
+		return err
+	}
+	pk, err := readPacket(c.reader)
+	if err != nil {
+		return fmt.Errorf("read CONNACK: %w", err)
+	}
+	if pk.kind != packetConnack || len(pk.body) < 2 {
+		return fmt.Errorf("unexpected packet type %d instead of CONNACK", pk.kind)
+	}
+	if code := pk.body[1]; code != 0 {
+		return fmt.Errorf("broker refused connection: %s", connackReason(code))
+	}
+	return nil
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("code %d", code)
+	}
+}
+
+func (c *client) readLoop() {
+	for {
+		if c.keepAlive > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		}
+		pk, err := readPacket(c.reader)
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+		switch pk.kind {
+		case packetPublish:
+			p, err := decodePublish(pk)
+			if err != nil {
+				c.shutdown(err)
+				return
+			}
+			if p.qos > 0 {
+				if err := c.write(encodeAck(packetPuback, p.id)); err != nil {
+					c.shutdown(err)
+					return
+				}
+			}
+			if c.onPublish != nil {
+				c.onPublish(p)
+			}
+		case packetPuback, packetSuback:
+			if len(pk.body) < 2 {
+				continue
+			}
+			id := binary.BigEndian.Uint16(pk.body)
+			c.mu.Lock()
+			ch, ok := c.acks[id]
+			delete(c.acks, id)
+			c.mu.Unlock()
+			if ok {
+				ch <- pk.body[2:]
+			}
+		}
+	}
+}
+
+func (c *client) pingLoop() {
+	ticker := time.NewTicker(c.keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			if err := c.write(encodePacket(packetPingreq, 0, nil)); err != nil {
+				c.shutdown(err)
+				return
+			}
+		}
+	}
+}
+
+func (c *client) write(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(15 * time.Second))
+	_, err := c.conn.Write(data)
+	return err
+}
+
+// register allocates a packet ID and the channel its acknowledgement goes to.
+func (c *client) register() (uint16, chan []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+		if _, busy := c.acks[c.nextID]; !busy {
+			break
+		}
+	}
+	ch := make(chan []byte, 1)
+	c.acks[c.nextID] = ch
+	return c.nextID, ch
+}
+
+func (c *client) unregister(id uint16) {
+	c.mu.Lock()
+	delete(c.acks, id)
+	c.mu.Unlock()
+}
+
+func (c *client) await(ctx context.Context, id uint16, ack chan []byte) ([]byte, error) {
+	select {
+	case body := <-ack:
+		return body, nil
+	case <-c.closed:
+		c.unregister(id)
+		return nil, c.Err()
+	case <-ctx.Done():
+		c.unregister(id)
+		return nil, ctx.Err()
+	}
+}
+
+// Publish sends a message; with QoS 1 it waits for the broker's PUBACK.
+func (c *client) Publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+	p := publishPacket{topic: topic, qos: qos, retain: retain, payload: payload}
+	if qos == 0 {
+		return c.write(encodePublish(p))
+	}
+	id, ack := c.register()
+	p.id = id
+	if err := c.write(encodePublish(p)); err != nil {
+		c.unregister(id)
+		return err
+	}
+	_, err := c.await(ctx, id, ack)
+	return err
+}
+
+// Subscribe subscribes to the filters and fails if the broker rejects any.
+func (c *client) Subscribe(ctx context.Context, filters []string, qos byte) error {
+	id, ack := c.register()
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, f := range filters {
+		body = appendString(body, f)
+		body = append(body, qos)
+	}
+	if err := c.write(encodePacket(packetSubscribe, 0x02, body)); err != nil {
+		c.unregister(id)
+		return err
+	}
+	codes, err := c.await(ctx, id, ack)
+	if err != nil {
+		return err
+	}
+	for i, code := range codes {
+		if code == 0x80 && i < len(filters) {
+			return fmt.Errorf("broker rejected subscription to %q", filters[i])
+		}
+	}
+	return nil
+}
+
+// Done is closed when the connection is lost or closed.
+func (c *client) Done() <-chan struct{} {
+	return c.closed
+}
+
+// Err returns why the connection ended.
+func (c *client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		return errClientClosed
+	}
+	return c.err
+}
+
+func (c *client) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// Close sends DISCONNECT, so the broker discards the will, and closes.
+func (c *client) Close() {
+	_ = c.write(encodePacket(packetDisconnect, 0, nil))
+	c.shutdown(errClientClosed)
+}
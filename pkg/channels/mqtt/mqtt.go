@@ -0,0 +1,384 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultReplyTopic = "picoclaw/{chat_id}/out"
+	chatIDPlaceholder = "{chat_id}"
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+	publishTimeout    = 15 * time.Second
+)
+
+// MQTTChannel talks to devices through an MQTT broker: messages on the
+// subscribed topics are inbound chats, replies go to a per-chat reply topic.
+type MQTTChannel struct {
+	*channels.BaseChannel
+	config    config.MQTTConfig
+	tlsConfig *tls.Config
+	clientID  string
+	qos       byte
+
+	mu     sync.RWMutex
+	client *client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// inboundPayload is the optional JSON form of a device message. Plain-text
+// payloads are used as the message content as they are.
+type inboundPayload struct {
+	Text      string `json:"text"`
+	Content   string `json:"content"`
+	SenderID  string `json:"sender_id"`
+	MessageID string `json:"message_id"`
+}
+
+// outboundPayload is published when payload_format is "json".
+type outboundPayload struct {
+	ChatID  string         `json:"chat_id"`
+	Content string         `json:"content,omitempty"`
+	Media   []outboundPart `json:"media,omitempty"`
+}
+
+type outboundPart struct {
+	Type        string `json:"type"`
+	Ref         string `json:"ref"`
+	Path        string `json:"path,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Caption     string `json:"caption,omitempty"`
+}
+
+// NewMQTTChannel creates an MQTT channel.
+func NewMQTTChannel(cfg config.MQTTConfig, messageBus *bus.MessageBus) (*MQTTChannel, error) {
+	if _, _, err := brokerAddress(cfg.Broker); err != nil {
+		return nil, err
+	}
+	if len(cfg.Topics) == 0 {
+		return nil, fmt.Errorf("mqtt topics are required")
+	}
+	if cfg.QoS < 0 || cfg.QoS > 1 {
+		return nil, fmt.Errorf("mqtt qos must be 0 or 1, got %d", cfg.QoS)
+	}
+	switch cfg.PayloadFormat {
+	case "", "text", "json":
+	default:
+		return nil, fmt.Errorf("mqtt payload_format must be \"text\" or \"json\", got %q", cfg.PayloadFormat)
+	}
+	if cfg.ReplyTopic == "" {
+		cfg.ReplyTopic = defaultReplyTopic
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read mqtt ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("mqtt ca_file %s contains no certificates", cfg.CAFile)
+		}
+		tlsConfig = &tls.Config{RootCAs: pool}
+	}
+
+	clientID := cfg.ClientID
+	if clientID == "" {
+		var b [4]byte
+		_, _ = rand.Read(b[:])
+		clientID = "picoclaw-" + hex.EncodeToString(b[:])
+	}
+
+	base := channels.NewBaseChannel("mqtt", cfg, messageBus, cfg.AllowFrom,
+		channels.WithReasoningChannelID(cfg.ReasoningChannelID),
+	)
+
+	return &MQTTChannel{
+		BaseChannel: base,
+		config:      cfg,
+		tlsConfig:   tlsConfig,
+		clientID:    clientID,
+		qos:         byte(cfg.QoS),
+	}, nil
+}
+
+// Start connects to the broker in the background and keeps reconnecting
+// until the channel is stopped.
+func (c *MQTTChannel) Start(ctx context.Context) error {
+	logger.InfoCF("mqtt", "Starting MQTT channel", map[string]any{
+		"broker": c.config.Broker,
+		"topics": []string(c.config.Topics),
+	})
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	c.SetRunning(true)
+	go c.run()
+	return nil
+}
+
+// Stop publishes the offline status and disconnects.
+func (c *MQTTChannel) Stop(ctx context.Context) error {
+	logger.InfoC("mqtt", "Stopping MQTT channel")
+	c.SetRunning(false)
+
+	c.mu.RLock()
+	cl := c.client
+	c.mu.RUnlock()
+	if cl != nil && c.config.StatusTopic != "" {
+		pubCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		_ = cl.Publish(pubCtx, c.config.StatusTopic, []byte("offline"), c.qos, true)
+		cancel()
+	}
+
+	// Cancelling ends the session, which disconnects the client.
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+	}
+	return nil
+}
+
+func (c *MQTTChannel) run() {
+	defer close(c.done)
+	delay := minReconnectDelay
+	for {
+		connectedAt := time.Now()
+		err := c.session()
+		if c.ctx.Err() != nil {
+			return
+		}
+		if time.Since(connectedAt) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		logger.WarnCF("mqtt", "MQTT connection lost, reconnecting", map[string]any{
+			"error": err.Error(),
+			"delay": delay.String(),
+		})
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// session connects, subscribes and blocks until the connection ends.
+func (c *MQTTChannel) session() error {
+	opts := clientOptions{
+		broker:    c.config.Broker,
+		clientID:  c.clientID,
+		username:  c.config.Username,
+		password:  c.config.Password,
+		keepAlive: time.Duration(c.config.KeepAlive) * time.Second,
+		tlsConfig: c.tlsConfig,
+	}
+	if c.config.StatusTopic != "" {
+		opts.will = &willMessage{topic: c.config.StatusTopic, payload: []byte("offline"), qos: c.qos, retain: true}
+	}
+
+	cl, err := dialMQTT(c.ctx, opts, c.handlePublish)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	subCtx, cancel := context.WithTimeout(c.ctx, publishTimeout)
+	err = cl.Subscribe(subCtx, c.config.Topics, c.qos)
+	if err == nil && c.config.StatusTopic != "" {
+		err = cl.Publish(subCtx, c.config.StatusTopic, []byte("online"), c.qos, true)
+	}
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.client = cl
+	c.mu.Unlock()
+	logger.InfoCF("mqtt", "Connected to MQTT broker", map[string]any{
+		"broker":    c.config.Broker,
+		"client_id": c.clientID,
+	})
+
+	select {
+	case <-cl.Done():
+	case <-c.ctx.Done():
+	}
+
+	c.mu.Lock()
+	if c.client == cl {
+		c.client = nil
+	}
+	c.mu.Unlock()
+	return cl.Err()
+}
+
+func (c *MQTTChannel) handlePublish(p publishPacket) {
+	if p.retain {
+		// Retained messages are stale state, not something a device just said.
+		logger.DebugCF("mqtt", "Ignoring retained message", map[string]any{"topic": p.topic})
+		return
+	}
+	if p.topic == c.config.StatusTopic {
+		return
+	}
+
+	chatID, ok := c.chatIDForTopic(p.topic)
+	if !ok {
+		return
+	}
+	if p.topic == c.replyTopic(chatID) {
+		// Our own reply, echoed back by an overlapping subscription.
+		return
+	}
+
+	content := strings.TrimSpace(string(p.payload))
+	senderID, messageID := chatID, ""
+	var payload inboundPayload
+	if strings.HasPrefix(content, "{") && json.Unmarshal(p.payload, &payload) == nil {
+		content = strings.TrimSpace(payload.Text)
+		if content == "" {
+			content = strings.TrimSpace(payload.Content)
+		}
+		if payload.SenderID != "" {
+			senderID = payload.SenderID
+		}
+		messageID = payload.MessageID
+	}
+	if content == "" {
+		return
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "mqtt",
+		PlatformID:  senderID,
+		CanonicalID: identity.BuildCanonicalID("mqtt", senderID),
+	}
+	if !c.IsAllowedSender(sender) {
+		logger.DebugCF("mqtt", "Message rejected by allowlist", map[string]any{
+			"topic":     p.topic,
+			"sender_id": senderID,
+		})
+		return
+	}
+
+	metadata := map[string]string{"topic": p.topic}
+	peer := bus.Peer{Kind: "group", ID: chatID}
+	c.HandleMessage(c.ctx, peer, messageID, senderID, chatID, content, nil, metadata, sender)
+}
+
+// chatIDForTopic derives the chat from the first subscription that matches:
+// the levels matched by its wildcards joined by "/", or the whole topic for
+// filters without wildcards.
+func (c *MQTTChannel) chatIDForTopic(topic string) (string, bool) {
+	for _, filter := range c.config.Topics {
+		captures, ok := matchTopic(filter, topic)
+		if !ok {
+			continue
+		}
+		if chatID := strings.Join(captures, "/"); chatID != "" {
+			return chatID, true
+		}
+		return topic, true
+	}
+	return "", false
+}
+
+func (c *MQTTChannel) replyTopic(chatID string) string {
+	return strings.ReplaceAll(c.config.ReplyTopic, chatIDPlaceholder, chatID)
+}
+
+// Send publishes the reply to the chat's reply topic.
+func (c *MQTTChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	payload := []byte(msg.Content)
+	if c.config.PayloadFormat == "json" {
+		var err error
+		payload, err = json.Marshal(outboundPayload{ChatID: msg.ChatID, Content: msg.Content})
+		if err != nil {
+			return err
+		}
+	}
+	return c.publish(ctx, c.replyTopic(msg.ChatID), payload)
+}
+
+// SendMedia publishes media store refs; devices on the same host can read the
+// files from the given paths. In text format each part becomes a caption line.
+func (c *MQTTChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessage) error {
+	store := c.GetMediaStore()
+	parts := make([]outboundPart, 0, len(msg.Parts))
+	for _, part := range msg.Parts {
+		out := outboundPart{
+			Type:        part.Type,
+			Ref:         part.Ref,
+			Filename:    part.Filename,
+			ContentType: part.ContentType,
+			Caption:     part.Caption,
+		}
+		if store != nil {
+			if path, err := store.Resolve(part.Ref); err == nil {
+				out.Path = path
+			}
+		}
+		parts = append(parts, out)
+	}
+
+	topic := c.replyTopic(msg.ChatID)
+	if c.config.PayloadFormat == "json" {
+		payload, err := json.Marshal(outboundPayload{ChatID: msg.ChatID, Media: parts})
+		if err != nil {
+			return err
+		}
+		return c.publish(ctx, topic, payload)
+	}
+	for _, part := range parts {
+		line := fmt.Sprintf("[%s: %s]", part.Type, part.Ref)
+		if part.Caption != "" {
+			line += " " + part.Caption
+		}
+		if err := c.publish(ctx, topic, []byte(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *MQTTChannel) publish(ctx context.Context, topic string, payload []byte) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	c.mu.RLock()
+	cl := c.client
+	c.mu.RUnlock()
+	if cl == nil {
+		return fmt.Errorf("%w: not connected to broker", channels.ErrTemporary)
+	}
+
+	pubCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	if err := cl.Publish(pubCtx, topic, payload, c.qos, c.config.Retain); err != nil {
+		return channels.ClassifyNetError(err)
+	}
+	return nil
+}
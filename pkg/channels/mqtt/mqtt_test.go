@@ -0,0 +1,362 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		captures      []string
+		ok            bool
+	}{
+		{"picoclaw/+/in", "picoclaw/kitchen/in", []string{"kitchen"}, true},
+		{"picoclaw/+/in", "picoclaw/kitchen/out", nil, false},
+		{"picoclaw/+/in", "picoclaw/a/b/in", nil, false},
+		{"sensors/#", "sensors/floor1/temp", []string{"floor1/temp"}, true},
+		{"sensors/#", "sensors", nil, true},
+		{"+/+/cmd", "home/door/cmd", []string{"home", "door"}, true},
+		{"home/door/cmd", "home/door/cmd", nil, true},
+		{"#", "$SYS/uptime", nil, false},
+	}
+	for _, tt := range tests {
+		captures, ok := matchTopic(tt.filter, tt.topic)
+		if ok != tt.ok || (ok && !reflect.DeepEqual(captures, tt.captures)) {
+			t.Errorf("matchTopic(%q, %q) = %v, %v; want %v, %v", tt.filter, tt.topic, captures, ok, tt.captures, tt.ok)
+		}
+	}
+}
+
+// testBroker is a small in-process MQTT 3.1.1 broker: it routes publishes to
+// matching subscriptions, keeps retained messages and fires wills.
+type testBroker struct {
+	t  *testing.T
+	ln net.Listener
+
+	mu       sync.Mutex
+	subs     map[*brokerConn][]string
+	retained map[string]publishPacket
+	logins   []string
+
+	received chan publishPacket
+}
+
+type brokerConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	will    *publishPacket
+}
+
+func (bc *brokerConn) write(data []byte) {
+	bc.writeMu.Lock()
+	defer bc.writeMu.Unlock()
+	_, _ = bc.conn.Write(data)
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{
+		t:        t,
+		ln:       ln,
+		subs:     make(map[*brokerConn][]string),
+		retained: make(map[string]publishPacket),
+		received: make(chan publishPacket, 32),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(&brokerConn{conn: conn})
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *testBroker) serve(bc *brokerConn) {
+	defer bc.conn.Close()
+	r := bufio.NewReader(bc.conn)
+	pk, err := readPacket(r)
+	if err != nil || pk.kind != packetConnect {
+		return
+	}
+	b.handleConnect(bc, pk.body)
+	bc.write(encodePacket(packetConnack, 0, []byte{0, 0}))
+
+	clean := false
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, bc)
+		b.mu.Unlock()
+		if !clean && bc.will != nil {
+			b.route(*bc.will)
+		}
+	}()
+
+	for {
+		pk, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch pk.kind {
+		case packetSubscribe:
+			id := binary.BigEndian.Uint16(pk.body)
+			rest := pk.body[2:]
+			var filters []string
+			var codes []byte
+			for len(rest) > 0 {
+				var f string
+				f, rest, _ = readString(rest)
+				filters = append(filters, f)
+				codes = append(codes, rest[0])
+				rest = rest[1:]
+			}
+			b.mu.Lock()
+			b.subs[bc] = append(b.subs[bc], filters...)
+			var retained []publishPacket
+			for _, p := range b.retained {
+				for _, f := range filters {
+					if _, ok := matchTopic(f, p.topic); ok {
+						retained = append(retained, p)
+						break
+					}
+				}
+			}
+			b.mu.Unlock()
+			bc.write(encodePacket(packetSuback, 0, append(binary.BigEndian.AppendUint16(nil, id), codes...)))
+			for _, p := range retained {
+				bc.write(encodePublish(publishPacket{topic: p.topic, payload: p.payload, retain: true}))
+			}
+		case packetPublish:
+			p, err := decodePublish(pk)
+			if err != nil {
+				return
+			}
+			if p.qos == 1 {
+				bc.write(encodeAck(packetPuback, p.id))
+			}
+			b.received <- p
+			b.route(p)
+		case packetPingreq:
+			bc.write(encodePacket(packetPingresp, 0, nil))
+		case packetDisconnect:
+			clean = true
+			return
+		}
+	}
+}
+
+func (b *testBroker) handleConnect(bc *brokerConn, body []byte) {
+	_, rest, _ := readString(body) // protocol name
+	flags := rest[1]
+	rest = rest[4:]               // level, flags, keep alive
+	_, rest, _ = readString(rest) // client id
+	if flags&0x04 != 0 {
+		var topic, payload string
+		topic, rest, _ = readString(rest)
+		payload, rest, _ = readString(rest)
+		bc.will = &publishPacket{topic: topic, payload: []byte(payload), retain: flags&0x20 != 0}
+	}
+	var login string
+	if flags&0x80 != 0 {
+		login, rest, _ = readString(rest)
+	}
+	if flags&0x40 != 0 {
+		var password string
+		password, _, _ = readString(rest)
+		login += ":" + password
+	}
+	b.mu.Lock()
+	b.logins = append(b.logins, login)
+	b.mu.Unlock()
+}
+
+// route delivers p to every matching subscription and stores it if retained.
+func (b *testBroker) route(p publishPacket) {
+	b.mu.Lock()
+	if p.retain {
+		b.retained[p.topic] = p
+	}
+	var targets []*brokerConn
+	for bc, filters := range b.subs {
+		for _, f := range filters {
+			if _, ok := matchTopic(f, p.topic); ok {
+				targets = append(targets, bc)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+	for _, bc := range targets {
+		bc.write(encodePublish(publishPacket{topic: p.topic, payload: p.payload}))
+	}
+}
+
+// next returns the next message a client published on topic.
+func (b *testBroker) next(topic string) publishPacket {
+	b.t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case p := <-b.received:
+			if p.topic == topic {
+				return p
+			}
+		case <-timeout:
+			b.t.Fatalf("no message published on %s", topic)
+		}
+	}
+}
+
+func nextInbound(t *testing.T, msgBus *bus.MessageBus) bus.InboundMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	return msg
+}
+
+func TestMQTTChannelRoundTrip(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.route(publishPacket{topic: "devices/old/in", payload: []byte("stale"), retain: true})
+
+	msgBus := bus.NewMessageBus()
+	ch, err := NewMQTTChannel(config.MQTTConfig{
+		Broker:      "tcp://" + broker.ln.Addr().String(),
+		Username:    "bot",
+		Password:    "pw",
+		Topics:      config.FlexibleStringSlice{"devices/+/in"},
+		ReplyTopic:  "devices/{chat_id}/out",
+		StatusTopic: "picoclaw/status",
+		QoS:         1,
+		KeepAlive:   30,
+	}, msgBus)
+	if err != nil {
+		t.Fatalf("NewMQTTChannel() error = %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := broker.next("picoclaw/status"); string(status.payload) != "online" || !status.retain {
+		t.Errorf("status = %q retain=%v, want retained online", status.payload, status.retain)
+	}
+	broker.mu.Lock()
+	logins := broker.logins
+	broker.mu.Unlock()
+	if len(logins) != 1 || logins[0] != "bot:pw" {
+		t.Errorf("logins = %v", logins)
+	}
+
+	broker.route(publishPacket{topic: "devices/kitchen/in", payload: []byte("turn on the lights")})
+	msg := nextInbound(t, msgBus)
+	if msg.ChatID != "kitchen" || msg.Content != "turn on the lights" || msg.Metadata["topic"] != "devices/kitchen/in" {
+		t.Errorf("inbound = %+v", msg)
+	}
+
+	broker.route(publishPacket{
+		topic:   "devices/door/in",
+		payload: []byte(`{"text":"who is there?","sender_id":"cam-1"}`),
+	})
+	msg = nextInbound(t, msgBus)
+	if msg.ChatID != "door" || msg.Content != "who is there?" || msg.Sender.PlatformID != "cam-1" {
+		t.Errorf("inbound JSON = %+v", msg)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{Channel: "mqtt", ChatID: "kitchen", Content: "done"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if reply := broker.next("devices/kitchen/out"); string(reply.payload) != "done" || reply.qos != 1 {
+		t.Errorf("reply = %q qos=%d", reply.payload, reply.qos)
+	}
+
+	if err := ch.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if status := broker.next("picoclaw/status"); string(status.payload) != "offline" {
+		t.Errorf("status after stop = %q, want offline", status.payload)
+	}
+}
+
+func TestMQTTChannelJSONMedia(t *testing.T) {
+	broker := newTestBroker(t)
+	ch, err := NewMQTTChannel(config.MQTTConfig{
+		Broker:        broker.ln.Addr().String(),
+		Topics:        config.FlexibleStringSlice{"picoclaw/+/in"},
+		PayloadFormat: "json",
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ch.Stop(context.Background())
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		ch.mu.RLock()
+		connected := ch.client != nil
+		ch.mu.RUnlock()
+		if connected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("channel did not connect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = ch.SendMedia(context.Background(), bus.OutboundMediaMessage{
+		Channel: "mqtt",
+		ChatID:  "cam",
+		Parts:   []bus.MediaPart{{Type: "image", Ref: "media://abc", Filename: "snap.jpg", Caption: "front door"}},
+	})
+	if err != nil {
+		t.Fatalf("SendMedia() error = %v", err)
+	}
+	var payload outboundPayload
+	if err := json.Unmarshal(broker.next("picoclaw/cam/out").payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ChatID != "cam" || len(payload.Media) != 1 || payload.Media[0].Ref != "media://abc" {
+		t.Errorf("media payload = %+v", payload)
+	}
+}
+
+func TestNewMQTTChannelValidation(t *testing.T) {
+	base := config.MQTTConfig{Broker: "tcp://localhost:1883", Topics: config.FlexibleStringSlice{"a/+"}}
+	bad := []func(c *config.MQTTConfig){
+		func(c *config.MQTTConfig) { c.Broker = "http://localhost" },
+		func(c *config.MQTTConfig) { c.Topics = nil },
+		func(c *config.MQTTConfig) { c.QoS = 2 },
+		func(c *config.MQTTConfig) { c.PayloadFormat = "xml" },
+	}
+	for i, mutate := range bad {
+		cfg := base
+		mutate(&cfg)
+		if _, err := NewMQTTChannel(cfg, bus.NewMessageBus()); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
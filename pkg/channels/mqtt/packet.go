@@ -0,0 +1,174 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MQTT 3.1.1 control packet types.
+const (
+	packetConnect    byte = 1
+	packetConnack    byte = 2
+	packetPublish    byte = 3
+	packetPuback     byte = 4
+	packetSubscribe  byte = 8
+	packetSuback     byte = 9
+	packetPingreq    byte = 12
+	packetPingresp   byte = 13
+	packetDisconnect byte = 14
+)
+
+// maxPacketSize bounds the remaining length we accept from the broker.
+const maxPacketSize = 16 << 20
+
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	var length, shift int
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+		if shift > 21 {
+			return packet{}, errors.New("mqtt: malformed remaining length")
+		}
+	}
+	if length > maxPacketSize {
+		return packet{}, fmt.Errorf("mqtt: packet of %d bytes exceeds limit", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+func encodePacket(kind, flags byte, body []byte) []byte {
+	out := []byte{kind<<4 | flags}
+	length := len(body)
+	for {
+		b := byte(length & 0x7f)
+		length >>= 7
+		if length > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+func appendString(buf []byte, s string) []byte {
+	return appendBytes(buf, []byte(s))
+}
+
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(b)))
+	return append(buf, b...)
+}
+
+func readString(body []byte) (string, []byte, error) {
+	if len(body) < 2 {
+		return "", nil, errors.New("mqtt: truncated string")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return "", nil, errors.New("mqtt: truncated string")
+	}
+	return string(body[2 : 2+n]), body[2+n:], nil
+}
+
+// publishPacket is a decoded PUBLISH.
+type publishPacket struct {
+	topic   string
+	id      uint16
+	qos     byte
+	retain  bool
+	payload []byte
+}
+
+func encodePublish(p publishPacket) []byte {
+	body := appendString(nil, p.topic)
+	if p.qos > 0 {
+		body = binary.BigEndian.AppendUint16(body, p.id)
+	}
+	body = append(body, p.payload...)
+	flags := p.qos << 1
+	if p.retain {
+		flags |= 0x01
+	}
+	return encodePacket(packetPublish, flags, body)
+}
+
+func decodePublish(pk packet) (publishPacket, error) {
+	p := publishPacket{qos: (pk.flags >> 1) & 0x03, retain: pk.flags&0x01 != 0}
+	topic, rest, err := readString(pk.body)
+	if err != nil {
+		return p, err
+	}
+	p.topic = topic
+	if p.qos > 0 {
+		if len(rest) < 2 {
+			return p, errors.New("mqtt: truncated publish")
+		}
+		p.id = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	p.payload = rest
+	return p, nil
+}
+
+func encodeAck(kind byte, id uint16) []byte {
+	return encodePacket(kind, 0, binary.BigEndian.AppendUint16(nil, id))
+}
+
+// matchTopic reports whether topic matches the subscription filter and
+// returns the levels matched by its "+" and "#" wildcards.
+func matchTopic(filter, topic string) ([]string, bool) {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	// Wildcards at the first level do not match $SYS-style topics.
+	if strings.HasPrefix(topic, "$") && (filterLevels[0] == "+" || filterLevels[0] == "#") {
+		return nil, false
+	}
+	var captures []string
+	for i, level := range filterLevels {
+		switch level {
+		case "#":
+			if i < len(topicLevels) {
+				captures = append(captures, strings.Join(topicLevels[i:], "/"))
+			}
+			return captures, true
+		case "+":
+			if i >= len(topicLevels) {
+				return nil, false
+			}
+			captures = append(captures, topicLevels[i])
+		default:
+			if i >= len(topicLevels) || topicLevels[i] != level {
+				return nil, false
+			}
+		}
+	}
+	return captures, len(filterLevels) == len(topicLevels)
+}
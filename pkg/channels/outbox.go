@@ -0,0 +1,280 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/outbox"
+)
+
+const (
+	outboxPollInterval = 5 * time.Second
+	outboxBatchSize    = 64
+)
+
+// outboundItem is a queued outbound message. outboxID is the persisted
+// entry backing it, or 0 when the outbox is disabled; progress is the
+// number of parts of a split message delivered in earlier rounds.
+type outboundItem struct {
+	msg      bus.OutboundMessage
+	outboxID int64
+	progress int
+}
+
+// outboundMediaItem is a queued outbound media message.
+type outboundMediaItem struct {
+	msg      bus.OutboundMediaMessage
+	outboxID int64
+}
+
+// SetOutbox makes the manager write every outbound message to store before
+// queueing it, redeliver failed messages with exponential backoff and move
+// them to the dead letters after maxAttempts failed rounds. It also takes
+// over the messages still buffered when the bus is closed. Call it before
+// StartAll.
+func (m *Manager) SetOutbox(store *outbox.Store, maxAttempts int) {
+	m.outbox = store
+	m.outboxMaxAttempts = maxAttempts
+	if m.bus != nil {
+		m.bus.SetOutboundSpill(m)
+	}
+}
+
+// persistOutbound stores payload for the channel instance and returns the
+// entry ID, or 0 when there is no outbox or the write failed. A failed write
+// is logged and the message is still delivered, just without durability.
+func (m *Manager) persistOutbound(kind outbox.Kind, channel, accountID, chatID string, payload any) int64 {
+	if m.outbox == nil {
+		return 0
+	}
+	key := ChannelKey(channel, accountID)
+	id, err := m.outbox.Add(context.Background(), kind, key, chatID, payload)
+	if err != nil {
+		logger.ErrorCF("channels", "Failed to persist outbound message", map[string]any{
+			"channel": key,
+			"chat_id": chatID,
+			"error":   err.Error(),
+		})
+		return 0
+	}
+	return id
+}
+
+// trackOutbound persists a message that is about to be queued and marks it
+// in flight so the retrier does not queue it a second time. The entry is due
+// as soon as it is inserted, so the insert and the marker happen under
+// outboxClaimMu, which the retrier also holds while it reads due entries.
+func (m *Manager) trackOutbound(kind outbox.Kind, channel, accountID, chatID string, payload any) int64 {
+	m.outboxClaimMu.Lock()
+	defer m.outboxClaimMu.Unlock()
+
+	id := m.persistOutbound(kind, channel, accountID, chatID, payload)
+	if id != 0 {
+		m.outboxInflight.Store(id, struct{}{})
+	}
+	return id
+}
+
+// SpillOutbound implements bus.OutboundSpill.
+func (m *Manager) SpillOutbound(msg bus.OutboundMessage) {
+	if m.acceptsSpill(msg.Channel, msg.AccountID) {
+		m.persistOutbound(outbox.KindMessage, msg.Channel, msg.AccountID, msg.ChatID, msg)
+	}
+}
+
+// SpillOutboundMedia implements bus.OutboundSpill.
+func (m *Manager) SpillOutboundMedia(msg bus.OutboundMediaMessage) {
+	if m.acceptsSpill(msg.Channel, msg.AccountID) {
+		m.persistOutbound(outbox.KindMedia, msg.Channel, msg.AccountID, msg.ChatID, msg)
+	}
+}
+
+// acceptsSpill applies the dispatcher's filter: internal and unknown
+// channels are never delivered, so there is nothing to keep.
+func (m *Manager) acceptsSpill(channel, accountID string) bool {
+	if constants.IsInternalChannel(channel) {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.channels[ChannelKey(channel, accountID)]
+	return ok
+}
+
+// settleOutbox records the outcome of a delivery round for a persisted
+// message. Delivered messages are removed. Messages interrupted by shutdown
+// stay pending for the next start; other failures are rescheduled, or
+// become dead letters when the error is permanent or attempts run out.
+// from and sent are the delivered part counts before and after the round.
+func (m *Manager) settleOutbox(id int64, from, sent int, err error) {
+	if id == 0 || m.outbox == nil {
+		return
+	}
+	// Update the entry and drop the marker together, so the retrier never
+	// sees a delivered entry without its marker.
+	m.outboxClaimMu.Lock()
+	defer m.outboxClaimMu.Unlock()
+	defer m.outboxInflight.Delete(id)
+
+	ctx := context.Background()
+	if err == nil {
+		if delErr := m.outbox.Delete(ctx, id); delErr != nil {
+			logger.ErrorCF("channels", "Failed to remove delivered message from outbox", map[string]any{
+				"id":    id,
+				"error": delErr.Error(),
+			})
+		}
+		return
+	}
+
+	if sent > from {
+		if progErr := m.outbox.SetProgress(ctx, id, sent); progErr != nil {
+			logger.ErrorCF("channels", "Failed to record outbox progress", map[string]any{
+				"id":    id,
+				"error": progErr.Error(),
+			})
+		}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	m.failOutbox(id, err.Error(), errors.Is(err, ErrSendFailed))
+}
+
+func (m *Manager) failOutbox(id int64, cause string, permanent bool) {
+	state, err := m.outbox.Fail(context.Background(), id, cause, permanent, m.outboxMaxAttempts)
+	if err != nil {
+		logger.ErrorCF("channels", "Failed to update outbox entry", map[string]any{
+			"id":    id,
+			"error": err.Error(),
+		})
+		return
+	}
+	if state == outbox.StateDead {
+		logger.ErrorCF("channels", "Outbound message moved to dead letters", map[string]any{
+			"id":    id,
+			"error": cause,
+		})
+		return
+	}
+	logger.WarnCF("channels", "Outbound message rescheduled", map[string]any{
+		"id":    id,
+		"error": cause,
+	})
+}
+
+// runOutboxRetrier queues persisted messages that are due: on start this
+// replays whatever an earlier run left behind, afterwards it picks up
+// rescheduled messages and those retried from the CLI.
+func (m *Manager) runOutboxRetrier(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		m.queueDueOutbox(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) queueDueOutbox(ctx context.Context) {
+	entries, err := m.claimDueOutbox(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.ErrorCF("channels", "Failed to read outbox", map[string]any{"error": err.Error()})
+		}
+		return
+	}
+
+	for _, e := range entries {
+		queued, err := m.queueOutboxEntry(ctx, e)
+		if err != nil {
+			m.failOutbox(e.ID, err.Error(), errors.Is(err, ErrSendFailed))
+			continue
+		}
+		if queued {
+			logger.DebugCF("channels", "Redelivering outbound message", map[string]any{
+				"id":       e.ID,
+				"channel":  e.Channel,
+				"attempts": e.Attempts,
+			})
+		}
+	}
+}
+
+// claimDueOutbox returns the due entries that are not in flight and marks
+// them in flight. Entries are inserted and settled under the same lock, so
+// a message the dispatcher is queueing is always seen with its marker.
+func (m *Manager) claimDueOutbox(ctx context.Context) ([]outbox.Entry, error) {
+	m.outboxClaimMu.Lock()
+	defer m.outboxClaimMu.Unlock()
+
+	entries, err := m.outbox.Due(ctx, time.Now(), outboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	claimed := entries[:0]
+	for _, e := range entries {
+		if _, busy := m.outboxInflight.LoadOrStore(e.ID, struct{}{}); !busy {
+			claimed = append(claimed, e)
+		}
+	}
+	return claimed, nil
+}
+
+// queueOutboxEntry hands a claimed entry to its channel worker without
+// blocking; a full queue leaves it for the next poll. The send happens
+// under the read lock so StopAll cannot close the queue underneath it.
+// The claim is released unless the entry was queued.
+func (m *Manager) queueOutboxEntry(ctx context.Context, e outbox.Entry) (queued bool, err error) {
+	defer func() {
+		if !queued {
+			m.outboxInflight.Delete(e.ID)
+		}
+	}()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	w := m.workers[e.Channel]
+	if w == nil {
+		return false, fmt.Errorf("channel %s has no active worker", e.Channel)
+	}
+
+	switch e.Kind {
+	case outbox.KindMessage:
+		var msg bus.OutboundMessage
+		if err := e.Decode(&msg); err != nil {
+			return false, fmt.Errorf("%v: %w", err, ErrSendFailed)
+		}
+		select {
+		case w.queue <- outboundItem{msg: msg, outboxID: e.ID, progress: e.Progress}:
+			queued = true
+		default:
+		}
+	case outbox.KindMedia:
+		var msg bus.OutboundMediaMessage
+		if err := e.Decode(&msg); err != nil {
+			return false, fmt.Errorf("%v: %w", err, ErrSendFailed)
+		}
+		select {
+		case w.mediaQueue <- outboundMediaItem{msg: msg, outboxID: e.ID}:
+			queued = true
+		default:
+		}
+	default:
+		return false, fmt.Errorf("unknown outbox entry kind %q: %w", e.Kind, ErrSendFailed)
+	}
+	return queued, nil
+}
@@ -0,0 +1,209 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/outbox"
+)
+
+func newTestOutboxManager(t *testing.T, ch Channel) (*Manager, *outbox.Store) {
+	t.Helper()
+	store, err := outbox.Open(filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatalf("outbox.Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	m := newTestManager()
+	m.bus = bus.NewMessageBus()
+	m.channels["test"] = ch
+	m.SetOutbox(store, 3)
+	return m, store
+}
+
+func startTestWorker(t *testing.T, m *Manager, ch Channel) {
+	t.Helper()
+	w := newChannelWorker("test", ch)
+	m.workers["test"] = w
+	go m.runWorker(t.Context(), "test", w)
+	go m.runMediaWorker(t.Context(), "test", w)
+}
+
+func waitForEntry(t *testing.T, store *outbox.Store, id int64, cond func(outbox.Entry, error) bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond(store.Get(context.Background(), id)) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	e, err := store.Get(context.Background(), id)
+	t.Fatalf("outbox entry %d did not reach the expected state: %+v, %v", id, e, err)
+}
+
+func TestOutbox_SplitMessageResumesAfterFailure(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	failed := false
+	ch := &mockChannelWithLength{
+		mockChannel: mockChannel{
+			sendFn: func(_ context.Context, msg bus.OutboundMessage) error {
+				mu.Lock()
+				defer mu.Unlock()
+				// The platform goes away after the first part.
+				if len(delivered) == 1 && !failed {
+					failed = true
+					return ErrNotRunning
+				}
+				delivered = append(delivered, msg.Content)
+				return nil
+			},
+		},
+		maxLen: 5,
+	}
+	m, store := newTestOutboxManager(t, ch)
+	startTestWorker(t, m, ch)
+
+	if err := m.SendToChannel(t.Context(), "test", "1", "hello world"); err != nil {
+		t.Fatalf("SendToChannel: %v", err)
+	}
+	const id = 1
+	waitForEntry(t, store, id, func(e outbox.Entry, err error) bool {
+		return err == nil && e.Attempts == 1
+	})
+	e, _ := store.Get(context.Background(), id)
+	if e.State != outbox.StatePending || e.Progress != 1 || e.LastError == "" {
+		t.Fatalf("unexpected entry after failed round: %+v", e)
+	}
+
+	// Make it due now instead of waiting out the backoff.
+	if _, err := store.Retry(context.Background(), outbox.Filter{State: outbox.StatePending}); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	m.queueDueOutbox(t.Context())
+	waitForEntry(t, store, id, func(_ outbox.Entry, err error) bool {
+		return errors.Is(err, outbox.ErrNotFound)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := SplitMessage("hello world", 5); !slices.Equal(delivered, want) {
+		t.Fatalf("delivered %q, want each part once: %q", delivered, want)
+	}
+}
+
+func TestOutbox_PermanentFailureIsDeadLettered(t *testing.T) {
+	ch := &mockChannel{
+		sendFn: func(context.Context, bus.OutboundMessage) error {
+			return ErrSendFailed
+		},
+	}
+	m, store := newTestOutboxManager(t, ch)
+	startTestWorker(t, m, ch)
+
+	if err := m.SendToChannel(t.Context(), "test", "1", "hi"); err != nil {
+		t.Fatalf("SendToChannel: %v", err)
+	}
+	waitForEntry(t, store, 1, func(e outbox.Entry, err error) bool {
+		return err == nil && e.State == outbox.StateDead
+	})
+}
+
+func TestOutbox_ReplaysMessagesSpilledByBusClose(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	ch := &mockChannel{
+		sendFn: func(_ context.Context, msg bus.OutboundMessage) error {
+			mu.Lock()
+			delivered = append(delivered, msg.Content)
+			mu.Unlock()
+			return nil
+		},
+	}
+	m, store := newTestOutboxManager(t, ch)
+
+	// Nothing is dispatching: the messages are still buffered on close.
+	ctx := context.Background()
+	m.bus.PublishOutbound(ctx, bus.OutboundMessage{Channel: "test", ChatID: "1", Content: "late reply"})
+	m.bus.PublishOutbound(ctx, bus.OutboundMessage{Channel: "unknown", ChatID: "1", Content: "dropped"})
+	m.bus.Close()
+
+	stats, err := store.Stats(ctx)
+	if err != nil || stats.Pending != 1 {
+		t.Fatalf("Stats = %+v, %v; want one pending entry", stats, err)
+	}
+
+	// The next run replays it.
+	startTestWorker(t, m, ch)
+	m.queueDueOutbox(t.Context())
+	waitForEntry(t, store, 1, func(_ outbox.Entry, err error) bool {
+		return errors.Is(err, outbox.ErrNotFound)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(delivered, []string{"late reply"}) {
+		t.Fatalf("delivered %q", delivered)
+	}
+}
+
+func TestOutbox_RetrierDoesNotDuplicateDispatchedMessages(t *testing.T) {
+	ch := &mockChannel{}
+	m, _ := newTestOutboxManager(t, ch)
+	// No worker drains the queue, so every dispatched entry stays in flight
+	// and the retrier must never queue it again.
+	const n = 300
+	w := newChannelWorker("test", ch)
+	w.queue = make(chan outboundItem, 2*n)
+	m.workers["test"] = w
+	go m.dispatchOutbound(t.Context())
+
+	stop := make(chan struct{})
+	retrierDone := make(chan struct{})
+	go func() {
+		defer close(retrierDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.queueDueOutbox(t.Context())
+			}
+		}
+	}()
+
+	for i := range n {
+		msg := bus.OutboundMessage{Channel: "test", ChatID: "1", Content: fmt.Sprintf("msg %d", i)}
+		if err := m.bus.PublishOutbound(t.Context(), msg); err != nil {
+			t.Fatalf("PublishOutbound: %v", err)
+		}
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for len(w.queue) < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(stop)
+	<-retrierDone
+
+	queued := make(map[int64]int)
+	for len(w.queue) > 0 {
+		queued[(<-w.queue).outboxID]++
+	}
+	if len(queued) != n {
+		t.Fatalf("queued %d distinct entries, want %d", len(queued), n)
+	}
+	for id, count := range queued {
+		if count != 1 {
+			t.Errorf("entry %d queued %d times", id, count)
+		}
+	}
+}
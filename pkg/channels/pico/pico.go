@@ -150,6 +150,23 @@ func (c *PicoChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	return c.broadcastToSession(msg.ChatID, outMsg)
 }
 
+// SendInteractive implements channels.InteractiveCapable.
+// The controls travel in the "interactive" payload field of message.create;
+// clients answer with message.action referencing the message_id.
+func (c *PicoChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	outMsg := newMessage(TypeMessageCreate, map[string]any{
+		"content":     msg.Content,
+		"message_id":  uuid.New().String(),
+		"interactive": msg.Interactive,
+	})
+
+	return c.broadcastToSession(msg.ChatID, outMsg)
+}
+
 // EditMessage implements channels.MessageEditor.
 func (c *PicoChannel) EditMessage(ctx context.Context, chatID string, messageID string, content string) error {
 	outMsg := newMessage(TypeMessageUpdate, map[string]any{
@@ -403,6 +420,9 @@ func (c *PicoChannel) handleMessage(pc *picoConn, msg PicoMessage) {
 	case TypeMessageSend:
 		c.handleMessageSend(pc, msg)
 
+	case TypeMessageAction:
+		c.handleMessageAction(pc, msg)
+
 	default:
 		errMsg := newError("unknown_type", fmt.Sprintf("unknown message type: %s", msg.Type))
 		pc.writeJSON(errMsg)
@@ -452,6 +472,45 @@ func (c *PicoChannel) handleMessageSend(pc *picoConn, msg PicoMessage) {
 	c.HandleMessage(c.ctx, peer, msg.ID, senderID, chatID, content, nil, metadata, sender)
 }
 
+// handleMessageAction processes a message.action (button click or select
+// choice) from a client.
+func (c *PicoChannel) handleMessageAction(pc *picoConn, msg PicoMessage) {
+	value, _ := msg.Payload["value"].(string)
+	if strings.TrimSpace(value) == "" {
+		pc.writeJSON(newError("empty_value", "action value is empty"))
+		return
+	}
+	kind, _ := msg.Payload["kind"].(string)
+	if kind != channels.InteractionSelect {
+		kind = channels.InteractionButton
+	}
+	messageID, _ := msg.Payload["message_id"].(string)
+
+	sessionID := msg.SessionID
+	if sessionID == "" {
+		sessionID = pc.sessionID
+	}
+
+	chatID := "pico:" + sessionID
+	senderID := "pico-user"
+
+	peer := bus.Peer{Kind: "direct", ID: "pico:" + sessionID}
+
+	metadata := map[string]string{
+		"platform":   "pico",
+		"session_id": sessionID,
+		"conn_id":    pc.id,
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "pico",
+		PlatformID:  senderID,
+		CanonicalID: identity.BuildCanonicalID("pico", senderID),
+	}
+
+	c.HandleInteraction(c.ctx, peer, senderID, chatID, messageID, kind, value, metadata, sender)
+}
+
 // truncate truncates a string to maxLen runes.
 func truncate(s string, maxLen int) string {
 	runes := []rune(s)
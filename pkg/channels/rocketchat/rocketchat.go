@@ -0,0 +1,668 @@
+package rocketchat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	apiPrefix         = "/api/v1"
+	reactionEmoji     = ":eyes:"
+	typingInterval    = 4 * time.Second
+	reconnectInterval = 5 * time.Second
+	// The server pings every 25s over DDP; a missing ping means a dead socket.
+	readTimeout = 90 * time.Second
+)
+
+// RocketChatChannel connects to a Rocket.Chat server as a bot user: messages
+// arrive over the realtime (DDP) API, replies go through the REST API.
+//
+// Chat IDs are "<room_id>" or "<room_id>/<thread_message_id>" for threads.
+// Messages in channels and groups always get a threaded reply, so every
+// thread is its own conversation.
+type RocketChatChannel struct {
+	*channels.BaseChannel
+	config      config.RocketChatConfig
+	baseURL     string
+	httpClient  *http.Client
+	botUsername string
+	// mentionPattern matches "@<bot username>" case-insensitively.
+	mentionPattern *regexp.Regexp
+	ctx            context.Context
+	cancel         context.CancelFunc
+
+	connMu sync.Mutex
+	conn   *websocket.Conn
+	callID atomic.Int64
+}
+
+type rcUser struct {
+	ID       string `json:"_id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+type rcFile struct {
+	ID   string `json:"_id"`
+	Name string `json:"name"`
+}
+
+type rcMessage struct {
+	ID       string          `json:"_id"`
+	RoomID   string          `json:"rid"`
+	Msg      string          `json:"msg"`
+	ThreadID string          `json:"tmid"`
+	Type     string          `json:"t"`
+	User     rcUser          `json:"u"`
+	Mentions []rcUser        `json:"mentions"`
+	Files    []rcFile        `json:"files"`
+	EditedAt json.RawMessage `json:"editedAt"`
+}
+
+type rcRoomInfo struct {
+	RoomType string `json:"roomType"`
+}
+
+// ddpMessage covers the DDP frames the channel reads.
+type ddpMessage struct {
+	Msg        string `json:"msg"`
+	ID         string `json:"id"`
+	Collection string `json:"collection"`
+	Fields     struct {
+		EventName string            `json:"eventName"`
+		Args      []json.RawMessage `json:"args"`
+	} `json:"fields"`
+	Error *struct {
+		Error   any    `json:"error"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewRocketChatChannel creates a Rocket.Chat channel.
+func NewRocketChatChannel(cfg config.RocketChatConfig, messageBus *bus.MessageBus) (*RocketChatChannel, error) {
+	if cfg.ServerURL == "" || cfg.UserID == "" || cfg.AuthToken == "" {
+		return nil, fmt.Errorf("rocketchat server_url, user_id and auth_token are required")
+	}
+	u, err := url.Parse(strings.TrimRight(cfg.ServerURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid rocketchat server_url %q", cfg.ServerURL)
+	}
+
+	base := channels.NewBaseChannel("rocketchat", cfg, messageBus, cfg.AllowFrom,
+		channels.WithMaxMessageLength(5000),
+		channels.WithGroupTrigger(cfg.GroupTrigger),
+		channels.WithReasoningChannelID(cfg.ReasoningChannelID),
+	)
+
+	return &RocketChatChannel{
+		BaseChannel: base,
+		config:      cfg,
+		baseURL:     u.String(),
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Start checks the credentials and connects the realtime API.
+func (c *RocketChatChannel) Start(ctx context.Context) error {
+	logger.InfoC("rocketchat", "Starting Rocket.Chat channel")
+
+	var me rcUser
+	if err := c.api(ctx, http.MethodGet, "/me", nil, &me); err != nil {
+		return fmt.Errorf("rocketchat auth failed: %w", err)
+	}
+	c.botUsername = me.Username
+	if me.Username != "" {
+		c.mentionPattern = regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(me.Username) + `\b`)
+	}
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	go c.eventLoop()
+
+	c.SetRunning(true)
+	logger.InfoCF("rocketchat", "Rocket.Chat channel started", map[string]any{
+		"user_id":  c.config.UserID,
+		"username": c.botUsername,
+	})
+	return nil
+}
+
+// Stop closes the realtime connection.
+func (c *RocketChatChannel) Stop(ctx context.Context) error {
+	logger.InfoC("rocketchat", "Stopping Rocket.Chat channel")
+	c.SetRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.connMu.Lock()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.connMu.Unlock()
+	return nil
+}
+
+func (c *RocketChatChannel) websocketURL() string {
+	u, _ := url.Parse(c.baseURL + "/websocket")
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	return u.String()
+}
+
+// eventLoop keeps a realtime connection open until the channel stops.
+func (c *RocketChatChannel) eventLoop() {
+	for {
+		if err := c.listen(); err != nil && c.ctx.Err() == nil {
+			logger.WarnCF("rocketchat", "Realtime API disconnected, reconnecting", map[string]any{
+				"error": err.Error(),
+			})
+		}
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(reconnectInterval):
+		}
+	}
+}
+
+func (c *RocketChatChannel) listen() error {
+	dialer := websocket.Dialer{HandshakeTimeout: 15 * time.Second, Proxy: http.ProxyFromEnvironment}
+	conn, resp, err := dialer.DialContext(c.ctx, c.websocketURL(), nil)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// DDP handshake: connect, log in with the personal access token, then
+	// subscribe to every room the bot is a member of.
+	if err := c.writeJSON(conn, map[string]any{"msg": "connect", "version": "1", "support": []string{"1"}}); err != nil {
+		return err
+	}
+	if _, err := c.await(conn, func(m ddpMessage) bool { return m.Msg == "connected" }); err != nil {
+		return err
+	}
+	loginID := c.nextCallID()
+	login := map[string]any{
+		"msg":    "method",
+		"method": "login",
+		"id":     loginID,
+		"params": []any{map[string]string{"resume": c.config.AuthToken}},
+	}
+	if err := c.writeJSON(conn, login); err != nil {
+		return err
+	}
+	result, err := c.await(conn, func(m ddpMessage) bool { return m.Msg == "result" && m.ID == loginID })
+	if err != nil {
+		return err
+	}
+	if result.Error != nil {
+		return fmt.Errorf("rocketchat login failed: %s", result.Error.Message)
+	}
+	sub := map[string]any{
+		"msg":    "sub",
+		"id":     c.nextCallID(),
+		"name":   "stream-room-messages",
+		"params": []any{"__my_messages__", false},
+	}
+	if err := c.writeJSON(conn, sub); err != nil {
+		return err
+	}
+
+	c.connMu.Lock()
+	c.conn = conn
+	c.connMu.Unlock()
+	defer func() {
+		c.connMu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.connMu.Unlock()
+	}()
+
+	for {
+		m, err := c.read(conn)
+		if err != nil {
+			return err
+		}
+		if m.Msg == "changed" && m.Collection == "stream-room-messages" && len(m.Fields.Args) > 0 {
+			c.handleMessage(m.Fields.Args)
+		}
+	}
+}
+
+// read returns the next DDP frame, answering server pings on the way.
+func (c *RocketChatChannel) read(conn *websocket.Conn) (ddpMessage, error) {
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return ddpMessage{}, err
+		}
+		var m ddpMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		if m.Msg == "ping" {
+			if err := c.writeJSON(conn, map[string]string{"msg": "pong"}); err != nil {
+				return ddpMessage{}, err
+			}
+			continue
+		}
+		return m, nil
+	}
+}
+
+func (c *RocketChatChannel) await(conn *websocket.Conn, match func(ddpMessage) bool) (ddpMessage, error) {
+	for {
+		m, err := c.read(conn)
+		if err != nil {
+			return m, err
+		}
+		if m.Msg == "failed" {
+			return m, fmt.Errorf("rocketchat DDP connect refused")
+		}
+		if match(m) {
+			return m, nil
+		}
+	}
+}
+
+func (c *RocketChatChannel) nextCallID() string {
+	return strconv.FormatInt(c.callID.Add(1), 10)
+}
+
+func (c *RocketChatChannel) writeJSON(conn *websocket.Conn, v any) error {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return conn.WriteJSON(v)
+}
+
+// callMethod sends a DDP method call without waiting for its result.
+func (c *RocketChatChannel) callMethod(method string, params ...any) error {
+	c.connMu.Lock()
+	conn := c.conn
+	c.connMu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("%w: realtime API not connected", channels.ErrTemporary)
+	}
+	return c.writeJSON(conn, map[string]any{
+		"msg":    "method",
+		"method": method,
+		"id":     c.nextCallID(),
+		"params": params,
+	})
+}
+
+func (c *RocketChatChannel) handleMessage(args []json.RawMessage) {
+	var msg rcMessage
+	if err := json.Unmarshal(args[0], &msg); err != nil {
+		return
+	}
+	var room rcRoomInfo
+	if len(args) > 1 {
+		_ = json.Unmarshal(args[1], &room)
+	}
+	// Skip our own messages, system messages and edits, which are streamed
+	// again with the same ID.
+	if msg.User.ID == c.config.UserID || msg.User.ID == "" || msg.Type != "" {
+		return
+	}
+	if len(msg.EditedAt) > 0 && string(msg.EditedAt) != "null" {
+		return
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "rocketchat",
+		PlatformID:  msg.User.ID,
+		CanonicalID: identity.BuildCanonicalID("rocketchat", msg.User.ID),
+		Username:    msg.User.Username,
+		DisplayName: msg.User.Name,
+	}
+	if !c.IsAllowedSender(sender) {
+		logger.DebugCF("rocketchat", "Message rejected by allowlist", map[string]any{
+			"user_id": msg.User.ID,
+		})
+		return
+	}
+
+	isDirect := room.RoomType == "d"
+	content, mentioned := c.stripBotMention(msg.Msg)
+	mentioned = mentioned || c.isMentioned(msg.Mentions)
+	if !isDirect {
+		respond, cleaned := c.ShouldRespondInGroup(mentioned, content)
+		if !respond {
+			return
+		}
+		content = cleaned
+	}
+
+	threadID := msg.ThreadID
+	if threadID == "" && !isDirect {
+		threadID = msg.ID
+	}
+	chatID := msg.RoomID
+	if threadID != "" {
+		chatID = msg.RoomID + "/" + threadID
+	}
+
+	var mediaPaths []string
+	if len(msg.Files) > 0 {
+		scope := channels.BuildMediaScope("rocketchat", chatID, msg.ID)
+		for _, file := range msg.Files {
+			ref := c.downloadFile(file, scope)
+			if ref == "" {
+				continue
+			}
+			mediaPaths = append(mediaPaths, ref)
+			content += fmt.Sprintf("\n[file: %s]", file.Name)
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	// Threads get their own session; direct messages stay with the user.
+	peer := bus.Peer{Kind: "channel", ID: chatID}
+	if isDirect {
+		peer = bus.Peer{Kind: "direct", ID: msg.User.ID}
+	}
+
+	metadata := map[string]string{
+		"platform":   "rocketchat",
+		"room_id":    msg.RoomID,
+		"room_type":  room.RoomType,
+		"thread_id":  threadID,
+		"message_id": msg.ID,
+	}
+	if mentioned {
+		metadata["is_mention"] = "true"
+	}
+
+	logger.DebugCF("rocketchat", "Received message", map[string]any{
+		"sender_id": msg.User.ID,
+		"chat_id":   chatID,
+		"preview":   utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(c.ctx, peer, msg.ID, msg.User.ID, chatID, content, mediaPaths, metadata, sender)
+}
+
+// stripBotMention removes "@<bot username>" and reports whether it was there.
+func (c *RocketChatChannel) stripBotMention(text string) (string, bool) {
+	if c.mentionPattern == nil || !c.mentionPattern.MatchString(text) {
+		return strings.TrimSpace(text), false
+	}
+	return strings.TrimSpace(c.mentionPattern.ReplaceAllString(text, "")), true
+}
+
+func (c *RocketChatChannel) isMentioned(mentions []rcUser) bool {
+	for _, m := range mentions {
+		if m.ID == c.config.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *RocketChatChannel) downloadFile(file rcFile, scope string) string {
+	fileURL := fmt.Sprintf("%s/file-upload/%s/%s", c.baseURL, file.ID, url.PathEscape(file.Name))
+	localPath := utils.DownloadFile(fileURL, file.Name, utils.DownloadOptions{
+		LoggerPrefix: "rocketchat",
+		ExtraHeaders: c.authHeaders(),
+	})
+	if localPath == "" {
+		return ""
+	}
+	if store := c.GetMediaStore(); store != nil {
+		ref, err := store.Store(localPath, media.MediaMeta{
+			Filename: file.Name,
+			Source:   "rocketchat",
+		}, scope)
+		if err == nil {
+			return ref
+		}
+	}
+	return localPath
+}
+
+func parseChatID(chatID string) (roomID, threadID string) {
+	roomID, threadID, _ = strings.Cut(chatID, "/")
+	return roomID, threadID
+}
+
+// Send posts a message, in the thread when the chat is one.
+func (c *RocketChatChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	_, err := c.postMessage(ctx, msg.ChatID, msg.Content)
+	return err
+}
+
+func (c *RocketChatChannel) postMessage(ctx context.Context, chatID, text string) (string, error) {
+	roomID, threadID := parseChatID(chatID)
+	if roomID == "" {
+		return "", fmt.Errorf("invalid rocketchat chat ID %q: %w", chatID, channels.ErrSendFailed)
+	}
+	body := map[string]string{"roomId": roomID, "text": text}
+	if threadID != "" {
+		body["tmid"] = threadID
+	}
+	var out struct {
+		Message rcMessage `json:"message"`
+	}
+	err := c.api(ctx, http.MethodPost, "/chat.postMessage", body, &out)
+	return out.Message.ID, err
+}
+
+// EditMessage implements channels.MessageEditor.
+func (c *RocketChatChannel) EditMessage(ctx context.Context, chatID, messageID, content string) error {
+	roomID, _ := parseChatID(chatID)
+	return c.api(ctx, http.MethodPost, "/chat.update", map[string]string{
+		"roomId": roomID,
+		"msgId":  messageID,
+		"text":   content,
+	}, nil)
+}
+
+// SendPlaceholder implements channels.PlaceholderCapable.
+func (c *RocketChatChannel) SendPlaceholder(ctx context.Context, chatID string) (string, error) {
+	if !c.config.Placeholder.Enabled {
+		return "", nil
+	}
+	text := c.config.Placeholder.Text
+	if text == "" {
+		text = "Thinking... 💭"
+	}
+	return c.postMessage(ctx, chatID, text)
+}
+
+// StartTyping implements channels.TypingCapable through the realtime API's
+// user-activity stream (Rocket.Chat 6.0+). The indicator is refreshed until
+// stop is called, which clears it.
+func (c *RocketChatChannel) StartTyping(ctx context.Context, chatID string) (func(), error) {
+	if !c.config.Typing.Enabled || !c.IsRunning() || c.botUsername == "" {
+		return func() {}, nil
+	}
+	roomID, threadID := parseChatID(chatID)
+	stream := roomID + "/user-activity"
+	extras := map[string]string{}
+	if threadID != "" {
+		extras["tmid"] = threadID
+	}
+
+	typingCtx, cancel := context.WithCancel(c.ctx)
+	go func() {
+		ticker := time.NewTicker(typingInterval)
+		defer ticker.Stop()
+		for {
+			if err := c.callMethod("stream-notify-room", stream, c.botUsername, []string{"user-typing"}, extras); err != nil {
+				logger.DebugCF("rocketchat", "Typing indicator failed", map[string]any{"error": err.Error()})
+			}
+			select {
+			case <-typingCtx.Done():
+				_ = c.callMethod("stream-notify-room", stream, c.botUsername, []string{}, extras)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel, nil
+}
+
+// ReactToMessage implements channels.ReactionCapable.
+func (c *RocketChatChannel) ReactToMessage(ctx context.Context, chatID, messageID string) (func(), error) {
+	react := func(ctx context.Context, on bool) error {
+		return c.api(ctx, http.MethodPost, "/chat.react", map[string]any{
+			"messageId":   messageID,
+			"emoji":       reactionEmoji,
+			"shouldReact": on,
+		}, nil)
+	}
+	if err := react(ctx, true); err != nil {
+		return func() {}, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { _ = react(context.Background(), false) })
+	}, nil
+}
+
+// SendMedia implements channels.MediaSender; each part is uploaded as its
+// own message with the caption as text.
+func (c *RocketChatChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	store := c.GetMediaStore()
+	if store == nil {
+		return fmt.Errorf("no media store available: %w", channels.ErrSendFailed)
+	}
+	roomID, threadID := parseChatID(msg.ChatID)
+
+	for _, part := range msg.Parts {
+		localPath, err := store.Resolve(part.Ref)
+		if err != nil {
+			logger.ErrorCF("rocketchat", "Failed to resolve media ref", map[string]any{
+				"ref":   part.Ref,
+				"error": err.Error(),
+			})
+			continue
+		}
+		filename := part.Filename
+		if filename == "" {
+			filename = filepath.Base(localPath)
+		}
+		if err := c.upload(ctx, roomID, threadID, localPath, filename, part.Caption); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RocketChatChannel) upload(ctx context.Context, roomID, threadID, localPath, filename, caption string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", localPath, channels.ErrSendFailed)
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return err
+	}
+	if caption != "" {
+		_ = w.WriteField("msg", caption)
+	}
+	if threadID != "" {
+		_ = w.WriteField("tmid", threadID)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/rooms.upload/"+url.PathEscape(roomID), &body, w.FormDataContentType(), nil)
+}
+
+func (c *RocketChatChannel) authHeaders() map[string]string {
+	return map[string]string{
+		"X-Auth-Token": c.config.AuthToken,
+		"X-User-Id":    c.config.UserID,
+	}
+}
+
+// api performs a JSON REST call; in and out may be nil.
+func (c *RocketChatChannel) api(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	return c.do(ctx, method, path, body, "application/json", out)
+}
+
+func (c *RocketChatChannel) do(ctx context.Context, method, path string, body io.Reader, contentType string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.authHeaders() {
+		req.Header.Set(k, v)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return channels.ClassifyNetError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return channels.ClassifySendError(resp.StatusCode,
+			fmt.Errorf("rocketchat %s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg))))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,287 @@
+package rocketchat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// fakeServer serves the REST endpoints and a DDP endpoint that accepts the
+// handshake and then streams the queued room messages.
+type fakeServer struct {
+	*httptest.Server
+	events chan any
+
+	mu      sync.Mutex
+	bodies  map[string][]map[string]any
+	methods []ddpCall
+}
+
+type ddpCall struct {
+	Method string `json:"method"`
+	Params []any  `json:"params"`
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	fs := &fakeServer{events: make(chan any, 8), bodies: make(map[string][]map[string]any)}
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != "tok" || r.Header.Get("X-User-Id") != "bot" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(rcUser{ID: "bot", Username: "picobot"})
+	})
+	mux.HandleFunc("/websocket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var writeMu sync.Mutex
+		write := func(v any) error {
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			return conn.WriteJSON(v)
+		}
+		go func() {
+			for {
+				var m map[string]any
+				if err := conn.ReadJSON(&m); err != nil {
+					return
+				}
+				switch m["msg"] {
+				case "connect":
+					_ = write(map[string]any{"msg": "connected", "session": "s1"})
+					_ = write(map[string]any{"msg": "ping"})
+				case "method":
+					var call ddpCall
+					data, _ := json.Marshal(m)
+					_ = json.Unmarshal(data, &call)
+					if call.Method == "login" {
+						params, _ := call.Params[0].(map[string]any)
+						if params["resume"] != "tok" {
+							_ = write(map[string]any{"msg": "result", "id": m["id"], "error": map[string]any{"message": "bad token"}})
+							continue
+						}
+						_ = write(map[string]any{"msg": "result", "id": m["id"], "result": map[string]any{"id": "bot"}})
+						continue
+					}
+					fs.mu.Lock()
+					fs.methods = append(fs.methods, call)
+					fs.mu.Unlock()
+				}
+			}
+		}()
+		for event := range fs.events {
+			if err := write(event); err != nil {
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/api/v1")
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		fs.mu.Lock()
+		fs.bodies[key] = append(fs.bodies[key], body)
+		fs.mu.Unlock()
+		if key == "POST /chat.postMessage" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "message": rcMessage{ID: "reply1"}})
+			return
+		}
+		w.Write([]byte(`{"success":true}`))
+	})
+	fs.Server = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		close(fs.events)
+		fs.Close()
+	})
+	return fs
+}
+
+func (fs *fakeServer) body(key string) map[string]any {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	list := fs.bodies[key]
+	if len(list) == 0 {
+		return nil
+	}
+	return list[len(list)-1]
+}
+
+func roomMessage(msg rcMessage, roomType string) map[string]any {
+	return map[string]any{
+		"msg":        "changed",
+		"collection": "stream-room-messages",
+		"id":         "id",
+		"fields": map[string]any{
+			"eventName": "__my_messages__",
+			"args":      []any{msg, rcRoomInfo{RoomType: roomType}},
+		},
+	}
+}
+
+func startChannel(t *testing.T, fs *fakeServer, cfg config.RocketChatConfig) (*RocketChatChannel, *bus.MessageBus) {
+	t.Helper()
+	cfg.ServerURL = fs.URL
+	cfg.UserID = "bot"
+	cfg.AuthToken = "tok"
+	msgBus := bus.NewMessageBus()
+	ch, err := NewRocketChatChannel(cfg, msgBus)
+	if err != nil {
+		t.Fatalf("NewRocketChatChannel() error = %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = ch.Stop(context.Background()) })
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		ch.connMu.Lock()
+		connected := ch.conn != nil
+		ch.connMu.Unlock()
+		if connected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("realtime API did not connect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return ch, msgBus
+}
+
+func nextInbound(t *testing.T, msgBus *bus.MessageBus) (bus.InboundMessage, bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	return msgBus.ConsumeInbound(ctx)
+}
+
+func TestRocketChatInboundThreadsAndMentions(t *testing.T) {
+	fs := newFakeServer(t)
+	_, msgBus := startChannel(t, fs, config.RocketChatConfig{
+		GroupTrigger: config.GroupTriggerConfig{MentionOnly: true},
+	})
+	alice := rcUser{ID: "alice", Username: "alice"}
+
+	// Not mentioned in a channel: ignored.
+	fs.events <- roomMessage(rcMessage{ID: "m0", RoomID: "general", Msg: "hello all", User: alice}, "c")
+	if msg, ok := nextInbound(t, msgBus); ok {
+		t.Fatalf("unexpected inbound %+v", msg)
+	}
+
+	// Mentioned top-level message: the reply starts a thread on it.
+	fs.events <- roomMessage(rcMessage{
+		ID: "m1", RoomID: "general", Msg: "@PicoBot what's up?", User: alice,
+		Mentions: []rcUser{{ID: "bot", Username: "picobot"}},
+	}, "c")
+	msg, ok := nextInbound(t, msgBus)
+	if !ok {
+		t.Fatal("no inbound for mention")
+	}
+	if msg.ChatID != "general/m1" || msg.Content != "what's up?" || msg.Peer.Kind != "channel" || msg.Peer.ID != "general/m1" {
+		t.Errorf("mention inbound = chat %q content %q peer %+v", msg.ChatID, msg.Content, msg.Peer)
+	}
+
+	// Follow-up in the thread keeps the same chat and session peer.
+	fs.events <- roomMessage(rcMessage{ID: "m2", RoomID: "general", ThreadID: "m1", Msg: "@picobot and now?", User: alice}, "c")
+	msg, ok = nextInbound(t, msgBus)
+	if !ok || msg.ChatID != "general/m1" || msg.Peer.ID != "general/m1" {
+		t.Errorf("thread reply inbound = %+v, %v", msg, ok)
+	}
+
+	// Direct messages need no mention and are keyed by user.
+	fs.events <- roomMessage(rcMessage{ID: "m3", RoomID: "dm", Msg: "hi", User: alice}, "d")
+	msg, ok = nextInbound(t, msgBus)
+	if !ok || msg.ChatID != "dm" || msg.Peer.Kind != "direct" || msg.Peer.ID != "alice" {
+		t.Errorf("DM inbound = %+v, %v", msg, ok)
+	}
+
+	// Own messages, system messages and edits are ignored.
+	fs.events <- roomMessage(rcMessage{ID: "m4", RoomID: "dm", Msg: "echo", User: rcUser{ID: "bot"}}, "d")
+	fs.events <- roomMessage(rcMessage{ID: "m5", RoomID: "dm", Type: "uj", User: alice}, "d")
+	fs.events <- roomMessage(rcMessage{
+		ID: "m3", RoomID: "dm", Msg: "hi!", User: alice,
+		EditedAt: json.RawMessage(`{"$date":1700000000000}`),
+	}, "d")
+	if msg, ok := nextInbound(t, msgBus); ok {
+		t.Fatalf("unexpected inbound %+v", msg)
+	}
+}
+
+func TestRocketChatOutbound(t *testing.T) {
+	fs := newFakeServer(t)
+	ch, _ := startChannel(t, fs, config.RocketChatConfig{
+		Typing:      config.TypingConfig{Enabled: true},
+		Placeholder: config.PlaceholderConfig{Enabled: true, Text: "..."},
+	})
+	ctx := context.Background()
+
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "general/m1", Content: "answer"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if body := fs.body("POST /chat.postMessage"); body["roomId"] != "general" || body["tmid"] != "m1" || body["text"] != "answer" {
+		t.Errorf("postMessage body = %v", body)
+	}
+
+	id, err := ch.SendPlaceholder(ctx, "general/m1")
+	if err != nil || id != "reply1" {
+		t.Fatalf("SendPlaceholder() = %q, %v", id, err)
+	}
+	if err := ch.EditMessage(ctx, "general/m1", id, "final"); err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	if body := fs.body("POST /chat.update"); body["roomId"] != "general" || body["msgId"] != "reply1" || body["text"] != "final" {
+		t.Errorf("update body = %v", body)
+	}
+
+	undo, err := ch.ReactToMessage(ctx, "general/m1", "m1")
+	if err != nil {
+		t.Fatalf("ReactToMessage() error = %v", err)
+	}
+	if body := fs.body("POST /chat.react"); body["messageId"] != "m1" || body["shouldReact"] != true {
+		t.Errorf("react body = %v", body)
+	}
+	undo()
+	if body := fs.body("POST /chat.react"); body["shouldReact"] != false {
+		t.Errorf("unreact body = %v", body)
+	}
+
+	stop, err := ch.StartTyping(ctx, "general/m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		fs.mu.Lock()
+		methods := append([]ddpCall(nil), fs.methods...)
+		fs.mu.Unlock()
+		if len(methods) > 0 {
+			call := methods[0]
+			if call.Method != "stream-notify-room" || len(call.Params) < 3 || call.Params[0] != "general/user-activity" || call.Params[1] != "picobot" {
+				t.Errorf("typing call = %+v", call)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no typing notification")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,205 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	actionsBlockID  = "picoclaw_actions"
+	buttonActionID  = "picoclaw_button"
+	selectActionID  = "picoclaw_select"
+	maxSectionText  = 3000 // Block Kit section text limit
+	maxButtonValue  = 2000
+	maxOptionValue  = 150
+	maxBlockElement = 25 // elements per actions block
+)
+
+// SendInteractive implements channels.InteractiveCapable with Block Kit:
+// a header for the card title, sections for the text, an image block and
+// one actions block holding the buttons and the select menu.
+func (c *SlackChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	channelID, threadTS := replyTarget(msg)
+	if channelID == "" {
+		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
+	}
+
+	opts := []slack.MsgOption{
+		// Plain text is still required for notifications and old clients.
+		slack.MsgOptionText(channels.InteractiveFallbackText(msg.Content, msg.Interactive), false),
+		slack.MsgOptionBlocks(c.buildBlocks(msg.Content, msg.Interactive)...),
+	}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+
+	if _, _, err := c.api.PostMessageContext(ctx, channelID, opts...); err != nil {
+		return fmt.Errorf("slack send: %w", channels.ErrTemporary)
+	}
+
+	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
+		msgRef := ref.(slackMessageRef)
+		c.api.AddReaction("white_check_mark", slack.ItemRef{
+			Channel:   msgRef.ChannelID,
+			Timestamp: msgRef.Timestamp,
+		})
+	}
+	return nil
+}
+
+func (c *SlackChannel) buildBlocks(content string, in *bus.Interactive) []slack.Block {
+	var blocks []slack.Block
+
+	if in.Card != nil && in.Card.Title != "" {
+		blocks = append(blocks, slack.NewHeaderBlock(
+			slack.NewTextBlockObject(slack.PlainTextType, in.Card.Title, true, false)))
+	}
+	if strings.TrimSpace(content) != "" {
+		for _, part := range channels.SplitMessage(content, maxSectionText) {
+			blocks = append(blocks, slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, part, false, false), nil, nil))
+		}
+	}
+	if in.Card != nil && in.Card.ImageURL != "" {
+		alt := in.Card.Title
+		if alt == "" {
+			alt = "image"
+		}
+		blocks = append(blocks, slack.NewImageBlock(in.Card.ImageURL, alt, "", nil))
+	}
+
+	var elements []slack.BlockElement
+	for i, b := range in.Buttons {
+		if len(elements) == maxBlockElement-1 {
+			break
+		}
+		btn := slack.NewButtonBlockElement(
+			fmt.Sprintf("%s_%d", buttonActionID, i),
+			c.callbacks.Encode(b.CallbackValue(), maxButtonValue),
+			slack.NewTextBlockObject(slack.PlainTextType, b.Label, true, false),
+		)
+		switch b.Style {
+		case "primary":
+			btn.WithStyle(slack.StylePrimary)
+		case "danger":
+			btn.WithStyle(slack.StyleDanger)
+		}
+		if b.URL != "" {
+			btn.WithURL(b.URL)
+		}
+		elements = append(elements, btn)
+	}
+	if in.Select != nil && len(in.Select.Options) > 0 {
+		options := make([]*slack.OptionBlockObject, 0, len(in.Select.Options))
+		for _, o := range in.Select.Options {
+			options = append(options, slack.NewOptionBlockObject(
+				c.callbacks.Encode(o.CallbackValue(), maxOptionValue),
+				slack.NewTextBlockObject(slack.PlainTextType, o.Label, true, false),
+				nil,
+			))
+		}
+		placeholder := in.Select.Placeholder
+		if placeholder == "" {
+			placeholder = "Choose an option"
+		}
+		elements = append(elements, slack.NewOptionsSelectBlockElement(
+			slack.OptTypeStatic,
+			slack.NewTextBlockObject(slack.PlainTextType, placeholder, true, false),
+			selectActionID,
+			options...,
+		))
+	}
+	if len(elements) > 0 {
+		blocks = append(blocks, slack.NewActionBlock(actionsBlockID, elements...))
+	}
+
+	return blocks
+}
+
+// handleInteractive acknowledges Socket Mode interactive events and turns
+// block_actions on our own controls into inbound messages.
+func (c *SlackChannel) handleInteractive(event socketmode.Event) {
+	if event.Request != nil {
+		c.socketClient.Ack(*event.Request)
+	}
+
+	callback, ok := event.Data.(slack.InteractionCallback)
+	if !ok || callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action == nil || action.BlockID != actionsBlockID {
+			continue
+		}
+		var kind, value string
+		switch {
+		case action.ActionID == selectActionID:
+			kind = channels.InteractionSelect
+			value = c.callbacks.Decode(action.SelectedOption.Value)
+		case strings.HasPrefix(action.ActionID, buttonActionID):
+			kind = channels.InteractionButton
+			value = c.callbacks.Decode(action.Value)
+		}
+		if value == "" {
+			continue
+		}
+		c.publishInteraction(callback, kind, value)
+	}
+}
+
+func (c *SlackChannel) publishInteraction(callback slack.InteractionCallback, kind, value string) {
+	userID := callback.User.ID
+	channelID := callback.Channel.ID
+	if channelID == "" {
+		channelID = callback.Container.ChannelID
+	}
+	if userID == "" || channelID == "" {
+		return
+	}
+
+	chatID := channelID
+	if callback.Container.ThreadTs != "" {
+		chatID = channelID + "/" + callback.Container.ThreadTs
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "slack",
+		PlatformID:  userID,
+		CanonicalID: identity.BuildCanonicalID("slack", userID),
+		Username:    callback.User.Name,
+	}
+
+	peer := bus.Peer{Kind: "channel", ID: channelID}
+	if strings.HasPrefix(channelID, "D") {
+		peer = bus.Peer{Kind: "direct", ID: userID}
+	}
+
+	metadata := map[string]string{
+		"channel_id": channelID,
+		"thread_ts":  callback.Container.ThreadTs,
+		"platform":   "slack",
+		"team_id":    c.teamID,
+	}
+
+	logger.DebugCF("slack", "Received interaction", map[string]any{
+		"sender_id": userID,
+		"chat_id":   chatID,
+		"kind":      kind,
+	})
+
+	c.HandleInteraction(c.ctx, peer, userID, chatID, callback.Container.MessageTs, kind, value, metadata, sender)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
@@ -29,6 +30,24 @@ type SlackChannel struct {
 	ctx          context.Context
 	cancel       context.CancelFunc
 	pendingAcks  sync.Map
+	callbacks    channels.CallbackRegistry
+
+	parentsMu sync.Mutex
+	parents   map[string]threadParentEntry // "channelID/threadTS" → root message
+}
+
+const (
+	// threadParentTTL is how long a fetched thread root is reused.
+	// conversations.replies is heavily rate limited, and without the cache
+	// it would be called for every message in a thread.
+	threadParentTTL = time.Hour
+	// maxThreadParents bounds the thread root cache.
+	maxThreadParents = 512
+)
+
+type threadParentEntry struct {
+	quoted  bus.QuotedMessage
+	fetched time.Time
 }
 
 type slackMessageRef struct {
@@ -113,7 +132,7 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return channels.ErrNotRunning
 	}
 
-	channelID, threadTS := parseSlackChatID(msg.ChatID)
+	channelID, threadTS := replyTarget(msg)
 	if channelID == "" {
 		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
@@ -238,9 +257,7 @@ func (c *SlackChannel) eventLoop() {
 			case socketmode.EventTypeSlashCommand:
 				c.handleSlashCommand(event)
 			case socketmode.EventTypeInteractive:
-				if event.Request != nil {
-					c.socketClient.Ack(*event.Request)
-				}
+				c.handleInteractive(event)
 			}
 		}
 	}
@@ -372,7 +389,12 @@ func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
 		"has_thread": threadTS != "",
 	})
 
-	c.HandleMessage(c.ctx, peer, messageTS, senderID, chatID, content, mediaPaths, metadata, sender)
+	thread := channels.ThreadInfo{ThreadID: threadTS}
+	if threadTS != "" && threadTS != messageTS {
+		thread.ReplyTo = c.threadParent(channelID, threadTS)
+	}
+
+	c.HandleThreadedMessage(c.ctx, peer, thread, messageTS, senderID, chatID, content, mediaPaths, metadata, sender)
 }
 
 func (c *SlackChannel) handleAppMention(ev *slackevents.AppMentionEvent) {
@@ -437,7 +459,78 @@ func (c *SlackChannel) handleAppMention(ev *slackevents.AppMentionEvent) {
 		"team_id":    c.teamID,
 	}
 
-	c.HandleMessage(c.ctx, mentionPeer, messageTS, senderID, chatID, content, nil, metadata, mentionSender)
+	// Replies to a mention outside a thread start one under it.
+	thread := channels.ThreadInfo{ThreadID: messageTS}
+	if threadTS != "" {
+		thread.ThreadID = threadTS
+		if threadTS != messageTS {
+			thread.ReplyTo = c.threadParent(channelID, threadTS)
+		}
+	}
+
+	c.HandleThreadedMessage(c.ctx, mentionPeer, thread, messageTS, senderID, chatID, content, nil, metadata, mentionSender)
+}
+
+// threadParent returns the root message of a thread, fetching it once per
+// threadParentTTL. Slack replies have no
+// other parent, so the root is what a threaded message answers.
+func (c *SlackChannel) threadParent(channelID, threadTS string) *bus.QuotedMessage {
+	key := channelID + "/" + threadTS
+	c.parentsMu.Lock()
+	entry, ok := c.parents[key]
+	c.parentsMu.Unlock()
+	if ok && time.Since(entry.fetched) < threadParentTTL {
+		quoted := entry.quoted
+		return &quoted
+	}
+
+	msgs, _, _, err := c.api.GetConversationRepliesContext(c.ctx, &slack.GetConversationRepliesParameters{
+		ChannelID: channelID,
+		Timestamp: threadTS,
+		Limit:     1,
+	})
+	if err != nil || len(msgs) == 0 {
+		logger.DebugCF("slack", "Failed to fetch thread parent", map[string]any{
+			"channel_id": channelID,
+			"thread_ts":  threadTS,
+			"error":      fmt.Sprint(err),
+		})
+		return &bus.QuotedMessage{MessageID: threadTS}
+	}
+	root := msgs[0]
+	quoted := bus.QuotedMessage{
+		MessageID:  threadTS,
+		SenderID:   root.User,
+		SenderName: root.Username,
+		Content:    c.stripBotMention(root.Text),
+	}
+	c.cacheThreadParent(key, quoted)
+	return &quoted
+}
+
+// cacheThreadParent stores a fetched thread root, dropping expired entries,
+// or an arbitrary one, when the cache is full.
+func (c *SlackChannel) cacheThreadParent(key string, quoted bus.QuotedMessage) {
+	c.parentsMu.Lock()
+	defer c.parentsMu.Unlock()
+
+	if c.parents == nil {
+		c.parents = make(map[string]threadParentEntry)
+	}
+	if len(c.parents) >= maxThreadParents {
+		for k, e := range c.parents {
+			if time.Since(e.fetched) >= threadParentTTL {
+				delete(c.parents, k)
+			}
+		}
+		for k := range c.parents {
+			if len(c.parents) < maxThreadParents {
+				break
+			}
+			delete(c.parents, k)
+		}
+	}
+	c.parents[key] = threadParentEntry{quoted: quoted, fetched: time.Now()}
 }
 
 func (c *SlackChannel) handleSlashCommand(event socketmode.Event) {
@@ -522,6 +615,20 @@ func (c *SlackChannel) stripBotMention(text string) string {
 	return strings.TrimSpace(text)
 }
 
+// replyTarget returns the channel and thread an outbound message is posted
+// in: the thread encoded in the chat ID, then msg.ThreadID. Slack has no
+// quoting replies, so replying to a message starts a thread under it.
+func replyTarget(msg bus.OutboundMessage) (channelID, threadTS string) {
+	channelID, threadTS = parseSlackChatID(msg.ChatID)
+	if threadTS == "" {
+		threadTS = msg.ThreadID
+	}
+	if threadTS == "" {
+		threadTS = msg.ReplyToMessageID
+	}
+	return channelID, threadTS
+}
+
 func parseSlackChatID(chatID string) (channelID, threadTS string) {
 	parts := strings.SplitN(chatID, "/", 2)
 	channelID = parts[0]
@@ -1,9 +1,21 @@
 package slack
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 )
 
@@ -172,3 +184,139 @@ func TestSlackChannelIsAllowed(t *testing.T) {
 		}
 	})
 }
+
+func TestBuildBlocksAndInteraction(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, err := NewSlackChannel(config.SlackConfig{BotToken: "xoxb", AppToken: "xapp"}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.ctx = context.Background()
+
+	blocks := ch.buildBlocks("Deploy?", &bus.Interactive{
+		Card:    &bus.Card{Title: "Release"},
+		Buttons: []bus.Button{{Label: "Yes", Value: "yes", Style: "primary"}, {Label: "Docs", URL: "https://example.com"}},
+		Select:  &bus.Select{Options: []bus.SelectOption{{Label: "staging"}}},
+	})
+	if len(blocks) != 3 {
+		t.Fatalf("blocks = %d, want header, section, actions", len(blocks))
+	}
+	actions, ok := blocks[2].(*slack.ActionBlock)
+	if !ok || actions.BlockID != actionsBlockID || len(actions.Elements.ElementSet) != 3 {
+		t.Fatalf("actions block = %+v", blocks[2])
+	}
+	yes := actions.Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	if yes.Value != "yes" || yes.Style != slack.StylePrimary {
+		t.Errorf("yes button = %+v", yes)
+	}
+	if docs := actions.Elements.ElementSet[1].(*slack.ButtonBlockElement); docs.URL != "https://example.com" {
+		t.Errorf("link button = %+v", docs)
+	}
+
+	ch.handleInteractive(socketmode.Event{
+		Type: socketmode.EventTypeInteractive,
+		Data: slack.InteractionCallback{
+			Type:      slack.InteractionTypeBlockActions,
+			User:      slack.User{ID: "U1"},
+			Container: slack.Container{ChannelID: "C1", MessageTs: "111.1", ThreadTs: "100.0"},
+			ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{{
+				ActionID:       selectActionID,
+				BlockID:        actionsBlockID,
+				SelectedOption: slack.OptionBlockObject{Value: "staging"},
+			}}},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	inbound, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound for block action")
+	}
+	if inbound.Content != "staging" || inbound.ChatID != "C1/100.0" ||
+		inbound.Metadata[channels.MetadataInteraction] != channels.InteractionSelect ||
+		inbound.Metadata[channels.MetadataInteractionMessageID] != "111.1" {
+		t.Errorf("inbound = %+v", inbound)
+	}
+}
+
+func TestReplyTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        bus.OutboundMessage
+		wantThread string
+	}{
+		{"thread in chat ID", bus.OutboundMessage{ChatID: "C1/1.1", ThreadID: "2.2"}, "1.1"},
+		{"thread field", bus.OutboundMessage{ChatID: "C1", ThreadID: "2.2", ReplyToMessageID: "3.3"}, "2.2"},
+		{"reply starts a thread", bus.OutboundMessage{ChatID: "C1", ReplyToMessageID: "3.3"}, "3.3"},
+		{"top level", bus.OutboundMessage{ChatID: "C1"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channelID, threadTS := replyTarget(tt.msg)
+			if channelID != "C1" || threadTS != tt.wantThread {
+				t.Errorf("replyTarget = (%q, %q), want (C1, %q)", channelID, threadTS, tt.wantThread)
+			}
+		})
+	}
+}
+
+func TestHandleMessageEvent_ThreadParent(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/conversations.replies") {
+			http.NotFound(w, r)
+			return
+		}
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":true,"messages":[{"type":"message","user":"U2","text":"<@UBOT> deploy plan","ts":"1700.1"}]}`)
+	}))
+	defer srv.Close()
+
+	messageBus := bus.NewMessageBus()
+	ch := &SlackChannel{
+		BaseChannel: channels.NewBaseChannel("slack", nil, messageBus, nil),
+		api:         slack.New("xoxb-test", slack.OptionAPIURL(srv.URL+"/")),
+		botUserID:   "UBOT",
+		ctx:         context.Background(),
+	}
+
+	ch.handleMessageEvent(&slackevents.MessageEvent{
+		User:            "U1",
+		Channel:         "D1",
+		Text:            "looks good",
+		TimeStamp:       "1700.5",
+		ThreadTimeStamp: "1700.1",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	inbound, ok := messageBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("expected inbound message")
+	}
+	if inbound.ChatID != "D1/1700.1" || inbound.ThreadID != "1700.1" {
+		t.Errorf("chat=%q thread=%q", inbound.ChatID, inbound.ThreadID)
+	}
+	want := bus.QuotedMessage{MessageID: "1700.1", SenderID: "U2", Content: "deploy plan"}
+	if inbound.ReplyTo == nil || *inbound.ReplyTo != want {
+		t.Errorf("reply_to = %+v, want %+v", inbound.ReplyTo, want)
+	}
+
+	// Further replies in the thread reuse the fetched root.
+	ch.handleMessageEvent(&slackevents.MessageEvent{
+		User:            "U1",
+		Channel:         "D1",
+		Text:            "ship it",
+		TimeStamp:       "1700.6",
+		ThreadTimeStamp: "1700.1",
+	})
+	inbound, ok = messageBus.ConsumeInbound(ctx)
+	if !ok || inbound.ReplyTo == nil || *inbound.ReplyTo != want {
+		t.Errorf("second reply_to = %+v, want %+v", inbound.ReplyTo, want)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("conversations.replies called %d times, want 1", n)
+	}
+}
@@ -0,0 +1,229 @@
+package channels
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultStreamEditInterval is the minimum gap between two progressive edits
+// of the same placeholder when a channel has no entry in channelStreamInterval.
+const defaultStreamEditInterval = 1 * time.Second
+
+// channelStreamInterval maps channel name to the minimum interval between
+// streaming edits. Platforms with strict edit quotas get longer intervals.
+var channelStreamInterval = map[string]time.Duration{
+	"telegram": 1500 * time.Millisecond,
+	"discord":  1500 * time.Millisecond,
+	"slack":    1 * time.Second,
+	"feishu":   1 * time.Second,
+	"matrix":   2 * time.Second,
+	"pico":     200 * time.Millisecond,
+}
+
+// streamState tracks the progressive edits of one placeholder while the LLM
+// is still generating the reply. finishStream closes it but leaves it in
+// Manager.streams as a marker, so that a late delta for the same placeholder
+// cannot start a new stream; preSend drops the marker once the placeholder
+// itself is gone.
+type streamState struct {
+	mu            sync.Mutex
+	placeholderID string
+	content       string
+	lastEdit      time.Time
+	scheduled     bool
+	closed        bool
+	createdAt     time.Time
+
+	// editMu serializes EditMessage calls so that the final edit done by
+	// preSend can never be overtaken by a late streaming edit.
+	editMu sync.Mutex
+}
+
+// close stops further streaming edits and waits for an in-flight edit to finish.
+func (s *streamState) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.editMu.Lock()
+	defer s.editMu.Unlock()
+}
+
+func streamIntervalFor(name string) time.Duration {
+	if d, ok := channelStreamInterval[name]; ok {
+		return d
+	}
+	return defaultStreamEditInterval
+}
+
+// dispatchOutboundDelta reads streaming deltas from the bus and turns them
+// into throttled placeholder edits.
+func (m *Manager) dispatchOutboundDelta(ctx context.Context) {
+	logger.InfoC("channels", "Outbound delta dispatcher started")
+
+	for {
+		msg, ok := m.bus.SubscribeOutboundDelta(ctx)
+		if !ok {
+			logger.InfoC("channels", "Outbound delta dispatcher stopped")
+			return
+		}
+		m.handleOutboundDelta(ctx, msg)
+	}
+}
+
+// handleOutboundDelta records the latest partial content for a chat and
+// schedules an edit of its placeholder. Chats without a placeholder, or
+// channels that cannot edit messages, simply wait for the final message.
+func (m *Manager) handleOutboundDelta(ctx context.Context, msg bus.OutboundDeltaMessage) {
+	if constants.IsInternalChannel(msg.Channel) || msg.Content == "" {
+		return
+	}
+
+	name := ChannelKey(msg.Channel, msg.AccountID)
+	m.mu.RLock()
+	ch, exists := m.channels[name]
+	m.mu.RUnlock()
+	if !exists {
+		return
+	}
+	editor, ok := ch.(MessageEditor)
+	if !ok {
+		return
+	}
+
+	key := name + ":" + msg.ChatID
+	v, ok := m.placeholders.Load(key)
+	if !ok {
+		return
+	}
+	entry, ok := v.(placeholderEntry)
+	if !ok || entry.id == "" {
+		return
+	}
+
+	st := m.streamFor(key, entry.id)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.closed {
+		return
+	}
+	st.content = msg.Content
+	if st.scheduled {
+		return
+	}
+	st.scheduled = true
+
+	wait := max(streamIntervalFor(msg.Channel)-time.Since(st.lastEdit), 0)
+	time.AfterFunc(wait, func() {
+		m.flushStream(ctx, name, msg.ChatID, ch, editor, st)
+	})
+}
+
+// streamFor returns the stream state of placeholderID, creating it unless
+// one (possibly a closed marker) is already stored. The compare-and-swap
+// loop keeps a concurrent finishStream from being overwritten.
+func (m *Manager) streamFor(key, placeholderID string) *streamState {
+	for {
+		existing, ok := m.streams.Load(key)
+		if ok {
+			if st := existing.(*streamState); st.placeholderID == placeholderID {
+				return st
+			}
+		}
+		st := &streamState{placeholderID: placeholderID, createdAt: time.Now()}
+		if ok {
+			if m.streams.CompareAndSwap(key, existing, st) {
+				return st
+			}
+		} else if _, loaded := m.streams.LoadOrStore(key, st); !loaded {
+			return st
+		}
+	}
+}
+
+// flushStream edits the placeholder with the most recent partial content.
+func (m *Manager) flushStream(
+	ctx context.Context,
+	name, chatID string,
+	ch Channel,
+	editor MessageEditor,
+	st *streamState,
+) {
+	st.editMu.Lock()
+	defer st.editMu.Unlock()
+
+	st.mu.Lock()
+	st.scheduled = false
+	if st.closed || ctx.Err() != nil {
+		st.mu.Unlock()
+		return
+	}
+	// The final message may already have consumed the placeholder.
+	key := name + ":" + chatID
+	if v, ok := m.placeholders.Load(key); !ok || v.(placeholderEntry).id != st.placeholderID {
+		st.closed = true
+		st.mu.Unlock()
+		m.streams.CompareAndDelete(key, st)
+		return
+	}
+	content := st.content
+	st.lastEdit = time.Now()
+	st.mu.Unlock()
+
+	// A partial reply longer than the platform limit cannot be shown in one
+	// message; show the head and let the final send split it properly.
+	if mlp, ok := ch.(MessageLengthProvider); ok {
+		if maxLen := mlp.MaxMessageLength(); maxLen > 0 {
+			if runes := []rune(content); len(runes) > maxLen {
+				content = string(runes[:maxLen-1]) + "…"
+			}
+		}
+	}
+
+	if err := editor.EditMessage(ctx, chatID, st.placeholderID, content); err != nil {
+		logger.DebugCF("channels", "Streaming edit failed", map[string]any{
+			"channel": name,
+			"chat_id": chatID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// finishStream ends streaming for a chat so that the final message wins.
+// While the placeholder is still recorded, a closed marker for it replaces
+// the stream state so that deltas arriving afterwards are dropped.
+func (m *Manager) finishStream(key string) {
+	var old any
+	var loaded bool
+	if v, ok := m.placeholders.Load(key); ok {
+		marker := &streamState{placeholderID: v.(placeholderEntry).id, closed: true, createdAt: time.Now()}
+		old, loaded = m.streams.Swap(key, marker)
+	} else {
+		old, loaded = m.streams.LoadAndDelete(key)
+	}
+	if loaded {
+		if st, ok := old.(*streamState); ok {
+			st.close()
+		}
+	}
+}
+
+// releaseStream removes the closed marker of key once its placeholder has
+// been consumed; later deltas then find no placeholder and stop early.
+func (m *Manager) releaseStream(key string) {
+	if v, ok := m.streams.Load(key); ok {
+		st := v.(*streamState)
+		st.mu.Lock()
+		closed := st.closed
+		st.mu.Unlock()
+		if closed {
+			m.streams.CompareAndDelete(key, v)
+		}
+	}
+}
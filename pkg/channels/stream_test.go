@@ -0,0 +1,168 @@
+package channels
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// setStreamInterval overrides the streaming throttle for a channel name for
+// the duration of a test.
+func setStreamInterval(t *testing.T, name string, d time.Duration) {
+	t.Helper()
+	prev, had := channelStreamInterval[name]
+	channelStreamInterval[name] = d
+	t.Cleanup(func() {
+		if had {
+			channelStreamInterval[name] = prev
+		} else {
+			delete(channelStreamInterval, name)
+		}
+	})
+}
+
+// recordingEditor is a MessageEditor that records every edit it receives.
+type recordingEditor struct {
+	mockChannel
+	mu    sync.Mutex
+	edits []string
+}
+
+func (r *recordingEditor) EditMessage(_ context.Context, _, _, content string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.edits = append(r.edits, content)
+	return nil
+}
+
+func (r *recordingEditor) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.edits...)
+}
+
+func TestHandleOutboundDelta_ThrottlesEdits(t *testing.T) {
+	setStreamInterval(t, "test", 50*time.Millisecond)
+
+	m := newTestManager()
+	ch := &recordingEditor{}
+	m.channels["test"] = ch
+	m.RecordPlaceholder("test", "123", "ph1")
+
+	ctx := context.Background()
+	m.handleOutboundDelta(ctx, bus.OutboundDeltaMessage{Channel: "test", ChatID: "123", Delta: "He", Content: "He"})
+	m.handleOutboundDelta(ctx, bus.OutboundDeltaMessage{Channel: "test", ChatID: "123", Delta: "llo", Content: "Hello"})
+
+	deadline := time.Now().Add(time.Second)
+	for len(ch.snapshot()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	edits := ch.snapshot()
+	if len(edits) != 1 {
+		t.Fatalf("expected a single coalesced edit, got %q", edits)
+	}
+	if edits[0] != "Hello" {
+		t.Fatalf("expected edit with latest content 'Hello', got %q", edits[0])
+	}
+}
+
+func TestHandleOutboundDelta_NoPlaceholder(t *testing.T) {
+	setStreamInterval(t, "test", time.Millisecond)
+
+	m := newTestManager()
+	ch := &recordingEditor{}
+	m.channels["test"] = ch
+
+	m.handleOutboundDelta(context.Background(), bus.OutboundDeltaMessage{
+		Channel: "test", ChatID: "123", Delta: "Hi", Content: "Hi",
+	})
+	time.Sleep(20 * time.Millisecond)
+
+	if edits := ch.snapshot(); len(edits) != 0 {
+		t.Fatalf("expected no edits without a placeholder, got %q", edits)
+	}
+	if _, ok := m.streams.Load("test:123"); ok {
+		t.Fatal("expected no stream state without a placeholder")
+	}
+}
+
+func TestPreSend_FinalMessageWinsOverPendingStream(t *testing.T) {
+	setStreamInterval(t, "test", 30*time.Millisecond)
+
+	m := newTestManager()
+	ch := &recordingEditor{}
+	m.channels["test"] = ch
+	m.RecordPlaceholder("test", "123", "ph1")
+
+	ctx := context.Background()
+	m.handleOutboundDelta(ctx, bus.OutboundDeltaMessage{Channel: "test", ChatID: "123", Delta: "a", Content: "a"})
+	time.Sleep(60 * time.Millisecond)
+	// Schedule a second edit, then deliver the final message before it fires.
+	m.handleOutboundDelta(ctx, bus.OutboundDeltaMessage{Channel: "test", ChatID: "123", Delta: "b", Content: "ab"})
+
+	msg := bus.OutboundMessage{Channel: "test", ChatID: "123", Content: "final"}
+	if !m.preSend(ctx, "test", msg, ch) {
+		t.Fatal("expected preSend to edit the placeholder")
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	edits := ch.snapshot()
+	if len(edits) == 0 || edits[len(edits)-1] != "final" {
+		t.Fatalf("expected final edit to be last, got %q", edits)
+	}
+	if _, ok := m.streams.Load("test:123"); ok {
+		t.Fatal("expected stream state to be removed by preSend")
+	}
+}
+
+func TestHandleOutboundDelta_LateDeltaCannotOverwriteFinal(t *testing.T) {
+	setStreamInterval(t, "test", time.Millisecond)
+
+	m := newTestManager()
+	ch := &recordingEditor{}
+	m.channels["test"] = ch
+	m.RecordPlaceholder("test", "123", "ph1")
+	ctx := context.Background()
+
+	// A delta arriving after streaming was finished but before the final
+	// message consumed the placeholder must be dropped.
+	m.finishStream("test:123")
+	m.handleOutboundDelta(ctx, bus.OutboundDeltaMessage{Channel: "test", ChatID: "123", Delta: "late", Content: "late"})
+
+	msg := bus.OutboundMessage{Channel: "test", ChatID: "123", Content: "final"}
+	if !m.preSend(ctx, "test", msg, ch) {
+		t.Fatal("expected preSend to edit the placeholder")
+	}
+	// And so must one arriving after it.
+	m.handleOutboundDelta(ctx, bus.OutboundDeltaMessage{Channel: "test", ChatID: "123", Delta: "later", Content: "later"})
+	time.Sleep(20 * time.Millisecond)
+
+	if edits := ch.snapshot(); len(edits) != 1 || edits[0] != "final" {
+		t.Fatalf("expected only the final edit, got %q", edits)
+	}
+	if _, ok := m.streams.Load("test:123"); ok {
+		t.Fatal("expected no stream state left behind")
+	}
+}
+
+func TestFlushStream_SkipsConsumedPlaceholder(t *testing.T) {
+	m := newTestManager()
+	ch := &recordingEditor{}
+	m.channels["test"] = ch
+
+	// A state created just before the placeholder was consumed.
+	st := &streamState{placeholderID: "ph1", content: "partial", scheduled: true, createdAt: time.Now()}
+	m.streams.Store("test:123", st)
+	m.flushStream(context.Background(), "test", "123", ch, ch, st)
+
+	if edits := ch.snapshot(); len(edits) != 0 {
+		t.Fatalf("expected no edit without a placeholder, got %q", edits)
+	}
+	if _, ok := m.streams.Load("test:123"); ok {
+		t.Fatal("expected the orphaned stream state to be removed")
+	}
+}
@@ -0,0 +1,170 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Telegram limits callback_data to 64 bytes; two of them carry the kind.
+const maxCallbackData = 64 - 2
+
+// SendInteractive implements channels.InteractiveCapable using an inline
+// keyboard. Buttons share one row when there are at most three of them;
+// select options have no native counterpart and get one row each.
+func (c *TelegramChannel) SendInteractive(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+
+	chatID, err := parseChatID(msg.ChatID)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID %s: %w", msg.ChatID, channels.ErrSendFailed)
+	}
+
+	in := msg.Interactive
+	content := msg.Content
+	if in.Card != nil && in.Card.Title != "" {
+		content = "**" + in.Card.Title + "**\n\n" + content
+	}
+	if strings.TrimSpace(content) == "" {
+		content = "Choose an option:"
+		if in.Select != nil && in.Select.Placeholder != "" {
+			content = in.Select.Placeholder
+		}
+	}
+
+	tgMsg := tu.Message(tu.ID(chatID), markdownToTelegramHTML(content))
+	tgMsg.ParseMode = telego.ModeHTML
+	tgMsg.ReplyMarkup = c.inlineKeyboard(in)
+	applyThreading(tgMsg, msg)
+	if in.Card != nil && in.Card.ImageURL != "" {
+		tgMsg.LinkPreviewOptions = &telego.LinkPreviewOptions{
+			URL:              in.Card.ImageURL,
+			PreferLargeMedia: true,
+			ShowAboveText:    true,
+		}
+	}
+
+	if _, err := c.bot.SendMessage(ctx, tgMsg); err != nil {
+		logger.ErrorCF("telegram", "HTML parse failed, falling back to plain text", map[string]any{
+			"error": err.Error(),
+		})
+		tgMsg.Text = content
+		tgMsg.ParseMode = ""
+		if _, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
+			return fmt.Errorf("telegram send: %w", channels.ErrTemporary)
+		}
+	}
+	return nil
+}
+
+func (c *TelegramChannel) inlineKeyboard(in *bus.Interactive) *telego.InlineKeyboardMarkup {
+	var rows [][]telego.InlineKeyboardButton
+
+	var buttons []telego.InlineKeyboardButton
+	for _, b := range in.Buttons {
+		btn := telego.InlineKeyboardButton{Text: b.Label, Style: b.Style}
+		if b.URL != "" {
+			btn.URL = b.URL
+		} else {
+			btn.CallbackData = "b|" + c.callbacks.Encode(b.CallbackValue(), maxCallbackData)
+		}
+		buttons = append(buttons, btn)
+	}
+	if len(buttons) <= 3 {
+		if len(buttons) > 0 {
+			rows = append(rows, buttons)
+		}
+	} else {
+		for _, btn := range buttons {
+			rows = append(rows, []telego.InlineKeyboardButton{btn})
+		}
+	}
+
+	if in.Select != nil {
+		for _, o := range in.Select.Options {
+			rows = append(rows, []telego.InlineKeyboardButton{{
+				Text:         o.Label,
+				CallbackData: "s|" + c.callbacks.Encode(o.CallbackValue(), maxCallbackData),
+			}})
+		}
+	}
+
+	return &telego.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// handleCallbackQuery turns an inline keyboard click into an inbound
+// message. Clicks answer the bot directly, so group mention rules do not
+// apply.
+func (c *TelegramChannel) handleCallbackQuery(ctx context.Context, query *telego.CallbackQuery) error {
+	// Always answer so the client stops showing a spinner on the button.
+	defer func() {
+		_ = c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+	}()
+
+	if query.Message == nil {
+		return nil
+	}
+
+	kind, data, ok := strings.Cut(query.Data, "|")
+	if !ok {
+		return nil
+	}
+	switch kind {
+	case "b":
+		kind = channels.InteractionButton
+	case "s":
+		kind = channels.InteractionSelect
+	default:
+		return nil
+	}
+	value := c.callbacks.Decode(data)
+	if value == "" {
+		logger.DebugCF("telegram", "Callback data expired", map[string]any{"data": query.Data})
+		return nil
+	}
+
+	user := query.From
+	platformID := fmt.Sprintf("%d", user.ID)
+	sender := bus.SenderInfo{
+		Platform:    "telegram",
+		PlatformID:  platformID,
+		CanonicalID: identity.BuildCanonicalID("telegram", platformID),
+		Username:    user.Username,
+		DisplayName: user.FirstName,
+	}
+
+	chat := query.Message.GetChat()
+	peer := bus.Peer{Kind: "direct", ID: platformID}
+	if chat.Type != "private" {
+		peer = bus.Peer{Kind: "group", ID: fmt.Sprintf("%d", chat.ID)}
+	}
+
+	metadata := map[string]string{
+		"user_id":    platformID,
+		"username":   user.Username,
+		"first_name": user.FirstName,
+		"is_group":   fmt.Sprintf("%t", chat.Type != "private"),
+	}
+
+	c.HandleInteraction(c.ctx,
+		peer,
+		platformID,
+		fmt.Sprintf("%d", chat.ID),
+		fmt.Sprintf("%d", query.Message.GetMessageID()),
+		kind,
+		value,
+		metadata,
+		sender,
+	)
+	return nil
+}
@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mymmrac/telego"
+	ta "github.com/mymmrac/telego/telegoapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+)
+
+// paramsConstructor keeps the parameters of every JSON request.
+type paramsConstructor struct {
+	stubConstructor
+	params []any
+}
+
+func (p *paramsConstructor) JSONRequest(parameters any) (*ta.RequestData, error) {
+	p.params = append(p.params, parameters)
+	return &ta.RequestData{}, nil
+}
+
+func TestSendInteractive_InlineKeyboard(t *testing.T) {
+	caller := &stubCaller{
+		callFn: func(ctx context.Context, url string, data *ta.RequestData) (*ta.Response, error) {
+			return successResponse(t), nil
+		},
+	}
+	constructor := &paramsConstructor{}
+	bot, err := telego.NewBot(testToken,
+		telego.WithAPICaller(caller),
+		telego.WithRequestConstructor(constructor),
+		telego.WithDiscardLogger(),
+	)
+	require.NoError(t, err)
+	ch := newTestChannel(t, caller)
+	ch.bot = bot
+
+	long := strings.Repeat("v", 100)
+	err = ch.SendInteractive(context.Background(), bus.OutboundMessage{
+		ChatID:  "12345",
+		Content: "Deploy?",
+		Interactive: &bus.Interactive{
+			Card: &bus.Card{Title: "Release"},
+			Buttons: []bus.Button{
+				{Label: "Yes", Value: "yes", Style: "primary"},
+				{Label: "Long", Value: long},
+				{Label: "Docs", URL: "https://example.com"},
+			},
+			Select: &bus.Select{Options: []bus.SelectOption{{Label: "staging"}}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, constructor.params, 1)
+
+	params, ok := constructor.params[0].(*telego.SendMessageParams)
+	require.True(t, ok)
+	assert.Contains(t, params.Text, "<b>Release</b>")
+	markup, ok := params.ReplyMarkup.(*telego.InlineKeyboardMarkup)
+	require.True(t, ok)
+	require.Len(t, markup.InlineKeyboard, 2)
+
+	row := markup.InlineKeyboard[0]
+	require.Len(t, row, 3)
+	assert.Equal(t, "b|yes", row[0].CallbackData)
+	assert.Equal(t, "primary", row[0].Style)
+	assert.LessOrEqual(t, len(row[1].CallbackData), 64)
+	assert.Equal(t, "https://example.com", row[2].URL)
+	assert.Empty(t, row[2].CallbackData)
+	assert.Equal(t, "s|staging", markup.InlineKeyboard[1][0].CallbackData)
+
+	// A click on the long-valued button resolves back to the full value.
+	messageBus := bus.NewMessageBus()
+	ch.BaseChannel = channels.NewBaseChannel("telegram", nil, messageBus, nil)
+	ch.ctx = context.Background()
+	err = ch.handleCallbackQuery(context.Background(), &telego.CallbackQuery{
+		ID:   "q1",
+		From: telego.User{ID: 7, FirstName: "Alice"},
+		Data: row[1].CallbackData,
+		Message: &telego.Message{
+			MessageID: 99,
+			Chat:      telego.Chat{ID: 12345, Type: "private"},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	inbound, ok := messageBus.ConsumeInbound(ctx)
+	require.True(t, ok)
+	assert.Equal(t, long, inbound.Content)
+	assert.Equal(t, "12345", inbound.ChatID)
+	assert.Equal(t, "7", inbound.Sender.PlatformID)
+	assert.Equal(t, channels.InteractionButton, inbound.Metadata[channels.MetadataInteraction])
+	assert.Equal(t, "99", inbound.Metadata[channels.MetadataInteractionMessageID])
+	assert.True(t, strings.HasSuffix(caller.calls[len(caller.calls)-1].URL, "/answerCallbackQuery"))
+}
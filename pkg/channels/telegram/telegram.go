@@ -49,6 +49,8 @@ type TelegramChannel struct {
 
 	registerFunc     func(context.Context, []commands.Definition) error
 	commandRegCancel context.CancelFunc
+
+	callbacks channels.CallbackRegistry
 }
 
 func NewTelegramChannel(cfg *config.Config, bus *bus.MessageBus) (*TelegramChannel, error) {
@@ -125,6 +127,10 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		return c.handleMessage(ctx, &message)
 	}, th.AnyMessage())
 
+	bh.HandleCallbackQuery(func(ctx *th.Context, query telego.CallbackQuery) error {
+		return c.handleCallbackQuery(ctx, &query)
+	}, th.AnyCallbackQueryWithMessage())
+
 	c.SetRunning(true)
 	logger.InfoCF("telegram", "Telegram bot connected", map[string]any{
 		"username": c.bot.Username(),
@@ -200,9 +206,11 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 			continue
 		}
 
-		if err := c.sendHTMLChunk(ctx, chatID, htmlContent, chunk); err != nil {
+		if err := c.sendHTMLChunk(ctx, chatID, htmlContent, chunk, msg); err != nil {
 			return err
 		}
+		// Only the first chunk quotes the replied-to message.
+		msg.ReplyToMessageID = ""
 	}
 
 	return nil
@@ -210,9 +218,16 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 
 // sendHTMLChunk sends a single HTML message, falling back to the original
 // markdown as plain text on parse failure so users never see raw HTML tags.
-func (c *TelegramChannel) sendHTMLChunk(ctx context.Context, chatID int64, htmlContent, mdFallback string) error {
+// The topic and reply target are taken from msg.
+func (c *TelegramChannel) sendHTMLChunk(
+	ctx context.Context,
+	chatID int64,
+	htmlContent, mdFallback string,
+	msg bus.OutboundMessage,
+) error {
 	tgMsg := tu.Message(tu.ID(chatID), htmlContent)
 	tgMsg.ParseMode = telego.ModeHTML
+	applyThreading(tgMsg, msg)
 
 	if _, err := c.bot.SendMessage(ctx, tgMsg); err != nil {
 		logger.ErrorCF("telegram", "HTML parse failed, falling back to plain text", map[string]any{
@@ -274,6 +289,19 @@ func (c *TelegramChannel) EditMessage(ctx context.Context, chatID string, messag
 	return err
 }
 
+// DeleteMessage implements channels.MessageDeleter.
+func (c *TelegramChannel) DeleteMessage(ctx context.Context, chatID string, messageID string) error {
+	cid, err := parseChatID(chatID)
+	if err != nil {
+		return err
+	}
+	mid, err := strconv.Atoi(messageID)
+	if err != nil {
+		return err
+	}
+	return c.bot.DeleteMessage(ctx, tu.Delete(tu.ID(cid), mid))
+}
+
 // SendPlaceholder implements channels.PlaceholderCapable.
 // It sends a placeholder message (e.g. "Thinking... 💭") that will later be
 // edited to the actual response via EditMessage (channels.MessageEditor).
@@ -531,8 +559,9 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 		"is_group":   fmt.Sprintf("%t", message.Chat.Type != "private"),
 	}
 
-	c.HandleMessage(c.ctx,
+	c.HandleThreadedMessage(c.ctx,
 		peer,
+		threadInfo(message),
 		messageID,
 		platformID,
 		fmt.Sprintf("%d", chatID),
@@ -544,6 +573,55 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 	return nil
 }
 
+// threadInfo reports the forum topic message was posted in and the message
+// it replies to. Inside a topic, messages that are not explicit replies point
+// at the topic's creation message, which is not treated as a quote.
+func threadInfo(message *telego.Message) channels.ThreadInfo {
+	var info channels.ThreadInfo
+	if message.IsTopicMessage && message.MessageThreadID != 0 {
+		info.ThreadID = strconv.Itoa(message.MessageThreadID)
+	}
+
+	parent := message.ReplyToMessage
+	if parent == nil || parent.ForumTopicCreated != nil {
+		return info
+	}
+	quoted := &bus.QuotedMessage{
+		MessageID: strconv.Itoa(parent.MessageID),
+		Content:   parent.Text,
+	}
+	if quoted.Content == "" {
+		quoted.Content = parent.Caption
+	}
+	// A partial quote is what the user actually selected.
+	if message.Quote != nil && message.Quote.Text != "" {
+		quoted.Content = message.Quote.Text
+	}
+	if parent.From != nil {
+		quoted.SenderID = strconv.FormatInt(parent.From.ID, 10)
+		quoted.SenderName = parent.From.FirstName
+		if quoted.SenderName == "" {
+			quoted.SenderName = parent.From.Username
+		}
+	}
+	info.ReplyTo = quoted
+	return info
+}
+
+// applyThreading sets the forum topic and reply target of an outgoing
+// message. Replies to a message that has since been deleted are still sent.
+func applyThreading(params *telego.SendMessageParams, msg bus.OutboundMessage) {
+	if id, err := strconv.Atoi(msg.ThreadID); err == nil {
+		params.MessageThreadID = id
+	}
+	if id, err := strconv.Atoi(msg.ReplyToMessageID); err == nil {
+		params.ReplyParameters = &telego.ReplyParameters{
+			MessageID:                id,
+			AllowSendingWithoutReply: true,
+		}
+	}
+}
+
 func (c *TelegramChannel) downloadPhoto(ctx context.Context, fileID string) string {
 	file, err := c.bot.GetFile(ctx, &telego.GetFileParams{FileID: fileID})
 	if err != nil {
@@ -0,0 +1,105 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mymmrac/telego"
+	ta "github.com/mymmrac/telego/telegoapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+)
+
+func TestSend_ThreadAndReply(t *testing.T) {
+	caller := &stubCaller{
+		callFn: func(ctx context.Context, url string, data *ta.RequestData) (*ta.Response, error) {
+			return successResponse(t), nil
+		},
+	}
+	constructor := &paramsConstructor{}
+	bot, err := telego.NewBot(testToken,
+		telego.WithAPICaller(caller),
+		telego.WithRequestConstructor(constructor),
+		telego.WithDiscardLogger(),
+	)
+	require.NoError(t, err)
+	ch := newTestChannel(t, caller)
+	ch.bot = bot
+
+	// HTML expansion forces two chunks; only the first one is a reply.
+	err = ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:           "12345",
+		Content:          strings.Repeat("**a** ", 600),
+		ThreadID:         "7",
+		ReplyToMessageID: "42",
+	})
+	require.NoError(t, err)
+	require.Greater(t, len(constructor.params), 1)
+
+	first := constructor.params[0].(*telego.SendMessageParams)
+	assert.Equal(t, 7, first.MessageThreadID)
+	require.NotNil(t, first.ReplyParameters)
+	assert.Equal(t, 42, first.ReplyParameters.MessageID)
+
+	second := constructor.params[1].(*telego.SendMessageParams)
+	assert.Equal(t, 7, second.MessageThreadID)
+	assert.Nil(t, second.ReplyParameters)
+}
+
+func TestHandleMessage_TopicReply(t *testing.T) {
+	messageBus := bus.NewMessageBus()
+	ch := &TelegramChannel{
+		BaseChannel: channels.NewBaseChannel("telegram", nil, messageBus, nil),
+		chatIDs:     make(map[string]int64),
+		ctx:         context.Background(),
+	}
+
+	chat := telego.Chat{ID: -100, Type: "supergroup", IsForum: true}
+	topicRoot := &telego.Message{
+		MessageID:         5,
+		Chat:              chat,
+		ForumTopicCreated: &telego.ForumTopicCreated{Name: "ops"},
+	}
+	msgs := []*telego.Message{
+		{
+			Text: "plain", MessageID: 10, MessageThreadID: 5, IsTopicMessage: true,
+			Chat: chat, From: &telego.User{ID: 1, FirstName: "Alice"},
+			ReplyToMessage: topicRoot,
+		},
+		{
+			Text: "why?", MessageID: 11, MessageThreadID: 5, IsTopicMessage: true,
+			Chat: chat, From: &telego.User{ID: 1, FirstName: "Alice"},
+			ReplyToMessage: &telego.Message{
+				MessageID: 9, Chat: chat, Text: "restart the db",
+				From: &telego.User{ID: 2, FirstName: "Bob"},
+			},
+		},
+	}
+
+	var got []bus.InboundMessage
+	for _, m := range msgs {
+		require.NoError(t, ch.handleMessage(context.Background(), m))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		inbound, ok := messageBus.ConsumeInbound(ctx)
+		cancel()
+		require.True(t, ok)
+		got = append(got, inbound)
+	}
+
+	assert.Equal(t, "5", got[0].ThreadID)
+	assert.Nil(t, got[0].ReplyTo, "topic root is not a quote")
+
+	assert.Equal(t, "5", got[1].ThreadID)
+	require.NotNil(t, got[1].ReplyTo)
+	assert.Equal(t, bus.QuotedMessage{
+		MessageID:  "9",
+		SenderID:   "2",
+		SenderName: "Bob",
+		Content:    "restart the db",
+	}, *got[1].ReplyTo)
+}
@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// renderTemplate replaces every {{ path }} placeholder in tmpl with the value
+// found at that path in doc. A placeholder may list alternatives separated by
+// "|"; the first non-empty one wins. Missing values render as "".
+func renderTemplate(tmpl string, doc any) string {
+	return placeholderPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
+		expr := placeholderPattern.FindStringSubmatch(match)[1]
+		for _, alt := range strings.Split(expr, "|") {
+			value, ok := lookupPath(doc, strings.TrimSpace(alt))
+			if !ok {
+				continue
+			}
+			if s := valueString(value); s != "" {
+				return s
+			}
+		}
+		return ""
+	})
+}
+
+// lookupPath resolves a JSONPath-like path: an optional leading "$", then
+// keys separated by "." and array indexes or quoted keys in brackets, e.g.
+// $.alerts[0].labels["alert name"]. Numeric dot segments index arrays too.
+func lookupPath(doc any, path string) (any, bool) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false
+	}
+	current := doc
+	for _, seg := range segments {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[seg]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			idx, err := strconv.Atoi(seg)
+			if err != nil {
+				return nil, false
+			}
+			if idx < 0 {
+				idx += len(node)
+			}
+			if idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			current = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func parsePath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var segments []string
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", path)
+			}
+			key := strings.TrimSpace(path[i+1 : i+end])
+			if unquoted, err := strconv.Unquote(key); err == nil {
+				key = unquoted
+			} else {
+				key = strings.Trim(key, "'")
+			}
+			segments = append(segments, key)
+			i += end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segments = append(segments, path[i:i+end])
+			i += end
+		}
+	}
+	return segments, nil
+}
+
+// valueString renders a decoded JSON value; objects and arrays are rendered
+// as compact JSON.
+func valueString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,290 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultWebhookPath     = "/webhook/generic"
+	defaultSignatureHeader = "X-Hub-Signature-256"
+	defaultSenderID        = "webhook"
+	defaultChatID          = "default"
+	maxBodyBytes           = 1 << 20
+
+	// Headers set on callback deliveries.
+	callbackSignatureHeader = "X-Picoclaw-Signature"
+	callbackTimestampHeader = "X-Picoclaw-Timestamp"
+)
+
+// WebhookChannel lets arbitrary systems talk to the agent: authenticated JSON
+// POSTs on the shared HTTP server become inbound messages, and replies are
+// POSTed to a callback URL.
+type WebhookChannel struct {
+	*channels.BaseChannel
+	config config.WebhookConfig
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWebhookChannel creates a webhook channel. At least one of auth_token and
+// secret is required so the endpoint is never open to anyone.
+func NewWebhookChannel(cfg config.WebhookConfig, messageBus *bus.MessageBus) (*WebhookChannel, error) {
+	if cfg.AuthToken == "" && cfg.Secret == "" {
+		return nil, fmt.Errorf("webhook auth_token or secret is required")
+	}
+
+	base := channels.NewBaseChannel("webhook", cfg, messageBus, cfg.AllowFrom,
+		channels.WithReasoningChannelID(cfg.ReasoningChannelID),
+	)
+
+	return &WebhookChannel{
+		BaseChannel: base,
+		config:      cfg,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Start marks the channel as running; requests arrive via ServeHTTP.
+func (c *WebhookChannel) Start(ctx context.Context) error {
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.SetRunning(true)
+	logger.InfoCF("webhook", "Webhook channel started", map[string]any{
+		"path":     c.WebhookPath(),
+		"callback": c.config.CallbackURL != "",
+	})
+	return nil
+}
+
+// Stop stops the channel.
+func (c *WebhookChannel) Stop(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.SetRunning(false)
+	logger.InfoC("webhook", "Webhook channel stopped")
+	return nil
+}
+
+// WebhookPath returns the path for registering on the shared HTTP server.
+func (c *WebhookChannel) WebhookPath() string {
+	if c.config.WebhookPath != "" {
+		return c.config.WebhookPath
+	}
+	return defaultWebhookPath
+}
+
+// ServeHTTP implements http.Handler for the shared HTTP server.
+func (c *WebhookChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !c.IsRunning() {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxBodyBytes {
+		http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !c.authenticate(r, body) {
+		logger.WarnCF("webhook", "Rejected unauthenticated request", map[string]any{
+			"remote_addr": r.RemoteAddr,
+		})
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var payload any
+	if err := dec.Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	msg := c.mapPayload(payload, body)
+	if strings.TrimSpace(msg.content) == "" {
+		http.Error(w, "Payload has no content", http.StatusUnprocessableEntity)
+		return
+	}
+
+	sender := bus.SenderInfo{
+		Platform:    "webhook",
+		PlatformID:  msg.senderID,
+		CanonicalID: identity.BuildCanonicalID("webhook", msg.senderID),
+	}
+	if !c.IsAllowedSender(sender) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	metadata := map[string]string{
+		"path": r.URL.Path,
+	}
+	peer := bus.Peer{Kind: "group", ID: msg.chatID}
+	c.HandleMessage(c.ctx, peer, msg.messageID, msg.senderID, msg.chatID, msg.content, nil, metadata, sender)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte(`{"status":"accepted"}`))
+}
+
+// authenticate checks every configured method: a shared token sent as
+// "Authorization: Bearer <token>" or X-Webhook-Token, and an HMAC-SHA256
+// signature of the body in the signature header ("sha256=<hex>" or bare hex).
+func (c *WebhookChannel) authenticate(r *http.Request, body []byte) bool {
+	if c.config.AuthToken != "" {
+		token := r.Header.Get("X-Webhook-Token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = strings.TrimSpace(bearer)
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.config.AuthToken)) != 1 {
+			return false
+		}
+	}
+	if c.config.Secret != "" {
+		header := c.config.SignatureHeader
+		if header == "" {
+			header = defaultSignatureHeader
+		}
+		signature := strings.TrimPrefix(strings.TrimSpace(r.Header.Get(header)), "sha256=")
+		got, err := hex.DecodeString(signature)
+		if err != nil || !hmac.Equal(got, sign(c.config.Secret, body)) {
+			return false
+		}
+	}
+	return true
+}
+
+type mappedMessage struct {
+	content   string
+	senderID  string
+	chatID    string
+	messageID string
+}
+
+// mapPayload applies the configured templates. Without a content template the
+// raw payload is passed to the agent as-is.
+func (c *WebhookChannel) mapPayload(payload any, raw []byte) mappedMessage {
+	mapping := c.config.Mapping
+	msg := mappedMessage{content: string(raw)}
+	if mapping.Content != "" {
+		msg.content = renderTemplate(mapping.Content, payload)
+	}
+	if mapping.SenderID != "" {
+		msg.senderID = strings.TrimSpace(renderTemplate(mapping.SenderID, payload))
+	}
+	if mapping.ChatID != "" {
+		msg.chatID = strings.TrimSpace(renderTemplate(mapping.ChatID, payload))
+	}
+	if mapping.MessageID != "" {
+		msg.messageID = strings.TrimSpace(renderTemplate(mapping.MessageID, payload))
+	}
+	if msg.senderID == "" {
+		msg.senderID = defaultSenderID
+	}
+	if msg.chatID == "" {
+		msg.chatID = defaultChatID
+	}
+	return msg
+}
+
+// callbackPayload is the JSON body POSTed to the callback URL.
+type callbackPayload struct {
+	Channel   string `json:"channel"`
+	AccountID string `json:"account_id,omitempty"`
+	ChatID    string `json:"chat_id"`
+	Content   string `json:"content"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Send POSTs the reply to the callback URL. Network errors and 5xx/429
+// responses are reported as temporary so the Manager retries them.
+func (c *WebhookChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return channels.ErrNotRunning
+	}
+	if c.config.CallbackURL == "" {
+		logger.DebugCF("webhook", "No callback_url configured, dropping reply", map[string]any{
+			"chat_id": msg.ChatID,
+		})
+		return nil
+	}
+
+	now := time.Now().Unix()
+	body, err := json.Marshal(callbackPayload{
+		Channel:   "webhook",
+		AccountID: c.AccountID(),
+		ChatID:    msg.ChatID,
+		Content:   msg.Content,
+		Timestamp: now,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal callback payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", channels.ErrSendFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range c.config.CallbackHeaders {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set(callbackTimestampHeader, fmt.Sprint(now))
+	if secret := c.callbackSecret(); secret != "" {
+		req.Header.Set(callbackSignatureHeader, "sha256="+hex.EncodeToString(sign(secret, body)))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return channels.ClassifyNetError(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return channels.ClassifySendError(resp.StatusCode,
+			fmt.Errorf("callback returned status %d", resp.StatusCode))
+	}
+	return nil
+}
+
+func (c *WebhookChannel) callbackSecret() string {
+	if c.config.CallbackSecret != "" {
+		return c.config.CallbackSecret
+	}
+	return c.config.Secret
+}
+
+func sign(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
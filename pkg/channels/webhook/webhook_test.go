@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	var doc any
+	dec := json.NewDecoder(strings.NewReader(`{
+		"status": "firing",
+		"alerts": [{"labels": {"alertname": "HighCPU", "the host": "web-1"}, "value": 97.5}],
+		"repo": {"full_name": "acme/app"},
+		"empty": ""
+	}`))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tmpl, want string
+	}{
+		{"{{ $.status }}", "firing"},
+		{"[{{$.alerts[0].labels.alertname}}] {{ $.alerts[0].value }}", "[HighCPU] 97.5"},
+		{`{{ $.alerts[0].labels["the host"] }}`, "web-1"},
+		{"{{ alerts.0.labels.alertname }}", "HighCPU"},
+		{"{{ $.alerts[-1].value }}", "97.5"},
+		{"{{ $.empty | $.repo.full_name }}", "acme/app"},
+		{"{{ $.missing }}", ""},
+		{"{{ $.repo }}", `{"full_name":"acme/app"}`},
+		{"static text", "static text"},
+	}
+	for _, tt := range tests {
+		if got := renderTemplate(tt.tmpl, doc); got != tt.want {
+			t.Errorf("renderTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func newTestChannel(t *testing.T, cfg config.WebhookConfig) (*WebhookChannel, *bus.MessageBus) {
+	t.Helper()
+	msgBus := bus.NewMessageBus()
+	ch, err := NewWebhookChannel(cfg, msgBus)
+	if err != nil {
+		t.Fatalf("NewWebhookChannel() error = %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ch.Stop(context.Background()) })
+	return ch, msgBus
+}
+
+func TestNewWebhookChannelRequiresAuth(t *testing.T) {
+	if _, err := NewWebhookChannel(config.WebhookConfig{Enabled: true}, bus.NewMessageBus()); err == nil {
+		t.Fatal("expected error without auth_token or secret")
+	}
+}
+
+func TestServeHTTPSignedPayload(t *testing.T) {
+	ch, msgBus := newTestChannel(t, config.WebhookConfig{
+		Secret: "s3cret",
+		Mapping: config.WebhookMappingConfig{
+			Content:  "Alert {{ $.alerts[0].labels.alertname }} is {{ $.status }}",
+			SenderID: "{{ $.receiver }}",
+			ChatID:   "{{ $.groupKey }}",
+		},
+	})
+	body := []byte(`{"status":"firing","receiver":"grafana","groupKey":"cpu","alerts":[{"labels":{"alertname":"HighCPU"}}]}`)
+
+	// Missing and wrong signatures are rejected.
+	for _, sig := range []string{"", "sha256=" + hex.EncodeToString(sign("wrong", body))} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/generic", bytes.NewReader(body))
+		if sig != "" {
+			req.Header.Set("X-Hub-Signature-256", sig)
+		}
+		rec := httptest.NewRecorder()
+		ch.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("signature %q: status = %d, want 401", sig, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/generic", bytes.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(sign("s3cret", body)))
+	rec := httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.Content != "Alert HighCPU is firing" || msg.Sender.PlatformID != "grafana" || msg.ChatID != "cpu" {
+		t.Errorf("inbound = %+v", msg)
+	}
+	if msg.Channel != "webhook" || msg.Peer.Kind != "group" || msg.Peer.ID != "cpu" {
+		t.Errorf("inbound channel/peer = %q %+v", msg.Channel, msg.Peer)
+	}
+}
+
+func TestServeHTTPBearerTokenAndRawContent(t *testing.T) {
+	ch, msgBus := newTestChannel(t, config.WebhookConfig{AuthToken: "tok", AllowFrom: []string{"webhook"}})
+	body := `{"event":"door_opened"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/generic", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer nope")
+	rec := httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want 401", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/webhook/generic", strings.NewReader("not json"))
+	req.Header.Set("Authorization", "Bearer tok")
+	rec = httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid JSON: status = %d, want 400", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/webhook/generic", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer tok")
+	rec = httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.Code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.Content != body || msg.Sender.PlatformID != "webhook" || msg.ChatID != "default" {
+		t.Errorf("inbound = %+v", msg)
+	}
+}
+
+func TestSendSignsCallback(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	deliveries := make(chan delivery, 1)
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{r.Header.Clone(), body}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	ch, _ := newTestChannel(t, config.WebhookConfig{
+		AuthToken:       "tok",
+		CallbackURL:     srv.URL,
+		CallbackSecret:  "cb-secret",
+		CallbackHeaders: map[string]string{"X-Env": "test"},
+	})
+
+	err := ch.Send(context.Background(), bus.OutboundMessage{Channel: "webhook", ChatID: "cpu", Content: "on it"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	d := <-deliveries
+	if got, want := d.header.Get("X-Picoclaw-Signature"), "sha256="+hex.EncodeToString(sign("cb-secret", d.body)); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if d.header.Get("X-Env") != "test" {
+		t.Errorf("custom header missing: %v", d.header)
+	}
+	var payload callbackPayload
+	if err := json.Unmarshal(d.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ChatID != "cpu" || payload.Content != "on it" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	status.Store(http.StatusBadGateway)
+	err = ch.Send(context.Background(), bus.OutboundMessage{Channel: "webhook", ChatID: "cpu", Content: "again"})
+	<-deliveries
+	if !errors.Is(err, channels.ErrTemporary) {
+		t.Errorf("Send() on 502 error = %v, want ErrTemporary", err)
+	}
+}
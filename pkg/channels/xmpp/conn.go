@@ -0,0 +1,609 @@
+package xmpp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// XML namespaces used by the channel.
+const (
+	nsClient     = "jabber:client"
+	nsStream     = "http://etherx.jabber.org/streams"
+	nsTLS        = "urn:ietf:params:xml:ns:xmpp-tls"
+	nsSASL       = "urn:ietf:params:xml:ns:xmpp-sasl"
+	nsBind       = "urn:ietf:params:xml:ns:xmpp-bind"
+	nsSession    = "urn:ietf:params:xml:ns:xmpp-session"
+	nsStanzas    = "urn:ietf:params:xml:ns:xmpp-stanzas"
+	nsChatStates = "http://jabber.org/protocol/chatstates"
+	nsUpload     = "urn:xmpp:http:upload:0"
+	nsPing       = "urn:xmpp:ping"
+	nsDiscoInfo  = "http://jabber.org/protocol/disco#info"
+	nsDiscoItems = "http://jabber.org/protocol/disco#items"
+)
+
+const (
+	negotiateTimeout  = 30 * time.Second
+	writeTimeout      = 10 * time.Second
+	keepAliveInterval = 60 * time.Second
+)
+
+// Stanzas. Only the parts the channel uses are modelled.
+
+type stanzaMessage struct {
+	XMLName xml.Name     `xml:"jabber:client message"`
+	From    string       `xml:"from,attr,omitempty"`
+	To      string       `xml:"to,attr,omitempty"`
+	ID      string       `xml:"id,attr,omitempty"`
+	Type    string       `xml:"type,attr,omitempty"`
+	Body    string       `xml:"body,omitempty"`
+	Subject string       `xml:"subject,omitempty"`
+	Replace *replaceElem `xml:"urn:xmpp:message-correct:0 replace"`
+	OOB     *oobElem     `xml:"jabber:x:oob x"`
+	Delay   *struct{}    `xml:"urn:xmpp:delay delay"`
+	// ChatState is an XEP-0085 element such as <composing/>; outbound only.
+	ChatState *chatStateElem
+}
+
+type replaceElem struct {
+	ID string `xml:"id,attr"`
+}
+
+type oobElem struct {
+	URL string `xml:"url"`
+}
+
+type chatStateElem struct {
+	XMLName xml.Name
+}
+
+func chatState(name string) *chatStateElem {
+	return &chatStateElem{XMLName: xml.Name{Space: nsChatStates, Local: name}}
+}
+
+type stanzaPresence struct {
+	XMLName xml.Name     `xml:"jabber:client presence"`
+	From    string       `xml:"from,attr,omitempty"`
+	To      string       `xml:"to,attr,omitempty"`
+	Type    string       `xml:"type,attr,omitempty"`
+	MUC     *mucJoin     `xml:"http://jabber.org/protocol/muc x"`
+	Error   *stanzaError `xml:"error"`
+}
+
+type mucJoin struct {
+	History *mucHistory `xml:"history"`
+}
+
+type mucHistory struct {
+	MaxStanzas int `xml:"maxstanzas,attr"`
+}
+
+type stanzaIQ struct {
+	XMLName xml.Name     `xml:"jabber:client iq"`
+	From    string       `xml:"from,attr,omitempty"`
+	To      string       `xml:"to,attr,omitempty"`
+	ID      string       `xml:"id,attr"`
+	Type    string       `xml:"type,attr"`
+	Payload []byte       `xml:",innerxml"`
+	Error   *stanzaError `xml:"error"`
+}
+
+// payloadName returns the name of the IQ's child element.
+func (iq stanzaIQ) payloadName() xml.Name {
+	dec := xml.NewDecoder(strings.NewReader(string(iq.Payload)))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.Name{}
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name
+		}
+	}
+}
+
+type stanzaError struct {
+	Type      string `xml:"type,attr"`
+	Condition struct {
+		XMLName xml.Name
+	} `xml:",any"`
+	Text string `xml:"text"`
+}
+
+func (e *stanzaError) Error() string {
+	msg := e.Condition.XMLName.Local
+	if msg == "" {
+		msg = e.Type
+	}
+	if e.Text != "" {
+		msg += ": " + e.Text
+	}
+	return "xmpp: " + msg
+}
+
+type streamFeatures struct {
+	StartTLS   *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-tls starttls"`
+	Mechanisms *struct {
+		Mechanism []string `xml:"mechanism"`
+	} `xml:"urn:ietf:params:xml:ns:xmpp-sasl mechanisms"`
+	Bind    *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-bind bind"`
+	Session *struct {
+		Optional *struct{} `xml:"optional"`
+	} `xml:"urn:ietf:params:xml:ns:xmpp-session session"`
+}
+
+// jid is a parsed Jabber ID: local@domain/resource.
+type jid struct {
+	Local, Domain, Resource string
+}
+
+func parseJID(s string) (jid, error) {
+	var j jid
+	rest, resource, _ := strings.Cut(s, "/")
+	j.Resource = resource
+	if local, domain, ok := strings.Cut(rest, "@"); ok {
+		j.Local, j.Domain = local, domain
+	} else {
+		j.Domain = rest
+	}
+	if j.Domain == "" {
+		return j, fmt.Errorf("invalid JID %q", s)
+	}
+	return j, nil
+}
+
+// Bare returns local@domain, lowercased for comparisons.
+func (j jid) Bare() string {
+	if j.Local == "" {
+		return strings.ToLower(j.Domain)
+	}
+	return strings.ToLower(j.Local + "@" + j.Domain)
+}
+
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// connOptions configures dialXMPP.
+type connOptions struct {
+	jid       jid
+	password  string
+	server    string // host:port; empty means SRV lookup, then domain:5222
+	directTLS bool   // XEP-0368 direct TLS instead of STARTTLS
+	tlsConfig *tls.Config
+}
+
+type stanzaHandlers struct {
+	message  func(stanzaMessage)
+	presence func(stanzaPresence)
+}
+
+// conn is an authenticated, resource-bound XMPP client stream.
+type conn struct {
+	raw      net.Conn
+	dec      *xml.Decoder
+	handlers stanzaHandlers
+	jid      string // full JID bound by the server
+
+	writeMu sync.Mutex
+
+	pendingMu sync.Mutex
+	pending   map[string]chan stanzaIQ
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// dialXMPP connects, secures and authenticates the stream, binds a resource
+// and starts reading stanzas into handlers.
+func dialXMPP(ctx context.Context, opts connOptions, handlers stanzaHandlers) (*conn, error) {
+	addr, err := resolveServer(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: negotiateTimeout, KeepAlive: 30 * time.Second}
+	raw, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := opts.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = opts.jid.Domain
+	}
+
+	c := &conn{
+		raw:      raw,
+		handlers: handlers,
+		pending:  make(map[string]chan stanzaIQ),
+		done:     make(chan struct{}),
+	}
+	_ = raw.SetDeadline(time.Now().Add(negotiateTimeout))
+	if opts.directTLS {
+		c.raw = tls.Client(raw, tlsConfig)
+	}
+	if err := c.negotiate(opts, tlsConfig); err != nil {
+		c.raw.Close()
+		return nil, err
+	}
+	_ = c.raw.SetDeadline(time.Time{})
+
+	go c.readLoop()
+	go c.keepAlive()
+	return c, nil
+}
+
+func resolveServer(ctx context.Context, opts connOptions) (string, error) {
+	if opts.server != "" {
+		if _, _, err := net.SplitHostPort(opts.server); err != nil {
+			port := "5222"
+			if opts.directTLS {
+				port = "5223"
+			}
+			return net.JoinHostPort(opts.server, port), nil
+		}
+		return opts.server, nil
+	}
+	service := "xmpp-client"
+	if opts.directTLS {
+		service = "xmpps-client"
+	}
+	if _, addrs, err := net.DefaultResolver.LookupSRV(ctx, service, "tcp", opts.jid.Domain); err == nil && len(addrs) > 0 {
+		target := strings.TrimSuffix(addrs[0].Target, ".")
+		return net.JoinHostPort(target, strconv.Itoa(int(addrs[0].Port))), nil
+	}
+	if opts.directTLS {
+		return net.JoinHostPort(opts.jid.Domain, "5223"), nil
+	}
+	return net.JoinHostPort(opts.jid.Domain, "5222"), nil
+}
+
+func (c *conn) negotiate(opts connOptions, tlsConfig *tls.Config) error {
+	features, err := c.openStream(opts.jid.Domain)
+	if err != nil {
+		return err
+	}
+
+	if _, secure := c.raw.(*tls.Conn); !secure {
+		if features.StartTLS == nil {
+			return errors.New("xmpp: server does not offer STARTTLS")
+		}
+		if err := c.writeRaw(fmt.Sprintf("<starttls xmlns='%s'/>", nsTLS)); err != nil {
+			return err
+		}
+		start, err := c.nextElement()
+		if err != nil {
+			return err
+		}
+		if start.Name.Local != "proceed" {
+			return errors.New("xmpp: STARTTLS refused")
+		}
+		tlsConn := tls.Client(c.raw, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("xmpp: TLS handshake: %w", err)
+		}
+		c.raw = tlsConn
+		if features, err = c.openStream(opts.jid.Domain); err != nil {
+			return err
+		}
+	}
+
+	if features.Mechanisms == nil || !containsFold(features.Mechanisms.Mechanism, "PLAIN") {
+		return errors.New("xmpp: server does not offer SASL PLAIN")
+	}
+	creds := base64.StdEncoding.EncodeToString([]byte("\x00" + opts.jid.Local + "\x00" + opts.password))
+	if err := c.writeRaw(fmt.Sprintf("<auth xmlns='%s' mechanism='PLAIN'>%s</auth>", nsSASL, creds)); err != nil {
+		return err
+	}
+	start, err := c.nextElement()
+	if err != nil {
+		return err
+	}
+	if start.Name.Local != "success" {
+		var failure struct {
+			Condition struct{ XMLName xml.Name } `xml:",any"`
+		}
+		_ = c.dec.DecodeElement(&failure, &start)
+		return fmt.Errorf("xmpp: authentication failed: %s", failure.Condition.XMLName.Local)
+	}
+	_ = c.dec.Skip()
+
+	if features, err = c.openStream(opts.jid.Domain); err != nil {
+		return err
+	}
+	if features.Bind == nil {
+		return errors.New("xmpp: server does not offer resource binding")
+	}
+	bind := fmt.Sprintf("<bind xmlns='%s'><resource>%s</resource></bind>", nsBind, xmlEscape(opts.jid.Resource))
+	result, err := c.negotiateIQ("set", bind)
+	if err != nil {
+		return fmt.Errorf("xmpp: bind: %w", err)
+	}
+	var bound struct {
+		JID string `xml:"jid"`
+	}
+	if err := xml.Unmarshal(result.Payload, &bound); err != nil || bound.JID == "" {
+		return errors.New("xmpp: bind returned no JID")
+	}
+	c.jid = bound.JID
+
+	// RFC 3921 servers still require a session; RFC 6121 ones mark it optional.
+	if features.Session != nil && features.Session.Optional == nil {
+		if _, err := c.negotiateIQ("set", fmt.Sprintf("<session xmlns='%s'/>", nsSession)); err != nil {
+			return fmt.Errorf("xmpp: session: %w", err)
+		}
+	}
+	return nil
+}
+
+// openStream sends a stream header and reads the server's features.
+func (c *conn) openStream(domain string) (streamFeatures, error) {
+	var features streamFeatures
+	header := fmt.Sprintf("<?xml version='1.0'?><stream:stream to='%s' xmlns='%s' xmlns:stream='%s' version='1.0'>",
+		xmlEscape(domain), nsClient, nsStream)
+	if err := c.writeRaw(header); err != nil {
+		return features, err
+	}
+	c.dec = xml.NewDecoder(c.raw)
+	for {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return features, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Space != nsStream || start.Name.Local != "stream" {
+				return features, fmt.Errorf("xmpp: unexpected <%s> instead of stream header", start.Name.Local)
+			}
+			break
+		}
+	}
+	start, err := c.nextElement()
+	if err != nil {
+		return features, err
+	}
+	if start.Name.Local != "features" {
+		return features, fmt.Errorf("xmpp: unexpected <%s> instead of stream features", start.Name.Local)
+	}
+	err = c.dec.DecodeElement(&features, &start)
+	return features, err
+}
+
+// negotiateIQ sends an IQ during stream setup, before the read loop runs.
+func (c *conn) negotiateIQ(typ, payload string) (stanzaIQ, error) {
+	iq := stanzaIQ{ID: newID(), Type: typ, Payload: []byte(payload)}
+	if err := c.send(iq); err != nil {
+		return iq, err
+	}
+	for {
+		start, err := c.nextElement()
+		if err != nil {
+			return iq, err
+		}
+		if start.Name.Local != "iq" {
+			_ = c.dec.Skip()
+			continue
+		}
+		var result stanzaIQ
+		if err := c.dec.DecodeElement(&result, &start); err != nil {
+			return result, err
+		}
+		if result.ID != iq.ID {
+			continue
+		}
+		if result.Type == "error" && result.Error != nil {
+			return result, result.Error
+		}
+		return result, nil
+	}
+}
+
+// nextElement returns the next top-level element of the stream.
+func (c *conn) nextElement() (xml.StartElement, error) {
+	for {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == nsStream && t.Name.Local == "error" {
+				var streamErr struct {
+					Condition struct{ XMLName xml.Name } `xml:",any"`
+					Text      string                     `xml:"text"`
+				}
+				_ = c.dec.DecodeElement(&streamErr, &t)
+				return t, fmt.Errorf("xmpp: stream error: %s %s", streamErr.Condition.XMLName.Local, streamErr.Text)
+			}
+			return t, nil
+		case xml.EndElement:
+			return xml.StartElement{}, io.EOF
+		}
+	}
+}
+
+func (c *conn) readLoop() {
+	var err error
+	defer func() { c.shutdown(err) }()
+	for {
+		var start xml.StartElement
+		start, err = c.nextElement()
+		if err != nil {
+			return
+		}
+		switch start.Name.Local {
+		case "message":
+			var m stanzaMessage
+			if err = c.dec.DecodeElement(&m, &start); err != nil {
+				return
+			}
+			if c.handlers.message != nil {
+				c.handlers.message(m)
+			}
+		case "presence":
+			var p stanzaPresence
+			if err = c.dec.DecodeElement(&p, &start); err != nil {
+				return
+			}
+			if c.handlers.presence != nil {
+				c.handlers.presence(p)
+			}
+		case "iq":
+			var iq stanzaIQ
+			if err = c.dec.DecodeElement(&iq, &start); err != nil {
+				return
+			}
+			c.handleIQ(iq)
+		default:
+			if err = c.dec.Skip(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *conn) handleIQ(iq stanzaIQ) {
+	switch iq.Type {
+	case "result", "error":
+		c.pendingMu.Lock()
+		ch, ok := c.pending[iq.ID]
+		delete(c.pending, iq.ID)
+		c.pendingMu.Unlock()
+		if ok {
+			ch <- iq
+		}
+	case "get", "set":
+		reply := stanzaIQ{To: iq.From, ID: iq.ID, Type: "result"}
+		if name := iq.payloadName(); name.Space != nsPing || name.Local != "ping" {
+			reply.Type = "error"
+			reply.Payload = []byte(fmt.Sprintf("<error type='cancel'><service-unavailable xmlns='%s'/></error>", nsStanzas))
+		}
+		_ = c.send(reply)
+	}
+}
+
+// IQ sends a request and waits for its result.
+func (c *conn) IQ(ctx context.Context, iq stanzaIQ) (stanzaIQ, error) {
+	iq.ID = newID()
+	ch := make(chan stanzaIQ, 1)
+	c.pendingMu.Lock()
+	c.pending[iq.ID] = ch
+	c.pendingMu.Unlock()
+	defer func() {
+		c.pendingMu.Lock()
+		delete(c.pending, iq.ID)
+		c.pendingMu.Unlock()
+	}()
+
+	if err := c.send(iq); err != nil {
+		return stanzaIQ{}, err
+	}
+	select {
+	case result := <-ch:
+		if result.Type == "error" {
+			if result.Error != nil {
+				return result, result.Error
+			}
+			return result, errors.New("xmpp: iq error")
+		}
+		return result, nil
+	case <-c.done:
+		return stanzaIQ{}, c.Err()
+	case <-ctx.Done():
+		return stanzaIQ{}, ctx.Err()
+	}
+}
+
+// send marshals and writes a stanza.
+func (c *conn) send(v any) error {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeRaw(string(data))
+}
+
+func (c *conn) writeRaw(s string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.raw.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := io.WriteString(c.raw, s)
+	return err
+}
+
+// keepAlive sends whitespace pings so idle NATs and servers keep the stream.
+func (c *conn) keepAlive() {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.writeRaw(" "); err != nil {
+				c.shutdown(err)
+				return
+			}
+		}
+	}
+}
+
+// Close ends the stream.
+func (c *conn) Close() error {
+	_ = c.writeRaw("</stream:stream>")
+	c.shutdown(errors.New("xmpp: connection closed"))
+	return nil
+}
+
+func (c *conn) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		c.raw.Close()
+		close(c.done)
+	})
+}
+
+// Done is closed when the stream ends.
+func (c *conn) Done() <-chan struct{} { return c.done }
+
+// Err returns why the stream ended.
+func (c *conn) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,713 @@
+package xmpp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/identity"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+	iqTimeout         = 30 * time.Second
+	uploadTimeout     = 5 * time.Minute
+	downloadTimeout   = time.Minute
+	// maxAttachmentSize caps inbound files fetched from XEP-0066 links.
+	maxAttachmentSize = 20 << 20
+)
+
+// XMPPChannel connects to an XMPP server as a regular client account. Direct
+// chats use the sender's bare JID as chat ID; multi-user chat rooms use the
+// room's bare JID, and private messages from a room occupant use the
+// occupant's full JID (room@service/nick).
+type XMPPChannel struct {
+	*channels.BaseChannel
+	config     config.XMPPConfig
+	jid        jid
+	nick       string
+	rooms      map[string]bool // bare room JIDs, lowercased
+	tlsConfig  *tls.Config
+	httpClient *http.Client
+	// maxDownload caps the size of a downloaded attachment.
+	maxDownload int64
+	// mentionPattern matches the room nick as a whole word.
+	mentionPattern *regexp.Regexp
+
+	mu            sync.RWMutex
+	conn          *conn
+	uploadService string // discovered XEP-0363 component, cached per channel
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewXMPPChannel creates an XMPP channel.
+func NewXMPPChannel(cfg config.XMPPConfig, messageBus *bus.MessageBus) (*XMPPChannel, error) {
+	if cfg.JID == "" || cfg.Password == "" {
+		return nil, fmt.Errorf("xmpp jid and password are required")
+	}
+	j, err := parseJID(cfg.JID)
+	if err != nil || j.Local == "" {
+		return nil, fmt.Errorf("invalid xmpp jid %q", cfg.JID)
+	}
+	if cfg.Resource != "" {
+		j.Resource = cfg.Resource
+	} else if j.Resource == "" {
+		j.Resource = "picoclaw"
+	}
+	nick := cfg.Nick
+	if nick == "" {
+		nick = j.Local
+	}
+
+	rooms := make(map[string]bool, len(cfg.Rooms))
+	for _, room := range cfg.Rooms {
+		r, err := parseJID(room)
+		if err != nil || r.Local == "" {
+			return nil, fmt.Errorf("invalid xmpp room %q", room)
+		}
+		rooms[r.Bare()] = true
+	}
+
+	base := channels.NewBaseChannel("xmpp", cfg, messageBus, cfg.AllowFrom,
+		channels.WithMaxMessageLength(10000),
+		channels.WithGroupTrigger(cfg.GroupTrigger),
+		channels.WithReasoningChannelID(cfg.ReasoningChannelID),
+	)
+
+	return &XMPPChannel{
+		BaseChannel:    base,
+		config:         cfg,
+		jid:            j,
+		nick:           nick,
+		rooms:          rooms,
+		httpClient:     &http.Client{Timeout: uploadTimeout},
+		maxDownload:    maxAttachmentSize,
+		mentionPattern: regexp.MustCompile(`(?i)(^|[^\pL\pN_])` + regexp.QuoteMeta(nick) + `($|[^\pL\pN_])`),
+		uploadService:  cfg.UploadService,
+	}, nil
+}
+
+// Start connects in the background and keeps reconnecting until the channel
+// is stopped.
+func (c *XMPPChannel) Start(ctx context.Context) error {
+	logger.InfoCF("xmpp", "Starting XMPP channel", map[string]any{
+		"jid":   c.jid.Bare(),
+		"rooms": len(c.rooms),
+	})
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	c.SetRunning(true)
+	go c.run()
+	return nil
+}
+
+// Stop leaves the rooms and closes the stream.
+func (c *XMPPChannel) Stop(ctx context.Context) error {
+	logger.InfoC("xmpp", "Stopping XMPP channel")
+	c.SetRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+	}
+	return nil
+}
+
+func (c *XMPPChannel) run() {
+	defer close(c.done)
+	delay := minReconnectDelay
+	for {
+		connectedAt := time.Now()
+		err := c.session()
+		if c.ctx.Err() != nil {
+			return
+		}
+		if time.Since(connectedAt) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		logger.WarnCF("xmpp", "XMPP connection lost, reconnecting", map[string]any{
+			"error": err.Error(),
+			"delay": delay.String(),
+		})
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// session connects, announces presence, joins the rooms and blocks until the
+// stream ends.
+func (c *XMPPChannel) session() error {
+	cl, err := dialXMPP(c.ctx, connOptions{
+		jid:       c.jid,
+		password:  c.config.Password,
+		server:    c.config.Server,
+		directTLS: c.config.DirectTLS,
+		tlsConfig: c.tlsConfig,
+	}, stanzaHandlers{
+		message:  c.handleMessage,
+		presence: c.handlePresence,
+	})
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	c.mu.Lock()
+	c.conn = cl
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		if c.conn == cl {
+			c.conn = nil
+		}
+		c.mu.Unlock()
+	}()
+
+	if err := cl.send(stanzaPresence{}); err != nil {
+		return err
+	}
+	for room := range c.rooms {
+		// Ask for no history: old messages would otherwise arrive as new ones.
+		join := stanzaPresence{
+			To:  room + "/" + c.nick,
+			MUC: &mucJoin{History: &mucHistory{MaxStanzas: 0}},
+		}
+		if err := cl.send(join); err != nil {
+			return err
+		}
+	}
+
+	logger.InfoCF("xmpp", "Connected to XMPP server", map[string]any{"jid": cl.jid})
+
+	select {
+	case <-cl.Done():
+	case <-c.ctx.Done():
+	}
+	return cl.Err()
+}
+
+func (c *XMPPChannel) handlePresence(p stanzaPresence) {
+	if p.Type != "error" || p.Error == nil {
+		return
+	}
+	from, _ := parseJID(p.From)
+	if c.rooms[from.Bare()] {
+		logger.WarnCF("xmpp", "Failed to join room", map[string]any{
+			"room":  from.Bare(),
+			"error": p.Error.Error(),
+		})
+	}
+}
+
+func (c *XMPPChannel) handleMessage(m stanzaMessage) {
+	// Skip errors, corrections of earlier messages and delayed (history or
+	// offline) messages.
+	if m.Type == "error" || m.Replace != nil || m.Delay != nil {
+		return
+	}
+	from, err := parseJID(m.From)
+	if err != nil {
+		return
+	}
+	content := strings.TrimSpace(m.Body)
+	var attachmentURL string
+	if m.OOB != nil && m.OOB.URL != "" {
+		attachmentURL = m.OOB.URL
+		if content == attachmentURL {
+			content = ""
+		}
+	}
+	if content == "" && attachmentURL == "" {
+		return
+	}
+
+	bare := from.Bare()
+	isRoom := c.rooms[bare]
+	var (
+		chatID, senderID string
+		peer             bus.Peer
+		sender           bus.SenderInfo
+	)
+	switch {
+	case m.Type == "groupchat":
+		// Our own messages are reflected back by the room.
+		if !isRoom || from.Resource == "" || from.Resource == c.nick {
+			return
+		}
+		chatID, senderID = bare, bare+"/"+from.Resource
+		peer = bus.Peer{Kind: "group", ID: chatID}
+		sender = bus.SenderInfo{Username: from.Resource, DisplayName: from.Resource}
+	case isRoom:
+		// Private message from a room occupant.
+		if from.Resource == "" {
+			return
+		}
+		chatID, senderID = bare+"/"+from.Resource, bare+"/"+from.Resource
+		peer = bus.Peer{Kind: "direct", ID: chatID}
+		sender = bus.SenderInfo{Username: from.Resource, DisplayName: from.Resource}
+	default:
+		if bare == c.jid.Bare() {
+			return
+		}
+		chatID, senderID = bare, bare
+		peer = bus.Peer{Kind: "direct", ID: bare}
+		sender = bus.SenderInfo{Username: from.Local}
+	}
+	sender.Platform = "xmpp"
+	sender.PlatformID = senderID
+	sender.CanonicalID = identity.BuildCanonicalID("xmpp", senderID)
+
+	if !c.IsAllowedSender(sender) {
+		logger.DebugCF("xmpp", "Message rejected by allowlist", map[string]any{
+			"sender_id": senderID,
+		})
+		return
+	}
+
+	mentioned := false
+	if m.Type == "groupchat" {
+		content, mentioned = c.stripNickMention(content)
+		respond, cleaned := c.ShouldRespondInGroup(mentioned, content)
+		if !respond {
+			return
+		}
+		content = cleaned
+	}
+
+	var mediaPaths []string
+	if attachmentURL != "" {
+		if ref, name := c.downloadAttachment(attachmentURL, chatID, m.ID); ref != "" {
+			mediaPaths = append(mediaPaths, ref)
+			content = strings.TrimSpace(content + fmt.Sprintf("\n[file: %s]", name))
+		}
+	}
+	if content == "" {
+		return
+	}
+
+	metadata := map[string]string{
+		"platform": "xmpp",
+		"from":     m.From,
+		"type":     m.Type,
+	}
+	if mentioned {
+		metadata["is_mention"] = "true"
+	}
+
+	logger.DebugCF("xmpp", "Received message", map[string]any{
+		"sender_id": senderID,
+		"chat_id":   chatID,
+		"preview":   utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(c.ctx, peer, m.ID, senderID, chatID, content, mediaPaths, metadata, sender)
+}
+
+// stripNickMention reports whether the room nick is mentioned and removes a
+// leading "nick:" or "nick," address.
+func (c *XMPPChannel) stripNickMention(content string) (string, bool) {
+	if !c.mentionPattern.MatchString(content) {
+		return content, false
+	}
+	n := len(c.nick)
+	if len(content) > n && strings.EqualFold(content[:n], c.nick) && (content[n] == ':' || content[n] == ',') {
+		return strings.TrimSpace(content[n+1:]), true
+	}
+	return content, true
+}
+
+// downloadAttachment fetches an XEP-0066 link into the media store. Links are
+// only followed when fetch_attachments is set, and only to the account's
+// domain, its subdomains or the upload service, so a message cannot make the
+// bot request arbitrary (e.g. internal) URLs.
+func (c *XMPPChannel) downloadAttachment(rawURL, chatID, messageID string) (ref, name string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", ""
+	}
+	if !c.config.FetchAttachments || !c.downloadAllowed(u) {
+		logger.DebugCF("xmpp", "Not downloading attachment", map[string]any{
+			"url":     rawURL,
+			"enabled": c.config.FetchAttachments,
+		})
+		return "", ""
+	}
+	name = path.Base(u.Path)
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	localPath, err := c.download(u, name)
+	if err != nil {
+		logger.WarnCF("xmpp", "Failed to download attachment", map[string]any{
+			"url":   rawURL,
+			"error": err.Error(),
+		})
+		return "", ""
+	}
+	if store := c.GetMediaStore(); store != nil {
+		scope := channels.BuildMediaScope("xmpp", chatID, messageID)
+		ref, err := store.Store(localPath, media.MediaMeta{Filename: name, Source: "xmpp"}, scope)
+		if err == nil {
+			return ref, name
+		}
+	}
+	return localPath, name
+}
+
+// downloadAllowed reports whether u points at the account's domain, one of
+// its subdomains or the upload service.
+func (c *XMPPChannel) downloadAllowed(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	c.mu.RLock()
+	service := c.uploadService
+	c.mu.RUnlock()
+	for _, domain := range []string{c.jid.Domain, service} {
+		domain = strings.ToLower(domain)
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// download saves u to the media temp directory, refusing redirects to other
+// hosts and bodies larger than c.maxDownload.
+func (c *XMPPChannel) download(u *url.URL, name string) (string, error) {
+	client := &http.Client{
+		Timeout: downloadTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 || !c.downloadAllowed(req.URL) {
+				return fmt.Errorf("redirect to %s not allowed", req.URL.Host)
+			}
+			return nil
+		},
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > c.maxDownload {
+		return "", fmt.Errorf("file of %d bytes exceeds the %d byte limit", resp.ContentLength, c.maxDownload)
+	}
+
+	dir := filepath.Join(os.TempDir(), "picoclaw_media")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	out, err := os.CreateTemp(dir, "xmpp-*_"+utils.SanitizeFilename(name))
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(out, io.LimitReader(resp.Body, c.maxDownload+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > c.maxDownload {
+		err = fmt.Errorf("file exceeds the %d byte limit", c.maxDownload)
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+func (c *XMPPChannel) currentConn() (*conn, error) {
+	if !c.IsRunning() {
+		return nil, channels.ErrNotRunning
+	}
+	c.mu.RLock()
+	cl := c.conn
+	c.mu.RUnlock()
+	if cl == nil {
+		return nil, fmt.Errorf("%w: not connected to XMPP server", channels.ErrTemporary)
+	}
+	return cl, nil
+}
+
+// message builds an outbound message of the right type for chatID.
+func (c *XMPPChannel) message(chatID string) stanzaMessage {
+	msgType := "chat"
+	if to, err := parseJID(chatID); err == nil && to.Resource == "" && c.rooms[to.Bare()] {
+		msgType = "groupchat"
+	}
+	return stanzaMessage{To: chatID, ID: newID(), Type: msgType}
+}
+
+func (c *XMPPChannel) sendStanza(m stanzaMessage) error {
+	cl, err := c.currentConn()
+	if err != nil {
+		return err
+	}
+	if err := cl.send(m); err != nil {
+		return channels.ClassifyNetError(err)
+	}
+	return nil
+}
+
+// Send delivers a message; it also resets the chat state to active.
+func (c *XMPPChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	_, err := c.sendText(msg.ChatID, msg.Content)
+	return err
+}
+
+func (c *XMPPChannel) sendText(chatID, text string) (string, error) {
+	m := c.message(chatID)
+	m.Body = text
+	m.ChatState = chatState("active")
+	return m.ID, c.sendStanza(m)
+}
+
+// EditMessage implements channels.MessageEditor with XEP-0308 Last Message
+// Correction. Clients without support show the correction as a new message.
+func (c *XMPPChannel) EditMessage(ctx context.Context, chatID, messageID, content string) error {
+	m := c.message(chatID)
+	m.Body = content
+	m.Replace = &replaceElem{ID: messageID}
+	return c.sendStanza(m)
+}
+
+// SendPlaceholder implements channels.PlaceholderCapable.
+func (c *XMPPChannel) SendPlaceholder(ctx context.Context, chatID string) (string, error) {
+	if !c.config.Placeholder.Enabled {
+		return "", nil
+	}
+	text := c.config.Placeholder.Text
+	if text == "" {
+		text = "Thinking... 💭"
+	}
+	return c.sendText(chatID, text)
+}
+
+// StartTyping implements channels.TypingCapable with XEP-0085 chat states:
+// <composing/> now, <active/> when stopped.
+func (c *XMPPChannel) StartTyping(ctx context.Context, chatID string) (func(), error) {
+	if !c.config.Typing.Enabled {
+		return func() {}, nil
+	}
+	m := c.message(chatID)
+	m.ChatState = chatState("composing")
+	if err := c.sendStanza(m); err != nil {
+		return func() {}, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m := c.message(chatID)
+			m.ChatState = chatState("active")
+			_ = c.sendStanza(m)
+		})
+	}, nil
+}
+
+// SendMedia implements channels.MediaSender with XEP-0363 HTTP File Upload:
+// each file is uploaded to a slot and its URL sent with an XEP-0066 out of
+// band reference, which clients display inline.
+func (c *XMPPChannel) SendMedia(ctx context.Context, msg bus.OutboundMediaMessage) error {
+	cl, err := c.currentConn()
+	if err != nil {
+		return err
+	}
+	store := c.GetMediaStore()
+	if store == nil {
+		return fmt.Errorf("no media store available: %w", channels.ErrSendFailed)
+	}
+
+	for _, part := range msg.Parts {
+		localPath, err := store.Resolve(part.Ref)
+		if err != nil {
+			logger.ErrorCF("xmpp", "Failed to resolve media ref", map[string]any{
+				"ref":   part.Ref,
+				"error": err.Error(),
+			})
+			continue
+		}
+		filename := part.Filename
+		if filename == "" {
+			filename = filepath.Base(localPath)
+		}
+		contentType := part.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(filename))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		getURL, err := c.upload(ctx, cl, localPath, filename, contentType)
+		if err != nil {
+			return err
+		}
+		m := c.message(msg.ChatID)
+		m.Body = getURL
+		m.OOB = &oobElem{URL: getURL}
+		if err := c.sendStanza(m); err != nil {
+			return err
+		}
+		if part.Caption != "" {
+			if _, err := c.sendText(msg.ChatID, part.Caption); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type uploadSlot struct {
+	Put struct {
+		URL     string `xml:"url,attr"`
+		Headers []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"header"`
+	} `xml:"put"`
+	Get struct {
+		URL string `xml:"url,attr"`
+	} `xml:"get"`
+}
+
+func (c *XMPPChannel) upload(ctx context.Context, cl *conn, localPath, filename, contentType string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", localPath, channels.ErrSendFailed)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("stat %s: %w", localPath, channels.ErrSendFailed)
+	}
+
+	service, err := c.findUploadService(ctx, cl)
+	if err != nil {
+		return "", err
+	}
+	iqCtx, cancel := context.WithTimeout(ctx, iqTimeout)
+	defer cancel()
+	request := fmt.Sprintf("<request xmlns='%s' filename='%s' size='%d' content-type='%s'/>",
+		nsUpload, xmlEscape(filename), info.Size(), xmlEscape(contentType))
+	result, err := cl.IQ(iqCtx, stanzaIQ{To: service, Type: "get", Payload: []byte(request)})
+	if err != nil {
+		return "", fmt.Errorf("xmpp upload slot: %v: %w", err, channels.ErrSendFailed)
+	}
+	var slot uploadSlot
+	if err := xml.Unmarshal(result.Payload, &slot); err != nil || slot.Put.URL == "" || slot.Get.URL == "" {
+		return "", fmt.Errorf("xmpp upload slot without URLs: %w", channels.ErrSendFailed)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, slot.Put.URL, f)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", contentType)
+	for _, h := range slot.Put.Headers {
+		// XEP-0363 only allows these headers to be passed through.
+		switch http.CanonicalHeaderKey(h.Name) {
+		case "Authorization", "Cookie", "Expires":
+			req.Header.Set(h.Name, strings.TrimSpace(h.Value))
+		}
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", channels.ClassifyNetError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", channels.ClassifySendError(resp.StatusCode,
+			fmt.Errorf("xmpp upload: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+	return slot.Get.URL, nil
+}
+
+type discoItems struct {
+	Items []struct {
+		JID string `xml:"jid,attr"`
+	} `xml:"item"`
+}
+
+type discoInfo struct {
+	Features []struct {
+		Var string `xml:"var,attr"`
+	} `xml:"feature"`
+}
+
+// findUploadService returns the configured upload component or discovers it
+// among the server's items.
+func (c *XMPPChannel) findUploadService(ctx context.Context, cl *conn) (string, error) {
+	c.mu.RLock()
+	service := c.uploadService
+	c.mu.RUnlock()
+	if service != "" {
+		return service, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, iqTimeout)
+	defer cancel()
+	candidates := []string{c.jid.Domain}
+	result, err := cl.IQ(ctx, stanzaIQ{
+		To:      c.jid.Domain,
+		Type:    "get",
+		Payload: []byte(fmt.Sprintf("<query xmlns='%s'/>", nsDiscoItems)),
+	})
+	if err == nil {
+		var items discoItems
+		if xml.Unmarshal(result.Payload, &items) == nil {
+			for _, item := range items.Items {
+				candidates = append(candidates, item.JID)
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		result, err := cl.IQ(ctx, stanzaIQ{
+			To:      candidate,
+			Type:    "get",
+			Payload: []byte(fmt.Sprintf("<query xmlns='%s'/>", nsDiscoInfo)),
+		})
+		if err != nil {
+			continue
+		}
+		var info discoInfo
+		if xml.Unmarshal(result.Payload, &info) != nil {
+			continue
+		}
+		for _, feature := range info.Features {
+			if feature.Var == nsUpload {
+				c.mu.Lock()
+				c.uploadService = candidate
+				c.mu.Unlock()
+				return candidate, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("xmpp server offers no HTTP File Upload service: %w", channels.ErrSendFailed)
+}
@@ -0,0 +1,477 @@
+package xmpp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/media"
+)
+
+const testRoom = "lounge@conference.example.com"
+
+// testServer is a minimal XMPP server: STARTTLS, SASL PLAIN, resource binding,
+// then it records every stanza the client sends and answers the disco and
+// HTTP upload queries.
+type testServer struct {
+	t         *testing.T
+	ln        net.Listener
+	tlsConfig *tls.Config
+	uploadURL string
+
+	mu   sync.Mutex
+	conn net.Conn
+
+	stanzas chan any
+}
+
+func newTestServer(t *testing.T, uploadURL string) (*testServer, *x509.CertPool) {
+	t.Helper()
+	// Borrow httptest's certificate, valid for example.com.
+	https := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := https.TLS.Certificates[0]
+	pool := x509.NewCertPool()
+	pool.AddCert(https.Certificate())
+	https.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{
+		t:         t,
+		ln:        ln,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		uploadURL: uploadURL,
+		stanzas:   make(chan any, 32),
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s, pool
+}
+
+func (s *testServer) write(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.conn, format, args...)
+}
+
+func (s *testServer) openStream(c net.Conn, features string) (*xml.Decoder, error) {
+	dec := xml.NewDecoder(c)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "stream" {
+			break
+		}
+	}
+	_, err := fmt.Fprintf(c, "<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='%s' from='example.com' version='1.0'><stream:features>%s</stream:features>",
+		nsStream, features)
+	return dec, err
+}
+
+func nextStart(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+func (s *testServer) serve(c net.Conn) {
+	defer c.Close()
+	dec, err := s.openStream(c, fmt.Sprintf("<starttls xmlns='%s'/>", nsTLS))
+	if err != nil {
+		return
+	}
+	if start, err := nextStart(dec); err != nil || start.Name.Local != "starttls" {
+		return
+	}
+	fmt.Fprintf(c, "<proceed xmlns='%s'/>", nsTLS)
+	tc := tls.Server(c, s.tlsConfig)
+	if err := tc.Handshake(); err != nil {
+		return
+	}
+
+	if dec, err = s.openStream(tc, fmt.Sprintf("<mechanisms xmlns='%s'><mechanism>PLAIN</mechanism></mechanisms>", nsSASL)); err != nil {
+		return
+	}
+	var auth struct {
+		Mechanism string `xml:"mechanism,attr"`
+		Data      string `xml:",chardata"`
+	}
+	start, err := nextStart(dec)
+	if err != nil || dec.DecodeElement(&auth, &start) != nil {
+		return
+	}
+	creds, _ := base64.StdEncoding.DecodeString(auth.Data)
+	if auth.Mechanism != "PLAIN" || string(creds) != "\x00bot\x00secret" {
+		fmt.Fprintf(tc, "<failure xmlns='%s'><not-authorized/></failure>", nsSASL)
+		return
+	}
+	fmt.Fprintf(tc, "<success xmlns='%s'/>", nsSASL)
+
+	if dec, err = s.openStream(tc, fmt.Sprintf("<bind xmlns='%s'/>", nsBind)); err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.conn = tc
+	s.mu.Unlock()
+
+	for {
+		start, err := nextStart(dec)
+		if err != nil {
+			return
+		}
+		switch start.Name.Local {
+		case "message":
+			var m sentMessage
+			if dec.DecodeElement(&m, &start) != nil {
+				return
+			}
+			s.stanzas <- m
+		case "presence":
+			var p stanzaPresence
+			if dec.DecodeElement(&p, &start) != nil {
+				return
+			}
+			s.stanzas <- p
+		case "iq":
+			var iq stanzaIQ
+			if dec.DecodeElement(&iq, &start) != nil {
+				return
+			}
+			s.answerIQ(iq)
+		default:
+			_ = dec.Skip()
+		}
+	}
+}
+
+func (s *testServer) answerIQ(iq stanzaIQ) {
+	name := iq.payloadName()
+	switch {
+	case name.Space == nsBind:
+		s.write("<iq type='result' id='%s'><bind xmlns='%s'><jid>bot@example.com/picoclaw</jid></bind></iq>", iq.ID, nsBind)
+	case name.Space == nsDiscoItems:
+		s.write("<iq type='result' id='%s' from='example.com'><query xmlns='%s'><item jid='upload.example.com'/></query></iq>", iq.ID, nsDiscoItems)
+	case name.Space == nsDiscoInfo && iq.To == "upload.example.com":
+		s.write("<iq type='result' id='%s' from='%s'><query xmlns='%s'><feature var='%s'/></query></iq>", iq.ID, iq.To, nsDiscoInfo, nsUpload)
+	case name.Space == nsDiscoInfo:
+		s.write("<iq type='result' id='%s' from='%s'><query xmlns='%s'/></iq>", iq.ID, iq.To, nsDiscoInfo)
+	case name.Space == nsUpload:
+		s.write("<iq type='result' id='%s' from='%s'><slot xmlns='%s'><put url='%s/put/snap.png'><header name='Authorization'>Bearer slot</header><header name='X-Evil'>no</header></put><get url='https://files.example.com/snap.png'/></slot></iq>",
+			iq.ID, iq.To, nsUpload, s.uploadURL)
+	default:
+		s.write("<iq type='error' id='%s'><error type='cancel'><service-unavailable xmlns='%s'/></error></iq>", iq.ID, nsStanzas)
+	}
+}
+
+// sentMessage is a message as the server sees it, chat states included.
+type sentMessage struct {
+	To        string       `xml:"to,attr"`
+	ID        string       `xml:"id,attr"`
+	Type      string       `xml:"type,attr"`
+	Body      string       `xml:"body"`
+	Replace   *replaceElem `xml:"urn:xmpp:message-correct:0 replace"`
+	OOB       *oobElem     `xml:"jabber:x:oob x"`
+	Composing *struct{}    `xml:"http://jabber.org/protocol/chatstates composing"`
+	Active    *struct{}    `xml:"http://jabber.org/protocol/chatstates active"`
+}
+
+// nextMessage returns the next message stanza the client sent.
+func (s *testServer) nextMessage() sentMessage {
+	s.t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case v := <-s.stanzas:
+			if m, ok := v.(sentMessage); ok {
+				return m
+			}
+		case <-timeout:
+			s.t.Fatal("no message from client")
+		}
+	}
+}
+
+func nextInbound(t *testing.T, msgBus *bus.MessageBus, wait time.Duration) (bus.InboundMessage, bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	return msgBus.ConsumeInbound(ctx)
+}
+
+func startChannel(t *testing.T, uploadURL string) (*XMPPChannel, *testServer, *bus.MessageBus) {
+	t.Helper()
+	srv, pool := newTestServer(t, uploadURL)
+	msgBus := bus.NewMessageBus()
+	ch, err := NewXMPPChannel(config.XMPPConfig{
+		JID:          "bot@example.com",
+		Password:     "secret",
+		Server:       srv.ln.Addr().String(),
+		Nick:         "pico",
+		Rooms:        config.FlexibleStringSlice{testRoom},
+		GroupTrigger: config.GroupTriggerConfig{MentionOnly: true},
+		Typing:       config.TypingConfig{Enabled: true},
+		Placeholder:  config.PlaceholderConfig{Enabled: true, Text: "..."},
+	}, msgBus)
+	if err != nil {
+		t.Fatalf("NewXMPPChannel() error = %v", err)
+	}
+	ch.tlsConfig = &tls.Config{RootCAs: pool}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ch.Stop(context.Background()) })
+
+	// Initial presence, then the room join.
+	timeout := time.After(3 * time.Second)
+	for joined := false; !joined; {
+		select {
+		case v := <-srv.stanzas:
+			if p, ok := v.(stanzaPresence); ok && p.To != "" {
+				if p.To != testRoom+"/pico" || p.MUC == nil || p.MUC.History == nil || p.MUC.History.MaxStanzas != 0 {
+					t.Fatalf("room join = %+v", p)
+				}
+				joined = true
+			}
+		case <-timeout:
+			t.Fatal("channel did not join the room")
+		}
+	}
+	return ch, srv, msgBus
+}
+
+func TestXMPPInbound(t *testing.T) {
+	_, srv, msgBus := startChannel(t, "")
+
+	srv.write("<message from='Alice@example.com/phone' to='bot@example.com' type='chat' id='m1'><body>hello</body></message>")
+	msg, ok := nextInbound(t, msgBus, 3*time.Second)
+	if !ok || msg.ChatID != "alice@example.com" || msg.Content != "hello" || msg.Peer.Kind != "direct" || msg.Sender.PlatformID != "alice@example.com" {
+		t.Fatalf("DM inbound = %+v, %v", msg, ok)
+	}
+
+	// Room messages need a mention; our own reflected messages, delayed
+	// history and corrections are ignored.
+	room := testRoom
+	srv.write("<message from='%s/alice' type='groupchat' id='g1'><body>morning all</body></message>", room)
+	srv.write("<message from='%s/pico' type='groupchat' id='g2'><body>pico: echo</body></message>", room)
+	srv.write("<message from='%s/bob' type='groupchat' id='g3'><body>pico: old</body><delay xmlns='urn:xmpp:delay' stamp='2020-01-01T00:00:00Z'/></message>", room)
+	srv.write("<message from='%s/bob' type='groupchat' id='g4'><body>pico: fixed</body><replace xmlns='urn:xmpp:message-correct:0' id='g0'/></message>", room)
+	srv.write("<message from='%s/alice' type='groupchat' id='g5'><body>Pico, what's the weather?</body></message>", room)
+	msg, ok = nextInbound(t, msgBus, 3*time.Second)
+	if !ok {
+		t.Fatal("no inbound for mention")
+	}
+	if msg.ChatID != room || msg.Content != "what's the weather?" || msg.Peer.Kind != "group" || msg.Sender.PlatformID != room+"/alice" {
+		t.Errorf("room inbound = chat %q content %q peer %+v sender %+v", msg.ChatID, msg.Content, msg.Peer, msg.Sender)
+	}
+
+	// Private message through the room.
+	srv.write("<message from='%s/alice' type='chat' id='p1'><body>psst</body></message>", room)
+	msg, ok = nextInbound(t, msgBus, 3*time.Second)
+	if !ok || msg.ChatID != room+"/alice" || msg.Peer.Kind != "direct" {
+		t.Errorf("room PM inbound = %+v, %v", msg, ok)
+	}
+
+	if msg, ok := nextInbound(t, msgBus, 300*time.Millisecond); ok {
+		t.Errorf("unexpected inbound %+v", msg)
+	}
+}
+
+func TestXMPPOutbound(t *testing.T) {
+	var (
+		uploadMu   sync.Mutex
+		uploadAuth string
+		uploadBody string
+		uploadEvil string
+	)
+	uploads := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploadMu.Lock()
+		uploadAuth, uploadBody, uploadEvil = r.Header.Get("Authorization"), string(body), r.Header.Get("X-Evil")
+		uploadMu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer uploads.Close()
+
+	ch, srv, _ := startChannel(t, uploads.URL)
+	ctx := context.Background()
+
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: testRoom, Content: "hi room"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if m := srv.nextMessage(); m.To != testRoom || m.Type != "groupchat" || m.Body != "hi room" || m.Active == nil {
+		t.Errorf("room message = %+v", m)
+	}
+
+	stop, err := ch.StartTyping(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := srv.nextMessage(); m.Type != "chat" || m.Body != "" || m.Composing == nil {
+		t.Errorf("composing message = %+v", m)
+	}
+	stop()
+	if m := srv.nextMessage(); m.Active == nil {
+		t.Errorf("message after typing = %+v, want active state", m)
+	}
+
+	id, err := ch.SendPlaceholder(ctx, "alice@example.com")
+	if err != nil || id == "" {
+		t.Fatalf("SendPlaceholder() = %q, %v", id, err)
+	}
+	if m := srv.nextMessage(); m.ID != id || m.Body != "..." {
+		t.Errorf("placeholder = %+v", m)
+	}
+	if err := ch.EditMessage(ctx, "alice@example.com", id, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if m := srv.nextMessage(); m.Body != "done" || m.Replace == nil || m.Replace.ID != id {
+		t.Errorf("correction = %+v", m)
+	}
+
+	store := media.NewFileMediaStore()
+	ch.SetMediaStore(store)
+	path := filepath.Join(t.TempDir(), "snap.png")
+	if err := os.WriteFile(path, []byte("png-bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := store.Store(path, media.MediaMeta{Filename: "snap.png"}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ch.SendMedia(ctx, bus.OutboundMediaMessage{
+		ChatID: "alice@example.com",
+		Parts:  []bus.MediaPart{{Type: "image", Ref: ref, Caption: "look"}},
+	})
+	if err != nil {
+		t.Fatalf("SendMedia() error = %v", err)
+	}
+	m := srv.nextMessage()
+	if m.Body != "https://files.example.com/snap.png" || m.OOB == nil || m.OOB.URL != m.Body {
+		t.Errorf("media message = %+v", m)
+	}
+	if caption := srv.nextMessage(); caption.Body != "look" {
+		t.Errorf("caption = %+v", caption)
+	}
+	uploadMu.Lock()
+	defer uploadMu.Unlock()
+	if uploadAuth != "Bearer slot" || uploadBody != "png-bytes" || uploadEvil != "" {
+		t.Errorf("upload auth=%q body=%q x-evil=%q", uploadAuth, uploadBody, uploadEvil)
+	}
+}
+
+func TestXMPPAttachmentDownload(t *testing.T) {
+	var (
+		hitsMu sync.Mutex
+		hits   int
+	)
+	var files *httptest.Server
+	files = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hitsMu.Lock()
+		hits++
+		hitsMu.Unlock()
+		switch r.URL.Path {
+		case "/small.txt":
+			io.WriteString(w, "hello")
+		case "/big.bin":
+			w.Write(make([]byte, 2048))
+		case "/redirect":
+			http.Redirect(w, r, strings.Replace(files.URL, "127.0.0.1", "localhost", 1)+"/small.txt", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer files.Close()
+	hitCount := func() int {
+		hitsMu.Lock()
+		defer hitsMu.Unlock()
+		return hits
+	}
+
+	ch, err := NewXMPPChannel(config.XMPPConfig{JID: "bot@127.0.0.1", Password: "x"}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.maxDownload = 1024
+
+	// Off by default: the link is not followed at all.
+	if ref, _ := ch.downloadAttachment(files.URL+"/small.txt", "alice@127.0.0.1", "m1"); ref != "" || hitCount() != 0 {
+		t.Fatalf("download with fetch_attachments off = %q, %d requests", ref, hitCount())
+	}
+
+	ch.config.FetchAttachments = true
+	ref, name := ch.downloadAttachment(files.URL+"/small.txt", "alice@127.0.0.1", "m2")
+	if ref == "" || name != "small.txt" {
+		t.Fatalf("download = %q, %q", ref, name)
+	}
+	defer os.Remove(ref)
+	if data, _ := os.ReadFile(ref); string(data) != "hello" {
+		t.Errorf("downloaded %q", data)
+	}
+
+	if ref, _ := ch.downloadAttachment(files.URL+"/big.bin", "alice@127.0.0.1", "m3"); ref != "" {
+		t.Errorf("oversized download = %q, want refusal", ref)
+	}
+	if ref, _ := ch.downloadAttachment(files.URL+"/redirect", "alice@127.0.0.1", "m4"); ref != "" {
+		t.Errorf("redirect to another host = %q, want refusal", ref)
+	}
+
+	before := hitCount()
+	ch.jid.Domain = "example.com"
+	if ref, _ := ch.downloadAttachment(files.URL+"/small.txt", "alice@example.com", "m5"); ref != "" || hitCount() != before {
+		t.Errorf("download from foreign host = %q, %d requests", ref, hitCount()-before)
+	}
+}
+
+func TestNewXMPPChannelValidation(t *testing.T) {
+	bad := []config.XMPPConfig{
+		{Password: "x"},
+		{JID: "example.com", Password: "x"},
+		{JID: "bot@example.com"},
+		{JID: "bot@example.com", Password: "x", Rooms: config.FlexibleStringSlice{"conference.example.com"}},
+	}
+	for i, cfg := range bad {
+		if _, err := NewXMPPChannel(cfg, bus.NewMessageBus()); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+	ch, err := NewXMPPChannel(config.XMPPConfig{JID: "bot@example.com/desk", Password: "x"}, bus.NewMessageBus())
+	if err != nil || ch.jid.Resource != "desk" || ch.nick != "bot" {
+		t.Errorf("NewXMPPChannel() = %+v, %v", ch, err)
+	}
+	if strings.Contains(ch.jid.Bare(), "/") {
+		t.Errorf("bare JID = %q", ch.jid.Bare())
+	}
+}
@@ -13,5 +13,11 @@ func BuiltinDefinitions() []Definition {
 		switchCommand(),
 		checkCommand(),
 		clearCommand(),
+		usageCommand(),
+		rewindCommand(),
+		branchCommand(),
+		sessionsCommand(),
+		approveCommand(),
+		denyCommand(),
 	}
 }
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+func approveCommand() Definition {
+	return Definition{
+		Name:        "approve",
+		Description: "Approve a pending tool call",
+		Usage:       "/approve <id>",
+		Handler: func(_ context.Context, req Request, rt *Runtime) error {
+			return resolveApproval(req, rt, "approve", true)
+		},
+	}
+}
+
+func denyCommand() Definition {
+	return Definition{
+		Name:        "deny",
+		Description: "Deny a pending tool call",
+		Usage:       "/deny <id>",
+		Handler: func(_ context.Context, req Request, rt *Runtime) error {
+			return resolveApproval(req, rt, "deny", false)
+		},
+	}
+}
+
+func resolveApproval(req Request, rt *Runtime, name string, approved bool) error {
+	if rt == nil || rt.ResolveApproval == nil {
+		return req.Reply(unavailableMsg)
+	}
+	id := nthToken(req.Text, 1)
+	if id == "" {
+		return req.Reply(fmt.Sprintf("Usage: /%s <id>", name))
+	}
+	tool, err := rt.ResolveApproval(id, approved)
+	if err != nil {
+		return req.Reply(fmt.Sprintf("Failed to %s %s: %v", name, id, err))
+	}
+	if approved {
+		return req.Reply(fmt.Sprintf("Approved %s (%s).", tool, id))
+	}
+	return req.Reply(fmt.Sprintf("Denied %s (%s).", tool, id))
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestApproveDeny_ResolvePendingCall(t *testing.T) {
+	var gotID string
+	var gotApproved bool
+	rt := &Runtime{
+		ResolveApproval: func(id string, approved bool) (string, error) {
+			if id == "missing" {
+				return "", errors.New("no pending approval with that id")
+			}
+			gotID, gotApproved = id, approved
+			return "exec", nil
+		},
+	}
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), rt)
+
+	var reply string
+	ex.Execute(context.Background(), Request{
+		Text:  "/approve ab12",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if gotID != "ab12" || !gotApproved || reply != "Approved exec (ab12)." {
+		t.Fatalf("id=%q approved=%v reply=%q", gotID, gotApproved, reply)
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/deny cd34",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if gotID != "cd34" || gotApproved || reply != "Denied exec (cd34)." {
+		t.Fatalf("id=%q approved=%v reply=%q", gotID, gotApproved, reply)
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/approve missing",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if reply != "Failed to approve missing: no pending approval with that id" {
+		t.Fatalf("reply=%q", reply)
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/deny",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if reply != "Usage: /deny <id>" {
+		t.Fatalf("reply=%q", reply)
+	}
+}
+
+func TestApprove_Unavailable(t *testing.T) {
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), &Runtime{})
+
+	var reply string
+	ex.Execute(context.Background(), Request{
+		Text:  "/approve ab12",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if reply != unavailableMsg {
+		t.Fatalf("reply=%q, want=%q", reply, unavailableMsg)
+	}
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+func branchCommand() Definition {
+	return Definition{
+		Name:        "branch",
+		Description: "Fork the conversation into a named branch",
+		Usage:       "/branch <name>",
+		Handler: func(_ context.Context, req Request, rt *Runtime) error {
+			if rt == nil || rt.BranchSession == nil {
+				return req.Reply(unavailableMsg)
+			}
+
+			name := nthToken(req.Text, 1)
+			if name == "" {
+				return req.Reply("Usage: /branch <name>")
+			}
+			branch, err := rt.BranchSession(name)
+			if err != nil {
+				return req.Reply("Failed to create branch: " + err.Error())
+			}
+			return req.Reply(fmt.Sprintf("Created branch %s and switched to it. Use /sessions to list branches.", branch))
+		},
+	}
+}
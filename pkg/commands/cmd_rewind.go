@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+func rewindCommand() Definition {
+	return Definition{
+		Name:        "rewind",
+		Description: "Drop the last N conversation turns",
+		Usage:       "/rewind [n]",
+		Handler: func(_ context.Context, req Request, rt *Runtime) error {
+			if rt == nil || rt.RewindHistory == nil {
+				return req.Reply(unavailableMsg)
+			}
+
+			turns := 1
+			if arg := nthToken(req.Text, 1); arg != "" {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 {
+					return req.Reply("Usage: /rewind [n] (n must be a positive number)")
+				}
+				turns = n
+			}
+
+			removed, err := rt.RewindHistory(turns)
+			if err != nil {
+				return req.Reply("Failed to rewind: " + err.Error())
+			}
+			switch removed {
+			case 0:
+				return req.Reply("Nothing to rewind.")
+			case 1:
+				return req.Reply("Rewound 1 turn.")
+			default:
+				return req.Reply(fmt.Sprintf("Rewound %d turns.", removed))
+			}
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRewind_DefaultsToOneTurn(t *testing.T) {
+	var gotTurns int
+	rt := &Runtime{
+		RewindHistory: func(turns int) (int, error) {
+			gotTurns = turns
+			return turns, nil
+		},
+	}
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), rt)
+
+	var reply string
+	res := ex.Execute(context.Background(), Request{
+		Text:  "/rewind",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if res.Outcome != OutcomeHandled {
+		t.Fatalf("outcome=%v, want=%v", res.Outcome, OutcomeHandled)
+	}
+	if gotTurns != 1 || reply != "Rewound 1 turn." {
+		t.Fatalf("turns=%d reply=%q", gotTurns, reply)
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/rewind 3",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if gotTurns != 3 || reply != "Rewound 3 turns." {
+		t.Fatalf("turns=%d reply=%q", gotTurns, reply)
+	}
+}
+
+func TestRewind_InvalidCountAndEmptyHistory(t *testing.T) {
+	called := false
+	rt := &Runtime{
+		RewindHistory: func(int) (int, error) {
+			called = true
+			return 0, nil
+		},
+	}
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), rt)
+
+	var reply string
+	for _, text := range []string{"/rewind 0", "/rewind two"} {
+		ex.Execute(context.Background(), Request{
+			Text:  text,
+			Reply: func(text string) error { reply = text; return nil },
+		})
+		if called || reply != "Usage: /rewind [n] (n must be a positive number)" {
+			t.Fatalf("%s: called=%v reply=%q", text, called, reply)
+		}
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/rewind",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if reply != "Nothing to rewind." {
+		t.Fatalf("reply=%q", reply)
+	}
+}
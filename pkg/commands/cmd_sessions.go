@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SessionBranch describes one conversation branch of the current chat.
+type SessionBranch struct {
+	Name     string
+	Messages int
+	Active   bool
+}
+
+func sessionsCommand() Definition {
+	return Definition{
+		Name:        "sessions",
+		Description: "List or switch conversation branches",
+		Usage:       "/sessions [switch <name>]",
+		Handler: func(_ context.Context, req Request, rt *Runtime) error {
+			switch sub := normalizeCommandName(nthToken(req.Text, 1)); sub {
+			case "", "list":
+				if rt == nil || rt.ListSessions == nil {
+					return req.Reply(unavailableMsg)
+				}
+				branches, err := rt.ListSessions()
+				if err != nil {
+					return req.Reply("Failed to list sessions: " + err.Error())
+				}
+				return req.Reply(formatSessionBranches(branches))
+			case "switch":
+				if rt == nil || rt.SwitchSession == nil {
+					return req.Reply(unavailableMsg)
+				}
+				name := nthToken(req.Text, 2)
+				if name == "" {
+					return req.Reply("Usage: /sessions switch <name>")
+				}
+				branch, err := rt.SwitchSession(name)
+				if err != nil {
+					return req.Reply("Failed to switch session: " + err.Error())
+				}
+				return req.Reply("Switched to branch " + branch)
+			default:
+				return req.Reply(fmt.Sprintf("Unknown option: %s. Usage: /sessions [switch <name>]", sub))
+			}
+		},
+	}
+}
+
+func formatSessionBranches(branches []SessionBranch) string {
+	var b strings.Builder
+	b.WriteString("Sessions for this chat:\n")
+	for _, br := range branches {
+		marker := "  "
+		if br.Active {
+			marker = "* "
+		}
+		fmt.Fprintf(&b, "%s%s (%d messages)\n", marker, br.Name, br.Messages)
+	}
+	b.WriteString("\nUse /sessions switch <name> to change branch.")
+	return b.String()
+}
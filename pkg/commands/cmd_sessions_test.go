@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBranch_CreatesNamedBranch(t *testing.T) {
+	var gotName string
+	rt := &Runtime{
+		BranchSession: func(name string) (string, error) {
+			gotName = name
+			return "idea-one", nil
+		},
+	}
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), rt)
+
+	var reply string
+	ex.Execute(context.Background(), Request{
+		Text:  "/branch Idea-One",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if gotName != "Idea-One" {
+		t.Fatalf("name=%q", gotName)
+	}
+	if !strings.HasPrefix(reply, "Created branch idea-one") {
+		t.Fatalf("reply=%q", reply)
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/branch",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if reply != "Usage: /branch <name>" {
+		t.Fatalf("reply=%q", reply)
+	}
+}
+
+func TestSessions_ListAndSwitch(t *testing.T) {
+	var switched string
+	rt := &Runtime{
+		ListSessions: func() ([]SessionBranch, error) {
+			return []SessionBranch{
+				{Name: "main", Messages: 4},
+				{Name: "idea", Messages: 6, Active: true},
+			}, nil
+		},
+		SwitchSession: func(name string) (string, error) {
+			if name == "missing" {
+				return "", errors.New("no branch named missing")
+			}
+			switched = name
+			return name, nil
+		},
+	}
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), rt)
+
+	var reply string
+	ex.Execute(context.Background(), Request{
+		Text:  "/sessions",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	for _, want := range []string{"  main (4 messages)", "* idea (6 messages)"} {
+		if !strings.Contains(reply, want) {
+			t.Fatalf("reply missing %q: %q", want, reply)
+		}
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/sessions switch main",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if switched != "main" || reply != "Switched to branch main" {
+		t.Fatalf("switched=%q reply=%q", switched, reply)
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/sessions switch missing",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if reply != "Failed to switch session: no branch named missing" {
+		t.Fatalf("reply=%q", reply)
+	}
+}
+
+func TestSessions_UnknownOptionAndUnavailable(t *testing.T) {
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), &Runtime{})
+
+	var reply string
+	for _, text := range []string{"/sessions", "/sessions switch x", "/branch x"} {
+		ex.Execute(context.Background(), Request{
+			Text:  text,
+			Reply: func(text string) error { reply = text; return nil },
+		})
+		if reply != unavailableMsg {
+			t.Fatalf("%s: reply=%q, want=%q", text, reply, unavailableMsg)
+		}
+	}
+
+	ex.Execute(context.Background(), Request{
+		Text:  "/sessions delete x",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if !strings.HasPrefix(reply, "Unknown option: delete") {
+		t.Fatalf("reply=%q", reply)
+	}
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+// Usage scopes accepted by /usage.
+const (
+	UsageScopeSession = "session"
+	UsageScopeAgent   = "agent"
+	UsageScopeAll     = "all"
+	// UsageScopeSender is what "all" becomes outside the operator's own
+	// console: every agent, but only the requesting sender's usage.
+	UsageScopeSender = "sender"
+)
+
+func usageCommand() Definition {
+	return Definition{
+		Name:        "usage",
+		Description: "Show token usage and cost",
+		Usage:       "/usage [session|agent|all]",
+		Handler: func(_ context.Context, req Request, rt *Runtime) error {
+			if rt == nil || rt.GetUsage == nil {
+				return req.Reply(unavailableMsg)
+			}
+
+			scope := normalizeCommandName(nthToken(req.Text, 1))
+			if scope == "" {
+				scope = UsageScopeSession
+			}
+			var title string
+			switch scope {
+			case UsageScopeSession:
+				title = "Usage for this session"
+			case UsageScopeAgent:
+				title = "Usage for this agent"
+			case UsageScopeAll:
+				// Spend of other chats and users is for the operator only;
+				// chat users see their own usage across all agents.
+				if constants.IsInternalChannel(req.Channel) {
+					title = "Usage for all agents"
+				} else {
+					scope = UsageScopeSender
+					title = "Your usage across all agents"
+				}
+			default:
+				return req.Reply(fmt.Sprintf("Unknown option: %s. Usage: /usage [session|agent|all]", scope))
+			}
+
+			summary, err := rt.GetUsage(scope)
+			if err != nil {
+				return req.Reply("Failed to load usage: " + err.Error())
+			}
+			return req.Reply(formatUsageSummary(title, scope, summary))
+		},
+	}
+}
+
+func formatUsageSummary(title, scope string, s usage.Summary) string {
+	if s.Total.Requests == 0 {
+		return title + ": no usage recorded yet."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n%s\n", title, s.Total)
+
+	writeGroup := func(name string, group map[string]usage.Totals) {
+		if len(group) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\nBy %s:\n", name)
+		for _, key := range usage.SortedKeys(group) {
+			fmt.Fprintf(&b, "- %s: %s\n", key, group[key])
+		}
+	}
+
+	writeGroup("model", s.ByModel)
+	if scope == UsageScopeAll {
+		writeGroup("agent", s.ByAgent)
+		writeGroup("channel", s.ByChannel)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+func TestUsage_DefaultsToSessionScope(t *testing.T) {
+	var gotScope string
+	rt := &Runtime{
+		GetUsage: func(scope string) (usage.Summary, error) {
+			gotScope = scope
+			return usage.Summarize([]usage.Record{
+				{AgentID: "main", Model: "gpt4", PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+			}), nil
+		},
+	}
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), rt)
+
+	var reply string
+	res := ex.Execute(context.Background(), Request{
+		Text: "/usage",
+		Reply: func(text string) error {
+			reply = text
+			return nil
+		},
+	})
+	if res.Outcome != OutcomeHandled {
+		t.Fatalf("outcome=%v, want=%v", res.Outcome, OutcomeHandled)
+	}
+	if gotScope != UsageScopeSession {
+		t.Fatalf("scope=%q, want=%q", gotScope, UsageScopeSession)
+	}
+	if !strings.Contains(reply, "1 requests, 15 tokens") || !strings.Contains(reply, "- gpt4:") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+}
+
+func TestUsage_AllScopeListsAgentsAndChannels(t *testing.T) {
+	rt := &Runtime{
+		GetUsage: func(scope string) (usage.Summary, error) {
+			return usage.Summarize([]usage.Record{
+				{AgentID: "main", Channel: "telegram", Model: "gpt4", TotalTokens: 15},
+				{AgentID: "helper", Channel: "slack", Model: "gpt4", TotalTokens: 5},
+			}), nil
+		},
+	}
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), rt)
+
+	var reply string
+	ex.Execute(context.Background(), Request{
+		Channel: "cli",
+		Text:    "/usage all",
+		Reply:   func(text string) error { reply = text; return nil },
+	})
+	for _, want := range []string{"By agent:", "- helper:", "By channel:", "- telegram:"} {
+		if !strings.Contains(reply, want) {
+			t.Fatalf("reply missing %q: %q", want, reply)
+		}
+	}
+}
+
+func TestUsage_AllScopeFromChatIsLimitedToSender(t *testing.T) {
+	var gotScope string
+	rt := &Runtime{
+		GetUsage: func(scope string) (usage.Summary, error) {
+			gotScope = scope
+			return usage.Summarize([]usage.Record{{AgentID: "main", Model: "gpt4", TotalTokens: 15}}), nil
+		},
+	}
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), rt)
+
+	var reply string
+	ex.Execute(context.Background(), Request{
+		Channel:  "telegram",
+		SenderID: "42",
+		Text:     "/usage all",
+		Reply:    func(text string) error { reply = text; return nil },
+	})
+	if gotScope != UsageScopeSender {
+		t.Fatalf("scope=%q, want=%q", gotScope, UsageScopeSender)
+	}
+	if !strings.HasPrefix(reply, "Your usage across all agents") || strings.Contains(reply, "By agent:") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+}
+
+func TestUsage_UnknownScopeAndUnavailable(t *testing.T) {
+	ex := NewExecutor(NewRegistry(BuiltinDefinitions()), &Runtime{})
+
+	var reply string
+	ex.Execute(context.Background(), Request{
+		Text:  "/usage",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if reply != unavailableMsg {
+		t.Fatalf("reply=%q, want=%q", reply, unavailableMsg)
+	}
+
+	ex = NewExecutor(NewRegistry(BuiltinDefinitions()), &Runtime{
+		GetUsage: func(string) (usage.Summary, error) { return usage.Summary{}, nil },
+	})
+	ex.Execute(context.Background(), Request{
+		Text:  "/usage yesterday",
+		Reply: func(text string) error { reply = text; return nil },
+	})
+	if !strings.HasPrefix(reply, "Unknown option: yesterday") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+}
@@ -1,6 +1,9 @@
 package commands
 
-import "github.com/sipeed/picoclaw/pkg/config"
+import (
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
 
 // Runtime provides runtime dependencies to command handlers. It is constructed
 // per-request by the agent loop so that per-request state (like session scope)
@@ -14,4 +17,10 @@ type Runtime struct {
 	SwitchModel        func(value string) (oldModel string, err error)
 	SwitchChannel      func(value string) error
 	ClearHistory       func() error
+	GetUsage           func(scope string) (usage.Summary, error)
+	RewindHistory      func(turns int) (removed int, err error)
+	BranchSession      func(name string) (branch string, err error)
+	ListSessions       func() ([]SessionBranch, error)
+	SwitchSession      func(name string) (branch string, err error)
+	ResolveApproval    func(id string, approved bool) (tool string, err error)
 }
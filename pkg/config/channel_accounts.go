@@ -0,0 +1,128 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChannelAccount is an additional, named account of a channel, e.g. a second
+// Telegram bot. Any field of the channel's own config may be given and
+// overrides the channel-level value; everything else is inherited.
+//
+//	"telegram": {
+//	  "enabled": true,
+//	  "token": "DEFAULT_BOT_TOKEN",
+//	  "accounts": [{"id": "support", "token": "SUPPORT_BOT_TOKEN"}]
+//	}
+type ChannelAccount struct {
+	ID     string
+	fields map[string]json.RawMessage
+}
+
+func (a *ChannelAccount) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if raw, ok := fields["id"]; ok {
+		if err := json.Unmarshal(raw, &a.ID); err != nil {
+			return fmt.Errorf("account id: %w", err)
+		}
+		delete(fields, "id")
+	}
+	delete(fields, "accounts")
+	a.fields = fields
+	return nil
+}
+
+func (a ChannelAccount) MarshalJSON() ([]byte, error) {
+	out := make(map[string]json.RawMessage, len(a.fields)+1)
+	for k, v := range a.fields {
+		out[k] = v
+	}
+	id, err := json.Marshal(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	out["id"] = id
+	return json.Marshal(out)
+}
+
+// ChannelAccounts lists the extra accounts of a channel.
+type ChannelAccounts []ChannelAccount
+
+// AccountIDs returns the IDs of the extra accounts configured for the channel
+// stored under configKey (its JSON key in "channels", e.g. "telegram").
+func (c ChannelsConfig) AccountIDs(configKey string) []string {
+	field, ok := channelConfigField(reflect.ValueOf(c), configKey)
+	if !ok {
+		return nil
+	}
+	accounts, _ := field.FieldByName("Accounts").Interface().(ChannelAccounts)
+	ids := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		ids = append(ids, account.ID)
+	}
+	return ids
+}
+
+// WithChannelAccount returns a shallow copy of c in which the config of the
+// channel under configKey is replaced by the settings of accountID: the
+// channel-level settings with the account's fields applied on top.
+func (c *Config) WithChannelAccount(configKey, accountID string) (*Config, error) {
+	cp := *c
+	field, ok := channelConfigField(reflect.ValueOf(&cp.Channels).Elem(), configKey)
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %q", configKey)
+	}
+	accounts, _ := field.FieldByName("Accounts").Interface().(ChannelAccounts)
+
+	var account *ChannelAccount
+	for i := range accounts {
+		if accounts[i].ID == accountID {
+			account = &accounts[i]
+			break
+		}
+	}
+	if account == nil {
+		return nil, fmt.Errorf("channel %s has no account %q", configKey, accountID)
+	}
+
+	base, err := json.Marshal(field.Interface())
+	if err != nil {
+		return nil, err
+	}
+	merged := reflect.New(field.Type())
+	if err := json.Unmarshal(base, merged.Interface()); err != nil {
+		return nil, err
+	}
+	overrides, err := json.Marshal(account.fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(overrides, merged.Interface()); err != nil {
+		return nil, fmt.Errorf("channel %s account %q: %w", configKey, accountID, err)
+	}
+	merged.Elem().FieldByName("Accounts").Set(reflect.Zero(reflect.TypeOf(ChannelAccounts(nil))))
+	field.Set(merged.Elem())
+	return &cp, nil
+}
+
+// channelConfigField finds the ChannelsConfig field whose JSON key is configKey.
+func channelConfigField(channels reflect.Value, configKey string) (reflect.Value, bool) {
+	t := channels.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != configKey {
+			continue
+		}
+		field := channels.Field(i)
+		if !field.FieldByName("Accounts").IsValid() {
+			return reflect.Value{}, false
+		}
+		return field, true
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWithChannelAccount(t *testing.T) {
+	cfg := DefaultConfig()
+	err := json.Unmarshal([]byte(`{
+		"enabled": true,
+		"token": "main-token",
+		"allow_from": ["1"],
+		"accounts": [{"id": "support", "token": "support-token"}]
+	}`), &cfg.Channels.Telegram)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if got := cfg.Channels.AccountIDs("telegram"); !reflect.DeepEqual(got, []string{"support"}) {
+		t.Fatalf("AccountIDs() = %v", got)
+	}
+	if got := cfg.Channels.AccountIDs("nope"); got != nil {
+		t.Errorf("AccountIDs(unknown) = %v, want nil", got)
+	}
+
+	acc, err := cfg.WithChannelAccount("telegram", "support")
+	if err != nil {
+		t.Fatalf("WithChannelAccount() error = %v", err)
+	}
+	tg := acc.Channels.Telegram
+	if tg.Token != "support-token" || !tg.Enabled {
+		t.Errorf("account config = %+v, want overridden token and inherited enabled", tg)
+	}
+	if len(tg.AllowFrom) != 1 || tg.AllowFrom[0] != "1" {
+		t.Errorf("AllowFrom = %v, want inherited [1]", tg.AllowFrom)
+	}
+	if len(tg.Accounts) != 0 {
+		t.Errorf("Accounts = %v, want none in account config", tg.Accounts)
+	}
+	if cfg.Channels.Telegram.Token != "main-token" {
+		t.Errorf("original config modified: token = %q", cfg.Channels.Telegram.Token)
+	}
+
+	if _, err := cfg.WithChannelAccount("telegram", "missing"); err == nil {
+		t.Error("WithChannelAccount(missing) error = nil")
+	}
+}
+
+func TestChannelAccountJSONRoundTrip(t *testing.T) {
+	var accounts ChannelAccounts
+	in := `[{"id":"a","token":"x","allow_from":["1"]}]`
+	if err := json.Unmarshal([]byte(in), &accounts); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, got any
+	_ = json.Unmarshal([]byte(in), &want)
+	_ = json.Unmarshal(out, &got)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("round trip = %s, want %s", out, in)
+	}
+}
@@ -137,6 +137,53 @@ type AgentConfig struct {
 	Model     *AgentModelConfig `json:"model,omitempty"`
 	Skills    []string          `json:"skills,omitempty"`
 	Subagents *SubagentsConfig  `json:"subagents,omitempty"`
+	Budgets   []BudgetConfig    `json:"budgets,omitempty"`
+	Tools     *AgentToolsConfig `json:"tools,omitempty"`
+}
+
+// AgentToolsConfig narrows the global tools settings for one agent.
+// Allow and Deny hold tool-name globs such as "exec" or "mcp_*", or
+// "mcp:<server>" and "mcp:<server>:<tool>" to pick MCP tools by server and
+// original tool name. An empty Allow permits every tool; Deny always wins.
+// Non-nil path lists and RestrictToWorkspace replace the global values.
+type AgentToolsConfig struct {
+	Allow               []string `json:"allow,omitempty"`
+	Deny                []string `json:"deny,omitempty"`
+	RestrictToWorkspace *bool    `json:"restrict_to_workspace,omitempty"`
+	ExecAllowPatterns   []string `json:"exec_allow_patterns,omitempty"`
+	AllowReadPaths      []string `json:"allow_read_paths,omitempty"`
+	AllowWritePaths     []string `json:"allow_write_paths,omitempty"`
+}
+
+// Budget scopes select whose usage a BudgetConfig counts.
+const (
+	BudgetScopeAgent   = "agent"
+	BudgetScopeSender  = "sender"
+	BudgetScopeChannel = "channel"
+)
+
+// Budget periods select the window a BudgetConfig counts usage over.
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+)
+
+// BudgetConfig limits how many tokens or how much money an agent may spend
+// in a period. Scope "agent" counts all of the agent's usage, "sender" and
+// "channel" count each sender or channel separately. Reaching a soft limit
+// forces the agent onto routing.light_model; reaching a hard limit makes it
+// refuse to call the LLM until the period rolls over. Zero limits are unset.
+// Cost limits are in Currency, which must match the pricing currency of every
+// priced model in model_list.
+type BudgetConfig struct {
+	Scope      string  `json:"scope"`
+	Period     string  `json:"period"`
+	SoftTokens int     `json:"soft_tokens,omitempty"`
+	HardTokens int     `json:"hard_tokens,omitempty"`
+	SoftCost   float64 `json:"soft_cost,omitempty"`
+	HardCost   float64 `json:"hard_cost,omitempty"`
+	Currency   string  `json:"currency,omitempty"` // currency of the cost limits, defaults to USD
+	Message    string  `json:"message,omitempty"`  // reply sent when the hard limit is hit
 }
 
 type SubagentsConfig struct {
@@ -165,6 +212,9 @@ type AgentBinding struct {
 type SessionConfig struct {
 	DMScope       string              `json:"dm_scope,omitempty"`
 	IdentityLinks map[string][]string `json:"identity_links,omitempty"`
+	// PerThread gives every platform thread (a Slack thread, a Telegram
+	// forum topic, ...) its own session instead of sharing the chat's.
+	PerThread bool `json:"per_thread,omitempty"`
 }
 
 // RoutingConfig controls the intelligent model routing feature.
@@ -180,26 +230,47 @@ type RoutingConfig struct {
 }
 
 type AgentDefaults struct {
-	Workspace                 string         `json:"workspace"                       env:"PICOCLAW_AGENTS_DEFAULTS_WORKSPACE"`
-	RestrictToWorkspace       bool           `json:"restrict_to_workspace"           env:"PICOCLAW_AGENTS_DEFAULTS_RESTRICT_TO_WORKSPACE"`
-	AllowReadOutsideWorkspace bool           `json:"allow_read_outside_workspace"    env:"PICOCLAW_AGENTS_DEFAULTS_ALLOW_READ_OUTSIDE_WORKSPACE"`
-	Provider                  string         `json:"provider"                        env:"PICOCLAW_AGENTS_DEFAULTS_PROVIDER"`
-	ModelName                 string         `json:"model_name,omitempty"            env:"PICOCLAW_AGENTS_DEFAULTS_MODEL_NAME"`
-	Model                     string         `json:"model"                           env:"PICOCLAW_AGENTS_DEFAULTS_MODEL"` // Deprecated: use model_name instead
-	ModelFallbacks            []string       `json:"model_fallbacks,omitempty"`
-	ImageModel                string         `json:"image_model,omitempty"           env:"PICOCLAW_AGENTS_DEFAULTS_IMAGE_MODEL"`
-	ImageModelFallbacks       []string       `json:"image_model_fallbacks,omitempty"`
-	MaxTokens                 int            `json:"max_tokens"                      env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
-	Temperature               *float64       `json:"temperature,omitempty"           env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
-	MaxToolIterations         int            `json:"max_tool_iterations"             env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
-	SummarizeMessageThreshold int            `json:"summarize_message_threshold"     env:"PICOCLAW_AGENTS_DEFAULTS_SUMMARIZE_MESSAGE_THRESHOLD"`
-	SummarizeTokenPercent     int            `json:"summarize_token_percent"         env:"PICOCLAW_AGENTS_DEFAULTS_SUMMARIZE_TOKEN_PERCENT"`
-	MaxMediaSize              int            `json:"max_media_size,omitempty"        env:"PICOCLAW_AGENTS_DEFAULTS_MAX_MEDIA_SIZE"`
-	Routing                   *RoutingConfig `json:"routing,omitempty"`
+	Workspace                 string                `json:"workspace"                       env:"PICOCLAW_AGENTS_DEFAULTS_WORKSPACE"`
+	RestrictToWorkspace       bool                  `json:"restrict_to_workspace"           env:"PICOCLAW_AGENTS_DEFAULTS_RESTRICT_TO_WORKSPACE"`
+	AllowReadOutsideWorkspace bool                  `json:"allow_read_outside_workspace"    env:"PICOCLAW_AGENTS_DEFAULTS_ALLOW_READ_OUTSIDE_WORKSPACE"`
+	Provider                  string                `json:"provider"                        env:"PICOCLAW_AGENTS_DEFAULTS_PROVIDER"`
+	ModelName                 string                `json:"model_name,omitempty"            env:"PICOCLAW_AGENTS_DEFAULTS_MODEL_NAME"`
+	Model                     string                `json:"model"                           env:"PICOCLAW_AGENTS_DEFAULTS_MODEL"` // Deprecated: use model_name instead
+	ModelFallbacks            []string              `json:"model_fallbacks,omitempty"`
+	ImageModel                string                `json:"image_model,omitempty"           env:"PICOCLAW_AGENTS_DEFAULTS_IMAGE_MODEL"`
+	ImageModelFallbacks       []string              `json:"image_model_fallbacks,omitempty"`
+	MaxTokens                 int                   `json:"max_tokens"                      env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
+	Temperature               *float64              `json:"temperature,omitempty"           env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
+	MaxToolIterations         int                   `json:"max_tool_iterations"             env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	SummarizeMessageThreshold int                   `json:"summarize_message_threshold"     env:"PICOCLAW_AGENTS_DEFAULTS_SUMMARIZE_MESSAGE_THRESHOLD"`
+	SummarizeTokenPercent     int                   `json:"summarize_token_percent"         env:"PICOCLAW_AGENTS_DEFAULTS_SUMMARIZE_TOKEN_PERCENT"`
+	MaxMediaSize              int                   `json:"max_media_size,omitempty"        env:"PICOCLAW_AGENTS_DEFAULTS_MAX_MEDIA_SIZE"`
+	Streaming                 bool                  `json:"streaming,omitempty"             env:"PICOCLAW_AGENTS_DEFAULTS_STREAMING"`
+	SessionStore              string                `json:"session_store,omitempty"         env:"PICOCLAW_AGENTS_DEFAULTS_SESSION_STORE"` // "json" (default), "jsonl" or "sqlite"
+	Routing                   *RoutingConfig        `json:"routing,omitempty"`
+	SemanticMemory            *SemanticMemoryConfig `json:"semantic_memory,omitempty"`
+}
+
+// SemanticMemoryConfig enables embedding-based long-term memory. Instead of
+// placing all of MEMORY.md and the recent daily notes in every system
+// prompt, the memory files are embedded into a local vector index and only
+// the TopK chunks most relevant to the current message are injected.
+type SemanticMemoryConfig struct {
+	Enabled        bool    `json:"enabled"`
+	EmbeddingModel string  `json:"embedding_model"`     // model_name from model_list serving an OpenAI-compatible /embeddings endpoint
+	TopK           int     `json:"top_k,omitempty"`     // memories injected per message, default 5
+	MinScore       float64 `json:"min_score,omitempty"` // minimum cosine similarity in [0,1], default 0.3
 }
 
 const DefaultMaxMediaSize = 20 * 1024 * 1024 // 20 MB
 
+// Session store backends selectable via agents.defaults.session_store.
+const (
+	SessionStoreJSON   = "json"
+	SessionStoreJSONL  = "jsonl"
+	SessionStoreSQLite = "sqlite"
+)
+
 func (d *AgentDefaults) GetMaxMediaSize() int {
 	if d.MaxMediaSize > 0 {
 		return d.MaxMediaSize
@@ -233,6 +304,56 @@ type ChannelsConfig struct {
 	WeComAIBot WeComAIBotConfig `json:"wecom_aibot"`
 	Pico       PicoConfig       `json:"pico"`
 	IRC        IRCConfig        `json:"irc"`
+	Email      EmailConfig      `json:"email"`
+	Webhook    WebhookConfig    `json:"webhook"`
+	MQTT       MQTTConfig       `json:"mqtt"`
+	Mattermost MattermostConfig `json:"mattermost"`
+	RocketChat RocketChatConfig `json:"rocketchat"`
+	XMPP       XMPPConfig       `json:"xmpp"`
+
+	// Limits applies to inbound messages of every channel.
+	Limits InboundLimitsConfig `json:"limits"`
+}
+
+// InboundLimitsConfig protects the agent from floods of inbound messages.
+// Messages over a limit are dropped before they reach the agent; the sender
+// is told once per minute with ThrottleReply (or TooLongReply for messages
+// longer than MaxMessageLength runes). A sender throttled FloodStrikes times
+// within FloodWindowSeconds is ignored for BanMinutes.
+//
+// Overrides replace these settings entirely for one channel, keyed by
+// channel name ("telegram") or, for a named account, "channel:account"
+// ("telegram:work").
+type InboundLimitsConfig struct {
+	Enabled            bool                           `json:"enabled"                        env:"PICOCLAW_CHANNELS_LIMITS_ENABLED"`
+	PerSender          RateLimitConfig                `json:"per_sender"`
+	PerChat            RateLimitConfig                `json:"per_chat"`
+	PerChannel         RateLimitConfig                `json:"per_channel"`
+	MaxMessageLength   int                            `json:"max_message_length,omitempty"`
+	FloodStrikes       int                            `json:"flood_strikes,omitempty"`
+	FloodWindowSeconds int                            `json:"flood_window_seconds,omitempty"`
+	BanMinutes         int                            `json:"ban_minutes,omitempty"`
+	ThrottleReply      string                         `json:"throttle_reply,omitempty"`
+	TooLongReply       string                         `json:"too_long_reply,omitempty"`
+	Overrides          map[string]InboundLimitsConfig `json:"overrides,omitempty"`
+}
+
+// RateLimitConfig is a token bucket refilled at PerMinute messages per
+// minute that holds up to Burst messages. A zero PerMinute means no limit.
+type RateLimitConfig struct {
+	PerMinute float64 `json:"per_minute,omitempty"`
+	Burst     int     `json:"burst,omitempty"`
+}
+
+// For returns the limits that apply to a channel instance.
+func (c InboundLimitsConfig) For(name, key string) InboundLimitsConfig {
+	if o, ok := c.Overrides[key]; ok {
+		return o
+	}
+	if o, ok := c.Overrides[name]; ok {
+		return o
+	}
+	return c
 }
 
 // GroupTriggerConfig controls when the bot responds in group chats.
@@ -259,6 +380,7 @@ type WhatsAppConfig struct {
 	SessionStorePath   string              `json:"session_store_path"   env:"PICOCLAW_CHANNELS_WHATSAPP_SESSION_STORE_PATH"`
 	AllowFrom          FlexibleStringSlice `json:"allow_from"           env:"PICOCLAW_CHANNELS_WHATSAPP_ALLOW_FROM"`
 	ReasoningChannelID string              `json:"reasoning_channel_id" env:"PICOCLAW_CHANNELS_WHATSAPP_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type TelegramConfig struct {
@@ -271,6 +393,7 @@ type TelegramConfig struct {
 	Typing             TypingConfig        `json:"typing,omitempty"`
 	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_TELEGRAM_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type FeishuConfig struct {
@@ -284,6 +407,7 @@ type FeishuConfig struct {
 	Placeholder         PlaceholderConfig   `json:"placeholder,omitempty"`
 	ReasoningChannelID  string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_FEISHU_REASONING_CHANNEL_ID"`
 	RandomReactionEmoji FlexibleStringSlice `json:"random_reaction_emoji"   env:"PICOCLAW_CHANNELS_FEISHU_RANDOM_REACTION_EMOJI"`
+	Accounts            ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type DiscordConfig struct {
@@ -296,6 +420,7 @@ type DiscordConfig struct {
 	Typing             TypingConfig        `json:"typing,omitempty"`
 	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_DISCORD_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type MaixCamConfig struct {
@@ -304,6 +429,7 @@ type MaixCamConfig struct {
 	Port               int                 `json:"port"                 env:"PICOCLAW_CHANNELS_MAIXCAM_PORT"`
 	AllowFrom          FlexibleStringSlice `json:"allow_from"           env:"PICOCLAW_CHANNELS_MAIXCAM_ALLOW_FROM"`
 	ReasoningChannelID string              `json:"reasoning_channel_id" env:"PICOCLAW_CHANNELS_MAIXCAM_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type QQConfig struct {
@@ -315,6 +441,7 @@ type QQConfig struct {
 	MaxMessageLength   int                 `json:"max_message_length"      env:"PICOCLAW_CHANNELS_QQ_MAX_MESSAGE_LENGTH"`
 	SendMarkdown       bool                `json:"send_markdown"           env:"PICOCLAW_CHANNELS_QQ_SEND_MARKDOWN"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_QQ_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type DingTalkConfig struct {
@@ -324,6 +451,7 @@ type DingTalkConfig struct {
 	AllowFrom          FlexibleStringSlice `json:"allow_from"              env:"PICOCLAW_CHANNELS_DINGTALK_ALLOW_FROM"`
 	GroupTrigger       GroupTriggerConfig  `json:"group_trigger,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_DINGTALK_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type SlackConfig struct {
@@ -335,6 +463,7 @@ type SlackConfig struct {
 	Typing             TypingConfig        `json:"typing,omitempty"`
 	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_SLACK_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type MatrixConfig struct {
@@ -348,6 +477,7 @@ type MatrixConfig struct {
 	GroupTrigger       GroupTriggerConfig  `json:"group_trigger,omitempty"`
 	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_MATRIX_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type LINEConfig struct {
@@ -362,6 +492,7 @@ type LINEConfig struct {
 	Typing             TypingConfig        `json:"typing,omitempty"`
 	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_LINE_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type OneBotConfig struct {
@@ -375,6 +506,7 @@ type OneBotConfig struct {
 	Typing             TypingConfig        `json:"typing,omitempty"`
 	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_ONEBOT_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type WeComConfig struct {
@@ -389,6 +521,7 @@ type WeComConfig struct {
 	ReplyTimeout       int                 `json:"reply_timeout"           env:"PICOCLAW_CHANNELS_WECOM_REPLY_TIMEOUT"`
 	GroupTrigger       GroupTriggerConfig  `json:"group_trigger,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_WECOM_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type WeComAppConfig struct {
@@ -405,6 +538,7 @@ type WeComAppConfig struct {
 	ReplyTimeout       int                 `json:"reply_timeout"           env:"PICOCLAW_CHANNELS_WECOM_APP_REPLY_TIMEOUT"`
 	GroupTrigger       GroupTriggerConfig  `json:"group_trigger,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_WECOM_APP_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type WeComAIBotConfig struct {
@@ -417,6 +551,7 @@ type WeComAIBotConfig struct {
 	MaxSteps           int                 `json:"max_steps"            env:"PICOCLAW_CHANNELS_WECOM_AIBOT_MAX_STEPS"`       // Maximum streaming steps
 	WelcomeMessage     string              `json:"welcome_message"      env:"PICOCLAW_CHANNELS_WECOM_AIBOT_WELCOME_MESSAGE"` // Sent on enter_chat event; empty = no welcome
 	ReasoningChannelID string              `json:"reasoning_channel_id" env:"PICOCLAW_CHANNELS_WECOM_AIBOT_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type PicoConfig struct {
@@ -430,6 +565,7 @@ type PicoConfig struct {
 	MaxConnections  int                 `json:"max_connections,omitempty"`
 	AllowFrom       FlexibleStringSlice `json:"allow_from"                  env:"PICOCLAW_CHANNELS_PICO_ALLOW_FROM"`
 	Placeholder     PlaceholderConfig   `json:"placeholder,omitempty"`
+	Accounts        ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type IRCConfig struct {
@@ -449,6 +585,113 @@ type IRCConfig struct {
 	GroupTrigger       GroupTriggerConfig  `json:"group_trigger,omitempty"`
 	Typing             TypingConfig        `json:"typing,omitempty"`
 	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_IRC_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
+}
+
+type EmailConfig struct {
+	Enabled            bool                `json:"enabled"                    env:"PICOCLAW_CHANNELS_EMAIL_ENABLED"`
+	IMAPServer         string              `json:"imap_server"                env:"PICOCLAW_CHANNELS_EMAIL_IMAP_SERVER"`
+	IMAPTLS            bool                `json:"imap_tls"                   env:"PICOCLAW_CHANNELS_EMAIL_IMAP_TLS"`
+	SMTPServer         string              `json:"smtp_server"                env:"PICOCLAW_CHANNELS_EMAIL_SMTP_SERVER"`
+	SMTPTLS            bool                `json:"smtp_tls"                   env:"PICOCLAW_CHANNELS_EMAIL_SMTP_TLS"`
+	Username           string              `json:"username"                   env:"PICOCLAW_CHANNELS_EMAIL_USERNAME"`
+	Password           string              `json:"password"                   env:"PICOCLAW_CHANNELS_EMAIL_PASSWORD"`
+	Address            string              `json:"address"                    env:"PICOCLAW_CHANNELS_EMAIL_ADDRESS"`
+	Mailbox            string              `json:"mailbox,omitempty"          env:"PICOCLAW_CHANNELS_EMAIL_MAILBOX"`
+	PollInterval       int                 `json:"poll_interval,omitempty"    env:"PICOCLAW_CHANNELS_EMAIL_POLL_INTERVAL"`    // seconds
+	MaxMessageSize     int                 `json:"max_message_size,omitempty" env:"PICOCLAW_CHANNELS_EMAIL_MAX_MESSAGE_SIZE"` // bytes
+	AllowFrom          FlexibleStringSlice `json:"allow_from"                 env:"PICOCLAW_CHANNELS_EMAIL_ALLOW_FROM"`
+	ReasoningChannelID string              `json:"reasoning_channel_id"       env:"PICOCLAW_CHANNELS_EMAIL_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
+}
+
+// WebhookMappingConfig maps fields of an inbound JSON payload to a message.
+// Each value is a template in which {{ path }} is replaced by the value at a
+// JSONPath-like path such as $.alerts[0].labels.alertname; "a | b" falls back
+// to b when a is empty.
+type WebhookMappingConfig struct {
+	Content   string `json:"content,omitempty"`
+	SenderID  string `json:"sender_id,omitempty"`
+	ChatID    string `json:"chat_id,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+}
+
+type WebhookConfig struct {
+	Enabled            bool                 `json:"enabled"                    env:"PICOCLAW_CHANNELS_WEBHOOK_ENABLED"`
+	WebhookPath        string               `json:"webhook_path"               env:"PICOCLAW_CHANNELS_WEBHOOK_WEBHOOK_PATH"`
+	AuthToken          string               `json:"auth_token"                 env:"PICOCLAW_CHANNELS_WEBHOOK_AUTH_TOKEN"`
+	Secret             string               `json:"secret"                     env:"PICOCLAW_CHANNELS_WEBHOOK_SECRET"`
+	SignatureHeader    string               `json:"signature_header,omitempty" env:"PICOCLAW_CHANNELS_WEBHOOK_SIGNATURE_HEADER"`
+	Mapping            WebhookMappingConfig `json:"mapping"`
+	CallbackURL        string               `json:"callback_url"               env:"PICOCLAW_CHANNELS_WEBHOOK_CALLBACK_URL"`
+	CallbackSecret     string               `json:"callback_secret"            env:"PICOCLAW_CHANNELS_WEBHOOK_CALLBACK_SECRET"`
+	CallbackHeaders    map[string]string    `json:"callback_headers,omitempty"`
+	AllowFrom          FlexibleStringSlice  `json:"allow_from"                 env:"PICOCLAW_CHANNELS_WEBHOOK_ALLOW_FROM"`
+	ReasoningChannelID string               `json:"reasoning_channel_id"       env:"PICOCLAW_CHANNELS_WEBHOOK_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts      `json:"accounts,omitempty"`
+}
+
+type MQTTConfig struct {
+	Enabled            bool                `json:"enabled"                  env:"PICOCLAW_CHANNELS_MQTT_ENABLED"`
+	Broker             string              `json:"broker"                   env:"PICOCLAW_CHANNELS_MQTT_BROKER"`
+	ClientID           string              `json:"client_id"                env:"PICOCLAW_CHANNELS_MQTT_CLIENT_ID"`
+	Username           string              `json:"username"                 env:"PICOCLAW_CHANNELS_MQTT_USERNAME"`
+	Password           string              `json:"password"                 env:"PICOCLAW_CHANNELS_MQTT_PASSWORD"`
+	Topics             FlexibleStringSlice `json:"topics"                   env:"PICOCLAW_CHANNELS_MQTT_TOPICS"`
+	ReplyTopic         string              `json:"reply_topic"              env:"PICOCLAW_CHANNELS_MQTT_REPLY_TOPIC"`
+	StatusTopic        string              `json:"status_topic,omitempty"   env:"PICOCLAW_CHANNELS_MQTT_STATUS_TOPIC"`
+	QoS                int                 `json:"qos"                      env:"PICOCLAW_CHANNELS_MQTT_QOS"`
+	Retain             bool                `json:"retain"                   env:"PICOCLAW_CHANNELS_MQTT_RETAIN"`
+	PayloadFormat      string              `json:"payload_format,omitempty" env:"PICOCLAW_CHANNELS_MQTT_PAYLOAD_FORMAT"` // "text" or "json"
+	KeepAlive          int                 `json:"keep_alive,omitempty"     env:"PICOCLAW_CHANNELS_MQTT_KEEP_ALIVE"`     // seconds
+	CAFile             string              `json:"ca_file,omitempty"        env:"PICOCLAW_CHANNELS_MQTT_CA_FILE"`
+	AllowFrom          FlexibleStringSlice `json:"allow_from"               env:"PICOCLAW_CHANNELS_MQTT_ALLOW_FROM"`
+	ReasoningChannelID string              `json:"reasoning_channel_id"     env:"PICOCLAW_CHANNELS_MQTT_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
+}
+
+type MattermostConfig struct {
+	Enabled            bool                `json:"enabled"                 env:"PICOCLAW_CHANNELS_MATTERMOST_ENABLED"`
+	ServerURL          string              `json:"server_url"              env:"PICOCLAW_CHANNELS_MATTERMOST_SERVER_URL"`
+	Token              string              `json:"token"                   env:"PICOCLAW_CHANNELS_MATTERMOST_TOKEN"`
+	AllowFrom          FlexibleStringSlice `json:"allow_from"              env:"PICOCLAW_CHANNELS_MATTERMOST_ALLOW_FROM"`
+	GroupTrigger       GroupTriggerConfig  `json:"group_trigger,omitempty"`
+	Typing             TypingConfig        `json:"typing,omitempty"`
+	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
+	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_MATTERMOST_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
+}
+
+type RocketChatConfig struct {
+	Enabled            bool                `json:"enabled"                 env:"PICOCLAW_CHANNELS_ROCKETCHAT_ENABLED"`
+	ServerURL          string              `json:"server_url"              env:"PICOCLAW_CHANNELS_ROCKETCHAT_SERVER_URL"`
+	UserID             string              `json:"user_id"                 env:"PICOCLAW_CHANNELS_ROCKETCHAT_USER_ID"`
+	AuthToken          string              `json:"auth_token"              env:"PICOCLAW_CHANNELS_ROCKETCHAT_AUTH_TOKEN"`
+	AllowFrom          FlexibleStringSlice `json:"allow_from"              env:"PICOCLAW_CHANNELS_ROCKETCHAT_ALLOW_FROM"`
+	GroupTrigger       GroupTriggerConfig  `json:"group_trigger,omitempty"`
+	Typing             TypingConfig        `json:"typing,omitempty"`
+	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
+	ReasoningChannelID string              `json:"reasoning_channel_id"    env:"PICOCLAW_CHANNELS_ROCKETCHAT_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
+}
+
+type XMPPConfig struct {
+	Enabled            bool                `json:"enabled"                  env:"PICOCLAW_CHANNELS_XMPP_ENABLED"`
+	JID                string              `json:"jid"                      env:"PICOCLAW_CHANNELS_XMPP_JID"`
+	Password           string              `json:"password"                 env:"PICOCLAW_CHANNELS_XMPP_PASSWORD"`
+	Server             string              `json:"server,omitempty"         env:"PICOCLAW_CHANNELS_XMPP_SERVER"`
+	DirectTLS          bool                `json:"direct_tls,omitempty"     env:"PICOCLAW_CHANNELS_XMPP_DIRECT_TLS"`
+	Resource           string              `json:"resource,omitempty"       env:"PICOCLAW_CHANNELS_XMPP_RESOURCE"`
+	Nick               string              `json:"nick,omitempty"           env:"PICOCLAW_CHANNELS_XMPP_NICK"`
+	Rooms              FlexibleStringSlice `json:"rooms"                    env:"PICOCLAW_CHANNELS_XMPP_ROOMS"`
+	UploadService      string              `json:"upload_service,omitempty" env:"PICOCLAW_CHANNELS_XMPP_UPLOAD_SERVICE"`
+	FetchAttachments   bool                `json:"fetch_attachments"        env:"PICOCLAW_CHANNELS_XMPP_FETCH_ATTACHMENTS"`
+	AllowFrom          FlexibleStringSlice `json:"allow_from"               env:"PICOCLAW_CHANNELS_XMPP_ALLOW_FROM"`
+	GroupTrigger       GroupTriggerConfig  `json:"group_trigger,omitempty"`
+	Typing             TypingConfig        `json:"typing,omitempty"`
+	Placeholder        PlaceholderConfig   `json:"placeholder,omitempty"`
+	ReasoningChannelID string              `json:"reasoning_channel_id"     env:"PICOCLAW_CHANNELS_XMPP_REASONING_CHANNEL_ID"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
 }
 
 type HeartbeatConfig struct {
@@ -562,6 +805,31 @@ type ModelConfig struct {
 	MaxTokensField string `json:"max_tokens_field,omitempty"` // Field name for max tokens (e.g., "max_completion_tokens")
 	RequestTimeout int    `json:"request_timeout,omitempty"`
 	ThinkingLevel  string `json:"thinking_level,omitempty"` // Extended thinking: off|low|medium|high|xhigh|adaptive
+	KeepAlive      string `json:"keep_alive,omitempty"`     // ollama: how long the model stays loaded, e.g. "10m" or "-1"
+
+	// gemini: harm category → block threshold, e.g. {"harassment": "block_only_high"}
+	SafetySettings map[string]string `json:"safety_settings,omitempty"`
+
+	// Cost accounting
+	Pricing *ModelPricing `json:"pricing,omitempty"` // Price per million tokens, used by the usage ledger
+
+	// Capability overrides; unset fields keep the built-in defaults for the model
+	ContextWindow    int   `json:"context_window,omitempty"`    // Total tokens the model accepts (prompt + output)
+	SupportsVision   *bool `json:"supports_vision,omitempty"`   // Accepts image input
+	SupportsFiles    *bool `json:"supports_files,omitempty"`    // Accepts document input such as PDF
+	SupportsTools    *bool `json:"supports_tools,omitempty"`    // Accepts tool definitions
+	SupportsThinking *bool `json:"supports_thinking,omitempty"` // Accepts thinking_level
+}
+
+// ModelPricing is the price of a model per one million tokens.
+// DefaultCurrency is the currency of prices and cost budgets that do not
+// name one.
+const DefaultCurrency = "USD"
+
+type ModelPricing struct {
+	Input    float64 `json:"input"`              // Price per 1M prompt tokens
+	Output   float64 `json:"output"`             // Price per 1M completion tokens
+	Currency string  `json:"currency,omitempty"` // Defaults to USD
 }
 
 // Validate checks if the ModelConfig has all required fields.
@@ -576,8 +844,18 @@ func (c *ModelConfig) Validate() error {
 }
 
 type GatewayConfig struct {
-	Host string `json:"host" env:"PICOCLAW_GATEWAY_HOST"`
-	Port int    `json:"port" env:"PICOCLAW_GATEWAY_PORT"`
+	Host   string       `json:"host"   env:"PICOCLAW_GATEWAY_HOST"`
+	Port   int          `json:"port"   env:"PICOCLAW_GATEWAY_PORT"`
+	Outbox OutboxConfig `json:"outbox"`
+}
+
+// OutboxConfig controls the persistent outbound queue. When enabled, every
+// outbound message is stored in the workspace until a channel accepts it,
+// and failed deliveries are retried with exponential backoff for up to
+// MaxAttempts rounds before being kept as dead letters.
+type OutboxConfig struct {
+	Enabled     bool `json:"enabled"      env:"PICOCLAW_GATEWAY_OUTBOX_ENABLED"`
+	MaxAttempts int  `json:"max_attempts" env:"PICOCLAW_GATEWAY_OUTBOX_MAX_ATTEMPTS"`
 }
 
 type ToolDiscoveryConfig struct {
@@ -659,6 +937,28 @@ type ExecConfig struct {
 	TimeoutSeconds      int      `                                 env:"PICOCLAW_TOOLS_EXEC_TIMEOUT_SECONDS"       json:"timeout_seconds"` // 0 means use default (60s)
 }
 
+// ApprovalConfig makes matching tool calls wait for a user to approve them
+// in the originating chat before they run. The agent loop handles one
+// message at a time, so other chats wait for up to TimeoutSeconds too.
+type ApprovalConfig struct {
+	Enabled        bool           `json:"enabled"         env:"PICOCLAW_TOOLS_APPROVAL_ENABLED"`
+	TimeoutSeconds int            `json:"timeout_seconds" env:"PICOCLAW_TOOLS_APPROVAL_TIMEOUT_SECONDS"` // 0 means use default (60s)
+	Rules          []ApprovalRule `json:"rules"`
+}
+
+// ApprovalRule selects tool calls that need approval. A rule without Pattern
+// or AllowPaths matches every call of the tool.
+type ApprovalRule struct {
+	// Tool is the tool name; shell-style globs such as "mcp_*" are allowed
+	Tool string `json:"tool"`
+	// Arg is the argument checked by Pattern and AllowPaths (default: all arguments as JSON)
+	Arg string `json:"arg,omitempty"`
+	// Pattern is a regular expression; matching calls need approval
+	Pattern string `json:"pattern,omitempty"`
+	// AllowPaths lists directories (relative to the workspace) that do not need approval
+	AllowPaths []string `json:"allow_paths,omitempty"`
+}
+
 type SkillsToolsConfig struct {
 	ToolConfig            `                       envPrefix:"PICOCLAW_TOOLS_SKILLS_"`
 	Registries            SkillsRegistriesConfig `                                   json:"registries"`
@@ -684,6 +984,22 @@ type MediaCleanupConfig struct {
 	Interval   int `                                    env:"PICOCLAW_MEDIA_CLEANUP_INTERVAL" json:"interval_minutes"`
 }
 
+// HistoryToolConfig configures the search_history tool. Scope limits what
+// a search can see: the current session (default), every conversation with
+// the current sender on the current channel, or all of the agent's
+// conversations, which also exposes other users' chats.
+type HistoryToolConfig struct {
+	ToolConfig `       envPrefix:"PICOCLAW_TOOLS_SEARCH_HISTORY_"`
+	Scope      string `                                           env:"PICOCLAW_TOOLS_SEARCH_HISTORY_SCOPE" json:"scope,omitempty"`
+}
+
+// search_history scopes.
+const (
+	SearchHistoryScopeSession = "session"
+	SearchHistoryScopeSender  = "sender"
+	SearchHistoryScopeAll     = "all"
+)
+
 type ReadFileToolConfig struct {
 	Enabled         bool `json:"enabled"`
 	MaxReadFileSize int  `json:"max_read_file_size"`
@@ -698,6 +1014,7 @@ type ToolsConfig struct {
 	Skills          SkillsToolsConfig  `json:"skills"`
 	MediaCleanup    MediaCleanupConfig `json:"media_cleanup"`
 	MCP             MCPConfig          `json:"mcp"`
+	Approval        ApprovalConfig     `json:"approval"`
 	GenerateImage   ImageToolConfig    `json:"generate_image"                                           envPrefix:"PICOCLAW_TOOLS_GENERATE_IMAGE_"`
 	AppendFile      ToolConfig         `json:"append_file"                                              envPrefix:"PICOCLAW_TOOLS_APPEND_FILE_"`
 	EditFile        ToolConfig         `json:"edit_file"                                                envPrefix:"PICOCLAW_TOOLS_EDIT_FILE_"`
@@ -707,6 +1024,9 @@ type ToolsConfig struct {
 	ListDir         ToolConfig         `json:"list_dir"                                                 envPrefix:"PICOCLAW_TOOLS_LIST_DIR_"`
 	Message         ToolConfig         `json:"message"                                                  envPrefix:"PICOCLAW_TOOLS_MESSAGE_"`
 	ReadFile        ReadFileToolConfig `json:"read_file"                                                envPrefix:"PICOCLAW_TOOLS_READ_FILE_"`
+	Recall          ToolConfig         `json:"recall"                                                   envPrefix:"PICOCLAW_TOOLS_RECALL_"`
+	Remember        ToolConfig         `json:"remember"                                                 envPrefix:"PICOCLAW_TOOLS_REMEMBER_"`
+	SearchHistory   HistoryToolConfig  `json:"search_history"                                           envPrefix:"PICOCLAW_TOOLS_SEARCH_HISTORY_"`
 	SendFile        ToolConfig         `json:"send_file"                                                envPrefix:"PICOCLAW_TOOLS_SEND_FILE_"`
 	Spawn           ToolConfig         `json:"spawn"                                                    envPrefix:"PICOCLAW_TOOLS_SPAWN_"`
 	SPI             ToolConfig         `json:"spi"                                                      envPrefix:"PICOCLAW_TOOLS_SPI_"`
@@ -809,6 +1129,9 @@ func LoadConfig(path string) (*Config, error) {
 	if err := cfg.ValidateModelList(); err != nil {
 		return nil, err
 	}
+	if err := cfg.ValidateBudgets(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -946,6 +1269,38 @@ func (c *Config) ValidateModelList() error {
 	return nil
 }
 
+// ValidateBudgets rejects cost budgets whose currency differs from the
+// pricing currency of a model in model_list: costs in different currencies
+// cannot be added up against one limit.
+func (c *Config) ValidateBudgets() error {
+	for i, agent := range c.Agents.List {
+		for j, b := range agent.Budgets {
+			if b.SoftCost <= 0 && b.HardCost <= 0 {
+				continue
+			}
+			currency := b.Currency
+			if currency == "" {
+				currency = DefaultCurrency
+			}
+			for _, mc := range c.ModelList {
+				if mc.Pricing == nil {
+					continue
+				}
+				priced := mc.Pricing.Currency
+				if priced == "" {
+					priced = DefaultCurrency
+				}
+				if priced != currency {
+					return fmt.Errorf(
+						"agents.list[%d].budgets[%d]: cost limit is in %s but model %q is priced in %s",
+						i, j, currency, mc.ModelName, priced)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (t *ToolsConfig) IsToolEnabled(name string) bool {
 	switch name {
 	case "web":
@@ -974,6 +1329,12 @@ func (t *ToolsConfig) IsToolEnabled(name string) bool {
 		return t.Message.Enabled
 	case "read_file":
 		return t.ReadFile.Enabled
+	case "recall":
+		return t.Recall.Enabled
+	case "remember":
+		return t.Remember.Enabled
+	case "search_history":
+		return t.SearchHistory.Enabled
 	case "spawn":
 		return t.Spawn.Enabled
 	case "spi":
@@ -175,6 +175,75 @@ func DefaultConfig() *Config {
 				MaxConnections: 100,
 				AllowFrom:      FlexibleStringSlice{},
 			},
+			Email: EmailConfig{
+				Enabled:      false,
+				IMAPTLS:      true,
+				SMTPTLS:      true,
+				Mailbox:      "INBOX",
+				PollInterval: 60,
+				AllowFrom:    FlexibleStringSlice{},
+			},
+			Webhook: WebhookConfig{
+				Enabled:         false,
+				WebhookPath:     "/webhook/generic",
+				SignatureHeader: "X-Hub-Signature-256",
+				AllowFrom:       FlexibleStringSlice{},
+			},
+			MQTT: MQTTConfig{
+				Enabled:       false,
+				Broker:        "tcp://localhost:1883",
+				Topics:        FlexibleStringSlice{"picoclaw/+/in"},
+				ReplyTopic:    "picoclaw/{chat_id}/out",
+				QoS:           1,
+				PayloadFormat: "text",
+				KeepAlive:     60,
+				AllowFrom:     FlexibleStringSlice{},
+			},
+			Mattermost: MattermostConfig{
+				Enabled:   false,
+				AllowFrom: FlexibleStringSlice{},
+				Typing:    TypingConfig{Enabled: true},
+				GroupTrigger: GroupTriggerConfig{
+					MentionOnly: true,
+				},
+				Placeholder: PlaceholderConfig{
+					Enabled: true,
+					Text:    "Thinking... 💭",
+				},
+			},
+			RocketChat: RocketChatConfig{
+				Enabled:   false,
+				AllowFrom: FlexibleStringSlice{},
+				Typing:    TypingConfig{Enabled: true},
+				GroupTrigger: GroupTriggerConfig{
+					MentionOnly: true,
+				},
+				Placeholder: PlaceholderConfig{
+					Enabled: true,
+					Text:    "Thinking... 💭",
+				},
+			},
+			XMPP: XMPPConfig{
+				Enabled:   false,
+				Resource:  "picoclaw",
+				Rooms:     FlexibleStringSlice{},
+				AllowFrom: FlexibleStringSlice{},
+				Typing:    TypingConfig{Enabled: true},
+				GroupTrigger: GroupTriggerConfig{
+					MentionOnly: true,
+				},
+			},
+			Limits: InboundLimitsConfig{
+				Enabled:            true,
+				PerSender:          RateLimitConfig{PerMinute: 20, Burst: 8},
+				PerChat:            RateLimitConfig{PerMinute: 60, Burst: 20},
+				MaxMessageLength:   20000,
+				FloodStrikes:       10,
+				FloodWindowSeconds: 60,
+				BanMinutes:         10,
+				ThrottleReply:      "You're sending messages too quickly. Please wait a moment before trying again.",
+				TooLongReply:       "Your message is too long for me to process. Please shorten it and try again.",
+			},
 		},
 		Providers: ProvidersConfig{
 			OpenAI: OpenAIProviderConfig{WebSearch: true},
@@ -366,6 +435,10 @@ func DefaultConfig() *Config {
 		Gateway: GatewayConfig{
 			Host: "127.0.0.1",
 			Port: 18790,
+			Outbox: OutboxConfig{
+				Enabled:     true,
+				MaxAttempts: 10,
+			},
 		},
 		Tools: ToolsConfig{
 			MediaCleanup: MediaCleanupConfig{
@@ -421,6 +494,14 @@ func DefaultConfig() *Config {
 				EnableDenyPatterns: true,
 				TimeoutSeconds:     60,
 			},
+			Approval: ApprovalConfig{
+				Enabled:        false,
+				TimeoutSeconds: 60,
+				Rules: []ApprovalRule{
+					{Tool: "exec", Arg: "command", Pattern: `\b(rm|rmdir|dd|mkfs|shutdown|reboot|kill|pkill)\b`},
+					{Tool: "write_file", Arg: "path", AllowPaths: []string{"."}},
+				},
+			},
 			Skills: SkillsToolsConfig{
 				ToolConfig: ToolConfig{
 					Enabled: true,
@@ -483,6 +564,18 @@ func DefaultConfig() *Config {
 				Enabled:         true,
 				MaxReadFileSize: 64 * 1024, // 64KB
 			},
+			Recall: ToolConfig{
+				Enabled: true,
+			},
+			Remember: ToolConfig{
+				Enabled: true,
+			},
+			SearchHistory: HistoryToolConfig{
+				ToolConfig: ToolConfig{
+					Enabled: false,
+				},
+				Scope: SearchHistoryScopeSession,
+			},
 			Spawn: ToolConfig{
 				Enabled: true,
 			},
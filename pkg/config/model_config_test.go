@@ -398,3 +398,30 @@ func TestModelConfig_RequestTimeoutDefaultZeroValue(t *testing.T) {
 		t.Fatalf("RequestTimeout = %d, want 0", cfg.RequestTimeout)
 	}
 }
+
+func TestConfig_ValidateBudgets(t *testing.T) {
+	cfg := &Config{
+		ModelList: []ModelConfig{
+			{ModelName: "gpt", Model: "openai/gpt", Pricing: &ModelPricing{Input: 1, Output: 2}},
+			{ModelName: "glm", Model: "zhipu/glm", Pricing: &ModelPricing{Input: 1, Output: 2, Currency: "CNY"}},
+		},
+		Agents: AgentsConfig{List: []AgentConfig{{
+			ID:      "main",
+			Budgets: []BudgetConfig{{Scope: "agent", Period: "daily", HardTokens: 1000}},
+		}}},
+	}
+	if err := cfg.ValidateBudgets(); err != nil {
+		t.Fatalf("token budgets must not care about currencies: %v", err)
+	}
+
+	cfg.Agents.List[0].Budgets[0].HardCost = 5
+	err := cfg.ValidateBudgets()
+	if err == nil || !strings.Contains(err.Error(), `model "glm" is priced in CNY`) {
+		t.Fatalf("ValidateBudgets() error = %v, want mixed currency error", err)
+	}
+
+	cfg.ModelList[1].Pricing.Currency = "USD"
+	if err := cfg.ValidateBudgets(); err != nil {
+		t.Fatalf("ValidateBudgets() error = %v", err)
+	}
+}
@@ -29,6 +29,7 @@ type CronPayload struct {
 	Command    string          `json:"command,omitempty"`
 	Deliver    bool            `json:"deliver"`
 	Channel    string          `json:"channel,omitempty"`
+	AccountID  string          `json:"account_id,omitempty"`
 	To         string          `json:"to,omitempty"`
 	SenderID   string          `json:"sender_id,omitempty"`
 	Delegation *CronDelegation `json:"delegation,omitempty"`
@@ -15,6 +15,7 @@ type Server struct {
 	mu        sync.RWMutex
 	ready     bool
 	checks    map[string]Check
+	details   map[string]func() any
 	startTime time.Time
 }
 
@@ -26,9 +27,10 @@ type Check struct {
 }
 
 type StatusResponse struct {
-	Status string           `json:"status"`
-	Uptime string           `json:"uptime"`
-	Checks map[string]Check `json:"checks,omitempty"`
+	Status  string           `json:"status"`
+	Uptime  string           `json:"uptime"`
+	Checks  map[string]Check `json:"checks,omitempty"`
+	Details map[string]any   `json:"details,omitempty"`
 }
 
 func NewServer(host string, port int) *Server {
@@ -36,6 +38,7 @@ func NewServer(host string, port int) *Server {
 	s := &Server{
 		ready:     false,
 		checks:    make(map[string]Check),
+		details:   make(map[string]func() any),
 		startTime: time.Now(),
 	}
 
@@ -104,6 +107,14 @@ func (s *Server) RegisterCheck(name string, checkFn func() (bool, string)) {
 	}
 }
 
+// RegisterDetail adds a section to the /health response. Unlike checks,
+// fn is evaluated on every request, so it should be cheap.
+func (s *Server) RegisterDetail(name string, fn func() any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.details[name] = fn
+}
+
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -114,6 +125,16 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 		Uptime: uptime.String(),
 	}
 
+	s.mu.RLock()
+	fns := maps.Clone(s.details)
+	s.mu.RUnlock()
+	if len(fns) > 0 {
+		resp.Details = make(map[string]any, len(fns))
+		for name, fn := range fns {
+			resp.Details[name] = fn()
+		}
+	}
+
 	json.NewEncoder(w).Encode(resp)
 }
 
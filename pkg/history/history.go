@@ -0,0 +1,431 @@
+// Package history keeps a searchable record of every conversation turn
+// across all sessions, agents and channels.
+//
+// Sessions only store the messages the model needs, keyed by session key,
+// and lose them on truncation or summarization. The history index is an
+// append-only JSONL log in the workspace that also records who said what,
+// where and when, so past conversations can be searched with filters.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/fileutil"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Entry is one indexed message.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	AgentID    string    `json:"agent_id"`
+	SessionKey string    `json:"session_key"`
+	Channel    string    `json:"channel,omitempty"`
+	ChatID     string    `json:"chat_id,omitempty"`
+	SenderID   string    `json:"sender_id,omitempty"`
+	Role       string    `json:"role"`
+	Content    string    `json:"content"`
+}
+
+// Filter selects entries. Zero-valued fields match everything.
+type Filter struct {
+	AgentID    string
+	SessionKey string
+	Channel    string
+	SenderID   string
+	Role       string
+	Since      time.Time
+	Until      time.Time
+}
+
+// Match reports whether e satisfies the filter.
+func (f Filter) Match(e Entry) bool {
+	if f.AgentID != "" && e.AgentID != f.AgentID {
+		return false
+	}
+	if f.SessionKey != "" && e.SessionKey != f.SessionKey {
+		return false
+	}
+	if f.Channel != "" && e.Channel != f.Channel {
+		return false
+	}
+	if f.SenderID != "" && e.SenderID != f.SenderID {
+		return false
+	}
+	if f.Role != "" && e.Role != f.Role {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Result is a search hit.
+type Result struct {
+	Entry
+	Score float64 `json:"score"`
+}
+
+// Index is the append-only history log of a workspace.
+type Index struct {
+	path string
+	mu   sync.Mutex
+}
+
+// IndexPath returns the location of the history index for a workspace.
+func IndexPath(workspace string) string {
+	return filepath.Join(workspace, "history", "messages.jsonl")
+}
+
+// NewIndex opens the history index of the given workspace.
+func NewIndex(workspace string) *Index {
+	return &Index{path: IndexPath(workspace)}
+}
+
+// Path returns the index file path.
+func (idx *Index) Path() string {
+	return idx.path
+}
+
+// Append writes entries to the index. Entries with empty content are
+// skipped; a zero Time is set to now.
+func (idx *Index) Append(entries ...Entry) error {
+	var buf []byte
+	now := time.Now()
+	for _, e := range entries {
+		if strings.TrimSpace(e.Content) == "" {
+			continue
+		}
+		if e.Time.IsZero() {
+			e.Time = now
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(idx.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history index: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(buf); err != nil {
+		return fmt.Errorf("failed to write history entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns all entries matching f, oldest first. A missing index
+// yields no entries. Malformed lines are skipped.
+func (idx *Index) Entries(f Filter) ([]Entry, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	file, err := os.Open(idx.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history index: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			logger.WarnCF("history", "Skipping malformed history entry", map[string]any{
+				"line":  lineNo,
+				"error": err.Error(),
+			})
+			continue
+		}
+		if f.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history index: %w", err)
+	}
+	return entries, nil
+}
+
+// SessionKeys returns the keys of agentID's sessions that have at least one
+// entry.
+func (idx *Index) SessionKeys(agentID string) (map[string]bool, error) {
+	entries, err := idx.Entries(Filter{AgentID: agentID})
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for _, e := range entries {
+		keys[e.SessionKey] = true
+	}
+	return keys, nil
+}
+
+// RemoveSession deletes every entry of agentID's session sessionKey by
+// rewriting the index, and returns how many were removed. Malformed lines
+// are kept as they are.
+func (idx *Index) RemoveSession(agentID, sessionKey string) (int, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	lines, err := idx.readLines()
+	if err != nil {
+		return 0, err
+	}
+	return idx.removeLines(lines, func(i int, e Entry) bool {
+		return e.AgentID == agentID && e.SessionKey == sessionKey
+	})
+}
+
+// RewindSession deletes the entries of the last turns user turns of
+// agentID's session sessionKey, as /rewind does with the session itself,
+// and returns how many entries were removed.
+func (idx *Index) RewindSession(agentID, sessionKey string, turns int) (int, error) {
+	if turns <= 0 {
+		return 0, nil
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	lines, err := idx.readLines()
+	if err != nil {
+		return 0, err
+	}
+	inSession := func(e Entry) bool { return e.AgentID == agentID && e.SessionKey == sessionKey }
+	cut, found := len(lines), 0
+	for i := len(lines) - 1; i >= 0 && found < turns; i-- {
+		if e, ok := lines[i].entry(); ok && inSession(e) && e.Role == "user" {
+			cut = i
+			found++
+		}
+	}
+	return idx.removeLines(lines, func(i int, e Entry) bool {
+		return i >= cut && inSession(e)
+	})
+}
+
+// indexLine is one raw line of the index file.
+type indexLine []byte
+
+func (l indexLine) entry() (Entry, bool) {
+	var e Entry
+	return e, json.Unmarshal(l, &e) == nil
+}
+
+// readLines returns the non-empty lines of the index. The caller must hold
+// idx.mu.
+func (idx *Index) readLines() ([]indexLine, error) {
+	data, err := os.ReadFile(idx.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history index: %w", err)
+	}
+	var lines []indexLine
+	for _, line := range bytes.SplitAfter(data, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// removeLines rewrites the index without the lines whose entry matches
+// remove and returns how many were dropped. Malformed lines are kept. The
+// caller must hold idx.mu.
+func (idx *Index) removeLines(lines []indexLine, remove func(i int, e Entry) bool) (int, error) {
+	var kept bytes.Buffer
+	removed := 0
+	for i, line := range lines {
+		if e, ok := line.entry(); ok && remove(i, e) {
+			removed++
+			continue
+		}
+		kept.Write(line)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := fileutil.WriteFileAtomic(idx.path, kept.Bytes(), 0o644); err != nil {
+		return 0, fmt.Errorf("failed to rewrite history index: %w", err)
+	}
+	return removed, nil
+}
+
+// backfillPath is the file recording which sessions have been backfilled.
+func (idx *Index) backfillPath() string {
+	return filepath.Join(filepath.Dir(idx.path), "backfilled.json")
+}
+
+// BackfilledSessions returns the session keys of agentID recorded with
+// SetBackfilledSessions. A missing or unreadable record yields none.
+func (idx *Index) BackfilledSessions(agentID string) map[string]bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	record := idx.readBackfillRecord()
+	keys := make(map[string]bool, len(record[agentID]))
+	for _, key := range record[agentID] {
+		keys[key] = true
+	}
+	return keys
+}
+
+// SetBackfilledSessions records that the sessions keys of agentID are in the
+// index, so later backfills do not have to load them again.
+func (idx *Index) SetBackfilledSessions(agentID string, keys []string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	record := idx.readBackfillRecord()
+	sorted := slices.Clone(keys)
+	slices.Sort(sorted)
+	record[agentID] = sorted
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backfill record: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := fileutil.WriteFileAtomic(idx.backfillPath(), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write backfill record: %w", err)
+	}
+	return nil
+}
+
+// readBackfillRecord returns the backfilled session keys by agent. The
+// caller must hold idx.mu.
+func (idx *Index) readBackfillRecord() map[string][]string {
+	record := make(map[string][]string)
+	data, err := os.ReadFile(idx.backfillPath())
+	if err != nil {
+		return record
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		logger.WarnCF("history", "Ignoring corrupt backfill record", map[string]any{"error": err.Error()})
+		return make(map[string][]string)
+	}
+	if record == nil {
+		record = make(map[string][]string)
+	}
+	return record
+}
+
+// Search ranks the entries matching f against query with BM25 and returns
+// at most limit results, best first. An empty query returns the most recent
+// matching entries instead, newest first.
+func (idx *Index) Search(query string, f Filter, limit int) ([]Result, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	entries, err := idx.Entries(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(query) == "" {
+		results := make([]Result, 0, min(limit, len(entries)))
+		for i := len(entries) - 1; i >= 0 && len(results) < limit; i-- {
+			results = append(results, Result{Entry: entries[i]})
+		}
+		return results, nil
+	}
+
+	engine := utils.NewBM25Engine(entries, func(e Entry) string { return e.Content })
+	hits := engine.Search(query, limit)
+	results := make([]Result, 0, len(hits))
+	for _, h := range hits {
+		results = append(results, Result{Entry: h.Document, Score: float64(h.Score)})
+	}
+	// Equal scores keep the most recent message first.
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Time.After(results[j].Time)
+	})
+	return results, nil
+}
+
+// ParseRange parses optional since/until bounds. Each accepts a calendar
+// date (2006-01-02), an RFC 3339 timestamp, a number of days ago (7d) or a
+// Go duration ago (36h). A bare date as until includes that whole day.
+func ParseRange(since, until string, now time.Time) (time.Time, time.Time, error) {
+	s, _, err := parseTime(since, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid since: %w", err)
+	}
+	u, dateOnly, err := parseTime(until, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid until: %w", err)
+	}
+	if dateOnly {
+		u = u.AddDate(0, 0, 1)
+	}
+	return s, u, nil
+}
+
+func parseTime(value string, now time.Time) (t time.Time, dateOnly bool, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), false, nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%q: use a date (2006-01-02), RFC 3339, days (7d) or a duration (24h)", value)
+}
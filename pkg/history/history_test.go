@@ -0,0 +1,166 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndex_SearchWithFilters(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	day := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: day, AgentID: "main", SessionKey: "a", Channel: "telegram", SenderID: "alice", Role: "user", Content: "the printer on floor two is jammed"},
+		{Time: day.Add(time.Minute), AgentID: "main", SessionKey: "a", Channel: "telegram", SenderID: "alice", Role: "assistant", Content: "I opened a ticket for the printer"},
+		{Time: day.Add(24 * time.Hour), AgentID: "main", SessionKey: "b", Channel: "discord", SenderID: "bob", Role: "user", Content: "printer ink is low"},
+		{Time: day.Add(48 * time.Hour), AgentID: "helper", SessionKey: "c", Channel: "telegram", SenderID: "alice", Role: "user", Content: "printer again"},
+		{Time: day, AgentID: "main", SessionKey: "a", Role: "user", Content: "   "},
+	}
+	if err := idx.Append(entries...); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	all, err := idx.Entries(Filter{})
+	if err != nil {
+		t.Fatalf("Entries() error: %v", err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("expected blank content to be skipped, got %d entries", len(all))
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		filter Filter
+		want   []string // session keys in order
+	}{
+		{"agent filter", "printer", Filter{AgentID: "helper"}, []string{"c"}},
+		{"channel filter", "printer", Filter{Channel: "discord"}, []string{"b"}},
+		{"sender and role", "printer", Filter{SenderID: "alice", Role: "user", AgentID: "main"}, []string{"a"}},
+		{"date range", "printer", Filter{Since: day.Add(12 * time.Hour), Until: day.Add(36 * time.Hour)}, []string{"b"}},
+		{"no match", "scanner", Filter{}, nil},
+		{"empty query lists newest first", "", Filter{AgentID: "main"}, []string{"b", "a", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := idx.Search(tt.query, tt.filter, 10)
+			if err != nil {
+				t.Fatalf("Search() error: %v", err)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results %+v, want %v", len(results), results, tt.want)
+			}
+			for i, r := range results {
+				if r.SessionKey != tt.want[i] {
+					t.Errorf("result %d session = %q, want %q", i, r.SessionKey, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIndex_RemoveSession(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	if err := idx.Append(
+		Entry{AgentID: "main", SessionKey: "a", Role: "user", Content: "forget me"},
+		Entry{AgentID: "main", SessionKey: "b", Role: "user", Content: "keep me"},
+		Entry{AgentID: "helper", SessionKey: "a", Role: "user", Content: "other agent"},
+		Entry{AgentID: "main", SessionKey: "a", Role: "assistant", Content: "forgotten"},
+	); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	removed, err := idx.RemoveSession("main", "a")
+	if err != nil {
+		t.Fatalf("RemoveSession() error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed = %d, want 2", removed)
+	}
+
+	keys, err := idx.SessionKeys("main")
+	if err != nil {
+		t.Fatalf("SessionKeys() error: %v", err)
+	}
+	if len(keys) != 1 || !keys["b"] {
+		t.Errorf("main sessions = %v, want only b", keys)
+	}
+	if keys, _ := idx.SessionKeys("helper"); !keys["a"] {
+		t.Errorf("helper session a was removed")
+	}
+}
+
+func TestIndex_RewindSession(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	if err := idx.Append(
+		Entry{AgentID: "main", SessionKey: "a", Role: "user", Content: "first"},
+		Entry{AgentID: "main", SessionKey: "a", Role: "assistant", Content: "first reply"},
+		Entry{AgentID: "main", SessionKey: "a", Role: "user", Content: "second"},
+		Entry{AgentID: "main", SessionKey: "b", Role: "user", Content: "other session"},
+		Entry{AgentID: "main", SessionKey: "a", Role: "assistant", Content: "second reply"},
+	); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	removed, err := idx.RewindSession("main", "a", 1)
+	if err != nil {
+		t.Fatalf("RewindSession() error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed = %d, want 2", removed)
+	}
+	entries, err := idx.Entries(Filter{})
+	if err != nil {
+		t.Fatalf("Entries() error: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Content)
+	}
+	if strings.Join(got, "|") != "first|first reply|other session" {
+		t.Errorf("entries after rewind = %q", got)
+	}
+}
+
+func TestIndex_BackfilledSessions(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	if got := idx.BackfilledSessions("main"); len(got) != 0 {
+		t.Fatalf("BackfilledSessions() before any record = %v", got)
+	}
+	if err := idx.SetBackfilledSessions("main", []string{"b", "a"}); err != nil {
+		t.Fatalf("SetBackfilledSessions() error: %v", err)
+	}
+	if err := idx.SetBackfilledSessions("helper", []string{"c"}); err != nil {
+		t.Fatalf("SetBackfilledSessions() error: %v", err)
+	}
+	if got := idx.BackfilledSessions("main"); len(got) != 2 || !got["a"] || !got["b"] {
+		t.Errorf("main backfilled = %v, want a and b", got)
+	}
+	if got := idx.BackfilledSessions("helper"); len(got) != 1 || !got["c"] {
+		t.Errorf("helper backfilled = %v, want c", got)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	since, until, err := ParseRange("7d", "2026-03-09", now)
+	if err != nil {
+		t.Fatalf("ParseRange() error: %v", err)
+	}
+	if !since.Equal(now.AddDate(0, 0, -7)) {
+		t.Errorf("since = %v", since)
+	}
+	if !until.Equal(time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("until = %v, want end of 2026-03-09", until)
+	}
+
+	since, until, err = ParseRange("", "2h", now)
+	if err != nil || !since.IsZero() || !until.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("ParseRange(\"\", 2h) = %v, %v, %v", since, until, err)
+	}
+
+	if _, _, err := ParseRange("last week", "", now); err == nil {
+		t.Error("expected error for invalid since")
+	}
+}
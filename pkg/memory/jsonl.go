@@ -99,9 +99,22 @@ func sanitizeKey(key string) string {
 // readMeta loads the metadata file for a session.
 // Returns a zero-value sessionMeta if the file does not exist.
 func (s *JSONLStore) readMeta(key string) (sessionMeta, error) {
-	data, err := os.ReadFile(s.metaPath(key))
+	meta, err := readMetaFile(s.metaPath(key))
+	if err != nil {
+		return sessionMeta{}, err
+	}
+	if meta.Key == "" {
+		meta.Key = key
+	}
+	return meta, nil
+}
+
+// readMetaFile decodes a .meta.json file. A missing file yields a
+// zero-value sessionMeta.
+func readMetaFile(path string) (sessionMeta, error) {
+	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return sessionMeta{Key: key}, nil
+		return sessionMeta{}, nil
 	}
 	if err != nil {
 		return sessionMeta{}, fmt.Errorf("memory: read meta: %w", err)
@@ -393,6 +406,62 @@ func (s *JSONLStore) SetHistory(
 	return s.rewriteJSONL(sessionKey, history)
 }
 
+// ListSessions reads the metadata file of every session in the store
+// directory. Metadata files that cannot be read are skipped.
+func (s *JSONLStore) ListSessions(_ context.Context) ([]SessionInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("memory: read sessions dir: %w", err)
+	}
+
+	sessions := []SessionInfo{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".meta.json") {
+			continue
+		}
+		meta, err := readMetaFile(filepath.Join(s.dir, name))
+		if err != nil {
+			log.Printf("memory: list sessions: skip %s: %v", name, err)
+			continue
+		}
+		if meta.Key == "" {
+			meta.Key = strings.TrimSuffix(name, ".meta.json")
+		}
+		sessions = append(sessions, SessionInfo{
+			Key:       meta.Key,
+			CreatedAt: meta.CreatedAt,
+			UpdatedAt: meta.UpdatedAt,
+		})
+	}
+	return sessions, nil
+}
+
+// DeleteSession removes the session's .jsonl and .meta.json files.
+func (s *JSONLStore) DeleteSession(
+	_ context.Context, sessionKey string,
+) error {
+	l := s.sessionLock(sessionKey)
+	l.Lock()
+	defer l.Unlock()
+
+	found := false
+	for _, path := range []string{s.jsonlPath(sessionKey), s.metaPath(sessionKey)} {
+		err := os.Remove(path)
+		if err == nil {
+			found = true
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("memory: delete session: %w", err)
+		}
+	}
+	if !found {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
 // Compact physically rewrites the JSONL file, dropping all logically
 // skipped lines. This reclaims disk space that accumulates after
 // repeated TruncateHistory calls.
@@ -48,8 +48,9 @@ func MigrateFromJSON(
 		if !strings.HasSuffix(name, ".json") {
 			continue
 		}
-		// Skip already-migrated files.
-		if strings.HasSuffix(name, ".migrated") {
+		// Skip already-migrated files and JSONLStore metadata files,
+		// which share the sessions directory.
+		if strings.HasSuffix(name, ".migrated") || strings.HasSuffix(name, ".meta.json") {
 			continue
 		}
 
@@ -106,3 +107,75 @@ func MigrateFromJSON(
 
 	return migrated, nil
 }
+
+// MigrateFromJSONL copies every session stored by a JSONLStore in dir into
+// store, then renames the session's .jsonl and .meta.json files to
+// .migrated as a backup. Returns the number of sessions migrated.
+//
+// Session keys are read from the metadata files, since JSONL filenames
+// are sanitized. A .jsonl file without metadata falls back to its
+// filename as the key. Like MigrateFromJSON, the import replaces the
+// target history, so re-running after a crash does not duplicate data.
+func MigrateFromJSONL(
+	ctx context.Context, dir string, store Store,
+) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("memory: read sessions dir: %w", err)
+	}
+
+	migrated := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		base := strings.TrimSuffix(name, ".jsonl")
+		jsonlPath := filepath.Join(dir, name)
+		metaPath := filepath.Join(dir, base+".meta.json")
+
+		key := base
+		meta, metaErr := readMetaFile(metaPath)
+		if metaErr != nil {
+			log.Printf("memory: migrate: skip %s: %v", name, metaErr)
+			continue
+		}
+		if meta.Key != "" {
+			key = meta.Key
+		}
+
+		history, readErr := readMessages(jsonlPath, meta.Skip)
+		if readErr != nil {
+			log.Printf("memory: migrate: skip %s: %v", name, readErr)
+			continue
+		}
+		if setErr := store.SetHistory(ctx, key, history); setErr != nil {
+			return migrated, fmt.Errorf(
+				"memory: migrate %s: set history: %w",
+				name, setErr,
+			)
+		}
+		if meta.Summary != "" {
+			if sumErr := store.SetSummary(ctx, key, meta.Summary); sumErr != nil {
+				return migrated, fmt.Errorf(
+					"memory: migrate %s: set summary: %w",
+					name, sumErr,
+				)
+			}
+		}
+
+		for _, path := range []string{jsonlPath, metaPath} {
+			renameErr := os.Rename(path, path+".migrated")
+			if renameErr != nil && !os.IsNotExist(renameErr) {
+				log.Printf("memory: migrate: rename %s: %v", filepath.Base(path), renameErr)
+			}
+		}
+
+		migrated++
+	}
+
+	return migrated, nil
+}
@@ -382,3 +382,80 @@ func TestMigrateFromJSON_NonexistentDir(t *testing.T) {
 		t.Errorf("expected 0, got %d", count)
 	}
 }
+
+func TestMigrateFromJSONL_Basic(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	src, err := NewJSONLStore(dir)
+	if err != nil {
+		t.Fatalf("NewJSONLStore: %v", err)
+	}
+	for _, content := range []string{"old", "kept1", "kept2"} {
+		if err := src.AddMessage(ctx, "telegram:42", "user", content); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+	}
+	if err := src.TruncateHistory(ctx, "telegram:42", 2); err != nil {
+		t.Fatalf("TruncateHistory: %v", err)
+	}
+	if err := src.SetSummary(ctx, "telegram:42", "sum"); err != nil {
+		t.Fatalf("SetSummary: %v", err)
+	}
+
+	dst, err := NewSQLiteStore(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer dst.Close()
+
+	count, err := MigrateFromJSONL(ctx, dir, dst)
+	if err != nil {
+		t.Fatalf("MigrateFromJSONL: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 migrated, got %d", count)
+	}
+
+	// The original key (with colon) is restored from the metadata and
+	// logically truncated messages are not imported.
+	history, _ := dst.GetHistory(ctx, "telegram:42")
+	if len(history) != 2 || history[0].Content != "kept1" {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	if summary, _ := dst.GetSummary(ctx, "telegram:42"); summary != "sum" {
+		t.Errorf("summary = %q, want sum", summary)
+	}
+
+	for _, name := range []string{"telegram_42.jsonl", "telegram_42.meta.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name+".migrated")); err != nil {
+			t.Errorf("expected %s to be renamed: %v", name, err)
+		}
+	}
+
+	count, err = MigrateFromJSONL(ctx, dir, dst)
+	if err != nil || count != 0 {
+		t.Fatalf("second run = %d, %v; want 0, nil", count, err)
+	}
+}
+
+func TestMigrateFromJSON_SkipsJSONLMetadata(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	src, err := NewJSONLStore(dir)
+	if err != nil {
+		t.Fatalf("NewJSONLStore: %v", err)
+	}
+	if err := src.AddMessage(ctx, "s", "user", "hi"); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+
+	count, err := MigrateFromJSON(ctx, dir, newTestStore(t))
+	if err != nil {
+		t.Fatalf("MigrateFromJSON: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected .meta.json files to be ignored, migrated %d", count)
+	}
+}
@@ -0,0 +1,298 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+const sqliteDriver = "sqlite"
+
+// sqliteSchema creates the session and message tables. Messages are stored
+// as the full JSON-encoded providers.Message; role and content are kept in
+// their own columns so they can be queried without decoding.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	key        TEXT PRIMARY KEY,
+	summary    TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS messages (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_key TEXT NOT NULL,
+	role        TEXT NOT NULL,
+	content     TEXT NOT NULL,
+	data        TEXT NOT NULL,
+	created_at  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_messages_session_key ON messages(session_key, id);
+`
+
+// SQLiteStore implements Store on top of a single SQLite database file.
+//
+// Unlike JSONLStore, truncation and history replacement delete rows
+// directly inside a transaction, so there is no dead data to compact and
+// no per-session metadata file.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if needed) the SQLite database at path.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("memory: create directory: %w", err)
+	}
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open(sqliteDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("memory: open sqlite: %w", err)
+	}
+	// SQLite serializes writers anyway; a single connection avoids
+	// SQLITE_BUSY errors between our own goroutines.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("memory: create schema: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// withTx runs fn inside a transaction, committing on success.
+func (s *SQLiteStore) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("memory: begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("memory: commit transaction: %w", err)
+	}
+	return nil
+}
+
+// touchSession creates the session row if needed and bumps updated_at.
+func touchSession(ctx context.Context, tx *sql.Tx, key string, now int64) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO sessions (key, created_at, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET updated_at = excluded.updated_at`,
+		key, now, now)
+	if err != nil {
+		return fmt.Errorf("memory: upsert session: %w", err)
+	}
+	return nil
+}
+
+// insertMessage appends one message row.
+func insertMessage(ctx context.Context, tx *sql.Tx, key string, msg providers.Message, now int64) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("memory: marshal message: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO messages (session_key, role, content, data, created_at) VALUES (?, ?, ?, ?, ?)`,
+		key, msg.Role, msg.Content, string(data), now)
+	if err != nil {
+		return fmt.Errorf("memory: insert message: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AddMessage(
+	ctx context.Context, sessionKey, role, content string,
+) error {
+	return s.AddFullMessage(ctx, sessionKey, providers.Message{
+		Role:    role,
+		Content: content,
+	})
+}
+
+func (s *SQLiteStore) AddFullMessage(
+	ctx context.Context, sessionKey string, msg providers.Message,
+) error {
+	now := time.Now().UnixNano()
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := touchSession(ctx, tx, sessionKey, now); err != nil {
+			return err
+		}
+		return insertMessage(ctx, tx, sessionKey, msg, now)
+	})
+}
+
+func (s *SQLiteStore) GetHistory(
+	ctx context.Context, sessionKey string,
+) ([]providers.Message, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT data FROM messages WHERE session_key = ? ORDER BY id`, sessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("memory: query history: %w", err)
+	}
+	defer rows.Close()
+
+	msgs := []providers.Message{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("memory: scan message: %w", err)
+		}
+		var msg providers.Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, fmt.Errorf("memory: decode message: %w", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("memory: read history: %w", err)
+	}
+	return msgs, nil
+}
+
+func (s *SQLiteStore) GetSummary(
+	ctx context.Context, sessionKey string,
+) (string, error) {
+	var summary string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT summary FROM sessions WHERE key = ?`, sessionKey).Scan(&summary)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("memory: query summary: %w", err)
+	}
+	return summary, nil
+}
+
+func (s *SQLiteStore) SetSummary(
+	ctx context.Context, sessionKey, summary string,
+) error {
+	now := time.Now().UnixNano()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO sessions (key, summary, created_at, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET summary = excluded.summary, updated_at = excluded.updated_at`,
+		sessionKey, summary, now, now)
+	if err != nil {
+		return fmt.Errorf("memory: set summary: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) TruncateHistory(
+	ctx context.Context, sessionKey string, keepLast int,
+) error {
+	now := time.Now().UnixNano()
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if keepLast <= 0 {
+			_, err = tx.ExecContext(ctx,
+				`DELETE FROM messages WHERE session_key = ?`, sessionKey)
+		} else {
+			_, err = tx.ExecContext(ctx,
+				`DELETE FROM messages WHERE session_key = ? AND id NOT IN (
+					SELECT id FROM messages WHERE session_key = ? ORDER BY id DESC LIMIT ?
+				)`,
+				sessionKey, sessionKey, keepLast)
+		}
+		if err != nil {
+			return fmt.Errorf("memory: truncate history: %w", err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE sessions SET updated_at = ? WHERE key = ?`, now, sessionKey)
+		if err != nil {
+			return fmt.Errorf("memory: update session: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) SetHistory(
+	ctx context.Context,
+	sessionKey string,
+	history []providers.Message,
+) error {
+	now := time.Now().UnixNano()
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := touchSession(ctx, tx, sessionKey, now); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM messages WHERE session_key = ?`, sessionKey); err != nil {
+			return fmt.Errorf("memory: clear history: %w", err)
+		}
+		for _, msg := range history {
+			if err := insertMessage(ctx, tx, sessionKey, msg, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, created_at, updated_at FROM sessions`)
+	if err != nil {
+		return nil, fmt.Errorf("memory: query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []SessionInfo{}
+	for rows.Next() {
+		var (
+			key              string
+			created, updated int64
+		)
+		if err := rows.Scan(&key, &created, &updated); err != nil {
+			return nil, fmt.Errorf("memory: scan session: %w", err)
+		}
+		sessions = append(sessions, SessionInfo{
+			Key:       key,
+			CreatedAt: time.Unix(0, created),
+			UpdatedAt: time.Unix(0, updated),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("memory: read sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (s *SQLiteStore) DeleteSession(ctx context.Context, sessionKey string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM messages WHERE session_key = ?`, sessionKey); err != nil {
+			return fmt.Errorf("memory: delete messages: %w", err)
+		}
+		res, err := tx.ExecContext(ctx,
+			`DELETE FROM sessions WHERE key = ?`, sessionKey)
+		if err != nil {
+			return fmt.Errorf("memory: delete session: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return ErrSessionNotFound
+		}
+		return nil
+	})
+}
+
+// Compact is a no-op: deleted rows are reclaimed by SQLite itself.
+func (s *SQLiteStore) Compact(_ context.Context, _ string) error {
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteStore_Roundtrip(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	history, err := store.GetHistory(ctx, "missing")
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if history == nil || len(history) != 0 {
+		t.Fatalf("expected empty non-nil history, got %#v", history)
+	}
+
+	if err := store.AddMessage(ctx, "telegram:1", "user", "hello"); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	err = store.AddFullMessage(ctx, "telegram:1", providers.Message{
+		Role: "assistant",
+		ToolCalls: []providers.ToolCall{{
+			ID:   "call_1",
+			Type: "function",
+			Function: &providers.FunctionCall{
+				Name:      "read_file",
+				Arguments: `{"path":"a.txt"}`,
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("AddFullMessage: %v", err)
+	}
+	if err := store.AddFullMessage(ctx, "telegram:1", providers.Message{
+		Role: "tool", Content: "contents", ToolCallID: "call_1",
+	}); err != nil {
+		t.Fatalf("AddFullMessage: %v", err)
+	}
+
+	history, err = store.GetHistory(ctx, "telegram:1")
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(history))
+	}
+	if history[0].Content != "hello" || history[2].ToolCallID != "call_1" {
+		t.Errorf("unexpected history: %+v", history)
+	}
+	if len(history[1].ToolCalls) != 1 || history[1].ToolCalls[0].Function == nil ||
+		history[1].ToolCalls[0].Function.Arguments != `{"path":"a.txt"}` {
+		t.Errorf("tool calls not preserved: %+v", history[1].ToolCalls)
+	}
+}
+
+func TestSQLiteStore_Summary(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	summary, err := store.GetSummary(ctx, "s")
+	if err != nil || summary != "" {
+		t.Fatalf("GetSummary on missing session = %q, %v", summary, err)
+	}
+	if err := store.SetSummary(ctx, "s", "first"); err != nil {
+		t.Fatalf("SetSummary: %v", err)
+	}
+	if err := store.AddMessage(ctx, "s", "user", "hi"); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	if err := store.SetSummary(ctx, "s", "second"); err != nil {
+		t.Fatalf("SetSummary: %v", err)
+	}
+	if summary, _ := store.GetSummary(ctx, "s"); summary != "second" {
+		t.Errorf("summary = %q, want second", summary)
+	}
+}
+
+func TestSQLiteStore_TruncateAndSetHistory(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if err := store.AddMessage(ctx, "s", "user", fmt.Sprintf("m%d", i)); err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+	}
+	if err := store.AddMessage(ctx, "other", "user", "keep"); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+
+	if err := store.TruncateHistory(ctx, "s", 2); err != nil {
+		t.Fatalf("TruncateHistory: %v", err)
+	}
+	history, _ := store.GetHistory(ctx, "s")
+	if len(history) != 2 || history[0].Content != "m3" || history[1].Content != "m4" {
+		t.Fatalf("after truncate: %+v", history)
+	}
+
+	if err := store.SetHistory(ctx, "s", []providers.Message{{Role: "user", Content: "new"}}); err != nil {
+		t.Fatalf("SetHistory: %v", err)
+	}
+	history, _ = store.GetHistory(ctx, "s")
+	if len(history) != 1 || history[0].Content != "new" {
+		t.Fatalf("after SetHistory: %+v", history)
+	}
+
+	if err := store.TruncateHistory(ctx, "s", 0); err != nil {
+		t.Fatalf("TruncateHistory(0): %v", err)
+	}
+	if history, _ = store.GetHistory(ctx, "s"); len(history) != 0 {
+		t.Fatalf("expected empty history, got %+v", history)
+	}
+	if history, _ = store.GetHistory(ctx, "other"); len(history) != 1 {
+		t.Fatalf("other session should be untouched, got %+v", history)
+	}
+	if err := store.Compact(ctx, "s"); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+}
+
+func TestSQLiteStore_PersistenceAcrossInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "sessions.db")
+	ctx := context.Background()
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	store.AddMessage(ctx, "s", "user", "persisted")
+	store.SetSummary(ctx, "s", "sum")
+	store.Close()
+
+	store, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	history, _ := store.GetHistory(ctx, "s")
+	if len(history) != 1 || history[0].Content != "persisted" {
+		t.Fatalf("history after reopen: %+v", history)
+	}
+	if summary, _ := store.GetSummary(ctx, "s"); summary != "sum" {
+		t.Errorf("summary after reopen = %q", summary)
+	}
+}
+
+func TestSQLiteStore_ConcurrentAdd(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := store.AddMessage(ctx, "s", "user", fmt.Sprintf("m%d", i)); err != nil {
+				t.Errorf("AddMessage: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	history, err := store.GetHistory(ctx, "s")
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(history) != 20 {
+		t.Errorf("expected 20 messages, got %d", len(history))
+	}
+}
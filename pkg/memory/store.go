@@ -2,10 +2,23 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
+// ErrSessionNotFound is returned by DeleteSession when the store holds no
+// session with the given key.
+var ErrSessionNotFound = errors.New("memory: session not found")
+
+// SessionInfo describes a stored session without loading its messages.
+type SessionInfo struct {
+	Key       string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
 // Store defines an interface for persistent session storage.
 // Each method is an atomic operation — there is no separate Save() call.
 type Store interface {
@@ -33,6 +46,13 @@ type Store interface {
 	// SetHistory replaces all messages in a session with the provided history.
 	SetHistory(ctx context.Context, sessionKey string, history []providers.Message) error
 
+	// ListSessions returns every stored session in no particular order.
+	ListSessions(ctx context.Context) ([]SessionInfo, error)
+
+	// DeleteSession removes a session with its messages and summary.
+	// Returns ErrSessionNotFound if the session does not exist.
+	DeleteSession(ctx context.Context, sessionKey string) error
+
 	// Compact reclaims storage by physically removing logically truncated
 	// data. Backends that do not accumulate dead data may return nil.
 	Compact(ctx context.Context, sessionKey string) error
@@ -0,0 +1,362 @@
+// Package outbox persists outbound channel messages until they are
+// delivered, so replies survive gateway restarts and platform outages.
+//
+// Every message the channel manager dispatches is written to a SQLite
+// database first and removed once the channel accepts it. Failed deliveries
+// are rescheduled with exponential backoff; messages that fail permanently
+// or exhaust their attempts are kept as dead letters until they are retried
+// or purged with `picoclaw outbox`.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteDriver = "sqlite"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS outbox (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind            TEXT NOT NULL,
+	channel         TEXT NOT NULL,
+	chat_id         TEXT NOT NULL,
+	payload         TEXT NOT NULL,
+	state           TEXT NOT NULL DEFAULT 'pending',
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	progress        INTEGER NOT NULL DEFAULT 0,
+	last_error      TEXT NOT NULL DEFAULT '',
+	next_attempt_at INTEGER NOT NULL,
+	created_at      INTEGER NOT NULL,
+	updated_at      INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox(state, next_attempt_at);
+`
+
+// Kind tells which bus message type an entry's payload holds.
+type Kind string
+
+const (
+	KindMessage Kind = "message" // bus.OutboundMessage
+	KindMedia   Kind = "media"   // bus.OutboundMediaMessage
+)
+
+// State is the delivery state of an entry.
+type State string
+
+const (
+	StatePending State = "pending"
+	StateDead    State = "dead"
+)
+
+const (
+	// DefaultMaxAttempts is used when the configured limit is not positive.
+	DefaultMaxAttempts = 10
+
+	baseBackoff = 30 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// ErrNotFound is returned when an entry does not exist.
+var ErrNotFound = errors.New("outbox: entry not found")
+
+// Entry is one persisted outbound message.
+type Entry struct {
+	ID      int64           `json:"id"`
+	Kind    Kind            `json:"kind"`
+	Channel string          `json:"channel"`
+	ChatID  string          `json:"chat_id"`
+	Payload json.RawMessage `json:"payload"`
+	State   State           `json:"state"`
+	// Attempts counts failed delivery rounds.
+	Attempts int `json:"attempts"`
+	// Progress is the number of parts of a split message already delivered,
+	// so a retry resumes where the last round stopped.
+	Progress      int       `json:"progress,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Decode unmarshals the entry payload into v.
+func (e Entry) Decode(v any) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("outbox: decode entry %d: %w", e.ID, err)
+	}
+	return nil
+}
+
+// Filter selects entries for List, Retry and Purge. Zero fields match everything.
+type Filter struct {
+	State   State
+	Channel string
+	IDs     []int64
+	Limit   int
+}
+
+// Stats summarizes the outbox for status reporting.
+type Stats struct {
+	Pending       int       `json:"pending"`
+	Dead          int       `json:"dead"`
+	OldestPending time.Time `json:"oldest_pending,omitzero"`
+}
+
+// Store is a SQLite-backed outbox.
+type Store struct {
+	db *sql.DB
+}
+
+// DefaultPath returns the location of the outbox database for a workspace.
+func DefaultPath(workspace string) string {
+	return filepath.Join(workspace, "outbox", "outbox.db")
+}
+
+// Open opens (creating if needed) the outbox database at path.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("outbox: create directory: %w", err)
+	}
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open(sqliteDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: open sqlite: %w", err)
+	}
+	// The gateway and the CLI may use the database at the same time; WAL
+	// plus busy_timeout covers that, one connection covers our goroutines.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("outbox: create schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add persists payload as a pending entry that is due immediately.
+func (s *Store) Add(ctx context.Context, kind Kind, channel, chatID string, payload any) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: marshal payload: %w", err)
+	}
+	now := time.Now().UnixNano()
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO outbox (kind, channel, chat_id, payload, next_attempt_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		string(kind), channel, chatID, string(data), now, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: insert entry: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// Get returns one entry.
+func (s *Store) Get(ctx context.Context, id int64) (Entry, error) {
+	entries, err := s.List(ctx, Filter{IDs: []int64{id}})
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, ErrNotFound
+	}
+	return entries[0], nil
+}
+
+// Delete removes a delivered entry.
+func (s *Store) Delete(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM outbox WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("outbox: delete entry: %w", err)
+	}
+	return nil
+}
+
+// SetProgress records how many parts of a split message were delivered.
+func (s *Store) SetProgress(ctx context.Context, id int64, progress int) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE outbox SET progress = ?, updated_at = ? WHERE id = ?`,
+		progress, time.Now().UnixNano(), id)
+	if err != nil {
+		return fmt.Errorf("outbox: update progress: %w", err)
+	}
+	return nil
+}
+
+// Fail records a failed delivery round. The entry becomes a dead letter
+// when permanent is set or maxAttempts rounds have failed; otherwise it is
+// rescheduled after Backoff(attempts). The resulting state is returned.
+func (s *Store) Fail(ctx context.Context, id int64, cause string, permanent bool, maxAttempts int) (State, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	entry, err := s.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	attempts := entry.Attempts + 1
+	state := StatePending
+	if permanent || attempts >= maxAttempts {
+		state = StateDead
+	}
+	now := time.Now()
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE outbox SET state = ?, attempts = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+		 WHERE id = ?`,
+		string(state), attempts, cause, now.Add(Backoff(attempts)).UnixNano(), now.UnixNano(), id)
+	if err != nil {
+		return "", fmt.Errorf("outbox: update entry: %w", err)
+	}
+	return state, nil
+}
+
+// Backoff returns the delay before the next round after attempts failed
+// rounds: 30s doubling per round, capped at one hour.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// Due returns up to limit pending entries whose next attempt is at or
+// before now, oldest first.
+func (s *Store) Due(ctx context.Context, now time.Time, limit int) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+entryColumns+` FROM outbox
+		 WHERE state = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`,
+		string(StatePending), now.UnixNano(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: query due entries: %w", err)
+	}
+	return scanEntries(rows)
+}
+
+// List returns the entries matching f, oldest first.
+func (s *Store) List(ctx context.Context, f Filter) ([]Entry, error) {
+	where, args := f.where()
+	query := `SELECT ` + entryColumns + ` FROM outbox` + where + ` ORDER BY id`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: query entries: %w", err)
+	}
+	return scanEntries(rows)
+}
+
+// Retry makes the matching entries pending and due now, resetting their
+// attempt count.
+func (s *Store) Retry(ctx context.Context, f Filter) (int, error) {
+	where, args := f.where()
+	now := time.Now().UnixNano()
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE outbox SET state = ?, attempts = 0, next_attempt_at = ?, updated_at = ?`+where,
+		append([]any{string(StatePending), now, now}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: retry entries: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Purge deletes the matching entries and returns how many were removed.
+func (s *Store) Purge(ctx context.Context, f Filter) (int, error) {
+	where, args := f.where()
+	res, err := s.db.ExecContext(ctx, `DELETE FROM outbox`+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: purge entries: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Stats counts pending and dead entries.
+func (s *Store) Stats(ctx context.Context) (Stats, error) {
+	var st Stats
+	var oldest sql.NullInt64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT
+			COALESCE(SUM(state = 'pending'), 0),
+			COALESCE(SUM(state = 'dead'), 0),
+			MIN(CASE WHEN state = 'pending' THEN created_at END)
+		 FROM outbox`).Scan(&st.Pending, &st.Dead, &oldest)
+	if err != nil {
+		return Stats{}, fmt.Errorf("outbox: query stats: %w", err)
+	}
+	if oldest.Valid {
+		st.OldestPending = time.Unix(0, oldest.Int64)
+	}
+	return st, nil
+}
+
+func (f Filter) where() (string, []any) {
+	var conds []string
+	var args []any
+	if f.State != "" {
+		conds = append(conds, "state = ?")
+		args = append(args, string(f.State))
+	}
+	if f.Channel != "" {
+		conds = append(conds, "channel = ?")
+		args = append(args, f.Channel)
+	}
+	if len(f.IDs) > 0 {
+		conds = append(conds, "id IN (?"+strings.Repeat(", ?", len(f.IDs)-1)+")")
+		for _, id := range f.IDs {
+			args = append(args, id)
+		}
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+const entryColumns = `id, kind, channel, chat_id, payload, state, attempts, progress, last_error,
+	next_attempt_at, created_at, updated_at`
+
+func scanEntries(rows *sql.Rows) ([]Entry, error) {
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var (
+			e                      Entry
+			payload                string
+			next, created, updated int64
+		)
+		if err := rows.Scan(&e.ID, &e.Kind, &e.Channel, &e.ChatID, &payload, &e.State,
+			&e.Attempts, &e.Progress, &e.LastError, &next, &created, &updated); err != nil {
+			return nil, fmt.Errorf("outbox: scan entry: %w", err)
+		}
+		e.Payload = json.RawMessage(payload)
+		e.NextAttemptAt = time.Unix(0, next)
+		e.CreatedAt = time.Unix(0, created)
+		e.UpdatedAt = time.Unix(0, updated)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("outbox: read entries: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,152 @@
+package outbox
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testPayload struct {
+	Text string `json:"text"`
+}
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStore_Lifecycle(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	id, err := store.Add(ctx, KindMessage, "telegram", "42", testPayload{Text: "hi"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	due, err := store.Due(ctx, time.Now(), 10)
+	if err != nil {
+		t.Fatalf("Due: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != id || due[0].Channel != "telegram" || due[0].ChatID != "42" {
+		t.Fatalf("unexpected due entries: %+v", due)
+	}
+	var p testPayload
+	if err := due[0].Decode(&p); err != nil || p.Text != "hi" {
+		t.Fatalf("Decode = %+v, %v", p, err)
+	}
+
+	// A temporary failure reschedules the entry into the future.
+	state, err := store.Fail(ctx, id, "timeout", false, 2)
+	if err != nil || state != StatePending {
+		t.Fatalf("Fail = %q, %v; want pending", state, err)
+	}
+	if due, _ := store.Due(ctx, time.Now(), 10); len(due) != 0 {
+		t.Fatalf("entry should not be due during backoff: %+v", due)
+	}
+	entry, err := store.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if entry.Attempts != 1 || entry.LastError != "timeout" {
+		t.Fatalf("unexpected entry after failure: %+v", entry)
+	}
+
+	// Reaching maxAttempts moves it to the dead letters.
+	state, err = store.Fail(ctx, id, "timeout again", false, 2)
+	if err != nil || state != StateDead {
+		t.Fatalf("Fail = %q, %v; want dead", state, err)
+	}
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Pending != 0 || stats.Dead != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	// Retry brings it back, due now and with a fresh attempt count.
+	n, err := store.Retry(ctx, Filter{State: StateDead})
+	if err != nil || n != 1 {
+		t.Fatalf("Retry = %d, %v", n, err)
+	}
+	due, _ = store.Due(ctx, time.Now(), 10)
+	if len(due) != 1 || due[0].Attempts != 0 || due[0].State != StatePending {
+		t.Fatalf("unexpected due entries after retry: %+v", due)
+	}
+
+	if err := store.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, id); err != ErrNotFound {
+		t.Fatalf("Get after delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStore_PermanentFailure(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	id, _ := store.Add(ctx, KindMedia, "slack", "C1", testPayload{})
+	state, err := store.Fail(ctx, id, "invalid chat", true, 10)
+	if err != nil || state != StateDead {
+		t.Fatalf("Fail = %q, %v; want dead", state, err)
+	}
+}
+
+func TestStore_ListAndPurge(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	a, _ := store.Add(ctx, KindMessage, "telegram", "1", testPayload{Text: "a"})
+	store.Add(ctx, KindMessage, "discord", "2", testPayload{Text: "b"})
+	c, _ := store.Add(ctx, KindMessage, "telegram", "3", testPayload{Text: "c"})
+	store.Fail(ctx, c, "gone", true, 10)
+
+	entries, err := store.List(ctx, Filter{Channel: "telegram"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != a || entries[1].ID != c {
+		t.Fatalf("unexpected telegram entries: %+v", entries)
+	}
+
+	entries, _ = store.List(ctx, Filter{State: StateDead})
+	if len(entries) != 1 || entries[0].ID != c {
+		t.Fatalf("unexpected dead entries: %+v", entries)
+	}
+
+	n, err := store.Purge(ctx, Filter{State: StateDead})
+	if err != nil || n != 1 {
+		t.Fatalf("Purge dead = %d, %v", n, err)
+	}
+	n, err = store.Purge(ctx, Filter{IDs: []int64{a}})
+	if err != nil || n != 1 {
+		t.Fatalf("Purge by id = %d, %v", n, err)
+	}
+	entries, _ = store.List(ctx, Filter{})
+	if len(entries) != 1 || entries[0].Channel != "discord" {
+		t.Fatalf("unexpected remaining entries: %+v", entries)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  30 * time.Second,
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		20: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
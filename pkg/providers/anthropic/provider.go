@@ -21,11 +21,19 @@ type (
 	Message                = protocoltypes.Message
 	ToolDefinition         = protocoltypes.ToolDefinition
 	ToolFunctionDefinition = protocoltypes.ToolFunctionDefinition
+	ResponseFormat         = protocoltypes.ResponseFormat
 )
 
 const (
 	defaultBaseURL      = "https://api.anthropic.com"
 	anthropicBetaHeader = "oauth-2025-04-20"
+
+	// structuredToolName is the tool Claude is made to call when the caller
+	// asks for a response format; its input is the structured answer.
+	structuredToolName = "structured_output"
+	// structuredValueKey wraps non-object schemas, since tool input must be
+	// a JSON object.
+	structuredValueKey = "value"
 )
 
 type Provider struct {
@@ -77,16 +85,9 @@ func (p *Provider) Chat(
 	model string,
 	options map[string]any,
 ) (*LLMResponse, error) {
-	var opts []option.RequestOption
-	if p.tokenSource != nil {
-		tok, err := p.tokenSource()
-		if err != nil {
-			return nil, fmt.Errorf("refreshing token: %w", err)
-		}
-		opts = append(opts,
-			option.WithAuthToken(tok),
-			option.WithHeader("anthropic-beta", anthropicBetaHeader),
-		)
+	opts, err := p.requestOptions()
+	if err != nil {
+		return nil, err
 	}
 
 	params, err := buildParams(messages, tools, model, options)
@@ -94,9 +95,15 @@ func (p *Provider) Chat(
 		return nil, err
 	}
 
+	rf := protocoltypes.ResponseFormatOption(options)
+
 	// OAuth/setup-tokens require streaming; API keys use non-streaming.
 	if p.tokenSource != nil {
-		return p.chatStreaming(ctx, params, opts)
+		resp, err := p.chatStreaming(ctx, params, opts, nil)
+		if err != nil {
+			return nil, err
+		}
+		return structuredResponse(resp, rf), nil
 	}
 
 	resp, err := p.client.Messages.New(ctx, params, opts...)
@@ -104,13 +111,55 @@ func (p *Provider) Chat(
 		return nil, fmt.Errorf("claude API call: %w", err)
 	}
 
-	return parseResponse(resp), nil
+	return structuredResponse(parseResponse(resp), rf), nil
+}
+
+// ChatStream is like Chat but always uses the streaming endpoint and reports
+// each text delta to onDelta as it arrives.
+func (p *Provider) ChatStream(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+	onDelta func(delta string),
+) (*LLMResponse, error) {
+	opts, err := p.requestOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := buildParams(messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.chatStreaming(ctx, params, opts, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return structuredResponse(resp, protocoltypes.ResponseFormatOption(options)), nil
+}
+
+func (p *Provider) requestOptions() ([]option.RequestOption, error) {
+	if p.tokenSource == nil {
+		return nil, nil
+	}
+	tok, err := p.tokenSource()
+	if err != nil {
+		return nil, fmt.Errorf("refreshing token: %w", err)
+	}
+	return []option.RequestOption{
+		option.WithAuthToken(tok),
+		option.WithHeader("anthropic-beta", anthropicBetaHeader),
+	}, nil
 }
 
 func (p *Provider) chatStreaming(
 	ctx context.Context,
 	params anthropic.MessageNewParams,
 	opts []option.RequestOption,
+	onDelta func(delta string),
 ) (*LLMResponse, error) {
 	stream := p.client.Messages.NewStreaming(ctx, params, opts...)
 	defer stream.Close()
@@ -121,6 +170,10 @@ func (p *Provider) chatStreaming(
 		if err := msg.Accumulate(event); err != nil {
 			return nil, fmt.Errorf("claude streaming accumulate: %w", err)
 		}
+		if onDelta != nil && event.Type == "content_block_delta" &&
+			event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+			onDelta(event.Delta.Text)
+		}
 	}
 	if err := stream.Err(); err != nil {
 		return nil, fmt.Errorf("claude API call: %w", err)
@@ -254,12 +307,23 @@ func buildParams(
 		params.Tools = translateTools(tools)
 	}
 
+	// Structured output is emulated with forced tool use, which the API
+	// does not allow together with extended thinking.
+	rf := protocoltypes.ResponseFormatOption(options)
+	if rf != nil {
+		applyResponseFormat(&params, rf)
+	}
+
 	// Extended Thinking / Adaptive Thinking
 	// The thinking_level value directly determines the API parameter format:
 	//   "adaptive" → {thinking: {type: "adaptive"}} + output_config.effort
 	//   "low/medium/high/xhigh" → {thinking: {type: "enabled", budget_tokens: N}}
 	if level, ok := options["thinking_level"].(string); ok && level != "" && level != "off" {
-		applyThinkingConfig(&params, level)
+		if rf != nil {
+			log.Printf("anthropic: thinking disabled because a response format is requested (level=%s)", level)
+		} else {
+			applyThinkingConfig(&params, level)
+		}
 	}
 
 	return params, nil
@@ -340,20 +404,96 @@ func translateTools(tools []ToolDefinition) []anthropic.ToolUnionParam {
 		if desc := t.Function.Description; desc != "" {
 			tool.Description = anthropic.String(desc)
 		}
-		if req, ok := t.Function.Parameters["required"].([]any); ok {
-			required := make([]string, 0, len(req))
-			for _, r := range req {
-				if s, ok := r.(string); ok {
-					required = append(required, s)
-				}
-			}
-			tool.InputSchema.Required = required
-		}
+		tool.InputSchema.Required = schemaRequired(t.Function.Parameters["required"])
 		result = append(result, anthropic.ToolUnionParam{OfTool: &tool})
 	}
 	return result
 }
 
+// schemaRequired reads a JSON Schema "required" list, which is []any when
+// the schema came from JSON and []string when it was built in Go.
+func schemaRequired(v any) []string {
+	switch req := v.(type) {
+	case []string:
+		return req
+	case []any:
+		required := make([]string, 0, len(req))
+		for _, r := range req {
+			if s, ok := r.(string); ok {
+				required = append(required, s)
+			}
+		}
+		return required
+	}
+	return nil
+}
+
+// applyResponseFormat adds the structured_output tool and makes Claude call
+// it: directly when it is the only tool, otherwise through tool_choice "any"
+// so the model can still use the caller's tools before answering.
+func applyResponseFormat(params *anthropic.MessageNewParams, rf *ResponseFormat) {
+	schema := rf.Schema
+	if schema == nil {
+		schema = map[string]any{"type": "object"}
+	}
+	if t, _ := schema["type"].(string); t != "object" {
+		schema = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{structuredValueKey: schema},
+			"required":   []string{structuredValueKey},
+		}
+	}
+
+	description := "Return the final answer by calling this tool with the result as its input."
+	if rf.Description != "" {
+		description = rf.Description + "\n\n" + description
+	}
+	tool := anthropic.ToolParam{
+		Name:        structuredToolName,
+		Description: anthropic.String(description),
+		InputSchema: anthropic.ToolInputSchemaParam{
+			Properties: schema["properties"],
+			Required:   schemaRequired(schema["required"]),
+		},
+	}
+	params.Tools = append(params.Tools, anthropic.ToolUnionParam{OfTool: &tool})
+
+	if len(params.Tools) == 1 {
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(structuredToolName)
+	} else {
+		params.ToolChoice = anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}
+	}
+}
+
+// structuredResponse turns a structured_output tool call back into the JSON
+// content of resp, as if the model had answered with it directly.
+func structuredResponse(resp *LLMResponse, rf *ResponseFormat) *LLMResponse {
+	if rf == nil || resp == nil {
+		return resp
+	}
+	for i, tc := range resp.ToolCalls {
+		if tc.Name != structuredToolName {
+			continue
+		}
+		var value any = tc.Arguments
+		if t, _ := rf.Schema["type"].(string); rf.Schema != nil && t != "object" {
+			value = tc.Arguments[structuredValueKey]
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			log.Printf("anthropic: failed to encode structured output: %v", err)
+			continue
+		}
+		resp.Content = string(data)
+		resp.ToolCalls = append(resp.ToolCalls[:i:i], resp.ToolCalls[i+1:]...)
+		if len(resp.ToolCalls) == 0 {
+			resp.FinishReason = "stop"
+		}
+		break
+	}
+	return resp
+}
+
 func parseResponse(resp *anthropic.Message) *LLMResponse {
 	var content strings.Builder
 	var reasoning strings.Builder
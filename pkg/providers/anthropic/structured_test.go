@@ -0,0 +1,111 @@
+package anthropicprovider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+func TestBuildParams_ResponseFormatForcesTool(t *testing.T) {
+	rf := &ResponseFormat{
+		Type: protocoltypes.ResponseFormatJSONSchema,
+		Schema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			"required":   []string{"city"},
+		},
+	}
+	params, err := buildParams([]Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4.6", map[string]any{
+		"response_format": rf,
+		"thinking_level":  "high",
+	})
+	if err != nil {
+		t.Fatalf("buildParams() error: %v", err)
+	}
+	if len(params.Tools) != 1 || params.Tools[0].OfTool.Name != structuredToolName {
+		t.Fatalf("Tools = %+v, want only %s", params.Tools, structuredToolName)
+	}
+	if got := params.Tools[0].OfTool.InputSchema.Required; len(got) != 1 || got[0] != "city" {
+		t.Errorf("Required = %v, want [city]", got)
+	}
+	if params.ToolChoice.OfTool == nil || params.ToolChoice.OfTool.Name != structuredToolName {
+		t.Errorf("ToolChoice = %+v, want forced %s", params.ToolChoice, structuredToolName)
+	}
+	if params.Thinking.OfEnabled != nil || params.Thinking.OfAdaptive != nil {
+		t.Error("thinking must be disabled with forced tool use")
+	}
+
+	// With other tools available Claude may still call them first.
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "search"}}}
+	params, err = buildParams([]Message{{Role: "user", Content: "Hi"}}, tools, "claude-sonnet-4.6", map[string]any{
+		"response_format": rf,
+	})
+	if err != nil {
+		t.Fatalf("buildParams() error: %v", err)
+	}
+	if len(params.Tools) != 2 || params.ToolChoice.OfAny == nil {
+		t.Errorf("Tools = %d, ToolChoice = %+v, want 2 tools and tool_choice any", len(params.Tools), params.ToolChoice)
+	}
+}
+
+func TestStructuredResponse(t *testing.T) {
+	resp := structuredResponse(&LLMResponse{
+		ToolCalls: []ToolCall{
+			{ID: "t1", Name: "search", Arguments: map[string]any{"q": "x"}},
+			{ID: "t2", Name: structuredToolName, Arguments: map[string]any{"value": []any{"a", "b"}}},
+		},
+		FinishReason: "tool_calls",
+	}, &ResponseFormat{
+		Type:   protocoltypes.ResponseFormatJSONSchema,
+		Schema: map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	})
+	if resp.Content != `["a","b"]` {
+		t.Errorf("Content = %q, want unwrapped array", resp.Content)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "search" || resp.FinishReason != "tool_calls" {
+		t.Errorf("ToolCalls = %+v, FinishReason = %q", resp.ToolCalls, resp.FinishReason)
+	}
+
+	plain := &LLMResponse{ToolCalls: []ToolCall{{Name: structuredToolName}}}
+	if got := structuredResponse(plain, nil); len(got.ToolCalls) != 1 {
+		t.Error("responses without a response format must be left untouched")
+	}
+}
+
+func TestProvider_ChatStructuredOutput(t *testing.T) {
+	var reqBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		resp := map[string]any{
+			"id":          "msg_test",
+			"type":        "message",
+			"role":        "assistant",
+			"model":       reqBody["model"],
+			"stop_reason": "tool_use",
+			"content": []map[string]any{
+				{"type": "tool_use", "id": "toolu_1", "name": structuredToolName, "input": map[string]any{"city": "Paris"}},
+			},
+			"usage": map[string]any{"input_tokens": 15, "output_tokens": 8},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	provider := NewProviderWithClient(createAnthropicTestClient(server.URL, "test-token"))
+	resp, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "Where?"}}, nil, "claude-sonnet-4.6",
+		map[string]any{"response_format": ResponseFormat{Type: protocoltypes.ResponseFormatJSONObject}})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != `{"city":"Paris"}` || len(resp.ToolCalls) != 0 || resp.FinishReason != "stop" {
+		t.Errorf("response = %+v", resp)
+	}
+	choice, _ := reqBody["tool_choice"].(map[string]any)
+	if choice["type"] != "tool" || choice["name"] != structuredToolName {
+		t.Errorf("tool_choice = %v", reqBody["tool_choice"])
+	}
+}
@@ -154,261 +154,20 @@ func (p *AntigravityProvider) GetDefaultModel() string {
 
 // --- Request building ---
 
-type antigravityRequest struct {
-	Contents     []antigravityContent     `json:"contents"`
-	Tools        []antigravityTool        `json:"tools,omitempty"`
-	SystemPrompt *antigravitySystemPrompt `json:"systemInstruction,omitempty"`
-	Config       *antigravityGenConfig    `json:"generationConfig,omitempty"`
-}
-
-type antigravityContent struct {
-	Role  string            `json:"role"`
-	Parts []antigravityPart `json:"parts"`
-}
-
-type antigravityPart struct {
-	Text                  string                       `json:"text,omitempty"`
-	ThoughtSignature      string                       `json:"thoughtSignature,omitempty"`
-	ThoughtSignatureSnake string                       `json:"thought_signature,omitempty"`
-	FunctionCall          *antigravityFunctionCall     `json:"functionCall,omitempty"`
-	FunctionResponse      *antigravityFunctionResponse `json:"functionResponse,omitempty"`
-}
-
-type antigravityFunctionCall struct {
-	Name string         `json:"name"`
-	Args map[string]any `json:"args"`
-}
-
-type antigravityFunctionResponse struct {
-	Name     string         `json:"name"`
-	Response map[string]any `json:"response"`
-}
-
-type antigravityTool struct {
-	FunctionDeclarations []antigravityFuncDecl `json:"functionDeclarations"`
-}
-
-type antigravityFuncDecl struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	Parameters  any    `json:"parameters,omitempty"`
-}
-
-type antigravitySystemPrompt struct {
-	Parts []antigravityPart `json:"parts"`
-}
-
-type antigravityGenConfig struct {
-	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
-	Temperature     float64 `json:"temperature,omitempty"`
-}
-
+// buildRequest builds the inner Gemini-format request; see gemini_wire.go.
 func (p *AntigravityProvider) buildRequest(
 	messages []Message,
 	tools []ToolDefinition,
 	model string,
 	options map[string]any,
-) antigravityRequest {
-	req := antigravityRequest{}
-	toolCallNames := make(map[string]string)
-
-	// Build contents from messages
-	for _, msg := range messages {
-		switch msg.Role {
-		case "system":
-			req.SystemPrompt = &antigravitySystemPrompt{
-				Parts: []antigravityPart{{Text: msg.Content}},
-			}
-		case "user":
-			if msg.ToolCallID != "" {
-				toolName := resolveToolResponseName(msg.ToolCallID, toolCallNames)
-				// Tool result
-				req.Contents = append(req.Contents, antigravityContent{
-					Role: "user",
-					Parts: []antigravityPart{{
-						FunctionResponse: &antigravityFunctionResponse{
-							Name: toolName,
-							Response: map[string]any{
-								"result": msg.Content,
-							},
-						},
-					}},
-				})
-			} else {
-				req.Contents = append(req.Contents, antigravityContent{
-					Role:  "user",
-					Parts: []antigravityPart{{Text: msg.Content}},
-				})
-			}
-		case "assistant":
-			content := antigravityContent{
-				Role: "model",
-			}
-			if msg.Content != "" {
-				content.Parts = append(content.Parts, antigravityPart{Text: msg.Content})
-			}
-			for _, tc := range msg.ToolCalls {
-				toolName, toolArgs, thoughtSignature := normalizeStoredToolCall(tc)
-				if toolName == "" {
-					logger.WarnCF(
-						"provider.antigravity",
-						"Skipping tool call with empty name in history",
-						map[string]any{
-							"tool_call_id": tc.ID,
-						},
-					)
-					continue
-				}
-				if tc.ID != "" {
-					toolCallNames[tc.ID] = toolName
-				}
-				content.Parts = append(content.Parts, antigravityPart{
-					ThoughtSignature:      thoughtSignature,
-					ThoughtSignatureSnake: thoughtSignature,
-					FunctionCall: &antigravityFunctionCall{
-						Name: toolName,
-						Args: toolArgs,
-					},
-				})
-			}
-			if len(content.Parts) > 0 {
-				req.Contents = append(req.Contents, content)
-			}
-		case "tool":
-			toolName := resolveToolResponseName(msg.ToolCallID, toolCallNames)
-			req.Contents = append(req.Contents, antigravityContent{
-				Role: "user",
-				Parts: []antigravityPart{{
-					FunctionResponse: &antigravityFunctionResponse{
-						Name: toolName,
-						Response: map[string]any{
-							"result": msg.Content,
-						},
-					},
-				}},
-			})
-		}
-	}
-
-	// Build tools (sanitize schemas for Gemini compatibility)
-	if len(tools) > 0 {
-		var funcDecls []antigravityFuncDecl
-		for _, t := range tools {
-			if t.Type != "function" {
-				continue
-			}
-			params := sanitizeSchemaForGemini(t.Function.Parameters)
-			funcDecls = append(funcDecls, antigravityFuncDecl{
-				Name:        t.Function.Name,
-				Description: t.Function.Description,
-				Parameters:  params,
-			})
-		}
-		if len(funcDecls) > 0 {
-			req.Tools = []antigravityTool{{FunctionDeclarations: funcDecls}}
-		}
-	}
-
-	// Generation config
-	config := &antigravityGenConfig{}
-	if val, ok := options["max_tokens"]; ok {
-		if maxTokens, ok := val.(int); ok && maxTokens > 0 {
-			config.MaxOutputTokens = maxTokens
-		} else if maxTokens, ok := val.(float64); ok && maxTokens > 0 {
-			config.MaxOutputTokens = int(maxTokens)
-		}
-	}
-	if temp, ok := options["temperature"].(float64); ok {
-		config.Temperature = temp
-	}
-	if config.MaxOutputTokens > 0 || config.Temperature > 0 {
-		req.Config = config
-	}
-
-	return req
-}
-
-func normalizeStoredToolCall(tc ToolCall) (string, map[string]any, string) {
-	name := tc.Name
-	args := tc.Arguments
-	thoughtSignature := ""
-
-	if name == "" && tc.Function != nil {
-		name = tc.Function.Name
-		thoughtSignature = tc.Function.ThoughtSignature
-	} else if tc.Function != nil {
-		thoughtSignature = tc.Function.ThoughtSignature
-	}
-
-	if args == nil {
-		args = map[string]any{}
-	}
-
-	if len(args) == 0 && tc.Function != nil && tc.Function.Arguments != "" {
-		var parsed map[string]any
-		if err := json.Unmarshal([]byte(tc.Function.Arguments), &parsed); err == nil && parsed != nil {
-			args = parsed
-		}
-	}
-
-	return name, args, thoughtSignature
-}
-
-func resolveToolResponseName(toolCallID string, toolCallNames map[string]string) string {
-	if toolCallID == "" {
-		return ""
-	}
-
-	if name, ok := toolCallNames[toolCallID]; ok && name != "" {
-		return name
-	}
-
-	return inferToolNameFromCallID(toolCallID)
-}
-
-func inferToolNameFromCallID(toolCallID string) string {
-	if !strings.HasPrefix(toolCallID, "call_") {
-		return toolCallID
-	}
-
-	rest := strings.TrimPrefix(toolCallID, "call_")
-	if idx := strings.LastIndex(rest, "_"); idx > 0 {
-		candidate := rest[:idx]
-		if candidate != "" {
-			return candidate
-		}
-	}
-
-	return toolCallID
+) geminiRequest {
+	return buildGeminiRequest(messages, tools, options)
 }
 
 // --- Response parsing ---
 
-type antigravityJSONResponse struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text                  string                   `json:"text,omitempty"`
-				ThoughtSignature      string                   `json:"thoughtSignature,omitempty"`
-				ThoughtSignatureSnake string                   `json:"thought_signature,omitempty"`
-				FunctionCall          *antigravityFunctionCall `json:"functionCall,omitempty"`
-			} `json:"parts"`
-			Role string `json:"role"`
-		} `json:"content"`
-		FinishReason string `json:"finishReason"`
-	} `json:"candidates"`
-	UsageMetadata struct {
-		PromptTokenCount     int `json:"promptTokenCount"`
-		CandidatesTokenCount int `json:"candidatesTokenCount"`
-		TotalTokenCount      int `json:"totalTokenCount"`
-	} `json:"usageMetadata"`
-}
-
 func (p *AntigravityProvider) parseSSEResponse(body string) (*LLMResponse, error) {
-	var contentParts []string
-	var toolCalls []ToolCall
-	var usage *UsageInfo
-	var finishReason string
+	var acc geminiAccumulator
 
 	scanner := bufio.NewScanner(strings.NewReader(body))
 	for scanner.Scan() {
@@ -423,138 +182,15 @@ func (p *AntigravityProvider) parseSSEResponse(body string) (*LLMResponse, error
 
 		// v1internal SSE wraps the Gemini response in a "response" field
 		var sseChunk struct {
-			Response antigravityJSONResponse `json:"response"`
+			Response geminiResponse `json:"response"`
 		}
 		if err := json.Unmarshal([]byte(data), &sseChunk); err != nil {
 			continue
 		}
-		resp := sseChunk.Response
-
-		for _, candidate := range resp.Candidates {
-			for _, part := range candidate.Content.Parts {
-				if part.Text != "" {
-					contentParts = append(contentParts, part.Text)
-				}
-				if part.FunctionCall != nil {
-					argumentsJSON, _ := json.Marshal(part.FunctionCall.Args)
-					toolCalls = append(toolCalls, ToolCall{
-						ID:        fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, time.Now().UnixNano()),
-						Name:      part.FunctionCall.Name,
-						Arguments: part.FunctionCall.Args,
-						Function: &FunctionCall{
-							Name:      part.FunctionCall.Name,
-							Arguments: string(argumentsJSON),
-							ThoughtSignature: extractPartThoughtSignature(
-								part.ThoughtSignature,
-								part.ThoughtSignatureSnake,
-							),
-						},
-					})
-				}
-			}
-			if candidate.FinishReason != "" {
-				finishReason = candidate.FinishReason
-			}
-		}
-
-		if resp.UsageMetadata.TotalTokenCount > 0 {
-			usage = &UsageInfo{
-				PromptTokens:     resp.UsageMetadata.PromptTokenCount,
-				CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
-				TotalTokens:      resp.UsageMetadata.TotalTokenCount,
-			}
-		}
-	}
-
-	mappedFinish := "stop"
-	if len(toolCalls) > 0 {
-		mappedFinish = "tool_calls"
-	}
-	if finishReason == "MAX_TOKENS" {
-		mappedFinish = "length"
-	}
-
-	return &LLMResponse{
-		Content:      strings.Join(contentParts, ""),
-		ToolCalls:    toolCalls,
-		FinishReason: mappedFinish,
-		Usage:        usage,
-	}, nil
-}
-
-func extractPartThoughtSignature(thoughtSignature string, thoughtSignatureSnake string) string {
-	if thoughtSignature != "" {
-		return thoughtSignature
-	}
-	if thoughtSignatureSnake != "" {
-		return thoughtSignatureSnake
-	}
-	return ""
-}
-
-// --- Schema sanitization ---
-
-// Google/Gemini doesn't support many JSON Schema keywords that other providers accept.
-var geminiUnsupportedKeywords = map[string]bool{
-	"patternProperties":    true,
-	"additionalProperties": true,
-	"$schema":              true,
-	"$id":                  true,
-	"$ref":                 true,
-	"$defs":                true,
-	"definitions":          true,
-	"examples":             true,
-	"minLength":            true,
-	"maxLength":            true,
-	"minimum":              true,
-	"maximum":              true,
-	"multipleOf":           true,
-	"pattern":              true,
-	"format":               true,
-	"minItems":             true,
-	"maxItems":             true,
-	"uniqueItems":          true,
-	"minProperties":        true,
-	"maxProperties":        true,
-}
-
-func sanitizeSchemaForGemini(schema map[string]any) map[string]any {
-	if schema == nil {
-		return nil
-	}
-
-	result := make(map[string]any)
-	for k, v := range schema {
-		if geminiUnsupportedKeywords[k] {
-			continue
-		}
-		// Recursively sanitize nested objects
-		switch val := v.(type) {
-		case map[string]any:
-			result[k] = sanitizeSchemaForGemini(val)
-		case []any:
-			sanitized := make([]any, len(val))
-			for i, item := range val {
-				if m, ok := item.(map[string]any); ok {
-					sanitized[i] = sanitizeSchemaForGemini(m)
-				} else {
-					sanitized[i] = item
-				}
-			}
-			result[k] = sanitized
-		default:
-			result[k] = v
-		}
-	}
-
-	// Ensure top-level has type: "object" if properties are present
-	if _, hasProps := result["properties"]; hasProps {
-		if _, hasType := result["type"]; !hasType {
-			result["type"] = "object"
-		}
+		acc.add(sseChunk.Response, nil)
 	}
 
-	return result
+	return acc.response(), nil
 }
 
 // --- Token source ---
@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// ModelCapabilities describes what a model accepts. ContextWindow is the
+// total number of tokens (prompt plus output) the model handles; 0 means
+// unknown.
+type ModelCapabilities struct {
+	ContextWindow int
+	Vision        bool
+	Files         bool
+	Tools         bool
+	Thinking      bool
+}
+
+// ModelRequirements is what a request needs from the model answering it.
+type ModelRequirements struct {
+	Vision bool
+	Files  bool
+	Tools  bool
+}
+
+// Satisfies reports whether a model with these capabilities can handle a
+// request with requirements r without anything being stripped.
+func (c ModelCapabilities) Satisfies(r ModelRequirements) bool {
+	return (!r.Vision || c.Vision) && (!r.Files || c.Files) && (!r.Tools || c.Tools)
+}
+
+// unknownModelCapabilities is assumed for models missing from the registry:
+// everything is passed through and the provider decides, as before the
+// registry existed.
+var unknownModelCapabilities = ModelCapabilities{Vision: true, Files: true, Tools: true, Thinking: true}
+
+// builtinCapabilities maps model ID prefixes to capabilities. The first
+// matching prefix wins, so more specific prefixes come first.
+var builtinCapabilities = []struct {
+	prefix string
+	caps   ModelCapabilities
+}{
+	// Anthropic
+	{"claude-3-haiku", ModelCapabilities{ContextWindow: 200_000, Vision: true, Files: true, Tools: true}},
+	{"claude-3-5", ModelCapabilities{ContextWindow: 200_000, Vision: true, Files: true, Tools: true}},
+	{"claude-3.5", ModelCapabilities{ContextWindow: 200_000, Vision: true, Files: true, Tools: true}},
+	{"claude", ModelCapabilities{ContextWindow: 200_000, Vision: true, Files: true, Tools: true, Thinking: true}},
+
+	// OpenAI
+	{"gpt-5", ModelCapabilities{ContextWindow: 400_000, Vision: true, Files: true, Tools: true, Thinking: true}},
+	{"gpt-4.1", ModelCapabilities{ContextWindow: 1_047_576, Vision: true, Files: true, Tools: true}},
+	{"gpt-4o", ModelCapabilities{ContextWindow: 128_000, Vision: true, Files: true, Tools: true}},
+	{"gpt-4-turbo", ModelCapabilities{ContextWindow: 128_000, Vision: true, Tools: true}},
+	{"gpt-4", ModelCapabilities{ContextWindow: 8_192, Tools: true}},
+	{"gpt-3.5", ModelCapabilities{ContextWindow: 16_385, Tools: true}},
+	{"gpt-oss", ModelCapabilities{ContextWindow: 131_072, Tools: true, Thinking: true}},
+	{"o1-mini", ModelCapabilities{ContextWindow: 128_000, Thinking: true}},
+	{"o1", ModelCapabilities{ContextWindow: 200_000, Vision: true, Files: true, Tools: true, Thinking: true}},
+	{"o3", ModelCapabilities{ContextWindow: 200_000, Vision: true, Files: true, Tools: true, Thinking: true}},
+	{"o4", ModelCapabilities{ContextWindow: 200_000, Vision: true, Files: true, Tools: true, Thinking: true}},
+
+	// Google
+	{"gemini-1.5-pro", ModelCapabilities{ContextWindow: 2_097_152, Vision: true, Files: true, Tools: true}},
+	{"gemini-1.5", ModelCapabilities{ContextWindow: 1_048_576, Vision: true, Files: true, Tools: true}},
+	{"gemini-2.0", ModelCapabilities{ContextWindow: 1_048_576, Vision: true, Files: true, Tools: true}},
+	{"gemini", ModelCapabilities{ContextWindow: 1_048_576, Vision: true, Files: true, Tools: true, Thinking: true}},
+	{"gemma", ModelCapabilities{ContextWindow: 131_072, Vision: true}},
+
+	// DeepSeek
+	{"deepseek-reasoner", ModelCapabilities{ContextWindow: 131_072, Tools: true, Thinking: true}},
+	{"deepseek-r1", ModelCapabilities{ContextWindow: 131_072, Thinking: true}},
+	{"deepseek", ModelCapabilities{ContextWindow: 131_072, Tools: true}},
+
+	// Qwen
+	{"qwen-vl", ModelCapabilities{ContextWindow: 131_072, Vision: true, Tools: true}},
+	{"qwen2.5-vl", ModelCapabilities{ContextWindow: 131_072, Vision: true, Tools: true}},
+	{"qwen3-vl", ModelCapabilities{ContextWindow: 262_144, Vision: true, Tools: true, Thinking: true}},
+	{"qwen3", ModelCapabilities{ContextWindow: 131_072, Tools: true, Thinking: true}},
+	{"qwen", ModelCapabilities{ContextWindow: 131_072, Tools: true}},
+
+	// Zhipu, Moonshot, MiniMax
+	{"glm-4v", ModelCapabilities{ContextWindow: 8_192, Vision: true}},
+	{"glm-4.5v", ModelCapabilities{ContextWindow: 65_536, Vision: true, Tools: true, Thinking: true}},
+	{"glm", ModelCapabilities{ContextWindow: 131_072, Tools: true, Thinking: true}},
+	{"kimi", ModelCapabilities{ContextWindow: 262_144, Tools: true}},
+	{"moonshot", ModelCapabilities{ContextWindow: 131_072, Tools: true}},
+	{"minimax", ModelCapabilities{ContextWindow: 204_800, Tools: true, Thinking: true}},
+
+	// Open-weight families commonly served by Ollama, vLLM or OpenRouter
+	{"llama3.2-vision", ModelCapabilities{ContextWindow: 131_072, Vision: true}},
+	{"llama-3.2-vision", ModelCapabilities{ContextWindow: 131_072, Vision: true}},
+	{"llama-4", ModelCapabilities{ContextWindow: 1_048_576, Vision: true, Tools: true}},
+	{"llama4", ModelCapabilities{ContextWindow: 1_048_576, Vision: true, Tools: true}},
+	{"llama3", ModelCapabilities{ContextWindow: 131_072, Tools: true}},
+	{"llama-3", ModelCapabilities{ContextWindow: 131_072, Tools: true}},
+	{"pixtral", ModelCapabilities{ContextWindow: 131_072, Vision: true, Tools: true}},
+	{"mistral", ModelCapabilities{ContextWindow: 131_072, Tools: true}},
+	{"grok-2-vision", ModelCapabilities{ContextWindow: 32_768, Vision: true, Tools: true}},
+	{"grok", ModelCapabilities{ContextWindow: 262_144, Vision: true, Tools: true, Thinking: true}},
+}
+
+// LookupCapabilities returns the built-in capabilities for a model ID such
+// as "gpt-4o", "openai/gpt-4o" or "openrouter/anthropic/claude-sonnet-4".
+// ok is false for unknown models, which get permissive defaults.
+func LookupCapabilities(model string) (caps ModelCapabilities, ok bool) {
+	id := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+	for _, entry := range builtinCapabilities {
+		if strings.HasPrefix(id, entry.prefix) {
+			return entry.caps, true
+		}
+	}
+	return unknownModelCapabilities, false
+}
+
+// CapabilitiesFor returns the capabilities of a model_list entry: the
+// built-in defaults for its model, with the entry's overrides applied.
+func CapabilitiesFor(mc *config.ModelConfig) ModelCapabilities {
+	if mc == nil {
+		return unknownModelCapabilities
+	}
+	caps, _ := LookupCapabilities(mc.Model)
+	if mc.ContextWindow > 0 {
+		caps.ContextWindow = mc.ContextWindow
+	}
+	if mc.SupportsVision != nil {
+		caps.Vision = *mc.SupportsVision
+	}
+	if mc.SupportsFiles != nil {
+		caps.Files = *mc.SupportsFiles
+	}
+	if mc.SupportsTools != nil {
+		caps.Tools = *mc.SupportsTools
+	}
+	if mc.SupportsThinking != nil {
+		caps.Thinking = *mc.SupportsThinking
+	}
+	return caps
+}
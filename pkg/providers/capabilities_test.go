@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestLookupCapabilities(t *testing.T) {
+	tests := []struct {
+		model      string
+		wantKnown  bool
+		wantVision bool
+		wantWindow int
+	}{
+		{"gpt-4o-mini", true, true, 128_000},
+		{"openai/gpt-4", true, false, 8_192},
+		{"openrouter/anthropic/claude-sonnet-4", true, true, 200_000},
+		{"DeepSeek-Chat", true, false, 131_072},
+		{"my-local-model", false, true, 0},
+	}
+	for _, tt := range tests {
+		caps, ok := LookupCapabilities(tt.model)
+		if ok != tt.wantKnown || caps.Vision != tt.wantVision || caps.ContextWindow != tt.wantWindow {
+			t.Errorf("LookupCapabilities(%q) = %+v, %v", tt.model, caps, ok)
+		}
+	}
+}
+
+func TestCapabilitiesFor_Overrides(t *testing.T) {
+	no := false
+	caps := CapabilitiesFor(&config.ModelConfig{
+		Model:          "openai/gpt-4o",
+		ContextWindow:  64_000,
+		SupportsVision: &no,
+	})
+	if caps.ContextWindow != 64_000 || caps.Vision || !caps.Tools {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+}
+
+func TestRankByCapability(t *testing.T) {
+	candidates := []FallbackCandidate{
+		{Provider: "deepseek", Model: "deepseek-chat", Capabilities: ModelCapabilities{Tools: true}},
+		{Provider: "openai", Model: "gpt-3.5-turbo", Capabilities: ModelCapabilities{Tools: true}},
+		{Provider: "openai", Model: "gpt-4o", Capabilities: ModelCapabilities{Vision: true, Tools: true}},
+	}
+
+	got := RankByCapability(candidates, ModelRequirements{Vision: true, Tools: true})
+	want := []string{"gpt-4o", "deepseek-chat", "gpt-3.5-turbo"}
+	for i, c := range got {
+		if c.Model != want[i] {
+			t.Fatalf("ranked order = %v, want %v", got, want)
+		}
+	}
+
+	got = RankByCapability(candidates, ModelRequirements{Tools: true})
+	if got[0].Model != "deepseek-chat" {
+		t.Fatalf("text-only request should keep the configured order, got %v", got)
+	}
+}
@@ -7,9 +7,12 @@ package providers
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
 )
 
 // createClaudeAuthProvider creates a Claude provider using OAuth credentials from auth store.
@@ -53,7 +56,7 @@ func ExtractProtocol(model string) (protocol, modelID string) {
 
 // CreateProviderFromConfig creates a provider based on the ModelConfig.
 // It uses the protocol prefix in the Model field to determine which provider to create.
-// Supported protocols: openai, litellm, anthropic, antigravity, claude-cli, codex-cli, github-copilot
+// Supported protocols: openai, litellm, gemini, ollama, anthropic, antigravity, claude-cli, codex-cli, github-copilot
 // Returns the provider, the model ID (without protocol prefix), and any error.
 func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, error) {
 	if cfg == nil {
@@ -92,8 +95,8 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 			cfg.RequestTimeout,
 		), modelID, nil
 
-	case "litellm", "openrouter", "groq", "zhipu", "gemini", "nvidia",
-		"ollama", "moonshot", "shengsuanyun", "deepseek", "cerebras",
+	case "litellm", "openrouter", "groq", "zhipu", "nvidia",
+		"moonshot", "shengsuanyun", "deepseek", "cerebras",
 		"vivgrid", "volcengine", "vllm", "qwen", "mistral", "avian",
 		"minimax":
 		// All other OpenAI-compatible HTTP providers
@@ -112,6 +115,31 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 			cfg.RequestTimeout,
 		), modelID, nil
 
+	case "gemini":
+		// Native Gemini API with an AI Studio key.
+		if cfg.APIKey == "" {
+			return nil, "", fmt.Errorf("api_key is required for gemini protocol (model: %s)", cfg.Model)
+		}
+		return NewGeminiProvider(
+			cfg.APIKey,
+			cfg.APIBase,
+			cfg.Proxy,
+			cfg.SafetySettings,
+			time.Duration(cfg.RequestTimeout)*time.Second,
+		), modelID, nil
+
+	case "ollama":
+		// Native Ollama API; a local server needs no key, and a legacy
+		// OpenAI-style ".../v1" api_base is accepted.
+		return ollama.NewProvider(
+			cfg.APIBase,
+			cfg.Proxy,
+			ollama.WithAPIKey(cfg.APIKey),
+			ollama.WithKeepAlive(cfg.KeepAlive),
+			ollama.WithNumCtx(cfg.ContextWindow),
+			ollama.WithRequestTimeout(time.Duration(cfg.RequestTimeout)*time.Second),
+		), modelID, nil
+
 	case "anthropic":
 		if cfg.AuthMethod == "oauth" || cfg.AuthMethod == "token" {
 			// Use OAuth credentials from auth store
@@ -174,6 +202,70 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 	}
 }
 
+// DefaultAPIBase returns the default API base URL for a given protocol, or
+// an empty string when the protocol has no well-known endpoint.
+func DefaultAPIBase(protocol string) string {
+	return getDefaultAPIBase(protocol)
+}
+
+// OllamaAPIBase returns the native API base of the Ollama server cfg points
+// at: the api_base of the first ollama/ model_list entry that sets one, the
+// legacy providers.ollama api_base, or the local default.
+func OllamaAPIBase(cfg *config.Config) string {
+	if cfg != nil {
+		for _, mc := range cfg.ModelList {
+			if protocol, _ := ExtractProtocol(mc.Model); protocol == "ollama" && mc.APIBase != "" {
+				return ollama.NormalizeAPIBase(mc.APIBase)
+			}
+		}
+		if base := cfg.Providers.Ollama.APIBase; base != "" {
+			return ollama.NormalizeAPIBase(base)
+		}
+	}
+	return ollama.DefaultAPIBase
+}
+
+// OllamaAPIBases returns every Ollama server cfg refers to, as native API
+// bases, starting with OllamaAPIBase(cfg).
+func OllamaAPIBases(cfg *config.Config) []string {
+	bases := []string{OllamaAPIBase(cfg)}
+	add := func(base string) {
+		base = ollama.NormalizeAPIBase(base)
+		if !slices.Contains(bases, base) {
+			bases = append(bases, base)
+		}
+	}
+	if cfg != nil {
+		for _, mc := range cfg.ModelList {
+			if protocol, _ := ExtractProtocol(mc.Model); protocol == "ollama" {
+				add(mc.APIBase)
+			}
+		}
+		if cfg.Providers.Ollama.APIBase != "" {
+			add(cfg.Providers.Ollama.APIBase)
+		}
+	}
+	return bases
+}
+
+// OllamaAPIKey returns the api_key configured for the Ollama server at the
+// native API base apiBase, or "" when none is.
+func OllamaAPIKey(cfg *config.Config, apiBase string) string {
+	if cfg == nil {
+		return ""
+	}
+	for _, mc := range cfg.ModelList {
+		if protocol, _ := ExtractProtocol(mc.Model); protocol == "ollama" && mc.APIKey != "" &&
+			ollama.NormalizeAPIBase(mc.APIBase) == apiBase {
+			return mc.APIKey
+		}
+	}
+	if p := cfg.Providers.Ollama; p.APIKey != "" && ollama.NormalizeAPIBase(p.APIBase) == apiBase {
+		return p.APIKey
+	}
+	return ""
+}
+
 // getDefaultAPIBase returns the default API base URL for a given protocol.
 func getDefaultAPIBase(protocol string) string {
 	switch protocol {
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
 )
 
 func TestExtractProtocol(t *testing.T) {
@@ -112,7 +113,6 @@ func TestCreateProviderFromConfig_DefaultAPIBase(t *testing.T) {
 		{"qwen", "qwen"},
 		{"vllm", "vllm"},
 		{"deepseek", "deepseek"},
-		{"ollama", "ollama"},
 	}
 
 	for _, tt := range tests {
@@ -136,6 +136,49 @@ func TestCreateProviderFromConfig_DefaultAPIBase(t *testing.T) {
 	}
 }
 
+func TestCreateProviderFromConfig_Ollama(t *testing.T) {
+	cfg := &config.ModelConfig{
+		ModelName:     "llama3",
+		Model:         "ollama/llama3.2:3b",
+		ContextWindow: 16384,
+		KeepAlive:     "30m",
+	}
+
+	provider, modelID, err := CreateProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*ollama.Provider); !ok {
+		t.Fatalf("expected *ollama.Provider, got %T", provider)
+	}
+	if modelID != "llama3.2:3b" {
+		t.Errorf("modelID = %q, want %q", modelID, "llama3.2:3b")
+	}
+	if _, ok := provider.(StreamingProvider); !ok {
+		t.Error("ollama provider should support streaming")
+	}
+}
+
+func TestOllamaAPIBase(t *testing.T) {
+	cfg := &config.Config{}
+	if got := OllamaAPIBase(cfg); got != ollama.DefaultAPIBase {
+		t.Errorf("OllamaAPIBase(empty) = %q, want %q", got, ollama.DefaultAPIBase)
+	}
+
+	cfg.Providers.Ollama.APIBase = "http://legacy:11434/v1"
+	if got := OllamaAPIBase(cfg); got != "http://legacy:11434" {
+		t.Errorf("OllamaAPIBase(legacy) = %q", got)
+	}
+
+	cfg.ModelList = []config.ModelConfig{
+		{ModelName: "gpt", Model: "openai/gpt-4o", APIBase: "https://api.openai.com/v1"},
+		{ModelName: "local", Model: "ollama/llama3", APIBase: "http://gpu-box:11434/v1/"},
+	}
+	if got := OllamaAPIBase(cfg); got != "http://gpu-box:11434" {
+		t.Errorf("OllamaAPIBase(model_list) = %q", got)
+	}
+}
+
 func TestGetDefaultAPIBase_LiteLLM(t *testing.T) {
 	if got := getDefaultAPIBase("litellm"); got != "http://localhost:4000/v1" {
 		t.Fatalf("getDefaultAPIBase(%q) = %q, want %q", "litellm", got, "http://localhost:4000/v1")
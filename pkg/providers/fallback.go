@@ -14,8 +14,9 @@ type FallbackChain struct {
 
 // FallbackCandidate represents one model/provider to try.
 type FallbackCandidate struct {
-	Provider string
-	Model    string
+	Provider     string
+	Model        string
+	Capabilities ModelCapabilities
 }
 
 // FallbackResult contains the successful response and metadata about all attempts.
@@ -71,9 +72,11 @@ func ResolveCandidatesWithLookup(
 			return
 		}
 		seen[key] = true
+		caps, _ := LookupCapabilities(ref.Model)
 		candidates = append(candidates, FallbackCandidate{
-			Provider: ref.Provider,
-			Model:    ref.Model,
+			Provider:     ref.Provider,
+			Model:        ref.Model,
+			Capabilities: caps,
 		})
 	}
 
@@ -88,6 +91,22 @@ func ResolveCandidatesWithLookup(
 	return candidates
 }
 
+// RankByCapability orders candidates so that those able to handle a request
+// with requirements req come first, keeping the configured order otherwise.
+// The rest stay as a last resort; callers strip what they cannot accept.
+func RankByCapability(candidates []FallbackCandidate, req ModelRequirements) []FallbackCandidate {
+	ranked := make([]FallbackCandidate, 0, len(candidates))
+	var rest []FallbackCandidate
+	for _, c := range candidates {
+		if c.Capabilities.Satisfies(req) {
+			ranked = append(ranked, c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	return append(ranked, rest...)
+}
+
 // Execute runs the fallback chain for text/chat requests.
 // It tries each candidate in order, respecting cooldowns and error classification.
 //
@@ -0,0 +1,221 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const geminiDefaultRequestTimeout = 120 * time.Second
+
+// GeminiProvider implements LLMProvider using the native Gemini API
+// (generateContent / streamGenerateContent) with an API key from Google AI
+// Studio. Unlike the OpenAI-compatible endpoint it supports inline files,
+// thought signatures, thinking budgets and safety settings.
+type GeminiProvider struct {
+	apiKey         string
+	apiBase        string
+	safetySettings []geminiSafetySetting
+	httpClient     *http.Client
+}
+
+// NewGeminiProvider creates a Gemini provider. An empty apiBase means
+// https://generativelanguage.googleapis.com/v1beta; safetySettings maps harm
+// categories to block thresholds and may be nil.
+func NewGeminiProvider(
+	apiKey, apiBase, proxy string,
+	safetySettings map[string]string,
+	requestTimeout time.Duration,
+) *GeminiProvider {
+	client := &http.Client{Timeout: geminiDefaultRequestTimeout}
+	if requestTimeout > 0 {
+		client.Timeout = requestTimeout
+	}
+	if proxy != "" {
+		if parsed, err := url.Parse(proxy); err == nil {
+			client.Transport = &http.Transport{Proxy: http.ProxyURL(parsed)}
+		} else {
+			logger.WarnCF("provider.gemini", "Ignoring invalid proxy URL", map[string]any{
+				"proxy": proxy,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	base := strings.TrimRight(strings.TrimSpace(apiBase), "/")
+	// An api_base left over from the OpenAI-compatible route.
+	base = strings.TrimSuffix(base, "/openai")
+	if base == "" {
+		base = getDefaultAPIBase("gemini")
+	}
+
+	return &GeminiProvider{
+		apiKey:         apiKey,
+		apiBase:        base,
+		safetySettings: geminiSafetySettings(safetySettings),
+		httpClient:     client,
+	}
+}
+
+// SupportsThinking implements ThinkingCapable.
+func (p *GeminiProvider) SupportsThinking() bool { return true }
+
+// Chat implements LLMProvider.Chat using models/{model}:generateContent.
+func (p *GeminiProvider) Chat(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+) (*LLMResponse, error) {
+	resp, err := p.post(ctx, model, "generateContent", messages, tools, options)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("gemini: failed to parse response: %w", err)
+	}
+
+	var acc geminiAccumulator
+	acc.add(body, nil)
+	return acc.finish()
+}
+
+// ChatStream implements StreamingProvider using
+// models/{model}:streamGenerateContent with server-sent events.
+func (p *GeminiProvider) ChatStream(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+	onDelta func(delta string),
+) (*LLMResponse, error) {
+	resp, err := p.post(ctx, model, "streamGenerateContent?alt=sse", messages, tools, options)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var acc geminiAccumulator
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" {
+			continue
+		}
+
+		var chunk struct {
+			geminiResponse
+			Error *geminiAPIError `json:"error,omitempty"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("gemini: failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("gemini stream error (%s): %s", chunk.Error.Status, chunk.Error.Message)
+		}
+		acc.add(chunk.geminiResponse, onDelta)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("gemini: failed to read stream: %w", err)
+	}
+
+	return acc.finish()
+}
+
+// GetDefaultModel returns the default model identifier.
+func (p *GeminiProvider) GetDefaultModel() string {
+	return ""
+}
+
+// post sends a generateContent-style request to models/{model}:{method}.
+// Non-200 statuses are turned into errors. The caller must close resp.Body.
+func (p *GeminiProvider) post(
+	ctx context.Context,
+	model, method string,
+	messages []Message,
+	tools []ToolDefinition,
+	options map[string]any,
+) (*http.Response, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("gemini: api_key is not configured")
+	}
+
+	req := buildGeminiRequest(messages, tools, options)
+	req.SafetySettings = p.safetySettings
+	bodyBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to marshal request: %w", err)
+	}
+
+	model = strings.TrimPrefix(model, "gemini/")
+	model = strings.TrimPrefix(model, "models/")
+	apiURL := fmt.Sprintf("%s/models/%s:%s", p.apiBase, url.PathEscape(model), method)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, parseGeminiError(resp.StatusCode, body)
+	}
+
+	return resp, nil
+}
+
+// finish returns the accumulated response, or an error when Gemini blocked
+// the prompt outright and produced nothing.
+func (a *geminiAccumulator) finish() (*LLMResponse, error) {
+	resp := a.response()
+	if a.blockReason != "" && resp.Content == "" && len(resp.ToolCalls) == 0 {
+		return nil, fmt.Errorf("gemini: prompt blocked by safety filters (%s)", a.blockReason)
+	}
+	return resp, nil
+}
+
+type geminiAPIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// parseGeminiError turns a Google API error body into an error that keeps
+// the HTTP status and the status name (RESOURCE_EXHAUSTED, ...) visible to
+// the failover classifier.
+func parseGeminiError(statusCode int, body []byte) error {
+	var errResp struct {
+		Error geminiAPIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Message == "" {
+		return fmt.Errorf("gemini API error (HTTP %d): %s", statusCode, truncateString(string(body), 500))
+	}
+	return fmt.Errorf("gemini API error (HTTP %d, %s): %s", statusCode, errResp.Error.Status, errResp.Error.Message)
+}
@@ -0,0 +1,261 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestBuildGeminiRequest_MediaThinkingAndSystem(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "system", Content: "Answer briefly."},
+		{
+			Role:    "user",
+			Content: "Summarize these.",
+			Media:   []string{"data:image/png;base64,iVBORw0KGgo=", "https://example.com/not-inline.png"},
+			Files:   []FileBlock{{Name: "report.pdf", MediaType: "application/pdf", Data: "JVBERi0x"}},
+		},
+	}
+
+	req := buildGeminiRequest(messages, nil, map[string]any{"thinking_level": "medium", "max_tokens": 2048})
+
+	if req.SystemInstruction == nil || len(req.SystemInstruction.Parts) != 2 {
+		t.Fatalf("systemInstruction = %+v, want both system messages", req.SystemInstruction)
+	}
+	if len(req.Contents) != 1 {
+		t.Fatalf("contents = %+v", req.Contents)
+	}
+	parts := req.Contents[0].Parts
+	if len(parts) != 3 || parts[0].Text != "Summarize these." {
+		t.Fatalf("parts = %+v, want text + image + pdf", parts)
+	}
+	if parts[1].InlineData == nil || parts[1].InlineData.MimeType != "image/png" || parts[1].InlineData.Data != "iVBORw0KGgo=" {
+		t.Errorf("image part = %+v", parts[1].InlineData)
+	}
+	if parts[2].InlineData == nil || parts[2].InlineData.MimeType != "application/pdf" {
+		t.Errorf("file part = %+v", parts[2].InlineData)
+	}
+
+	cfg := req.Config
+	if cfg == nil || cfg.MaxOutputTokens != 2048 || cfg.ThinkingConfig == nil {
+		t.Fatalf("generationConfig = %+v", cfg)
+	}
+	if *cfg.ThinkingConfig.ThinkingBudget != 8192 || !cfg.ThinkingConfig.IncludeThoughts {
+		t.Errorf("thinkingConfig = %+v", cfg.ThinkingConfig)
+	}
+
+	// "off" must be sent explicitly, since some models think by default.
+	req = buildGeminiRequest(messages, nil, map[string]any{"thinking_level": "off"})
+	data, _ := json.Marshal(req.Config)
+	if !strings.Contains(string(data), `"thinkingBudget":0`) || strings.Contains(string(data), "includeThoughts") {
+		t.Errorf("generationConfig for off = %s", data)
+	}
+}
+
+func TestBuildGeminiRequest_ResponseFormat(t *testing.T) {
+	messages := []Message{{Role: "user", Content: "List three colors."}}
+	rf := &ResponseFormat{
+		Type: ResponseFormatJSONSchema,
+		Schema: map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{"colors": map[string]any{"type": "array", "minItems": 3}},
+			"additionalProperties": false,
+		},
+	}
+
+	req := buildGeminiRequest(messages, nil, map[string]any{"response_format": rf})
+	if req.Config == nil || req.Config.ResponseMimeType != "application/json" {
+		t.Fatalf("generationConfig = %+v", req.Config)
+	}
+	if _, ok := req.Config.ResponseSchema["additionalProperties"]; ok {
+		t.Errorf("responseSchema was not sanitized: %v", req.Config.ResponseSchema)
+	}
+
+	req = buildGeminiRequest(messages, nil, map[string]any{"response_format": &ResponseFormat{Type: ResponseFormatJSONObject}})
+	if req.Config == nil || req.Config.ResponseMimeType != "application/json" || req.Config.ResponseSchema != nil {
+		t.Errorf("generationConfig for json_object = %+v", req.Config)
+	}
+
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "search"}}}
+	req = buildGeminiRequest(messages, tools, map[string]any{"response_format": rf})
+	if req.Config != nil && req.Config.ResponseMimeType != "" {
+		t.Errorf("JSON mode must not be combined with function declarations: %+v", req.Config)
+	}
+}
+
+func TestGeminiSafetySettings(t *testing.T) {
+	got := geminiSafetySettings(map[string]string{
+		"harassment":                      "block_only_high",
+		"HARM_CATEGORY_DANGEROUS_CONTENT": "BLOCK_NONE",
+	})
+	want := []geminiSafetySetting{
+		{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+		{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("geminiSafetySettings() = %v, want %v", got, want)
+	}
+	if geminiSafetySettings(nil) != nil {
+		t.Error("geminiSafetySettings(nil) should be nil")
+	}
+}
+
+// fakeGemini answers generateContent with resp and records the request.
+func fakeGemini(t *testing.T, resp string, lastReq *map[string]any, lastURL *string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":403,"message":"API key not valid","status":"PERMISSION_DENIED"}}`))
+			return
+		}
+		*lastURL = r.URL.String()
+		json.NewDecoder(r.Body).Decode(lastReq)
+		if strings.Contains(r.URL.Path, ":streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, line := range strings.Split(resp, "\n") {
+				fmt.Fprintf(w, "data: %s\n\n", line)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGeminiProvider_ChatFunctionCall(t *testing.T) {
+	var req map[string]any
+	var reqURL string
+	server := fakeGemini(t, `{
+		"candidates": [{
+			"content": {"role": "model", "parts": [
+				{"text": "Checking the weather.", "thought": true},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}, "thoughtSignature": "sig-1"}
+			]},
+			"finishReason": "STOP"
+		}],
+		"usageMetadata": {"promptTokenCount": 20, "candidatesTokenCount": 5, "thoughtsTokenCount": 7, "totalTokenCount": 32}
+	}`, &req, &reqURL)
+
+	p := NewGeminiProvider("test-key", server.URL+"/openai/", "", map[string]string{"hate_speech": "block_none"}, 0)
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Weather in Paris?"}}, []ToolDefinition{{
+		Type: "function",
+		Function: ToolFunctionDefinition{
+			Name:       "get_weather",
+			Parameters: map[string]any{"type": "object", "additionalProperties": false},
+		},
+	}}, "gemini-2.5-flash", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if reqURL != "/models/gemini-2.5-flash:generateContent" {
+		t.Errorf("request URL = %q", reqURL)
+	}
+	safety, _ := req["safetySettings"].([]any)
+	if len(safety) != 1 || safety[0].(map[string]any)["category"] != "HARM_CATEGORY_HATE_SPEECH" {
+		t.Errorf("safetySettings = %v", req["safetySettings"])
+	}
+	decl := req["tools"].([]any)[0].(map[string]any)["functionDeclarations"].([]any)[0].(map[string]any)
+	if _, ok := decl["parameters"].(map[string]any)["additionalProperties"]; ok {
+		t.Errorf("schema was not sanitized: %v", decl["parameters"])
+	}
+
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	tc := resp.ToolCalls[0]
+	if tc.Name != "get_weather" || tc.Arguments["city"] != "Paris" || tc.Function.ThoughtSignature != "sig-1" {
+		t.Errorf("tool call = %+v (function %+v)", tc, tc.Function)
+	}
+	if resp.Content != "" || resp.ReasoningContent != "Checking the weather." {
+		t.Errorf("content = %q, reasoning = %q", resp.Content, resp.ReasoningContent)
+	}
+	if resp.Usage == nil || resp.Usage.CompletionTokens != 12 || resp.Usage.TotalTokens != 32 {
+		t.Errorf("usage = %+v, want thoughts counted as completion", resp.Usage)
+	}
+}
+
+func TestGeminiProvider_ChatStream(t *testing.T) {
+	var req map[string]any
+	var reqURL string
+	server := fakeGemini(t, strings.Join([]string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":", world"}]},"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}`,
+	}, "\n"), &req, &reqURL)
+
+	var deltas []string
+	p := NewGeminiProvider("test-key", server.URL, "", nil, 0)
+	resp, err := p.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gemini-2.5-pro",
+		map[string]any{"thinking_level": "high"}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if reqURL != "/models/gemini-2.5-pro:streamGenerateContent?alt=sse" {
+		t.Errorf("request URL = %q", reqURL)
+	}
+	if strings.Join(deltas, "|") != "Hello|, world" || resp.Content != "Hello, world" || resp.FinishReason != "length" {
+		t.Errorf("deltas = %q, response = %+v", deltas, resp)
+	}
+	thinking := req["generationConfig"].(map[string]any)["thinkingConfig"].(map[string]any)
+	if thinking["thinkingBudget"] != float64(24576) {
+		t.Errorf("thinkingConfig = %v", thinking)
+	}
+}
+
+func TestGeminiProvider_Errors(t *testing.T) {
+	var req map[string]any
+	var reqURL string
+	server := fakeGemini(t, `{"promptFeedback":{"blockReason":"SAFETY"}}`, &req, &reqURL)
+
+	_, err := NewGeminiProvider("test-key", server.URL, "", nil, 0).
+		Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gemini-2.5-flash", nil)
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("blocked prompt error = %v", err)
+	}
+
+	_, err = NewGeminiProvider("wrong-key", server.URL, "", nil, 0).
+		Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gemini-2.5-flash", nil)
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "API key not valid") {
+		t.Errorf("auth error = %v", err)
+	}
+	if reason := ClassifyError(err, "gemini", "gemini-2.5-flash"); reason == nil || reason.Reason != FailoverAuth {
+		t.Errorf("ClassifyError() = %+v, want auth", reason)
+	}
+}
+
+func TestCreateProviderFromConfig_Gemini(t *testing.T) {
+	cfg := &config.ModelConfig{
+		ModelName:      "gemini",
+		Model:          "gemini/gemini-2.5-flash",
+		APIKey:         "test-key",
+		SafetySettings: map[string]string{"harassment": "block_none"},
+	}
+	provider, modelID, err := CreateProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	gp, ok := provider.(*GeminiProvider)
+	if !ok {
+		t.Fatalf("expected *GeminiProvider, got %T", provider)
+	}
+	if modelID != "gemini-2.5-flash" || gp.apiBase != "https://generativelanguage.googleapis.com/v1beta" {
+		t.Errorf("modelID = %q, apiBase = %q", modelID, gp.apiBase)
+	}
+	if len(gp.safetySettings) != 1 {
+		t.Errorf("safetySettings = %v", gp.safetySettings)
+	}
+
+	cfg.APIKey = ""
+	if _, _, err := CreateProviderFromConfig(cfg); err == nil {
+		t.Error("expected an error without api_key")
+	}
+}
@@ -0,0 +1,507 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Gemini request/response translation shared by GeminiProvider (API keys,
+// generativelanguage.googleapis.com) and AntigravityProvider (OAuth, Cloud
+// Code Assist), which wraps the same request in an envelope.
+
+// --- Request building ---
+
+type geminiRequest struct {
+	Contents          []geminiContent       `json:"contents"`
+	Tools             []geminiTool          `json:"tools,omitempty"`
+	SystemInstruction *geminiContent        `json:"systemInstruction,omitempty"`
+	Config            *geminiGenConfig      `json:"generationConfig,omitempty"`
+	SafetySettings    []geminiSafetySetting `json:"safetySettings,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text                  string                  `json:"text,omitempty"`
+	Thought               bool                    `json:"thought,omitempty"`
+	ThoughtSignature      string                  `json:"thoughtSignature,omitempty"`
+	ThoughtSignatureSnake string                  `json:"thought_signature,omitempty"`
+	InlineData            *geminiBlob             `json:"inlineData,omitempty"`
+	FunctionCall          *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse      *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// geminiBlob is inline media: images, PDFs and other documents.
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
+type geminiFunctionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFuncDecl `json:"functionDeclarations"`
+}
+
+type geminiFuncDecl struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type geminiGenConfig struct {
+	MaxOutputTokens int                   `json:"maxOutputTokens,omitempty"`
+	Temperature     float64               `json:"temperature,omitempty"`
+	ThinkingConfig  *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+	// ResponseMimeType and ResponseSchema request JSON output.
+	ResponseMimeType string         `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any `json:"responseSchema,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"` // 0 disables thinking, -1 lets the model decide
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// geminiThinkingBudgets maps thinking_level to a thinkingBudget in tokens.
+var geminiThinkingBudgets = map[string]int{
+	"off":      0,
+	"low":      1024,
+	"medium":   8192,
+	"high":     24576,
+	"xhigh":    32768,
+	"adaptive": -1,
+}
+
+// buildGeminiRequest translates messages, tools and options
+// (max_tokens, temperature, thinking_level) into a generateContent request.
+func buildGeminiRequest(messages []Message, tools []ToolDefinition, options map[string]any) geminiRequest {
+	req := geminiRequest{}
+	toolCallNames := make(map[string]string)
+
+	toolResult := func(msg Message) geminiContent {
+		return geminiContent{
+			Role: "user",
+			Parts: []geminiPart{{
+				FunctionResponse: &geminiFunctionResponse{
+					Name: resolveToolResponseName(msg.ToolCallID, toolCallNames),
+					Response: map[string]any{
+						"result": msg.Content,
+					},
+				},
+			}},
+		}
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &geminiContent{}
+			}
+			req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, geminiPart{Text: msg.Content})
+		case "user":
+			if msg.ToolCallID != "" {
+				req.Contents = append(req.Contents, toolResult(msg))
+				continue
+			}
+			content := geminiContent{Role: "user"}
+			if msg.Content != "" {
+				content.Parts = append(content.Parts, geminiPart{Text: msg.Content})
+			}
+			content.Parts = append(content.Parts, geminiMediaParts(msg)...)
+			if len(content.Parts) == 0 {
+				content.Parts = []geminiPart{{Text: msg.Content}}
+			}
+			req.Contents = append(req.Contents, content)
+		case "assistant":
+			content := geminiContent{
+				Role: "model",
+			}
+			if msg.Content != "" {
+				content.Parts = append(content.Parts, geminiPart{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				toolName, toolArgs, thoughtSignature := normalizeStoredToolCall(tc)
+				if toolName == "" {
+					logger.WarnCF(
+						"provider.gemini",
+						"Skipping tool call with empty name in history",
+						map[string]any{
+							"tool_call_id": tc.ID,
+						},
+					)
+					continue
+				}
+				if tc.ID != "" {
+					toolCallNames[tc.ID] = toolName
+				}
+				content.Parts = append(content.Parts, geminiPart{
+					ThoughtSignature:      thoughtSignature,
+					ThoughtSignatureSnake: thoughtSignature,
+					FunctionCall: &geminiFunctionCall{
+						Name: toolName,
+						Args: toolArgs,
+					},
+				})
+			}
+			if len(content.Parts) > 0 {
+				req.Contents = append(req.Contents, content)
+			}
+		case "tool":
+			req.Contents = append(req.Contents, toolResult(msg))
+		}
+	}
+
+	// Build tools (sanitize schemas for Gemini compatibility)
+	if len(tools) > 0 {
+		var funcDecls []geminiFuncDecl
+		for _, t := range tools {
+			if t.Type != "function" {
+				continue
+			}
+			params := sanitizeSchemaForGemini(t.Function.Parameters)
+			funcDecls = append(funcDecls, geminiFuncDecl{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  params,
+			})
+		}
+		if len(funcDecls) > 0 {
+			req.Tools = []geminiTool{{FunctionDeclarations: funcDecls}}
+		}
+	}
+
+	// Generation config
+	config := &geminiGenConfig{}
+	if val, ok := options["max_tokens"]; ok {
+		if maxTokens, ok := val.(int); ok && maxTokens > 0 {
+			config.MaxOutputTokens = maxTokens
+		} else if maxTokens, ok := val.(float64); ok && maxTokens > 0 {
+			config.MaxOutputTokens = int(maxTokens)
+		}
+	}
+	if temp, ok := options["temperature"].(float64); ok {
+		config.Temperature = temp
+	}
+	if level, ok := options["thinking_level"].(string); ok {
+		if budget, ok := geminiThinkingBudgets[level]; ok {
+			config.ThinkingConfig = &geminiThinkingConfig{
+				ThinkingBudget:  &budget,
+				IncludeThoughts: budget != 0,
+			}
+		}
+	}
+	// Most models reject a JSON response type alongside function calling,
+	// so with tools declared the caller has to rely on validating the reply.
+	if rf := ResponseFormatOption(options); rf != nil && req.Tools == nil {
+		config.ResponseMimeType = "application/json"
+		if rf.Type == ResponseFormatJSONSchema {
+			config.ResponseSchema = sanitizeSchemaForGemini(rf.Schema)
+		}
+	}
+	if config.MaxOutputTokens > 0 || config.Temperature > 0 || config.ThinkingConfig != nil ||
+		config.ResponseMimeType != "" {
+		req.Config = config
+	}
+
+	return req
+}
+
+// geminiMediaParts returns the attachments of a user message as inline
+// data: data URLs in Media, image blocks and file blocks.
+func geminiMediaParts(msg Message) []geminiPart {
+	var parts []geminiPart
+	for _, ref := range msg.Media {
+		rest, ok := strings.CutPrefix(ref, "data:")
+		if !ok {
+			continue
+		}
+		mimeType, data, ok := strings.Cut(rest, ";base64,")
+		if !ok || data == "" {
+			continue
+		}
+		parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: mimeType, Data: data}})
+	}
+	for _, img := range msg.Images {
+		parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: img.MediaType, Data: img.Data}})
+	}
+	for _, f := range msg.Files {
+		parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: f.MediaType, Data: f.Data}})
+	}
+	return parts
+}
+
+// geminiSafetySettings converts the safety_settings config, keyed by harm
+// category, into request settings. Categories and thresholds may be given
+// in short lower-case form ("harassment": "block_none").
+func geminiSafetySettings(settings map[string]string) []geminiSafetySetting {
+	if len(settings) == 0 {
+		return nil
+	}
+	out := make([]geminiSafetySetting, 0, len(settings))
+	for category, threshold := range settings {
+		category = strings.ToUpper(strings.TrimSpace(category))
+		if !strings.HasPrefix(category, "HARM_CATEGORY_") {
+			category = "HARM_CATEGORY_" + category
+		}
+		out = append(out, geminiSafetySetting{
+			Category:  category,
+			Threshold: strings.ToUpper(strings.TrimSpace(threshold)),
+		})
+	}
+	// Map order is random; keep requests stable for caching and tests.
+	sort.Slice(out, func(i, j int) bool { return out[i].Category < out[j].Category })
+	return out
+}
+
+func normalizeStoredToolCall(tc ToolCall) (string, map[string]any, string) {
+	name := tc.Name
+	args := tc.Arguments
+	thoughtSignature := ""
+
+	if name == "" && tc.Function != nil {
+		name = tc.Function.Name
+		thoughtSignature = tc.Function.ThoughtSignature
+	} else if tc.Function != nil {
+		thoughtSignature = tc.Function.ThoughtSignature
+	}
+
+	if args == nil {
+		args = map[string]any{}
+	}
+
+	if len(args) == 0 && tc.Function != nil && tc.Function.Arguments != "" {
+		var parsed map[string]any
+		if err := json.Unmarshal([]byte(tc.Function.Arguments), &parsed); err == nil && parsed != nil {
+			args = parsed
+		}
+	}
+
+	return name, args, thoughtSignature
+}
+
+func resolveToolResponseName(toolCallID string, toolCallNames map[string]string) string {
+	if toolCallID == "" {
+		return ""
+	}
+
+	if name, ok := toolCallNames[toolCallID]; ok && name != "" {
+		return name
+	}
+
+	return inferToolNameFromCallID(toolCallID)
+}
+
+func inferToolNameFromCallID(toolCallID string) string {
+	if !strings.HasPrefix(toolCallID, "call_") {
+		return toolCallID
+	}
+
+	rest := strings.TrimPrefix(toolCallID, "call_")
+	if idx := strings.LastIndex(rest, "_"); idx > 0 {
+		candidate := rest[:idx]
+		if candidate != "" {
+			return candidate
+		}
+	}
+
+	return toolCallID
+}
+
+// --- Response parsing ---
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []geminiPart `json:"parts"`
+			Role  string       `json:"role"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiAccumulator merges generateContent responses, or the chunks of a
+// streamGenerateContent stream, into one LLMResponse.
+type geminiAccumulator struct {
+	content      strings.Builder
+	reasoning    strings.Builder
+	toolCalls    []ToolCall
+	finishReason string
+	blockReason  string
+	usage        *UsageInfo
+}
+
+func (a *geminiAccumulator) add(resp geminiResponse, onDelta func(string)) {
+	for _, candidate := range resp.Candidates {
+		for _, part := range candidate.Content.Parts {
+			if part.Thought {
+				a.reasoning.WriteString(part.Text)
+				continue
+			}
+			if part.Text != "" {
+				a.content.WriteString(part.Text)
+				if onDelta != nil {
+					onDelta(part.Text)
+				}
+			}
+			if part.FunctionCall != nil {
+				argumentsJSON, _ := json.Marshal(part.FunctionCall.Args)
+				a.toolCalls = append(a.toolCalls, ToolCall{
+					ID:        fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, time.Now().UnixNano()),
+					Name:      part.FunctionCall.Name,
+					Arguments: part.FunctionCall.Args,
+					Function: &FunctionCall{
+						Name:      part.FunctionCall.Name,
+						Arguments: string(argumentsJSON),
+						ThoughtSignature: extractPartThoughtSignature(
+							part.ThoughtSignature,
+							part.ThoughtSignatureSnake,
+						),
+					},
+				})
+			}
+		}
+		if candidate.FinishReason != "" {
+			a.finishReason = candidate.FinishReason
+		}
+	}
+
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		a.blockReason = resp.PromptFeedback.BlockReason
+	}
+
+	if resp.UsageMetadata.TotalTokenCount > 0 {
+		a.usage = &UsageInfo{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount + resp.UsageMetadata.ThoughtsTokenCount,
+			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		}
+	}
+}
+
+func (a *geminiAccumulator) response() *LLMResponse {
+	mappedFinish := "stop"
+	if len(a.toolCalls) > 0 {
+		mappedFinish = "tool_calls"
+	}
+	switch a.finishReason {
+	case "MAX_TOKENS":
+		mappedFinish = "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		mappedFinish = "content_filter"
+	}
+
+	return &LLMResponse{
+		Content:          a.content.String(),
+		ReasoningContent: a.reasoning.String(),
+		ToolCalls:        a.toolCalls,
+		FinishReason:     mappedFinish,
+		Usage:            a.usage,
+	}
+}
+
+func extractPartThoughtSignature(thoughtSignature string, thoughtSignatureSnake string) string {
+	if thoughtSignature != "" {
+		return thoughtSignature
+	}
+	if thoughtSignatureSnake != "" {
+		return thoughtSignatureSnake
+	}
+	return ""
+}
+
+// --- Schema sanitization ---
+
+// Google/Gemini doesn't support many JSON Schema keywords that other providers accept.
+var geminiUnsupportedKeywords = map[string]bool{
+	"patternProperties":    true,
+	"additionalProperties": true,
+	"$schema":              true,
+	"$id":                  true,
+	"$ref":                 true,
+	"$defs":                true,
+	"definitions":          true,
+	"examples":             true,
+	"minLength":            true,
+	"maxLength":            true,
+	"minimum":              true,
+	"maximum":              true,
+	"multipleOf":           true,
+	"pattern":              true,
+	"format":               true,
+	"minItems":             true,
+	"maxItems":             true,
+	"uniqueItems":          true,
+	"minProperties":        true,
+	"maxProperties":        true,
+}
+
+func sanitizeSchemaForGemini(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+
+	result := make(map[string]any)
+	for k, v := range schema {
+		if geminiUnsupportedKeywords[k] {
+			continue
+		}
+		// Recursively sanitize nested objects
+		switch val := v.(type) {
+		case map[string]any:
+			result[k] = sanitizeSchemaForGemini(val)
+		case []any:
+			sanitized := make([]any, len(val))
+			for i, item := range val {
+				if m, ok := item.(map[string]any); ok {
+					sanitized[i] = sanitizeSchemaForGemini(m)
+				} else {
+					sanitized[i] = item
+				}
+			}
+			result[k] = sanitized
+		default:
+			result[k] = v
+		}
+	}
+
+	// Ensure top-level has type: "object" if properties are present
+	if _, hasProps := result["properties"]; hasProps {
+		if _, hasType := result["type"]; !hasType {
+			result["type"] = "object"
+		}
+	}
+
+	return result
+}
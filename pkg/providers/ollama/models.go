@@ -0,0 +1,107 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// LocalModel is a model installed on the Ollama server.
+type LocalModel struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Digest     string    `json:"digest"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Details    struct {
+		Family            string `json:"family,omitempty"`
+		ParameterSize     string `json:"parameter_size,omitempty"`
+		QuantizationLevel string `json:"quantization_level,omitempty"`
+	} `json:"details"`
+}
+
+// PullProgress is one status update of a model download. Total and
+// Completed are byte counts of the layer named by Digest.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// ListModels returns the models installed on the server.
+func (p *Provider) ListModels(ctx context.Context) ([]LocalModel, error) {
+	resp, err := p.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Models []LocalModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
+	if out.Models == nil {
+		out.Models = []LocalModel{}
+	}
+	return out.Models, nil
+}
+
+// PullModel downloads name from the Ollama registry, reporting progress to
+// onProgress when it is non-nil. Downloads can take far longer than a chat
+// request, so only ctx bounds it.
+func (p *Provider) PullModel(ctx context.Context, name string, onProgress func(PullProgress)) error {
+	client := *p.httpClient
+	client.Timeout = 0
+	pull := *p
+	pull.httpClient = &client
+
+	resp, err := pull.do(ctx, http.MethodPost, "/api/pull", map[string]any{"model": name, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var update struct {
+			PullProgress
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(line, &update); err != nil {
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+		if update.Error != "" {
+			return fmt.Errorf("pull %s: %s", name, update.Error)
+		}
+		if onProgress != nil {
+			onProgress(update.PullProgress)
+		}
+		if update.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read pull progress: %w", err)
+	}
+	return fmt.Errorf("pull %s: stream ended before the download completed", name)
+}
+
+// DeleteModel removes name from the server.
+func (p *Provider) DeleteModel(ctx context.Context, name string) error {
+	resp, err := p.do(ctx, http.MethodDelete, "/api/delete", map[string]any{"model": name})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,334 @@
+// Package ollama talks to a local Ollama server through its native API
+// (/api/chat, /api/tags, /api/pull, /api/delete) instead of the
+// OpenAI-compatible shim, which drops keep_alive, num_ctx and thinking.
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+type (
+	ToolCall       = protocoltypes.ToolCall
+	FunctionCall   = protocoltypes.FunctionCall
+	LLMResponse    = protocoltypes.LLMResponse
+	UsageInfo      = protocoltypes.UsageInfo
+	Message        = protocoltypes.Message
+	ToolDefinition = protocoltypes.ToolDefinition
+)
+
+// DefaultAPIBase is where a locally installed Ollama listens.
+const DefaultAPIBase = "http://localhost:11434"
+
+// Local models can take minutes to load and answer on modest hardware.
+const defaultRequestTimeout = 300 * time.Second
+
+type Provider struct {
+	apiBase    string
+	apiKey     string // sent as a bearer token, for servers behind an authenticating proxy or ollama.com
+	keepAlive  string // how long the model stays loaded after a request, e.g. "5m" or "-1"
+	numCtx     int    // context length to load the model with; 0 keeps the server default
+	httpClient *http.Client
+}
+
+type Option func(*Provider)
+
+// WithKeepAlive sets how long Ollama keeps the model in memory after each
+// request ("10m", "1h", "-1" for forever, "0" to unload immediately).
+func WithKeepAlive(keepAlive string) Option {
+	return func(p *Provider) {
+		p.keepAlive = strings.TrimSpace(keepAlive)
+	}
+}
+
+// WithNumCtx sets the context window the model is loaded with. Ollama's own
+// default is small (2–4K tokens) and silently truncates longer prompts.
+func WithNumCtx(numCtx int) Option {
+	return func(p *Provider) {
+		if numCtx > 0 {
+			p.numCtx = numCtx
+		}
+	}
+}
+
+// WithAPIKey sets the key sent as "Authorization: Bearer <key>". A local
+// server needs none.
+func WithAPIKey(apiKey string) Option {
+	return func(p *Provider) {
+		p.apiKey = strings.TrimSpace(apiKey)
+	}
+}
+
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		if timeout > 0 {
+			p.httpClient.Timeout = timeout
+		}
+	}
+}
+
+// NewProvider returns a client for the Ollama server at apiBase. An empty
+// apiBase means DefaultAPIBase; a trailing "/v1" left over from an
+// OpenAI-compatible configuration is ignored.
+func NewProvider(apiBase, proxy string, opts ...Option) *Provider {
+	client := &http.Client{
+		Timeout: defaultRequestTimeout,
+	}
+
+	if proxy != "" {
+		parsed, err := url.Parse(proxy)
+		if err == nil {
+			client.Transport = &http.Transport{
+				Proxy: http.ProxyURL(parsed),
+			}
+		} else {
+			log.Printf("ollama: invalid proxy URL %q: %v", proxy, err)
+		}
+	}
+
+	p := &Provider{
+		apiBase:    NormalizeAPIBase(apiBase),
+		httpClient: client,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+
+	return p
+}
+
+// NormalizeAPIBase strips trailing slashes and an OpenAI-style "/v1" suffix
+// from apiBase, defaulting to DefaultAPIBase.
+func NormalizeAPIBase(apiBase string) string {
+	base := strings.TrimRight(strings.TrimSpace(apiBase), "/")
+	base = strings.TrimSuffix(base, "/v1")
+	if base == "" {
+		return DefaultAPIBase
+	}
+	return base
+}
+
+// SupportsThinking implements providers.ThinkingCapable. Ollama accepts
+// "think" for every model and ignores it for those that cannot reason.
+func (p *Provider) SupportsThinking() bool { return true }
+
+func (p *Provider) Chat(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+) (*LLMResponse, error) {
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+	requestBody["stream"] = false
+
+	resp, err := p.do(ctx, http.MethodPost, "/api/chat", requestBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chunk chatChunk
+	if err := json.NewDecoder(resp.Body).Decode(&chunk); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if chunk.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", chunk.Error)
+	}
+
+	var acc accumulator
+	acc.add(chunk, nil)
+	return acc.response(), nil
+}
+
+// ChatStream is like Chat but reads Ollama's newline-delimited JSON stream
+// and reports each content fragment to onDelta as it arrives.
+func (p *Provider) ChatStream(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+	onDelta func(delta string),
+) (*LLMResponse, error) {
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+	requestBody["stream"] = true
+
+	resp, err := p.do(ctx, http.MethodPost, "/api/chat", requestBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var acc accumulator
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk chatChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama stream error: %s", chunk.Error)
+		}
+		acc.add(chunk, onDelta)
+		if chunk.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return acc.response(), nil
+}
+
+func (p *Provider) GetDefaultModel() string {
+	return ""
+}
+
+func (p *Provider) buildRequestBody(
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+) map[string]any {
+	requestBody := map[string]any{
+		"model":    strings.TrimPrefix(model, "ollama/"),
+		"messages": serializeMessages(messages),
+	}
+
+	if len(tools) > 0 {
+		requestBody["tools"] = tools
+	}
+
+	if p.keepAlive != "" {
+		requestBody["keep_alive"] = p.keepAlive
+	}
+
+	modelOptions := map[string]any{}
+	if p.numCtx > 0 {
+		modelOptions["num_ctx"] = p.numCtx
+	}
+	if maxTokens, ok := asInt(options["max_tokens"]); ok && maxTokens > 0 {
+		modelOptions["num_predict"] = maxTokens
+	}
+	if temperature, ok := asFloat(options["temperature"]); ok {
+		modelOptions["temperature"] = temperature
+	}
+	if len(modelOptions) > 0 {
+		requestBody["options"] = modelOptions
+	}
+
+	if level, ok := options["thinking_level"].(string); ok && level != "" {
+		requestBody["think"] = level != "off"
+	}
+
+	// format constrains decoding to JSON, which would also suppress tool
+	// calls, so it is only sent once the model has no tools left to use.
+	if rf := protocoltypes.ResponseFormatOption(options); rf != nil && len(tools) == 0 {
+		if rf.Type == protocoltypes.ResponseFormatJSONSchema && len(rf.Schema) > 0 {
+			requestBody["format"] = rf.Schema
+		} else {
+			requestBody["format"] = "json"
+		}
+	}
+
+	return requestBody
+}
+
+// do sends body to path as JSON and returns the response. Non-200 statuses
+// are turned into errors carrying Ollama's error message. The caller must
+// close resp.Body.
+func (p *Provider) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.apiBase+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ollama at %s: %w", p.apiBase, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("ollama request failed (status %d): %s", resp.StatusCode, apiErr.Error)
+		}
+		return nil, fmt.Errorf(
+			"ollama request failed:\n  Status: %d\n  Body:   %s",
+			resp.StatusCode,
+			strings.TrimSpace(string(data)),
+		)
+	}
+
+	return resp, nil
+}
+
+func asInt(v any) (int, bool) {
+	switch val := v.(type) {
+	case int:
+		return val, true
+	case int64:
+		return int(val), true
+	case float64:
+		return int(val), true
+	case float32:
+		return int(val), true
+	default:
+		return 0, false
+	}
+}
+
+func asFloat(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	default:
+		return 0, false
+	}
+}
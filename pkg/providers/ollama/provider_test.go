@@ -0,0 +1,384 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+// fakeOllama is an in-process stand-in for an Ollama server. It records
+// the last /api/chat request and answers with the configured chunks,
+// streamed as NDJSON when the request asks for it.
+type fakeOllama struct {
+	mu       sync.Mutex
+	lastReq  map[string]any
+	lastAuth string
+	chunks   []map[string]any
+	models   []string
+	pulled   []string
+	deleted  []string
+}
+
+func (f *fakeOllama) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chat", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.lastReq = req
+		f.lastAuth = r.Header.Get("Authorization")
+		chunks := f.chunks
+		f.mu.Unlock()
+
+		if stream, _ := req["stream"].(bool); stream {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for _, c := range chunks {
+				json.NewEncoder(w).Encode(c)
+			}
+			return
+		}
+		// Non-streaming: merge the chunks into one answer like Ollama does.
+		merged := map[string]any{"done": true}
+		var content strings.Builder
+		msg := map[string]any{"role": "assistant"}
+		for _, c := range chunks {
+			m, _ := c["message"].(map[string]any)
+			if s, ok := m["content"].(string); ok {
+				content.WriteString(s)
+			}
+			if tc, ok := m["tool_calls"]; ok {
+				msg["tool_calls"] = tc
+			}
+			for _, k := range []string{"done_reason", "prompt_eval_count", "eval_count"} {
+				if v, ok := c[k]; ok {
+					merged[k] = v
+				}
+			}
+		}
+		msg["content"] = content.String()
+		merged["message"] = msg
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(merged)
+	})
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		models := make([]map[string]any, 0, len(f.models))
+		for _, name := range f.models {
+			models = append(models, map[string]any{
+				"name":    name,
+				"size":    2019393189,
+				"digest":  "a80c4f17acd5",
+				"details": map[string]any{"family": "llama", "parameter_size": "3.2B"},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"models": models})
+	})
+	mux.HandleFunc("POST /api/pull", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "missing" {
+			json.NewEncoder(w).Encode(map[string]any{"status": "pulling manifest"})
+			json.NewEncoder(w).Encode(map[string]any{"error": "pull model manifest: file does not exist"})
+			return
+		}
+		f.mu.Lock()
+		f.pulled = append(f.pulled, req.Model)
+		f.models = append(f.models, req.Model)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"status": "pulling manifest"})
+		json.NewEncoder(w).Encode(map[string]any{"status": "pulling dde5aa3fc5ff", "digest": "dde5aa3fc5ff", "total": 100, "completed": 50})
+		json.NewEncoder(w).Encode(map[string]any{"status": "pulling dde5aa3fc5ff", "digest": "dde5aa3fc5ff", "total": 100, "completed": 100})
+		json.NewEncoder(w).Encode(map[string]any{"status": "success"})
+	})
+	mux.HandleFunc("DELETE /api/delete", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, name := range f.models {
+			if name == req.Model {
+				f.models = append(f.models[:i], f.models[i+1:]...)
+				f.deleted = append(f.deleted, name)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error":"model '%s' not found"}`, req.Model)
+	})
+	return mux
+}
+
+func newFake(t *testing.T, f *fakeOllama) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(f.handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNormalizeAPIBase(t *testing.T) {
+	tests := map[string]string{
+		"":                           DefaultAPIBase,
+		"http://localhost:11434/v1":  "http://localhost:11434",
+		"http://localhost:11434/v1/": "http://localhost:11434",
+		"http://gpu-box:11434/":      "http://gpu-box:11434",
+	}
+	for in, want := range tests {
+		if got := NormalizeAPIBase(in); got != want {
+			t.Errorf("NormalizeAPIBase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestChat_SendsNativeRequest(t *testing.T) {
+	fake := &fakeOllama{chunks: []map[string]any{
+		{"message": map[string]any{"role": "assistant", "content": "It is sunny."}, "done": true, "done_reason": "stop", "prompt_eval_count": 42, "eval_count": 5},
+	}}
+	server := newFake(t, fake)
+
+	p := NewProvider(server.URL+"/v1", "", WithKeepAlive("30m"), WithNumCtx(16384))
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "What is on this picture?", Media: []string{"data:image/png;base64,iVBORw0KGgo="}},
+		{Role: "assistant", ToolCalls: []ToolCall{{
+			ID:       "call_1",
+			Type:     "function",
+			Function: &FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+		}}},
+		{Role: "tool", Content: "sunny", ToolCallID: "call_1"},
+	}
+	tools := []ToolDefinition{{
+		Type: "function",
+		Function: protocoltypes.ToolFunctionDefinition{
+			Name:       "get_weather",
+			Parameters: map[string]any{"type": "object"},
+		},
+	}}
+
+	resp, err := p.Chat(t.Context(), messages, tools, "llama3.2", map[string]any{
+		"max_tokens":     512,
+		"temperature":    0.2,
+		"thinking_level": "off",
+	})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.Content != "It is sunny." || resp.FinishReason != "stop" {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 42 || resp.Usage.TotalTokens != 47 {
+		t.Errorf("usage = %+v, want 42 prompt / 47 total", resp.Usage)
+	}
+
+	req := fake.lastReq
+	if req["model"] != "llama3.2" || req["stream"] != false || req["keep_alive"] != "30m" || req["think"] != false {
+		t.Errorf("request = %v", req)
+	}
+	opts, _ := req["options"].(map[string]any)
+	if opts["num_ctx"] != float64(16384) || opts["num_predict"] != float64(512) || opts["temperature"] != 0.2 {
+		t.Errorf("options = %v", opts)
+	}
+	if tools, _ := req["tools"].([]any); len(tools) != 1 {
+		t.Errorf("tools = %v", req["tools"])
+	}
+
+	msgs, _ := req["messages"].([]any)
+	if len(msgs) != 4 {
+		t.Fatalf("messages = %v", msgs)
+	}
+	user := msgs[1].(map[string]any)
+	if images, _ := user["images"].([]any); len(images) != 1 || images[0] != "iVBORw0KGgo=" {
+		t.Errorf("user images = %v, want bare base64", user["images"])
+	}
+	assistant := msgs[2].(map[string]any)
+	call := assistant["tool_calls"].([]any)[0].(map[string]any)["function"].(map[string]any)
+	if args, _ := call["arguments"].(map[string]any); args["city"] != "Paris" {
+		t.Errorf("tool call arguments = %v, want a JSON object", call["arguments"])
+	}
+	if tool := msgs[3].(map[string]any); tool["tool_name"] != "get_weather" {
+		t.Errorf("tool result = %v, want tool_name get_weather", tool)
+	}
+}
+
+func TestChat_SendsAPIKey(t *testing.T) {
+	fake := &fakeOllama{chunks: []map[string]any{
+		{"message": map[string]any{"role": "assistant", "content": "hi"}, "done": true},
+	}}
+	server := newFake(t, fake)
+
+	if _, err := NewProvider(server.URL, "").Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "llama3.2", nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if fake.lastAuth != "" {
+		t.Errorf("Authorization without a key = %q, want none", fake.lastAuth)
+	}
+
+	p := NewProvider(server.URL, "", WithAPIKey("secret"))
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "llama3.2", nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if fake.lastAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the bearer key", fake.lastAuth)
+	}
+}
+
+func TestBuildRequestBody_ResponseFormat(t *testing.T) {
+	p := NewProvider("", "")
+	schema := map[string]any{"type": "object", "properties": map[string]any{"ok": map[string]any{"type": "boolean"}}}
+	messages := []Message{{Role: "user", Content: "hi"}}
+	rf := &protocoltypes.ResponseFormat{Type: protocoltypes.ResponseFormatJSONSchema, Schema: schema}
+
+	body := p.buildRequestBody(messages, nil, "llama3.2", map[string]any{"response_format": rf})
+	if format, _ := body["format"].(map[string]any); format["type"] != "object" {
+		t.Errorf("format = %v, want the schema", body["format"])
+	}
+
+	body = p.buildRequestBody(messages, nil, "llama3.2", map[string]any{
+		"response_format": &protocoltypes.ResponseFormat{Type: protocoltypes.ResponseFormatJSONObject},
+	})
+	if body["format"] != "json" {
+		t.Errorf("format = %v, want json", body["format"])
+	}
+
+	tools := []ToolDefinition{{Type: "function", Function: protocoltypes.ToolFunctionDefinition{Name: "search"}}}
+	body = p.buildRequestBody(messages, tools, "llama3.2", map[string]any{"response_format": rf})
+	if _, ok := body["format"]; ok {
+		t.Errorf("format must not be sent while tools are available: %v", body["format"])
+	}
+}
+
+func TestChat_ParsesToolCalls(t *testing.T) {
+	fake := &fakeOllama{chunks: []map[string]any{
+		{
+			"message": map[string]any{
+				"role":    "assistant",
+				"content": "",
+				"tool_calls": []map[string]any{
+					{"function": map[string]any{"name": "get_weather", "arguments": map[string]any{"city": "SF"}}},
+					{"function": map[string]any{"name": "get_time", "arguments": map[string]any{}}},
+				},
+			},
+			"done":        true,
+			"done_reason": "stop",
+		},
+	}}
+	server := newFake(t, fake)
+
+	resp, err := NewProvider(server.URL, "").Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "qwen3", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	first := resp.ToolCalls[0]
+	if first.Name != "get_weather" || first.Arguments["city"] != "SF" {
+		t.Errorf("first tool call = %+v", first)
+	}
+	if first.ID == "" || first.ID == resp.ToolCalls[1].ID {
+		t.Errorf("tool call IDs must be unique and non-empty: %q, %q", first.ID, resp.ToolCalls[1].ID)
+	}
+	if _, ok := fake.lastReq["options"]; ok {
+		t.Errorf("options should be omitted when nothing is set: %v", fake.lastReq["options"])
+	}
+}
+
+func TestChatStream_DeliversDeltas(t *testing.T) {
+	fake := &fakeOllama{chunks: []map[string]any{
+		{"message": map[string]any{"role": "assistant", "content": "", "thinking": "Hmm. "}, "done": false},
+		{"message": map[string]any{"role": "assistant", "content": "Hello"}, "done": false},
+		{"message": map[string]any{"role": "assistant", "content": ", world"}, "done": false},
+		{"message": map[string]any{"role": "assistant", "content": ""}, "done": true, "done_reason": "length", "prompt_eval_count": 3, "eval_count": 2},
+	}}
+	server := newFake(t, fake)
+
+	var deltas []string
+	resp, err := NewProvider(server.URL, "").ChatStream(
+		t.Context(),
+		[]Message{{Role: "user", Content: "hi"}},
+		nil,
+		"qwen3",
+		map[string]any{"thinking_level": "high"},
+		func(d string) { deltas = append(deltas, d) },
+	)
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if strings.Join(deltas, "|") != "Hello|, world" {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if resp.Content != "Hello, world" || resp.ReasoningContent != "Hmm. " || resp.FinishReason != "length" {
+		t.Errorf("response = %+v", resp)
+	}
+	if fake.lastReq["think"] != true {
+		t.Errorf("think = %v, want true", fake.lastReq["think"])
+	}
+}
+
+func TestChat_ReportsServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model \"nope\" not found, try pulling it first"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewProvider(server.URL, "").Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "nope", nil)
+	if err == nil || !strings.Contains(err.Error(), "try pulling it first") {
+		t.Fatalf("Chat() error = %v, want Ollama's message", err)
+	}
+}
+
+func TestModelManagement(t *testing.T) {
+	fake := &fakeOllama{models: []string{"llama3.2:3b"}}
+	server := newFake(t, fake)
+	p := NewProvider(server.URL, "")
+	ctx := t.Context()
+
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "llama3.2:3b" || models[0].Details.ParameterSize != "3.2B" {
+		t.Fatalf("models = %+v", models)
+	}
+
+	var updates []PullProgress
+	if err := p.PullModel(ctx, "qwen3:8b", func(u PullProgress) { updates = append(updates, u) }); err != nil {
+		t.Fatalf("PullModel() error = %v", err)
+	}
+	if len(updates) != 4 || updates[2].Completed != 100 || updates[3].Status != "success" {
+		t.Errorf("progress = %+v", updates)
+	}
+
+	if err := p.PullModel(ctx, "missing", nil); err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("PullModel(missing) error = %v", err)
+	}
+
+	if err := p.DeleteModel(ctx, "llama3.2:3b"); err != nil {
+		t.Fatalf("DeleteModel() error = %v", err)
+	}
+	if err := p.DeleteModel(ctx, "llama3.2:3b"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("second DeleteModel() error = %v, want not found", err)
+	}
+
+	models, err = p.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "qwen3:8b" {
+		t.Errorf("models after pull and delete = %+v", models)
+	}
+}
@@ -0,0 +1,176 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ollamaMessage is the wire format of a /api/chat message. Images are bare
+// base64 strings, tool call arguments are JSON objects rather than encoded
+// strings, and tool results name the tool instead of a call ID.
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+// chatChunk is one /api/chat response object: the whole answer when not
+// streaming, one fragment of it otherwise. Counters arrive with Done.
+type chatChunk struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// serializeMessages converts internal messages to the /api/chat format.
+// Images come from data URLs in Media and from Images blocks; other
+// attachments are not supported by Ollama and are dropped.
+func serializeMessages(messages []Message) []ollamaMessage {
+	toolNames := make(map[string]string)
+	out := make([]ollamaMessage, 0, len(messages))
+	for _, m := range messages {
+		msg := ollamaMessage{
+			Role:     m.Role,
+			Content:  m.Content,
+			Thinking: m.ReasoningContent,
+		}
+
+		for _, ref := range m.Media {
+			if data, ok := imageData(ref); ok {
+				msg.Images = append(msg.Images, data)
+			}
+		}
+		for _, img := range m.Images {
+			msg.Images = append(msg.Images, img.Data)
+		}
+
+		for _, tc := range m.ToolCalls {
+			var call ollamaToolCall
+			call.ID = tc.ID
+			call.Function.Name, call.Function.Arguments = toolCallParts(tc)
+			if call.Function.Arguments == nil {
+				call.Function.Arguments = map[string]any{}
+			}
+			toolNames[tc.ID] = call.Function.Name
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+
+		if m.ToolCallID != "" {
+			msg.ToolName = toolNames[m.ToolCallID]
+		}
+
+		out = append(out, msg)
+	}
+	return out
+}
+
+// imageData extracts the base64 payload of a data:image/... URL.
+func imageData(ref string) (string, bool) {
+	if !strings.HasPrefix(ref, "data:image/") {
+		return "", false
+	}
+	_, data, ok := strings.Cut(ref, ";base64,")
+	return data, ok && data != ""
+}
+
+// toolCallParts returns the name and arguments of a tool call from history,
+// which carries them either decoded or as an OpenAI-style function object.
+func toolCallParts(tc ToolCall) (string, map[string]any) {
+	if tc.Function == nil {
+		return tc.Name, tc.Arguments
+	}
+	name := tc.Function.Name
+	if name == "" {
+		name = tc.Name
+	}
+	if tc.Function.Arguments == "" {
+		return name, tc.Arguments
+	}
+	var arguments map[string]any
+	if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
+		log.Printf("ollama: failed to decode tool call arguments for %q: %v", name, err)
+		arguments = map[string]any{"raw": tc.Function.Arguments}
+	}
+	return name, arguments
+}
+
+// accumulator merges chat chunks into one response.
+type accumulator struct {
+	content    strings.Builder
+	thinking   strings.Builder
+	toolCalls  []ToolCall
+	doneReason string
+	usage      *UsageInfo
+}
+
+func (a *accumulator) add(chunk chatChunk, onDelta func(string)) {
+	if chunk.Message.Content != "" {
+		a.content.WriteString(chunk.Message.Content)
+		if onDelta != nil {
+			onDelta(chunk.Message.Content)
+		}
+	}
+	a.thinking.WriteString(chunk.Message.Thinking)
+
+	for _, tc := range chunk.Message.ToolCalls {
+		id := tc.ID
+		if id == "" {
+			// Ollama does not number tool calls; results are matched by
+			// position and name, so any unique ID will do.
+			id = fmt.Sprintf("call_%d", len(a.toolCalls)+1)
+		}
+		arguments := tc.Function.Arguments
+		if arguments == nil {
+			arguments = map[string]any{}
+		}
+		a.toolCalls = append(a.toolCalls, ToolCall{
+			ID:        id,
+			Name:      tc.Function.Name,
+			Arguments: arguments,
+		})
+	}
+
+	if chunk.Done {
+		a.doneReason = chunk.DoneReason
+		if chunk.PromptEvalCount > 0 || chunk.EvalCount > 0 {
+			a.usage = &UsageInfo{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+				TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+			}
+		}
+	}
+}
+
+func (a *accumulator) response() *LLMResponse {
+	finishReason := a.doneReason
+	switch {
+	case len(a.toolCalls) > 0:
+		finishReason = "tool_calls"
+	case finishReason == "":
+		finishReason = "stop"
+	}
+	return &LLMResponse{
+		Content:          a.content.String(),
+		ReasoningContent: a.thinking.String(),
+		ToolCalls:        a.toolCalls,
+		FinishReason:     finishReason,
+		Usage:            a.usage,
+	}
+}
@@ -27,6 +27,7 @@ type (
 	ExtraContent           = protocoltypes.ExtraContent
 	GoogleExtra            = protocoltypes.GoogleExtra
 	ReasoningDetail        = protocoltypes.ReasoningDetail
+	ResponseFormat         = protocoltypes.ResponseFormat
 )
 
 type Provider struct {
@@ -113,6 +114,73 @@ func (p *Provider) Chat(
 		return nil, fmt.Errorf("API base not configured")
 	}
 
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+
+	resp, reader, err := p.post(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out, err := parseResponse(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	return out, nil
+}
+
+// ChatStream is like Chat but asks the endpoint for a server-sent event stream
+// and reports each content fragment to onDelta as it arrives.
+func (p *Provider) ChatStream(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+	onDelta func(delta string),
+) (*LLMResponse, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
+	}
+
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+	requestBody["stream"] = true
+	requestBody["stream_options"] = map[string]any{"include_usage": true}
+
+	resp, reader, err := p.post(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Some OpenAI-compatible servers ignore "stream" and answer with a
+	// regular JSON body; handle that transparently.
+	if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/event-stream") {
+		out, err := parseResponse(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+		}
+		if onDelta != nil && out.Content != "" {
+			onDelta(out.Content)
+		}
+		return out, nil
+	}
+
+	out, err := parseStream(reader, onDelta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stream response: %w", err)
+	}
+
+	return out, nil
+}
+
+func (p *Provider) buildRequestBody(
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+) map[string]any {
 	model = normalizeModel(model, p.apiBase)
 
 	requestBody := map[string]any{
@@ -163,14 +231,51 @@ func (p *Provider) Chat(
 		}
 	}
 
+	if rf := protocoltypes.ResponseFormatOption(options); rf != nil {
+		requestBody["response_format"] = responseFormatBody(rf)
+	}
+
+	return requestBody
+}
+
+// responseFormatBody maps rf onto the chat completions response_format
+// parameter. A schema-less json_schema request degrades to json_object.
+func responseFormatBody(rf *ResponseFormat) map[string]any {
+	if rf.Type != protocoltypes.ResponseFormatJSONSchema || len(rf.Schema) == 0 {
+		return map[string]any{"type": protocoltypes.ResponseFormatJSONObject}
+	}
+	name := rf.Name
+	if name == "" {
+		name = "response"
+	}
+	schema := map[string]any{
+		"name":   name,
+		"schema": rf.Schema,
+	}
+	if rf.Description != "" {
+		schema["description"] = rf.Description
+	}
+	if rf.Strict {
+		schema["strict"] = true
+	}
+	return map[string]any{
+		"type":        protocoltypes.ResponseFormatJSONSchema,
+		"json_schema": schema,
+	}
+}
+
+// post sends requestBody to the chat completions endpoint and returns the
+// response together with a buffered reader over its body. Non-200 statuses
+// and HTML pages are turned into errors. The caller must close resp.Body.
+func (p *Provider) post(ctx context.Context, requestBody map[string]any) (*http.Response, *bufio.Reader, error) {
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -180,44 +285,41 @@ func (p *Provider) Chat(
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer resp.Body.Close()
 
 	contentType := resp.Header.Get("Content-Type")
 
 	// Non-200: read a prefix to tell HTML error page apart from JSON error body.
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		body, readErr := io.ReadAll(io.LimitReader(resp.Body, 256))
 		if readErr != nil {
-			return nil, fmt.Errorf("failed to read response: %w", readErr)
+			return nil, nil, fmt.Errorf("failed to read response: %w", readErr)
 		}
 		if looksLikeHTML(body, contentType) {
-			return nil, wrapHTMLResponseError(resp.StatusCode, body, contentType, p.apiBase)
+			return nil, nil, wrapHTMLResponseError(resp.StatusCode, body, contentType, p.apiBase)
 		}
-		return nil, fmt.Errorf(
+		return nil, nil, fmt.Errorf(
 			"API request failed:\n  Status: %d\n  Body:   %s",
 			resp.StatusCode,
 			responsePreview(body, 128),
 		)
 	}
 
-	// Peek without consuming so the full stream reaches the JSON decoder.
+	// Peek without consuming so the full stream reaches the decoder.
 	reader := bufio.NewReader(resp.Body)
 	prefix, err := reader.Peek(256) // io.EOF/ErrBufferFull are normal; only real errors abort
 	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
-		return nil, fmt.Errorf("failed to inspect response: %w", err)
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("failed to inspect response: %w", err)
 	}
 	if looksLikeHTML(prefix, contentType) {
-		return nil, wrapHTMLResponseError(resp.StatusCode, prefix, contentType, p.apiBase)
-	}
-
-	out, err := parseResponse(reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+		resp.Body.Close()
+		return nil, nil, wrapHTMLResponseError(resp.StatusCode, prefix, contentType, p.apiBase)
 	}
 
-	return out, nil
+	return resp, reader, nil
 }
 
 func wrapHTMLResponseError(statusCode int, body []byte, contentType, apiBase string) error {
@@ -312,7 +414,7 @@ func parseResponse(body io.Reader) (*LLMResponse, error) {
 	choice := apiResponse.Choices[0]
 	toolCalls := make([]ToolCall, 0, len(choice.Message.ToolCalls))
 	for _, tc := range choice.Message.ToolCalls {
-		arguments := make(map[string]any)
+		var arguments map[string]any
 		name := ""
 
 		// Extract thought_signature from Gemini/Google-specific extra content
@@ -323,31 +425,12 @@ func parseResponse(body io.Reader) (*LLMResponse, error) {
 
 		if tc.Function != nil {
 			name = tc.Function.Name
-			if tc.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
-					log.Printf("openai_compat: failed to decode tool call arguments for %q: %v", name, err)
-					arguments["raw"] = tc.Function.Arguments
-				}
-			}
-		}
-
-		// Build ToolCall with ExtraContent for Gemini 3 thought_signature persistence
-		toolCall := ToolCall{
-			ID:               tc.ID,
-			Name:             name,
-			Arguments:        arguments,
-			ThoughtSignature: thoughtSignature,
-		}
-
-		if thoughtSignature != "" {
-			toolCall.ExtraContent = &ExtraContent{
-				Google: &GoogleExtra{
-					ThoughtSignature: thoughtSignature,
-				},
-			}
+			arguments = decodeToolArguments(name, tc.Function.Arguments)
+		} else {
+			arguments = make(map[string]any)
 		}
 
-		toolCalls = append(toolCalls, toolCall)
+		toolCalls = append(toolCalls, newToolCall(tc.ID, name, arguments, thoughtSignature))
 	}
 
 	return &LLMResponse{
@@ -361,6 +444,41 @@ func parseResponse(body io.Reader) (*LLMResponse, error) {
 	}, nil
 }
 
+// decodeToolArguments parses the JSON-encoded arguments of a tool call.
+// Undecodable input is preserved under the "raw" key.
+func decodeToolArguments(name, raw string) map[string]any {
+	arguments := make(map[string]any)
+	if raw == "" {
+		return arguments
+	}
+	if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+		log.Printf("openai_compat: failed to decode tool call arguments for %q: %v", name, err)
+		arguments["raw"] = raw
+	}
+	return arguments
+}
+
+// newToolCall builds a ToolCall, attaching ExtraContent so that Gemini 3
+// thought_signature values survive a round trip through history.
+func newToolCall(id, name string, arguments map[string]any, thoughtSignature string) ToolCall {
+	toolCall := ToolCall{
+		ID:               id,
+		Name:             name,
+		Arguments:        arguments,
+		ThoughtSignature: thoughtSignature,
+	}
+
+	if thoughtSignature != "" {
+		toolCall.ExtraContent = &ExtraContent{
+			Google: &GoogleExtra{
+				ThoughtSignature: thoughtSignature,
+			},
+		}
+	}
+
+	return toolCall
+}
+
 // openaiMessage is the wire-format message for OpenAI-compatible APIs.
 // It mirrors protocoltypes.Message but omits SystemParts, which is an
 // internal field that would be unknown to third-party endpoints.
@@ -511,6 +511,71 @@ func TestProviderChat_AcceptsNumericOptionTypes(t *testing.T) {
 }
 
 
+func TestProviderChat_SendsResponseFormat(t *testing.T) {
+	var requestBody map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]any{
+			"choices": []map[string]any{
+				{
+					"message":       map[string]any{"content": `{"ok":true}`},
+					"finish_reason": "stop",
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "")
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"ok": map[string]any{"type": "boolean"}},
+	}
+	_, err := p.Chat(
+		t.Context(),
+		[]Message{{Role: "user", Content: "hi"}},
+		nil,
+		"gpt-4o",
+		map[string]any{"response_format": &ResponseFormat{
+			Type:   protocoltypes.ResponseFormatJSONSchema,
+			Schema: schema,
+			Strict: true,
+		}},
+	)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	rf, _ := requestBody["response_format"].(map[string]any)
+	if rf["type"] != "json_schema" {
+		t.Fatalf("response_format = %v", requestBody["response_format"])
+	}
+	js, _ := rf["json_schema"].(map[string]any)
+	if js["name"] != "response" || js["strict"] != true || js["schema"] == nil {
+		t.Errorf("json_schema = %v", js)
+	}
+
+	_, err = p.Chat(
+		t.Context(),
+		[]Message{{Role: "user", Content: "hi"}},
+		nil,
+		"gpt-4o",
+		map[string]any{"response_format": ResponseFormat{Type: protocoltypes.ResponseFormatJSONObject}},
+	)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if rf, _ := requestBody["response_format"].(map[string]any); rf["type"] != "json_object" || rf["json_schema"] != nil {
+		t.Errorf("response_format = %v, want json_object", requestBody["response_format"])
+	}
+}
+
 func TestNormalizeModel_UsesAPIBase(t *testing.T) {
 	if got := normalizeModel("deepseek/deepseek-chat", "https://api.deepseek.com/v1"); got != "deepseek-chat" {
 		t.Fatalf("normalizeModel(deepseek) = %q, want %q", got, "deepseek-chat")
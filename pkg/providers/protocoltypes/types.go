@@ -111,3 +111,37 @@ type ToolFunctionDefinition struct {
 	Description string         `json:"description"`
 	Parameters  map[string]any `json:"parameters"`
 }
+
+// Response format types understood by ResponseFormat.Type.
+const (
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat asks the model to answer with machine-readable JSON instead
+// of free text. It is passed to providers as options["response_format"];
+// each adapter maps it onto its native mechanism (response_format,
+// responseSchema, forced tool use, ...).
+type ResponseFormat struct {
+	Type        string         `json:"type"`                  // "json_object" or "json_schema"
+	Name        string         `json:"name,omitempty"`        // schema name, required by some APIs
+	Description string         `json:"description,omitempty"` // what the output represents
+	Schema      map[string]any `json:"schema,omitempty"`      // JSON Schema, for "json_schema"
+	Strict      bool           `json:"strict,omitempty"`      // ask for strict schema adherence where supported
+}
+
+// ResponseFormatOption returns the response format requested in a provider
+// options map, or nil when the caller asked for plain text.
+func ResponseFormatOption(options map[string]any) *ResponseFormat {
+	switch rf := options["response_format"].(type) {
+	case *ResponseFormat:
+		if rf != nil && rf.Type != "" {
+			return rf
+		}
+	case ResponseFormat:
+		if rf.Type != "" {
+			return &rf
+		}
+	}
+	return nil
+}
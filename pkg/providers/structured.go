@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// ParseStructuredOutput extracts the JSON value from a model reply and checks
+// it against format. Models without native JSON modes tend to wrap the value
+// in a markdown fence or a sentence of prose, so those are stripped first.
+// On success the compact JSON is returned; the error is meant to be shown to
+// the model so it can repair its answer.
+func ParseStructuredOutput(content string, format *ResponseFormat) (json.RawMessage, error) {
+	raw := extractJSON(content)
+	if raw == "" {
+		return nil, fmt.Errorf("the reply does not contain a JSON value")
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, fmt.Errorf("the reply is not valid JSON: %w", err)
+	}
+
+	if format == nil || format.Type != ResponseFormatJSONSchema || len(format.Schema) == 0 {
+		if _, ok := value.(map[string]any); !ok {
+			return nil, fmt.Errorf("the reply must be a JSON object")
+		}
+	} else {
+		resolved, err := resolveSchema(format.Schema)
+		if err != nil {
+			return nil, err
+		}
+		if err := resolved.Validate(value); err != nil {
+			return nil, fmt.Errorf("the reply does not match the schema: %w", err)
+		}
+	}
+
+	compact, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode structured output: %w", err)
+	}
+	return compact, nil
+}
+
+// ValidateResponseSchema reports whether schema is a JSON Schema that
+// ParseStructuredOutput can validate against.
+func ValidateResponseSchema(schema map[string]any) error {
+	_, err := resolveSchema(schema)
+	return err
+}
+
+func resolveSchema(schema map[string]any) (*jsonschema.Resolved, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response schema: %w", err)
+	}
+	var s jsonschema.Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid response schema: %w", err)
+	}
+	resolved, err := s.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response schema: %w", err)
+	}
+	return resolved, nil
+}
+
+// extractJSON returns the JSON text in content: content itself when it is
+// valid JSON, which may quote a fence inside a string value; otherwise the
+// body of a ```json fence when there is one, or else the span from the
+// first '{' or '[' to the last matching closing bracket.
+func extractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if json.Valid([]byte(content)) {
+		return content
+	}
+	if start := strings.Index(content, "```"); start >= 0 {
+		body := content[start+3:]
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			body = body[nl+1:] // drop the language tag
+		}
+		if end := strings.Index(body, "```"); end >= 0 {
+			return strings.TrimSpace(body[:end])
+		}
+	}
+
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return ""
+	}
+	closer := "}"
+	if content[start] == '[' {
+		closer = "]"
+	}
+	end := strings.LastIndex(content, closer)
+	if end < start {
+		return ""
+	}
+	return content[start : end+1]
+}
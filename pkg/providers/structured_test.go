@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStructuredOutput(t *testing.T) {
+	format := &ResponseFormat{
+		Type: ResponseFormatJSONSchema,
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"city":  map[string]any{"type": "string"},
+				"temp":  map[string]any{"type": "number"},
+				"sunny": map[string]any{"type": "boolean"},
+			},
+			"required": []any{"city", "temp"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{"plain", `{"city": "Paris", "temp": 21.5}`, `{"city":"Paris","temp":21.5}`, ""},
+		{"fenced", "Here you go:\n```json\n{\"city\":\"Oslo\",\"temp\":-3}\n```", `{"city":"Oslo","temp":-3}`, ""},
+		{
+			"fence inside a string", `{"city":"Use ` + "```go\\nx := 1\\n```" + ` here","temp":1}`,
+			`{"city":"Use ` + "```go\\nx := 1\\n```" + ` here","temp":1}`, "",
+		},
+		{"prose", `The result is {"city":"Rome","temp":30,"sunny":true}.`, `{"city":"Rome","sunny":true,"temp":30}`, ""},
+		{"missing field", `{"city":"Paris"}`, "", "does not match the schema"},
+		{"wrong type", `{"city":"Paris","temp":"warm"}`, "", "does not match the schema"},
+		{"not json", `It is warm in Paris.`, "", "does not contain a JSON value"},
+		{"broken json", `{"city": "Paris",}`, "", "not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStructuredOutput(tt.content, format)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseStructuredOutput() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStructuredOutput() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ParseStructuredOutput() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStructuredOutput_JSONObject(t *testing.T) {
+	format := &ResponseFormat{Type: ResponseFormatJSONObject}
+	if _, err := ParseStructuredOutput(`{"any": ["thing"]}`, format); err != nil {
+		t.Errorf("object rejected: %v", err)
+	}
+	if _, err := ParseStructuredOutput(`[1, 2]`, format); err == nil {
+		t.Error("json_object must reject arrays")
+	}
+}
+
+func TestValidateResponseSchema(t *testing.T) {
+	if err := ValidateResponseSchema(map[string]any{"type": "object"}); err != nil {
+		t.Errorf("valid schema rejected: %v", err)
+	}
+	if err := ValidateResponseSchema(map[string]any{"type": 42}); err == nil {
+		t.Error("expected an error for a malformed schema")
+	}
+}
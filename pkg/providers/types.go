@@ -22,8 +22,21 @@ type (
 	ImageBlock             = protocoltypes.ImageBlock
 	FileBlock              = protocoltypes.FileBlock
 	FileRefMeta            = protocoltypes.FileRefMeta
+	ResponseFormat         = protocoltypes.ResponseFormat
 )
 
+// Response format types, see ResponseFormat.
+const (
+	ResponseFormatJSONObject = protocoltypes.ResponseFormatJSONObject
+	ResponseFormatJSONSchema = protocoltypes.ResponseFormatJSONSchema
+)
+
+// ResponseFormatOption returns the response format requested in a provider
+// options map, or nil for plain text.
+func ResponseFormatOption(options map[string]any) *ResponseFormat {
+	return protocoltypes.ResponseFormatOption(options)
+}
+
 type LLMProvider interface {
 	Chat(
 		ctx context.Context,
@@ -47,6 +60,21 @@ type ThinkingCapable interface {
 	SupportsThinking() bool
 }
 
+// StreamingProvider is an optional interface for providers that can stream
+// the assistant's text as it is generated. onDelta is called with each new
+// content fragment; the returned response is the fully assembled message,
+// identical in shape to what Chat would have produced.
+type StreamingProvider interface {
+	ChatStream(
+		ctx context.Context,
+		messages []Message,
+		tools []ToolDefinition,
+		model string,
+		options map[string]any,
+		onDelta func(delta string),
+	) (*LLMResponse, error)
+}
+
 // FailoverReason classifies why an LLM request failed for fallback decisions.
 type FailoverReason string
 
@@ -2,9 +2,9 @@ package routing
 
 import (
 	"strings"
-	"unicode/utf8"
 
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tokenizer"
 )
 
 // lookbackWindow is the number of recent history entries scanned for tool calls.
@@ -15,9 +15,8 @@ const lookbackWindow = 6
 // Every dimension is language-agnostic by construction — no keyword or pattern matching
 // against natural-language content. This ensures consistent routing for all locales.
 type Features struct {
-	// TokenEstimate is a proxy for token count.
-	// CJK runes count as 1 token each; non-CJK runes as 0.25 tokens each.
-	// This avoids API calls while giving accurate estimates for all scripts.
+	// TokenEstimate is the message's token count from the primary model's
+	// tokenizer, so the thresholds mean the same for every script.
 	TokenEstimate int
 
 	// CodeBlockCount is the number of fenced code blocks (``` pairs) in the message.
@@ -37,12 +36,16 @@ type Features struct {
 	HasAttachments bool
 }
 
-// ExtractFeatures computes the structural feature vector for a message.
-// It is a pure function with no side effects and zero allocations beyond
-// the returned struct.
+// ExtractFeatures computes the structural feature vector for a message,
+// counting tokens with tokenizer.Default(). It is a pure function with no
+// side effects.
 func ExtractFeatures(msg string, history []providers.Message) Features {
+	return extractFeatures(msg, history, tokenizer.Default())
+}
+
+func extractFeatures(msg string, history []providers.Message, counter tokenizer.TokenCounter) Features {
 	return Features{
-		TokenEstimate:     estimateTokens(msg),
+		TokenEstimate:     counter.Count(msg),
 		CodeBlockCount:    countCodeBlocks(msg),
 		RecentToolCalls:   countRecentToolCalls(history),
 		ConversationDepth: len(history),
@@ -50,25 +53,6 @@ func ExtractFeatures(msg string, history []providers.Message) Features {
 	}
 }
 
-// estimateTokens returns a token count proxy that handles both CJK and Latin text.
-// CJK runes (U+2E80–U+9FFF, U+F900–U+FAFF, U+AC00–U+D7AF) map to roughly one
-// token each, while non-CJK runes average ~0.25 tokens/rune (≈4 chars per token
-// for English). Splitting the count this way avoids the 3x underestimation that a
-// flat rune_count/3 would produce for Chinese, Japanese, and Korean text.
-func estimateTokens(msg string) int {
-	total := utf8.RuneCountInString(msg)
-	if total == 0 {
-		return 0
-	}
-	cjk := 0
-	for _, r := range msg {
-		if r >= 0x2E80 && r <= 0x9FFF || r >= 0xF900 && r <= 0xFAFF || r >= 0xAC00 && r <= 0xD7AF {
-			cjk++
-		}
-	}
-	return cjk + (total-cjk)/4
-}
-
 // countCodeBlocks counts the number of complete fenced code blocks.
 // Each ``` delimiter increments a counter; pairs of delimiters form one block.
 // An unclosed opening fence (odd count) is treated as zero complete blocks
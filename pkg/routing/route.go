@@ -14,6 +14,7 @@ type RouteInput struct {
 	ParentPeer *RoutePeer
 	GuildID    string
 	TeamID     string
+	ThreadID   string // platform thread; scopes the session when session.per_thread is set
 }
 
 // ResolvedRoute is the result of agent routing.
@@ -49,6 +50,10 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 		dmScope = DMScopeMain
 	}
 	identityLinks := r.cfg.Session.IdentityLinks
+	var threadID string
+	if r.cfg.Session.PerThread {
+		threadID = input.ThreadID
+	}
 
 	bindings := r.filterBindings(channel, accountID)
 
@@ -61,6 +66,7 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 			Peer:          peer,
 			DMScope:       dmScope,
 			IdentityLinks: identityLinks,
+			ThreadID:      threadID,
 		}))
 		mainSessionKey := strings.ToLower(BuildAgentMainSessionKey(resolvedAgentID))
 		return ResolvedRoute{
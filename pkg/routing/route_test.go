@@ -295,3 +295,29 @@ func TestResolveRoute_NoDefaultUsesFirst(t *testing.T) {
 		t.Errorf("AgentID = %q, want 'alpha' (first in list)", route.AgentID)
 	}
 }
+
+func TestResolveRoute_PerThreadSessions(t *testing.T) {
+	cfg := testConfig(nil, nil)
+	input := RouteInput{
+		Channel:  "slack",
+		Peer:     &RoutePeer{Kind: "channel", ID: "C123"},
+		ThreadID: "1700000000.0001",
+	}
+
+	route := NewRouteResolver(cfg).ResolveRoute(input)
+	if route.SessionKey != "agent:main:slack:channel:c123" {
+		t.Errorf("SessionKey without per_thread = %q", route.SessionKey)
+	}
+
+	cfg.Session.PerThread = true
+	route = NewRouteResolver(cfg).ResolveRoute(input)
+	if route.SessionKey != "agent:main:slack:channel:c123:thread:1700000000.0001" {
+		t.Errorf("SessionKey with per_thread = %q", route.SessionKey)
+	}
+
+	input.ThreadID = ""
+	route = NewRouteResolver(cfg).ResolveRoute(input)
+	if route.SessionKey != "agent:main:slack:channel:c123" {
+		t.Errorf("SessionKey outside a thread = %q", route.SessionKey)
+	}
+}
@@ -2,6 +2,7 @@ package routing
 
 import (
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tokenizer"
 )
 
 // defaultThreshold is used when the config threshold is zero or negative.
@@ -20,6 +21,9 @@ type RouterConfig struct {
 	// score >= Threshold → primary (heavy) model.
 	// score <  Threshold → light model.
 	Threshold float64
+
+	// Tokenizer counts message tokens; nil uses tokenizer.Default().
+	Tokenizer tokenizer.TokenCounter
 }
 
 // Router selects the appropriate model tier for each incoming message.
@@ -35,6 +39,9 @@ func New(cfg RouterConfig) *Router {
 	if cfg.Threshold <= 0 {
 		cfg.Threshold = defaultThreshold
 	}
+	if cfg.Tokenizer == nil {
+		cfg.Tokenizer = tokenizer.Default()
+	}
 	return &Router{
 		cfg:        cfg,
 		classifier: &RuleClassifier{},
@@ -47,6 +54,9 @@ func newWithClassifier(cfg RouterConfig, c Classifier) *Router {
 	if cfg.Threshold <= 0 {
 		cfg.Threshold = defaultThreshold
 	}
+	if cfg.Tokenizer == nil {
+		cfg.Tokenizer = tokenizer.Default()
+	}
 	return &Router{cfg: cfg, classifier: c}
 }
 
@@ -63,7 +73,7 @@ func (r *Router) SelectModel(
 	history []providers.Message,
 	primaryModel string,
 ) (model string, usedLight bool, score float64) {
-	features := ExtractFeatures(msg, history)
+	features := extractFeatures(msg, history, r.cfg.Tokenizer)
 	score = r.classifier.Score(features)
 	if score < r.cfg.Threshold {
 		return r.cfg.LightModel, true, score
@@ -29,11 +29,11 @@ func TestExtractFeatures_EmptyMessage(t *testing.T) {
 }
 
 func TestExtractFeatures_TokenEstimate(t *testing.T) {
-	// 30 ASCII runes: 0 CJK + 30/4 = 7 tokens
+	// A 30-letter run is one piece estimated at one token per 7 letters: 5 tokens
 	msg := strings.Repeat("a", 30)
 	f := ExtractFeatures(msg, nil)
-	if f.TokenEstimate != 7 {
-		t.Errorf("TokenEstimate: got %d, want 7", f.TokenEstimate)
+	if f.TokenEstimate != 5 {
+		t.Errorf("TokenEstimate: got %d, want 5", f.TokenEstimate)
 	}
 }
 
@@ -52,7 +52,7 @@ func TestExtractFeatures_TokenEstimate_CJK(t *testing.T) {
 }
 
 func TestExtractFeatures_TokenEstimate_Mixed(t *testing.T) {
-	// Mixed: 4 CJK runes + 8 ASCII runes → 4 + 8/4 = 6 tokens.
+	// Mixed: 4 CJK runes + two short words → 4 + 1 + 1 = 6 tokens.
 	msg := string([]rune{0x4F60, 0x597D, 0x4E16, 0x754C}) + "hello ok"
 	f := ExtractFeatures(msg, nil)
 	if f.TokenEstimate != 6 {
@@ -29,6 +29,8 @@ type SessionKeyParams struct {
 	Peer          *RoutePeer
 	DMScope       DMScope
 	IdentityLinks map[string][]string
+	// ThreadID, when set, scopes the session to one thread of the peer.
+	ThreadID string
 }
 
 // ParsedSessionKey is the result of parsing an agent-scoped session key.
@@ -43,7 +45,18 @@ func BuildAgentMainSessionKey(agentID string) string {
 }
 
 // BuildAgentPeerSessionKey constructs a session key based on agent, channel, peer, and DM scope.
+// A non-empty ThreadID appends ":thread:<id>" to the peer's key.
 func BuildAgentPeerSessionKey(params SessionKeyParams) string {
+	key := buildPeerSessionKey(params)
+	if threadID := strings.ToLower(strings.TrimSpace(params.ThreadID)); threadID != "" {
+		key += threadKeyMarker + threadID
+	}
+	return key
+}
+
+const threadKeyMarker = ":thread:"
+
+func buildPeerSessionKey(params SessionKeyParams) string {
 	agentID := NormalizeAgentID(params.AgentID)
 
 	peer := params.Peer
@@ -136,6 +149,42 @@ func IsSubagentSessionKey(sessionKey string) bool {
 	return strings.HasPrefix(strings.ToLower(parsed.Rest), "subagent:")
 }
 
+// DefaultBranchName names the original conversation of a chat, stored under
+// the unsuffixed session key.
+const DefaultBranchName = "main"
+
+const branchKeyMarker = ":branch:"
+
+// BuildBranchSessionKey returns "<baseKey>:branch:<name>". The default branch
+// maps to baseKey itself so existing sessions stay where they are.
+func BuildBranchSessionKey(baseKey, branch string) string {
+	branch = NormalizeBranchName(branch)
+	if branch == DefaultBranchName {
+		return baseKey
+	}
+	return baseKey + branchKeyMarker + branch
+}
+
+// ParseBranchSessionKey splits a session key into its base key and branch
+// name. Keys without a branch suffix belong to DefaultBranchName.
+func ParseBranchSessionKey(sessionKey string) (baseKey, branch string) {
+	idx := strings.LastIndex(sessionKey, branchKeyMarker)
+	if idx <= 0 {
+		return sessionKey, DefaultBranchName
+	}
+	name := sessionKey[idx+len(branchKeyMarker):]
+	if name == "" || !validIDRe.MatchString(name) {
+		return sessionKey, DefaultBranchName
+	}
+	return sessionKey[:idx], name
+}
+
+// NormalizeBranchName sanitizes a branch name like NormalizeAgentID. Empty
+// input returns DefaultBranchName.
+func NormalizeBranchName(name string) string {
+	return NormalizeAgentID(name)
+}
+
 func normalizeChannel(channel string) string {
 	c := strings.TrimSpace(strings.ToLower(channel))
 	if c == "" {
@@ -205,3 +205,42 @@ func TestIsSubagentSessionKey(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildBranchSessionKey(t *testing.T) {
+	base := "agent:main:telegram:direct:user123"
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"", base},
+		{"main", base},
+		{"Idea One", base + ":branch:idea-one"},
+		{"draft_2", base + ":branch:draft_2"},
+	}
+	for _, tt := range tests {
+		if got := BuildBranchSessionKey(base, tt.branch); got != tt.want {
+			t.Errorf("BuildBranchSessionKey(%q) = %q, want %q", tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestParseBranchSessionKey(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantBase   string
+		wantBranch string
+	}{
+		{"agent:main:main", "agent:main:main", "main"},
+		{"agent:main:main:branch:idea", "agent:main:main", "idea"},
+		{"agent:main:slack:group:c1:branch:draft_2", "agent:main:slack:group:c1", "draft_2"},
+		{"agent:main:main:branch:", "agent:main:main:branch:", "main"},
+		{"agent:main:main:branch:Bad Name", "agent:main:main:branch:Bad Name", "main"},
+	}
+	for _, tt := range tests {
+		base, branch := ParseBranchSessionKey(tt.input)
+		if base != tt.wantBase || branch != tt.wantBranch {
+			t.Errorf("ParseBranchSessionKey(%q) = (%q, %q), want (%q, %q)",
+				tt.input, base, branch, tt.wantBase, tt.wantBranch)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package semantic
+
+import (
+	"strings"
+)
+
+// maxChunkRunes caps a chunk so long paragraphs stay within embedding input
+// limits and do not dominate the prompt when recalled.
+const maxChunkRunes = 1200
+
+type chunk struct {
+	section string
+	text    string
+}
+
+// chunkMarkdown splits a memory file into paragraphs and list items. Each
+// list item is its own chunk, so a single remembered fact can be recalled
+// without its neighbours. Headings are not chunks themselves but are kept
+// as the section of the chunks below them.
+func chunkMarkdown(content string) []chunk {
+	var (
+		chunks  []chunk
+		section string
+		block   []string
+	)
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(block, "\n"))
+		block = block[:0]
+		for text != "" {
+			part := text
+			if runes := []rune(text); len(runes) > maxChunkRunes {
+				part = string(runes[:maxChunkRunes])
+			}
+			text = strings.TrimSpace(strings.TrimPrefix(text, part))
+			chunks = append(chunks, chunk{section: section, text: strings.TrimSpace(part)})
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			section = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		case isListItem(line):
+			flush()
+			block = append(block, listItemText(trimmed))
+		default:
+			block = append(block, trimmed)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// isListItem reports whether line starts a top-level markdown list item.
+// Indented items are treated as continuation of their parent.
+func isListItem(line string) bool {
+	if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "+ ") {
+		return true
+	}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	return digits > 0 && strings.HasPrefix(line[digits:], ". ")
+}
+
+// listItemText strips the list marker from a trimmed list item line.
+func listItemText(item string) string {
+	if _, rest, ok := strings.Cut(item, " "); ok {
+		return strings.TrimSpace(rest)
+	}
+	return item
+}
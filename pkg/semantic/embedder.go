@@ -0,0 +1,170 @@
+// Package semantic implements embedding-backed long-term memory.
+//
+// Markdown files in the workspace memory directory (MEMORY.md and the daily
+// notes) stay the source of truth. They are split into chunks, embedded and
+// cached in a local vector index, so only the memories relevant to the
+// current message need to be put into the prompt.
+package semantic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Embedder turns texts into embedding vectors.
+type Embedder interface {
+	// Embed returns one vector per input text, in input order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model identifies the embedding model. Vectors from different models
+	// are not comparable, so the index re-embeds when it changes.
+	Model() string
+}
+
+const (
+	defaultEmbedTimeout = 60 * time.Second
+	// embedBatchSize bounds the number of inputs sent per request.
+	embedBatchSize = 64
+)
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint. It works
+// with OpenAI itself and local stand-ins such as Ollama, LM Studio, vLLM or
+// LiteLLM.
+type OpenAIEmbedder struct {
+	apiBase    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIEmbedder creates an embedder for apiBase (e.g.
+// "https://api.openai.com/v1"). apiKey may be empty for local servers.
+func NewOpenAIEmbedder(apiBase, apiKey, model string) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		apiBase:    strings.TrimRight(apiBase, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: defaultEmbedTimeout},
+	}
+}
+
+// NewEmbedderFromConfig creates an embedder from a model_list entry. The
+// protocol prefix of the model selects the default API base when api_base
+// is not set.
+func NewEmbedderFromConfig(mc *config.ModelConfig) (*OpenAIEmbedder, error) {
+	if mc == nil {
+		return nil, fmt.Errorf("embedding model config is nil")
+	}
+	protocol, modelID := providers.ExtractProtocol(mc.Model)
+	apiBase := mc.APIBase
+	if apiBase == "" {
+		apiBase = providers.DefaultAPIBase(protocol)
+	}
+	if apiBase == "" {
+		return nil, fmt.Errorf("api_base is required for embedding model %q", mc.ModelName)
+	}
+
+	e := NewOpenAIEmbedder(apiBase, mc.APIKey, modelID)
+	if mc.RequestTimeout > 0 {
+		e.httpClient.Timeout = time.Duration(mc.RequestTimeout) * time.Second
+	}
+	if mc.Proxy != "" {
+		proxy, err := url.Parse(mc.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", mc.Proxy, err)
+		}
+		e.httpClient.Transport = &http.Transport{Proxy: http.ProxyURL(proxy)}
+	}
+	return e, nil
+}
+
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(texts))
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.apiBase+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding API returned status %d: %s",
+			resp.StatusCode, utils.Truncate(strings.TrimSpace(string(data)), 200))
+	}
+
+	var parsed embeddingResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding API returned %d vectors for %d inputs", len(parsed.Data), len(texts))
+	}
+
+	sort.SliceStable(parsed.Data, func(i, j int) bool {
+		return parsed.Data[i].Index < parsed.Data[j].Index
+	})
+	vectors := make([][]float32, len(parsed.Data))
+	for i, d := range parsed.Data {
+		if len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("embedding API returned an empty vector for input %d", i)
+		}
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,93 @@
+package semantic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestOpenAIEmbedder_Embed(t *testing.T) {
+	var gotReq embeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("path = %q, want /v1/embeddings", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		// Return the vectors out of order; the embedder sorts by index.
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[
+			{"index":1,"embedding":[0,1]},
+			{"index":0,"embedding":[1,0]}
+		]}`))
+	}))
+	defer server.Close()
+
+	e, err := NewEmbedderFromConfig(&config.ModelConfig{
+		ModelName: "embeddings",
+		Model:     "openai/text-embedding-3-small",
+		APIBase:   server.URL + "/v1/",
+		APIKey:    "sk-test",
+	})
+	if err != nil {
+		t.Fatalf("NewEmbedderFromConfig() error: %v", err)
+	}
+	if e.Model() != "text-embedding-3-small" {
+		t.Errorf("Model() = %q", e.Model())
+	}
+
+	vectors, err := e.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if gotReq.Model != "text-embedding-3-small" || len(gotReq.Input) != 2 || gotReq.Input[1] != "second" {
+		t.Errorf("request = %+v", gotReq)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+}
+
+func TestOpenAIEmbedder_Errors(t *testing.T) {
+	status := http.StatusUnauthorized
+	body := `{"error":"bad key"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	e := NewOpenAIEmbedder(server.URL, "", "local")
+	if _, err := e.Embed(context.Background(), []string{"x"}); err == nil ||
+		!strings.Contains(err.Error(), "401") {
+		t.Errorf("expected status error, got %v", err)
+	}
+
+	status, body = http.StatusOK, `{"data":[]}`
+	if _, err := e.Embed(context.Background(), []string{"x"}); err == nil {
+		t.Error("expected error for missing vectors")
+	}
+}
+
+func TestNewEmbedderFromConfig_DefaultAPIBase(t *testing.T) {
+	e, err := NewEmbedderFromConfig(&config.ModelConfig{ModelName: "e", Model: "ollama/nomic-embed-text"})
+	if err != nil {
+		t.Fatalf("NewEmbedderFromConfig() error: %v", err)
+	}
+	if e.apiBase != "http://localhost:11434/v1" || e.model != "nomic-embed-text" {
+		t.Errorf("apiBase = %q, model = %q", e.apiBase, e.model)
+	}
+
+	if _, err := NewEmbedderFromConfig(&config.ModelConfig{ModelName: "e", Model: "unknown/x"}); err == nil {
+		t.Error("expected error without api_base for unknown protocol")
+	}
+}
@@ -0,0 +1,423 @@
+package semantic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/fileutil"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// IndexFileName is the vector index file kept in the memory directory.
+const IndexFileName = "vectors.json"
+
+// longTermFile is the memory file new memories are appended to.
+const longTermFile = "MEMORY.md"
+
+// Memory is one chunk of a memory file together with its embedding.
+type Memory struct {
+	Source  string    `json:"source"`            // file relative to the memory directory
+	Section string    `json:"section,omitempty"` // nearest heading above the chunk
+	Text    string    `json:"text"`
+	Vector  []float32 `json:"vector"`
+}
+
+// embedInput is the text that gets embedded: the chunk with its heading
+// for context.
+func (m Memory) embedInput() string {
+	if m.Section == "" {
+		return m.Text
+	}
+	return m.Section + "\n" + m.Text
+}
+
+// Hit is a recalled memory and its cosine similarity to the query.
+type Hit struct {
+	Memory
+	Score float64
+}
+
+// fileStamp identifies the version of a memory file that was indexed.
+type fileStamp struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+}
+
+type indexData struct {
+	Model    string               `json:"model"`
+	Files    map[string]fileStamp `json:"files"` // keyed by source
+	Memories []Memory             `json:"memories"`
+}
+
+// Index is the vector index over the memory files of one workspace. It is
+// synced with the files lazily before every lookup, so edits made with the
+// file tools are picked up and only new or changed chunks are embedded.
+//
+// Lookups do not hold the lock while waiting for the embedding server, so
+// a slow or unreachable server does not queue every caller behind one
+// request.
+type Index struct {
+	memoryDir string
+	path      string
+	embedder  Embedder
+
+	mu     sync.Mutex
+	loaded bool
+	gen    uint64 // bumped whenever data changes
+	data   indexData
+}
+
+// NewIndex creates the index for workspace/memory using embedder.
+func NewIndex(workspace string, embedder Embedder) *Index {
+	memoryDir := filepath.Join(workspace, "memory")
+	return &Index{
+		memoryDir: memoryDir,
+		path:      filepath.Join(memoryDir, IndexFileName),
+		embedder:  embedder,
+	}
+}
+
+// Path returns the index file path.
+func (idx *Index) Path() string {
+	return idx.path
+}
+
+// Len returns the number of indexed chunks.
+func (idx *Index) Len() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.loadLocked()
+	return len(idx.data.Memories)
+}
+
+// Sync brings the index up to date with the memory files. New chunks are
+// embedded without holding the lock; if the index changed in the meantime
+// the result is dropped and the next sync starts over.
+func (idx *Index) Sync(ctx context.Context) error {
+	idx.mu.Lock()
+	plan, err := idx.planLocked()
+	idx.mu.Unlock()
+	if err != nil || plan == nil {
+		return err
+	}
+
+	vectors, err := idx.embedPlan(ctx, plan)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.gen != plan.gen {
+		return nil
+	}
+	return idx.applyLocked(plan, vectors)
+}
+
+// Remember appends text to MEMORY.md as a bullet and indexes it. It reports
+// false when the same memory is already stored.
+func (idx *Index) Remember(ctx context.Context, text string) (bool, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return false, fmt.Errorf("memory text is empty")
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.syncLocked(ctx); err != nil {
+		return false, err
+	}
+	for _, m := range idx.data.Memories {
+		if m.Text == text {
+			return false, nil
+		}
+	}
+
+	path := filepath.Join(idx.memoryDir, longTermFile)
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to read %s: %w", longTermFile, err)
+	}
+	content := string(existing)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += "- " + text + "\n"
+	if err := fileutil.WriteFileAtomic(path, []byte(content), 0o600); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", longTermFile, err)
+	}
+
+	return true, idx.syncLocked(ctx)
+}
+
+// Recall returns up to k memories most similar to query, best first.
+// Memories scoring below minScore are dropped.
+func (idx *Index) Recall(ctx context.Context, query string, k int, minScore float64) ([]Hit, error) {
+	if strings.TrimSpace(query) == "" || k <= 0 {
+		return nil, nil
+	}
+
+	if err := idx.Sync(ctx); err != nil {
+		return nil, err
+	}
+	// Syncs replace the slice instead of modifying it, so the snapshot can
+	// be scored without the lock.
+	idx.mu.Lock()
+	memories := idx.data.Memories
+	idx.mu.Unlock()
+	if len(memories) == 0 {
+		return nil, nil
+	}
+
+	vectors, err := idx.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	q := vectors[0]
+
+	var hits []Hit
+	for _, m := range memories {
+		score, ok := cosine(q, m.Vector)
+		if !ok || score < minScore {
+			continue
+		}
+		hits = append(hits, Hit{Memory: m, Score: score})
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// loadLocked reads the index file once. A missing or corrupt file starts an
+// empty index; it is rebuilt from the memory files on the next sync.
+func (idx *Index) loadLocked() {
+	if idx.loaded {
+		return
+	}
+	idx.loaded = true
+
+	data, err := os.ReadFile(idx.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.WarnCF("semantic", "Failed to read memory index", map[string]any{
+				"path":  idx.path,
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &idx.data); err != nil {
+		logger.WarnCF("semantic", "Rebuilding corrupt memory index", map[string]any{
+			"path":  idx.path,
+			"error": err.Error(),
+		})
+		idx.data = indexData{}
+	}
+}
+
+// syncPlan is the index data a sync will store, with the vectors of the
+// memories at pending still to be embedded.
+type syncPlan struct {
+	gen     uint64
+	data    indexData
+	pending []int
+	stale   int
+}
+
+// syncLocked is Sync for callers that must keep the index locked across
+// the sync, e.g. to write a memory file and index it atomically.
+func (idx *Index) syncLocked(ctx context.Context) error {
+	plan, err := idx.planLocked()
+	if err != nil || plan == nil {
+		return err
+	}
+	vectors, err := idx.embedPlan(ctx, plan)
+	if err != nil {
+		return err
+	}
+	return idx.applyLocked(plan, vectors)
+}
+
+// planLocked re-chunks memory files that were added or modified since they
+// were indexed and drops chunks of deleted files. Vectors of unchanged
+// chunks are reused unless the embedding model changed. It returns nil when
+// the index is up to date.
+func (idx *Index) planLocked() (*syncPlan, error) {
+	idx.loadLocked()
+
+	files, err := idx.scanFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	model := idx.embedder.Model()
+	modelChanged := idx.data.Model != model
+	changed := modelChanged
+
+	var stale []string
+	for source, stamp := range files {
+		if indexed, ok := idx.data.Files[source]; modelChanged || !ok ||
+			!indexed.ModTime.Equal(stamp.ModTime) || indexed.Size != stamp.Size {
+			stale = append(stale, source)
+		}
+	}
+	for source := range idx.data.Files {
+		if _, ok := files[source]; !ok {
+			changed = true
+		}
+	}
+	if len(stale) == 0 && !changed {
+		return nil, nil
+	}
+	sort.Strings(stale)
+	isStale := make(map[string]bool, len(stale))
+	for _, source := range stale {
+		isStale[source] = true
+	}
+
+	reuse := make(map[string][]float32)
+	var memories []Memory
+	for _, m := range idx.data.Memories {
+		if _, ok := files[m.Source]; !ok {
+			continue
+		}
+		if !modelChanged {
+			reuse[m.embedInput()] = m.Vector
+		}
+		if !isStale[m.Source] {
+			memories = append(memories, m)
+		}
+	}
+
+	var pending []int
+	for _, source := range stale {
+		content, err := os.ReadFile(filepath.Join(idx.memoryDir, filepath.FromSlash(source)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read memory file %s: %w", source, err)
+		}
+		for _, c := range chunkMarkdown(string(content)) {
+			m := Memory{Source: source, Section: c.section, Text: c.text}
+			if v, ok := reuse[m.embedInput()]; ok {
+				m.Vector = v
+			} else {
+				pending = append(pending, len(memories))
+			}
+			memories = append(memories, m)
+		}
+	}
+
+	return &syncPlan{
+		gen:     idx.gen,
+		data:    indexData{Model: model, Files: files, Memories: memories},
+		pending: pending,
+		stale:   len(stale),
+	}, nil
+}
+
+// embedPlan embeds the pending memories of plan. It does not touch the index.
+func (idx *Index) embedPlan(ctx context.Context, plan *syncPlan) ([][]float32, error) {
+	if len(plan.pending) == 0 {
+		return nil, nil
+	}
+	inputs := make([]string, len(plan.pending))
+	for i, n := range plan.pending {
+		inputs[i] = plan.data.Memories[n].embedInput()
+	}
+	vectors, err := idx.embedder.Embed(ctx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed memories: %w", err)
+	}
+	return vectors, nil
+}
+
+// applyLocked stores plan with the embedded vectors and saves the index.
+func (idx *Index) applyLocked(plan *syncPlan, vectors [][]float32) error {
+	for i, n := range plan.pending {
+		plan.data.Memories[n].Vector = vectors[i]
+	}
+	idx.data = plan.data
+	idx.gen++
+	logger.DebugCF("semantic", "Memory index synced", map[string]any{
+		"files":    len(plan.data.Files),
+		"updated":  plan.stale,
+		"embedded": len(plan.pending),
+		"memories": len(plan.data.Memories),
+	})
+	return idx.saveLocked()
+}
+
+// scanFiles returns the markdown files in the memory directory with their
+// stamps, keyed by slash-separated relative path.
+func (idx *Index) scanFiles() (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(idx.memoryDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(idx.memoryDir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fileStamp{ModTime: info.ModTime(), Size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan memory directory: %w", err)
+	}
+	return files, nil
+}
+
+func (idx *Index) saveLocked() error {
+	data, err := json.Marshal(idx.data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory index: %w", err)
+	}
+	if err := os.MkdirAll(idx.memoryDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	return fileutil.WriteFileAtomic(idx.path, data, 0o600)
+}
+
+// cosine returns the cosine similarity of a and b. It reports false for
+// vectors of different dimensions or zero length.
+func cosine(a, b []float32) (float64, bool) {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0, false
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0, false
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb)), true
+}
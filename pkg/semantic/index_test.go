@@ -0,0 +1,273 @@
+package semantic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keywordEmbedder maps each text to a vector of vocabulary hits, so texts
+// sharing keywords are similar. It counts embedded inputs.
+type keywordEmbedder struct {
+	model  string
+	vocab  []string
+	inputs int
+}
+
+func (e *keywordEmbedder) Model() string {
+	return e.model
+}
+
+func (e *keywordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.inputs += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		text = strings.ToLower(text)
+		v := make([]float32, len(e.vocab)+1)
+		v[len(e.vocab)] = 0.01
+		for j, w := range e.vocab {
+			if strings.Contains(text, w) {
+				v[j] = 1
+			}
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func newTestEmbedder() *keywordEmbedder {
+	return &keywordEmbedder{model: "kw-1", vocab: []string{"cat", "coffee", "golang", "birthday"}}
+}
+
+func writeMemoryFile(t *testing.T, workspace, name, content string) {
+	t.Helper()
+	path := filepath.Join(workspace, "memory", name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	content := `# Long-term Memory
+
+## Preferences
+- Likes black coffee
+- Writes golang
+  at work
+
+The cat is called Miso.
+Miso is grey.
+
+1. First numbered item
+`
+	chunks := chunkMarkdown(content)
+	want := []chunk{
+		{section: "Preferences", text: "Likes black coffee"},
+		{section: "Preferences", text: "Writes golang\nat work"},
+		{section: "Preferences", text: "The cat is called Miso.\nMiso is grey."},
+		{section: "Preferences", text: "First numbered item"},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("chunkMarkdown() = %+v, want %+v", chunks, want)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i, chunks[i], want[i])
+		}
+	}
+}
+
+func TestChunkMarkdown_SplitsLongParagraphs(t *testing.T) {
+	chunks := chunkMarkdown(strings.Repeat("a", maxChunkRunes*2+10))
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+}
+
+func TestIndex_RecallRanksBySimilarity(t *testing.T) {
+	workspace := t.TempDir()
+	writeMemoryFile(t, workspace, "MEMORY.md", "- User drinks coffee every morning\n- User's cat is named Miso\n")
+	writeMemoryFile(t, workspace, "202603/20260317.md", "# 2026-03-17\n\nHelped debug a golang service.\n")
+
+	idx := NewIndex(workspace, newTestEmbedder())
+	hits, err := idx.Recall(context.Background(), "what is the name of my cat?", 2, 0.5)
+	if err != nil {
+		t.Fatalf("Recall() error: %v", err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("Recall() = %+v, want one hit", hits)
+	}
+	if hits[0].Text != "User's cat is named Miso" || hits[0].Source != "MEMORY.md" {
+		t.Errorf("hit = %+v", hits[0].Memory)
+	}
+
+	hits, err = idx.Recall(context.Background(), "golang", 5, 0.5)
+	if err != nil {
+		t.Fatalf("Recall() error: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Source != "202603/20260317.md" || hits[0].Section != "2026-03-17" {
+		t.Errorf("daily note hit = %+v", hits)
+	}
+}
+
+// stallingEmbedder blocks in Embed while stall is set, until release is closed.
+type stallingEmbedder struct {
+	*keywordEmbedder
+	stall   bool
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (e *stallingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.stall {
+		close(e.entered)
+		<-e.release
+		return nil, ctx.Err()
+	}
+	return e.keywordEmbedder.Embed(ctx, texts)
+}
+
+func TestIndex_RecallDoesNotLockWhileEmbedding(t *testing.T) {
+	workspace := t.TempDir()
+	writeMemoryFile(t, workspace, "MEMORY.md", "- User's cat is named Miso\n")
+
+	embedder := &stallingEmbedder{
+		keywordEmbedder: newTestEmbedder(),
+		entered:         make(chan struct{}),
+		release:         make(chan struct{}),
+	}
+	idx := NewIndex(workspace, embedder)
+	if err := idx.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+
+	embedder.stall = true
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := idx.Recall(ctx, "cat", 1, 0.5)
+		done <- err
+	}()
+	<-embedder.entered
+
+	lenDone := make(chan int, 1)
+	go func() { lenDone <- idx.Len() }()
+	select {
+	case n := <-lenDone:
+		if n != 1 {
+			t.Errorf("Len() = %d, want 1", n)
+		}
+	case <-time.After(time.Second):
+		t.Error("index stayed locked while the query was being embedded")
+	}
+
+	cancel()
+	close(embedder.release)
+	if err := <-done; err == nil {
+		t.Error("Recall() should fail when embedding fails")
+	}
+}
+
+func TestIndex_RememberAppendsAndDedupes(t *testing.T) {
+	workspace := t.TempDir()
+	writeMemoryFile(t, workspace, "MEMORY.md", "# Memory\n- User's cat is named Miso")
+	emb := newTestEmbedder()
+	idx := NewIndex(workspace, emb)
+	ctx := context.Background()
+
+	added, err := idx.Remember(ctx, "  Birthday is\nMarch 3 ")
+	if err != nil || !added {
+		t.Fatalf("Remember() = %v, %v", added, err)
+	}
+	added, err = idx.Remember(ctx, "Birthday is March 3")
+	if err != nil || added {
+		t.Fatalf("duplicate Remember() = %v, %v", added, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workspace, "memory", "MEMORY.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "# Memory\n- User's cat is named Miso\n- Birthday is March 3\n" {
+		t.Errorf("MEMORY.md = %q", got)
+	}
+	// The existing chunk is embedded once; remembering only embeds the new one.
+	if emb.inputs != 2 {
+		t.Errorf("embedded %d inputs, want 2", emb.inputs)
+	}
+
+	hits, err := idx.Recall(ctx, "when is the birthday", 1, 0.5)
+	if err != nil || len(hits) != 1 || hits[0].Text != "Birthday is March 3" {
+		t.Errorf("Recall() = %+v, %v", hits, err)
+	}
+}
+
+func TestIndex_SyncPersistsAndTracksFiles(t *testing.T) {
+	workspace := t.TempDir()
+	writeMemoryFile(t, workspace, "MEMORY.md", "- cat facts\n")
+	writeMemoryFile(t, workspace, "202603/20260317.md", "coffee notes\n")
+	ctx := context.Background()
+
+	emb := newTestEmbedder()
+	if err := NewIndex(workspace, emb).Sync(ctx); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "memory", IndexFileName)); err != nil {
+		t.Fatalf("index file not written: %v", err)
+	}
+
+	// A fresh index loads the persisted vectors without embedding again.
+	emb = newTestEmbedder()
+	idx := NewIndex(workspace, emb)
+	if err := idx.Sync(ctx); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	if emb.inputs != 0 || idx.Len() != 2 {
+		t.Fatalf("reload embedded %d inputs, len %d", emb.inputs, idx.Len())
+	}
+
+	// Deleted files drop their chunks; edited files are re-chunked.
+	if err := os.Remove(filepath.Join(workspace, "memory", "202603", "20260317.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeMemoryFile(t, workspace, "MEMORY.md", "- cat facts\n- golang facts\n")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(workspace, "memory", "MEMORY.md"), future, future)
+	if err := idx.Sync(ctx); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	if emb.inputs != 1 || idx.Len() != 2 {
+		t.Errorf("after edit embedded %d inputs, len %d; want 1, 2", emb.inputs, idx.Len())
+	}
+
+	// A different embedding model re-embeds everything.
+	emb = newTestEmbedder()
+	emb.model = "kw-2"
+	if err := NewIndex(workspace, emb).Sync(ctx); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	if emb.inputs != 2 {
+		t.Errorf("model change embedded %d inputs, want 2", emb.inputs)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	if s, ok := cosine([]float32{1, 0}, []float32{1, 0}); !ok || s < 0.999 {
+		t.Errorf("identical vectors = %v, %v", s, ok)
+	}
+	if s, ok := cosine([]float32{1, 0}, []float32{0, 1}); !ok || s != 0 {
+		t.Errorf("orthogonal vectors = %v, %v", s, ok)
+	}
+	if _, ok := cosine([]float32{1}, []float32{1, 0}); ok {
+		t.Error("expected dimension mismatch to be rejected")
+	}
+	if _, ok := cosine([]float32{0, 0}, []float32{1, 0}); ok {
+		t.Error("expected zero vector to be rejected")
+	}
+}
@@ -0,0 +1,579 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Transcript formats supported by EncodeTranscript and DecodeTranscript.
+const (
+	// FormatJSONL writes a session header line followed by one line per
+	// message, each message in the same form the stores persist it.
+	FormatJSONL = "jsonl"
+	// FormatMarkdown writes a readable transcript. Message metadata (tool
+	// calls, attachments) is kept in HTML comments so it can be imported.
+	FormatMarkdown = "markdown"
+	// FormatOpenAI writes an OpenAI chat completions style {"messages": [...]}
+	// document; the session key and summary go into "metadata".
+	FormatOpenAI = "openai"
+)
+
+// TranscriptFormats lists the supported transcript formats.
+var TranscriptFormats = []string{FormatJSONL, FormatMarkdown, FormatOpenAI}
+
+// ErrSessionNotFound is returned when exporting or deleting a session that
+// does not exist. A session with neither messages nor a summary is treated
+// as missing by ExportTranscript.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Transcript is a portable copy of one session.
+type Transcript struct {
+	Key      string              `json:"key"`
+	Summary  string              `json:"summary,omitempty"`
+	Messages []providers.Message `json:"messages"`
+}
+
+// ExportTranscript reads the session key from store.
+func ExportTranscript(store SessionStore, key string) (Transcript, error) {
+	history := store.GetHistory(key)
+	summary := store.GetSummary(key)
+	if len(history) == 0 && summary == "" {
+		return Transcript{}, fmt.Errorf("%w: %s", ErrSessionNotFound, key)
+	}
+	return Transcript{Key: key, Summary: summary, Messages: history}, nil
+}
+
+// ImportTranscript writes t into store, replacing any session with the same
+// key.
+func ImportTranscript(store SessionStore, t Transcript) error {
+	if strings.TrimSpace(t.Key) == "" {
+		return fmt.Errorf("transcript has no session key")
+	}
+	if err := store.ReplaceSession(t.Key, t.Messages, t.Summary); err != nil {
+		return err
+	}
+	return store.Save(t.Key)
+}
+
+// FormatFromPath infers the transcript format from a file extension. It
+// returns an empty string for unknown extensions.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".json":
+		return FormatOpenAI
+	}
+	return ""
+}
+
+// FormatExtension returns the file extension used for format.
+func FormatExtension(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return ".md"
+	case FormatOpenAI:
+		return ".json"
+	default:
+		return ".jsonl"
+	}
+}
+
+// FormatContentType returns the MIME type used when serving format.
+func FormatContentType(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatOpenAI:
+		return "application/json"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+func unknownFormat(format string) error {
+	return fmt.Errorf("unknown transcript format %q (want %s)", format, strings.Join(TranscriptFormats, ", "))
+}
+
+// EncodeTranscript writes t to w in the given format.
+func EncodeTranscript(w io.Writer, t Transcript, format string) error {
+	switch format {
+	case FormatJSONL:
+		return encodeJSONL(w, t)
+	case FormatMarkdown:
+		return encodeMarkdown(w, t)
+	case FormatOpenAI:
+		return encodeOpenAI(w, t)
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// DecodeTranscript reads a transcript in the given format from r.
+func DecodeTranscript(r io.Reader, format string) (Transcript, error) {
+	switch format {
+	case FormatJSONL:
+		return decodeJSONL(r)
+	case FormatMarkdown:
+		return decodeMarkdown(r)
+	case FormatOpenAI:
+		return decodeOpenAI(r)
+	default:
+		return Transcript{}, unknownFormat(format)
+	}
+}
+
+// --- JSONL ---
+
+const transcriptVersion = 1
+
+type jsonlRecord struct {
+	Type    string             `json:"type"` // "session" or "message"
+	Version int                `json:"version,omitempty"`
+	Key     string             `json:"key,omitempty"`
+	Summary string             `json:"summary,omitempty"`
+	Message *providers.Message `json:"message,omitempty"`
+}
+
+func encodeJSONL(w io.Writer, t Transcript) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(jsonlRecord{
+		Type:    "session",
+		Version: transcriptVersion,
+		Key:     t.Key,
+		Summary: t.Summary,
+	}); err != nil {
+		return err
+	}
+	for _, m := range t.Messages {
+		m.ToolCalls = exportToolCalls(m.ToolCalls)
+		if err := enc.Encode(jsonlRecord{Type: "message", Message: &m}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeJSONL(r io.Reader) (Transcript, error) {
+	t := Transcript{Messages: []providers.Message{}}
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var rec jsonlRecord
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return Transcript{}, fmt.Errorf("invalid JSONL transcript record %d: %w", n, err)
+		}
+		switch rec.Type {
+		case "session":
+			t.Key = rec.Key
+			t.Summary = rec.Summary
+		case "message":
+			if rec.Message != nil {
+				t.Messages = append(t.Messages, *rec.Message)
+			}
+		default:
+			return Transcript{}, fmt.Errorf("invalid JSONL transcript record %d: unknown type %q", n, rec.Type)
+		}
+	}
+	return t, nil
+}
+
+// --- OpenAI messages JSON ---
+
+type openAITranscript struct {
+	Messages []openAIMessage `json:"messages"`
+	Metadata *openAIMetadata `json:"metadata,omitempty"`
+}
+
+type openAIMetadata struct {
+	SessionKey string `json:"session_key,omitempty"`
+	Summary    string `json:"summary,omitempty"`
+}
+
+type openAIMessage struct {
+	Role             string               `json:"role"`
+	Content          json.RawMessage      `json:"content"`
+	ReasoningContent string               `json:"reasoning_content,omitempty"`
+	ToolCalls        []providers.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID       string               `json:"tool_call_id,omitempty"`
+
+	// picoclaw extensions, ignored by other OpenAI-compatible consumers.
+	Media    []string                `json:"media,omitempty"`
+	FileRefs []providers.FileRefMeta `json:"file_refs,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+	File     *openAIFile     `json:"file,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIFile struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
+}
+
+func encodeOpenAI(w io.Writer, t Transcript) error {
+	doc := openAITranscript{Messages: make([]openAIMessage, 0, len(t.Messages))}
+	if t.Key != "" || t.Summary != "" {
+		doc.Metadata = &openAIMetadata{SessionKey: t.Key, Summary: t.Summary}
+	}
+
+	for _, m := range t.Messages {
+		var content any = m.Content
+		if len(m.Images) > 0 || len(m.Files) > 0 {
+			parts := make([]openAIContentPart, 0, 1+len(m.Images)+len(m.Files))
+			if m.Content != "" {
+				parts = append(parts, openAIContentPart{Type: "text", Text: m.Content})
+			}
+			for _, img := range m.Images {
+				parts = append(parts, openAIContentPart{
+					Type:     "image_url",
+					ImageURL: &openAIImageURL{URL: dataURL(img.MediaType, img.Data)},
+				})
+			}
+			for _, f := range m.Files {
+				parts = append(parts, openAIContentPart{
+					Type: "file",
+					File: &openAIFile{Filename: f.Name, FileData: dataURL(f.MediaType, f.Data)},
+				})
+			}
+			content = parts
+		}
+		raw, err := json.Marshal(content)
+		if err != nil {
+			return err
+		}
+		doc.Messages = append(doc.Messages, openAIMessage{
+			Role:             m.Role,
+			Content:          raw,
+			ReasoningContent: m.ReasoningContent,
+			ToolCalls:        exportToolCalls(m.ToolCalls),
+			ToolCallID:       m.ToolCallID,
+			Media:            m.Media,
+			FileRefs:         m.FileRefs,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// exportToolCalls makes sure every call carries its OpenAI "function" form;
+// calls built in memory may only have Name and Arguments set.
+func exportToolCalls(calls []providers.ToolCall) []providers.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]providers.ToolCall, len(calls))
+	for i, tc := range calls {
+		if tc.Function == nil && tc.Name != "" {
+			args, _ := json.Marshal(tc.Arguments)
+			tc.Function = &providers.FunctionCall{Name: tc.Name, Arguments: string(args)}
+		}
+		if tc.Type == "" {
+			tc.Type = "function"
+		}
+		out[i] = tc
+	}
+	return out
+}
+
+func decodeOpenAI(r io.Reader) (Transcript, error) {
+	var doc openAITranscript
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return Transcript{}, fmt.Errorf("invalid OpenAI messages JSON: %w", err)
+	}
+
+	t := Transcript{Messages: make([]providers.Message, 0, len(doc.Messages))}
+	if doc.Metadata != nil {
+		t.Key = doc.Metadata.SessionKey
+		t.Summary = doc.Metadata.Summary
+	}
+	for i, om := range doc.Messages {
+		m := providers.Message{
+			Role:             om.Role,
+			ReasoningContent: om.ReasoningContent,
+			ToolCalls:        om.ToolCalls,
+			ToolCallID:       om.ToolCallID,
+			Media:            om.Media,
+			FileRefs:         om.FileRefs,
+		}
+		if err := decodeOpenAIContent(om.Content, &m); err != nil {
+			return Transcript{}, fmt.Errorf("invalid content in message %d: %w", i+1, err)
+		}
+		t.Messages = append(t.Messages, m)
+	}
+	return t, nil
+}
+
+// decodeOpenAIContent fills the content, images and files of m from a
+// string, null or content-part array.
+func decodeOpenAIContent(raw json.RawMessage, m *providers.Message) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if raw[0] == '"' {
+		return json.Unmarshal(raw, &m.Content)
+	}
+
+	var parts []openAIContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return err
+	}
+	var texts []string
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			texts = append(texts, p.Text)
+		case "image_url":
+			if p.ImageURL == nil {
+				continue
+			}
+			if mediaType, data, ok := parseDataURL(p.ImageURL.URL); ok {
+				m.Images = append(m.Images, providers.ImageBlock{MediaType: mediaType, Data: data})
+			} else {
+				texts = append(texts, "[image: "+p.ImageURL.URL+"]")
+			}
+		case "file":
+			if p.File == nil {
+				continue
+			}
+			if mediaType, data, ok := parseDataURL(p.File.FileData); ok {
+				m.Files = append(m.Files, providers.FileBlock{Name: p.File.Filename, MediaType: mediaType, Data: data})
+			}
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
+
+func dataURL(mediaType, data string) string {
+	return "data:" + mediaType + ";base64," + data
+}
+
+func parseDataURL(url string) (mediaType, data string, ok bool) {
+	rest, found := strings.CutPrefix(url, "data:")
+	if !found {
+		return "", "", false
+	}
+	header, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mediaType, found = strings.CutSuffix(header, ";base64")
+	if !found {
+		return "", "", false
+	}
+	return mediaType, data, true
+}
+
+// --- Markdown ---
+
+const (
+	mdSessionMarker = "<!-- picoclaw:session "
+	mdSummaryStart  = "<!-- picoclaw:summary -->"
+	mdSummaryEnd    = "<!-- /picoclaw:summary -->"
+	mdMessageMarker = "<!-- picoclaw:message "
+	mdMessageEnd    = "<!-- /picoclaw:message -->"
+	mdMarkerSuffix  = " -->"
+	mdMarkerPrefix  = "<!-- picoclaw:"
+	mdEndPrefix     = "<!-- /picoclaw:"
+
+	// mdMaxLineLength allows marker lines carrying base64 attachments.
+	mdMaxLineLength   = 64 * 1024 * 1024
+	mdToolArgsPreview = 200
+)
+
+type mdSessionMeta struct {
+	Version int    `json:"version"`
+	Key     string `json:"key"`
+}
+
+// encodeMarkdown writes the content of each message between marker
+// comments. encoding/json escapes '<' and '>', so the JSON inside a marker
+// can never close the comment early. Content lines that look like markers
+// are escaped with a leading backslash, see escapeMarkdownBlock.
+func encodeMarkdown(w io.Writer, t Transcript) error {
+	bw := bufio.NewWriter(w)
+
+	meta, err := json.Marshal(mdSessionMeta{Version: transcriptVersion, Key: t.Key})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(bw, "# Session %s\n\n%s%s%s\n\n", t.Key, mdSessionMarker, meta, mdMarkerSuffix)
+
+	if t.Summary != "" {
+		fmt.Fprintf(bw, "## Summary\n\n%s\n%s\n%s\n\n", mdSummaryStart, escapeMarkdownBlock(t.Summary), mdSummaryEnd)
+	}
+
+	bw.WriteString("## Messages\n")
+	for _, m := range t.Messages {
+		content := m.Content
+		m.Content = ""
+		m.ToolCalls = exportToolCalls(m.ToolCalls)
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "\n### %s\n\n%s%s%s\n%s\n%s\n", markdownRoleTitle(m.Role),
+			mdMessageMarker, data, mdMarkerSuffix, escapeMarkdownBlock(content), mdMessageEnd)
+		writeMarkdownAttachments(bw, m)
+	}
+
+	return bw.Flush()
+}
+
+// escapeMarkdownBlock prefixes a backslash to every line of s that, once
+// its leading backslashes are removed, starts like a picoclaw marker.
+// Content therefore never contains a bare marker line that could end its
+// block early, and unescapeMarkdownLine can strip exactly one backslash to
+// restore it.
+func escapeMarkdownBlock(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if isMarkdownMarkerLike(line) {
+			lines[i] = `\` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func unescapeMarkdownLine(line string) string {
+	if strings.HasPrefix(line, `\`) && isMarkdownMarkerLike(line) {
+		return line[1:]
+	}
+	return line
+}
+
+func isMarkdownMarkerLike(line string) bool {
+	rest := strings.TrimLeft(line, `\`)
+	return strings.HasPrefix(rest, mdMarkerPrefix) || strings.HasPrefix(rest, mdEndPrefix)
+}
+
+func markdownRoleTitle(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "tool":
+		return "Tool result"
+	case "system":
+		return "System"
+	default:
+		return role
+	}
+}
+
+// writeMarkdownAttachments lists tool calls and attachments for readers.
+// It is written after the end marker and ignored on import.
+func writeMarkdownAttachments(w io.Writer, m providers.Message) {
+	var lines []string
+	for _, tc := range exportToolCalls(m.ToolCalls) {
+		name, args := tc.Name, ""
+		if tc.Function != nil {
+			name, args = tc.Function.Name, tc.Function.Arguments
+		}
+		lines = append(lines, fmt.Sprintf("- Tool call `%s`: `%s`", name, utils.Truncate(args, mdToolArgsPreview)))
+	}
+	for _, img := range m.Images {
+		lines = append(lines, fmt.Sprintf("- Image (%s)", img.MediaType))
+	}
+	for _, f := range m.Files {
+		lines = append(lines, fmt.Sprintf("- File `%s` (%s)", f.Name, f.MediaType))
+	}
+	for _, ref := range m.FileRefs {
+		lines = append(lines, fmt.Sprintf("- File reference `%s` (%s)", ref.Name, ref.Source))
+	}
+	for _, ref := range m.Media {
+		lines = append(lines, fmt.Sprintf("- Media `%s`", ref))
+	}
+	if len(lines) > 0 {
+		fmt.Fprintf(w, "\n%s\n", strings.Join(lines, "\n"))
+	}
+}
+
+func decodeMarkdown(r io.Reader) (Transcript, error) {
+	t := Transcript{Messages: []providers.Message{}}
+	foundSession := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), mdMaxLineLength)
+
+	var (
+		inSummary bool
+		inMessage bool
+		current   providers.Message
+		block     []string
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		marker := strings.TrimSuffix(line, "\r")
+
+		switch {
+		case inSummary:
+			if marker == mdSummaryEnd {
+				t.Summary = strings.Join(block, "\n")
+				inSummary, block = false, nil
+			} else {
+				block = append(block, unescapeMarkdownLine(line))
+			}
+		case inMessage:
+			if marker == mdMessageEnd {
+				current.Content = strings.Join(block, "\n")
+				t.Messages = append(t.Messages, current)
+				inMessage, block = false, nil
+			} else {
+				block = append(block, unescapeMarkdownLine(line))
+			}
+		case strings.HasPrefix(marker, mdSessionMarker) && strings.HasSuffix(marker, mdMarkerSuffix):
+			var meta mdSessionMeta
+			if err := json.Unmarshal([]byte(markerJSON(marker, mdSessionMarker)), &meta); err != nil {
+				return Transcript{}, fmt.Errorf("invalid session marker: %w", err)
+			}
+			t.Key = meta.Key
+			foundSession = true
+		case marker == mdSummaryStart:
+			inSummary = true
+		case strings.HasPrefix(marker, mdMessageMarker) && strings.HasSuffix(marker, mdMarkerSuffix):
+			current = providers.Message{}
+			if err := json.Unmarshal([]byte(markerJSON(marker, mdMessageMarker)), &current); err != nil {
+				return Transcript{}, fmt.Errorf("invalid message marker: %w", err)
+			}
+			inMessage = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Transcript{}, fmt.Errorf("failed to read Markdown transcript: %w", err)
+	}
+	if !foundSession {
+		return Transcript{}, fmt.Errorf("not a picoclaw Markdown transcript: session marker missing")
+	}
+	if inSummary || inMessage {
+		return Transcript{}, fmt.Errorf("truncated Markdown transcript: unterminated block")
+	}
+	return t, nil
+}
+
+func markerJSON(line, prefix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(line, prefix), mdMarkerSuffix)
+}
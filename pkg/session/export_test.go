@@ -0,0 +1,191 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func sampleTranscript() Transcript {
+	return Transcript{
+		Key:     "agent:main:telegram:direct:42",
+		Summary: "User asked about the weather.\n<!-- /picoclaw:summary -->\nIt was sunny.",
+		Messages: []providers.Message{
+			{
+				Role:    "user",
+				Content: "What's in this picture?\n\n<!-- not a marker -->",
+				Media:   []string{"media://abc"},
+				Images:  []providers.ImageBlock{{MediaType: "image/png", Data: "aGVsbG8="}},
+				FileRefs: []providers.FileRefMeta{{
+					Name: "report.pdf", MediaType: "application/pdf", Kind: "file", Source: "feishu",
+					FeishuMessageID: "om_1", FeishuFileKey: "file_1", FeishuResType: "file",
+				}},
+			},
+			{
+				Role:             "assistant",
+				ReasoningContent: "Need to look it up.",
+				ToolCalls: []providers.ToolCall{{
+					ID:   "call_1",
+					Type: "function",
+					Function: &providers.FunctionCall{
+						Name:      "web_search",
+						Arguments: `{"query":"<weather> today"}`,
+					},
+				}},
+			},
+			{
+				Role:       "tool",
+				Content:    "Sunny, 25°C\n<!-- /picoclaw:message -->\n\\<!-- picoclaw:message {} -->\n\\\\",
+				ToolCallID: "call_1",
+			},
+			{
+				Role:    "user",
+				Content: "And this document?",
+				Files:   []providers.FileBlock{{Name: "a.pdf", MediaType: "application/pdf", Data: "JVBERi0="}},
+			},
+			{Role: "assistant", Content: "It is sunny.\n"},
+		},
+	}
+}
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	want := sampleTranscript()
+	for _, format := range TranscriptFormats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeTranscript(&buf, want, format); err != nil {
+				t.Fatalf("EncodeTranscript() error: %v", err)
+			}
+			got, err := DecodeTranscript(&buf, format)
+			if err != nil {
+				t.Fatalf("DecodeTranscript() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip mismatch\n got: %+v\nwant: %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeTranscript_OpenAIShape(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeTranscript(&buf, sampleTranscript(), FormatOpenAI); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`"session_key": "agent:main:telegram:direct:42"`,
+		`"url": "data:image/png;base64,aGVsbG8="`,
+		`"file_data": "data:application/pdf;base64,JVBERi0="`,
+		`"name": "web_search"`,
+		`"tool_call_id": "call_1"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("OpenAI export missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestEncodeTranscript_MarkdownIsReadable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeTranscript(&buf, sampleTranscript(), FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Session agent:main:telegram:direct:42",
+		"## Summary",
+		"### User",
+		"### Tool result",
+		"- Tool call `web_search`",
+		"- File reference `report.pdf` (feishu)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown export missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDecodeTranscript_Errors(t *testing.T) {
+	if _, err := DecodeTranscript(strings.NewReader("# Notes\n\nhello\n"), FormatMarkdown); err == nil {
+		t.Error("expected error for Markdown without session marker")
+	}
+	if _, err := DecodeTranscript(strings.NewReader(`{"type":"bogus"}`), FormatJSONL); err == nil {
+		t.Error("expected error for unknown JSONL record type")
+	}
+	if _, err := DecodeTranscript(strings.NewReader(""), "xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestDecodeTranscript_PlainOpenAIMessages(t *testing.T) {
+	doc := `{"messages":[
+		{"role":"user","content":[{"type":"text","text":"hi"},{"type":"image_url","image_url":{"url":"https://x/y.png"}}]},
+		{"role":"assistant","content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"f","arguments":"{}"}}]}
+	]}`
+	got, err := DecodeTranscript(strings.NewReader(doc), FormatOpenAI)
+	if err != nil {
+		t.Fatalf("DecodeTranscript() error: %v", err)
+	}
+	if got.Key != "" || len(got.Messages) != 2 {
+		t.Fatalf("got %+v", got)
+	}
+	if got.Messages[0].Content != "hi\n[image: https://x/y.png]" {
+		t.Errorf("content = %q", got.Messages[0].Content)
+	}
+	if tc := got.Messages[1].ToolCalls; len(tc) != 1 || tc[0].Function == nil || tc[0].Function.Name != "f" {
+		t.Errorf("tool calls = %+v", tc)
+	}
+}
+
+func TestExportImportTranscript_Backends(t *testing.T) {
+	want := sampleTranscript()
+	for _, backend := range []string{config.SessionStoreJSON, config.SessionStoreJSONL, config.SessionStoreSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := OpenStore(backend, dir)
+			if err != nil {
+				t.Fatalf("OpenStore() error: %v", err)
+			}
+			if _, err := ExportTranscript(store, want.Key); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("ExportTranscript() on missing session error = %v", err)
+			}
+
+			// Importing into a new session creates it.
+			fresh := want
+			fresh.Key = "agent:main:telegram:direct:43"
+			if err := ImportTranscript(store, fresh); err != nil {
+				t.Fatalf("ImportTranscript() error: %v", err)
+			}
+			if got, err := ExportTranscript(store, fresh.Key); err != nil || !reflect.DeepEqual(got, fresh) {
+				t.Errorf("export of new session = %+v, %v", got, err)
+			}
+
+			// Importing over an existing session replaces it.
+			store.AddMessage(want.Key, "user", "stale")
+			if err := ImportTranscript(store, want); err != nil {
+				t.Fatalf("ImportTranscript() error: %v", err)
+			}
+			store.Close()
+
+			// Reopen to make sure the import was persisted.
+			store, err = OpenStore(backend, dir)
+			if err != nil {
+				t.Fatalf("OpenStore() error: %v", err)
+			}
+			defer store.Close()
+			got, err := ExportTranscript(store, want.Key)
+			if err != nil {
+				t.Fatalf("ExportTranscript() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("export after import mismatch\n got: %+v\nwant: %+v", got, want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -265,6 +266,65 @@ func (sm *SessionManager) loadSessions() error {
 	return nil
 }
 
+// ReplaceSession creates the session, or replaces its history and summary
+// if it exists. Unlike SetHistory it does not require an existing session.
+func (sm *SessionManager) ReplaceSession(key string, history []providers.Message, summary string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	now := time.Now()
+	session, ok := sm.sessions[key]
+	if !ok {
+		session = &Session{Key: key, Created: now}
+		sm.sessions[key] = session
+	}
+	session.Messages = make([]providers.Message, len(history))
+	copy(session.Messages, history)
+	session.Summary = summary
+	session.Updated = now
+	return nil
+}
+
+// ListSessions returns the sessions held in memory, which includes every
+// session file present when the manager was created.
+func (sm *SessionManager) ListSessions() ([]SessionInfo, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	sessions := make([]SessionInfo, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		sessions = append(sessions, SessionInfo{Key: s.Key, CreatedAt: s.Created, UpdatedAt: s.Updated})
+	}
+	return sessions, nil
+}
+
+// DeleteSession removes the session from memory and deletes its file. It
+// returns ErrSessionNotFound if neither exists.
+func (sm *SessionManager) DeleteSession(key string) error {
+	sm.mu.Lock()
+	_, found := sm.sessions[key]
+	delete(sm.sessions, key)
+	sm.mu.Unlock()
+
+	if sm.storage != "" {
+		filename := sanitizeFilename(key)
+		if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+			return os.ErrInvalid
+		}
+		err := os.Remove(filepath.Join(sm.storage, filename+".json"))
+		switch {
+		case err == nil:
+			found = true
+		case !os.IsNotExist(err):
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, key)
+	}
+	return nil
+}
+
 // SetHistory updates the messages of a session.
 func (sm *SessionManager) SetHistory(key string, history []providers.Message) {
 	sm.mu.Lock()
@@ -280,3 +340,8 @@ func (sm *SessionManager) SetHistory(key string, history []providers.Message) {
 		session.Updated = time.Now()
 	}
 }
+
+// Close is a no-op; sessions are persisted by Save.
+func (sm *SessionManager) Close() error {
+	return nil
+}
@@ -0,0 +1,211 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/memory"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// SessionStore is the session API used by the agent loop. SessionManager
+// implements it with one JSON file per session; StoreBackend adapts any
+// memory.Store (JSONL, SQLite).
+type SessionStore interface {
+	AddMessage(sessionKey, role, content string)
+	AddFullMessage(sessionKey string, msg providers.Message)
+	GetHistory(key string) []providers.Message
+	GetSummary(key string) string
+	SetSummary(key string, summary string)
+	SetHistory(key string, history []providers.Message)
+	TruncateHistory(key string, keepLast int)
+	ReplaceSession(key string, history []providers.Message, summary string) error
+	ListSessions() ([]SessionInfo, error)
+	DeleteSession(key string) error
+	Save(key string) error
+	Close() error
+}
+
+// SessionInfo describes a stored session without its messages.
+type SessionInfo = memory.SessionInfo
+
+// SQLiteFileName is the database file the sqlite backend keeps in the
+// sessions directory.
+const SQLiteFileName = "sessions.db"
+
+// OpenStore opens the session backend named by backend (one of the
+// config.SessionStore* values; empty means JSON) rooted at dir. Opening a
+// store never modifies sessions written by other backends; they are
+// imported by "picoclaw sessions migrate".
+func OpenStore(backend, dir string) (SessionStore, error) {
+	var store memory.Store
+	switch backend {
+	case "", config.SessionStoreJSON:
+		return NewSessionManager(dir), nil
+	case config.SessionStoreJSONL:
+		s, err := memory.NewJSONLStore(dir)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	case config.SessionStoreSQLite:
+		s, err := memory.NewSQLiteStore(filepath.Join(dir, SQLiteFileName))
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	default:
+		return nil, fmt.Errorf("unknown session store %q", backend)
+	}
+
+	if n := countLegacySessions(backend, dir); n > 0 {
+		logger.WarnCF("session", "Sessions from another backend are not loaded; run 'picoclaw sessions migrate' to import them",
+			map[string]any{
+				"backend":  backend,
+				"dir":      dir,
+				"sessions": n,
+			})
+	}
+	return NewStoreBackend(store), nil
+}
+
+// countLegacySessions counts the session files in dir that backend does
+// not read: JSON files, and for SQLite also JSONL files.
+func countLegacySessions(backend, dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".meta.json"):
+		case strings.HasSuffix(name, ".json"):
+			n++
+		case strings.HasSuffix(name, ".jsonl") && backend == config.SessionStoreSQLite:
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	_ SessionStore = (*SessionManager)(nil)
+	_ SessionStore = (*StoreBackend)(nil)
+)
+
+// StoreBackend adapts a memory.Store to SessionStore. Every write is
+// persisted immediately by the store, so Save only compacts. Store errors
+// are logged, matching the fire-and-forget SessionManager API.
+type StoreBackend struct {
+	store memory.Store
+}
+
+// NewStoreBackend wraps store.
+func NewStoreBackend(store memory.Store) *StoreBackend {
+	return &StoreBackend{store: store}
+}
+
+// Store returns the underlying memory.Store.
+func (b *StoreBackend) Store() memory.Store {
+	return b.store
+}
+
+func (b *StoreBackend) logError(op, key string, err error) {
+	logger.WarnCF("session", "Session store operation failed", map[string]any{
+		"op":          op,
+		"session_key": key,
+		"error":       err.Error(),
+	})
+}
+
+func (b *StoreBackend) AddMessage(sessionKey, role, content string) {
+	if err := b.store.AddMessage(context.Background(), sessionKey, role, content); err != nil {
+		b.logError("add_message", sessionKey, err)
+	}
+}
+
+func (b *StoreBackend) AddFullMessage(sessionKey string, msg providers.Message) {
+	if err := b.store.AddFullMessage(context.Background(), sessionKey, msg); err != nil {
+		b.logError("add_message", sessionKey, err)
+	}
+}
+
+func (b *StoreBackend) GetHistory(key string) []providers.Message {
+	history, err := b.store.GetHistory(context.Background(), key)
+	if err != nil {
+		b.logError("get_history", key, err)
+		return []providers.Message{}
+	}
+	return history
+}
+
+func (b *StoreBackend) GetSummary(key string) string {
+	summary, err := b.store.GetSummary(context.Background(), key)
+	if err != nil {
+		b.logError("get_summary", key, err)
+		return ""
+	}
+	return summary
+}
+
+func (b *StoreBackend) SetSummary(key string, summary string) {
+	if err := b.store.SetSummary(context.Background(), key, summary); err != nil {
+		b.logError("set_summary", key, err)
+	}
+}
+
+func (b *StoreBackend) SetHistory(key string, history []providers.Message) {
+	if err := b.store.SetHistory(context.Background(), key, history); err != nil {
+		b.logError("set_history", key, err)
+	}
+}
+
+// ReplaceSession creates the session, or replaces its history and summary
+// if it exists.
+func (b *StoreBackend) ReplaceSession(key string, history []providers.Message, summary string) error {
+	ctx := context.Background()
+	if err := b.store.SetHistory(ctx, key, history); err != nil {
+		return err
+	}
+	return b.store.SetSummary(ctx, key, summary)
+}
+
+func (b *StoreBackend) ListSessions() ([]SessionInfo, error) {
+	return b.store.ListSessions(context.Background())
+}
+
+// DeleteSession removes the session. It returns ErrSessionNotFound if the
+// store has no such session.
+func (b *StoreBackend) DeleteSession(key string) error {
+	err := b.store.DeleteSession(context.Background(), key)
+	if errors.Is(err, memory.ErrSessionNotFound) {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, key)
+	}
+	return err
+}
+
+func (b *StoreBackend) TruncateHistory(key string, keepLast int) {
+	if err := b.store.TruncateHistory(context.Background(), key, keepLast); err != nil {
+		b.logError("truncate_history", key, err)
+	}
+}
+
+// Save compacts the session; messages were already written by the store.
+func (b *StoreBackend) Save(key string) error {
+	return b.store.Compact(context.Background(), key)
+}
+
+func (b *StoreBackend) Close() error {
+	return b.store.Close()
+}
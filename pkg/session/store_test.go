@@ -0,0 +1,122 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestOpenStore_SQLiteLeavesJSONSessionsAlone(t *testing.T) {
+	dir := t.TempDir()
+
+	legacy := NewSessionManager(dir)
+	legacy.AddMessage("telegram:1", "user", "hello")
+	if err := legacy.Save("telegram:1"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	store, err := OpenStore(config.SessionStoreSQLite, dir)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, SQLiteFileName)); err != nil {
+		t.Fatalf("expected sqlite database: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "telegram_1.json")); err != nil {
+		t.Fatalf("legacy session file was moved: %v", err)
+	}
+	if history := store.GetHistory("telegram:1"); len(history) != 0 {
+		t.Fatalf("history = %+v, want legacy session not imported", history)
+	}
+
+	store.AddMessage("telegram:1", "user", "again")
+	store.AddMessage("telegram:1", "assistant", "hi")
+	store.TruncateHistory("telegram:1", 1)
+	if err := store.Save("telegram:1"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	history := store.GetHistory("telegram:1")
+	if len(history) != 1 || history[0].Content != "hi" {
+		t.Fatalf("history after truncate = %+v", history)
+	}
+}
+
+func TestSessionStore_ListAndDelete(t *testing.T) {
+	for _, backend := range []string{config.SessionStoreJSON, config.SessionStoreJSONL, config.SessionStoreSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := OpenStore(backend, dir)
+			if err != nil {
+				t.Fatalf("OpenStore: %v", err)
+			}
+			defer store.Close()
+
+			store.AddMessage("telegram:1", "user", "hello")
+			store.AddMessage("discord:2", "user", "hey")
+			for _, key := range []string{"telegram:1", "discord:2"} {
+				if err := store.Save(key); err != nil {
+					t.Fatalf("Save(%s): %v", key, err)
+				}
+			}
+
+			sessions, err := store.ListSessions()
+			if err != nil {
+				t.Fatalf("ListSessions: %v", err)
+			}
+			keys := make([]string, 0, len(sessions))
+			for _, s := range sessions {
+				keys = append(keys, s.Key)
+				if s.UpdatedAt.IsZero() {
+					t.Errorf("session %s has no update time", s.Key)
+				}
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, []string{"discord:2", "telegram:1"}) {
+				t.Fatalf("ListSessions keys = %v", keys)
+			}
+
+			if err := store.DeleteSession("telegram:1"); err != nil {
+				t.Fatalf("DeleteSession: %v", err)
+			}
+			if history := store.GetHistory("telegram:1"); len(history) != 0 {
+				t.Errorf("history after delete = %+v", history)
+			}
+			if err := store.DeleteSession("telegram:1"); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("second DeleteSession error = %v, want ErrSessionNotFound", err)
+			}
+			if sessions, _ := store.ListSessions(); len(sessions) != 1 || sessions[0].Key != "discord:2" {
+				t.Errorf("sessions after delete = %+v", sessions)
+			}
+		})
+	}
+}
+
+func TestOpenStore_Backends(t *testing.T) {
+	for _, backend := range []string{"", config.SessionStoreJSON} {
+		store, err := OpenStore(backend, t.TempDir())
+		if err != nil {
+			t.Fatalf("OpenStore(%q): %v", backend, err)
+		}
+		if _, ok := store.(*SessionManager); !ok {
+			t.Errorf("OpenStore(%q) = %T, want *SessionManager", backend, store)
+		}
+	}
+
+	store, err := OpenStore(config.SessionStoreJSONL, t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenStore(jsonl): %v", err)
+	}
+	if _, ok := store.(*StoreBackend); !ok {
+		t.Errorf("OpenStore(jsonl) = %T, want *StoreBackend", store)
+	}
+
+	if _, err := OpenStore("redis", t.TempDir()); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
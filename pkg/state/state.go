@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -21,10 +22,23 @@ type State struct {
 	// LastChatID is the last chat ID used for communication
 	LastChatID string `json:"last_chat_id,omitempty"`
 
+	// Branches maps a chat's base session key to its named conversation branches
+	Branches map[string]*BranchState `json:"branches,omitempty"`
+
 	// Timestamp is the last time this state was updated
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// BranchState records the named branches forked from one chat session and
+// which of them new messages are routed to.
+type BranchState struct {
+	// Active is the branch currently in use; empty means the original session
+	Active string `json:"active,omitempty"`
+
+	// Names lists the branches in creation order
+	Names []string `json:"names,omitempty"`
+}
+
 // Manager manages persistent state with atomic saves.
 type Manager struct {
 	workspace string
@@ -129,6 +143,78 @@ func (sm *Manager) GetTimestamp() time.Time {
 	return sm.state.Timestamp
 }
 
+// AddBranch records a new branch for baseKey and saves the state.
+// Adding an existing branch is a no-op.
+func (sm *Manager) AddBranch(baseKey, name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.state.Branches == nil {
+		sm.state.Branches = make(map[string]*BranchState)
+	}
+	bs := sm.state.Branches[baseKey]
+	if bs == nil {
+		bs = &BranchState{}
+		sm.state.Branches[baseKey] = bs
+	}
+	if slices.Contains(bs.Names, name) {
+		return nil
+	}
+	bs.Names = append(bs.Names, name)
+	sm.state.Timestamp = time.Now()
+
+	if err := sm.saveAtomic(); err != nil {
+		return fmt.Errorf("failed to save state atomically: %w", err)
+	}
+	return nil
+}
+
+// SetActiveBranch selects the branch new messages for baseKey are routed to
+// and saves the state. An empty name selects the original session.
+func (sm *Manager) SetActiveBranch(baseKey, name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	bs := sm.state.Branches[baseKey]
+	if bs == nil {
+		if name == "" {
+			return nil
+		}
+		return fmt.Errorf("no branches recorded for %s", baseKey)
+	}
+	if name != "" && !slices.Contains(bs.Names, name) {
+		return fmt.Errorf("unknown branch %q", name)
+	}
+	bs.Active = name
+	sm.state.Timestamp = time.Now()
+
+	if err := sm.saveAtomic(); err != nil {
+		return fmt.Errorf("failed to save state atomically: %w", err)
+	}
+	return nil
+}
+
+// GetActiveBranch returns the active branch for baseKey, or an empty string
+// when the original session is in use.
+func (sm *Manager) GetActiveBranch(baseKey string) string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	if bs := sm.state.Branches[baseKey]; bs != nil {
+		return bs.Active
+	}
+	return ""
+}
+
+// GetBranches returns the branch names recorded for baseKey.
+func (sm *Manager) GetBranches(baseKey string) []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	if bs := sm.state.Branches[baseKey]; bs != nil {
+		return slices.Clone(bs.Names)
+	}
+	return nil
+}
+
 // saveAtomic performs an atomic save using temp file + rename.
 // This ensures that the state file is never corrupted:
 // 1. Write to a temp file
@@ -194,6 +194,43 @@ func TestNewManager_ExistingState(t *testing.T) {
 	}
 }
 
+func TestBranches_PersistAcrossManagers(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := "agent:main:telegram:direct:42"
+
+	sm1 := NewManager(tmpDir)
+	if err := sm1.SetActiveBranch(base, "idea"); err == nil {
+		t.Error("Expected error when activating an unknown branch")
+	}
+	if err := sm1.AddBranch(base, "idea"); err != nil {
+		t.Fatalf("AddBranch failed: %v", err)
+	}
+	if err := sm1.AddBranch(base, "idea"); err != nil {
+		t.Fatalf("AddBranch (duplicate) failed: %v", err)
+	}
+	if err := sm1.SetActiveBranch(base, "idea"); err != nil {
+		t.Fatalf("SetActiveBranch failed: %v", err)
+	}
+
+	sm2 := NewManager(tmpDir)
+	if got := sm2.GetActiveBranch(base); got != "idea" {
+		t.Errorf("Expected active branch 'idea', got '%s'", got)
+	}
+	if got := sm2.GetBranches(base); len(got) != 1 || got[0] != "idea" {
+		t.Errorf("Expected branches [idea], got %v", got)
+	}
+	if got := sm2.GetActiveBranch("agent:main:main"); got != "" {
+		t.Errorf("Expected no active branch for other chats, got '%s'", got)
+	}
+
+	if err := sm2.SetActiveBranch(base, ""); err != nil {
+		t.Fatalf("SetActiveBranch to original failed: %v", err)
+	}
+	if got := sm2.GetActiveBranch(base); got != "" {
+		t.Errorf("Expected original session to be active, got '%s'", got)
+	}
+}
+
 func TestNewManager_EmptyWorkspace(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "state-test-*")
 	if err != nil {
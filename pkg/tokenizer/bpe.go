@@ -0,0 +1,112 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxPieceBytes bounds the quadratic merge loop; longer pieces (base64
+// blobs, minified code) are counted in chunks of this size.
+const maxPieceBytes = 256
+
+// BPE is a byte-level byte-pair encoder using a tiktoken rank table.
+type BPE struct {
+	ranks       map[string]int
+	imageTokens int
+	split       func(string) []string // pre-tokenizer; cl100k_base's when nil
+}
+
+// NewBPE returns an encoder for ranks, which maps each token's bytes to its
+// merge priority (lower merges first).
+func NewBPE(ranks map[string]int, imageTokens int) *BPE {
+	return &BPE{ranks: ranks, imageTokens: imageTokens}
+}
+
+// LoadRanks reads a tiktoken rank file: one "<base64 token> <rank>" pair
+// per line, as published for cl100k_base and o200k_base.
+func LoadRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		tok, rankStr, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("tokenizer: line %d: expected \"<token> <rank>\"", line)
+		}
+		b, err := base64.StdEncoding.DecodeString(tok)
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(rankStr)
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: line %d: %w", line, err)
+		}
+		ranks[string(b)] = rank
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("tokenizer: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("tokenizer: empty rank file")
+	}
+	return ranks, nil
+}
+
+// Count implements TokenCounter.
+func (b *BPE) Count(text string) int {
+	split := b.split
+	if split == nil {
+		split = splitPieces
+	}
+	total := 0
+	for _, piece := range split(text) {
+		for len(piece) > maxPieceBytes {
+			total += b.countPiece(piece[:maxPieceBytes])
+			piece = piece[maxPieceBytes:]
+		}
+		total += b.countPiece(piece)
+	}
+	return total
+}
+
+// ImageTokens implements TokenCounter.
+func (b *BPE) ImageTokens() int { return b.imageTokens }
+
+// countPiece runs the merge loop on one pre-token: starting from single
+// bytes, it repeatedly merges the adjacent pair with the lowest rank until
+// no pair is in the table, and returns the number of parts left.
+func (b *BPE) countPiece(piece string) int {
+	if piece == "" {
+		return 0
+	}
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+
+	// bounds holds the start offset of every part, followed by len(piece).
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, at := math.MaxInt, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := b.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < best {
+				best, at = rank, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		bounds = append(bounds[:at+1], bounds[at+2:]...)
+	}
+	return len(bounds) - 1
+}
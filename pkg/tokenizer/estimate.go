@@ -0,0 +1,109 @@
+package tokenizer
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimator approximates a BPE tokenizer without its vocabulary. It splits
+// text with the same pre-tokenizer and prices each piece by shape: common
+// words are a single token, long or irregular ones a few, digits come in
+// groups of three, and CJK characters cost CJKPerRune each.
+type Estimator struct {
+	CJKPerRune   float64 // tokens per Han, Kana or Hangul character
+	OtherPerRune float64 // tokens per letter of other non-ASCII scripts
+	ImageCost    int     // tokens per image attachment
+
+	split func(string) []string // pre-tokenizer; cl100k_base's when nil
+}
+
+// Count implements TokenCounter.
+func (e *Estimator) Count(text string) int {
+	split := e.split
+	if split == nil {
+		split = splitPieces
+	}
+	var total float64
+	for _, piece := range split(text) {
+		total += e.piece(piece)
+	}
+	return int(math.Ceil(total))
+}
+
+// ImageTokens implements TokenCounter.
+func (e *Estimator) ImageTokens() int { return e.ImageCost }
+
+func (e *Estimator) piece(p string) float64 {
+	r, _ := utf8.DecodeRuneInString(p)
+	switch {
+	case unicode.IsNumber(r):
+		return 1
+	case unicode.IsSpace(r) && len(p) > 1 && !hasLetter(p):
+		// Whitespace runs and indentation have dedicated tokens.
+		return 1
+	}
+
+	var tokens float64
+	ascii, asciiUpper, asciiInner := 0, 0, 0
+	flushWord := func() {
+		if ascii > 0 {
+			tokens += wordTokens(ascii, asciiInner > 0)
+		}
+		ascii, asciiUpper, asciiInner = 0, 0, 0
+	}
+	punct := 0
+	for _, c := range p {
+		switch {
+		case c < utf8.RuneSelf && unicode.IsLetter(c):
+			if unicode.IsUpper(c) {
+				if ascii > 0 && asciiUpper < ascii {
+					asciiInner++ // a capital after lower-case letters: camelCase or random
+				}
+				asciiUpper++
+			}
+			ascii++
+		case isCJK(c):
+			flushWord()
+			tokens += e.CJKPerRune
+		case unicode.IsLetter(c):
+			flushWord()
+			tokens += e.OtherPerRune
+		case unicode.IsSpace(c):
+			// A leading space merges into the following word.
+		default:
+			punct++
+		}
+	}
+	flushWord()
+	// Punctuation runs like "```", "...", "{\"" are mostly one token per
+	// two characters; a single mark before a word merges into it.
+	if punct > 1 || tokens == 0 {
+		tokens += math.Ceil(float64(punct) / 2)
+	}
+	return math.Max(tokens, 1)
+}
+
+// wordTokens prices a run of n ASCII letters: regular words up to seven
+// letters are one token, longer ones one more per seven letters, and
+// irregular mixed-case runs (identifiers, base64) about one per three.
+func wordTokens(n int, irregular bool) float64 {
+	if irregular {
+		return math.Ceil(float64(n) / 3)
+	}
+	return float64(1 + (n-1)/7)
+}
+
+func hasLetter(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,78 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Framing overhead of the chat format: every message is wrapped in role
+// markers, and the reply is primed with a few more.
+const (
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// CountMessages returns the prompt size of a chat request: message text,
+// tool calls and their arguments, attachments and the tool schemas.
+func CountMessages(c TokenCounter, messages []providers.Message, tools []providers.ToolDefinition) int {
+	total := CountTools(c, tools)
+	for _, m := range messages {
+		total += CountMessage(c, m)
+	}
+	if len(messages) > 0 {
+		total += tokensPerReply
+	}
+	return total
+}
+
+// CountMessage returns the size of one message including its framing.
+func CountMessage(c TokenCounter, m providers.Message) int {
+	// SystemParts repeat Content as blocks, so only Content is counted.
+	total := tokensPerMessage + c.Count(m.Role) + c.Count(m.Content) + c.Count(m.ReasoningContent)
+	for _, tc := range m.ToolCalls {
+		total += c.Count(tc.ID)
+		if tc.Function != nil {
+			total += c.Count(tc.Function.Name) + c.Count(tc.Function.Arguments)
+		} else {
+			total += c.Count(tc.Name)
+			if args, err := json.Marshal(tc.Arguments); err == nil {
+				total += c.Count(string(args))
+			}
+		}
+	}
+	if m.ToolCallID != "" {
+		total += c.Count(m.ToolCallID)
+	}
+	total += len(m.Images) * c.ImageTokens()
+	for _, f := range m.Files {
+		total += fileTokens(f.Data)
+	}
+	for _, ref := range m.Media {
+		if strings.HasPrefix(ref, "data:") && !strings.HasPrefix(ref, "data:image/") {
+			total += fileTokens(ref)
+		} else {
+			total += c.ImageTokens()
+		}
+	}
+	return total
+}
+
+// CountTools returns the size of the tool schemas sent with a request.
+func CountTools(c TokenCounter, tools []providers.ToolDefinition) int {
+	total := 0
+	for _, t := range tools {
+		total += c.Count(t.Function.Name) + c.Count(t.Function.Description)
+		if params, err := json.Marshal(t.Function.Parameters); err == nil {
+			total += c.Count(string(params))
+		}
+	}
+	return total
+}
+
+// fileTokens prices a base64 document at one token per three bytes of
+// content, which errs high for PDFs with images; that is the safe side.
+func fileTokens(b64 string) int {
+	return len(b64) / 4
+}
@@ -0,0 +1,264 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitPieces splits text the way the cl100k_base pattern does before BPE
+// runs, without needing a regexp engine with lookahead:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitPieces(text string) []string {
+	return split(text, nextPiece)
+}
+
+// splitPiecesO200K splits text the way the o200k_base pattern does:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Unlike cl100k it splits words at case changes ("camelCase" is "camel",
+// "Case") and keeps contractions with the word before them.
+func splitPiecesO200K(text string) []string {
+	return split(text, nextPieceO200K)
+}
+
+func split(text string, next func(string) int) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		n := next(text[i:])
+		pieces = append(pieces, text[i:i+n])
+		i += n
+	}
+	return pieces
+}
+
+// nextPiece returns the byte length of the piece at the start of s, trying
+// the alternatives of the pattern in order.
+func nextPiece(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	if n := contraction(s); n > 0 {
+		return n
+	}
+
+	// [^\r\n\p{L}\p{N}]?\p{L}+
+	if unicode.IsLetter(r) {
+		return size + letterRun(s[size:])
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) {
+		if n := letterRun(s[size:]); n > 0 {
+			return size + n
+		}
+	}
+
+	// \p{N}{1,3}
+	if unicode.IsNumber(r) {
+		n := size
+		for count := 1; count < 3 && n < len(s); count++ {
+			next, nsize := utf8.DecodeRuneInString(s[n:])
+			if !unicode.IsNumber(next) {
+				break
+			}
+			n += nsize
+		}
+		return n
+	}
+
+	// ' ?[^\s\p{L}\p{N}]+[\r\n]*'
+	if n := punctPiece(s, "\r\n"); n > 0 {
+		return n
+	}
+	return spacePiece(s)
+}
+
+// nextPieceO200K is nextPiece for the o200k_base pattern.
+func nextPieceO200K(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	// The two word alternatives, each first with its optional leading
+	// character.
+	for _, word := range []func(string) int{lowerWord, upperWord} {
+		if r != '\r' && r != '\n' && !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			if n := word(s[size:]); n > 0 {
+				return size + n
+			}
+		}
+		if n := word(s); n > 0 {
+			return n
+		}
+	}
+
+	// \p{N}{1,3}
+	if unicode.IsNumber(r) {
+		n := size
+		for count := 1; count < 3 && n < len(s); count++ {
+			next, nsize := utf8.DecodeRuneInString(s[n:])
+			if !unicode.IsNumber(next) {
+				break
+			}
+			n += nsize
+		}
+		return n
+	}
+
+	// ' ?[^\s\p{L}\p{N}]+[\r\n/]*'
+	if n := punctPiece(s, "\r\n/"); n > 0 {
+		return n
+	}
+	return spacePiece(s)
+}
+
+// lowerWord matches U*L+ and an optional contraction at the start of s,
+// where U is [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}] and L is [\p{Ll}\p{Lm}\p{Lo}\p{M}].
+// Lm, Lo and M belong to both classes, so U* gives back runes until L+
+// matches, as a backtracking regexp engine would.
+func lowerWord(s string) int {
+	upperEnds := []int{0} // offsets after 0, 1, 2, ... runes of the U run
+	for n := 0; n < len(s); {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isUpperClass(r) {
+			break
+		}
+		n += size
+		upperEnds = append(upperEnds, n)
+	}
+	for i := len(upperEnds) - 1; i >= 0; i-- {
+		start := upperEnds[i]
+		if l := lowerRun(s[start:]); l > 0 {
+			return start + l + contraction(s[start+l:])
+		}
+	}
+	return 0
+}
+
+// upperWord matches U+L* and an optional contraction at the start of s.
+func upperWord(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isUpperClass(r) {
+			break
+		}
+		n += size
+	}
+	if n == 0 {
+		return 0
+	}
+	n += lowerRun(s[n:])
+	return n + contraction(s[n:])
+}
+
+func isUpperClass(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func isLowerClass(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func lowerRun(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isLowerClass(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// punctPiece matches ' ?[^\s\p{L}\p{N}]+' followed by any run of the bytes
+// in trailing.
+func punctPiece(s, trailing string) int {
+	start := 0
+	if len(s) > 0 && s[0] == ' ' {
+		start = 1
+	}
+	n := punctRun(s[start:])
+	if n == 0 {
+		return 0
+	}
+	end := start + n
+	for end < len(s) && strings.IndexByte(trailing, s[end]) >= 0 {
+		end++
+	}
+	return end
+}
+
+// spacePiece matches the whitespace alternatives shared by both patterns,
+// \s*[\r\n]+ | \s+(?!\S) | \s+, or a single rune when nothing else did.
+func spacePiece(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+	if unicode.IsSpace(r) {
+		end, lastNewline, lastStart := 0, -1, 0
+		for end < len(s) {
+			next, nsize := utf8.DecodeRuneInString(s[end:])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			if next == '\r' || next == '\n' {
+				lastNewline = end + nsize
+			}
+			lastStart = end
+			end += nsize
+		}
+		if lastNewline > 0 {
+			return lastNewline
+		}
+		if end == len(s) || lastStart == 0 {
+			return end
+		}
+		// Leave the last space to prefix the following word.
+		return lastStart
+	}
+
+	return size
+}
+
+func contraction(s string) int {
+	if len(s) < 2 || s[0] != '\'' {
+		return 0
+	}
+	lower := func(b byte) byte { return b | 0x20 }
+	switch lower(s[1]) {
+	case 's', 't', 'm', 'd':
+		return 2
+	}
+	if len(s) >= 3 {
+		switch string([]byte{lower(s[1]), lower(s[2])}) {
+		case "re", "ve", "ll":
+			return 3
+		}
+	}
+	return 0
+}
+
+func letterRun(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func punctRun(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if unicode.IsSpace(r) || unicode.IsLetter(r) || unicode.IsNumber(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
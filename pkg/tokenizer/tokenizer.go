@@ -0,0 +1,289 @@
+// Package tokenizer counts tokens the way model tokenizers do, so context
+// budgets can be checked before a request is sent instead of after the
+// provider rejects it.
+//
+// Counting is exact for OpenAI models once the tiktoken rank files
+// (cl100k_base.tiktoken, o200k_base.tiktoken) are in the vocabulary
+// directory, and estimated from the same pre-tokenization until then. When
+// a download URL is set, missing rank files are fetched in the background
+// and checked against their published SHA-256. Claude counts are
+// approximated from cl100k, which Anthropic's tokenizer exceeds by roughly
+// a sixth.
+package tokenizer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/fileutil"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// TokenCounter counts tokens for one tokenizer family.
+type TokenCounter interface {
+	// Count returns the number of tokens text encodes to.
+	Count(text string) int
+	// ImageTokens returns what one image attachment costs.
+	ImageTokens() int
+}
+
+// Encoding names a tokenizer family.
+type Encoding string
+
+const (
+	CL100K Encoding = "cl100k_base" // GPT-4, GPT-3.5, embeddings; default for unknown models
+	O200K  Encoding = "o200k_base"  // GPT-4o, GPT-4.1, GPT-5, o-series
+	Claude Encoding = "claude"      // Anthropic models, approximated from cl100k
+)
+
+// Image costs for a typical photo: OpenAI's high-detail 1024×1024 price and
+// Anthropic's width×height/750 for a 1092×1092 image.
+const (
+	openAIImageTokens = 765
+	claudeImageTokens = 1600
+)
+
+// claudeScale is how many more tokens Anthropic's tokenizer produces than
+// cl100k for typical chat and code.
+const claudeScale = 1.16
+
+// DefaultVocabURL is where OpenAI publishes the tiktoken rank files.
+const DefaultVocabURL = "https://openaipublic.blob.core.windows.net/encodings"
+
+// maxVocabBytes bounds a downloaded rank file; o200k_base is about 3.6 MB.
+const maxVocabBytes = 16 << 20
+
+// vocabSHA256 pins the published rank files, as tiktoken does.
+var vocabSHA256 = map[Encoding]string{
+	CL100K: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	O200K:  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+}
+
+var (
+	vocabDirMu sync.RWMutex
+	vocabDir   string
+	vocabURL   string
+
+	bpeMu    sync.Mutex
+	bpeCache = map[string]*BPE{} // vocabulary path → encoder, nil if unusable or missing
+)
+
+// SetVocabDir sets where the tiktoken rank files are looked up. The default
+// is $PICOCLAW_HOME/tokenizers (~/.picoclaw/tokenizers).
+func SetVocabDir(dir string) {
+	vocabDirMu.Lock()
+	defer vocabDirMu.Unlock()
+	vocabDir = dir
+}
+
+// SetVocabURL sets the base URL missing rank files are downloaded from,
+// usually DefaultVocabURL. Downloading is off while it is empty, which is
+// the default.
+func SetVocabURL(url string) {
+	vocabDirMu.Lock()
+	defer vocabDirMu.Unlock()
+	vocabURL = strings.TrimRight(url, "/")
+}
+
+func vocabSourceURL() string {
+	vocabDirMu.RLock()
+	defer vocabDirMu.RUnlock()
+	return vocabURL
+}
+
+// VocabDir returns the directory tiktoken rank files are read from.
+func VocabDir() string {
+	vocabDirMu.RLock()
+	defer vocabDirMu.RUnlock()
+	if vocabDir != "" {
+		return vocabDir
+	}
+	if home := os.Getenv("PICOCLAW_HOME"); home != "" {
+		return filepath.Join(home, "tokenizers")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "tokenizers")
+}
+
+// EncodingForModel returns the tokenizer family of a model ID such as
+// "gpt-4o", "openai/gpt-4o" or "openrouter/anthropic/claude-sonnet-4".
+func EncodingForModel(model string) Encoding {
+	id := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(id, "claude"):
+		return Claude
+	case strings.HasPrefix(id, "gpt-4o"), strings.HasPrefix(id, "chatgpt-4o"),
+		strings.HasPrefix(id, "gpt-4.1"), strings.HasPrefix(id, "gpt-4.5"),
+		strings.HasPrefix(id, "gpt-5"), strings.HasPrefix(id, "gpt-oss"),
+		strings.HasPrefix(id, "o1"), strings.HasPrefix(id, "o3"), strings.HasPrefix(id, "o4"):
+		return O200K
+	}
+	return CL100K
+}
+
+// ForModel returns the counter for a model ID.
+func ForModel(model string) TokenCounter {
+	return ForEncoding(EncodingForModel(model))
+}
+
+// ForEncoding returns the counter for an encoding. It counts with the exact
+// BPE encoder once the rank file is loaded and estimates until then.
+func ForEncoding(enc Encoding) TokenCounter {
+	switch enc {
+	case Claude:
+		base := ForEncoding(CL100K)
+		return scaled{base: base, factor: claudeScale, imageTokens: claudeImageTokens}
+	case O200K:
+		return &vocabCounter{enc: O200K, estimate: &Estimator{
+			CJKPerRune: 0.75, OtherPerRune: 0.35, ImageCost: openAIImageTokens, split: splitPiecesO200K,
+		}}
+	}
+	return &vocabCounter{enc: CL100K, estimate: &Estimator{CJKPerRune: 1, OtherPerRune: 0.5, ImageCost: openAIImageTokens}}
+}
+
+// Default returns the counter used when the model is unknown.
+func Default() TokenCounter { return ForEncoding(CL100K) }
+
+// vocabCounter counts with the BPE encoder of enc when its rank file is
+// available, so a file that is downloaded later is picked up without
+// rebuilding the agents holding the counter.
+type vocabCounter struct {
+	enc      Encoding
+	estimate *Estimator
+}
+
+// Count implements TokenCounter.
+func (c *vocabCounter) Count(text string) int {
+	if bpe := loadBPE(c.enc); bpe != nil {
+		return bpe.Count(text)
+	}
+	return c.estimate.Count(text)
+}
+
+// ImageTokens implements TokenCounter.
+func (c *vocabCounter) ImageTokens() int { return openAIImageTokens }
+
+// loadBPE reads <VocabDir>/<enc>.tiktoken once; a broken file yields nil
+// and the caller falls back to estimating. A missing file yields nil too
+// and starts a download when a vocabulary URL is set.
+func loadBPE(enc Encoding) *BPE {
+	path := filepath.Join(VocabDir(), string(enc)+".tiktoken")
+
+	bpeMu.Lock()
+	defer bpeMu.Unlock()
+	if bpe, ok := bpeCache[path]; ok {
+		return bpe
+	}
+
+	var bpe *BPE
+	f, err := os.Open(path)
+	switch {
+	case err == nil:
+		ranks, err := LoadRanks(f)
+		f.Close()
+		if err != nil {
+			logger.WarnCF("tokenizer", "Ignoring unreadable vocabulary, estimating instead", map[string]any{
+				"path":  path,
+				"error": err.Error(),
+			})
+		} else {
+			bpe = newEncodingBPE(enc, ranks)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		if base := vocabSourceURL(); base != "" && vocabSHA256[enc] != "" {
+			go downloadVocab(enc, base+"/"+string(enc)+".tiktoken", path)
+		}
+	}
+	bpeCache[path] = bpe
+	return bpe
+}
+
+func newEncodingBPE(enc Encoding, ranks map[string]int) *BPE {
+	bpe := NewBPE(ranks, openAIImageTokens)
+	if enc == O200K {
+		bpe.split = splitPiecesO200K
+	}
+	return bpe
+}
+
+// downloadVocab fetches the rank file of enc, verifies it and saves it to
+// path. Counters switch to the encoder as soon as it is cached.
+func downloadVocab(enc Encoding, url, path string) {
+	data, err := fetchVocab(url, vocabSHA256[enc])
+	var ranks map[string]int
+	if err == nil {
+		ranks, err = LoadRanks(bytes.NewReader(data))
+	}
+	if err != nil {
+		logger.WarnCF("tokenizer", "Failed to download vocabulary, estimating instead", map[string]any{
+			"url":   url,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logger.WarnCF("tokenizer", "Failed to save vocabulary", map[string]any{"path": path, "error": err.Error()})
+	} else if err := fileutil.WriteFileAtomic(path, data, 0o644); err != nil {
+		logger.WarnCF("tokenizer", "Failed to save vocabulary", map[string]any{"path": path, "error": err.Error()})
+	}
+
+	bpeMu.Lock()
+	bpeCache[path] = newEncodingBPE(enc, ranks)
+	bpeMu.Unlock()
+	logger.InfoCF("tokenizer", "Downloaded vocabulary", map[string]any{"encoding": string(enc), "path": path})
+}
+
+// fetchVocab downloads url and checks its SHA-256 against want.
+func fetchVocab(url, want string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxVocabBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxVocabBytes {
+		return nil, fmt.Errorf("vocabulary larger than %d bytes", maxVocabBytes)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("sha256 mismatch: got %s, want %s", got, want)
+	}
+	return data, nil
+}
+
+// scaled adapts a counter to a tokenizer known to produce about factor
+// times as many tokens.
+type scaled struct {
+	base        TokenCounter
+	factor      float64
+	imageTokens int
+}
+
+func (s scaled) Count(text string) int {
+	return int(math.Ceil(float64(s.base.Count(text)) * s.factor))
+}
+
+func (s scaled) ImageTokens() int { return s.imageTokens }
@@ -0,0 +1,257 @@
+package tokenizer
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestSplitPieces(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Hello world, how's it going?", []string{"Hello", " world", ",", " how", "'s", " it", " going", "?"}},
+		{"12345 abc", []string{"123", "45", " abc"}},
+		{"a  b", []string{"a", " ", " b"}},
+		{"end.\n\n  next", []string{"end", ".\n\n", " ", " next"}},
+		{"x   ", []string{"x", "   "}},
+	}
+	for _, tt := range tests {
+		if got := splitPieces(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("splitPieces(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitPiecesO200K(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Hello world, how's it going?", []string{"Hello", " world", ",", " how's", " it", " going", "?"}},
+		{"camelCase HTTPServer ABC", []string{"camel", "Case", " HTTPServer", " ABC"}},
+		{"I'M done", []string{"I'M", " done"}},
+		{"path/to/file\n", []string{"path", "/to", "/file", "\n"}},
+		{"x = 1;//\n", []string{"x", " =", " ", "1", ";//\n"}},
+		{"12345 abc", []string{"123", "45", " abc"}},
+	}
+	for _, tt := range tests {
+		if got := splitPiecesO200K(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("splitPiecesO200K(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// rankFile returns a tiktoken file with every single byte plus merges.
+func rankFile(merges ...string) string {
+	var sb strings.Builder
+	rank := 0
+	for b := range 256 {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), rank)
+		rank++
+	}
+	for _, m := range merges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(m)), rank)
+		rank++
+	}
+	return sb.String()
+}
+
+// writeRanks writes a rank file with rankFile's content to dir.
+func writeRanks(t *testing.T, dir string, enc Encoding, merges ...string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, string(enc)+".tiktoken"), []byte(rankFile(merges...)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBPE_FromVocabDir(t *testing.T) {
+	dir := t.TempDir()
+	SetVocabDir(dir)
+	t.Cleanup(func() { SetVocabDir("") })
+	writeRanks(t, dir, CL100K, "he", "ll", "hell", "hello", " w", " wo")
+
+	c := ForModel("openai/gpt-4")
+	if loadBPE(CL100K) == nil {
+		t.Fatal("expected the BPE encoder when the rank file exists")
+	}
+	// "hello" is a single token; " world" merges to " wo" + r + l + d.
+	if got := c.Count("hello world"); got != 5 {
+		t.Fatalf("Count = %d, want 5", got)
+	}
+
+	// o200k has no rank file here and downloading is off, so it is estimated.
+	if loadBPE(O200K) != nil {
+		t.Fatal("expected no o200k encoder without o200k_base.tiktoken")
+	}
+}
+
+func TestBPE_DownloadsVerifiedVocab(t *testing.T) {
+	good := rankFile("he", "ll", "hell", "hello")
+	var (
+		servedMu sync.Mutex
+		served   string
+	)
+	serve := func(body string) {
+		servedMu.Lock()
+		defer servedMu.Unlock()
+		served = body
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(O200K)+".tiktoken" {
+			http.NotFound(w, r)
+			return
+		}
+		servedMu.Lock()
+		defer servedMu.Unlock()
+		fmt.Fprint(w, served)
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256([]byte(good))
+	prev := vocabSHA256[O200K]
+	vocabSHA256[O200K] = hex.EncodeToString(sum[:])
+	SetVocabURL(srv.URL)
+	t.Cleanup(func() {
+		vocabSHA256[O200K] = prev
+		SetVocabURL("")
+		SetVocabDir("")
+	})
+
+	// A tampered file is rejected and nothing is saved.
+	dir := t.TempDir()
+	SetVocabDir(dir)
+	serve(good + "aGk= 999\n")
+	c := ForModel("gpt-4o")
+	c.Count("hello")
+	time.Sleep(200 * time.Millisecond)
+	if loadBPE(O200K) != nil {
+		t.Fatal("vocabulary with a wrong hash was used")
+	}
+	if _, err := os.Stat(filepath.Join(dir, string(O200K)+".tiktoken")); err == nil {
+		t.Fatal("vocabulary with a wrong hash was saved")
+	}
+
+	// The published file is downloaded in the background and picked up by
+	// the counter that already exists.
+	dir = t.TempDir()
+	SetVocabDir(dir)
+	serve(good)
+	c.Count("hello")
+	deadline := time.Now().Add(3 * time.Second)
+	for loadBPE(O200K) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("vocabulary was not downloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := c.Count("hello"); got != 1 {
+		t.Fatalf("Count = %d, want 1 with the downloaded vocabulary", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, string(O200K)+".tiktoken")); err != nil {
+		t.Fatalf("downloaded vocabulary not saved: %v", err)
+	}
+}
+
+func TestLoadRanks_Invalid(t *testing.T) {
+	if _, err := LoadRanks(strings.NewReader("not-a-rank-line\n")); err == nil {
+		t.Fatal("expected an error for a malformed line")
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := map[string]Encoding{
+		"gpt-4o-mini":                         O200K,
+		"openai/gpt-5.2":                      O200K,
+		"o3-mini":                             O200K,
+		"gpt-4":                               CL100K,
+		"anthropic/claude-sonnet-4.6":         Claude,
+		"openrouter/anthropic/claude-3-haiku": Claude,
+		"deepseek/deepseek-chat":              CL100K,
+	}
+	for model, want := range tests {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestEstimator(t *testing.T) {
+	SetVocabDir(t.TempDir())
+	t.Cleanup(func() { SetVocabDir("") })
+
+	c := Default()
+	tests := map[string]int{
+		"":                             0,
+		"Hello world, how's it going?": 8,
+		"12345":                        2,
+		"internationalization":         3,
+		"aGVsbG8gd29ybGQ":              7,
+	}
+	for text, want := range tests {
+		if got := c.Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+
+	cjk := string([]rune{0x4F60, 0x597D, 0x4E16, 0x754C})
+	if got := c.Count(cjk); got != 4 {
+		t.Errorf("cl100k CJK count = %d, want 4", got)
+	}
+	if got := ForModel("gpt-4o").Count(cjk); got != 3 {
+		t.Errorf("o200k CJK count = %d, want 3", got)
+	}
+	if got := ForModel("claude-sonnet-4").Count("Hello world, how's it going?"); got != 10 {
+		t.Errorf("claude count = %d, want 10", got)
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	SetVocabDir(t.TempDir())
+	t.Cleanup(func() { SetVocabDir("") })
+	c := Default()
+
+	text := []providers.Message{{Role: "user", Content: "hello"}}
+	base := CountMessages(c, text, nil)
+	if base != tokensPerMessage+1+1+tokensPerReply {
+		t.Fatalf("base count = %d", base)
+	}
+
+	withImage := []providers.Message{{Role: "user", Content: "hello", Media: []string{"data:image/png;base64,AAAA"}}}
+	if got := CountMessages(c, withImage, nil); got != base+c.ImageTokens() {
+		t.Fatalf("image count = %d, want %d", got, base+c.ImageTokens())
+	}
+
+	withCall := []providers.Message{{
+		Role:      "assistant",
+		ToolCalls: []providers.ToolCall{{ID: "c1", Function: &providers.FunctionCall{Name: "read_file", Arguments: `{"path":"notes.md"}`}}},
+	}}
+	if CountMessages(c, withCall, nil) <= CountMessages(c, []providers.Message{{Role: "assistant"}}, nil) {
+		t.Fatal("tool call arguments should be counted")
+	}
+
+	tools := []providers.ToolDefinition{{
+		Type: "function",
+		Function: providers.ToolFunctionDefinition{
+			Name:        "read_file",
+			Description: "Read a file from the workspace",
+			Parameters:  map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}}},
+		},
+	}}
+	if got := CountMessages(c, text, tools); got <= base {
+		t.Fatalf("tool schemas should be counted, got %d", got)
+	}
+}
@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const defaultApprovalTimeout = time.Minute
+
+var (
+	// ErrApprovalNotFound is returned when resolving an unknown or expired approval.
+	ErrApprovalNotFound = errors.New("no pending approval with that id")
+	// ErrApprovalForbidden is returned when someone other than the requester resolves an approval.
+	ErrApprovalForbidden = errors.New("only the user who triggered the tool call can resolve it")
+)
+
+type approvalRule struct {
+	tool       string
+	arg        string
+	pattern    *regexp.Regexp
+	allowPaths []string
+}
+
+// ApprovalPolicy decides which tool calls need human approval.
+type ApprovalPolicy struct {
+	workspace string
+	rules     []approvalRule
+}
+
+// NewApprovalPolicy compiles rules for an agent workspace. Invalid rules are
+// skipped and reported in the returned error; the policy is usable either way.
+func NewApprovalPolicy(rules []config.ApprovalRule, workspace string) (*ApprovalPolicy, error) {
+	p := &ApprovalPolicy{workspace: workspace}
+	var errs []error
+	for i, r := range rules {
+		if strings.TrimSpace(r.Tool) == "" {
+			errs = append(errs, fmt.Errorf("rule %d: tool is required", i))
+			continue
+		}
+		if _, err := path.Match(r.Tool, ""); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: invalid tool pattern %q: %w", i, r.Tool, err))
+			continue
+		}
+		rule := approvalRule{tool: r.Tool, arg: r.Arg}
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %d: invalid pattern %q: %w", i, r.Pattern, err))
+				continue
+			}
+			rule.pattern = re
+		}
+		for _, dir := range r.AllowPaths {
+			rule.allowPaths = append(rule.allowPaths, p.resolve(dir))
+		}
+		p.rules = append(p.rules, rule)
+	}
+	return p, errors.Join(errs...)
+}
+
+// Match reports whether a call to tool with args needs approval, and why.
+func (p *ApprovalPolicy) Match(tool string, args map[string]any) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	for _, r := range p.rules {
+		if ok, _ := path.Match(r.tool, tool); !ok {
+			continue
+		}
+		value := approvalArgValue(args, r.arg)
+		if r.pattern != nil && !r.pattern.MatchString(value) {
+			continue
+		}
+		if len(r.allowPaths) > 0 {
+			if value == "" || p.allowedPath(value, r.allowPaths) {
+				continue
+			}
+			return fmt.Sprintf("%s is outside the allowed directories", value), true
+		}
+		if r.pattern != nil {
+			return fmt.Sprintf("%s matches %s", approvalArgName(r.arg), r.pattern), true
+		}
+		return fmt.Sprintf("%s always requires approval", tool), true
+	}
+	return "", false
+}
+
+func (p *ApprovalPolicy) resolve(dir string) string {
+	if filepath.IsAbs(dir) || p.workspace == "" {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(p.workspace, dir)
+}
+
+func (p *ApprovalPolicy) allowedPath(value string, allowPaths []string) bool {
+	target := p.resolve(value)
+	for _, dir := range allowPaths {
+		if isWithinWorkspace(target, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func approvalArgValue(args map[string]any, name string) string {
+	if name == "" {
+		raw, _ := json.Marshal(args)
+		return string(raw)
+	}
+	switch v := args[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+}
+
+func approvalArgName(name string) string {
+	if name == "" {
+		return "arguments"
+	}
+	return name
+}
+
+// ApprovalRequest describes a tool call waiting for a decision.
+type ApprovalRequest struct {
+	ID        string
+	Tool      string
+	Args      map[string]any
+	Reason    string
+	Channel   string
+	AccountID string
+	ChatID    string
+	SenderID  string
+	Timeout   time.Duration
+}
+
+// Prompt renders the message posted to the chat for this request.
+func (r ApprovalRequest) Prompt() string {
+	args, _ := json.Marshal(r.Args)
+	return fmt.Sprintf(
+		"Approval required for tool %s (%s).\nArguments: %s\n\nReply /approve %s or /deny %s within %s.",
+		r.Tool, r.Reason, utils.Truncate(string(args), 500),
+		r.ID, r.ID, r.Timeout,
+	)
+}
+
+// ApprovalNotifier posts an approval prompt to the chat a request came from.
+type ApprovalNotifier func(ctx context.Context, req ApprovalRequest) error
+
+type pendingApproval struct {
+	req      ApprovalRequest
+	decision chan bool
+}
+
+// ApprovalManager pauses tool calls until a user approves or denies them, or
+// the timeout passes. It is shared by all agents of a loop.
+type ApprovalManager struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	notify  ApprovalNotifier
+	pending map[string]*pendingApproval
+}
+
+// NewApprovalManager creates a manager; timeout <= 0 uses one minute.
+func NewApprovalManager(timeout time.Duration, notify ApprovalNotifier) *ApprovalManager {
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+	return &ApprovalManager{
+		timeout: timeout,
+		notify:  notify,
+		pending: make(map[string]*pendingApproval),
+	}
+}
+
+// Await posts an approval prompt for req and blocks until it is resolved.
+// It returns nil only when the call was approved. The agent loop processes
+// one message at a time, so every chat waits while Await blocks; keep the
+// timeout short.
+func (m *ApprovalManager) Await(ctx context.Context, req ApprovalRequest) error {
+	if req.Channel == "" || req.ChatID == "" {
+		return fmt.Errorf("tool %s requires approval (%s) but there is no chat to ask", req.Tool, req.Reason)
+	}
+
+	req.ID = strings.SplitN(uuid.NewString(), "-", 2)[0]
+	req.Timeout = m.timeout
+	p := &pendingApproval{req: req, decision: make(chan bool, 1)}
+
+	m.mu.Lock()
+	m.pending[req.ID] = p
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.pending, req.ID)
+		m.mu.Unlock()
+	}()
+
+	if m.notify != nil {
+		if err := m.notify(ctx, req); err != nil {
+			return fmt.Errorf("failed to request approval for tool %s: %w", req.Tool, err)
+		}
+	}
+	logger.InfoCF("tool", "Waiting for tool approval",
+		map[string]any{
+			"tool":    req.Tool,
+			"id":      req.ID,
+			"channel": req.Channel,
+			"chat_id": req.ChatID,
+		})
+
+	timer := time.NewTimer(m.timeout)
+	defer timer.Stop()
+	select {
+	case approved := <-p.decision:
+		if !approved {
+			return fmt.Errorf("the user denied the %s tool call", req.Tool)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("the %s tool call was not approved within %s", req.Tool, m.timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Resolve approves or denies a pending request. The decision must come from
+// the same chat on the same channel account, and from the same sender when
+// the request recorded one.
+func (m *ApprovalManager) Resolve(
+	id, channel, accountID, chatID, senderID string,
+	approved bool,
+) (ApprovalRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.pending[strings.ToLower(strings.TrimSpace(id))]
+	if !ok || p.req.Channel != channel || p.req.AccountID != accountID || p.req.ChatID != chatID {
+		return ApprovalRequest{}, ErrApprovalNotFound
+	}
+	if p.req.SenderID != "" && senderID != p.req.SenderID {
+		return ApprovalRequest{}, ErrApprovalForbidden
+	}
+	select {
+	case p.decision <- approved:
+	default:
+		// Already resolved; the waiting call has not cleaned up yet.
+		return ApprovalRequest{}, ErrApprovalNotFound
+	}
+	return p.req, nil
+}
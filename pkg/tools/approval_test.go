@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestApprovalPolicy_Match(t *testing.T) {
+	workspace := t.TempDir()
+	policy, err := NewApprovalPolicy([]config.ApprovalRule{
+		{Tool: "exec", Arg: "command", Pattern: `\brm\b`},
+		{Tool: "write_file", Arg: "path", AllowPaths: []string{"."}},
+		{Tool: "mcp_*_delete*"},
+		{Tool: "exec", Pattern: "("},
+		{Tool: ""},
+	}, workspace)
+	if err == nil || !strings.Contains(err.Error(), "rule 3") || !strings.Contains(err.Error(), "rule 4") {
+		t.Fatalf("NewApprovalPolicy() error = %v, want errors for rules 3 and 4", err)
+	}
+
+	tests := []struct {
+		tool string
+		args map[string]any
+		want bool
+	}{
+		{"exec", map[string]any{"command": "rm -rf build"}, true},
+		{"exec", map[string]any{"command": "ls -la"}, false},
+		{"exec", map[string]any{"command": "format"}, false},
+		{"write_file", map[string]any{"path": "notes/todo.md"}, false},
+		{"write_file", map[string]any{"path": filepath.Join(workspace, "a.txt")}, false},
+		{"write_file", map[string]any{"path": "../outside.txt"}, true},
+		{"write_file", map[string]any{"path": "/etc/hosts"}, true},
+		{"mcp_github_delete_repo", map[string]any{"name": "x"}, true},
+		{"mcp_github_list_repos", nil, false},
+		{"read_file", map[string]any{"path": "/etc/hosts"}, false},
+	}
+	for _, tt := range tests {
+		reason, got := policy.Match(tt.tool, tt.args)
+		if got != tt.want {
+			t.Errorf("Match(%s, %v) = %v (%q), want %v", tt.tool, tt.args, got, reason, tt.want)
+		}
+	}
+
+	var nilPolicy *ApprovalPolicy
+	if _, ok := nilPolicy.Match("exec", nil); ok {
+		t.Error("nil policy should not match")
+	}
+}
+
+func TestApprovalManager_Resolve(t *testing.T) {
+	prompts := make(chan ApprovalRequest, 1)
+	m := NewApprovalManager(time.Second, func(_ context.Context, req ApprovalRequest) error {
+		prompts <- req
+		return nil
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- m.Await(context.Background(), ApprovalRequest{
+			Tool: "exec", Reason: "test", Channel: "telegram", AccountID: "work", ChatID: "c1", SenderID: "u1",
+		})
+	}()
+	req := <-prompts
+	if !strings.Contains(req.Prompt(), "/approve "+req.ID) {
+		t.Fatalf("prompt missing approve instruction: %q", req.Prompt())
+	}
+
+	if _, err := m.Resolve(req.ID, "telegram", "work", "other", "u1", true); !errors.Is(err, ErrApprovalNotFound) {
+		t.Errorf("Resolve from another chat error = %v", err)
+	}
+	if _, err := m.Resolve(req.ID, "telegram", "", "c1", "u1", true); !errors.Is(err, ErrApprovalNotFound) {
+		t.Errorf("Resolve from another account error = %v", err)
+	}
+	if _, err := m.Resolve(req.ID, "telegram", "work", "c1", "u2", true); !errors.Is(err, ErrApprovalForbidden) {
+		t.Errorf("Resolve by another sender error = %v", err)
+	}
+	if got, err := m.Resolve(strings.ToUpper(req.ID), "telegram", "work", "c1", "u1", true); err != nil || got.Tool != "exec" {
+		t.Fatalf("Resolve() = %+v, %v", got, err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Await() error = %v, want approval", err)
+	}
+	if _, err := m.Resolve(req.ID, "telegram", "work", "c1", "u1", true); !errors.Is(err, ErrApprovalNotFound) {
+		t.Errorf("Resolve after completion error = %v", err)
+	}
+}
+
+func TestApprovalManager_TimeoutAndNoChat(t *testing.T) {
+	m := NewApprovalManager(20*time.Millisecond, nil)
+
+	err := m.Await(context.Background(), ApprovalRequest{Tool: "exec", Channel: "telegram", ChatID: "c1"})
+	if err == nil || !strings.Contains(err.Error(), "not approved within") {
+		t.Fatalf("Await() error = %v, want timeout", err)
+	}
+	err = m.Await(context.Background(), ApprovalRequest{Tool: "exec"})
+	if err == nil || !strings.Contains(err.Error(), "no chat to ask") {
+		t.Fatalf("Await() error = %v, want missing chat error", err)
+	}
+}
+
+func TestToolRegistry_ApprovalDenied(t *testing.T) {
+	r := NewToolRegistry()
+	r.Register(&mockRegistryTool{name: "exec", desc: "exec", params: map[string]any{}, result: SilentResult("ran")})
+
+	policy, err := NewApprovalPolicy([]config.ApprovalRule{{Tool: "exec"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m *ApprovalManager
+	m = NewApprovalManager(time.Second, func(_ context.Context, req ApprovalRequest) error {
+		go m.Resolve(req.ID, req.Channel, req.AccountID, req.ChatID, req.SenderID, false)
+		return nil
+	})
+	r.SetApproval(policy, m)
+
+	result := r.ExecuteWithContext(context.Background(), "exec", nil, "telegram", "c1", "u1", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "denied") {
+		t.Fatalf("result = %+v, want denied error", result)
+	}
+}
@@ -24,6 +24,10 @@ var (
 	ctxKeyChannel = &toolCtxKey{"channel"}
 	ctxKeyChatID  = &toolCtxKey{"chatID"}
 	ctxKeySender  = &toolCtxKey{"senderID"}
+	ctxKeyAccount = &toolCtxKey{"accountID"}
+	ctxKeyThread  = &toolCtxKey{"threadID"}
+	ctxKeyMessage = &toolCtxKey{"messageID"}
+	ctxKeySession = &toolCtxKey{"sessionKey"}
 )
 
 // WithToolContext returns a child context carrying channel and chatID.
@@ -61,6 +65,52 @@ func ToolSenderID(ctx context.Context) string {
 	return v
 }
 
+// WithToolAccount returns a child context carrying the channel account the
+// conversation arrived on. It survives WithToolContext, so delegated calls
+// (subagents) keep replying through the same account.
+func WithToolAccount(ctx context.Context, accountID string) context.Context {
+	return context.WithValue(ctx, ctxKeyAccount, accountID)
+}
+
+// ToolAccountID extracts the channel account from ctx, or "" (the default
+// account) if unset.
+func ToolAccountID(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyAccount).(string)
+	return v
+}
+
+// WithToolThread returns a child context carrying the platform thread of the
+// conversation and the ID of the inbound message being answered, so replies
+// on the current chat can stay in the thread or quote that message.
+func WithToolThread(ctx context.Context, threadID, messageID string) context.Context {
+	ctx = context.WithValue(ctx, ctxKeyThread, threadID)
+	return context.WithValue(ctx, ctxKeyMessage, messageID)
+}
+
+// ToolThreadID extracts the platform thread from ctx, or "" if unset.
+func ToolThreadID(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyThread).(string)
+	return v
+}
+
+// WithToolSession returns a child context carrying the key of the session
+// the tool call belongs to.
+func WithToolSession(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, ctxKeySession, sessionKey)
+}
+
+// ToolSessionKey extracts the session key from ctx, or "" if unset.
+func ToolSessionKey(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeySession).(string)
+	return v
+}
+
+// ToolMessageID extracts the inbound message ID from ctx, or "" if unset.
+func ToolMessageID(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyMessage).(string)
+	return v
+}
+
 // AsyncCallback is a function type that async tools use to notify completion.
 // When an async tool finishes its work, it calls this callback with the result.
 //
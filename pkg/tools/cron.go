@@ -198,6 +198,7 @@ func (t *CronTool) addJob(ctx context.Context, args map[string]any) *ToolResult
 	if command != "" {
 		job.Payload.Command = command
 	}
+	job.Payload.AccountID = ToolAccountID(ctx)
 	if senderID != "" {
 		// 中文注释：记录创建任务的原始 sender，用于后续执行时恢复用户态身份。
 		job.Payload.SenderID = senderID
@@ -302,9 +303,10 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer pubCancel()
 		if err := t.msgBus.PublishOutbound(pubCtx, bus.OutboundMessage{
-			Channel: channel,
-			ChatID:  chatID,
-			Content: output,
+			Channel:   channel,
+			AccountID: job.Payload.AccountID,
+			ChatID:    chatID,
+			Content:   output,
 		}); err != nil {
 			return fmt.Sprintf("Error: publish cron command result failed: %v", err)
 		}
@@ -316,9 +318,10 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer pubCancel()
 		if err := t.msgBus.PublishOutbound(pubCtx, bus.OutboundMessage{
-			Channel: channel,
-			ChatID:  chatID,
-			Content: job.Payload.Message,
+			Channel:   channel,
+			AccountID: job.Payload.AccountID,
+			ChatID:    chatID,
+			Content:   job.Payload.Message,
 		}); err != nil {
 			return fmt.Sprintf("Error: publish cron direct delivery failed: %v", err)
 		}
@@ -337,6 +340,7 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 	defer pubCancel()
 	if err := t.msgBus.PublishInbound(pubCtx, bus.InboundMessage{
 		Channel:    channel,
+		AccountID:  job.Payload.AccountID,
 		SenderID:   effectiveSenderID,
 		ChatID:     chatID,
 		Content:    job.Payload.Message,
@@ -91,6 +91,16 @@ func sanitizeIdentifierComponent(s string) string {
 	return result
 }
 
+// Server returns the name of the MCP server providing the tool.
+func (t *MCPTool) Server() string {
+	return t.serverName
+}
+
+// RemoteName returns the tool name as advertised by the MCP server.
+func (t *MCPTool) RemoteName() string {
+	return t.tool.Name
+}
+
 // Name returns the tool name, prefixed with the server name.
 // The total length is capped at 64 characters (OpenAI-compatible API limit).
 // A short hash of the original (unsanitized) server and tool names is appended
@@ -2,11 +2,16 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync/atomic"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
 )
 
-type SendCallback func(channel, chatID, content string) error
+// SendCallback delivers msg to its channel. msg.AccountID selects the
+// channel account; it is empty for the default one.
+type SendCallback func(msg bus.OutboundMessage) error
 
 type MessageTool struct {
 	sendCallback SendCallback
@@ -41,6 +46,48 @@ func (t *MessageTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Optional: target chat/user ID",
 			},
+			"reply": map[string]any{
+				"type":        "boolean",
+				"description": "Optional: send as a reply quoting the user's current message",
+			},
+			"title": map[string]any{
+				"type":        "string",
+				"description": "Optional: card title shown above the message",
+			},
+			"buttons": map[string]any{
+				"type": "array",
+				"description": "Optional: buttons shown under the message. " +
+					"A click comes back as a user message containing the button's value (or label).",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"label": map[string]any{"type": "string"},
+						"value": map[string]any{"type": "string", "description": "Text sent back on click"},
+						"style": map[string]any{"type": "string", "enum": []string{"primary", "danger"}},
+						"url":   map[string]any{"type": "string", "description": "Open this link instead of replying"},
+					},
+					"required": []string{"label"},
+				},
+			},
+			"select": map[string]any{
+				"type":        "object",
+				"description": "Optional: single-choice menu; the chosen option's value comes back as a user message",
+				"properties": map[string]any{
+					"placeholder": map[string]any{"type": "string"},
+					"options": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"label": map[string]any{"type": "string"},
+								"value": map[string]any{"type": "string"},
+							},
+							"required": []string{"label"},
+						},
+					},
+				},
+				"required": []string{"options"},
+			},
 		},
 		"required": []string{"content"},
 	}
@@ -70,6 +117,12 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 	channel, _ := args["channel"].(string)
 	chatID, _ := args["chat_id"].(string)
 
+	// Replies on the current channel go out through the account the
+	// conversation came in on; other channels use their default account.
+	var accountID string
+	if channel == "" || channel == ToolChannel(ctx) {
+		accountID = ToolAccountID(ctx)
+	}
 	if channel == "" {
 		channel = ToolChannel(ctx)
 	}
@@ -85,7 +138,27 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 		return &ToolResult{ForLLM: "Message sending not configured", IsError: true}
 	}
 
-	if err := t.sendCallback(channel, chatID, content); err != nil {
+	interactive, err := parseInteractive(args)
+	if err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+
+	out := bus.OutboundMessage{
+		Channel:     channel,
+		AccountID:   accountID,
+		ChatID:      chatID,
+		Content:     content,
+		Interactive: interactive,
+	}
+	// Thread and reply only make sense in the conversation being answered.
+	if channel == ToolChannel(ctx) && chatID == ToolChatID(ctx) {
+		out.ThreadID = ToolThreadID(ctx)
+		if reply, _ := args["reply"].(bool); reply {
+			out.ReplyToMessageID = ToolMessageID(ctx)
+		}
+	}
+
+	if err := t.sendCallback(out); err != nil {
 		return &ToolResult{
 			ForLLM:  fmt.Sprintf("sending message: %v", err),
 			IsError: true,
@@ -100,3 +173,49 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 		Silent: true,
 	}
 }
+
+// parseInteractive builds the optional title/buttons/select arguments into a
+// bus.Interactive. It returns nil when none of them is set.
+func parseInteractive(args map[string]any) (*bus.Interactive, error) {
+	in := &bus.Interactive{}
+	if title, _ := args["title"].(string); title != "" {
+		in.Card = &bus.Card{Title: title}
+	}
+	if raw, ok := args["buttons"]; ok && raw != nil {
+		if err := remarshal(raw, &in.Buttons); err != nil {
+			return nil, fmt.Errorf("invalid buttons: %v", err)
+		}
+		for _, b := range in.Buttons {
+			if b.Label == "" {
+				return nil, fmt.Errorf("invalid buttons: every button needs a label")
+			}
+		}
+	}
+	if raw, ok := args["select"]; ok && raw != nil {
+		in.Select = &bus.Select{}
+		if err := remarshal(raw, in.Select); err != nil {
+			return nil, fmt.Errorf("invalid select: %v", err)
+		}
+		if len(in.Select.Options) == 0 {
+			return nil, fmt.Errorf("invalid select: options are required")
+		}
+		for _, o := range in.Select.Options {
+			if o.Label == "" {
+				return nil, fmt.Errorf("invalid select: every option needs a label")
+			}
+		}
+	}
+	if in.Card == nil && len(in.Buttons) == 0 && in.Select == nil {
+		return nil, nil
+	}
+	return in, nil
+}
+
+// remarshal converts loosely typed tool arguments into a typed value.
+func remarshal(src, dst any) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
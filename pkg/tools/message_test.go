@@ -4,16 +4,18 @@ import (
 	"context"
 	"errors"
 	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
 )
 
 func TestMessageTool_Execute_Success(t *testing.T) {
 	tool := NewMessageTool()
 
 	var sentChannel, sentChatID, sentContent string
-	tool.SetSendCallback(func(channel, chatID, content string) error {
-		sentChannel = channel
-		sentChatID = chatID
-		sentContent = content
+	tool.SetSendCallback(func(msg bus.OutboundMessage) error {
+		sentChannel = msg.Channel
+		sentChatID = msg.ChatID
+		sentContent = msg.Content
 		return nil
 	})
 
@@ -61,9 +63,9 @@ func TestMessageTool_Execute_WithCustomChannel(t *testing.T) {
 	tool := NewMessageTool()
 
 	var sentChannel, sentChatID string
-	tool.SetSendCallback(func(channel, chatID, content string) error {
-		sentChannel = channel
-		sentChatID = chatID
+	tool.SetSendCallback(func(msg bus.OutboundMessage) error {
+		sentChannel = msg.Channel
+		sentChatID = msg.ChatID
 		return nil
 	})
 
@@ -96,7 +98,7 @@ func TestMessageTool_Execute_SendFailure(t *testing.T) {
 	tool := NewMessageTool()
 
 	sendErr := errors.New("network error")
-	tool.SetSendCallback(func(channel, chatID, content string) error {
+	tool.SetSendCallback(func(msg bus.OutboundMessage) error {
 		return sendErr
 	})
 
@@ -128,6 +130,87 @@ func TestMessageTool_Execute_SendFailure(t *testing.T) {
 	}
 }
 
+func TestMessageTool_Execute_Interactive(t *testing.T) {
+	tool := NewMessageTool()
+
+	var sent bus.OutboundMessage
+	tool.SetSendCallback(func(msg bus.OutboundMessage) error {
+		sent = msg
+		return nil
+	})
+
+	ctx := WithToolContext(context.Background(), "test-channel", "test-chat-id")
+	result := tool.Execute(ctx, map[string]any{
+		"content": "Deploy now?",
+		"title":   "Deploy",
+		"buttons": []any{
+			map[string]any{"label": "Yes", "value": "deploy", "style": "primary"},
+			map[string]any{"label": "Docs", "url": "https://example.com"},
+		},
+		"select": map[string]any{
+			"placeholder": "Environment",
+			"options":     []any{map[string]any{"label": "staging"}},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+
+	in := sent.Interactive
+	if in == nil || in.Card == nil || in.Card.Title != "Deploy" {
+		t.Fatalf("expected card with title, got %+v", in)
+	}
+	if len(in.Buttons) != 2 || in.Buttons[0].CallbackValue() != "deploy" || in.Buttons[1].URL != "https://example.com" {
+		t.Errorf("unexpected buttons: %+v", in.Buttons)
+	}
+	if in.Select == nil || len(in.Select.Options) != 1 || in.Select.Options[0].CallbackValue() != "staging" {
+		t.Errorf("unexpected select: %+v", in.Select)
+	}
+
+	// Plain messages carry no interactive payload.
+	tool.Execute(ctx, map[string]any{"content": "hi"})
+	if sent.Interactive != nil {
+		t.Errorf("expected no interactive payload, got %+v", sent.Interactive)
+	}
+
+	result = tool.Execute(ctx, map[string]any{
+		"content": "x",
+		"buttons": []any{map[string]any{"value": "no label"}},
+	})
+	if !result.IsError {
+		t.Error("expected error for button without label")
+	}
+}
+
+func TestMessageTool_Execute_ThreadAndReply(t *testing.T) {
+	tool := NewMessageTool()
+
+	var sent bus.OutboundMessage
+	tool.SetSendCallback(func(msg bus.OutboundMessage) error {
+		sent = msg
+		return nil
+	})
+
+	ctx := WithToolContext(context.Background(), "slack", "C1/1700.1")
+	ctx = WithToolThread(ctx, "1700.1", "1700.5")
+
+	tool.Execute(ctx, map[string]any{"content": "hi"})
+	if sent.ThreadID != "1700.1" || sent.ReplyToMessageID != "" {
+		t.Errorf("plain reply: thread=%q reply_to=%q", sent.ThreadID, sent.ReplyToMessageID)
+	}
+
+	tool.Execute(ctx, map[string]any{"content": "hi", "reply": true})
+	if sent.ThreadID != "1700.1" || sent.ReplyToMessageID != "1700.5" {
+		t.Errorf("quoted reply: thread=%q reply_to=%q", sent.ThreadID, sent.ReplyToMessageID)
+	}
+
+	// Another chat is outside the current thread.
+	tool.Execute(ctx, map[string]any{"content": "hi", "chat_id": "C2", "reply": true})
+	if sent.ThreadID != "" || sent.ReplyToMessageID != "" {
+		t.Errorf("other chat: thread=%q reply_to=%q", sent.ThreadID, sent.ReplyToMessageID)
+	}
+}
+
 func TestMessageTool_Execute_MissingContent(t *testing.T) {
 	tool := NewMessageTool()
 
@@ -149,7 +232,7 @@ func TestMessageTool_Execute_NoTargetChannel(t *testing.T) {
 	tool := NewMessageTool()
 	// No WithToolContext — channel/chatID are empty
 
-	tool.SetSendCallback(func(channel, chatID, content string) error {
+	tool.SetSendCallback(func(msg bus.OutboundMessage) error {
 		return nil
 	})
 
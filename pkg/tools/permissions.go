@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const mcpPermissionPrefix = "mcp:"
+
+// ToolPermissions decides which tools an agent may register. Entries are
+// tool-name globs ("exec", "mcp_*"), or "mcp:<server>" and
+// "mcp:<server>:<tool>" to select MCP tools by server and original name;
+// both parts of the MCP form accept globs too.
+type ToolPermissions struct {
+	allow []string
+	deny  []string
+}
+
+// NewToolPermissions validates allow and deny entries. An empty allow list
+// permits every tool that is not denied.
+func NewToolPermissions(allow, deny []string) (*ToolPermissions, error) {
+	for _, entry := range append(append([]string{}, allow...), deny...) {
+		if err := validatePermissionEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+	return &ToolPermissions{allow: allow, deny: deny}, nil
+}
+
+// Allows reports whether tool may be registered.
+func (p *ToolPermissions) Allows(tool Tool) bool {
+	if p == nil {
+		return true
+	}
+	if matchesAnyPermission(p.deny, tool) {
+		return false
+	}
+	return len(p.allow) == 0 || matchesAnyPermission(p.allow, tool)
+}
+
+func validatePermissionEntry(entry string) error {
+	if strings.TrimSpace(entry) == "" {
+		return fmt.Errorf("empty tool permission entry")
+	}
+	patterns := []string{entry}
+	if rest, ok := strings.CutPrefix(entry, mcpPermissionPrefix); ok {
+		patterns = strings.SplitN(rest, ":", 2)
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool permission %q: %w", entry, err)
+		}
+	}
+	return nil
+}
+
+func matchesAnyPermission(entries []string, tool Tool) bool {
+	for _, entry := range entries {
+		if matchesPermission(entry, tool) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPermission(entry string, tool Tool) bool {
+	rest, ok := strings.CutPrefix(entry, mcpPermissionPrefix)
+	if !ok {
+		matched, _ := path.Match(entry, tool.Name())
+		return matched
+	}
+
+	mcpTool, ok := tool.(*MCPTool)
+	if !ok {
+		return false
+	}
+	server, name, hasName := strings.Cut(rest, ":")
+	if matched, _ := path.Match(server, mcpTool.Server()); !matched {
+		return false
+	}
+	if !hasName {
+		return true
+	}
+	matched, _ := path.Match(name, mcpTool.RemoteName())
+	return matched
+}
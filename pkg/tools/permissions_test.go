@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestToolPermissions_Allows(t *testing.T) {
+	exec := &mockRegistryTool{name: "exec"}
+	readFile := &mockRegistryTool{name: "read_file"}
+	ghList := NewMCPTool(nil, "github", &mcp.Tool{Name: "list_issues"})
+	ghDelete := NewMCPTool(nil, "github", &mcp.Tool{Name: "delete_repo"})
+	notion := NewMCPTool(nil, "notion", &mcp.Tool{Name: "search"})
+
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		want  map[Tool]bool
+	}{
+		{
+			name: "no rules allow everything",
+			want: map[Tool]bool{exec: true, readFile: true, ghDelete: true},
+		},
+		{
+			name: "deny by name",
+			deny: []string{"exec"},
+			want: map[Tool]bool{exec: false, readFile: true, notion: true},
+		},
+		{
+			name:  "allow list with MCP server",
+			allow: []string{"read_*", "mcp:github"},
+			want:  map[Tool]bool{exec: false, readFile: true, ghList: true, notion: false},
+		},
+		{
+			name:  "deny MCP tool by remote name wins over allow",
+			allow: []string{"mcp:*"},
+			deny:  []string{"mcp:github:delete_*"},
+			want:  map[Tool]bool{ghList: true, ghDelete: false, notion: true, exec: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perms, err := NewToolPermissions(tt.allow, tt.deny)
+			if err != nil {
+				t.Fatalf("NewToolPermissions() error: %v", err)
+			}
+			for tool, want := range tt.want {
+				if got := perms.Allows(tool); got != want {
+					t.Errorf("Allows(%s) = %v, want %v", tool.Name(), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestToolPermissions_InvalidEntries(t *testing.T) {
+	for _, entry := range []string{"", "exec[", "mcp:github:[x"} {
+		if _, err := NewToolPermissions(nil, []string{entry}); err == nil {
+			t.Errorf("NewToolPermissions(%q) expected error", entry)
+		}
+	}
+}
+
+func TestToolRegistry_SkipsToolsNotPermitted(t *testing.T) {
+	perms, err := NewToolPermissions(nil, []string{"exec", "mcp:github"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewToolRegistry()
+	r.SetPermissions(perms)
+	r.Register(&mockRegistryTool{name: "exec"})
+	r.Register(&mockRegistryTool{name: "read_file"})
+	r.RegisterHidden(NewMCPTool(nil, "github", &mcp.Tool{Name: "list_issues"}))
+
+	if got := r.List(); len(got) != 1 || got[0] != "read_file" {
+		t.Fatalf("List() = %v, want [read_file]", got)
+	}
+}
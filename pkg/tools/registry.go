@@ -19,9 +19,12 @@ type ToolEntry struct {
 }
 
 type ToolRegistry struct {
-	tools   map[string]*ToolEntry
-	mu      sync.RWMutex
-	version atomic.Uint64 // incremented on Register/RegisterHidden for cache invalidation
+	tools     map[string]*ToolEntry
+	mu        sync.RWMutex
+	version   atomic.Uint64 // incremented on Register/RegisterHidden for cache invalidation
+	approval  *ApprovalPolicy
+	approvals *ApprovalManager
+	perms     *ToolPermissions
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -30,10 +33,22 @@ func NewToolRegistry() *ToolRegistry {
 	}
 }
 
+// SetPermissions restricts which tools later Register and RegisterHidden
+// calls accept. Tools that are not permitted are skipped.
+func (r *ToolRegistry) SetPermissions(perms *ToolPermissions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.perms = perms
+}
+
 func (r *ToolRegistry) Register(tool Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	name := tool.Name()
+	if !r.perms.Allows(tool) {
+		logger.DebugCF("tools", "Skipped tool not permitted for this agent", map[string]any{"name": name})
+		return
+	}
 	if _, exists := r.tools[name]; exists {
 		logger.WarnCF("tools", "Tool registration overwrites existing tool",
 			map[string]any{"name": name})
@@ -52,6 +67,10 @@ func (r *ToolRegistry) RegisterHidden(tool Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	name := tool.Name()
+	if !r.perms.Allows(tool) {
+		logger.DebugCF("tools", "Skipped tool not permitted for this agent", map[string]any{"name": name})
+		return
+	}
 	if _, exists := r.tools[name]; exists {
 		logger.WarnCF("tools", "Hidden tool registration overwrites existing tool",
 			map[string]any{"name": name})
@@ -150,6 +169,15 @@ func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	return entry.Tool, true
 }
 
+// SetApproval makes calls matching policy wait for a decision from manager
+// before they execute.
+func (r *ToolRegistry) SetApproval(policy *ApprovalPolicy, manager *ApprovalManager) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approval = policy
+	r.approvals = manager
+}
+
 func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string]any) *ToolResult {
 	return r.ExecuteWithContext(ctx, name, args, "", "", "", nil)
 }
@@ -187,6 +215,10 @@ func (r *ToolRegistry) ExecuteWithContext(
 		ctx = WithToolSender(ctx, senderID)
 	}
 
+	if result := r.awaitApproval(ctx, name, args, channel, chatID, senderID); result != nil {
+		return result
+	}
+
 	// If tool implements AsyncExecutor and callback is provided, use ExecuteAsync.
 	// The callback is a call parameter, not mutable state on the tool instance.
 	var result *ToolResult
@@ -228,6 +260,47 @@ func (r *ToolRegistry) ExecuteWithContext(
 	return result
 }
 
+// awaitApproval blocks until a call that the approval policy matches is
+// approved. It returns an error result when the call must not run.
+func (r *ToolRegistry) awaitApproval(
+	ctx context.Context,
+	name string,
+	args map[string]any,
+	channel, chatID, senderID string,
+) *ToolResult {
+	r.mu.RLock()
+	policy, manager := r.approval, r.approvals
+	r.mu.RUnlock()
+	if manager == nil {
+		return nil
+	}
+	reason, ok := policy.Match(name, args)
+	if !ok {
+		return nil
+	}
+
+	err := manager.Await(ctx, ApprovalRequest{
+		Tool:      name,
+		Args:      args,
+		Reason:    reason,
+		Channel:   channel,
+		AccountID: ToolAccountID(ctx),
+		ChatID:    chatID,
+		SenderID:  senderID,
+	})
+	if err != nil {
+		logger.WarnCF("tool", "Tool call not approved",
+			map[string]any{
+				"tool":  name,
+				"error": err.Error(),
+			})
+		return ErrorResult(fmt.Sprintf("Tool call blocked: %v. Do not retry it unless the user asks.", err)).
+			WithError(err)
+	}
+	logger.InfoCF("tool", "Tool call approved", map[string]any{"tool": name})
+	return nil
+}
+
 // sortedToolNames returns tool names in sorted order for deterministic iteration.
 // This is critical for KV cache stability: non-deterministic map iteration would
 // produce different system prompts and tool definitions on each call, invalidating
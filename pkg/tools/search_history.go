@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/history"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// searchHistorySnippetLen caps each hit's content so a search cannot flood
+// the context window with long past replies.
+const searchHistorySnippetLen = 400
+
+// SearchHistoryTool lets the agent full-text search its own past
+// conversations.
+type SearchHistoryTool struct {
+	index   *history.Index
+	agentID string
+	scope   string
+}
+
+// NewSearchHistoryTool creates a SearchHistoryTool. Results are limited to
+// messages handled by agentID and, depending on scope (one of the
+// config.SearchHistoryScope* values), to the current session or sender.
+// An empty or unknown scope means the current session.
+func NewSearchHistoryTool(index *history.Index, agentID, scope string) *SearchHistoryTool {
+	switch scope {
+	case config.SearchHistoryScopeSender, config.SearchHistoryScopeAll:
+	default:
+		scope = config.SearchHistoryScopeSession
+	}
+	return &SearchHistoryTool{
+		index:   index,
+		agentID: agentID,
+		scope:   scope,
+	}
+}
+
+func (t *SearchHistoryTool) Name() string {
+	return "search_history"
+}
+
+func (t *SearchHistoryTool) Description() string {
+	var what string
+	switch t.scope {
+	case config.SearchHistoryScopeAll:
+		what = "Search past conversations across all sessions and channels."
+	case config.SearchHistoryScopeSender:
+		what = "Search past conversations with the current user on this channel."
+	default:
+		what = "Search earlier messages of the current conversation, including ones no longer in context."
+	}
+	return what + " Returns matching messages with time, channel, sender and role. Use this to recall what was discussed before when it is not in the current conversation."
+}
+
+func (t *SearchHistoryTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "Keywords to search for. Leave empty to list the most recent matching messages.",
+			},
+			"channel": map[string]any{
+				"type":        "string",
+				"description": "Only search messages from this channel (e.g. telegram, discord)",
+			},
+			"sender": map[string]any{
+				"type":        "string",
+				"description": "Only search conversations with this sender ID",
+			},
+			"since": map[string]any{
+				"type":        "string",
+				"description": "Only messages after this time: a date (2006-01-02), RFC 3339, days ago (7d) or a duration ago (24h)",
+			},
+			"until": map[string]any{
+				"type":        "string",
+				"description": "Only messages before this time, same formats as since; a date includes that whole day",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of results to return (1-20, default 5)",
+				"minimum":     1.0,
+				"maximum":     20.0,
+			},
+		},
+	}
+}
+
+func (t *SearchHistoryTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	query, _ := args["query"].(string)
+	channel, _ := args["channel"].(string)
+	sender, _ := args["sender"].(string)
+	since, _ := args["since"].(string)
+	until, _ := args["until"].(string)
+
+	limit := 5
+	if l, ok := args["limit"].(float64); ok {
+		li := int(l)
+		if li >= 1 && li <= 20 {
+			limit = li
+		}
+	}
+
+	sinceTime, untilTime, err := history.ParseRange(since, until, time.Now())
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	filter := history.Filter{
+		AgentID:  t.agentID,
+		Channel:  strings.TrimSpace(channel),
+		SenderID: strings.TrimSpace(sender),
+		Since:    sinceTime,
+		Until:    untilTime,
+	}
+	if err := t.restrict(ctx, &filter); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	results, err := t.index.Search(query, filter, limit)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("history search failed: %v", err))
+	}
+
+	return SilentResult(formatHistoryResults(query, results))
+}
+
+// restrict narrows f to the part of the history the tool's scope lets the
+// current conversation see. Scope bounds override the model's arguments.
+func (t *SearchHistoryTool) restrict(ctx context.Context, f *history.Filter) error {
+	switch t.scope {
+	case config.SearchHistoryScopeAll:
+		return nil
+	case config.SearchHistoryScopeSender:
+		channel, sender := ToolChannel(ctx), ToolSenderID(ctx)
+		if channel == "" || sender == "" {
+			return fmt.Errorf("history search is limited to the current sender, who is unknown here")
+		}
+		f.Channel, f.SenderID = channel, sender
+	default:
+		key := ToolSessionKey(ctx)
+		if key == "" {
+			return fmt.Errorf("history search is limited to the current session, which is unknown here")
+		}
+		f.SessionKey = key
+	}
+	return nil
+}
+
+func formatHistoryResults(query string, results []history.Result) string {
+	if len(results) == 0 {
+		if strings.TrimSpace(query) == "" {
+			return "No messages found."
+		}
+		return fmt.Sprintf("No past messages found for %q.", query)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d message(s):\n", len(results))
+	for i, r := range results {
+		who := r.Role
+		if r.Role == "user" && r.SenderID != "" {
+			who = "user " + r.SenderID
+		}
+		fmt.Fprintf(&sb, "\n%d. [%s] %s/%s %s:\n   %s\n",
+			i+1,
+			r.Time.Local().Format("2006-01-02 15:04"),
+			r.Channel, r.ChatID, who,
+			utils.Truncate(strings.ReplaceAll(r.Content, "\n", " "), searchHistorySnippetLen),
+		)
+	}
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/history"
+)
+
+func TestSearchHistoryTool_ScopedToAgent(t *testing.T) {
+	idx := history.NewIndex(t.TempDir())
+	now := time.Now()
+	if err := idx.Append(
+		history.Entry{Time: now, AgentID: "main", SessionKey: "s1", Channel: "telegram", ChatID: "1", SenderID: "42", Role: "user", Content: "remind me about the dentist"},
+		history.Entry{Time: now, AgentID: "other", SessionKey: "s2", Channel: "telegram", ChatID: "2", Role: "user", Content: "dentist secret from another agent"},
+	); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	tool := NewSearchHistoryTool(idx, "main", config.SearchHistoryScopeAll)
+	result := tool.Execute(context.Background(), map[string]any{"query": "dentist"})
+	if result.IsError {
+		t.Fatalf("Execute() error: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "remind me about the dentist") {
+		t.Errorf("expected own message in result, got %q", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "another agent") {
+		t.Errorf("result leaked another agent's history: %q", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "user 42") {
+		t.Errorf("expected sender in result, got %q", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"query": "dentist", "channel": "discord"})
+	if !strings.Contains(result.ForLLM, "No past messages found") {
+		t.Errorf("expected no results for discord, got %q", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"query": "dentist", "since": "someday"})
+	if !result.IsError {
+		t.Errorf("expected error for invalid since, got %q", result.ForLLM)
+	}
+}
+
+func TestSearchHistoryTool_Scopes(t *testing.T) {
+	idx := history.NewIndex(t.TempDir())
+	now := time.Now()
+	if err := idx.Append(
+		history.Entry{Time: now, AgentID: "main", SessionKey: "s1", Channel: "telegram", ChatID: "1", SenderID: "42", Role: "user", Content: "my dentist is on monday"},
+		history.Entry{Time: now, AgentID: "main", SessionKey: "s2", Channel: "telegram", ChatID: "group", SenderID: "42", Role: "user", Content: "dentist talk in the group"},
+		history.Entry{Time: now, AgentID: "main", SessionKey: "s3", Channel: "telegram", ChatID: "3", SenderID: "77", Role: "user", Content: "private dentist note from someone else"},
+	); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	ctx := WithToolSession(WithToolSender(WithToolContext(context.Background(), "telegram", "1"), "42"), "s1")
+	tests := []struct {
+		scope string
+		want  []string
+		leak  []string
+	}{
+		{"", []string{"monday"}, []string{"group", "someone else"}},
+		{config.SearchHistoryScopeSession, []string{"monday"}, []string{"group", "someone else"}},
+		{config.SearchHistoryScopeSender, []string{"monday", "group"}, []string{"someone else"}},
+		{config.SearchHistoryScopeAll, []string{"monday", "group", "someone else"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			tool := NewSearchHistoryTool(idx, "main", tt.scope)
+			result := tool.Execute(ctx, map[string]any{"query": "dentist"})
+			if result.IsError {
+				t.Fatalf("Execute() error: %s", result.ForLLM)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(result.ForLLM, w) {
+					t.Errorf("expected %q in result, got %q", w, result.ForLLM)
+				}
+			}
+			for _, l := range tt.leak {
+				if strings.Contains(result.ForLLM, l) {
+					t.Errorf("result leaked %q: %q", l, result.ForLLM)
+				}
+			}
+		})
+	}
+
+	// The model cannot widen the scope through its arguments.
+	tool := NewSearchHistoryTool(idx, "main", config.SearchHistoryScopeSender)
+	result := tool.Execute(ctx, map[string]any{"query": "dentist", "sender": "77"})
+	if strings.Contains(result.ForLLM, "someone else") {
+		t.Errorf("sender argument widened the scope: %q", result.ForLLM)
+	}
+
+	tool = NewSearchHistoryTool(idx, "main", config.SearchHistoryScopeSession)
+	if result := tool.Execute(context.Background(), map[string]any{"query": "dentist"}); !result.IsError {
+		t.Errorf("expected error without a current session, got %q", result.ForLLM)
+	}
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/semantic"
+)
+
+// RememberTool stores a fact in long-term memory (MEMORY.md) and indexes
+// it for semantic recall.
+type RememberTool struct {
+	index *semantic.Index
+}
+
+// NewRememberTool creates a RememberTool backed by index.
+func NewRememberTool(index *semantic.Index) *RememberTool {
+	return &RememberTool{index: index}
+}
+
+func (t *RememberTool) Name() string {
+	return "remember"
+}
+
+func (t *RememberTool) Description() string {
+	return "Save a fact to long-term memory so it can be recalled in future conversations. Store one self-contained fact per call, e.g. a user preference, a decision or an important detail."
+}
+
+func (t *RememberTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"text": map[string]any{
+				"type":        "string",
+				"description": "The fact to remember, written so it makes sense on its own",
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+func (t *RememberTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	text, _ := args["text"].(string)
+	if strings.TrimSpace(text) == "" {
+		return ErrorResult("text is required")
+	}
+
+	added, err := t.index.Remember(ctx, text)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to remember: %v", err))
+	}
+	if !added {
+		return SilentResult("Already in memory.")
+	}
+	return SilentResult("Saved to memory.")
+}
+
+// RecallTool searches long-term memory by meaning rather than keywords.
+type RecallTool struct {
+	index    *semantic.Index
+	minScore float64
+}
+
+// NewRecallTool creates a RecallTool. Memories with a similarity below
+// minScore are not returned.
+func NewRecallTool(index *semantic.Index, minScore float64) *RecallTool {
+	return &RecallTool{
+		index:    index,
+		minScore: minScore,
+	}
+}
+
+func (t *RecallTool) Name() string {
+	return "recall"
+}
+
+func (t *RecallTool) Description() string {
+	return "Search long-term memory (MEMORY.md and daily notes) for facts related to a query. Matches by meaning, so describe what you are looking for in plain words."
+}
+
+func (t *RecallTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "What to look for in memory",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of memories to return (1-20, default 5)",
+				"minimum":     1.0,
+				"maximum":     20.0,
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *RecallTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return ErrorResult("query is required")
+	}
+
+	limit := 5
+	if l, ok := args["limit"].(float64); ok {
+		li := int(l)
+		if li >= 1 && li <= 20 {
+			limit = li
+		}
+	}
+
+	hits, err := t.index.Recall(ctx, query, limit, t.minScore)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("memory recall failed: %v", err))
+	}
+	if len(hits) == 0 {
+		return SilentResult(fmt.Sprintf("No memories found for %q.", query))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d memory(ies):\n", len(hits))
+	for i, h := range hits {
+		fmt.Fprintf(&sb, "\n%d. (%s, score %.2f) %s", i+1, h.Source, h.Score, h.Text)
+	}
+	return SilentResult(sb.String())
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/semantic"
+)
+
+// wordEmbedder embeds texts as hits against a small vocabulary.
+type wordEmbedder struct{}
+
+func (wordEmbedder) Model() string {
+	return "words"
+}
+
+func (wordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vocab := []string{"tea", "dog", "berlin"}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := []float32{0.01, 0, 0, 0}
+		for j, w := range vocab {
+			if strings.Contains(strings.ToLower(text), w) {
+				v[j+1] = 1
+			}
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func TestRememberAndRecallTools(t *testing.T) {
+	workspace := t.TempDir()
+	index := semantic.NewIndex(workspace, wordEmbedder{})
+	remember := NewRememberTool(index)
+	recall := NewRecallTool(index, 0.5)
+	ctx := context.Background()
+
+	for _, fact := range []string{"User prefers green tea", "User lives in Berlin"} {
+		result := remember.Execute(ctx, map[string]any{"text": fact})
+		if result.IsError || !result.Silent {
+			t.Fatalf("remember(%q) = %+v", fact, result)
+		}
+	}
+	if result := remember.Execute(ctx, map[string]any{"text": "User lives in Berlin"}); result.ForLLM != "Already in memory." {
+		t.Errorf("duplicate remember = %q", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "memory", "MEMORY.md")); err != nil {
+		t.Errorf("MEMORY.md not written: %v", err)
+	}
+
+	result := recall.Execute(ctx, map[string]any{"query": "does the user live near Berlin?", "limit": 3.0})
+	if result.IsError {
+		t.Fatalf("recall error: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "User lives in Berlin") || strings.Contains(result.ForLLM, "tea") {
+		t.Errorf("recall = %q", result.ForLLM)
+	}
+
+	result = recall.Execute(ctx, map[string]any{"query": "dog"})
+	if result.ForLLM != `No memories found for "dog".` {
+		t.Errorf("recall without match = %q", result.ForLLM)
+	}
+
+	if result := recall.Execute(ctx, map[string]any{}); !result.IsError {
+		t.Error("expected error for missing query")
+	}
+	if result := remember.Execute(ctx, map[string]any{"text": " "}); !result.IsError {
+		t.Error("expected error for empty text")
+	}
+}
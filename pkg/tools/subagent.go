@@ -265,6 +265,10 @@ func (t *SubagentTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Optional short label for the task (for display)",
 			},
+			"output_schema": map[string]any{
+				"type":        "object",
+				"description": "Optional JSON Schema the result must match. When given, the subagent returns validated JSON instead of prose",
+			},
 		},
 		"required": []string{"task"},
 	}
@@ -282,6 +286,18 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult("Subagent manager not configured").WithError(fmt.Errorf("manager is nil"))
 	}
 
+	var structuredOutput *providers.ResponseFormat
+	if schema, ok := args["output_schema"].(map[string]any); ok && len(schema) > 0 {
+		if err := providers.ValidateResponseSchema(schema); err != nil {
+			return ErrorResult(err.Error()).WithError(err)
+		}
+		structuredOutput = &providers.ResponseFormat{
+			Type:   providers.ResponseFormatJSONSchema,
+			Name:   "subagent_result",
+			Schema: schema,
+		}
+	}
+
 	// Build messages for subagent
 	messages := []providers.Message{
 		{
@@ -328,11 +344,12 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 	}
 
 	loopResult, err := RunToolLoop(ctx, ToolLoopConfig{
-		Provider:      sm.provider,
-		Model:         sm.defaultModel,
-		Tools:         tools,
-		MaxIterations: maxIter,
-		LLMOptions:    llmOptions,
+		Provider:         sm.provider,
+		Model:            sm.defaultModel,
+		Tools:            tools,
+		MaxIterations:    maxIter,
+		LLMOptions:       llmOptions,
+		StructuredOutput: structuredOutput,
 	}, messages, channel, chatID, ToolSenderID(ctx))
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("Subagent execution failed: %v", err)).WithError(err)
@@ -145,6 +145,41 @@ func TestSubagentTool_Parameters(t *testing.T) {
 	}
 }
 
+// TestSubagentTool_Execute_OutputSchema verifies that output_schema makes the
+// subagent return validated JSON
+func TestSubagentTool_Execute_OutputSchema(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{`{"count": 3}`}}
+	manager := NewSubagentManager(provider, "test-model", "/tmp/test")
+	tool := NewSubagentTool(manager)
+
+	ctx := WithToolContext(context.Background(), "cli", "direct")
+	result := tool.Execute(ctx, map[string]any{
+		"task": "Count the files",
+		"output_schema": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"count": map[string]any{"type": "integer"}},
+			"required":   []any{"count"},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, `Result: {"count":3}`) {
+		t.Errorf("ForLLM should contain the JSON result, got: %s", result.ForLLM)
+	}
+	if provider.options[0]["response_format"] == nil {
+		t.Error("Expected response_format to be passed to the provider")
+	}
+
+	result = tool.Execute(ctx, map[string]any{
+		"task":          "Count the files",
+		"output_schema": map[string]any{"type": 42},
+	})
+	if !result.IsError {
+		t.Error("Expected an error for an invalid output_schema")
+	}
+}
+
 // TestSubagentTool_Execute_Success tests successful execution
 func TestSubagentTool_Execute_Success(t *testing.T) {
 	provider := &MockLLMProvider{}
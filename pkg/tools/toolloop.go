@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"sync"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
@@ -24,14 +25,25 @@ type ToolLoopConfig struct {
 	Tools         *ToolRegistry
 	MaxIterations int
 	LLMOptions    map[string]any
+
+	// StructuredOutput, when set, requires the final answer to be JSON in
+	// this format. Answers that fail validation are sent back to the model
+	// with the validation error, up to StructuredRetries times (default 2).
+	StructuredOutput  *providers.ResponseFormat
+	StructuredRetries int
 }
 
 // ToolLoopResult contains the result of running the tool loop.
 type ToolLoopResult struct {
 	Content    string
 	Iterations int
+	// Structured is the validated final answer when StructuredOutput was
+	// requested; Content then holds the same JSON.
+	Structured json.RawMessage
 }
 
+const defaultStructuredRetries = 2
+
 // RunToolLoop executes the LLM + tool call iteration loop.
 // This is the core agent logic that can be reused by both main agent and subagents.
 func RunToolLoop(
@@ -42,8 +54,26 @@ func RunToolLoop(
 ) (*ToolLoopResult, error) {
 	iteration := 0
 	var finalContent string
+	var structured json.RawMessage
+
+	llmOpts := config.LLMOptions
+	if llmOpts == nil {
+		llmOpts = map[string]any{}
+	}
 
-	for iteration < config.MaxIterations {
+	repairs, maxRepairs := 0, 0
+	if config.StructuredOutput != nil {
+		maxRepairs = config.StructuredRetries
+		if maxRepairs <= 0 {
+			maxRepairs = defaultStructuredRetries
+		}
+		llmOpts = maps.Clone(llmOpts)
+		llmOpts["response_format"] = config.StructuredOutput
+		messages = withStructuredInstruction(messages, config.StructuredOutput)
+	}
+
+	// Repair attempts do not use up the tool-call budget.
+	for iteration < config.MaxIterations+repairs {
 		iteration++
 
 		logger.DebugCF("toolloop", "LLM iteration",
@@ -58,12 +88,7 @@ func RunToolLoop(
 			providerToolDefs = config.Tools.ToProviderDefs()
 		}
 
-		// 2. Set default LLM options
-		llmOpts := config.LLMOptions
-		if llmOpts == nil {
-			llmOpts = map[string]any{}
-		}
-		// 3. Call LLM
+		// 2. Call LLM
 		response, err := config.Provider.Chat(ctx, messages, providerToolDefs, config.Model, llmOpts)
 		if err != nil {
 			logger.ErrorCF("toolloop", "LLM call failed",
@@ -74,8 +99,31 @@ func RunToolLoop(
 			return nil, fmt.Errorf("LLM call failed: %w", err)
 		}
 
-		// 4. If no tool calls, we're done
+		// 3. If no tool calls, we're done, unless the answer has to be
+		// valid structured output and is not.
 		if len(response.ToolCalls) == 0 {
+			if config.StructuredOutput != nil {
+				value, err := providers.ParseStructuredOutput(response.Content, config.StructuredOutput)
+				if err != nil {
+					if repairs >= maxRepairs {
+						return nil, fmt.Errorf("structured output still invalid after %d repair attempts: %w", repairs, err)
+					}
+					repairs++
+					logger.WarnCF("toolloop", "Structured output failed validation, asking for a repair",
+						map[string]any{
+							"iteration": iteration,
+							"attempt":   repairs,
+							"error":     err.Error(),
+						})
+					messages = append(messages,
+						providers.Message{Role: "assistant", Content: response.Content},
+						providers.Message{Role: "user", Content: structuredRepairPrompt(err)},
+					)
+					continue
+				}
+				structured = value
+				response.Content = string(value)
+			}
 			finalContent = response.Content
 			logger.InfoCF("toolloop", "LLM response without tool calls (direct answer)",
 				map[string]any{
@@ -90,7 +138,7 @@ func RunToolLoop(
 			normalizedToolCalls = append(normalizedToolCalls, providers.NormalizeToolCall(tc))
 		}
 
-		// 5. Log tool calls
+		// 4. Log tool calls
 		toolNames := make([]string, 0, len(normalizedToolCalls))
 		for _, tc := range normalizedToolCalls {
 			toolNames = append(toolNames, tc.Name)
@@ -102,7 +150,7 @@ func RunToolLoop(
 				"iteration": iteration,
 			})
 
-		// 6. Build assistant message with tool calls
+		// 5. Build assistant message with tool calls
 		assistantMsg := providers.Message{
 			Role:    "assistant",
 			Content: response.Content,
@@ -122,7 +170,7 @@ func RunToolLoop(
 		}
 		messages = append(messages, assistantMsg)
 
-		// 7. Execute tool calls in parallel
+		// 6. Execute tool calls in parallel
 		type indexedResult struct {
 			result *ToolResult
 			tc     providers.ToolCall
@@ -183,5 +231,41 @@ func RunToolLoop(
 	return &ToolLoopResult{
 		Content:    finalContent,
 		Iterations: iteration,
+		Structured: structured,
 	}, nil
 }
+
+// withStructuredInstruction tells the model about the expected answer format
+// in the system prompt. Providers enforce the format natively where they can,
+// but not all of them can while tools are offered. The caller's messages are
+// not modified.
+func withStructuredInstruction(messages []providers.Message, format *providers.ResponseFormat) []providers.Message {
+	instruction := "When you have finished, reply with only a JSON object and no other text."
+	if format.Type == providers.ResponseFormatJSONSchema && len(format.Schema) > 0 {
+		schema, _ := json.Marshal(format.Schema)
+		instruction = "When you have finished, reply with only a JSON value matching this JSON Schema " +
+			"and no other text:\n" + string(schema)
+	}
+	if format.Description != "" {
+		instruction = format.Description + "\n" + instruction
+	}
+
+	if len(messages) == 0 || messages[0].Role != "system" {
+		return append([]providers.Message{{Role: "system", Content: instruction}}, messages...)
+	}
+
+	out := append([]providers.Message(nil), messages...)
+	system := out[0]
+	system.Content += "\n\n" + instruction
+	if len(system.SystemParts) > 0 {
+		system.SystemParts = append(append([]providers.ContentBlock(nil), system.SystemParts...),
+			providers.ContentBlock{Type: "text", Text: instruction})
+	}
+	out[0] = system
+	return out
+}
+
+func structuredRepairPrompt(err error) string {
+	return fmt.Sprintf("Your answer could not be used: %v.\n"+
+		"Reply again with only the corrected JSON and no other text.", err)
+}
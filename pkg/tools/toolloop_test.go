@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// scriptedProvider answers each Chat call with the next reply in order and
+// records the requests it received.
+type scriptedProvider struct {
+	replies  []string
+	messages [][]providers.Message
+	options  []map[string]any
+}
+
+func (p *scriptedProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	options map[string]any,
+) (*providers.LLMResponse, error) {
+	p.messages = append(p.messages, messages)
+	p.options = append(p.options, options)
+	reply := p.replies[0]
+	if len(p.replies) > 1 {
+		p.replies = p.replies[1:]
+	}
+	return &providers.LLMResponse{Content: reply, FinishReason: "stop"}, nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string { return "test-model" }
+
+var cityFormat = &providers.ResponseFormat{
+	Type: providers.ResponseFormatJSONSchema,
+	Schema: map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]any{"type": "string"}},
+		"required":   []any{"city"},
+	},
+}
+
+func TestRunToolLoop_StructuredOutputRepair(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		"The city is Paris.",
+		"```json\n{\"city\": \"Paris\"}\n```",
+	}}
+	messages := []providers.Message{
+		{Role: "system", Content: "You are a subagent."},
+		{Role: "user", Content: "Which city?"},
+	}
+
+	result, err := RunToolLoop(context.Background(), ToolLoopConfig{
+		Provider:         provider,
+		Model:            "test-model",
+		MaxIterations:    1,
+		LLMOptions:       map[string]any{"max_tokens": 256},
+		StructuredOutput: cityFormat,
+	}, messages, "cli", "direct", "")
+	if err != nil {
+		t.Fatalf("RunToolLoop() error = %v", err)
+	}
+
+	if string(result.Structured) != `{"city":"Paris"}` || result.Content != `{"city":"Paris"}` {
+		t.Errorf("result = %+v", result)
+	}
+	if result.Iterations != 2 {
+		t.Errorf("Iterations = %d, want 2 (the repair must not count against MaxIterations)", result.Iterations)
+	}
+
+	first := provider.messages[0]
+	if !strings.Contains(first[0].Content, `"required":["city"]`) {
+		t.Errorf("system prompt does not describe the schema: %q", first[0].Content)
+	}
+	if messages[0].Content != "You are a subagent." {
+		t.Error("the caller's messages must not be modified")
+	}
+	if provider.options[0]["response_format"] != cityFormat || provider.options[0]["max_tokens"] != 256 {
+		t.Errorf("options = %v", provider.options[0])
+	}
+
+	repair := provider.messages[1]
+	last := repair[len(repair)-1]
+	if last.Role != "user" || !strings.Contains(last.Content, "does not contain a JSON value") {
+		t.Errorf("repair prompt = %+v", last)
+	}
+	if prev := repair[len(repair)-2]; prev.Role != "assistant" || prev.Content != "The city is Paris." {
+		t.Errorf("the invalid answer should precede the repair prompt, got %+v", prev)
+	}
+}
+
+func TestRunToolLoop_StructuredOutputGivesUp(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{`{"town": "Paris"}`}}
+
+	_, err := RunToolLoop(context.Background(), ToolLoopConfig{
+		Provider:          provider,
+		Model:             "test-model",
+		MaxIterations:     5,
+		StructuredOutput:  cityFormat,
+		StructuredRetries: 1,
+	}, []providers.Message{{Role: "user", Content: "Which city?"}}, "cli", "direct", "")
+	if err == nil || !strings.Contains(err.Error(), "does not match the schema") {
+		t.Fatalf("RunToolLoop() error = %v, want a schema error", err)
+	}
+	if len(provider.messages) != 2 {
+		t.Errorf("provider called %d times, want 2 (one repair)", len(provider.messages))
+	}
+	if provider.messages[0][0].Role != "system" {
+		t.Errorf("expected a system message with the format instruction, got %+v", provider.messages[0][0])
+	}
+}
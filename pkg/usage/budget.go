@@ -0,0 +1,277 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// BudgetState is the outcome of checking usage against a set of budgets.
+type BudgetState int
+
+const (
+	// BudgetOK means no limit has been reached.
+	BudgetOK BudgetState = iota
+	// BudgetSoft means a soft limit has been reached; the agent should
+	// downgrade to its light model.
+	BudgetSoft
+	// BudgetHard means a hard limit has been reached; the agent must not
+	// call the LLM.
+	BudgetHard
+)
+
+func (s BudgetState) String() string {
+	switch s {
+	case BudgetSoft:
+		return "soft"
+	case BudgetHard:
+		return "hard"
+	default:
+		return "ok"
+	}
+}
+
+// BudgetSubject identifies the request a budget check is made for.
+type BudgetSubject struct {
+	AgentID  string
+	SenderID string
+	Channel  string
+}
+
+// BudgetResult reports the most severe budget reached and the usage that
+// was counted against it.
+type BudgetResult struct {
+	State  BudgetState
+	Budget config.BudgetConfig
+	Used   Totals
+}
+
+// Message returns the reply shown to the user when a hard limit is reached.
+func (r BudgetResult) Message() string {
+	if r.Budget.Message != "" {
+		return r.Budget.Message
+	}
+	return fmt.Sprintf("The %s %s usage budget has been reached. Please try again later.",
+		r.Budget.Period, r.Budget.Scope)
+}
+
+// PeriodStart returns the start of the budget period that contains now, in
+// now's location. It reports false for an unknown period.
+func PeriodStart(period string, now time.Time) (time.Time, bool) {
+	y, m, d := now.Date()
+	switch period {
+	case config.BudgetPeriodDaily:
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), true
+	case config.BudgetPeriodMonthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, now.Location()), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// budgetKey returns the index key a budget counts for subject. It reports
+// false when the budget is malformed or does not apply, e.g. a sender budget
+// for a request without a sender.
+func budgetKey(b config.BudgetConfig, subject BudgetSubject) (budgetIndexKey, bool) {
+	k := budgetIndexKey{agent: subject.AgentID}
+	switch b.Scope {
+	case config.BudgetScopeAgent:
+	case config.BudgetScopeSender:
+		if subject.SenderID == "" {
+			return budgetIndexKey{}, false
+		}
+		k.sender = subject.SenderID
+	case config.BudgetScopeChannel:
+		if subject.Channel == "" {
+			return budgetIndexKey{}, false
+		}
+		k.channel = subject.Channel
+	default:
+		return budgetIndexKey{}, false
+	}
+	return k, true
+}
+
+// budgetState compares used against the limits of b.
+func budgetState(b config.BudgetConfig, used Totals) BudgetState {
+	if (b.HardTokens > 0 && used.TotalTokens >= b.HardTokens) ||
+		(b.HardCost > 0 && used.Cost >= b.HardCost) {
+		return BudgetHard
+	}
+	if (b.SoftTokens > 0 && used.TotalTokens >= b.SoftTokens) ||
+		(b.SoftCost > 0 && used.Cost >= b.SoftCost) {
+		return BudgetSoft
+	}
+	return BudgetOK
+}
+
+// CheckBudgets evaluates budgets for subject against the usage recorded up
+// to now and returns the most severe result. Budgets with an unknown scope
+// or period are ignored with a warning. Cost limits only count costs in the
+// budget's currency.
+//
+// The ledger is read once, on the first call; after that budgets are checked
+// against running per-day totals that Append keeps up to date.
+func (l *Ledger) CheckBudgets(
+	budgets []config.BudgetConfig,
+	subject BudgetSubject,
+	now time.Time,
+) (BudgetResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var result BudgetResult
+	for _, b := range budgets {
+		start, ok := PeriodStart(b.Period, now)
+		key, applies := budgetKey(b, subject)
+		if !ok || !applies {
+			if !ok || !knownBudgetScope(b.Scope) {
+				logger.WarnCF("usage", "Ignoring budget with unknown scope or period", map[string]any{
+					"agent_id": subject.AgentID,
+					"scope":    b.Scope,
+					"period":   b.Period,
+				})
+			}
+			continue
+		}
+		if err := l.loadBudgetIndex(now); err != nil {
+			return BudgetResult{}, err
+		}
+
+		used := l.index.sum(key, start, now, budgetCurrency(b))
+		if state := budgetState(b, used); state > result.State {
+			result = BudgetResult{State: state, Budget: b, Used: used}
+		}
+	}
+	return result, nil
+}
+
+// budgetCurrency returns the currency a budget's cost limits are set in.
+func budgetCurrency(b config.BudgetConfig) string {
+	if b.Currency != "" {
+		return b.Currency
+	}
+	return DefaultCurrency
+}
+
+func knownBudgetScope(scope string) bool {
+	switch scope {
+	case config.BudgetScopeAgent, config.BudgetScopeSender, config.BudgetScopeChannel:
+		return true
+	default:
+		return false
+	}
+}
+
+// budgetIndexKey identifies whose usage a bucket counts. Agent budgets use
+// the key with empty sender and channel.
+type budgetIndexKey struct {
+	agent, sender, channel string
+}
+
+type budgetBucketKey struct {
+	day string // 2006-01-02 in the index's location
+	budgetIndexKey
+	currency string
+}
+
+// budgetIndex holds per-day usage totals for the current month, which is
+// the longest budget period.
+type budgetIndex struct {
+	loc     *time.Location
+	since   time.Time // start of the oldest month kept
+	buckets map[budgetBucketKey]Totals
+}
+
+// loadBudgetIndex reads the ledger into l.index on first use and drops days
+// of months that ended before now. The caller must hold l.mu.
+func (l *Ledger) loadBudgetIndex(now time.Time) error {
+	monthStart, _ := PeriodStart(config.BudgetPeriodMonthly, now)
+	if l.index != nil {
+		if monthStart.After(l.index.since) {
+			cutoff := monthStart.Format(time.DateOnly)
+			for k := range l.index.buckets {
+				if k.day < cutoff {
+					delete(l.index.buckets, k)
+				}
+			}
+			l.index.since = monthStart
+		}
+		return nil
+	}
+
+	idx := &budgetIndex{loc: now.Location(), since: monthStart, buckets: make(map[budgetBucketKey]Totals)}
+	file, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			l.index = idx
+			return nil
+		}
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // reported by Records
+		}
+		idx.add(r)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	l.index = idx
+	return nil
+}
+
+// add counts r towards the agent, sender and channel totals of its day.
+func (idx *budgetIndex) add(r Record) {
+	if r.Time.Before(idx.since) {
+		return
+	}
+	day := r.Time.In(idx.loc).Format(time.DateOnly)
+	keys := []budgetIndexKey{{agent: r.AgentID}}
+	if r.SenderID != "" {
+		keys = append(keys, budgetIndexKey{agent: r.AgentID, sender: r.SenderID})
+	}
+	if r.Channel != "" {
+		keys = append(keys, budgetIndexKey{agent: r.AgentID, channel: r.Channel})
+	}
+	for _, k := range keys {
+		bk := budgetBucketKey{day: day, budgetIndexKey: k, currency: r.Currency}
+		t := idx.buckets[bk]
+		t.Add(r)
+		idx.buckets[bk] = t
+	}
+}
+
+// sum adds up the buckets of key from start to now. Tokens are counted in
+// every currency, cost only in currency.
+func (idx *budgetIndex) sum(key budgetIndexKey, start, now time.Time, currency string) Totals {
+	from := start.In(idx.loc).Format(time.DateOnly)
+	to := now.In(idx.loc).Format(time.DateOnly)
+	used := Totals{Currency: currency}
+	for k, t := range idx.buckets {
+		if k.budgetIndexKey != key || k.day < from || k.day > to {
+			continue
+		}
+		used.Requests += t.Requests
+		used.PromptTokens += t.PromptTokens
+		used.CompletionTokens += t.CompletionTokens
+		used.TotalTokens += t.TotalTokens
+		if k.currency == currency {
+			used.Cost += t.Cost
+		}
+	}
+	return used
+}
@@ -0,0 +1,193 @@
+package usage
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestPeriodStart(t *testing.T) {
+	now := time.Date(2026, 3, 17, 15, 4, 5, 0, time.UTC)
+
+	if got, ok := PeriodStart(config.BudgetPeriodDaily, now); !ok || !got.Equal(time.Date(2026, 3, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("daily start = %v, %v", got, ok)
+	}
+	if got, ok := PeriodStart(config.BudgetPeriodMonthly, now); !ok || !got.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("monthly start = %v, %v", got, ok)
+	}
+	if _, ok := PeriodStart("weekly", now); ok {
+		t.Error("expected unknown period to be rejected")
+	}
+}
+
+func TestLedger_CheckBudgets(t *testing.T) {
+	l := NewLedger(t.TempDir(), NewPriceTable([]config.ModelConfig{
+		{ModelName: "gpt", Model: "openai/gpt", Pricing: &config.ModelPricing{Input: 1000, Output: 1000}},
+	}))
+	now := time.Date(2026, 3, 17, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		// Last month: outside every period below.
+		{Time: now.AddDate(0, -1, 0), AgentID: "main", SenderID: "alice", Channel: "telegram", Model: "gpt", PromptTokens: 9000},
+		// Earlier this month.
+		{Time: now.AddDate(0, 0, -3), AgentID: "main", SenderID: "alice", Channel: "telegram", Model: "gpt", PromptTokens: 400},
+		// Today.
+		{Time: now.Add(-time.Hour), AgentID: "main", SenderID: "alice", Channel: "telegram", Model: "gpt", PromptTokens: 100},
+		{Time: now.Add(-time.Hour), AgentID: "main", SenderID: "bob", Channel: "discord", Model: "gpt", PromptTokens: 50},
+		{Time: now.Add(-time.Hour), AgentID: "other", SenderID: "alice", Channel: "telegram", Model: "gpt", PromptTokens: 5000},
+	}
+	for _, r := range records {
+		if err := l.Append(r); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	alice := BudgetSubject{AgentID: "main", SenderID: "alice", Channel: "telegram"}
+	bob := BudgetSubject{AgentID: "main", SenderID: "bob", Channel: "discord"}
+
+	tests := []struct {
+		name    string
+		budgets []config.BudgetConfig
+		subject BudgetSubject
+		want    BudgetState
+		used    int
+	}{
+		{
+			name:    "daily sender under limit",
+			budgets: []config.BudgetConfig{{Scope: "sender", Period: "daily", SoftTokens: 200}},
+			subject: alice,
+			want:    BudgetOK,
+		},
+		{
+			name:    "monthly sender soft",
+			budgets: []config.BudgetConfig{{Scope: "sender", Period: "monthly", SoftTokens: 500, HardTokens: 1000}},
+			subject: alice,
+			want:    BudgetSoft,
+			used:    500,
+		},
+		{
+			name:    "daily agent hard by cost",
+			budgets: []config.BudgetConfig{{Scope: "agent", Period: "daily", HardCost: 0.14}},
+			subject: bob,
+			want:    BudgetHard,
+			used:    150,
+		},
+		{
+			name:    "channel budget counts only that channel",
+			budgets: []config.BudgetConfig{{Scope: "channel", Period: "daily", HardTokens: 60}},
+			subject: bob,
+			want:    BudgetOK,
+		},
+		{
+			name: "most severe budget wins",
+			budgets: []config.BudgetConfig{
+				{Scope: "agent", Period: "monthly", SoftTokens: 100},
+				{Scope: "sender", Period: "monthly", HardTokens: 500},
+			},
+			subject: alice,
+			want:    BudgetHard,
+			used:    500,
+		},
+		{
+			name:    "unknown scope ignored",
+			budgets: []config.BudgetConfig{{Scope: "group", Period: "daily", HardTokens: 1}},
+			subject: alice,
+			want:    BudgetOK,
+		},
+		{
+			name:    "sender budget without sender",
+			budgets: []config.BudgetConfig{{Scope: "sender", Period: "daily", HardTokens: 1}},
+			subject: BudgetSubject{AgentID: "main"},
+			want:    BudgetOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.CheckBudgets(tt.budgets, tt.subject, now)
+			if err != nil {
+				t.Fatalf("CheckBudgets() error: %v", err)
+			}
+			if got.State != tt.want {
+				t.Fatalf("State = %v, want %v (used %+v)", got.State, tt.want, got.Used)
+			}
+			if tt.used != 0 && got.Used.TotalTokens != tt.used {
+				t.Errorf("Used.TotalTokens = %d, want %d", got.Used.TotalTokens, tt.used)
+			}
+		})
+	}
+}
+
+func TestBudgetResult_Message(t *testing.T) {
+	r := BudgetResult{State: BudgetHard, Budget: config.BudgetConfig{Scope: "sender", Period: "daily"}}
+	if got := r.Message(); got != "The daily sender usage budget has been reached. Please try again later." {
+		t.Errorf("Message() = %q", got)
+	}
+	r.Budget.Message = "Out of credit."
+	if got := r.Message(); got != "Out of credit." {
+		t.Errorf("Message() with override = %q", got)
+	}
+}
+
+func TestLedger_CheckBudgetsUsesRunningTotals(t *testing.T) {
+	l := NewLedger(t.TempDir(), nil)
+	now := time.Date(2026, 3, 17, 12, 0, 0, 0, time.UTC)
+	budgets := []config.BudgetConfig{{Scope: "agent", Period: "daily", HardTokens: 100}}
+	subject := BudgetSubject{AgentID: "main"}
+
+	if err := l.Append(Record{Time: now.Add(-time.Hour), AgentID: "main", Model: "gpt", TotalTokens: 60}); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	if got, _ := l.CheckBudgets(budgets, subject, now); got.State != BudgetOK {
+		t.Fatalf("State = %v, want ok", got.State)
+	}
+
+	// The ledger file is only read once; later records come from Append.
+	if err := os.Remove(l.Path()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append(Record{Time: now, AgentID: "main", Model: "gpt", TotalTokens: 40}); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	got, err := l.CheckBudgets(budgets, subject, now)
+	if err != nil {
+		t.Fatalf("CheckBudgets() error: %v", err)
+	}
+	if got.State != BudgetHard || got.Used.TotalTokens != 100 {
+		t.Fatalf("result = %+v, want hard at 100 tokens", got)
+	}
+
+	// The next day starts from zero.
+	if got, _ := l.CheckBudgets(budgets, subject, now.AddDate(0, 0, 1)); got.State != BudgetOK {
+		t.Fatalf("State on the next day = %v, want ok", got.State)
+	}
+}
+
+func TestLedger_CheckBudgetsCountsOnlyBudgetCurrency(t *testing.T) {
+	l := NewLedger(t.TempDir(), nil)
+	now := time.Date(2026, 3, 17, 12, 0, 0, 0, time.UTC)
+	for _, r := range []Record{
+		{Time: now, AgentID: "main", Model: "a", TotalTokens: 10, Cost: 0.5, Currency: "USD"},
+		{Time: now, AgentID: "main", Model: "b", TotalTokens: 10, Cost: 5, Currency: "EUR"},
+	} {
+		if err := l.Append(r); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	got, err := l.CheckBudgets([]config.BudgetConfig{{Scope: "agent", Period: "daily", HardCost: 1}},
+		BudgetSubject{AgentID: "main"}, now)
+	if err != nil {
+		t.Fatalf("CheckBudgets() error: %v", err)
+	}
+	if got.State != BudgetOK {
+		t.Fatalf("State = %v, EUR costs must not count against a USD limit", got.State)
+	}
+
+	got, _ = l.CheckBudgets([]config.BudgetConfig{{Scope: "agent", Period: "daily", HardCost: 1, Currency: "EUR"}},
+		BudgetSubject{AgentID: "main"}, now)
+	if got.State != BudgetHard || got.Used.Cost != 5 || got.Used.Currency != "EUR" {
+		t.Fatalf("result = %+v, want hard at 5 EUR", got)
+	}
+}
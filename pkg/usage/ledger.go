@@ -0,0 +1,282 @@
+// Package usage records LLM token consumption and cost so that operators can
+// see what each agent, session and channel actually costs.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Record is one LLM call as stored in the ledger.
+type Record struct {
+	Time             time.Time `json:"time"`
+	AgentID          string    `json:"agent_id"`
+	SessionKey       string    `json:"session_key,omitempty"`
+	Channel          string    `json:"channel,omitempty"`
+	SenderID         string    `json:"sender_id,omitempty"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Cost             float64   `json:"cost,omitempty"`
+	Currency         string    `json:"currency,omitempty"`
+}
+
+// Filter selects ledger records. Zero-valued fields match everything.
+type Filter struct {
+	AgentID    string
+	SessionKey string
+	Channel    string
+	SenderID   string
+	Model      string
+	Since      time.Time
+	Until      time.Time
+}
+
+// Match reports whether r satisfies the filter.
+func (f Filter) Match(r Record) bool {
+	if f.AgentID != "" && r.AgentID != f.AgentID {
+		return false
+	}
+	if f.SessionKey != "" && r.SessionKey != f.SessionKey {
+		return false
+	}
+	if f.Channel != "" && r.Channel != f.Channel {
+		return false
+	}
+	if f.SenderID != "" && r.SenderID != f.SenderID {
+		return false
+	}
+	if f.Model != "" && r.Model != f.Model {
+		return false
+	}
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// mixedCurrency marks totals that add up costs in different currencies.
+const mixedCurrency = "mixed"
+
+// Totals aggregates a set of records.
+type Totals struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	Currency         string  `json:"currency,omitempty"`
+}
+
+// Add accumulates r into t.
+func (t *Totals) Add(r Record) {
+	t.Requests++
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.TotalTokens += r.TotalTokens
+	t.Cost += r.Cost
+	if r.Currency != "" {
+		switch t.Currency {
+		case "":
+			t.Currency = r.Currency
+		case r.Currency, mixedCurrency:
+		default:
+			t.Currency = mixedCurrency
+		}
+	}
+}
+
+// String renders the totals as a single human-readable line.
+func (t Totals) String() string {
+	s := fmt.Sprintf("%d requests, %d tokens (prompt %d, completion %d)",
+		t.Requests, t.TotalTokens, t.PromptTokens, t.CompletionTokens)
+	if t.Currency != "" {
+		s += fmt.Sprintf(", cost %.4f %s", t.Cost, t.Currency)
+	}
+	return s
+}
+
+// Summary groups totals by model, agent, channel and session.
+type Summary struct {
+	Total     Totals            `json:"total"`
+	ByModel   map[string]Totals `json:"by_model"`
+	ByAgent   map[string]Totals `json:"by_agent"`
+	ByChannel map[string]Totals `json:"by_channel"`
+	BySession map[string]Totals `json:"by_session"`
+}
+
+// Summarize aggregates records into a Summary.
+func Summarize(records []Record) Summary {
+	s := Summary{
+		ByModel:   make(map[string]Totals),
+		ByAgent:   make(map[string]Totals),
+		ByChannel: make(map[string]Totals),
+		BySession: make(map[string]Totals),
+	}
+	add := func(m map[string]Totals, key string, r Record) {
+		if key == "" {
+			return
+		}
+		t := m[key]
+		t.Add(r)
+		m[key] = t
+	}
+	for _, r := range records {
+		s.Total.Add(r)
+		add(s.ByModel, r.Model, r)
+		add(s.ByAgent, r.AgentID, r)
+		add(s.ByChannel, r.Channel, r)
+		add(s.BySession, r.SessionKey, r)
+	}
+	return s
+}
+
+// SortedKeys returns the keys of a totals map ordered by descending total
+// tokens, then by name, which is the order reports list them in.
+func SortedKeys(m map[string]Totals) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ti, tj := m[keys[i]].TotalTokens, m[keys[j]].TotalTokens
+		if ti != tj {
+			return ti > tj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Ledger is an append-only JSONL log of LLM usage stored in the workspace.
+type Ledger struct {
+	path   string
+	prices PriceTable
+	mu     sync.Mutex
+	index  *budgetIndex // loaded by the first CheckBudgets
+}
+
+// LedgerPath returns the location of the usage ledger for a workspace.
+func LedgerPath(workspace string) string {
+	return filepath.Join(workspace, "usage", "usage.jsonl")
+}
+
+// NewLedger opens the ledger of the given workspace. prices may be nil, in
+// which case records are stored without cost.
+func NewLedger(workspace string, prices PriceTable) *Ledger {
+	return &Ledger{
+		path:   LedgerPath(workspace),
+		prices: prices,
+	}
+}
+
+// Path returns the ledger file path.
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append prices r (when its cost is not already set) and writes it to the ledger.
+func (l *Ledger) Append(r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if r.TotalTokens == 0 {
+		r.TotalTokens = r.PromptTokens + r.CompletionTokens
+	}
+	if r.Cost == 0 && r.Currency == "" {
+		r.Cost, r.Currency = l.prices.Cost(r.Model, r.PromptTokens, r.CompletionTokens)
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write usage record: %w", err)
+	}
+	if l.index != nil {
+		l.index.add(r)
+	}
+	return nil
+}
+
+// Records returns all ledger records matching f, oldest first. A missing
+// ledger yields no records. Malformed lines are skipped.
+func (l *Ledger) Records(f Filter) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			logger.WarnCF("usage", "Skipping malformed usage record", map[string]any{
+				"line":  lineNo,
+				"error": err.Error(),
+			})
+			continue
+		}
+		if f.Match(r) {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return records, nil
+}
+
+// Summarize aggregates the records matching f.
+func (l *Ledger) Summarize(f Filter) (Summary, error) {
+	records, err := l.Records(f)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summarize(records), nil
+}
@@ -0,0 +1,120 @@
+package usage
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestPriceTable_Cost(t *testing.T) {
+	pt := NewPriceTable([]config.ModelConfig{
+		{
+			ModelName: "gpt4",
+			Model:     "openai/gpt-5.2",
+			Pricing:   &config.ModelPricing{Input: 2, Output: 8},
+		},
+		{ModelName: "free", Model: "ollama/llama3"},
+	})
+
+	for _, model := range []string{"gpt4", "openai/gpt-5.2", "gpt-5.2"} {
+		cost, currency := pt.Cost(model, 1_000_000, 500_000)
+		if math.Abs(cost-6) > 1e-9 {
+			t.Errorf("Cost(%q) = %v, want 6", model, cost)
+		}
+		if currency != DefaultCurrency {
+			t.Errorf("Cost(%q) currency = %q, want %q", model, currency, DefaultCurrency)
+		}
+	}
+
+	if cost, currency := pt.Cost("free", 1000, 1000); cost != 0 || currency != "" {
+		t.Errorf("Cost(free) = %v %q, want 0 and no currency", cost, currency)
+	}
+}
+
+func TestLedger_AppendAndSummarize(t *testing.T) {
+	workspace := t.TempDir()
+	prices := NewPriceTable([]config.ModelConfig{
+		{ModelName: "gpt4", Model: "openai/gpt-5.2", Pricing: &config.ModelPricing{Input: 1, Output: 2}},
+	})
+	l := NewLedger(workspace, prices)
+
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: day, AgentID: "main", SessionKey: "s1", Channel: "telegram", Model: "gpt4", PromptTokens: 100, CompletionTokens: 50},
+		{Time: day.Add(time.Hour), AgentID: "main", SessionKey: "s2", Channel: "discord", Model: "gpt4", PromptTokens: 10, CompletionTokens: 5},
+		{Time: day.Add(48 * time.Hour), AgentID: "helper", SessionKey: "s3", Channel: "telegram", Model: "local", PromptTokens: 7, CompletionTokens: 3},
+	}
+	for _, r := range records {
+		if err := l.Append(r); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	all, err := l.Summarize(Filter{})
+	if err != nil {
+		t.Fatalf("Summarize() error: %v", err)
+	}
+	if all.Total.Requests != 3 || all.Total.TotalTokens != 175 {
+		t.Fatalf("Total = %+v, want 3 requests / 175 tokens", all.Total)
+	}
+	if got := all.ByAgent["main"].TotalTokens; got != 165 {
+		t.Errorf("ByAgent[main].TotalTokens = %d, want 165", got)
+	}
+	if got := all.ByChannel["telegram"].Requests; got != 2 {
+		t.Errorf("ByChannel[telegram].Requests = %d, want 2", got)
+	}
+	wantCost := (110*1.0 + 55*2.0) / 1e6
+	if math.Abs(all.ByModel["gpt4"].Cost-wantCost) > 1e-12 {
+		t.Errorf("ByModel[gpt4].Cost = %v, want %v", all.ByModel["gpt4"].Cost, wantCost)
+	}
+	if all.ByModel["local"].Currency != "" {
+		t.Errorf("unpriced model should have no currency, got %q", all.ByModel["local"].Currency)
+	}
+
+	bySession, err := l.Summarize(Filter{SessionKey: "s2"})
+	if err != nil {
+		t.Fatalf("Summarize(session) error: %v", err)
+	}
+	if bySession.Total.Requests != 1 || bySession.Total.TotalTokens != 15 {
+		t.Errorf("session total = %+v, want 1 request / 15 tokens", bySession.Total)
+	}
+
+	since, err := l.Records(Filter{Since: day.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Records(since) error: %v", err)
+	}
+	if len(since) != 1 || since[0].AgentID != "helper" {
+		t.Errorf("Records(since) = %+v, want only helper record", since)
+	}
+}
+
+func TestLedger_MissingAndMalformed(t *testing.T) {
+	workspace := t.TempDir()
+	l := NewLedger(workspace, nil)
+
+	records, err := l.Records(Filter{})
+	if err != nil || len(records) != 0 {
+		t.Fatalf("Records() on missing ledger = %v, %v; want empty, nil", records, err)
+	}
+
+	if err := l.Append(Record{AgentID: "main", Model: "m", PromptTokens: 1}); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	f, err := os.OpenFile(l.Path(), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not json\n")
+	f.Close()
+
+	records, err = l.Records(Filter{})
+	if err != nil {
+		t.Fatalf("Records() error: %v", err)
+	}
+	if len(records) != 1 || records[0].TotalTokens != 1 {
+		t.Fatalf("Records() = %+v, want the single valid record", records)
+	}
+}
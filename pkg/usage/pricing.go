@@ -0,0 +1,61 @@
+package usage
+
+import (
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// DefaultCurrency is used when a ModelPricing entry does not name one.
+const DefaultCurrency = config.DefaultCurrency
+
+// PriceTable resolves the per-million-token price of a model. Entries are
+// keyed by model_name alias, by full "protocol/model" identifier and by the
+// bare model ID, so both the agent's configured alias and the resolved
+// fallback candidate model can be priced.
+type PriceTable map[string]config.ModelPricing
+
+// NewPriceTable builds a PriceTable from the pricing set on model_list entries.
+func NewPriceTable(models []config.ModelConfig) PriceTable {
+	pt := make(PriceTable)
+	for _, mc := range models {
+		if mc.Pricing == nil {
+			continue
+		}
+		price := *mc.Pricing
+		if price.Currency == "" {
+			price.Currency = DefaultCurrency
+		}
+		for _, key := range priceKeys(mc.ModelName, mc.Model) {
+			if _, exists := pt[key]; !exists {
+				pt[key] = price
+			}
+		}
+	}
+	return pt
+}
+
+func priceKeys(modelName, model string) []string {
+	keys := make([]string, 0, 3)
+	if name := strings.TrimSpace(modelName); name != "" {
+		keys = append(keys, name)
+	}
+	if full := strings.TrimSpace(model); full != "" {
+		keys = append(keys, full)
+		if _, id, ok := strings.Cut(full, "/"); ok && id != "" {
+			keys = append(keys, id)
+		}
+	}
+	return keys
+}
+
+// Cost returns the cost of a call and its currency. Unpriced models cost 0
+// and report an empty currency.
+func (pt PriceTable) Cost(model string, promptTokens, completionTokens int) (float64, string) {
+	price, ok := pt[strings.TrimSpace(model)]
+	if !ok {
+		return 0, ""
+	}
+	cost := float64(promptTokens)*price.Input/1e6 + float64(completionTokens)*price.Output/1e6
+	return cost, price.Currency
+}
@@ -29,6 +29,12 @@ var channelCatalog = []channelCatalogItem{
 	{Name: "maixcam", ConfigKey: "maixcam"},
 	{Name: "matrix", ConfigKey: "matrix"},
 	{Name: "irc", ConfigKey: "irc"},
+	{Name: "email", ConfigKey: "email"},
+	{Name: "webhook", ConfigKey: "webhook"},
+	{Name: "mqtt", ConfigKey: "mqtt"},
+	{Name: "mattermost", ConfigKey: "mattermost"},
+	{Name: "rocketchat", ConfigKey: "rocketchat"},
+	{Name: "xmpp", ConfigKey: "xmpp"},
 }
 
 // registerChannelRoutes binds read-only channel catalog endpoints to the ServeMux.
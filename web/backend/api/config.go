@@ -176,6 +176,9 @@ func validateConfig(cfg *config.Config) []string {
 	if err := cfg.ValidateModelList(); err != nil {
 		errs = append(errs, err.Error())
 	}
+	if err := cfg.ValidateBudgets(); err != nil {
+		errs = append(errs, err.Error())
+	}
 
 	// Gateway port range
 	if cfg.Gateway.Port != 0 && (cfg.Gateway.Port < 1 || cfg.Gateway.Port > 65535) {
@@ -1,20 +1,30 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/providers/ollama"
 )
 
+// ollamaListTimeout bounds the model picker's wait for a local server that
+// may not be running.
+const ollamaListTimeout = 5 * time.Second
+
 // registerModelRoutes binds model list management endpoints to the ServeMux.
 func (h *Handler) registerModelRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/models", h.handleListModels)
 	mux.HandleFunc("POST /api/models", h.handleAddModel)
 	mux.HandleFunc("POST /api/models/default", h.handleSetDefaultModel)
+	mux.HandleFunc("GET /api/models/ollama", h.handleListOllamaModels)
 	mux.HandleFunc("PUT /api/models/{index}", h.handleUpdateModel)
 	mux.HandleFunc("DELETE /api/models/{index}", h.handleDeleteModel)
 }
@@ -36,6 +46,7 @@ type modelResponse struct {
 	MaxTokensField string `json:"max_tokens_field,omitempty"`
 	RequestTimeout int    `json:"request_timeout,omitempty"`
 	ThinkingLevel  string `json:"thinking_level,omitempty"`
+	KeepAlive      string `json:"keep_alive,omitempty"`
 	// Meta
 	Configured bool `json:"configured"`
 	IsDefault  bool `json:"is_default"`
@@ -69,7 +80,8 @@ func (h *Handler) handleListModels(w http.ResponseWriter, r *http.Request) {
 			MaxTokensField: m.MaxTokensField,
 			RequestTimeout: m.RequestTimeout,
 			ThinkingLevel:  m.ThinkingLevel,
-			Configured:     m.APIKey != "" || m.AuthMethod != "",
+			KeepAlive:      m.KeepAlive,
+			Configured:     m.APIKey != "" || m.AuthMethod != "" || isOllamaModel(m.Model),
 			IsDefault:      m.ModelName == defaultModel,
 		})
 	}
@@ -82,6 +94,89 @@ func (h *Handler) handleListModels(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ollamaModelResponse is a model installed on the Ollama server. Model is
+// the value to put in a model_list entry to use it.
+type ollamaModelResponse struct {
+	Name              string    `json:"name"`
+	Model             string    `json:"model"`
+	Size              int64     `json:"size"`
+	Family            string    `json:"family,omitempty"`
+	ParameterSize     string    `json:"parameter_size,omitempty"`
+	QuantizationLevel string    `json:"quantization_level,omitempty"`
+	ModifiedAt        time.Time `json:"modified_at"`
+	Configured        bool      `json:"configured"` // already referenced by a model_list entry
+}
+
+// handleListOllamaModels lists the models installed on the Ollama server
+// the config points at, or on ?api_base= when given. Only servers that are
+// already configured can be queried, so the endpoint cannot be used to make
+// the backend fetch arbitrary URLs. An unreachable server is reported as
+// available=false rather than an error, since not running Ollama is the
+// common case.
+//
+//	GET /api/models/ollama
+func (h *Handler) handleListOllamaModels(w http.ResponseWriter, r *http.Request) {
+	cfg, err := h.loadFilteredConfig()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load config: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	apiBase := providers.OllamaAPIBase(cfg)
+	if base := r.URL.Query().Get("api_base"); base != "" {
+		apiBase = ollama.NormalizeAPIBase(base)
+		if !slices.Contains(providers.OllamaAPIBases(cfg), apiBase) {
+			http.Error(w, "api_base is not a configured Ollama server", http.StatusBadRequest)
+			return
+		}
+	}
+
+	configured := make(map[string]bool)
+	for _, m := range cfg.ModelList {
+		if protocol, modelID := providers.ExtractProtocol(m.Model); protocol == "ollama" {
+			configured[modelID] = true
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ollamaListTimeout)
+	defer cancel()
+
+	resp := map[string]any{"api_base": apiBase}
+	client := ollama.NewProvider(apiBase, "", ollama.WithAPIKey(providers.OllamaAPIKey(cfg, apiBase)))
+	installed, err := client.ListModels(ctx)
+	if err != nil {
+		resp["available"] = false
+		resp["error"] = err.Error()
+		resp["models"] = []ollamaModelResponse{}
+	} else {
+		models := make([]ollamaModelResponse, 0, len(installed))
+		for _, m := range installed {
+			models = append(models, ollamaModelResponse{
+				Name:              m.Name,
+				Model:             "ollama/" + m.Name,
+				Size:              m.Size,
+				Family:            m.Details.Family,
+				ParameterSize:     m.Details.ParameterSize,
+				QuantizationLevel: m.Details.QuantizationLevel,
+				ModifiedAt:        m.ModifiedAt,
+				Configured:        configured[m.Name],
+			})
+		}
+		resp["available"] = true
+		resp["models"] = models
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// isOllamaModel reports whether model uses the ollama protocol, which needs
+// no API key.
+func isOllamaModel(model string) bool {
+	protocol, _ := providers.ExtractProtocol(model)
+	return protocol == "ollama"
+}
+
 // handleAddModel appends a new model configuration entry.
 //
 //	POST /api/models
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestHandleListOllamaModels(t *testing.T) {
+	ollamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer ollama-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"models": []map[string]any{
+			{"name": "llama3.2:3b", "size": 2019393189, "details": map[string]any{"family": "llama", "parameter_size": "3.2B"}},
+			{"name": "qwen3:8b", "size": 5225376047},
+		}})
+	}))
+	defer ollamaServer.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cfg := config.DefaultConfig()
+	cfg.ModelList = []config.ModelConfig{
+		{ModelName: "llama", Model: "ollama/llama3.2:3b", APIBase: ollamaServer.URL + "/v1", APIKey: "ollama-key"},
+	}
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	NewHandler(configPath).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/models/ollama", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Available bool                  `json:"available"`
+		APIBase   string                `json:"api_base"`
+		Models    []ollamaModelResponse `json:"models"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !resp.Available || resp.APIBase != ollamaServer.URL {
+		t.Fatalf("available = %v, api_base = %q", resp.Available, resp.APIBase)
+	}
+	if len(resp.Models) != 2 {
+		t.Fatalf("models = %+v, want 2", resp.Models)
+	}
+	if m := resp.Models[0]; m.Model != "ollama/llama3.2:3b" || !m.Configured || m.ParameterSize != "3.2B" {
+		t.Errorf("models[0] = %+v", m)
+	}
+	if m := resp.Models[1]; m.Configured {
+		t.Errorf("models[1] = %+v, want not configured", m)
+	}
+
+	// The configured model needs no API key to count as configured.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/models", nil))
+	var list struct {
+		Models []modelResponse `json:"models"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(list.Models) != 1 || !list.Models[0].Configured {
+		t.Errorf("models = %+v, want the ollama entry configured", list.Models)
+	}
+}
+
+func TestHandleListOllamaModels_Unreachable(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cfg := config.DefaultConfig()
+	cfg.ModelList = []config.ModelConfig{
+		{ModelName: "local", Model: "ollama/llama3.2:3b"},
+		{ModelName: "remote", Model: "ollama/qwen3:8b", APIBase: downURL},
+	}
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	NewHandler(configPath).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/models/ollama?api_base="+downURL, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if resp["available"] != false || resp["error"] == "" || resp["api_base"] != downURL {
+		t.Errorf("response = %v", resp)
+	}
+	if models, _ := resp["models"].([]any); models == nil || len(models) != 0 {
+		t.Errorf("models = %v, want empty list", resp["models"])
+	}
+}
+
+func TestHandleListOllamaModels_RejectsUnconfiguredAPIBase(t *testing.T) {
+	var hits atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer other.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := config.SaveConfig(configPath, config.DefaultConfig()); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	NewHandler(configPath).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/models/ollama?api_base="+other.URL, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if hits.Load() != 0 {
+		t.Fatal("the backend requested an unconfigured api_base")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/sipeed/picoclaw/pkg/session"
 	"github.com/sipeed/picoclaw/web/backend/launcherconfig"
 )
 
@@ -16,6 +17,11 @@ type Handler struct {
 	oauthMu      sync.Mutex
 	oauthFlows   map[string]*oauthFlow
 	oauthState   map[string]string
+
+	sessionsMu      sync.Mutex
+	sessions        session.SessionStore // see sessionStore
+	sessionsBackend string
+	sessionsPath    string
 }
 
 // NewHandler creates an instance of the API handler.
@@ -49,6 +55,9 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	// Session history
 	h.registerSessionRoutes(mux)
 
+	// Token usage and cost accounting
+	h.registerUsageRoutes(mux)
+
 	// OAuth login and credential management
 	h.registerOAuthRoutes(mux)
 
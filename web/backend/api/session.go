@@ -1,35 +1,34 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
-	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/history"
+	"github.com/sipeed/picoclaw/pkg/routing"
+	"github.com/sipeed/picoclaw/pkg/session"
 )
 
 // registerSessionRoutes binds session list and detail endpoints to the ServeMux.
 func (h *Handler) registerSessionRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/sessions", h.handleListSessions)
+	mux.HandleFunc("GET /api/sessions/search", h.handleSearchSessions)
 	mux.HandleFunc("GET /api/sessions/{id}", h.handleGetSession)
+	mux.HandleFunc("GET /api/sessions/{id}/export", h.handleExportSession)
 	mux.HandleFunc("DELETE /api/sessions/{id}", h.handleDeleteSession)
 }
 
-// sessionFile mirrors the on-disk session JSON structure from pkg/session.
-type sessionFile struct {
-	Key      string              `json:"key"`
-	Messages []providers.Message `json:"messages"`
-	Summary  string              `json:"summary,omitempty"`
-	Created  time.Time           `json:"created"`
-	Updated  time.Time           `json:"updated"`
-}
-
 // sessionListItem is a lightweight summary returned by GET /api/sessions.
 type sessionListItem struct {
 	ID           string `json:"id"`
@@ -43,10 +42,6 @@ type sessionListItem struct {
 // channel sessions. The full key format is:
 //
 //	agent:main:pico:direct:pico:<session-uuid>
-//
-// The sanitized filename replaces ':' with '_', so on disk it becomes:
-//
-//	agent_main_pico_direct_pico_<session-uuid>.json
 const picoSessionPrefix = "agent:main:pico:direct:pico:"
 
 // extractPicoSessionID extracts the session UUID from a full session key.
@@ -85,50 +80,85 @@ func (h *Handler) sessionsDir() (string, error) {
 	return filepath.Join(workspace, "sessions"), nil
 }
 
+// sessionStore returns the session store the gateway is configured to
+// use. JSONL and SQLite stores read through to disk, so they are opened once
+// and reused until the configured backend or workspace changes. The JSON
+// backend keeps sessions in memory while the gateway process writes their
+// files, so it is reloaded on every call. The returned store is shared and
+// must not be closed by the caller.
+func (h *Handler) sessionStore() (session.SessionStore, error) {
+	cfg, err := config.LoadConfig(h.configPath)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := h.sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	backend := cfg.Agents.Defaults.SessionStore
+	if backend == "" || backend == config.SessionStoreJSON {
+		return session.NewSessionManager(dir), nil
+	}
+
+	h.sessionsMu.Lock()
+	defer h.sessionsMu.Unlock()
+
+	if h.sessions != nil && h.sessionsBackend == backend && h.sessionsPath == dir {
+		return h.sessions, nil
+	}
+	store, err := session.OpenStore(backend, dir)
+	if err != nil {
+		return nil, err
+	}
+	if h.sessions != nil {
+		h.sessions.Close()
+	}
+	h.sessions, h.sessionsBackend, h.sessionsPath = store, backend, dir
+	return store, nil
+}
+
+// findPicoSession looks up the Pico session with the given UUID.
+func findPicoSession(store session.SessionStore, sessionID string) (session.SessionInfo, bool, error) {
+	sessions, err := store.ListSessions()
+	if err != nil {
+		return session.SessionInfo{}, false, err
+	}
+	for _, info := range sessions {
+		if info.Key == picoSessionPrefix+sessionID {
+			return info, true, nil
+		}
+	}
+	return session.SessionInfo{}, false, nil
+}
+
 // handleListSessions returns a list of Pico session summaries.
 //
 //	GET /api/sessions
 func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
-	dir, err := h.sessionsDir()
+	store, err := h.sessionStore()
 	if err != nil {
-		http.Error(w, "failed to resolve sessions directory", http.StatusInternalServerError)
+		http.Error(w, "failed to open session store", http.StatusInternalServerError)
 		return
 	}
-
-	entries, err := os.ReadDir(dir)
+	sessions, err := store.ListSessions()
 	if err != nil {
-		// Directory doesn't exist yet = no sessions
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode([]sessionListItem{})
+		http.Error(w, "failed to list sessions", http.StatusInternalServerError)
 		return
 	}
 
 	items := []sessionListItem{}
 
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
-
-		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
-		if err != nil {
-			continue
-		}
-
-		var sess sessionFile
-		if err := json.Unmarshal(data, &sess); err != nil {
-			continue
-		}
-
+	for _, info := range sessions {
 		// Only include Pico channel sessions
-		sessionID, ok := extractPicoSessionID(sess.Key)
+		sessionID, ok := extractPicoSessionID(info.Key)
 		if !ok {
 			continue
 		}
+		messages := store.GetHistory(info.Key)
 
 		// Build a preview from the first user message
 		preview := ""
-		for _, msg := range sess.Messages {
+		for _, msg := range messages {
 			if msg.Role == "user" && strings.TrimSpace(msg.Content) != "" {
 				preview = msg.Content
 				break
@@ -143,7 +173,7 @@ func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
 
 		// Only count non-empty user and assistant messages
 		validMessageCount := 0
-		for _, msg := range sess.Messages {
+		for _, msg := range messages {
 			if (msg.Role == "user" || msg.Role == "assistant") && strings.TrimSpace(msg.Content) != "" {
 				validMessageCount++
 			}
@@ -153,8 +183,8 @@ func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
 			ID:           sessionID,
 			Preview:      preview,
 			MessageCount: validMessageCount,
-			Created:      sess.Created.Format(time.RFC3339),
-			Updated:      sess.Updated.Format(time.RFC3339),
+			Created:      info.CreatedAt.Format(time.RFC3339),
+			Updated:      info.UpdatedAt.Format(time.RFC3339),
 		})
 	}
 
@@ -193,6 +223,61 @@ func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(items)
 }
 
+// sessionSearchResult is a history search hit returned by
+// GET /api/sessions/search. ID is set when the hit belongs to a Pico session
+// so the UI can open it.
+type sessionSearchResult struct {
+	history.Result
+	ID string `json:"id,omitempty"`
+}
+
+// handleSearchSessions full-text searches the conversation history index.
+//
+//	GET /api/sessions/search?q=&agent=&session=&channel=&sender=&since=&until=&limit=
+//
+// since/until accept dates (2006-01-02), RFC 3339 timestamps or durations
+// ago (24h, 7d). An empty q returns the most recent matching messages.
+func (h *Handler) handleSearchSessions(w http.ResponseWriter, r *http.Request) {
+	cfg, err := config.LoadConfig(h.configPath)
+	if err != nil {
+		http.Error(w, "failed to load config", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	since, until, err := history.ParseRange(q.Get("since"), q.Get("until"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := 20
+	if val, err := strconv.Atoi(q.Get("limit")); err == nil && val > 0 {
+		limit = val
+	}
+
+	results, err := history.NewIndex(cfg.WorkspacePath()).Search(q.Get("q"), history.Filter{
+		AgentID:    q.Get("agent"),
+		SessionKey: q.Get("session"),
+		Channel:    q.Get("channel"),
+		SenderID:   q.Get("sender"),
+		Since:      since,
+		Until:      until,
+	}, limit)
+	if err != nil {
+		http.Error(w, "failed to search history", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]sessionSearchResult, 0, len(results))
+	for _, res := range results {
+		id, _ := extractPicoSessionID(res.SessionKey)
+		items = append(items, sessionSearchResult{Result: res, ID: id})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
 // handleGetSession returns the full message history for a specific session.
 //
 //	GET /api/sessions/{id}
@@ -203,27 +288,21 @@ func (h *Handler) handleGetSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dir, err := h.sessionsDir()
+	store, err := h.sessionStore()
 	if err != nil {
-		http.Error(w, "failed to resolve sessions directory", http.StatusInternalServerError)
+		http.Error(w, "failed to open session store", http.StatusInternalServerError)
 		return
 	}
-
-	// The sanitized filename replaces ':' with '_':
-	// agent:main:pico:direct:pico:<uuid> -> agent_main_pico_direct_pico_<uuid>.json
-	filename := strings.ReplaceAll(picoSessionPrefix+sessionID, ":", "_") + ".json"
-
-	data, err := os.ReadFile(filepath.Join(dir, filename))
+	info, found, err := findPicoSession(store, sessionID)
 	if err != nil {
-		http.Error(w, "session not found", http.StatusNotFound)
+		http.Error(w, "failed to list sessions", http.StatusInternalServerError)
 		return
 	}
-
-	var sess sessionFile
-	if err := json.Unmarshal(data, &sess); err != nil {
-		http.Error(w, "failed to parse session", http.StatusInternalServerError)
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
 		return
 	}
+	history := store.GetHistory(info.Key)
 
 	// Convert to a simpler format for the frontend
 	type chatMessage struct {
@@ -231,8 +310,8 @@ func (h *Handler) handleGetSession(w http.ResponseWriter, r *http.Request) {
 		Content string `json:"content"`
 	}
 
-	messages := make([]chatMessage, 0, len(sess.Messages))
-	for _, msg := range sess.Messages {
+	messages := make([]chatMessage, 0, len(history))
+	for _, msg := range history {
 		// Only include user and assistant messages that have actual content
 		if (msg.Role == "user" || msg.Role == "assistant") && strings.TrimSpace(msg.Content) != "" {
 			messages = append(messages, chatMessage{
@@ -246,12 +325,60 @@ func (h *Handler) handleGetSession(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]any{
 		"id":       sessionID,
 		"messages": messages,
-		"summary":  sess.Summary,
-		"created":  sess.Created.Format(time.RFC3339),
-		"updated":  sess.Updated.Format(time.RFC3339),
+		"summary":  store.GetSummary(info.Key),
+		"created":  info.CreatedAt.Format(time.RFC3339),
+		"updated":  info.UpdatedAt.Format(time.RFC3339),
 	})
 }
 
+// handleExportSession downloads a session as a portable transcript,
+// including tool calls, attachments and the summary.
+//
+//	GET /api/sessions/{id}/export?format=jsonl|markdown|openai
+func (h *Handler) handleExportSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+	if sessionID == "" {
+		http.Error(w, "missing session id", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = session.FormatJSONL
+	}
+	if !slices.Contains(session.TranscriptFormats, format) {
+		http.Error(w, "unsupported format", http.StatusBadRequest)
+		return
+	}
+
+	store, err := h.sessionStore()
+	if err != nil {
+		http.Error(w, "failed to open session store", http.StatusInternalServerError)
+		return
+	}
+
+	transcript, err := session.ExportTranscript(store, picoSessionPrefix+sessionID)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			http.Error(w, "session not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "failed to export session", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := session.EncodeTranscript(&buf, transcript, format); err != nil {
+		http.Error(w, "failed to encode session", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "picoclaw-session-" + sessionID + session.FormatExtension(format)
+	w.Header().Set("Content-Type", session.FormatContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Write(buf.Bytes())
+}
+
 // handleDeleteSession deletes a specific session.
 //
 //	DELETE /api/sessions/{id}
@@ -262,19 +389,15 @@ func (h *Handler) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dir, err := h.sessionsDir()
+	store, err := h.sessionStore()
 	if err != nil {
-		http.Error(w, "failed to resolve sessions directory", http.StatusInternalServerError)
+		http.Error(w, "failed to open session store", http.StatusInternalServerError)
 		return
 	}
 
-	// The sanitized filename replaces ':' with '_':
-	// agent:main:pico:direct:pico:<uuid> -> agent_main_pico_direct_pico_<uuid>.json
-	filename := strings.ReplaceAll(picoSessionPrefix+sessionID, ":", "_") + ".json"
-	filePath := filepath.Join(dir, filename)
-
-	if err := os.Remove(filePath); err != nil {
-		if os.IsNotExist(err) {
+	key := picoSessionPrefix + sessionID
+	if err := store.DeleteSession(key); err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
 			http.Error(w, "session not found", http.StatusNotFound)
 		} else {
 			http.Error(w, "failed to delete session", http.StatusInternalServerError)
@@ -282,5 +405,14 @@ func (h *Handler) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Deleted conversations must not stay searchable.
+	if cfg, err := config.LoadConfig(h.configPath); err == nil {
+		agentID := routing.ParseAgentSessionKey(key).AgentID
+		if _, err := history.NewIndex(cfg.WorkspacePath()).RemoveSession(agentID, key); err != nil {
+			http.Error(w, "failed to delete session history", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/history"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func TestHandleSearchSessions(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	configPath := filepath.Join(dir, "config.json")
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	now := time.Now()
+	if err := history.NewIndex(workspace).Append(
+		history.Entry{Time: now, AgentID: "main", SessionKey: picoSessionPrefix + "abc", Channel: "pico", Role: "user", Content: "backup the database"},
+		history.Entry{Time: now, AgentID: "main", SessionKey: "agent:main:telegram:direct:1", Channel: "telegram", Role: "user", Content: "database is slow"},
+	); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	NewHandler(configPath).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/search?q=database&channel=pico", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var results []sessionSearchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "abc" || results[0].Content != "backup the database" {
+		t.Fatalf("results = %+v, want the pico hit with id abc", results)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/search?q=x&since=someday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleExportSession(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	configPath := filepath.Join(dir, "config.json")
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	store := session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	if err := session.ImportTranscript(store, session.Transcript{
+		Key:     picoSessionPrefix + "abc",
+		Summary: "greetings",
+		Messages: []providers.Message{
+			{Role: "user", Content: "hello"},
+			{Role: "assistant", Content: "hi"},
+		},
+	}); err != nil {
+		t.Fatalf("ImportTranscript() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	NewHandler(configPath).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/abc/export?format=markdown", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=picoclaw-session-abc.md` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	transcript, err := session.DecodeTranscript(rec.Body, session.FormatMarkdown)
+	if err != nil {
+		t.Fatalf("DecodeTranscript() error = %v", err)
+	}
+	if transcript.Summary != "greetings" || len(transcript.Messages) != 2 {
+		t.Errorf("transcript = %+v", transcript)
+	}
+
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/api/sessions/missing/export", http.StatusNotFound},
+		{"/api/sessions/abc/export?format=xml", http.StatusBadRequest},
+	} {
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("GET %s status = %d, want %d", tc.path, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestSessionHandlers_SQLiteStore(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	configPath := filepath.Join(dir, "config.json")
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Agents.Defaults.SessionStore = config.SessionStoreSQLite
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	store, err := session.OpenStore(config.SessionStoreSQLite, filepath.Join(workspace, "sessions"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	store.AddMessage(picoSessionPrefix+"abc", "user", "hello")
+	store.AddMessage(picoSessionPrefix+"abc", "assistant", "hi")
+	store.AddMessage("agent:main:telegram:direct:1", "user", "not pico")
+	store.Close()
+	if err := history.NewIndex(workspace).Append(
+		history.Entry{AgentID: "main", SessionKey: picoSessionPrefix + "abc", Role: "user", Content: "hello"},
+	); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	h := NewHandler(configPath)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sessions", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var items []sessionListItem
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "abc" || items[0].Preview != "hello" || items[0].MessageCount != 2 {
+		t.Fatalf("items = %+v, want the pico session abc", items)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/abc", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var detail struct {
+		Messages []struct{ Role, Content string } `json:"messages"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(detail.Messages) != 2 || detail.Messages[1].Content != "hi" {
+		t.Fatalf("messages = %+v", detail.Messages)
+	}
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/sessions/abc", nil))
+		if rec.Code != want {
+			t.Fatalf("delete status = %d, want %d", rec.Code, want)
+		}
+	}
+	if entries, _ := history.NewIndex(workspace).Entries(history.Filter{}); len(entries) != 0 {
+		t.Fatalf("history after delete = %+v, want none", entries)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/abc", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("get after delete status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	h.sessions.Close()
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+// registerUsageRoutes binds the token usage endpoint to the ServeMux.
+func (h *Handler) registerUsageRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/usage", h.handleGetUsage)
+}
+
+// handleGetUsage returns aggregated token usage and cost from the usage ledger.
+//
+//	GET /api/usage?agent=&session=&channel=&model=&since=&until=
+//
+// since/until accept RFC 3339 timestamps or dates (2006-01-02).
+func (h *Handler) handleGetUsage(w http.ResponseWriter, r *http.Request) {
+	cfg, err := config.LoadConfig(h.configPath)
+	if err != nil {
+		http.Error(w, "failed to load config", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := usage.Filter{
+		AgentID:    q.Get("agent"),
+		SessionKey: q.Get("session"),
+		Channel:    q.Get("channel"),
+		Model:      q.Get("model"),
+	}
+	if filter.Since, err = parseUsageTime(q.Get("since")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = parseUsageTime(q.Get("until")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summary, err := usage.NewLedger(cfg.WorkspacePath(), nil).Summarize(filter)
+	if err != nil {
+		http.Error(w, "failed to read usage ledger", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+func parseUsageTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or 2006-01-02", value)
+}
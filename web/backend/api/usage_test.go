@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/usage"
+)
+
+func TestHandleGetUsage(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	configPath := filepath.Join(dir, "config.json")
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	ledger := usage.NewLedger(workspace, nil)
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, rec := range []usage.Record{
+		{Time: day, AgentID: "main", Channel: "pico", Model: "gpt4", TotalTokens: 10},
+		{Time: day.Add(48 * time.Hour), AgentID: "main", Channel: "telegram", Model: "gpt4", TotalTokens: 20},
+	} {
+		if err := ledger.Append(rec); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	NewHandler(configPath).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/usage?since=2026-03-02T00:00:00Z", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var summary usage.Summary
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if summary.Total.Requests != 1 || summary.Total.TotalTokens != 20 {
+		t.Fatalf("Total = %+v, want 1 request / 20 tokens", summary.Total)
+	}
+	if _, ok := summary.ByChannel["telegram"]; !ok {
+		t.Fatalf("ByChannel = %+v, want telegram entry", summary.ByChannel)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/usage?since=tomorrow", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
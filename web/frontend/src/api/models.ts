@@ -17,6 +17,7 @@ export interface ModelInfo {
   max_tokens_field?: string
   request_timeout?: number
   thinking_level?: string
+  keep_alive?: string
   // Meta
   configured: boolean
   is_default: boolean
@@ -28,6 +29,24 @@ interface ModelsListResponse {
   default_model: string
 }
 
+export interface OllamaModel {
+  name: string
+  model: string
+  size: number
+  family?: string
+  parameter_size?: string
+  quantization_level?: string
+  modified_at: string
+  configured: boolean
+}
+
+interface OllamaModelsResponse {
+  available: boolean
+  api_base: string
+  models: OllamaModel[]
+  error?: string
+}
+
 interface ModelActionResponse {
   status: string
   index?: number
@@ -48,6 +67,13 @@ export async function getModels(): Promise<ModelsListResponse> {
   return request<ModelsListResponse>("/api/models")
 }
 
+export async function getOllamaModels(
+  apiBase?: string,
+): Promise<OllamaModelsResponse> {
+  const query = apiBase ? `?api_base=${encodeURIComponent(apiBase)}` : ""
+  return request<OllamaModelsResponse>(`/api/models/ollama${query}`)
+}
+
 export async function addModel(
   model: Partial<ModelInfo>,
 ): Promise<ModelActionResponse> {
@@ -88,4 +114,4 @@ export async function setDefaultModel(
   return response
 }
 
-export type { ModelsListResponse, ModelActionResponse }
+export type { ModelsListResponse, ModelActionResponse, OllamaModelsResponse }
@@ -40,6 +40,17 @@ export async function getSessionHistory(id: string): Promise<SessionDetail> {
   return res.json()
 }
 
+export type SessionExportFormat = "markdown" | "jsonl" | "openai"
+
+// Returns the download URL of a session transcript in the given format.
+export function getSessionExportUrl(
+  id: string,
+  format: SessionExportFormat,
+): string {
+  const params = new URLSearchParams({ format })
+  return `/api/sessions/${encodeURIComponent(id)}/export?${params.toString()}`
+}
+
 export async function deleteSession(id: string): Promise<void> {
   const res = await fetch(`/api/sessions/${encodeURIComponent(id)}`, {
     method: "DELETE",
@@ -166,6 +166,30 @@ function isConfigured(
       )
     case "irc":
       return asString(config.server) !== ""
+    case "email":
+      return (
+        asString(config.imap_server) !== "" &&
+        asString(config.smtp_server) !== "" &&
+        asString(config.address) !== ""
+      )
+    case "mqtt":
+      return asString(config.broker) !== ""
+    case "mattermost":
+      return (
+        asString(config.server_url) !== "" && asString(config.token) !== ""
+      )
+    case "rocketchat":
+      return (
+        asString(config.server_url) !== "" &&
+        asString(config.user_id) !== "" &&
+        asString(config.auth_token) !== ""
+      )
+    case "xmpp":
+      return asString(config.jid) !== "" && asString(config.password) !== ""
+    case "webhook":
+      return (
+        asString(config.auth_token) !== "" || asString(config.secret) !== ""
+      )
     default:
       return false
   }
@@ -205,6 +229,16 @@ function getRequiredFieldKeys(channelName: string): string[] {
       return ["homeserver", "user_id", "access_token"]
     case "irc":
       return ["server"]
+    case "email":
+      return ["imap_server", "smtp_server", "address"]
+    case "mqtt":
+      return ["broker"]
+    case "mattermost":
+      return ["server_url", "token"]
+    case "rocketchat":
+      return ["server_url", "user_id", "auth_token"]
+    case "xmpp":
+      return ["jid", "password"]
     default:
       return []
   }
@@ -232,6 +266,12 @@ const CHANNELS_WITHOUT_DOCS = new Set([
   "wecom",
   "matrix",
   "irc",
+  "email",
+  "webhook",
+  "mqtt",
+  "mattermost",
+  "rocketchat",
+  "xmpp",
   "whatsapp",
   "whatsapp_native",
 ])
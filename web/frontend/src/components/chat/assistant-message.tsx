@@ -3,17 +3,26 @@ import { useState } from "react"
 import ReactMarkdown from "react-markdown"
 import remarkGfm from "remark-gfm"
 
+import { InteractiveControls } from "@/components/chat/interactive-controls"
 import { Button } from "@/components/ui/button"
-import { formatMessageTime } from "@/hooks/use-pico-chat"
+import {
+  type InteractionKind,
+  type Interactive,
+  formatMessageTime,
+} from "@/hooks/use-pico-chat"
 
 interface AssistantMessageProps {
   content: string
   timestamp?: string | number
+  interactive?: Interactive
+  onAction?: (kind: InteractionKind, value: string, label: string) => void
 }
 
 export function AssistantMessage({
   content,
   timestamp = "",
+  interactive,
+  onAction,
 }: AssistantMessageProps) {
   const [isCopied, setIsCopied] = useState(false)
   const formattedTimestamp =
@@ -41,9 +50,24 @@ export function AssistantMessage({
       </div>
 
       <div className="bg-card text-card-foreground relative overflow-hidden rounded-xl border">
+        {interactive?.card?.title && (
+          <div className="border-b px-4 py-3 font-semibold">
+            {interactive.card.title}
+          </div>
+        )}
+        {interactive?.card?.image_url && (
+          <img
+            src={interactive.card.image_url}
+            alt={interactive.card.title ?? ""}
+            className="max-h-64 w-full object-cover"
+          />
+        )}
         <div className="prose dark:prose-invert prose-p:my-2 prose-pre:my-2 prose-pre:rounded-lg prose-pre:border prose-pre:bg-zinc-950 prose-pre:p-3 max-w-none p-4 text-[15px] leading-relaxed">
           <ReactMarkdown remarkPlugins={[remarkGfm]}>{content}</ReactMarkdown>
         </div>
+        {interactive && onAction && (
+          <InteractiveControls interactive={interactive} onAction={onAction} />
+        )}
         <Button
           variant="ghost"
           size="icon"
@@ -6,6 +6,7 @@ import { AssistantMessage } from "@/components/chat/assistant-message"
 import { ChatComposer } from "@/components/chat/chat-composer"
 import { ChatEmptyState } from "@/components/chat/chat-empty-state"
 import { ModelSelector } from "@/components/chat/model-selector"
+import { SessionExportMenu } from "@/components/chat/session-export-menu"
 import { SessionHistoryMenu } from "@/components/chat/session-history-menu"
 import { TypingIndicator } from "@/components/chat/typing-indicator"
 import { UserMessage } from "@/components/chat/user-message"
@@ -27,6 +28,7 @@ export function ChatPage() {
     isTyping,
     activeSessionId,
     sendMessage,
+    sendAction,
     switchSession,
     newChat,
   } = usePicoChat()
@@ -92,6 +94,11 @@ export function ChatPage() {
           <span className="hidden sm:inline">{t("chat.newChat")}</span>
         </Button>
 
+        <SessionExportMenu
+          sessionId={activeSessionId}
+          disabled={messages.length === 0}
+        />
+
         <SessionHistoryMenu
           sessions={sessions}
           activeSessionId={activeSessionId}
@@ -127,6 +134,10 @@ export function ChatPage() {
                 <AssistantMessage
                   content={msg.content}
                   timestamp={msg.timestamp}
+                  interactive={msg.interactive}
+                  onAction={(kind, value, label) =>
+                    sendAction(msg.id, kind, value, label)
+                  }
                 />
               ) : (
                 <UserMessage content={msg.content} />
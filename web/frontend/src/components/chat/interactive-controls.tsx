@@ -0,0 +1,90 @@
+import { useState } from "react"
+
+import { Button } from "@/components/ui/button"
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select"
+import type { InteractionKind, Interactive } from "@/hooks/use-pico-chat"
+
+interface InteractiveControlsProps {
+  interactive: Interactive
+  onAction: (kind: InteractionKind, value: string, label: string) => void
+}
+
+// Renders the buttons and select menu of an assistant message. Controls are
+// disabled after the first answer so a question is only answered once.
+export function InteractiveControls({
+  interactive,
+  onAction,
+}: InteractiveControlsProps) {
+  const [answered, setAnswered] = useState(false)
+  const buttons = interactive.buttons ?? []
+  const options = interactive.select?.options ?? []
+
+  if (buttons.length === 0 && options.length === 0) {
+    return null
+  }
+
+  const answer = (kind: InteractionKind, value: string, label: string) => {
+    setAnswered(true)
+    onAction(kind, value, label)
+  }
+
+  return (
+    <div className="flex flex-wrap items-center gap-2 border-t px-4 py-3">
+      {buttons.map((button, i) =>
+        button.url ? (
+          <Button key={i} variant="link" size="sm" asChild>
+            <a href={button.url} target="_blank" rel="noreferrer">
+              {button.label}
+            </a>
+          </Button>
+        ) : (
+          <Button
+            key={i}
+            size="sm"
+            disabled={answered}
+            variant={
+              button.style === "primary"
+                ? "default"
+                : button.style === "danger"
+                  ? "destructive"
+                  : "outline"
+            }
+            onClick={() =>
+              answer("button", button.value || button.label, button.label)
+            }
+          >
+            {button.label}
+          </Button>
+        ),
+      )}
+      {options.length > 0 && (
+        <Select
+          disabled={answered}
+          onValueChange={(index: string) => {
+            const option = options[Number(index)]
+            if (option) {
+              answer("select", option.value || option.label, option.label)
+            }
+          }}
+        >
+          <SelectTrigger size="sm" className="min-w-[160px]">
+            <SelectValue placeholder={interactive.select?.placeholder} />
+          </SelectTrigger>
+          <SelectContent>
+            {options.map((option, i) => (
+              <SelectItem key={i} value={String(i)}>
+                {option.label}
+              </SelectItem>
+            ))}
+          </SelectContent>
+        </Select>
+      )}
+    </div>
+  )
+}
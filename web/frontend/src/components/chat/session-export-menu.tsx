@@ -0,0 +1,50 @@
+import { IconDownload } from "@tabler/icons-react"
+import { useTranslation } from "react-i18next"
+
+import { type SessionExportFormat, getSessionExportUrl } from "@/api/sessions"
+import { Button } from "@/components/ui/button"
+import {
+  DropdownMenu,
+  DropdownMenuContent,
+  DropdownMenuItem,
+  DropdownMenuTrigger,
+} from "@/components/ui/dropdown-menu"
+
+const exportFormats: SessionExportFormat[] = ["markdown", "jsonl", "openai"]
+
+interface SessionExportMenuProps {
+  sessionId: string
+  disabled?: boolean
+}
+
+export function SessionExportMenu({
+  sessionId,
+  disabled,
+}: SessionExportMenuProps) {
+  const { t } = useTranslation()
+
+  return (
+    <DropdownMenu>
+      <DropdownMenuTrigger asChild>
+        <Button
+          variant="outline"
+          size="sm"
+          className="h-9 gap-2"
+          disabled={disabled}
+        >
+          <IconDownload className="size-4" />
+          <span className="hidden sm:inline">{t("chat.export")}</span>
+        </Button>
+      </DropdownMenuTrigger>
+      <DropdownMenuContent align="end">
+        {exportFormats.map((format) => (
+          <DropdownMenuItem key={format} asChild>
+            <a href={getSessionExportUrl(sessionId, format)} download>
+              {t(`chat.exportFormat.${format}`)}
+            </a>
+          </DropdownMenuItem>
+        ))}
+      </DropdownMenuContent>
+    </DropdownMenu>
+  )
+}
@@ -188,6 +188,7 @@ export function ConfigPage() {
           },
           session: {
             dm_scope: dmScope,
+            per_thread: form.perThread,
           },
           heartbeat: {
             enabled: form.heartbeatEnabled,
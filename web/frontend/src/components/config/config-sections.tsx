@@ -167,6 +167,13 @@ export function RuntimeSection({ form, onFieldChange }: RuntimeSectionProps) {
           </Select>
         </Field>
 
+        <SwitchCardField
+          label={t("pages.config.session_per_thread")}
+          hint={t("pages.config.session_per_thread_hint")}
+          checked={form.perThread}
+          onCheckedChange={(checked) => onFieldChange("perThread", checked)}
+        />
+
         <SwitchCardField
           label={t("pages.config.heartbeat_enabled")}
           hint={t("pages.config.heartbeat_enabled_hint")}
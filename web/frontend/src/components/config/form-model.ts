@@ -8,6 +8,7 @@ export interface CoreConfigForm {
   summarizeMessageThreshold: string
   summarizeTokenPercent: string
   dmScope: string
+  perThread: boolean
   heartbeatEnabled: boolean
   heartbeatInterval: string
   devicesEnabled: boolean
@@ -59,6 +60,7 @@ export const EMPTY_FORM: CoreConfigForm = {
   summarizeMessageThreshold: "20",
   summarizeTokenPercent: "75",
   dmScope: "per-channel-peer",
+  perThread: false,
   heartbeatEnabled: true,
   heartbeatInterval: "30",
   devicesEnabled: false,
@@ -124,6 +126,7 @@ export function buildFormFromConfig(config: unknown): CoreConfigForm {
       EMPTY_FORM.summarizeTokenPercent,
     ),
     dmScope: asString(session.dm_scope) || EMPTY_FORM.dmScope,
+    perThread: asBool(session.per_thread),
     heartbeatEnabled:
       heartbeat.enabled === undefined
         ? EMPTY_FORM.heartbeatEnabled
@@ -15,11 +15,33 @@ interface PicoMessage {
   payload?: Record<string, unknown>
 }
 
+export interface InteractiveButton {
+  label: string
+  value?: string
+  style?: "primary" | "danger" | ""
+  url?: string
+}
+
+export interface InteractiveSelectOption {
+  label: string
+  value?: string
+}
+
+// Buttons, select menu and card header attached to an assistant message.
+export interface Interactive {
+  card?: { title?: string; image_url?: string }
+  buttons?: InteractiveButton[]
+  select?: { placeholder?: string; options: InteractiveSelectOption[] }
+}
+
+export type InteractionKind = "button" | "select"
+
 export interface ChatMessage {
   id: string
   role: "user" | "assistant"
   content: string
   timestamp: number | string
+  interactive?: Interactive
 }
 
 type ConnectionState = "disconnected" | "connecting" | "connected" | "error"
@@ -131,6 +153,8 @@ export function usePicoChat() {
             ? normalizeUnixTimestamp(Number(msg.timestamp))
             : Date.now()
 
+        const interactive = payload.interactive as Interactive | undefined
+
         setMessages((prev) => [
           ...prev,
           {
@@ -138,6 +162,7 @@ export function usePicoChat() {
             role: "assistant",
             content,
             timestamp: timestampRaw,
+            interactive,
           },
         ])
         setIsTyping(false)
@@ -314,6 +339,32 @@ export function usePicoChat() {
     wsRef.current.send(JSON.stringify(picoMsg))
   }, [])
 
+  // Answer a button click or select choice on an assistant message. The
+  // label is shown locally; the value is what the agent receives.
+  const sendAction = useCallback(
+    (messageId: string, kind: InteractionKind, value: string, label: string) => {
+      if (!wsRef.current || wsRef.current.readyState !== WebSocket.OPEN) {
+        console.warn("WebSocket not connected")
+        return
+      }
+
+      const id = `msg-${++msgIdCounter.current}-${Date.now()}`
+      setMessages((prev) => [
+        ...prev,
+        { id, role: "user", content: label, timestamp: Date.now() },
+      ])
+      setIsTyping(true)
+
+      const picoMsg: PicoMessage = {
+        type: "message.action",
+        id,
+        payload: { message_id: messageId, kind, value },
+      }
+      wsRef.current.send(JSON.stringify(picoMsg))
+    },
+    [],
+  )
+
   // Switch to a historical session
   const switchSession = useCallback(
     async (sessionId: string) => {
@@ -382,6 +433,7 @@ export function usePicoChat() {
     isTyping,
     activeSessionId,
     sendMessage,
+    sendAction,
     switchSession,
     newChat,
   }
@@ -41,9 +41,15 @@ const CHANNEL_IMPORTANCE_ORDER = [
   "qq",
   "onebot",
   "matrix",
+  "mattermost",
+  "rocketchat",
+  "xmpp",
   "pico",
   "maixcam",
   "irc",
+  "email",
+  "webhook",
+  "mqtt",
   "whatsapp",
   "whatsapp_native",
 ]